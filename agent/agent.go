@@ -3,10 +3,19 @@ package agent
 import (
 	"context"
 	"fmt"
+	"strings"
 
+	"github.com/counhopig/gittyai/debugger"
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/injection"
+	"github.com/counhopig/gittyai/limits"
 	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/locale"
 	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/presets"
+	"github.com/counhopig/gittyai/prompts"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/tools"
 )
 
 // Agent represents an AI agent with specific capabilities and behavior
@@ -17,16 +26,63 @@ type Agent struct {
 	Goal      string
 	Backstory string
 
+	// Skills are capability tags (e.g. "research", "sql") used to route tasks
+	Skills []string
+
+	// Tools lists the registry tools the agent may execute (empty = all);
+	// DenyTools are never allowed. Both apply to tools executed through a
+	// tools.Registry during the agent's tasks, including the tool calls of
+	// its responses when the context carries a registry.
+	Tools     []string
+	DenyTools []string
+
+	// Injection checks the results of the agent's tool calls, which are
+	// always enclosed in untrusted blocks before they reach the prompt
+	// (optional: without a detector, results are only enclosed)
+	Injection injection.Options
+
 	// Behavior
 	Verbose bool
+	// MaxIter bounds the LLM calls of a task that calls tools
 	MaxIter int
 	MaxRPM  int
 
+	// FailOnTruncation returns an error instead of a partial result when
+	// the LLM stops at its token limit
+	FailOnTruncation bool
+
+	// ChatMessages sends tasks as a conversation to providers implementing
+	// llm.ChatLLM: the agent's description as a system message, earlier
+	// session turns as user and assistant messages, then the task. Prompts
+	// edited by a debugger and responses streamed with OnChunk are sent as
+	// one prompt.
+	ChatMessages bool
+
+	// OnChunk, when set, streams responses: it is called with each chunk
+	// of text as the LLM produces it, or once with the whole response when
+	// the provider cannot stream. An error it returns fails the task.
+	OnChunk func(chunk string) error
+
 	// Memory
 	Memory memory.Memory
 
+	// Prompt optionally replaces the default prompt layout; it is rendered
+	// with a PromptData value
+	Prompt *prompts.Template
+
+	// Language is the language the agent writes in, e.g. "German" or "de".
+	// When empty, the language of the run carried by the context applies.
+	Language string
+
 	// LLM Provider
 	LLM llm.LLM
+	// Model references the LLM, e.g. a profile name, recorded in the
+	// agent's Definition (optional)
+	Model string
+
+	// Session, when set with OpenSession, carries the conversation history
+	// into prompts and persists every completed task
+	Session *Session
 }
 
 // Config represents the configuration for creating an Agent
@@ -35,18 +91,29 @@ type Config struct {
 	Role      string
 	Goal      string
 	Backstory string
+	Skills    []string
+	Tools     []string
+	DenyTools []string
+	Injection injection.Options
 	Verbose   bool
 	MaxIter   int
 	MaxRPM    int
 	LLM       llm.LLM
+	Model     string
 	Memory    memory.Memory
+	Prompt    *prompts.Template
+	Language  string
+
+	FailOnTruncation bool
+	ChatMessages     bool
+	OnChunk          func(chunk string) error
 }
 
 // New creates a new Agent
 func New(cfg Config) *Agent {
 	maxIter := cfg.MaxIter
 	if maxIter <= 0 {
-		maxIter = 25
+		maxIter = defaultMaxIter
 	}
 
 	maxRPM := cfg.MaxRPM
@@ -59,11 +126,22 @@ func New(cfg Config) *Agent {
 		Role:      cfg.Role,
 		Goal:      cfg.Goal,
 		Backstory: cfg.Backstory,
+		Skills:    cfg.Skills,
+		Tools:     cfg.Tools,
+		DenyTools: cfg.DenyTools,
+		Injection: cfg.Injection,
 		Verbose:   cfg.Verbose,
 		MaxIter:   maxIter,
 		MaxRPM:    maxRPM,
 		LLM:       cfg.LLM,
+		Model:     cfg.Model,
 		Memory:    cfg.Memory,
+		Prompt:    cfg.Prompt,
+		Language:  cfg.Language,
+
+		FailOnTruncation: cfg.FailOnTruncation,
+		ChatMessages:     cfg.ChatMessages,
+		OnChunk:          cfg.OnChunk,
 	}
 }
 
@@ -73,42 +151,224 @@ func (a *Agent) Execute(ctx context.Context, taskDescription string) (string, er
 		return "", errors.MissingConfig("LLM provider").WithContext("agent", a.Name)
 	}
 
-	// Build the prompt
-	prompt := a.buildPrompt(taskDescription)
+	if len(a.Tools) > 0 || len(a.DenyTools) > 0 {
+		ctx = tools.NewPermissionsContext(ctx, tools.Permissions{Agent: a.Name, Allow: a.Tools, Deny: a.DenyTools})
+	}
 
-	// Call LLM
-	resp, err := a.LLM.Generate(ctx, prompt)
+	prompt, err := a.PromptFor(ctx, taskDescription)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to execute task", err).WithContext("agent", a.Name).WithContext("task_length", len(taskDescription))
+		return "", err
+	}
+	var messages []llm.Message
+	if a.ChatMessages && a.OnChunk == nil {
+		if messages, err = a.MessagesFor(ctx, taskDescription); err != nil {
+			return "", err
+		}
+	}
+
+	// Agents with tools get their descriptions and may call them until they
+	// answer, within MaxIter LLM calls. Tool results are untrusted, so the
+	// model is told how to treat their blocks.
+	registry, usable := toolsFor(ctx)
+	if registry != nil {
+		section := injection.Notice + "\n\n" + toolsPrompt(usable)
+		prompt += "\n\n" + section
+		if len(messages) > 0 {
+			messages[len(messages)-1].Content += "\n\n" + section
+		}
+	}
+	maxIter := a.MaxIter
+	if maxIter <= 0 {
+		maxIter = defaultMaxIter
 	}
 
+	var completion *llm.Response
+	for iter := 1; ; iter++ {
+		if completion, err = a.complete(ctx, prompt, messages, taskDescription); err != nil {
+			return "", err
+		}
+		call := toolCall(registry, completion.Content)
+		if call == nil {
+			break
+		}
+		if iter >= maxIter {
+			return "", errors.Newf(errors.ErrQuotaExceeded, "agent %s called tools for %d iterations without answering", a.Name, iter).
+				WithContext("agent", a.Name).
+				WithContext("max_iter", maxIter)
+		}
+		result := toolResult(call, callTool(ctx, registry, call, a.Injection))
+		prompt += "\n\n" + completion.Content + "\n\n" + result
+		if messages != nil {
+			messages = append(messages,
+				llm.Message{Role: llm.RoleAssistant, Content: completion.Content},
+				llm.Message{Role: llm.RoleUser, Content: result},
+			)
+		}
+	}
+	resp := completion.Content
+
 	// Store in memory
 	if a.Memory != nil {
 		_ = a.Memory.Store(ctx, memory.Record{
 			AgentName: a.Name,
 			Content:   fmt.Sprintf("Task: %s\nResult: %s", taskDescription, resp),
+			RunID:     runid.FromContext(ctx),
 		})
 	}
 
+	if a.Session != nil {
+		if err := a.Session.record(ctx, a, taskDescription, resp); err != nil {
+			return "", err
+		}
+	}
+
 	return resp, nil
 }
 
-// buildPrompt constructs the prompt for the agent
-func (a *Agent) buildPrompt(task string) string {
+// complete makes one LLM call of a task: it sends messages, or prompt when
+// there are none or a debugger edited it, unless the debugger answers
+func (a *Agent) complete(ctx context.Context, prompt string, messages []llm.Message, taskDescription string) (*llm.Response, error) {
+	// A debugger may edit the prompt or answer in place of the LLM
+	decision, err := debugger.Pause(ctx, debugger.Step{Kind: debugger.KindLLM, RunID: runid.FromContext(ctx), Agent: a.Name, Prompt: prompt})
+	if err != nil {
+		return nil, err
+	}
+
+	var completion *llm.Response
+	switch decision.Action {
+	case debugger.Skip, debugger.Respond:
+		completion = &llm.Response{Content: decision.Response, FinishReason: llm.FinishStop}
+	default:
+		meter := limits.FromContext(ctx)
+		if err := meter.LLMCall(); err != nil {
+			return nil, err
+		}
+		switch {
+		case a.OnChunk != nil:
+			completion, err = llm.GenerateStream(ctx, a.LLM, decision.Prompt, a.OnChunk)
+		case messages != nil && decision.Prompt == prompt:
+			completion, err = llm.ChatResponse(ctx, a.LLM, messages)
+		default:
+			completion, err = llm.GenerateResponse(ctx, a.LLM, decision.Prompt)
+		}
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInternal, "failed to execute task", err).WithContext("agent", a.Name).WithContext("task_length", len(taskDescription))
+		}
+		meter.RecordResponse(completion)
+	}
+	if a.FailOnTruncation && completion.Truncated() {
+		return nil, errors.OutputTruncated(completion.FinishReason).WithContext("agent", a.Name)
+	}
+	return completion, nil
+}
+
+// PromptFor returns the prompt Execute sends to the LLM for a task, in the
+// agent's language or the one carried by ctx
+func (a *Agent) PromptFor(ctx context.Context, taskDescription string) (string, error) {
+	language := a.Language
+	if language == "" {
+		language = locale.FromContext(ctx)
+	}
+	prompt, err := a.buildPrompt(taskDescription, language)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInvalidConfig, "failed to build prompt", err).WithContext("agent", a.Name)
+	}
+	return prompt, nil
+}
+
+// MessagesFor returns the conversation Execute sends for a task when
+// ChatMessages is set: the agent's description as a system message, the
+// recent turns of its session, then the task. An agent with a prompt
+// template sends the rendered template as a single user message.
+func (a *Agent) MessagesFor(ctx context.Context, taskDescription string) ([]llm.Message, error) {
+	if a.Prompt != nil {
+		prompt, err := a.PromptFor(ctx, taskDescription)
+		if err != nil {
+			return nil, err
+		}
+		return []llm.Message{{Role: llm.RoleUser, Content: prompt}}, nil
+	}
+
+	language := a.Language
+	if language == "" {
+		language = locale.FromContext(ctx)
+	}
+	messages := []llm.Message{{Role: llm.RoleSystem, Content: a.description(language)}}
+	if a.Session != nil {
+		for _, t := range a.Session.recentTurns() {
+			messages = append(messages,
+				llm.Message{Role: llm.RoleUser, Content: t.Task},
+				llm.Message{Role: llm.RoleAssistant, Content: t.Response},
+			)
+		}
+	}
+	return append(messages, llm.Message{Role: llm.RoleUser, Content: taskDescription}), nil
+}
+
+// PromptData is the data available to agent prompt templates
+type PromptData struct {
+	Name      string
+	Role      string
+	Goal      string
+	Backstory string
+	Task      string
+	Language  string // Output language, empty when unset
+	History   string // Earlier turns of the agent's session, empty without one
+}
+
+// buildPrompt constructs the prompt for the agent. The default layout puts
+// the agent's static description and instructions before the task, so that
+// prompts for the same agent share a prefix providers can cache.
+func (a *Agent) buildPrompt(task, language string) (string, error) {
+	var history string
+	if a.Session != nil {
+		history = a.Session.historyPrompt()
+	}
+	if a.Prompt != nil {
+		prompt, err := a.Prompt.Render(PromptData{
+			Name:      a.Name,
+			Role:      a.Role,
+			Goal:      a.Goal,
+			Backstory: a.Backstory,
+			Task:      task,
+			Language:  language,
+			History:   history,
+		})
+		if err != nil {
+			return "", err
+		}
+		if instruction := locale.Instruction(language); instruction != "" {
+			prompt += "\n\n" + instruction
+		}
+		return prompt, nil
+	}
+
+	prompt := a.description(language)
+	if history != "" {
+		prompt += "\n\nConversation so far:\n" + history
+	}
+	return prompt + "\n\nTask: " + task, nil
+}
+
+// description returns the agent's static description and instructions,
+// which the default layout puts before the task
+func (a *Agent) description(language string) string {
+	instruction := locale.Instruction(language)
+	if instruction != "" {
+		instruction = "\n" + instruction
+	}
 	return fmt.Sprintf(
 		`You are %s.
 Your role is: %s
 Your goal is: %s
 Your backstory: %s
 
-Task: %s
-
-Please complete the task and provide a clear, detailed response.`,
+Please complete the task below and provide a clear, detailed response.%s`,
 		a.Name,
 		a.Role,
 		a.Goal,
 		a.Backstory,
-		task,
+		instruction,
 	)
 }
 
@@ -116,3 +376,52 @@ Please complete the task and provide a clear, detailed response.`,
 func (a *Agent) String() string {
 	return fmt.Sprintf("Agent{Name: %s, Role: %s, Goal: %s}", a.Name, a.Role, a.Goal)
 }
+
+// HasSkills reports whether the agent has every required skill.
+// Skills are compared case-insensitively.
+func (a *Agent) HasSkills(required []string) bool {
+	for _, want := range required {
+		found := false
+		for _, have := range a.Skills {
+			if strings.EqualFold(have, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// FromPreset creates an agent from a preset archetype such as "researcher".
+// Non-zero fields of overrides replace the preset's; the agent's name
+// defaults to the preset name.
+func FromPreset(name string, overrides Config) (*Agent, error) {
+	preset, err := presets.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cfg := overrides
+	if cfg.Name == "" {
+		cfg.Name = preset.Name
+	}
+	if cfg.Role == "" {
+		cfg.Role = preset.Role
+	}
+	if cfg.Goal == "" {
+		cfg.Goal = preset.Goal
+	}
+	if cfg.Backstory == "" {
+		cfg.Backstory = preset.Backstory
+	}
+	if len(cfg.Skills) == 0 {
+		cfg.Skills = preset.Skills
+	}
+	if len(cfg.Tools) == 0 {
+		cfg.Tools = preset.Tools
+	}
+	return New(cfg), nil
+}
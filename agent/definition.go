@@ -0,0 +1,120 @@
+package agent
+
+import (
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/prompts"
+)
+
+// Definition is the serializable part of an agent: its identity, behavior
+// and references to the runtime resources it needs. Definitions can be
+// stored, or shipped to workers that rebuild the agent with FromDefinition
+// and their own LLM clients and memory.
+type Definition struct {
+	Name      string   `json:"name" yaml:"name"`
+	Role      string   `json:"role" yaml:"role"`
+	Goal      string   `json:"goal" yaml:"goal"`
+	Backstory string   `json:"backstory,omitempty" yaml:"backstory,omitempty"`
+	Skills    []string `json:"skills,omitempty" yaml:"skills,omitempty"`
+	Tools     []string `json:"tools,omitempty" yaml:"tools,omitempty"`
+	DenyTools []string `json:"deny_tools,omitempty" yaml:"deny_tools,omitempty"`
+	MaxIter   int      `json:"max_iter,omitempty" yaml:"max_iter,omitempty"`
+	MaxRPM    int      `json:"max_rpm,omitempty" yaml:"max_rpm,omitempty"`
+	Language  string   `json:"language,omitempty" yaml:"language,omitempty"`
+
+	FailOnTruncation bool `json:"fail_on_truncation,omitempty" yaml:"fail_on_truncation,omitempty"`
+	ChatMessages     bool `json:"chat_messages,omitempty" yaml:"chat_messages,omitempty"`
+
+	// Model references the LLM the agent runs on, resolved by Runtime.LLM
+	Model string `json:"model,omitempty" yaml:"model,omitempty"`
+	// Prompt references the agent's prompt template ("name@version"),
+	// resolved by Runtime.Prompts
+	Prompt string `json:"prompt,omitempty" yaml:"prompt,omitempty"`
+}
+
+// Runtime provides the resources that definitions reference
+type Runtime struct {
+	// LLM returns the provider of the named agent for a model reference
+	LLM func(model, agent string) (llm.LLM, error)
+	// Memory returns the memory of the named agent (optional)
+	Memory func(name string) memory.Memory
+	// Prompts resolves prompt references (required for definitions with one)
+	Prompts *prompts.Library
+}
+
+// Definition returns the agent's definition
+func (a *Agent) Definition() Definition {
+	def := Definition{
+		Name:             a.Name,
+		Role:             a.Role,
+		Goal:             a.Goal,
+		Backstory:        a.Backstory,
+		Skills:           a.Skills,
+		Tools:            a.Tools,
+		DenyTools:        a.DenyTools,
+		MaxIter:          a.MaxIter,
+		MaxRPM:           a.MaxRPM,
+		Language:         a.Language,
+		FailOnTruncation: a.FailOnTruncation,
+		ChatMessages:     a.ChatMessages,
+		Model:            a.Model,
+	}
+	if a.Prompt != nil {
+		def.Prompt = a.Prompt.Ref()
+	}
+	return def
+}
+
+// FromDefinition builds an agent from its definition, resolving its model
+// and prompt references with rt
+func FromDefinition(def Definition, rt Runtime) (*Agent, error) {
+	if def.Name == "" {
+		return nil, errors.RequiredField("agent name")
+	}
+	if rt.LLM == nil {
+		return nil, errors.MissingConfig("runtime LLM resolver").WithContext("agent", def.Name)
+	}
+	provider, err := rt.LLM(def.Model, def.Name)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to resolve the agent's model", err).
+			WithContext("agent", def.Name).
+			WithContext("model", def.Model)
+	}
+
+	var prompt *prompts.Template
+	if def.Prompt != "" {
+		if rt.Prompts == nil {
+			return nil, errors.MissingConfig("runtime prompt library").WithContext("agent", def.Name).WithContext("prompt", def.Prompt)
+		}
+		if prompt, err = rt.Prompts.Get(def.Prompt); err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to resolve the agent's prompt", err).
+				WithContext("agent", def.Name).
+				WithContext("prompt", def.Prompt)
+		}
+	}
+
+	var mem memory.Memory
+	if rt.Memory != nil {
+		mem = rt.Memory(def.Name)
+	}
+
+	return New(Config{
+		Name:             def.Name,
+		Role:             def.Role,
+		Goal:             def.Goal,
+		Backstory:        def.Backstory,
+		Skills:           def.Skills,
+		Tools:            def.Tools,
+		DenyTools:        def.DenyTools,
+		MaxIter:          def.MaxIter,
+		MaxRPM:           def.MaxRPM,
+		LLM:              provider,
+		Model:            def.Model,
+		Memory:           mem,
+		Prompt:           prompt,
+		Language:         def.Language,
+		FailOnTruncation: def.FailOnTruncation,
+		ChatMessages:     def.ChatMessages,
+	}), nil
+}
@@ -0,0 +1,300 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// DefaultSessionHistory is the number of past turns included in prompts
+const DefaultSessionHistory = 20
+
+// maxStoredTurns bounds the turns kept in a session; the oldest are dropped
+const maxStoredTurns = 1000
+
+// Turn is one task handled by an agent in a session and its response
+type Turn struct {
+	Task     string    `json:"task"`
+	Response string    `json:"response"`
+	RunID    string    `json:"run_id,omitempty"`
+	Time     time.Time `json:"time"`
+}
+
+// SessionState is the persisted state of a session
+type SessionState struct {
+	ID        string          `json:"id"`
+	Agent     string          `json:"agent"`
+	History   []Turn          `json:"history"`
+	Memory    []memory.Record `json:"memory,omitempty"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// SessionStore persists sessions. Implementations must be safe for
+// concurrent use.
+type SessionStore interface {
+	// Load returns a session, or an ErrNotFound error if it does not exist
+	Load(ctx context.Context, id string) (*SessionState, error)
+	// Save creates or replaces a session
+	Save(ctx context.Context, state *SessionState) error
+}
+
+// SessionConfig configures a session
+type SessionConfig struct {
+	ID    string
+	Store SessionStore
+	// MaxHistory is the number of past turns included in prompts
+	// (0 = DefaultSessionHistory, negative = none)
+	MaxHistory int
+}
+
+// Session keeps an agent's conversation history and memory across
+// orchestrator runs, so a conversational product can resume days later with
+// full context. Every task the agent completes is appended to the history
+// and saved with a snapshot of the agent's memory.
+type Session struct {
+	store      SessionStore
+	maxHistory int
+
+	mu    sync.Mutex
+	state *SessionState
+}
+
+// OpenSession loads the session cfg.ID for a, or starts it if it does not
+// exist, and attaches it to the agent. The agent's memory is restored from
+// the session when it is empty; an agent without memory gets a new one.
+func OpenSession(ctx context.Context, a *Agent, cfg SessionConfig) (*Session, error) {
+	if cfg.ID == "" {
+		return nil, errors.RequiredField("session ID")
+	}
+	if cfg.Store == nil {
+		return nil, errors.RequiredField("session store")
+	}
+	if cfg.MaxHistory == 0 {
+		cfg.MaxHistory = DefaultSessionHistory
+	}
+
+	state, err := cfg.Store.Load(ctx, cfg.ID)
+	switch {
+	case errors.HasCode(err, errors.ErrNotFound):
+		now := time.Now().UTC()
+		state = &SessionState{ID: cfg.ID, Agent: a.Name, CreatedAt: now, UpdatedAt: now}
+	case err != nil:
+		return nil, err
+	case state.Agent != a.Name:
+		return nil, errors.InvalidField("session ID", "belongs to another agent").
+			WithContext("session", cfg.ID).
+			WithContext("session_agent", state.Agent).
+			WithContext("agent", a.Name)
+	}
+
+	if a.Memory == nil {
+		a.Memory = memory.New()
+	}
+	if len(state.Memory) > 0 {
+		existing, err := a.Memory.Retrieve(ctx, "", 1)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInternal, "failed to read agent memory", err).WithContext("agent", a.Name)
+		}
+		if len(existing) == 0 {
+			for _, record := range state.Memory {
+				if err := a.Memory.Store(ctx, record); err != nil {
+					return nil, errors.Wrap(errors.ErrInternal, "failed to restore agent memory", err).WithContext("agent", a.Name)
+				}
+			}
+		}
+	}
+
+	s := &Session{store: cfg.Store, maxHistory: cfg.MaxHistory, state: state}
+	a.Session = s
+	return s, nil
+}
+
+// ID returns the session ID
+func (s *Session) ID() string {
+	return s.state.ID
+}
+
+// History returns the turns of the session, oldest first
+func (s *Session) History() []Turn {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Turn(nil), s.state.History...)
+}
+
+// recentTurns returns the most recent turns included in prompts
+func (s *Session) recentTurns() []Turn {
+	if s.maxHistory < 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	turns := s.state.History
+	if len(turns) > s.maxHistory {
+		turns = turns[len(turns)-s.maxHistory:]
+	}
+	return append([]Turn(nil), turns...)
+}
+
+// historyPrompt formats the most recent turns for inclusion in a prompt
+func (s *Session) historyPrompt() string {
+	var b strings.Builder
+	for _, t := range s.recentTurns() {
+		fmt.Fprintf(&b, "User: %s\nYou: %s\n\n", t.Task, t.Response)
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// record appends a turn and saves the session with a snapshot of the
+// agent's memory
+func (s *Session) record(ctx context.Context, a *Agent, task, response string) error {
+	// Only the agent's own records are kept; a shared memory holds others'
+	var records []memory.Record
+	if a.Memory != nil {
+		all, err := a.Memory.Retrieve(ctx, "", 0)
+		if err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to read agent memory", err).WithContext("agent", a.Name)
+		}
+		for _, r := range all {
+			if r.AgentName == a.Name {
+				records = append(records, r)
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now().UTC()
+	s.state.History = append(s.state.History, Turn{Task: task, Response: response, RunID: runid.FromContext(ctx), Time: now})
+	if len(s.state.History) > maxStoredTurns {
+		s.state.History = s.state.History[len(s.state.History)-maxStoredTurns:]
+	}
+	s.state.Memory = records
+	s.state.UpdatedAt = now
+	if err := s.store.Save(ctx, s.state); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to save session", err).WithContext("session", s.state.ID)
+	}
+	return nil
+}
+
+// MemorySessionStore keeps sessions in memory, for tests and single-process
+// use
+type MemorySessionStore struct {
+	mu       sync.Mutex
+	sessions map[string][]byte
+}
+
+// NewMemorySessionStore creates an empty in-memory session store
+func NewMemorySessionStore() *MemorySessionStore {
+	return &MemorySessionStore{sessions: make(map[string][]byte)}
+}
+
+// Load returns a copy of the stored session
+func (s *MemorySessionStore) Load(ctx context.Context, id string) (*SessionState, error) {
+	s.mu.Lock()
+	data, ok := s.sessions[id]
+	s.mu.Unlock()
+	if !ok {
+		return nil, errors.NotFound("session", id)
+	}
+	return decodeSession(data, id)
+}
+
+// Save stores a copy of the session
+func (s *MemorySessionStore) Save(ctx context.Context, state *SessionState) error {
+	data, err := json.Marshal(state)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to encode session", err).WithContext("session", state.ID)
+	}
+	s.mu.Lock()
+	s.sessions[state.ID] = data
+	s.mu.Unlock()
+	return nil
+}
+
+// validSessionID restricts session IDs stored as file names
+var validSessionID = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// FileSessionStore keeps each session in a JSON file named after its ID
+type FileSessionStore struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileSessionStore creates a store writing sessions to dir
+func NewFileSessionStore(dir string) (*FileSessionStore, error) {
+	if dir == "" {
+		return nil, errors.RequiredField("directory")
+	}
+	return &FileSessionStore{dir: dir}, nil
+}
+
+// path returns the file of a session
+func (s *FileSessionStore) path(id string) (string, error) {
+	if !validSessionID.MatchString(id) {
+		return "", errors.InvalidField("session ID", "must contain only letters, digits, '.', '_' and '-'").WithContext("session", id)
+	}
+	return filepath.Join(s.dir, id+".json"), nil
+}
+
+// Load reads a session file
+func (s *FileSessionStore) Load(ctx context.Context, id string) (*SessionState, error) {
+	path, err := s.path(id)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("session", id)
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read session", err).WithContext("path", path)
+	}
+	return decodeSession(data, id)
+}
+
+// Save writes the session to a temporary file and renames it over the
+// session's file, so a crash never leaves a partial file
+func (s *FileSessionStore) Save(ctx context.Context, state *SessionState) error {
+	path, err := s.path(state.ID)
+	if err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to encode session", err).WithContext("session", state.ID)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := os.MkdirAll(s.dir, 0o755); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to create session directory", err).WithContext("path", s.dir)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write session", err).WithContext("path", path)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write session", err).WithContext("path", path)
+	}
+	return nil
+}
+
+// decodeSession parses a stored session
+func decodeSession(data []byte, id string) (*SessionState, error) {
+	var state SessionState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid session", err).WithContext("session", id)
+	}
+	return &state, nil
+}
@@ -0,0 +1,110 @@
+package agent
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+)
+
+type promptRecorder struct {
+	prompts *[]string
+}
+
+func (r promptRecorder) Generate(ctx context.Context, prompt string) (string, error) {
+	*r.prompts = append(*r.prompts, prompt)
+	return "Noted.", nil
+}
+
+func TestSession_ResumesAcrossRuns(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileSessionStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileSessionStore() unexpected error: %v", err)
+	}
+	var prompts []string
+
+	first := New(Config{Name: "assistant", LLM: promptRecorder{prompts: &prompts}})
+	if _, err := OpenSession(ctx, first, SessionConfig{ID: "user-42", Store: store}); err != nil {
+		t.Fatalf("OpenSession() unexpected error: %v", err)
+	}
+	if _, err := first.Execute(ctx, "My name is Ada."); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+
+	// A new agent, as in a later process, resumes the session
+	second := New(Config{Name: "assistant", LLM: promptRecorder{prompts: &prompts}})
+	session, err := OpenSession(ctx, second, SessionConfig{ID: "user-42", Store: store})
+	if err != nil {
+		t.Fatalf("OpenSession() resume unexpected error: %v", err)
+	}
+	if len(session.History()) != 1 {
+		t.Fatalf("History() = %+v, want 1 turn", session.History())
+	}
+	if records, _ := second.Memory.Retrieve(ctx, "Ada", 0); len(records) != 1 {
+		t.Errorf("restored memory = %+v, want the first task", records)
+	}
+	if _, err := second.Execute(ctx, "What is my name?"); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if !strings.Contains(prompts[1], "User: My name is Ada.\nYou: Noted.") {
+		t.Errorf("prompt missing history:\n%s", prompts[1])
+	}
+
+	other := New(Config{Name: "reviewer", LLM: replyLLM{}})
+	if _, err := OpenSession(ctx, other, SessionConfig{ID: "user-42", Store: store}); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("OpenSession() for another agent error = %v, want ErrInvalidField", err)
+	}
+	if _, err := store.Load(ctx, "../escape"); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("Load() with path ID error = %v, want ErrInvalidField", err)
+	}
+}
+
+// chatRecorder records the conversations it is sent
+type chatRecorder struct {
+	replyLLM
+	conversations *[][]llm.Message
+}
+
+func (r chatRecorder) Chat(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	resp, err := r.ChatResponse(ctx, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: resp.Content}, nil
+}
+
+func (r chatRecorder) ChatResponse(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	*r.conversations = append(*r.conversations, messages)
+	return &llm.Response{Content: r.reply}, nil
+}
+
+func TestSession_ChatMessages(t *testing.T) {
+	ctx := context.Background()
+	var conversations [][]llm.Message
+	a := New(Config{Name: "assistant", Role: "Helper", ChatMessages: true, LLM: chatRecorder{replyLLM{reply: "Noted."}, &conversations}})
+	if _, err := OpenSession(ctx, a, SessionConfig{ID: "user-7", Store: NewMemorySessionStore()}); err != nil {
+		t.Fatalf("OpenSession() unexpected error: %v", err)
+	}
+	for _, task := range []string{"My name is Ada.", "What is my name?"} {
+		if _, err := a.Execute(ctx, task); err != nil {
+			t.Fatalf("Execute() unexpected error: %v", err)
+		}
+	}
+
+	if len(conversations) != 2 {
+		t.Fatalf("conversations = %d, want 2", len(conversations))
+	}
+	got := conversations[1]
+	want := []llm.Message{
+		{Role: llm.RoleUser, Content: "My name is Ada."},
+		{Role: llm.RoleAssistant, Content: "Noted."},
+		{Role: llm.RoleUser, Content: "What is my name?"},
+	}
+	if len(got) != 4 || got[0].Role != llm.RoleSystem || !strings.Contains(got[0].Content, "Your role is: Helper") || !reflect.DeepEqual(got[1:], want) {
+		t.Errorf("conversation = %+v, want the description, the earlier turn, then the task", got)
+	}
+}
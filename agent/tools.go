@@ -0,0 +1,99 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/counhopig/gittyai/injection"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// defaultMaxIter bounds the LLM calls of a task when MaxIter is unset
+const defaultMaxIter = 25
+
+// toolsFor returns the registry carried by ctx and the tools of it the
+// permissions carried by ctx let the agent use, sorted by name. The
+// registry is nil when the agent has no tool to use.
+func toolsFor(ctx context.Context) (*tools.Registry, []tools.Tool) {
+	registry := tools.RegistryFromContext(ctx)
+	if registry == nil {
+		return nil, nil
+	}
+	p, restricted := tools.PermissionsFromContext(ctx)
+	names := registry.List()
+	sort.Strings(names)
+	var usable []tools.Tool
+	for _, name := range names {
+		if restricted && !p.Permits(name) {
+			continue
+		}
+		if tool, err := registry.Get(name); err == nil {
+			usable = append(usable, tool)
+		}
+	}
+	if len(usable) == 0 {
+		return nil, nil
+	}
+	return registry, usable
+}
+
+// toolsPrompt describes the tools an agent may use and how to call them
+func toolsPrompt(usable []tools.Tool) string {
+	var b strings.Builder
+	b.WriteString("You can use these tools:\n")
+	for _, tool := range usable {
+		fmt.Fprintf(&b, "- %s: %s", tool.Name(), tool.Description())
+		if args, err := json.Marshal(tool.Args()); err == nil && len(tool.Args()) > 0 {
+			fmt.Fprintf(&b, " Arguments: %s", args)
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(`To use a tool, reply with only a JSON object such as {"name": "tool_name", "arguments": {...}}; its result will be sent back to you. When you have the final answer, reply with the answer instead.`)
+	return b.String()
+}
+
+// toolCall returns the tool call a response makes, or nil when the response
+// is an answer. Calls to tools of the registry the agent may not use are
+// returned too, so the registry reports them to the model.
+func toolCall(registry *tools.Registry, response string) *tools.ToolCall {
+	if registry == nil || !strings.Contains(response, `"name"`) {
+		return nil
+	}
+	call, err := tools.ParseToolCall(response)
+	if err != nil || call.Name == "" {
+		return nil
+	}
+	if _, err := registry.Get(call.Name); err != nil {
+		return nil
+	}
+	return call
+}
+
+// callTool executes a tool call and returns its output enclosed in an
+// untrusted block, checked with opts. Tools wrapped with injection.WrapTool
+// guard their own output. Failures, including blocked output, are
+// described to the model like the registry does.
+func callTool(ctx context.Context, registry *tools.Registry, call *tools.ToolCall, opts injection.Options) string {
+	output, err := registry.Execute(ctx, call.Name, call.Arguments)
+	if err != nil {
+		return tools.DescribeError(err)
+	}
+	if tool, err := registry.Get(call.Name); err == nil {
+		if _, guarded := tool.(*injection.Tool); guarded {
+			return output
+		}
+	}
+	guarded, err := injection.Guard(ctx, "tool:"+call.Name, output, opts)
+	if err != nil {
+		return tools.DescribeError(err)
+	}
+	return guarded
+}
+
+// toolResult is the message giving a tool's output back to the model
+func toolResult(call *tools.ToolCall, output string) string {
+	return fmt.Sprintf("Result of %s:\n%s", call.Name, output)
+}
@@ -0,0 +1,89 @@
+package agent
+
+import (
+	"context"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+)
+
+// probePrompt is a request any working model can answer in a few tokens
+const probePrompt = "Reply with the single word OK."
+
+// selfTestTask asks the model to confirm it understands the agent's role
+const selfTestTask = `Before any work starts, confirm you understand your role.
+Answer with CONFIRMED followed by one sentence stating your role and goal, or with UNCLEAR followed by what is ambiguous or contradictory.`
+
+// Validate checks that the agent can run before a long run starts: its LLM
+// is configured and reachable, and accepts a trivial request. Provider
+// rejections are classified: bad credentials as ErrInvalidAPIKey, unknown
+// models as ErrNotFound and exhausted quotas as ErrRateLimitExceeded.
+func (a *Agent) Validate(ctx context.Context) error {
+	if a.LLM == nil {
+		return errors.MissingConfig("LLM provider").WithContext("agent", a.Name)
+	}
+	if err := llm.Ping(ctx, a.LLM); err != nil {
+		return classifyProbeError(err).WithContext("agent", a.Name)
+	}
+	if _, err := a.LLM.Generate(ctx, probePrompt); err != nil {
+		return classifyProbeError(err).WithContext("agent", a.Name)
+	}
+	return nil
+}
+
+// SelfTest asks the model to restate the agent's role and goal with the
+// agent's full prompt, returning its answer. It fails with ErrInvalidConfig
+// when the model reports the role as unclear.
+func (a *Agent) SelfTest(ctx context.Context) (string, error) {
+	if err := a.Validate(ctx); err != nil {
+		return "", err
+	}
+	prompt, err := a.buildPrompt(selfTestTask, "")
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInvalidConfig, "failed to build prompt", err).WithContext("agent", a.Name)
+	}
+	answer, err := a.LLM.Generate(ctx, prompt)
+	if err != nil {
+		return "", classifyProbeError(err).WithContext("agent", a.Name)
+	}
+
+	answer = strings.TrimSpace(answer)
+	if strings.HasPrefix(strings.ToUpper(answer), "UNCLEAR") {
+		return answer, errors.New(errors.ErrInvalidConfig, "model reports the agent's role is unclear").
+			WithContext("agent", a.Name).
+			WithContext("answer", answer)
+	}
+	return answer, nil
+}
+
+// classifyProbeError maps provider status codes to specific error codes
+func classifyProbeError(err error) *errors.Error {
+	var apiErr *errors.Error
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		if se, ok := e.(*errors.Error); ok && se.Code == errors.ErrAPIStatusCode {
+			apiErr = se
+			break
+		}
+	}
+
+	if apiErr != nil {
+		status, _ := apiErr.Context["status_code"].(int)
+		switch status {
+		case http.StatusUnauthorized:
+			return errors.Wrap(errors.ErrInvalidAPIKey, "provider rejected the API key", err)
+		case http.StatusForbidden:
+			return errors.Wrap(errors.ErrUnauthorized, "API key is not allowed to use the model", err)
+		case http.StatusNotFound:
+			return errors.Wrap(errors.ErrNotFound, "provider does not serve the model", err)
+		case http.StatusTooManyRequests:
+			return errors.Wrap(errors.ErrRateLimitExceeded, "provider rate limit or quota exceeded", err).WithRetryable(true)
+		}
+	}
+	if se, ok := err.(*errors.Error); ok {
+		return se
+	}
+	return errors.Wrap(errors.ErrAPICall, "LLM probe failed", err)
+}
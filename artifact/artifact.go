@@ -0,0 +1,62 @@
+// Package artifact stores large task outputs and tool-produced files in
+// object storage, so results carry references instead of the content.
+package artifact
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Ref points to a stored artifact
+type Ref struct {
+	URI         string // e.g. "s3://bucket/key", "gs://bucket/key" or "file:///path"
+	Size        int64
+	ContentType string
+}
+
+// Store saves and loads artifacts
+type Store interface {
+	// Put stores data under key. An empty contentType is detected from data.
+	Put(ctx context.Context, key string, data []byte, contentType string) (*Ref, error)
+	// Get loads the artifact a Ref returned by Put points to
+	Get(ctx context.Context, uri string) ([]byte, error)
+}
+
+// DetectContentType returns contentType, or a type sniffed from data when empty
+func DetectContentType(data []byte, contentType string) string {
+	if contentType != "" {
+		return contentType
+	}
+	return http.DetectContentType(data)
+}
+
+// splitURI splits "scheme://bucket/key" into bucket and key
+func splitURI(uri, scheme string) (bucket, key string, err error) {
+	rest, ok := strings.CutPrefix(uri, scheme+"://")
+	if !ok {
+		return "", "", errors.Newf(errors.ErrInvalidFormat, "artifact URI must start with %s://", scheme).WithContext("uri", uri)
+	}
+	bucket, key, ok = strings.Cut(rest, "/")
+	if !ok || bucket == "" || key == "" {
+		return "", "", errors.New(errors.ErrInvalidFormat, "artifact URI must include a bucket and key").WithContext("uri", uri)
+	}
+	return bucket, key, nil
+}
+
+// contextKey is the context key for the store
+type contextKey struct{}
+
+// NewContext returns a context carrying the store, so tools can save the
+// files they produce
+func NewContext(ctx context.Context, s Store) context.Context {
+	return context.WithValue(ctx, contextKey{}, s)
+}
+
+// FromContext returns the store carried by ctx, or nil if none
+func FromContext(ctx context.Context) Store {
+	s, _ := ctx.Value(contextKey{}).(Store)
+	return s
+}
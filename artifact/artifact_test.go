@@ -0,0 +1,69 @@
+package artifact
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestS3Store_PutGet(t *testing.T) {
+	objects := make(map[string][]byte)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := r.Header.Get("Authorization")
+		if !strings.HasPrefix(auth, "AWS4-HMAC-SHA256 Credential=AKID/") || !strings.Contains(auth, "x-amz-content-sha256;x-amz-date") {
+			t.Errorf("Authorization = %q, want a SigV4 signature", auth)
+		}
+		switch r.Method {
+		case http.MethodPut:
+			data, _ := io.ReadAll(r.Body)
+			objects[r.URL.Path] = data
+		case http.MethodGet:
+			data, ok := objects[r.URL.Path]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(data)
+		}
+	}))
+	defer server.Close()
+
+	store, err := NewS3Store(S3Config{
+		Bucket:          "results",
+		Prefix:          "runs/",
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		Endpoint:        server.URL,
+	})
+	if err != nil {
+		t.Fatalf("NewS3Store() unexpected error: %v", err)
+	}
+
+	ref, err := store.Put(context.Background(), "a b.txt", []byte("hello"), "")
+	if err != nil {
+		t.Fatalf("Put() unexpected error: %v", err)
+	}
+	if ref.URI != "s3://results/runs/a b.txt" || ref.Size != 5 || !strings.HasPrefix(ref.ContentType, "text/plain") {
+		t.Errorf("Put() = %+v, want s3 URI, size 5 and text/plain", ref)
+	}
+	if _, ok := objects["/results/runs/a b.txt"]; !ok {
+		t.Errorf("objects = %v, want path-style key", objects)
+	}
+
+	data, err := store.Get(context.Background(), ref.URI)
+	if err != nil || string(data) != "hello" {
+		t.Errorf("Get() = %q, %v; want %q", data, err, "hello")
+	}
+	if _, err := store.Get(context.Background(), "s3://results/missing"); err == nil {
+		t.Error("Get() of missing object: expected error")
+	}
+}
+
+func TestEscapePath(t *testing.T) {
+	if got := escapePath("runs/a b+c~.txt"); got != "runs/a%20b%2Bc~.txt" {
+		t.Errorf("escapePath() = %q", got)
+	}
+}
@@ -0,0 +1,79 @@
+package artifact
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// FileStore stores artifacts in a local directory, for development and tests
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore creates a store rooted at dir, creating it if needed
+func NewFileStore(dir string) (*FileStore, error) {
+	if dir == "" {
+		return nil, errors.RequiredField("directory")
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid artifact directory", err).WithContext("dir", dir)
+	}
+	if err := os.MkdirAll(abs, 0o755); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to create artifact directory", err).WithContext("dir", abs)
+	}
+	return &FileStore{dir: abs}, nil
+}
+
+// Put writes data to a file named by key
+func (s *FileStore) Put(ctx context.Context, key string, data []byte, contentType string) (*Ref, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to create artifact directory", err).WithContext("key", key)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to write artifact", err).WithContext("key", key)
+	}
+	return &Ref{
+		URI:         (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String(),
+		Size:        int64(len(data)),
+		ContentType: DetectContentType(data, contentType),
+	}, nil
+}
+
+// Get reads the file a "file://" URI points to
+func (s *FileStore) Get(ctx context.Context, uri string) ([]byte, error) {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return nil, errors.New(errors.ErrInvalidFormat, "artifact URI must start with file://").WithContext("uri", uri)
+	}
+	path := filepath.FromSlash(u.Path)
+	if !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+		return nil, errors.NotFound("artifact", uri)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("artifact", uri)
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read artifact", err).WithContext("uri", uri)
+	}
+	return data, nil
+}
+
+// path maps a key to a file inside the store's directory
+func (s *FileStore) path(key string) (string, error) {
+	path := filepath.Join(s.dir, filepath.FromSlash(key))
+	if key == "" || !strings.HasPrefix(path, s.dir+string(filepath.Separator)) {
+		return "", errors.InvalidField("key", "must be a relative path inside the store").WithContext("key", key)
+	}
+	return path, nil
+}
@@ -0,0 +1,119 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// GCSConfig represents the configuration for a Google Cloud Storage store
+type GCSConfig struct {
+	// Bucket receives all artifacts
+	Bucket string
+	// Prefix is prepended to every key, e.g. "gittyai/"
+	Prefix string
+	// TokenSource returns an OAuth2 access token for each request, e.g. from
+	// the metadata server or golang.org/x/oauth2/google
+	TokenSource func(ctx context.Context) (string, error)
+	// BaseURL overrides the API endpoint (default: "https://storage.googleapis.com")
+	BaseURL string
+}
+
+// GCSStore stores artifacts in Google Cloud Storage through its JSON API
+type GCSStore struct {
+	config GCSConfig
+	client *http.Client
+}
+
+// NewGCSStore creates a Google Cloud Storage store
+func NewGCSStore(cfg GCSConfig) (*GCSStore, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.RequiredField("bucket")
+	}
+	if cfg.TokenSource == nil {
+		return nil, errors.RequiredField("token source")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://storage.googleapis.com"
+	}
+	return &GCSStore{
+		config: cfg,
+		client: &http.Client{},
+	}, nil
+}
+
+// Put uploads data as an object
+func (s *GCSStore) Put(ctx context.Context, key string, data []byte, contentType string) (*Ref, error) {
+	key = s.config.Prefix + key
+	contentType = DetectContentType(data, contentType)
+
+	endpoint := s.config.BaseURL + "/upload/storage/v1/b/" + url.PathEscape(s.config.Bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(key)
+	status, body, err := s.do(ctx, http.MethodPost, endpoint, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("key", key)
+	}
+	return &Ref{
+		URI:         "gs://" + s.config.Bucket + "/" + key,
+		Size:        int64(len(data)),
+		ContentType: contentType,
+	}, nil
+}
+
+// Get downloads the object a "gs://bucket/key" URI points to
+func (s *GCSStore) Get(ctx context.Context, uri string) ([]byte, error) {
+	bucket, key, err := splitURI(uri, "gs")
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := s.config.BaseURL + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(key) + "?alt=media"
+	status, body, err := s.do(ctx, http.MethodGet, endpoint, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, errors.NotFound("artifact", uri)
+	default:
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("key", key)
+	}
+}
+
+// do sends an authenticated request
+func (s *GCSStore) do(ctx context.Context, method, endpoint string, payload []byte, contentType string) (int, []byte, error) {
+	token, err := s.config.TokenSource(ctx)
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrUnauthorized, "failed to get access token", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, errors.APICallError("call Cloud Storage", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+	}
+	return resp.StatusCode, body, nil
+}
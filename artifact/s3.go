@@ -0,0 +1,211 @@
+package artifact
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// S3Config represents the configuration for an S3 store
+type S3Config struct {
+	// Bucket receives all artifacts
+	Bucket string
+	// Region of the bucket (default: "us-east-1")
+	Region string
+	// Prefix is prepended to every key, e.g. "gittyai/"
+	Prefix string
+	// AccessKeyID and SecretAccessKey sign requests
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is set for temporary credentials
+	SessionToken string
+	// Endpoint overrides the AWS endpoint for S3-compatible stores such as
+	// MinIO (e.g. "http://localhost:9000"); requests then use path-style URLs
+	Endpoint string
+}
+
+// S3Store stores artifacts in Amazon S3 or an S3-compatible store
+type S3Store struct {
+	config S3Config
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewS3Store creates an S3 store
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.RequiredField("bucket")
+	}
+	if cfg.AccessKeyID == "" || cfg.SecretAccessKey == "" {
+		return nil, errors.RequiredField("AWS credentials")
+	}
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	return &S3Store{
+		config: cfg,
+		client: &http.Client{},
+		now:    time.Now,
+	}, nil
+}
+
+// Put uploads data as an object
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) (*Ref, error) {
+	key = s.config.Prefix + key
+	contentType = DetectContentType(data, contentType)
+
+	status, body, err := s.do(ctx, http.MethodPut, key, data, contentType)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("key", key)
+	}
+	return &Ref{
+		URI:         "s3://" + s.config.Bucket + "/" + key,
+		Size:        int64(len(data)),
+		ContentType: contentType,
+	}, nil
+}
+
+// Get downloads the object an "s3://bucket/key" URI points to
+func (s *S3Store) Get(ctx context.Context, uri string) ([]byte, error) {
+	bucket, key, err := splitURI(uri, "s3")
+	if err != nil {
+		return nil, err
+	}
+	if bucket != s.config.Bucket {
+		return nil, errors.NotFound("artifact", uri).WithContext("bucket", s.config.Bucket)
+	}
+
+	status, body, err := s.do(ctx, http.MethodGet, key, nil, "")
+	if err != nil {
+		return nil, err
+	}
+	switch status {
+	case http.StatusOK:
+		return body, nil
+	case http.StatusNotFound:
+		return nil, errors.NotFound("artifact", uri)
+	default:
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("key", key)
+	}
+}
+
+// do sends a signed request for an object
+func (s *S3Store) do(ctx context.Context, method, key string, payload []byte, contentType string) (int, []byte, error) {
+	host, path := s.location(key)
+	scheme := "https"
+	if strings.HasPrefix(s.config.Endpoint, "http://") {
+		scheme = "http"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, scheme+"://"+host+path, bytes.NewReader(payload))
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	s.sign(req, host, path, payload)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return 0, nil, errors.APICallError("call S3", err).WithContext("key", key)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// location returns the host and escaped path of an object
+func (s *S3Store) location(key string) (host, path string) {
+	if s.config.Endpoint != "" {
+		host = strings.TrimPrefix(strings.TrimPrefix(s.config.Endpoint, "https://"), "http://")
+		return strings.TrimSuffix(host, "/"), "/" + s.config.Bucket + "/" + escapePath(key)
+	}
+	return fmt.Sprintf("%s.s3.%s.amazonaws.com", s.config.Bucket, s.config.Region), "/" + escapePath(key)
+}
+
+// sign adds AWS Signature Version 4 headers to req
+func (s *S3Store) sign(req *http.Request, host, path string, payload []byte) {
+	now := s.now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(payload)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if s.config.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", s.config.SessionToken)
+	}
+
+	names := []string{"host", "x-amz-content-sha256", "x-amz-date"}
+	values := map[string]string{"host": host, "x-amz-content-sha256": payloadHash, "x-amz-date": amzDate}
+	if ct := req.Header.Get("Content-Type"); ct != "" {
+		names = append([]string{"content-type"}, names...)
+		values["content-type"] = ct
+	}
+	if s.config.SessionToken != "" {
+		names = append(names, "x-amz-security-token")
+		values["x-amz-security-token"] = s.config.SessionToken
+	}
+
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + values[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{req.Method, path, "", canonicalHeaders.String(), signedHeaders, payloadHash}, "\n")
+	scope := date + "/" + s.config.Region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+s.config.SecretAccessKey), date)
+	key = hmacSHA256(key, s.config.Region)
+	key = hmacSHA256(key, "s3")
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		s.config.AccessKeyID, scope, signedHeaders, signature))
+}
+
+// escapePath URI-encodes each segment of a key as S3 expects, keeping slashes
+func escapePath(key string) string {
+	var b strings.Builder
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		if c == '/' || c == '-' || c == '_' || c == '.' || c == '~' ||
+			('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9') {
+			b.WriteByte(c)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
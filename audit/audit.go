@@ -0,0 +1,239 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/tenant"
+)
+
+// Kind identifies what an audit entry records
+type Kind string
+
+// Entry kinds
+const (
+	KindPrompt   Kind = "prompt"
+	KindResponse Kind = "response"
+	KindToolCall Kind = "tool_call"
+	KindApproval Kind = "approval"
+)
+
+// Entry is a single line of the audit log. Each entry carries the hash of
+// the previous one, so any modification or deletion breaks the chain.
+type Entry struct {
+	Seq      int64             `json:"seq"`
+	Time     time.Time         `json:"time"`
+	Kind     Kind              `json:"kind"`
+	RunID    string            `json:"run_id,omitempty"`
+	Tenant   string            `json:"tenant,omitempty"`
+	Model    string            `json:"model,omitempty"`
+	Content  string            `json:"content,omitempty"` // Redacted content that left the organization
+	Digest   string            `json:"digest,omitempty"`  // SHA-256 of content that is not stored, e.g. responses
+	Metadata map[string]string `json:"metadata,omitempty"`
+	PrevHash string            `json:"prev_hash"`
+	Hash     string            `json:"hash"`
+}
+
+// Redactor rewrites content before it is written to the log
+type Redactor func(string) string
+
+// Options configures an audit log
+type Options struct {
+	// Redactors are applied in order to every recorded content
+	Redactors []Redactor
+}
+
+// Log is an append-only, hash-chained JSONL audit log
+type Log struct {
+	mu        sync.Mutex
+	w         io.Writer
+	closer    io.Closer
+	redactors []Redactor
+	seq       int64
+	lastHash  string
+}
+
+// NewLog creates an audit log writing to w, starting a new chain
+func NewLog(w io.Writer, opts Options) *Log {
+	return &Log{
+		w:         w,
+		redactors: opts.Redactors,
+	}
+}
+
+// OpenFile opens or creates an audit log file for appending, continuing the
+// chain of any entries already present after verifying them
+func OpenFile(path string, opts Options) (*Log, error) {
+	var seq int64
+	var lastHash string
+
+	if f, err := os.Open(path); err == nil {
+		last, verr := verify(f)
+		f.Close()
+		if verr != nil {
+			return nil, verr
+		}
+		if last != nil {
+			seq, lastHash = last.Seq, last.Hash
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to open audit log", err).WithContext("path", path)
+	}
+
+	return &Log{
+		w:         f,
+		closer:    f,
+		redactors: opts.Redactors,
+		seq:       seq,
+		lastHash:  lastHash,
+	}, nil
+}
+
+// Close closes the underlying file, if the log owns one
+func (l *Log) Close() error {
+	if l.closer == nil {
+		return nil
+	}
+	return l.closer.Close()
+}
+
+// Record appends an entry. Seq, Time, run ID, tenant and hashes are filled
+// in by the log; Content is redacted before writing.
+func (l *Log) Record(ctx context.Context, entry Entry) error {
+	entry.Content = l.redact(entry.Content)
+	entry.RunID = runid.FromContext(ctx)
+	entry.Tenant = tenant.FromContext(ctx)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry.Seq = l.seq + 1
+	entry.Time = time.Now().UTC()
+	entry.PrevHash = l.lastHash
+	entry.Hash = ""
+
+	hash, err := hashEntry(entry)
+	if err != nil {
+		return err
+	}
+	entry.Hash = hash
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal audit entry", err)
+	}
+	if _, err := l.w.Write(append(data, '\n')); err != nil {
+		// Losing audit records is a compliance issue, so treat it as critical
+		return errors.Wrap(errors.ErrInternal, "failed to write audit entry", err).WithSeverity(errors.SeverityCritical)
+	}
+
+	l.seq = entry.Seq
+	l.lastHash = entry.Hash
+	return nil
+}
+
+// RecordToolCall records a tool invocation and its arguments
+func (l *Log) RecordToolCall(ctx context.Context, tool string, args map[string]interface{}) error {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal tool arguments", err).WithContext("tool", tool)
+	}
+	return l.Record(ctx, Entry{
+		Kind:     KindToolCall,
+		Content:  string(data),
+		Metadata: map[string]string{"tool": tool},
+	})
+}
+
+// RecordApproval records a human or policy approval decision
+func (l *Log) RecordApproval(ctx context.Context, subject, approver string, approved bool) error {
+	decision := "rejected"
+	if approved {
+		decision = "approved"
+	}
+	return l.Record(ctx, Entry{
+		Kind:    KindApproval,
+		Content: subject,
+		Metadata: map[string]string{
+			"approver": approver,
+			"decision": decision,
+		},
+	})
+}
+
+// redact applies every redactor to content
+func (l *Log) redact(content string) string {
+	for _, r := range l.redactors {
+		content = r(content)
+	}
+	return content
+}
+
+// Digest returns the hex SHA-256 digest of content
+func Digest(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// hashEntry computes the chain hash of an entry with an empty Hash field
+func hashEntry(entry Entry) (string, error) {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "failed to marshal audit entry", err)
+	}
+	return Digest(string(data)), nil
+}
+
+// Verify checks the hash chain of an audit log
+func Verify(r io.Reader) error {
+	_, err := verify(r)
+	return err
+}
+
+// verify checks the chain and returns the last entry, or nil for an empty log
+func verify(r io.Reader) (*Entry, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+
+	var last *Entry
+	prevHash := ""
+	for line := 1; scanner.Scan(); line++ {
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidFormat, "malformed audit entry", err).WithContext("line", line)
+		}
+		if entry.PrevHash != prevHash {
+			return nil, errors.Validationf("audit chain broken at line %d: previous hash mismatch", line).WithContext("seq", entry.Seq)
+		}
+
+		want := entry.Hash
+		entry.Hash = ""
+		got, err := hashEntry(entry)
+		if err != nil {
+			return nil, err
+		}
+		if got != want {
+			return nil, errors.Validationf("audit chain broken at line %d: entry hash mismatch", line).WithContext("seq", entry.Seq)
+		}
+
+		entry.Hash = want
+		prevHash = want
+		last = &entry
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read audit log", err)
+	}
+	return last, nil
+}
@@ -0,0 +1,89 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/llm"
+)
+
+type echoLLM struct{}
+
+func (echoLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return "echo: " + prompt, nil
+}
+
+// usageLLM reports token usage with its completions
+type usageLLM struct{ echoLLM }
+
+func (usageLLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	return &llm.Response{Content: "echo: " + prompt, Model: "test-model", Usage: llm.TokenUsage{PromptTokens: 3, CompletionTokens: 2}}, nil
+}
+
+func TestWrapLLM_KeepsResponseMetadata(t *testing.T) {
+	var buf bytes.Buffer
+	provider := WrapLLM(usageLLM{}, NewLog(&buf, Options{}), "test-model")
+	resp, err := llm.GenerateResponse(context.Background(), provider, "hello")
+	if err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if resp.Content != "echo: hello" || resp.Model != "test-model" || resp.Usage.Total() != 5 {
+		t.Errorf("GenerateResponse() = %+v, want the provider's content, model and usage", resp)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 2 {
+		t.Errorf("audit log has %d entries, want 2", got)
+	}
+}
+
+func TestLog_ChainAndRedaction(t *testing.T) {
+	var buf bytes.Buffer
+	log := NewLog(&buf, Options{
+		Redactors: []Redactor{func(s string) string { return strings.ReplaceAll(s, "hunter2", "[REDACTED]") }},
+	})
+
+	provider := WrapLLM(echoLLM{}, log, "test-model")
+	if _, err := provider.Generate(context.Background(), "password is hunter2"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if err := log.RecordApproval(context.Background(), "publish report", "alice", true); err != nil {
+		t.Fatalf("RecordApproval() unexpected error: %v", err)
+	}
+
+	out := buf.String()
+	if strings.Contains(out, "hunter2") {
+		t.Error("audit log contains unredacted secret")
+	}
+	if got := strings.Count(out, "\n"); got != 3 {
+		t.Errorf("audit log has %d entries, want 3", got)
+	}
+	if err := Verify(strings.NewReader(out)); err != nil {
+		t.Errorf("Verify() unexpected error: %v", err)
+	}
+
+	tampered := strings.Replace(out, "publish report", "delete report", 1)
+	if err := Verify(strings.NewReader(tampered)); err == nil {
+		t.Error("Verify() expected error for tampered log")
+	}
+}
+
+func TestOpenFile_ContinuesChain(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.jsonl")
+
+	for i := 0; i < 2; i++ {
+		log, err := OpenFile(path, Options{})
+		if err != nil {
+			t.Fatalf("OpenFile() unexpected error: %v", err)
+		}
+		if err := log.RecordToolCall(context.Background(), "search", map[string]interface{}{"q": "go"}); err != nil {
+			t.Fatalf("RecordToolCall() unexpected error: %v", err)
+		}
+		log.Close()
+	}
+
+	if _, err := OpenFile(path, Options{}); err != nil {
+		t.Errorf("OpenFile() on a valid chain returned error: %v", err)
+	}
+}
@@ -0,0 +1,49 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/counhopig/gittyai/llm"
+)
+
+// LLM wraps a provider and records every prompt sent and a digest of every
+// response received
+type LLM struct {
+	inner llm.LLM
+	log   *Log
+	model string
+}
+
+// WrapLLM returns an LLM that audits all traffic through inner. The model
+// name is recorded with each entry.
+func WrapLLM(inner llm.LLM, log *Log, model string) *LLM {
+	return &LLM{inner: inner, log: log, model: model}
+}
+
+// Generate records the prompt, calls the wrapped provider and records the
+// response digest. Nothing is sent if the prompt cannot be recorded.
+func (a *LLM) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := a.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// GenerateResponse audits like Generate and returns the completion with its
+// token usage and metadata
+func (a *LLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	if err := a.log.Record(ctx, Entry{Kind: KindPrompt, Model: a.model, Content: prompt}); err != nil {
+		return nil, err
+	}
+
+	resp, err := llm.GenerateResponse(ctx, a.inner, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := a.log.Record(ctx, Entry{Kind: KindResponse, Model: a.model, Digest: Digest(resp.Content)}); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
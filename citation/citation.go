@@ -0,0 +1,214 @@
+// Package citation tracks the sources, such as documents returned by search
+// or retrieval tools, that fed each agent prompt. The orchestrator attaches
+// a tracker to every task and reports what it collected in
+// TaskResult.Sources, so reports can cite them and claims can be traced to
+// their origins.
+package citation
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Source identifies a document that contributed to a result
+type Source struct {
+	ID    string `json:"id,omitempty"`
+	URL   string `json:"url,omitempty"`
+	Title string `json:"title,omitempty"`
+	Tool  string `json:"tool,omitempty"` // Tool that returned the document
+}
+
+// key identifies a source for deduplication: its URL, or its ID
+func (s Source) key() string {
+	if s.URL != "" {
+		return s.URL
+	}
+	return s.ID
+}
+
+// String formats a source for reports
+func (s Source) String() string {
+	label := s.Title
+	if label == "" {
+		label = s.ID
+	}
+	switch {
+	case s.URL == "":
+		return label
+	case label == "":
+		return s.URL
+	default:
+		return label + " (" + s.URL + ")"
+	}
+}
+
+// Tracker collects the sources of one task. It is safe for concurrent use.
+type Tracker struct {
+	mu      sync.Mutex
+	sources []Source
+	seen    map[string]bool
+}
+
+// NewTracker creates an empty tracker
+func NewTracker() *Tracker {
+	return &Tracker{seen: make(map[string]bool)}
+}
+
+// Add records sources, ignoring those without a URL or ID and those
+// already recorded
+func (t *Tracker) Add(sources ...Source) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, s := range sources {
+		key := s.key()
+		if key == "" || t.seen[key] {
+			continue
+		}
+		t.seen[key] = true
+		t.sources = append(t.sources, s)
+	}
+}
+
+// Sources returns the recorded sources in the order they were first seen
+func (t *Tracker) Sources() []Source {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Source(nil), t.sources...)
+}
+
+// Merge returns the sources of all lists without duplicates, in order
+func Merge(lists ...[]Source) []Source {
+	t := NewTracker()
+	for _, l := range lists {
+		t.Add(l...)
+	}
+	return t.Sources()
+}
+
+// contextKey is the context key for the tracker
+type contextKey struct{}
+
+// NewContext returns a context carrying the tracker
+func NewContext(ctx context.Context, t *Tracker) context.Context {
+	return context.WithValue(ctx, contextKey{}, t)
+}
+
+// FromContext returns the tracker carried by ctx, or nil if none
+func FromContext(ctx context.Context) *Tracker {
+	t, _ := ctx.Value(contextKey{}).(*Tracker)
+	return t
+}
+
+// Record adds sources to the tracker carried by ctx. Tools call it to cite
+// the documents they return; it does nothing outside a tracked task.
+func Record(ctx context.Context, sources ...Source) {
+	if t := FromContext(ctx); t != nil {
+		t.Add(sources...)
+	}
+}
+
+// Extractor finds the sources in a tool's output
+type Extractor func(output string) []Source
+
+// Tool records the sources found in the output of another tool
+type Tool struct {
+	tools.Tool
+	extract Extractor
+}
+
+// WrapTool returns a tool recording the sources extract finds in the output
+// of inner; a nil extract uses ExtractJSON
+func WrapTool(inner tools.Tool, extract Extractor) *Tool {
+	if extract == nil {
+		extract = ExtractJSON
+	}
+	return &Tool{Tool: inner, extract: extract}
+}
+
+// SideEffects reports whether the wrapped tool has side effects, so that
+// wrapping keeps it behind confirmation
+func (t *Tool) SideEffects() bool { return tools.HasSideEffects(t.Tool) }
+
+// Execute runs the wrapped tool and records the sources of its output
+func (t *Tool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	output, err := t.Tool.Execute(ctx, args)
+	if err != nil {
+		return output, err
+	}
+	sources := t.extract(output)
+	for i := range sources {
+		if sources[i].Tool == "" {
+			sources[i].Tool = t.Name()
+		}
+	}
+	Record(ctx, sources...)
+	return output, nil
+}
+
+// Field names recognized by ExtractJSON
+var (
+	urlFields   = []string{"url", "link", "href", "source_url", "source"}
+	idFields    = []string{"id", "doc_id", "document_id", "source_id"}
+	titleFields = []string{"title", "name"}
+	listFields  = []string{"results", "documents", "items", "sources", "data"}
+)
+
+// ExtractJSON finds sources in JSON tool output: an object or an array of
+// objects, possibly nested under a "results", "documents", "items",
+// "sources" or "data" field, with URL, ID and title fields. Output that is
+// not JSON has no sources.
+func ExtractJSON(output string) []Source {
+	var doc interface{}
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &doc); err != nil {
+		return nil
+	}
+	var sources []Source
+	collect(doc, &sources)
+	return sources
+}
+
+// collect appends the sources found in a decoded JSON value
+func collect(v interface{}, sources *[]Source) {
+	switch v := v.(type) {
+	case []interface{}:
+		for _, item := range v {
+			collect(item, sources)
+		}
+	case map[string]interface{}:
+		// An object holding a list of documents is an envelope, not a source
+		envelope := false
+		for _, name := range listFields {
+			if list, ok := v[name].([]interface{}); ok {
+				envelope = true
+				collect(list, sources)
+			}
+		}
+		if envelope {
+			return
+		}
+		s := Source{URL: field(v, urlFields), ID: field(v, idFields), Title: field(v, titleFields)}
+		if s.URL != "" || s.ID != "" {
+			*sources = append(*sources, s)
+		}
+	}
+}
+
+// field returns the first string or number value among names
+func field(obj map[string]interface{}, names []string) string {
+	for _, name := range names {
+		switch value := obj[name].(type) {
+		case string:
+			if value != "" {
+				return value
+			}
+		case float64:
+			return strconv.FormatFloat(value, 'f', -1, 64)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,86 @@
+package citation
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/counhopig/gittyai/tools"
+)
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name   string
+		output string
+		want   []Source
+	}{
+		{
+			name:   "array of documents",
+			output: `[{"title": "A", "url": "https://a.example"}, {"id": 42, "name": "B"}]`,
+			want:   []Source{{Title: "A", URL: "https://a.example"}, {ID: "42", Title: "B"}},
+		},
+		{
+			name:   "envelope",
+			output: `{"id": "query-1", "documents": [{"doc_id": "d1", "link": "https://d.example"}]}`,
+			want:   []Source{{ID: "d1", URL: "https://d.example"}},
+		},
+		{
+			name:   "single document",
+			output: ` {"source": "https://s.example"} `,
+			want:   []Source{{URL: "https://s.example"}},
+		},
+		{
+			name:   "plain text",
+			output: "see https://x.example",
+			want:   nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ExtractJSON(tt.output); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ExtractJSON() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTracker_Deduplicates(t *testing.T) {
+	tracker := NewTracker()
+	ctx := NewContext(context.Background(), tracker)
+	Record(ctx, Source{URL: "https://a.example"}, Source{Title: "no id"}, Source{ID: "b"})
+	Record(ctx, Source{URL: "https://a.example", Title: "again"})
+	Record(context.Background(), Source{ID: "untracked"})
+
+	want := []Source{{URL: "https://a.example"}, {ID: "b"}}
+	if got := tracker.Sources(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Sources() = %+v, want %+v", got, want)
+	}
+	if got := Merge(want, []Source{{ID: "b"}, {ID: "c"}}); len(got) != 3 {
+		t.Errorf("Merge() = %+v, want 3 sources", got)
+	}
+}
+
+// deleteTool records whether it ran
+type deleteTool struct {
+	*tools.BaseTool
+	ran *bool
+}
+
+func (d deleteTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	*d.ran = true
+	return "deleted", nil
+}
+
+func TestWrapTool_KeepsSideEffects(t *testing.T) {
+	var ran bool
+	registry := tools.NewRegistry()
+	_ = registry.Register(WrapTool(tools.MarkSideEffecting(deleteTool{tools.NewBaseTool("delete", "Deletes a file", nil), &ran}), nil))
+
+	ctx := tools.NewConfirmContext(context.Background(), tools.DryRunAll)
+	if _, err := registry.Execute(ctx, "delete", nil); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if ran {
+		t.Error("wrapped side-effecting tool ran under DryRunAll")
+	}
+}
@@ -1,11 +1,17 @@
 package config
 
 import (
+	"context"
+	"sync"
+	"time"
+
 	"github.com/counhopig/gittyai/agent"
 	"github.com/counhopig/gittyai/errors"
 	"github.com/counhopig/gittyai/llm"
 	"github.com/counhopig/gittyai/memory"
 	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/prompts"
+	"github.com/counhopig/gittyai/quota"
 	"github.com/counhopig/gittyai/task"
 )
 
@@ -14,40 +20,195 @@ type Builder struct {
 	project *Project
 	agents  []*agent.Agent
 	tasks   []*task.Task
+	prompts *prompts.Library
+	loaded  bool               // Whether project prompts were registered into the library
+	llms    map[string]llm.LLM // LLM providers by profile name ("" = project default)
+	verify  bool               // Whether providers are health-checked when built
+	quotas  *quota.Manager     // Enforces project quotas; nil when none are configured
+	sim     bool               // Whether providers are replaced by a synthetic LLM
 }
 
+// healthCheckTimeout bounds each provider health check during build
+const healthCheckTimeout = 10 * time.Second
+
 // NewBuilder creates a new configuration builder
 func NewBuilder(project *Project) *Builder {
 	return &Builder{
 		project: project,
 		agents:  make([]*agent.Agent, 0),
 		tasks:   make([]*task.Task, 0),
+		llms:    make(map[string]llm.LLM),
+	}
+}
+
+// WithPrompts sets the prompt library used to resolve prompt references.
+// Prompts declared in the project are registered into it at build time.
+func (b *Builder) WithPrompts(lib *prompts.Library) *Builder {
+	b.prompts = lib
+	b.loaded = false
+	return b
+}
+
+// WithHealthCheck makes the builder ping each LLM provider when it is built,
+// failing fast if the endpoint is unreachable or the model is missing
+func (b *Builder) WithHealthCheck(enabled bool) *Builder {
+	b.verify = enabled
+	return b
+}
+
+// WithSimulation replaces every LLM provider with a synthetic LLM that
+// answers instantly, as if the project used the "simulated" provider, so
+// task graphs and formatting can be checked without spending tokens
+func (b *Builder) WithSimulation(enabled bool) *Builder {
+	b.sim = enabled
+	return b
+}
+
+// WithQuotas enforces the project's quotas with m, so several builders in
+// one process can share quota state. By default the builder creates a
+// manager from the project's quota configuration.
+func (b *Builder) WithQuotas(m *quota.Manager) *Builder {
+	b.quotas = m
+	return b
+}
+
+// quotaManager returns the manager enforcing project quotas, creating it on
+// first use, or nil when the project sets no quotas
+func (b *Builder) quotaManager() (*quota.Manager, error) {
+	if b.quotas != nil || b.project.Quotas == nil {
+		return b.quotas, nil
+	}
+
+	cfg := quota.Config{
+		Agents: quotaLimits(b.project.Quotas.Agents),
+		Keys:   quotaLimits(b.project.Quotas.Keys),
+	}
+	if b.project.Quotas.Store != "" {
+		store, err := quota.NewFileStore(b.project.Quotas.Store)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Store = store
+	}
+	b.quotas = quota.NewManager(cfg)
+	return b.quotas, nil
+}
+
+// quotaLimits converts configured limits by name
+func quotaLimits(cfgs map[string]QuotaLimitConfig) map[string]quota.Limit {
+	limits := make(map[string]quota.Limit, len(cfgs))
+	for name, c := range cfgs {
+		limits[name] = quota.Limit{MaxTokensPerDay: c.MaxTokensPerDay, MaxCallsPerRun: c.MaxCallsPerRun}
+	}
+	return limits
+}
+
+// withQuotas wraps provider to enforce the quotas of an agent and of the
+// project's API key. Pass an empty agent name for the manager. Simulated
+// providers spend no tokens, so simulations neither count against quotas
+// nor are stopped by them.
+func (b *Builder) withQuotas(provider llm.LLM, agentName string) (llm.LLM, error) {
+	if _, simulated := provider.(*llm.Simulator); simulated || b.sim {
+		return provider, nil
+	}
+	m, err := b.quotaManager()
+	if err != nil || m == nil {
+		return provider, err
+	}
+	key := b.project.LLM.QuotaKey
+	if key == "" {
+		key = b.project.LLM.Provider
+	}
+	return m.Wrap(provider, agentName, key), nil
+}
+
+// promptLibrary returns the prompt library with project prompts registered
+func (b *Builder) promptLibrary() (*prompts.Library, error) {
+	if b.prompts == nil {
+		b.prompts = prompts.NewLibrary()
+	}
+	if !b.loaded {
+		for _, p := range b.project.Prompts {
+			if err := b.prompts.Register(p.Name, p.Version, p.Template); err != nil {
+				return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to register prompt", err).
+					WithContext("prompt", p.Name+"@"+p.Version)
+			}
+		}
+		b.loaded = true
+	}
+	return b.prompts, nil
+}
+
+// resolvePrompt looks up a prompt reference in the library
+func (b *Builder) resolvePrompt(ref string) (*prompts.Template, error) {
+	lib, err := b.promptLibrary()
+	if err != nil {
+		return nil, err
+	}
+	tmpl, err := lib.Get(ref)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to resolve prompt", err).WithContext("prompt", ref)
 	}
+	return tmpl, nil
+}
+
+// resolveModel applies the alias table and model policy to the configured
+// model. Its deprecation warning is reported by Lint.
+func resolveModel(cfg LLMConfig) (llm.ModelResolution, error) {
+	aliases := llm.DefaultModelAliases()
+	for model, replacement := range cfg.ModelAliases {
+		aliases[model] = llm.ModelAliasEntry{Replacement: replacement, Status: llm.ModelAlias}
+	}
+
+	return llm.ResolveModel(cfg.Model, llm.AliasPolicy(cfg.ModelPolicy), aliases)
 }
 
 // BuildLLM creates an LLM provider from configuration
 func BuildLLM(cfg LLMConfig) (llm.LLM, error) {
+	res, err := resolveModel(cfg)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Model = res.Model
+
 	switch cfg.Provider {
 	case ProviderOpenAI:
 		return llm.NewOpenAI(llm.Config{
-			APIKey:      cfg.APIKey,
-			Model:       cfg.Model,
-			Temperature: cfg.Temperature,
-			MaxTokens:   cfg.MaxTokens,
+			APIKey:           cfg.APIKey,
+			Model:            cfg.Model,
+			Temperature:      cfg.Temperature,
+			TemperatureSet:   cfg.TemperatureSet,
+			MaxTokens:        cfg.MaxTokens,
+			TopP:             cfg.TopP,
+			ExtraBody:        cfg.ExtraBody,
+			MaxContinuations: cfg.MaxContinuations,
+			MaxPromptTokens:  cfg.MaxPromptTokens,
+			Timeout:          cfg.Timeout,
+			MaxResponseBytes: cfg.MaxResponseBytes,
+			Client:           clientIdentity(cfg.Client),
 		})
 	case ProviderAnthropic:
 		if cfg.Model == "" {
 			cfg.Model = "claude-3-haiku-20240307" // Set a reasonable default
 		}
 		return llm.NewAnthropic(llm.Config{
-			APIKey:      cfg.APIKey,
-			Model:       cfg.Model,
-			Temperature: cfg.Temperature,
-			MaxTokens:   cfg.MaxTokens,
+			APIKey:           cfg.APIKey,
+			Model:            cfg.Model,
+			Temperature:      cfg.Temperature,
+			TemperatureSet:   cfg.TemperatureSet,
+			MaxTokens:        cfg.MaxTokens,
+			TopP:             cfg.TopP,
+			MaxContinuations: cfg.MaxContinuations,
+			MaxPromptTokens:  cfg.MaxPromptTokens,
+			Timeout:          cfg.Timeout,
+			MaxResponseBytes: cfg.MaxResponseBytes,
+			Client:           clientIdentity(cfg.Client),
 		})
 	case ProviderAzureOpenAI:
 		return buildAzureOpenAI(cfg)
-	case ProviderOllama, ProviderLMStudio, ProviderGroq, ProviderTogether, ProviderDeepseek, ProviderOpenrouter, ProviderOpenAILike:
+	case ProviderSimulated:
+		return llm.NewSimulator(llm.SimulatorConfig{Echo: cfg.Echo}), nil
+	case ProviderOllama, ProviderLMStudio, ProviderGroq, ProviderTogether, ProviderDeepseek, ProviderOpenrouter, ProviderVLLM, ProviderTGI, ProviderOpenAILike:
 		// Handle OpenAI-like providers
 		baseURL := cfg.BaseURL
 		model := cfg.Model
@@ -85,23 +246,73 @@ func BuildLLM(cfg LLMConfig) (llm.LLM, error) {
 				if model == "" {
 					model = "openai/gpt-4o-mini"
 				}
+			case ProviderVLLM:
+				baseURL = "http://localhost:8000/v1"
+			case ProviderTGI:
+				baseURL = "http://localhost:8080/v1"
+				if model == "" {
+					model = "tgi"
+				}
 			}
 		}
 
-		return llm.NewOpenAILike(llm.OpenAILikeConfig{
-			BaseURL:      baseURL,
-			APIKey:       cfg.APIKey,
-			Model:        model,
-			Temperature:  cfg.Temperature,
-			MaxTokens:    cfg.MaxTokens,
-			Headers:      cfg.Headers,
-			SystemPrompt: cfg.SystemPrompt,
-		})
+		likeCfg := llm.OpenAILikeConfig{
+			BaseURL:          baseURL,
+			APIKey:           cfg.APIKey,
+			Model:            model,
+			Temperature:      cfg.Temperature,
+			TemperatureSet:   cfg.TemperatureSet,
+			MaxTokens:        cfg.MaxTokens,
+			TopP:             cfg.TopP,
+			Headers:          cfg.Headers,
+			SystemPrompt:     cfg.SystemPrompt,
+			ExtraBody:        cfg.ExtraBody,
+			MaxContinuations: cfg.MaxContinuations,
+			MaxPromptTokens:  cfg.MaxPromptTokens,
+			Timeout:          cfg.Timeout,
+			MaxResponseBytes: cfg.MaxResponseBytes,
+			Client:           clientIdentity(cfg.Client),
+		}
+		if cfg.Provider == ProviderOpenrouter && cfg.OpenRouter != nil {
+			applyOpenRouter(&likeCfg, cfg.OpenRouter)
+		}
+		return llm.NewOpenAILike(likeCfg)
 	default:
 		return nil, errors.UnsupportedType(cfg.Provider).WithContext("provider", cfg.Provider)
 	}
 }
 
+// applyOpenRouter adds OpenRouter routing preferences and attribution headers
+func applyOpenRouter(likeCfg *llm.OpenAILikeConfig, or *OpenRouterConfig) {
+	headers := make(map[string]string, len(likeCfg.Headers)+2)
+	for key, value := range likeCfg.Headers {
+		headers[key] = value
+	}
+	if or.Referer != "" {
+		headers["HTTP-Referer"] = or.Referer
+	}
+	if or.Title != "" {
+		headers["X-Title"] = or.Title
+	}
+	likeCfg.Headers = headers
+	likeCfg.FallbackModels = or.FallbackModels
+
+	routing := &llm.OpenRouterRouting{
+		Order:          or.ProviderOrder,
+		AllowFallbacks: or.AllowFallbacks,
+		Ignore:         or.IgnoreProviders,
+	}
+	if or.MaxPromptPrice > 0 || or.MaxCompletionPrice > 0 {
+		routing.MaxPrice = &llm.OpenRouterMaxPrice{
+			Prompt:     or.MaxPromptPrice,
+			Completion: or.MaxCompletionPrice,
+		}
+	}
+	if len(routing.Order) > 0 || routing.AllowFallbacks != nil || len(routing.Ignore) > 0 || routing.MaxPrice != nil {
+		likeCfg.Routing = routing
+	}
+}
+
 // buildAzureOpenAI creates an Azure OpenAI LLM provider from configuration
 func buildAzureOpenAI(cfg LLMConfig) (llm.LLM, error) {
 	if cfg.Endpoint == "" {
@@ -117,35 +328,161 @@ func buildAzureOpenAI(cfg LLMConfig) (llm.LLM, error) {
 	}
 
 	return llm.NewAzureOpenAI(llm.AzureOpenAIConfig{
-		Endpoint:       cfg.Endpoint,
-		APIKey:         cfg.APIKey,
-		DeploymentName: cfg.DeploymentName,
-		APIVersion:     apiVersion,
-		Temperature:    cfg.Temperature,
-		MaxTokens:      cfg.MaxTokens,
+		Endpoint:         cfg.Endpoint,
+		APIKey:           cfg.APIKey,
+		DeploymentName:   cfg.DeploymentName,
+		APIVersion:       apiVersion,
+		Temperature:      cfg.Temperature,
+		TemperatureSet:   cfg.TemperatureSet,
+		MaxTokens:        cfg.MaxTokens,
+		TopP:             cfg.TopP,
+		ExtraBody:        cfg.ExtraBody,
+		MaxPromptTokens:  cfg.MaxPromptTokens,
+		Timeout:          cfg.Timeout,
+		MaxResponseBytes: cfg.MaxResponseBytes,
+		Client:           clientIdentity(cfg.Client),
 	})
 }
 
-// BuildAgents creates agents from configuration
-func (b *Builder) BuildAgents() error {
-	llmProvider, err := BuildLLM(b.project.LLM)
+// clientIdentity converts the client section of an LLM config, returning nil
+// without one so the provider uses the default identity
+func clientIdentity(c *ClientConfig) *llm.ClientIdentity {
+	if c == nil {
+		return nil
+	}
+	return &llm.ClientIdentity{AppName: c.AppName, AppVersion: c.AppVersion}
+}
+
+// profileLLM returns the LLM provider for a profile, building it on first use.
+// Agents and tasks sharing a profile share a provider.
+func (b *Builder) profileLLM(name string) (llm.LLM, error) {
+	if provider, ok := b.llms[name]; ok {
+		return provider, nil
+	}
+
+	cfg := b.project.LLM
+	if name != "" {
+		profile, err := b.project.Profile(name)
+		if err != nil {
+			return nil, err
+		}
+		cfg = cfg.WithProfile(profile)
+	}
+	if b.sim || cfg.Provider == ProviderSimulated {
+		provider := b.simulatedLLM(cfg)
+		b.llms[name] = provider
+		return provider, nil
+	}
+
+	provider, err := BuildLLM(cfg)
 	if err != nil {
-		return errors.Wrap(errors.ErrInvalidConfig, "failed to build LLM", err).WithContext("provider", b.project.LLM.Provider)
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to build LLM", err).
+			WithContext("provider", cfg.Provider).
+			WithContext("profile", name)
+	}
+	if b.verify {
+		ctx, cancel := context.WithTimeout(context.Background(), healthCheckTimeout)
+		defer cancel()
+		if err := llm.Ping(ctx, provider); err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidConfig, "LLM health check failed", err).
+				WithContext("provider", cfg.Provider).
+				WithContext("model", cfg.Model)
+		}
 	}
 
-	mem := memory.New()
+	b.llms[name] = provider
+	return provider, nil
+}
+
+// simulatedLLM returns a synthetic LLM that also answers the manager prompts
+// of the project's agents
+func (b *Builder) simulatedLLM(cfg LLMConfig) llm.LLM {
+	names := make([]string, 0, len(b.project.Agents))
+	for _, a := range b.project.Agents {
+		names = append(names, a.Name)
+	}
+	return llm.NewSimulator(llm.SimulatorConfig{
+		Responses: orchestrator.SimulatedManagerResponses(names...),
+		Echo:      cfg.Echo,
+	})
+}
+
+// memoryScope returns the memory scope of an agent
+func (b *Builder) memoryScope(agentCfg AgentConfig) string {
+	scope := b.project.Memory.Scope
+	if agentCfg.Memory != nil && agentCfg.Memory.Scope != "" {
+		scope = agentCfg.Memory.Scope
+	}
+	if scope == "" {
+		return MemoryScopeAgent
+	}
+	return scope
+}
+
+// memoryHalfLife returns the memory half-life of an agent, 0 without decay
+func (b *Builder) memoryHalfLife(agentCfg AgentConfig) time.Duration {
+	if agentCfg.Memory != nil && agentCfg.Memory.HalfLife > 0 {
+		return agentCfg.Memory.HalfLife
+	}
+	return b.project.Memory.HalfLife
+}
+
+// newMemory creates a memory, decaying with the given half-life if set
+func newMemory(halfLife time.Duration) memory.Memory {
+	if halfLife > 0 {
+		return memory.NewDecaying(memory.DecayConfig{HalfLife: halfLife})
+	}
+	return memory.New()
+}
+
+// BuildAgents creates agents from configuration. Each agent gets its own
+// memory, so one agent's records do not pollute another's recall, unless
+// its memory scope is "shared". The shared memory decays with the project's
+// half-life.
+func (b *Builder) BuildAgents() error {
+	shared := newMemory(b.project.Memory.HalfLife)
 
 	for _, agentCfg := range b.project.Agents {
+		agentCfg, err := agentCfg.withPreset()
+		if err != nil {
+			return err
+		}
+		llmProvider, err := b.profileLLM(agentCfg.Profile)
+		if err != nil {
+			return err
+		}
+		if llmProvider, err = b.withQuotas(llmProvider, agentCfg.Name); err != nil {
+			return err
+		}
+
+		var prompt *prompts.Template
+		if agentCfg.Prompt != "" {
+			if prompt, err = b.resolvePrompt(agentCfg.Prompt); err != nil {
+				return err
+			}
+		}
+
+		mem := shared
+		if b.memoryScope(agentCfg) == MemoryScopeAgent {
+			mem = newMemory(b.memoryHalfLife(agentCfg))
+		}
+
 		ag := agent.New(agent.Config{
 			Name:      agentCfg.Name,
 			Role:      agentCfg.Role,
 			Goal:      agentCfg.Goal,
 			Backstory: agentCfg.Backstory,
+			Skills:    agentCfg.Skills,
+			Tools:     agentCfg.Tools,
+			DenyTools: agentCfg.DenyTools,
 			Verbose:   agentCfg.Verbose,
 			MaxIter:   agentCfg.MaxIter,
 			MaxRPM:    agentCfg.MaxRPM,
-			LLM:       llmProvider, // Each agent uses the global LLM
+			LLM:       llmProvider, // Agents share the provider of their profile
+			Model:     agentCfg.Profile,
 			Memory:    mem,
+			Prompt:    prompt,
+			Language:  agentCfg.Language,
 		})
 		b.agents = append(b.agents, ag)
 	}
@@ -153,6 +490,75 @@ func (b *Builder) BuildAgents() error {
 	return nil
 }
 
+// Runtime resolves agent definitions against the project, e.g. on
+// distributed workers, the way BuildAgents builds agents: a model reference
+// is a profile name, empty for the llm section, and providers enforce the
+// quotas of their agent. An agent gets one memory, its own or the shared one
+// depending on its memory scope, however many times it is resolved.
+// Providers and memories are reused across resolutions; the runtime is safe
+// for concurrent use.
+func (b *Builder) Runtime() (agent.Runtime, error) {
+	lib, err := b.promptLibrary()
+	if err != nil {
+		return agent.Runtime{}, err
+	}
+	var mu sync.Mutex
+	providers := make(map[string]llm.LLM) // By profile and agent name
+	memories := make(map[string]memory.Memory)
+	var shared memory.Memory
+	return agent.Runtime{
+		LLM: func(model, name string) (llm.LLM, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			key := model + "\x00" + name
+			if provider, ok := providers[key]; ok {
+				return provider, nil
+			}
+			provider, err := b.profileLLM(model)
+			if err != nil {
+				return nil, err
+			}
+			if provider, err = b.withQuotas(provider, name); err != nil {
+				return nil, err
+			}
+			providers[key] = provider
+			return provider, nil
+		},
+		Memory: func(name string) memory.Memory {
+			mu.Lock()
+			defer mu.Unlock()
+			if mem, ok := memories[name]; ok {
+				return mem
+			}
+			agentCfg := b.agentConfig(name)
+			mem := shared
+			if b.memoryScope(agentCfg) == MemoryScopeAgent {
+				mem = newMemory(b.memoryHalfLife(agentCfg))
+			} else if mem == nil {
+				mem = newMemory(b.project.Memory.HalfLife)
+				shared = mem
+			}
+			memories[name] = mem
+			return mem
+		},
+		Prompts: lib,
+	}, nil
+}
+
+// agentConfig returns the configuration of the named project agent, with
+// its preset applied; agents outside the project get the project defaults
+func (b *Builder) agentConfig(name string) AgentConfig {
+	for _, agentCfg := range b.project.Agents {
+		if agentCfg.Name == name {
+			if withPreset, err := agentCfg.withPreset(); err == nil {
+				return withPreset
+			}
+			return agentCfg
+		}
+	}
+	return AgentConfig{Name: name}
+}
+
 // BuildTasks creates tasks from configuration
 func (b *Builder) BuildTasks() error {
 	if len(b.agents) == 0 {
@@ -171,11 +577,60 @@ func (b *Builder) BuildTasks() error {
 			return errors.Configf("task '%s' references non-existent agent: %s", taskCfg.Description, taskCfg.Agent)
 		}
 
+		description := taskCfg.Description
+		promptRef := ""
+		if taskCfg.Prompt != "" {
+			tmpl, err := b.resolvePrompt(taskCfg.Prompt)
+			if err != nil {
+				return err
+			}
+			description, err = tmpl.Render(task.PromptData{
+				Description:    taskCfg.Description,
+				ExpectedOutput: taskCfg.ExpectedOutput,
+				Agent:          taskCfg.Agent,
+				Context:        taskCfg.Context,
+			})
+			if err != nil {
+				return err
+			}
+			promptRef = tmpl.Ref()
+		}
+
+		// A task-level profile runs the task on a copy of its agent that
+		// uses the profile's provider
+		if taskCfg.Profile != "" {
+			llmProvider, err := b.profileLLM(taskCfg.Profile)
+			if err != nil {
+				return err
+			}
+			if llmProvider, err = b.withQuotas(llmProvider, ag.Name); err != nil {
+				return err
+			}
+			clone := *ag
+			clone.LLM = llmProvider
+			ag = &clone
+		}
+
+		postProcess, err := BuildPostProcess(taskCfg.PostProcess)
+		if err != nil {
+			return err
+		}
+		outputFormat, err := BuildOutputFormat(taskCfg.OutputFormat)
+		if err != nil {
+			return err
+		}
+
 		tsk := task.New(task.Config{
-			Description:    taskCfg.Description,
+			Description:    description,
 			ExpectedOutput: taskCfg.ExpectedOutput,
 			Agent:          ag,
 			Context:        taskCfg.Context,
+			PromptRef:      promptRef,
+			Skills:         taskCfg.Skills,
+			IdempotencyKey: taskCfg.IdempotencyKey,
+			PostProcess:    postProcess,
+			OutputFormat:   outputFormat,
+			Priority:       taskCfg.Priority,
 		})
 
 		b.tasks = append(b.tasks, tsk)
@@ -204,18 +659,70 @@ func (b *Builder) Build() (*orchestrator.Orchestrator, error) {
 		return nil, err
 	}
 
-	var process orchestrator.Process
-	switch b.project.Execution.Process {
-	case "parallel":
-		process = orchestrator.Parallel
-	default:
-		process = orchestrator.Sequential
+	// Catch agents that would be indistinguishable to the orchestrator
+	if _, err := orchestrator.NewAgentRegistry(b.agents...); err != nil {
+		return nil, err
+	}
+
+	process, err := orchestrator.ParseProcess(b.project.Execution.Process)
+	if err != nil {
+		return nil, err
+	}
+
+	var abortSeverity errors.Severity
+	if b.project.Execution.AbortSeverity != "" {
+		if abortSeverity, err = errors.ParseSeverity(b.project.Execution.AbortSeverity); err != nil {
+			return nil, err
+		}
+	}
+
+	// The manager of a hierarchical run uses the project's default provider
+	var manager llm.LLM
+	switch process {
+	case orchestrator.Hierarchical:
+		manager, err = b.profileLLM("")
+		if err != nil {
+			return nil, err
+		}
+		if manager, err = b.withQuotas(manager, ""); err != nil {
+			return nil, err
+		}
+	case orchestrator.Distributed:
+		return nil, errors.InvalidConfig("process", "distributed execution needs a queue; build the orchestrator in Go with orchestrator.Config.Queue")
+	}
+
+	postProcess, err := BuildPostProcess(b.project.Execution.PostProcess)
+	if err != nil {
+		return nil, err
+	}
+
+	var selectionPrompt, planningPrompt *prompts.Template
+	if ref := b.project.Execution.SelectionPrompt; ref != "" {
+		if selectionPrompt, err = b.resolvePrompt(ref); err != nil {
+			return nil, err
+		}
+	}
+	if ref := b.project.Execution.PlanningPrompt; ref != "" {
+		if planningPrompt, err = b.resolvePrompt(ref); err != nil {
+			return nil, err
+		}
 	}
 
 	return orchestrator.New(orchestrator.Config{
-		Agents:  b.agents,
-		Tasks:   b.tasks,
-		Process: process,
+		Agents:          b.agents,
+		Tasks:           b.tasks,
+		Process:         process,
+		ManagerLLM:      manager,
+		PostProcess:     postProcess,
+		Verbose:         b.project.Execution.Verbose,
+		Speculative:     b.project.Execution.Speculative,
+		DedupPrompts:    b.project.Execution.DedupPrompts,
+		MaxConcurrency:  b.project.Execution.MaxConcurrency,
+		ContinueOnError: b.project.Execution.ContinueOnError,
+		AbortSeverity:   abortSeverity,
+		Language:        b.project.Language,
+		SelectionPrompt: selectionPrompt,
+		PlanningPrompt:  planningPrompt,
 	}), nil
 }
 
@@ -1,5 +1,11 @@
 package config
 
+import (
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
 // Provider constants for LLM providers
 const (
 	ProviderOpenAI      = "openai"
@@ -11,72 +17,279 @@ const (
 	ProviderTogether    = "together"
 	ProviderDeepseek    = "deepseek"
 	ProviderOpenrouter  = "openrouter"
+	ProviderVLLM        = "vllm"
+	ProviderTGI         = "tgi"
 	ProviderOpenAILike  = "openai-like" // Generic fallback for OpenAI-compatible APIs
+	ProviderSimulated   = "simulated"   // Synthetic LLM for dry runs; no API calls are made
 )
 
 // Project represents the complete configuration for a project
 type Project struct {
-	Project   string            `yaml:"project"`
-	Version   string            `yaml:"version"`
-	Agents    []AgentConfig     `yaml:"agents"`
-	Tasks     []TaskConfig      `yaml:"tasks"`
-	Execution ExecutionConfig   `yaml:"execution"`
-	LLM       LLMConfig         `yaml:"llm"`
-	Settings  map[string]interface{} `yaml:"settings,omitempty"`
+	Project   string                   `yaml:"project"`
+	Version   string                   `yaml:"version"`
+	Language  string                   `yaml:"language,omitempty"` // Output language of agents and reports, e.g. "German" or "de"
+	Agents    []AgentConfig            `yaml:"agents"`
+	Tasks     []TaskConfig             `yaml:"tasks"`
+	Execution ExecutionConfig          `yaml:"execution"`
+	LLM       LLMConfig                `yaml:"llm"`
+	Prompts   []PromptConfig           `yaml:"prompts,omitempty"`
+	Profiles  map[string]ProfileConfig `yaml:"profiles,omitempty"`
+	Settings  map[string]interface{}   `yaml:"settings,omitempty"`
+	Quotas    *QuotaConfig             `yaml:"quotas,omitempty"` // Usage quotas per agent and provider key
+	Memory    MemoryConfig             `yaml:"memory,omitempty"` // Default memory settings of agents
+
+	// Migrated lists the changes made by LoadYAML to upgrade an older
+	// config version to SchemaVersion
+	Migrated []Warning `yaml:"-"`
+	// Warnings lists the problems LoadYAML worked around, such as a remote
+	// config served from the cache because fetching it failed
+	Warnings []Warning `yaml:"-"`
+	// Environment is the environment selected when loading, if any
+	Environment string `yaml:"-"`
 }
 
 // AgentConfig represents an agent configuration
 type AgentConfig struct {
-	Name      string   `yaml:"name"`
-	Role      string   `yaml:"role"`
-	Goal      string   `yaml:"goal"`
-	Backstory string   `yaml:"backstory"`
-	Verbose   bool     `yaml:"verbose,omitempty"`
-	MaxIter   int      `yaml:"max_iter,omitempty"`
-	MaxRPM    int      `yaml:"max_rpm,omitempty"`
-	Tools     []string `yaml:"tools,omitempty"`
+	Name      string        `yaml:"name"`
+	Role      string        `yaml:"role"`
+	Goal      string        `yaml:"goal"`
+	Backstory string        `yaml:"backstory"`
+	Verbose   bool          `yaml:"verbose,omitempty"`
+	MaxIter   int           `yaml:"max_iter,omitempty"`
+	MaxRPM    int           `yaml:"max_rpm,omitempty"`
+	Tools     []string      `yaml:"tools,omitempty"`      // Tools the agent may use (empty = all)
+	DenyTools []string      `yaml:"deny_tools,omitempty"` // Tools the agent may never use
+	Skills    []string      `yaml:"skills,omitempty"`     // Capability tags used to route tasks
+	Prompt    string        `yaml:"prompt,omitempty"`     // Prompt template reference, e.g. "research@v2"
+	Profile   string        `yaml:"profile,omitempty"`    // Generation parameter profile, e.g. "precise"
+	Language  string        `yaml:"language,omitempty"`   // Overrides the project language for this agent
+	Preset    string        `yaml:"preset,omitempty"`     // Archetype filling unset fields, e.g. "researcher"
+	Memory    *MemoryConfig `yaml:"memory,omitempty"`     // Overrides the project memory settings
 }
 
 // TaskConfig represents a task configuration
 type TaskConfig struct {
-	Description    string   `yaml:"description"`
-	ExpectedOutput string   `yaml:"expected_output,omitempty"`
-	Agent          string   `yaml:"agent"`
-	Context        []string `yaml:"context,omitempty"`
+	Description    string              `yaml:"description"`
+	ExpectedOutput string              `yaml:"expected_output,omitempty"`
+	Agent          string              `yaml:"agent"`
+	Context        []string            `yaml:"context,omitempty"`
+	Prompt         string              `yaml:"prompt,omitempty"`          // Prompt template reference, e.g. "research@v2"
+	Profile        string              `yaml:"profile,omitempty"`         // Overrides the agent's profile for this task
+	Skills         []string            `yaml:"skills,omitempty"`          // Skills required of the assigned agent
+	IdempotencyKey string              `yaml:"idempotency_key,omitempty"` // Overrides the key derived from the task's content
+	PostProcess    []PostProcessConfig `yaml:"post_process,omitempty"`    // Applied to the task's output, in order
+	OutputFormat   *OutputFormatConfig `yaml:"output_format,omitempty"`   // Contract the task's output must satisfy
+	Priority       int                 `yaml:"priority,omitempty"`        // Higher-priority tasks start first when parallel slots are limited
+}
+
+// PostProcessConfig represents one step of a post-processor chain
+type PostProcessConfig struct {
+	Type string `yaml:"type"`           // "trim", "strip_fences", "extract_json", "write_file"
+	Path string `yaml:"path,omitempty"` // Output file for write_file
+}
+
+// OutputFormatConfig represents a task's output format contract
+type OutputFormatConfig struct {
+	Type     string   `yaml:"type"`               // "markdown", "json", "csv", "code"
+	Headers  []string `yaml:"headers,omitempty"`  // Required CSV header columns
+	Language string   `yaml:"language,omitempty"` // Code fence language
+	Retries  int      `yaml:"retries,omitempty"`  // Attempts after a violation (default: 2)
+	Repair   bool     `yaml:"repair,omitempty"`   // Accept repaired JSON, e.g. truncated output
+}
+
+// Memory scopes
+const (
+	MemoryScopeAgent  = "agent"  // Each agent has its own memory (default)
+	MemoryScopeShared = "shared" // Agents with this scope share one memory
+)
+
+// MemoryConfig controls how agents' memories are scoped and ranked
+type MemoryConfig struct {
+	Scope string `yaml:"scope,omitempty"` // "agent" (default) or "shared"
+	// HalfLife makes records decay while unused and strengthen when
+	// retrieved, e.g. 168h (default: no decay, most recent records first)
+	HalfLife time.Duration `yaml:"half_life,omitempty"`
+}
+
+// QuotaConfig holds usage quotas enforced across runs
+type QuotaConfig struct {
+	Store  string                      `yaml:"store,omitempty"`  // JSON file persisting daily usage (default: in memory)
+	Agents map[string]QuotaLimitConfig `yaml:"agents,omitempty"` // Limits by agent name
+	Keys   map[string]QuotaLimitConfig `yaml:"keys,omitempty"`   // Limits by key label (llm.quota_key, default: provider)
+}
+
+// QuotaLimitConfig caps the usage of one agent or key (0 = unlimited)
+type QuotaLimitConfig struct {
+	MaxTokensPerDay int `yaml:"max_tokens_per_day,omitempty"`
+	MaxCallsPerRun  int `yaml:"max_calls_per_run,omitempty"`
+}
+
+// PromptConfig represents a versioned prompt template
+type PromptConfig struct {
+	Name     string `yaml:"name"`
+	Version  string `yaml:"version"`
+	Template string `yaml:"template"`
+}
+
+// ProfileConfig represents a named set of generation parameters.
+// Unset fields inherit from the project LLM configuration.
+type ProfileConfig struct {
+	Model       string   `yaml:"model,omitempty"`
+	Temperature *float32 `yaml:"temperature,omitempty"`
+	TopP        *float32 `yaml:"top_p,omitempty"`
+	MaxTokens   int      `yaml:"max_tokens,omitempty"`
 }
 
 // ExecutionConfig controls how tasks are executed
 type ExecutionConfig struct {
-	Process string `yaml:"process"` // "sequential", "parallel", "hierarchical"
+	Process     string              `yaml:"process"`                // "sequential", "parallel", "hierarchical"
+	PostProcess []PostProcessConfig `yaml:"post_process,omitempty"` // Applied to every task's output
+	Verbose     bool                `yaml:"verbose,omitempty"`      // Print run progress to stdout
+
+	// Speculative starts the next sequential task while the current one
+	// runs, when the next task has no context references
+	Speculative bool `yaml:"speculative,omitempty"`
+	// DedupPrompts caches large prompt prefixes shared by parallel tasks
+	DedupPrompts bool `yaml:"dedup_prompts,omitempty"`
+	// MaxConcurrency bounds the parallel tasks executed at once (0 = all)
+	MaxConcurrency int `yaml:"max_concurrency,omitempty"`
+	// ContinueOnError keeps a sequential run going past failed tasks
+	ContinueOnError bool `yaml:"continue_on_error,omitempty"`
+	// AbortSeverity aborts the run on a task error of this severity or
+	// higher: "medium", "high" or "critical" (default: never)
+	AbortSeverity string `yaml:"abort_severity,omitempty"`
+
+	// Manager prompt overrides for hierarchical runs, as prompt references
+	// rendered with orchestrator.SelectionPromptData / PlanningPromptData
+	SelectionPrompt string `yaml:"selection_prompt,omitempty"`
+	PlanningPrompt  string `yaml:"planning_prompt,omitempty"`
 }
 
 // LLMConfig holds the LLM provider configuration
 type LLMConfig struct {
-	Provider    string                 `yaml:"provider"`
-	APIKey      string                 `yaml:"api_key,omitempty"`
-	Model       string                 `yaml:"model"`
-	Temperature float32                `yaml:"temperature,omitempty"`
-	MaxTokens   int                    `yaml:"max_tokens,omitempty"`
+	Provider    string  `yaml:"provider"`
+	APIKey      string  `yaml:"api_key,omitempty"`
+	Model       string  `yaml:"model"`
+	Temperature float32 `yaml:"temperature,omitempty"`
+	MaxTokens   int     `yaml:"max_tokens,omitempty"`
+	TopP        float32 `yaml:"top_p,omitempty"`
+
+	// TemperatureSet sends Temperature even when it is 0; decoding YAML
+	// sets it when the temperature key is present (0 otherwise leaves the
+	// provider's default)
+	TemperatureSet bool `yaml:"-"`
+
+	// MaxContinuations re-prompts up to this many times when a response is
+	// cut off at max_tokens, stitching the chunks together (0 = disabled)
+	MaxContinuations int `yaml:"max_continuations,omitempty"`
+
+	// MaxPromptTokens caps the estimated prompt size checked before each
+	// request (0 = model's context window minus max_tokens, -1 = no check)
+	MaxPromptTokens int `yaml:"max_prompt_tokens,omitempty"`
+
+	// Timeout bounds each provider request, e.g. "90s" (default: 120s)
+	Timeout time.Duration `yaml:"timeout,omitempty"`
+
+	// MaxResponseBytes caps the decoded size of each response body
+	// (default: 32 MiB, -1 = no cap)
+	MaxResponseBytes int64 `yaml:"max_response_bytes,omitempty"`
+
+	// Echo includes the prompt in responses of the simulated provider
+	Echo bool `yaml:"echo,omitempty"`
+
+	// QuotaKey labels the API key for quotas.keys (default: the provider name)
+	QuotaKey string `yaml:"quota_key,omitempty"`
+
+	// Model alias handling: "warn" (default), "upgrade", "strict" or "ignore"
+	ModelPolicy  string            `yaml:"model_policy,omitempty"`
+	ModelAliases map[string]string `yaml:"model_aliases,omitempty"` // Extra aliases, model -> replacement
 
 	// OpenAI-like specific fields
 	BaseURL      string                 `yaml:"base_url,omitempty"`
 	SystemPrompt string                 `yaml:"system_prompt,omitempty"`
 	Headers      map[string]string      `yaml:"headers,omitempty"`
+	ExtraBody    map[string]interface{} `yaml:"extra_body,omitempty"` // Merged into the request payload
 
 	// Azure OpenAI specific fields
-	Endpoint       string                 `yaml:"endpoint,omitempty"`
-	DeploymentName string                 `yaml:"deployment_name,omitempty"`
-	APIVersion     string                 `yaml:"api_version,omitempty"`
+	Endpoint       string `yaml:"endpoint,omitempty"`
+	DeploymentName string `yaml:"deployment_name,omitempty"`
+	APIVersion     string `yaml:"api_version,omitempty"`
+
+	// OpenRouter specific fields
+	OpenRouter *OpenRouterConfig `yaml:"openrouter,omitempty"`
+
+	// Client identifies the application in request headers
+	Client *ClientConfig `yaml:"client,omitempty"`
 
 	// Generic extra fields for provider-specific configurations
-	Extra       map[string]interface{} `yaml:",inline"`
+	Extra map[string]interface{} `yaml:",inline"`
+}
+
+// ClientConfig names the application sent in the User-Agent and
+// X-Client-Name/X-Client-Version headers of provider requests
+type ClientConfig struct {
+	AppName    string `yaml:"app_name,omitempty"`
+	AppVersion string `yaml:"app_version,omitempty"`
+}
+
+// OpenRouterConfig holds OpenRouter routing preferences and app attribution
+type OpenRouterConfig struct {
+	Referer            string   `yaml:"referer,omitempty"` // Sent as HTTP-Referer
+	Title              string   `yaml:"title,omitempty"`   // Sent as X-Title
+	ProviderOrder      []string `yaml:"provider_order,omitempty"`
+	AllowFallbacks     *bool    `yaml:"allow_fallbacks,omitempty"`
+	IgnoreProviders    []string `yaml:"ignore_providers,omitempty"`
+	FallbackModels     []string `yaml:"fallback_models,omitempty"`
+	MaxPromptPrice     float64  `yaml:"max_prompt_price,omitempty"`     // USD per million tokens
+	MaxCompletionPrice float64  `yaml:"max_completion_price,omitempty"` // USD per million tokens
+}
+
+// llmConfigYAML is LLMConfig without its YAML methods
+type llmConfigYAML LLMConfig
+
+// UnmarshalYAML decodes the configuration and records whether temperature
+// is set, so that an explicit 0 is sent to the provider
+func (c *LLMConfig) UnmarshalYAML(node *yaml.Node) error {
+	if err := node.Decode((*llmConfigYAML)(c)); err != nil {
+		return err
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "temperature" {
+			c.TemperatureSet = true
+		}
+	}
+	return nil
+}
+
+// MarshalYAML encodes the configuration, keeping a temperature of 0 that
+// was set explicitly
+func (c LLMConfig) MarshalYAML() (interface{}, error) {
+	var node yaml.Node
+	if err := node.Encode(llmConfigYAML(c)); err != nil {
+		return nil, err
+	}
+	if c.TemperatureSet && c.Temperature == 0 {
+		// Place the key after the model, where omitempty dropped it
+		at := len(node.Content)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "model" {
+				at = i + 2
+			}
+		}
+		temperature := []*yaml.Node{
+			{Kind: yaml.ScalarNode, Value: "temperature"},
+			{Kind: yaml.ScalarNode, Value: "0"},
+		}
+		node.Content = append(node.Content[:at], append(temperature, node.Content[at:]...)...)
+	}
+	return &node, nil
 }
 
 // DefaultProject returns a minimal default project
 func DefaultProject() *Project {
 	return &Project{
 		Project: "my-agent-project",
-		Version: "1.0",
+		Version: SchemaVersion,
 		Execution: ExecutionConfig{
 			Process: "sequential",
 		},
@@ -86,4 +299,4 @@ func DefaultProject() *Project {
 			Temperature: 0.7,
 		},
 	}
-}
\ No newline at end of file
+}
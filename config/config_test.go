@@ -1,11 +1,25 @@
 package config
 
 import (
+	"context"
+	"net/http"
+	"net/http/httptest"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/quota"
+	"github.com/counhopig/gittyai/tools"
 )
 
 func TestDefaultProject(t *testing.T) {
@@ -15,8 +29,8 @@ func TestDefaultProject(t *testing.T) {
 		t.Errorf("DefaultProject().Project = %v, want %v", project.Project, "my-agent-project")
 	}
 
-	if project.Version != "1.0" {
-		t.Errorf("DefaultProject().Version = %v, want %v", project.Version, "1.0")
+	if project.Version != SchemaVersion {
+		t.Errorf("DefaultProject().Version = %v, want %v", project.Version, SchemaVersion)
 	}
 
 	if project.LLM.Provider != ProviderOpenAI {
@@ -143,6 +157,24 @@ func TestProject_Validate(t *testing.T) {
 			wantErr: true,
 			errCode: errors.CategoryValidation,
 		},
+		{
+			name: "agent lacks task skills",
+			project: &Project{
+				Project: "test-project",
+				Agents: []AgentConfig{
+					{Name: "agent1", Role: "role1", Goal: "goal1", Skills: []string{"research"}},
+				},
+				Tasks: []TaskConfig{
+					{Description: "task1", Agent: "agent1", Skills: []string{"Research", "sql"}},
+				},
+				LLM: LLMConfig{
+					Provider: ProviderOpenAI,
+					Model:    "gpt-4o",
+				},
+			},
+			wantErr: true,
+			errCode: errors.CategoryValidation,
+		},
 		{
 			name: "task references non-existent agent",
 			project: &Project{
@@ -161,6 +193,25 @@ func TestProject_Validate(t *testing.T) {
 			wantErr: true,
 			errCode: errors.CategoryValidation,
 		},
+		{
+			name: "misspelled process",
+			project: &Project{
+				Project: "test-project",
+				Agents: []AgentConfig{
+					{Name: "agent1", Role: "role1", Goal: "goal1"},
+				},
+				Tasks: []TaskConfig{
+					{Description: "task1", Agent: "agent1"},
+				},
+				Execution: ExecutionConfig{Process: "paralell"},
+				LLM: LLMConfig{
+					Provider: ProviderOpenAI,
+					Model:    "gpt-4o",
+				},
+			},
+			wantErr: true,
+			errCode: errors.CategoryValidation,
+		},
 	}
 
 	for _, tt := range tests {
@@ -229,6 +280,445 @@ llm:
 	}
 }
 
+func TestLoadYAML_Migrates(t *testing.T) {
+	yamlContent := `
+project: legacy
+version: 1.0
+agents:
+  - name: researcher
+    role: Research Analyst
+    goal: Gather information
+tasks:
+  - description: Research AI trends
+    agent: researcher
+llm:
+  provider: openai
+  model: gpt-4o
+  seed: 7
+settings:
+  log_level: info
+  timeout_seconds: 90
+`
+	tmpFile := filepath.Join(t.TempDir(), "legacy.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	project, err := LoadYAML(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+	if project.Version != SchemaVersion {
+		t.Errorf("Version = %q, want %q", project.Version, SchemaVersion)
+	}
+	if project.LLM.Timeout != 90*time.Second {
+		t.Errorf("LLM.Timeout = %v, want 90s", project.LLM.Timeout)
+	}
+	if project.LLM.ExtraBody["seed"] != 7 {
+		t.Errorf("LLM.ExtraBody = %v, want seed 7", project.LLM.ExtraBody)
+	}
+	if _, ok := project.Settings["timeout_seconds"]; ok {
+		t.Errorf("Settings still has timeout_seconds: %v", project.Settings)
+	}
+
+	fields := make(map[string]bool)
+	for _, w := range project.Migrated {
+		fields[w.Field] = true
+	}
+	for _, field := range []string{"settings.timeout_seconds", "llm.seed", "version"} {
+		if !fields[field] {
+			t.Errorf("Migrated = %v, missing a warning for %s", project.Migrated, field)
+		}
+	}
+	if lint := project.Lint(nil); len(lint) < len(project.Migrated) || lint[0] != project.Migrated[0] {
+		t.Errorf("Lint() = %v, want the migration warnings first", lint)
+	}
+}
+
+func TestLoadYAML_Defaults(t *testing.T) {
+	yamlContent := `
+project: defaults
+agent_defaults:
+  verbose: true
+  max_iter: 5
+  tools: [web_search]
+  profile: precise
+  memory:
+    scope: shared
+agents:
+  - name: researcher
+    role: Research Analyst
+    goal: Gather information
+  - name: writer
+    role: Writer
+    goal: Write
+    verbose: false
+    tools: [file_write]
+    memory:
+      half_life: 24h
+  - name: critic
+    preset: critic
+task_defaults:
+  expected_output: A short summary
+  priority: 2
+tasks:
+  - description: Research AI trends
+    agent: researcher
+  - description: Write it up
+    agent: writer
+    priority: 5
+llm:
+  provider: openai
+  model: gpt-4o
+profiles:
+  precise:
+    temperature: 0.1
+`
+	tmpFile := filepath.Join(t.TempDir(), "defaults.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	project, err := LoadYAML(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+
+	researcher, writer, critic := project.Agents[0], project.Agents[1], project.Agents[2]
+	if !researcher.Verbose || researcher.MaxIter != 5 || researcher.Profile != "precise" || !reflect.DeepEqual(researcher.Tools, []string{"web_search"}) {
+		t.Errorf("researcher = %+v, want the agent defaults", researcher)
+	}
+	if writer.Verbose || writer.MaxIter != 5 || !reflect.DeepEqual(writer.Tools, []string{"file_write"}) {
+		t.Errorf("writer = %+v, want its own verbose and tools", writer)
+	}
+	if writer.Memory == nil || writer.Memory.Scope != MemoryScopeShared || writer.Memory.HalfLife != 24*time.Hour {
+		t.Errorf("writer.Memory = %+v, want the default scope merged with its half-life", writer.Memory)
+	}
+	if critic.Tools != nil || critic.MaxIter != 5 {
+		t.Errorf("critic = %+v, want tools left to its preset", critic)
+	}
+
+	if project.Tasks[0].ExpectedOutput != "A short summary" || project.Tasks[0].Priority != 2 {
+		t.Errorf("tasks[0] = %+v, want the task defaults", project.Tasks[0])
+	}
+	if project.Tasks[1].Priority != 5 {
+		t.Errorf("tasks[1].Priority = %d, want its own 5", project.Tasks[1].Priority)
+	}
+
+	bad := strings.Replace(yamlContent, "  max_iter: 5", "  name: everyone", 1)
+	if err := os.WriteFile(tmpFile, []byte(bad), 0644); err != nil {
+		t.Fatalf("Failed to write temp file: %v", err)
+	}
+	if _, err := LoadYAML(tmpFile); err == nil || !strings.Contains(err.Error(), "agent_defaults") {
+		t.Errorf("LoadYAML() error = %v, want agent_defaults.name rejected", err)
+	}
+}
+
+func TestLoadYAMLEnv(t *testing.T) {
+	yamlContent := `
+project: envs
+agents:
+  - name: researcher
+    role: Research Analyst
+    goal: Gather information
+  - name: writer
+    role: Writer
+    goal: Write
+tasks:
+  - description: Research AI trends
+    agent: researcher
+llm:
+  provider: ollama
+  model: llama3
+  temperature: 0.2
+environments:
+  prod:
+    llm:
+      provider: azure-openai
+      model: gpt-4o
+      endpoint: https://example.openai.azure.com
+    agents:
+      - name: writer
+        max_iter: 3
+`
+	tmpFile := filepath.Join(t.TempDir(), "envs.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	dev, err := LoadYAMLEnv(tmpFile, "")
+	if err != nil {
+		t.Fatalf("LoadYAMLEnv(dev) error = %v", err)
+	}
+	if dev.LLM.Provider != ProviderOllama || dev.Agents[1].MaxIter != 0 {
+		t.Errorf("dev = %+v, want no overrides", dev.LLM)
+	}
+
+	t.Setenv(EnvironmentVar, "prod")
+	prod, err := LoadYAML(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadYAML(prod) error = %v", err)
+	}
+	if prod.Environment != "prod" || prod.LLM.Provider != ProviderAzureOpenAI || prod.LLM.Model != "gpt-4o" {
+		t.Errorf("prod.LLM = %+v, want the prod overrides", prod.LLM)
+	}
+	if prod.LLM.Temperature != 0.2 {
+		t.Errorf("prod.LLM.Temperature = %v, want 0.2 kept from the base", prod.LLM.Temperature)
+	}
+	if len(prod.Agents) != 2 || prod.Agents[1].MaxIter != 3 || prod.Agents[1].Role != "Writer" {
+		t.Errorf("prod.Agents = %+v, want writer merged by name", prod.Agents)
+	}
+
+	if _, err := LoadYAMLEnv(tmpFile, "staging"); err == nil || !strings.Contains(err.Error(), "prod") {
+		t.Errorf("LoadYAMLEnv(staging) error = %v, want unknown environment listing prod", err)
+	}
+}
+
+func TestLoadYAML_EncryptedValues(t *testing.T) {
+	key, err := NewAESKey()
+	if err != nil {
+		t.Fatalf("NewAESKey() error = %v", err)
+	}
+	secret, err := EncryptValue(key, "sk-secret")
+	if err != nil {
+		t.Fatalf("EncryptValue() error = %v", err)
+	}
+	if strings.Contains(secret, "sk-secret") || !strings.HasPrefix(secret, "enc[") {
+		t.Fatalf("EncryptValue() = %q, want an enc[...] ciphertext", secret)
+	}
+
+	yamlContent := `
+project: secrets
+agents:
+  - name: researcher
+    role: Research Analyst
+    goal: Gather information
+tasks:
+  - description: Research AI trends
+    agent: researcher
+llm:
+  provider: openai
+  model: gpt-4o
+  api_key: "` + secret + `"
+`
+	tmpFile := filepath.Join(t.TempDir(), "secrets.yaml")
+	if err := os.WriteFile(tmpFile, []byte(yamlContent), 0644); err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+
+	t.Setenv(KeyVar, "")
+	if _, err := LoadYAML(tmpFile); err == nil || !errors.HasCode(err, errors.ErrInvalidConfig) {
+		t.Errorf("LoadYAML() without a key error = %v, want ErrInvalidConfig", err)
+	}
+
+	t.Setenv(KeyVar, key.String())
+	project, err := LoadYAML(tmpFile)
+	if err != nil {
+		t.Fatalf("LoadYAML() error = %v", err)
+	}
+	if project.LLM.APIKey != "sk-secret" {
+		t.Errorf("LLM.APIKey = %q, want the decrypted value", project.LLM.APIKey)
+	}
+
+	other, _ := NewAESKey()
+	SetCipher(other)
+	defer SetCipher(nil)
+	if _, err := LoadYAML(tmpFile); err == nil || strings.Contains(err.Error(), "sk-secret") {
+		t.Errorf("LoadYAML() with the wrong key error = %v, want a decryption failure", err)
+	}
+}
+
+func TestLoadYAML_Remote(t *testing.T) {
+	yamlContent := `
+project: remote
+agents:
+  - name: researcher
+    role: Research Analyst
+    goal: Gather information
+tasks:
+  - description: Research AI trends
+    agent: researcher
+llm:
+  provider: openai
+  model: gpt-4o
+`
+	requests := 0
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.URL.Query().Has("checksum") {
+			t.Errorf("checksum sent to the server: %s", r.URL)
+		}
+		if r.URL.Path == "/huge.yaml" {
+			w.Write([]byte(strings.Repeat("#", maxConfigSize+1)))
+			return
+		}
+		w.Write([]byte(yamlContent))
+	}))
+	t.Setenv(CacheDirVar, t.TempDir())
+	defaultClient := http.DefaultClient
+	http.DefaultClient = server.Client()
+	t.Cleanup(func() { http.DefaultClient = defaultClient })
+
+	project, err := LoadYAML(server.URL + "/crew.yaml")
+	if err != nil {
+		t.Fatalf("LoadYAML(url) error = %v", err)
+	}
+	if project.Project != "remote" || len(project.Warnings) != 0 {
+		t.Errorf("Project = %q with warnings %v, want remote", project.Project, project.Warnings)
+	}
+	if _, err := LoadYAML(server.URL + "/huge.yaml"); !errors.HasCode(err, errors.ErrInvalidConfig) {
+		t.Errorf("LoadYAML(oversized) error = %v, want ErrInvalidConfig", err)
+	}
+
+	pinned := server.URL + "/crew.yaml?checksum=sha256:" + sha256Hex([]byte(yamlContent))
+	if _, err := LoadYAML(pinned); err != nil {
+		t.Fatalf("LoadYAML(pinned) error = %v", err)
+	}
+	if _, err := LoadYAML(server.URL + "/crew.yaml?checksum=sha256:" + strings.Repeat("0", 64)); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("LoadYAML(wrong checksum) error = %v, want a checksum mismatch", err)
+	}
+
+	// Plain HTTP configs load only when pinned
+	plain := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(yamlContent))
+	}))
+	defer plain.Close()
+	if _, err := LoadYAML(plain.URL + "/crew.yaml"); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("LoadYAML(unpinned http) error = %v, want ErrInvalidField", err)
+	}
+	if _, err := LoadYAML(plain.URL + "/crew.yaml?checksum=sha256:" + sha256Hex([]byte(yamlContent))); err != nil {
+		t.Errorf("LoadYAML(pinned http) error = %v", err)
+	}
+
+	// Pinned configs come from the cache, unpinned ones fall back to it
+	server.Close()
+	before := requests
+	if _, err := LoadYAML(pinned); err != nil {
+		t.Errorf("LoadYAML(pinned, offline) error = %v, want the cached copy", err)
+	}
+	if project, err := LoadYAML(server.URL + "/crew.yaml"); err != nil {
+		t.Errorf("LoadYAML(url, offline) error = %v, want the cached copy", err)
+	} else if len(project.Warnings) != 1 || !strings.Contains(project.Warnings[0].Message, "using the cached copy") {
+		t.Errorf("Warnings = %v, want the fallback to the cached copy", project.Warnings)
+	}
+	if requests != before {
+		t.Errorf("requests = %d after the server closed, want %d", requests, before)
+	}
+}
+
+func TestLoadYAML_Git(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	repo := t.TempDir()
+	data, err := os.ReadFile("../examples/simple.yaml")
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	if err := os.MkdirAll(filepath.Join(repo, "crews"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(repo, "crews", "simple.yaml"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	for _, args := range [][]string{
+		{"init", "--quiet"},
+		{"add", "."},
+		{"-c", "user.name=test", "-c", "user.email=test@example.com", "commit", "--quiet", "-m", "crew"},
+		{"tag", "v1"},
+	} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = repo
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+	}
+	t.Setenv(CacheDirVar, t.TempDir())
+
+	project, err := LoadYAML("git::file://" + repo + "//crews/simple.yaml?ref=v1")
+	if err != nil {
+		t.Fatalf("LoadYAML(git) error = %v", err)
+	}
+	if project.Project != "simple-research" {
+		t.Errorf("Project = %q, want simple-research", project.Project)
+	}
+	if _, err := LoadYAML("git::file://" + repo + "//../secret.yaml"); err == nil {
+		t.Error("LoadYAML(git) read a file outside the repository")
+	}
+
+	// Neither the ref nor the repository is passed to git as an option
+	if _, err := LoadYAML("git::file://" + repo + "//crews/simple.yaml?ref=--upload-pack=touch"); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("LoadYAML(git, option ref) error = %v, want ErrInvalidField", err)
+	}
+	args := cloneArgs("--upload-pack=touch /tmp/x", "v1", "dir")
+	if got := strings.Join(args[len(args)-3:], " "); got != "-- --upload-pack=touch /tmp/x dir" {
+		t.Errorf("cloneArgs() = %q, want the repository after --", args)
+	}
+}
+
+func TestMigrate_Versions(t *testing.T) {
+	tests := []struct {
+		version interface{}
+		wantErr bool
+		migrate bool
+	}{
+		{nil, false, false},
+		{"2.0", false, false},
+		{2.0, false, false},
+		{"1", false, true},
+		{"v1.5", false, true},
+		{"2.1", true, false},
+		{"3.0", true, false},
+		{"latest", true, false},
+	}
+	for _, tt := range tests {
+		doc := map[string]interface{}{"project": "p"}
+		if tt.version != nil {
+			doc["version"] = tt.version
+		}
+		warnings, err := Migrate(doc)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Migrate(version %v) error = %v, wantErr %v", tt.version, err, tt.wantErr)
+			continue
+		}
+		if migrated := len(warnings) > 0; migrated != tt.migrate {
+			t.Errorf("Migrate(version %v) warnings = %v, want migration %v", tt.version, warnings, tt.migrate)
+		}
+		if tt.migrate && doc["version"] != SchemaVersion {
+			t.Errorf("Migrate(version %v) left version %v", tt.version, doc["version"])
+		}
+	}
+
+	project := DefaultProject()
+	project.Version = "9.0"
+	if err := project.Validate(); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("Validate() error = %v, want a config newer than SchemaVersion rejected", err)
+	}
+
+	// Unknown llm keys of version 1 are typos, not request fields
+	doc := map[string]interface{}{"version": "1.0", "llm": map[string]interface{}{"provider": "openai", "temprature": 0.2}}
+	if _, err := Migrate(doc); !errors.HasCode(err, errors.ErrInvalidField) || !strings.Contains(err.Error(), "llm.temprature") {
+		t.Errorf("Migrate() with an unknown llm key error = %v, want invalid llm.temprature", err)
+	}
+}
+
+func TestValidate_RunLevelWriteFile(t *testing.T) {
+	project := &Project{
+		Project: "write",
+		Agents:  []AgentConfig{{Name: "writer", Role: "writer", Goal: "write"}},
+		Tasks:   []TaskConfig{{Description: "write", Agent: "writer"}},
+		LLM:     LLMConfig{Provider: ProviderOllama, Model: "llama3.2"},
+	}
+	project.Execution.PostProcess = []PostProcessConfig{{Type: PostProcessWriteFile, Path: "out/result.md"}}
+	if err := project.Validate(); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("Validate() with a shared run-level file error = %v, want ErrInvalidField", err)
+	}
+
+	project.Execution.PostProcess[0].Path = "out/{key}.md"
+	if err := project.Validate(); err != nil {
+		t.Errorf("Validate() with a per-task file error = %v", err)
+	}
+}
+
 func TestLoadYAML_InvalidFile(t *testing.T) {
 	_, err := LoadYAML("/nonexistent/file.yaml")
 	if err == nil {
@@ -277,6 +767,43 @@ func TestSaveYAML(t *testing.T) {
 	}
 }
 
+func TestLLMConfig_ExplicitZeroTemperature(t *testing.T) {
+	project := &Project{
+		Project: "zero-temperature",
+		Version: "1.0",
+		Agents:  []AgentConfig{{Name: "writer", Role: "Writer", Goal: "Write"}},
+		Tasks:   []TaskConfig{{Description: "Write", Agent: "writer"}},
+		LLM:     LLMConfig{Provider: ProviderOpenAI, Model: "gpt-4o", TemperatureSet: true},
+	}
+	path := filepath.Join(t.TempDir(), "project.yaml")
+	if err := SaveYAML(project, path); err != nil {
+		t.Fatalf("SaveYAML() unexpected error: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if !strings.Contains(string(data), "temperature: 0") {
+		t.Errorf("saved YAML dropped the explicit temperature:\n%s", data)
+	}
+
+	loaded, err := LoadYAML(path)
+	if err != nil {
+		t.Fatalf("LoadYAML() unexpected error: %v", err)
+	}
+	if loaded.LLM.Temperature != 0 || !loaded.LLM.TemperatureSet {
+		t.Errorf("loaded LLM = %+v, want an explicit temperature of 0", loaded.LLM)
+	}
+
+	project.LLM.TemperatureSet = false
+	if err := SaveYAML(project, path); err != nil {
+		t.Fatalf("SaveYAML() unexpected error: %v", err)
+	}
+	if loaded, err = LoadYAML(path); err != nil {
+		t.Fatalf("LoadYAML() unexpected error: %v", err)
+	}
+	if loaded.LLM.TemperatureSet {
+		t.Errorf("loaded LLM = %+v, want the provider's default temperature", loaded.LLM)
+	}
+}
+
 func TestProviderConstants(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -292,6 +819,8 @@ func TestProviderConstants(t *testing.T) {
 		{"Together", ProviderTogether, "together"},
 		{"Deepseek", ProviderDeepseek, "deepseek"},
 		{"Openrouter", ProviderOpenrouter, "openrouter"},
+		{"VLLM", ProviderVLLM, "vllm"},
+		{"TGI", ProviderTGI, "tgi"},
 		{"OpenAILike", ProviderOpenAILike, "openai-like"},
 	}
 
@@ -303,3 +832,459 @@ func TestProviderConstants(t *testing.T) {
 		})
 	}
 }
+
+func TestBuilder_ResolvesPrompts(t *testing.T) {
+	project := &Project{
+		Project: "prompt-test",
+		Agents: []AgentConfig{
+			{Name: "researcher", Role: "Researcher", Goal: "Research", Prompt: "persona"},
+		},
+		Tasks: []TaskConfig{
+			{Description: "AI trends", Agent: "researcher", Prompt: "research@v1"},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama},
+		Prompts: []PromptConfig{
+			{Name: "research", Version: "v1", Template: "Research {{.Description}} for {{.Agent}}"},
+			{Name: "research", Version: "v2", Template: "Investigate {{.Description}}"},
+			{Name: "persona", Version: "v1", Template: "You are {{.Role}}. {{.Task}}"},
+		},
+	}
+
+	builder := NewBuilder(project)
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	tsk := builder.GetTasks()[0]
+	if tsk.Description != "Research AI trends for researcher" {
+		t.Errorf("task description = %q, want rendered v1 template", tsk.Description)
+	}
+	if tsk.PromptRef != "research@v1" {
+		t.Errorf("task PromptRef = %q, want %q", tsk.PromptRef, "research@v1")
+	}
+	if ag := builder.GetAgents()[0]; ag.Prompt == nil || ag.Prompt.Ref() != "persona@v1" {
+		t.Errorf("agent prompt = %v, want persona@v1", ag.Prompt)
+	}
+
+	project.Tasks[0].Prompt = "research@v9"
+	if _, err := NewBuilder(project).Build(); err == nil {
+		t.Errorf("Build() expected error for unknown prompt version")
+	}
+}
+
+func TestBuilder_AppliesProfiles(t *testing.T) {
+	temp := float32(0.0)
+	project := &Project{
+		Project: "profile-test",
+		Agents: []AgentConfig{
+			{Name: "writer", Role: "Writer", Goal: "Write", Profile: ProfileCreative},
+			{Name: "checker", Role: "Checker", Goal: "Check"},
+		},
+		Tasks: []TaskConfig{
+			{Description: "Draft", Agent: "writer"},
+			{Description: "Summarize", Agent: "writer", Profile: "fast"},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama, Temperature: 0.7},
+		Profiles: map[string]ProfileConfig{
+			"fast": {Model: "llama3.2:1b", Temperature: &temp, MaxTokens: 128},
+		},
+	}
+
+	cfg := project.LLM.WithProfile(project.Profiles["fast"])
+	if cfg.Model != "llama3.2:1b" || cfg.Temperature != 0 || !cfg.TemperatureSet || cfg.MaxTokens != 128 {
+		t.Errorf("WithProfile() = %+v, want fast profile applied", cfg)
+	}
+
+	builder := NewBuilder(project)
+	if _, err := builder.Build(); err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+
+	agents := builder.GetAgents()
+	if agents[0].LLM == agents[1].LLM {
+		t.Errorf("agents with different profiles share an LLM provider")
+	}
+
+	tasks := builder.GetTasks()
+	if tasks[0].Agent != agents[0] {
+		t.Errorf("task without profile should use its agent directly")
+	}
+	if tasks[1].Agent == agents[0] || tasks[1].Agent.LLM == agents[0].LLM {
+		t.Errorf("task profile should run on a copy of the agent with its own LLM")
+	}
+	if tasks[1].Agent.Name != "writer" {
+		t.Errorf("task agent name = %q, want %q", tasks[1].Agent.Name, "writer")
+	}
+
+	project.Tasks[1].Profile = "unknown"
+	if err := project.Validate(); err == nil {
+		t.Errorf("Validate() expected error for unknown profile")
+	}
+}
+
+type namedTool struct {
+	*tools.BaseTool
+}
+
+func (namedTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return "", nil
+}
+
+func TestProject_Lint(t *testing.T) {
+	project := &Project{
+		Project: "lint",
+		Agents: []AgentConfig{
+			{Name: "writer", Role: "writer", Goal: "write", Tools: []string{"search", "search"}},
+			{Name: "idle", Role: "idle", Goal: "wait", Tools: []string{"calculator"}},
+		},
+		Tasks: []TaskConfig{
+			{Description: "write", Agent: "writer", ExpectedOutput: "an essay", Profile: "long"},
+			{Description: "edit", Agent: "writer"},
+		},
+		Profiles: map[string]ProfileConfig{
+			"long": {MaxTokens: 100000},
+		},
+		LLM: LLMConfig{Provider: ProviderAnthropic, Model: "claude-3-5-sonnet-latest", Temperature: 1.5},
+	}
+	if err := project.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	registry := tools.NewRegistry()
+	_ = registry.Register(namedTool{tools.NewBaseTool("search", "web search", nil)})
+	_ = registry.Register(namedTool{tools.NewBaseTool("scraper", "fetches pages", nil)})
+
+	var got []string
+	for _, w := range project.Lint(registry) {
+		got = append(got, w.Field)
+	}
+	want := []string{
+		"llm.temperature",
+		"profiles[long].temperature",
+		"profiles[long].max_tokens",
+		"tasks[1]",
+		"agents[writer].tools",
+		"agents[idle]",
+		"agents[idle].tools",
+		"agents[idle].tools",
+		"tools",
+	}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("Lint() fields = %v, want %v", got, want)
+	}
+
+	// Deprecated models are reported instead of printed when building
+	deprecated := *project
+	deprecated.LLM = LLMConfig{Provider: ProviderOpenAI, Model: "gpt-4-1106-preview"}
+	var model string
+	for _, w := range deprecated.Lint(nil) {
+		if w.Field == "llm.model" {
+			model = w.Message
+		}
+	}
+	if !strings.Contains(model, "gpt-4-turbo") {
+		t.Errorf("Lint() model warning = %q, want deprecation of gpt-4-1106-preview", model)
+	}
+
+	project.LLM.Temperature = 3
+	if err := project.Validate(); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("Validate() error = %v, want invalid temperature", err)
+	}
+}
+
+func TestBuilder_AppliesPresets(t *testing.T) {
+	project := &Project{
+		Project: "presets",
+		Agents: []AgentConfig{
+			{Name: "analyst", Preset: "researcher", Goal: "Research electric cars"},
+		},
+		Tasks: []TaskConfig{
+			{Description: "research", Agent: "analyst", Skills: []string{"research"}},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama, Model: "llama3.2"},
+	}
+	if err := project.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	builder := NewBuilder(project)
+	if err := builder.BuildAgents(); err != nil {
+		t.Fatalf("BuildAgents() unexpected error: %v", err)
+	}
+	a := builder.GetAgents()[0]
+	if a.Name != "analyst" || a.Role != "Senior Research Analyst" || a.Goal != "Research electric cars" {
+		t.Errorf("agent = %+v, want preset role with overridden goal", a)
+	}
+
+	project.Agents[0].Preset = "astronaut"
+	if err := project.Validate(); err == nil || !strings.Contains(err.Error(), "agent 'analyst' references unknown preset: astronaut") {
+		t.Errorf("Validate() error = %v, want the agent and its unknown preset", err)
+	}
+}
+
+func TestBuilder_WrapsQuotas(t *testing.T) {
+	project := &Project{
+		Project: "quotas",
+		Agents: []AgentConfig{
+			{Name: "writer", Role: "Writer", Goal: "Write"},
+		},
+		Tasks: []TaskConfig{
+			{Description: "write", Agent: "writer"},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama, Model: "llama3.2"},
+		Quotas: &QuotaConfig{
+			Store:  filepath.Join(t.TempDir(), "quotas.json"),
+			Agents: map[string]QuotaLimitConfig{"writer": {MaxCallsPerRun: 5}},
+		},
+	}
+	if err := project.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	builder := NewBuilder(project)
+	if err := builder.BuildAgents(); err != nil {
+		t.Fatalf("BuildAgents() unexpected error: %v", err)
+	}
+	if _, ok := builder.GetAgents()[0].LLM.(*quota.LLM); !ok {
+		t.Errorf("agent LLM = %T, want *quota.LLM", builder.GetAgents()[0].LLM)
+	}
+
+	// Simulations neither use nor spend quotas
+	simulated := *project
+	simulated.LLM.Provider = ProviderSimulated
+	for _, builder := range []*Builder{NewBuilder(project).WithSimulation(true), NewBuilder(&simulated)} {
+		if err := builder.BuildAgents(); err != nil {
+			t.Fatalf("BuildAgents() unexpected error: %v", err)
+		}
+		if _, ok := builder.GetAgents()[0].LLM.(*llm.Simulator); !ok {
+			t.Errorf("simulated agent LLM = %T, want *llm.Simulator", builder.GetAgents()[0].LLM)
+		}
+	}
+
+	project.Quotas.Agents["ghost"] = QuotaLimitConfig{MaxCallsPerRun: 1}
+	if err := project.Validate(); err == nil {
+		t.Error("Validate() expected error for quota on unknown agent")
+	}
+}
+
+func TestBuilder_Runtime(t *testing.T) {
+	project := &Project{
+		Project: "runtime",
+		Agents: []AgentConfig{
+			{Name: "writer", Role: "Writer", Goal: "Write", Memory: &MemoryConfig{HalfLife: time.Hour}},
+			{Name: "editor", Role: "Editor", Goal: "Edit", Memory: &MemoryConfig{Scope: MemoryScopeShared}},
+		},
+		Tasks: []TaskConfig{
+			{Description: "write", Agent: "writer"},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama, Model: "llama3.2"},
+		Quotas: &QuotaConfig{
+			Store:  filepath.Join(t.TempDir(), "quotas.json"),
+			Agents: map[string]QuotaLimitConfig{"writer": {MaxCallsPerRun: 5}},
+		},
+	}
+	rt, err := NewBuilder(project).Runtime()
+	if err != nil {
+		t.Fatalf("Runtime() unexpected error: %v", err)
+	}
+
+	provider, err := rt.LLM("", "writer")
+	if err != nil {
+		t.Fatalf("LLM() unexpected error: %v", err)
+	}
+	if _, ok := provider.(*quota.LLM); !ok {
+		t.Errorf("LLM() = %T, want *quota.LLM", provider)
+	}
+	if again, _ := rt.LLM("", "writer"); again != provider {
+		t.Error("LLM() built a new provider for the same agent")
+	}
+
+	mem := rt.Memory("writer")
+	if _, ok := mem.(*memory.Decaying); !ok {
+		t.Errorf("Memory(writer) = %T, want the agent's decaying memory", mem)
+	}
+	if rt.Memory("writer") != mem {
+		t.Error("Memory() created a new memory for the same agent")
+	}
+	if rt.Memory("editor") == mem || rt.Memory("editor") != rt.Memory("editor") {
+		t.Error("Memory(editor) is not the shared memory")
+	}
+}
+
+func TestBuilder_WithSimulation(t *testing.T) {
+	project := &Project{
+		Project: "dry-run",
+		Agents: []AgentConfig{
+			{Name: "writer", Role: "Writer", Goal: "Write"},
+		},
+		Tasks: []TaskConfig{
+			{Description: "write a haiku", Agent: "writer"},
+		},
+		LLM:       LLMConfig{Provider: ProviderOpenAI, Model: "gpt-4o-mini"},
+		Execution: ExecutionConfig{Process: "sequential"},
+	}
+
+	orch, err := NewBuilder(project).WithSimulation(true).Build()
+	if err != nil {
+		t.Fatalf("Build() unexpected error: %v", err)
+	}
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(results[0].Result, "[simulated]") {
+		t.Errorf("Result = %q, want a simulated response", results[0].Result)
+	}
+}
+
+func TestBuilder_MemoryScopes(t *testing.T) {
+	project := &Project{
+		Project: "memory",
+		Agents: []AgentConfig{
+			{Name: "researcher", Role: "Researcher", Goal: "Research"},
+			{Name: "writer", Role: "Writer", Goal: "Write", Memory: &MemoryConfig{Scope: MemoryScopeShared}},
+			{Name: "editor", Role: "Editor", Goal: "Edit", Memory: &MemoryConfig{Scope: MemoryScopeShared}},
+		},
+		Tasks: []TaskConfig{
+			{Description: "research", Agent: "researcher"},
+		},
+		LLM: LLMConfig{Provider: ProviderOllama, Model: "llama3.2"},
+	}
+	if err := project.Validate(); err != nil {
+		t.Fatalf("Validate() unexpected error: %v", err)
+	}
+
+	builder := NewBuilder(project)
+	if err := builder.BuildAgents(); err != nil {
+		t.Fatalf("BuildAgents() unexpected error: %v", err)
+	}
+	agents := builder.GetAgents()
+	if agents[0].Memory == agents[1].Memory {
+		t.Error("isolated agent shares memory with a shared agent")
+	}
+	if agents[1].Memory != agents[2].Memory {
+		t.Error("shared agents have separate memories")
+	}
+
+	if _, ok := agents[0].Memory.(*memory.Base); !ok {
+		t.Errorf("memory without half-life = %T, want *memory.Base", agents[0].Memory)
+	}
+
+	project.Agents[0].Memory = &MemoryConfig{HalfLife: 48 * time.Hour}
+	builder = NewBuilder(project)
+	if err := builder.BuildAgents(); err != nil {
+		t.Fatalf("BuildAgents() unexpected error: %v", err)
+	}
+	if _, ok := builder.GetAgents()[0].Memory.(*memory.Decaying); !ok {
+		t.Errorf("memory with half-life = %T, want *memory.Decaying", builder.GetAgents()[0].Memory)
+	}
+
+	project.Agents[0].Memory.HalfLife = -time.Hour
+	if err := project.Validate(); err == nil {
+		t.Error("Validate() expected error for negative half-life")
+	}
+	project.Agents[0].Memory = nil
+	project.Memory.Scope = "global"
+	if err := project.Validate(); err == nil {
+		t.Error("Validate() expected error for unknown memory scope")
+	}
+}
+
+// eventLog records published events
+type eventLog struct {
+	mu     sync.Mutex
+	events []events.Event
+}
+
+func (l *eventLog) Publish(ctx context.Context, event events.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.events = append(l.events, event)
+	return nil
+}
+
+func TestWatcher_Reload(t *testing.T) {
+	crew := func(model string) string {
+		return `
+project: watched
+agents:
+  - name: writer
+    role: Writer
+    goal: Write
+tasks:
+  - description: write a haiku
+    agent: writer
+llm:
+  provider: openai
+  model: ` + model + "\n"
+	}
+	path := filepath.Join(t.TempDir(), "crew.yaml")
+	if err := os.WriteFile(path, []byte(crew("gpt-4o-mini")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	log := &eventLog{}
+	w, err := NewWatcher(WatchConfig{
+		Path:   path,
+		Events: log,
+		Build: func(project *Project) (*orchestrator.Orchestrator, error) {
+			return NewBuilder(project).WithSimulation(true).Build()
+		},
+	})
+	if err != nil {
+		t.Fatalf("NewWatcher() error = %v", err)
+	}
+	first := w.Orchestrator()
+
+	if reloaded, err := w.Reload(context.Background()); reloaded || err != nil {
+		t.Errorf("Reload() unchanged = %v, %v, want no reload", reloaded, err)
+	}
+
+	// An invalid config keeps the previous one active
+	if err := os.WriteFile(path, []byte("project: watched\nagents: []\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if reloaded, err := w.Reload(context.Background()); reloaded || err == nil {
+		t.Errorf("Reload() invalid = %v, %v, want an error", reloaded, err)
+	}
+	if w.Orchestrator() != first || w.Project().LLM.Model != "gpt-4o-mini" {
+		t.Error("Reload() replaced the config with an invalid one")
+	}
+	// The same invalid content is not rebuilt nor reported again
+	if reloaded, err := w.Reload(context.Background()); reloaded || err == nil {
+		t.Errorf("Reload() invalid again = %v, %v, want the previous error", reloaded, err)
+	}
+
+	if err := os.WriteFile(path, []byte(crew("gpt-4o")), 0644); err != nil {
+		t.Fatal(err)
+	}
+	inFlight := w.acquire()
+	if reloaded, err := w.Reload(context.Background()); !reloaded || err != nil {
+		t.Fatalf("Reload() = %v, %v, want the new config", reloaded, err)
+	}
+	if w.Orchestrator() == first || w.Project().LLM.Model != "gpt-4o" {
+		t.Error("Reload() kept the old config")
+	}
+	if _, err := w.Kickoff(context.Background()); err != nil {
+		t.Errorf("Kickoff() error = %v", err)
+	}
+	// The replaced orchestrator is shut down once its runs finish
+	if _, err := first.Kickoff(context.Background()); err != nil {
+		t.Errorf("Kickoff() on the previous orchestrator with a run in flight error = %v", err)
+	}
+	inFlight.release()
+	if _, err := first.Kickoff(context.Background()); !errors.HasCode(err, errors.ErrShutdown) {
+		t.Errorf("Kickoff() on the previous orchestrator error = %v, want ErrShutdown", err)
+	}
+
+	var types []events.Type
+	for _, e := range log.events {
+		if e.Source != path {
+			t.Errorf("event source = %q, want %q", e.Source, path)
+		}
+		types = append(types, e.Type)
+	}
+	if want := []events.Type{events.ConfigReloadFailed, events.ConfigReloaded}; !reflect.DeepEqual(types, want) {
+		t.Errorf("events = %v, want %v", types, want)
+	}
+}
@@ -0,0 +1,102 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// presetFields are the agent fields a preset fills; an agent with a preset
+// takes them from the preset rather than from agent_defaults
+var presetFields = map[string]bool{"role": true, "goal": true, "backstory": true, "skills": true, "tools": true}
+
+// inheritDefaults fills the agents and tasks of a parsed config document
+// from its agent_defaults and task_defaults blocks and removes the blocks.
+// Keys an agent or task sets itself override the defaults; nested mappings
+// such as memory are merged key by key.
+func inheritDefaults(doc map[string]interface{}) error {
+	agentDefaults, err := defaultsBlock(doc, "agent_defaults", reflect.TypeOf(AgentConfig{}), "name")
+	if err != nil {
+		return err
+	}
+	taskDefaults, err := defaultsBlock(doc, "task_defaults", reflect.TypeOf(TaskConfig{}), "description")
+	if err != nil {
+		return err
+	}
+
+	if agents, ok := doc["agents"].([]interface{}); ok && agentDefaults != nil {
+		for _, item := range agents {
+			if a, ok := item.(map[string]interface{}); ok {
+				_, hasPreset := a["preset"]
+				if _, ok := agentDefaults["preset"]; ok {
+					hasPreset = true
+				}
+				inherit(a, agentDefaults, func(key string) bool { return hasPreset && presetFields[key] })
+			}
+		}
+	}
+	if tasks, ok := doc["tasks"].([]interface{}); ok && taskDefaults != nil {
+		for _, item := range tasks {
+			if t, ok := item.(map[string]interface{}); ok {
+				inherit(t, taskDefaults, nil)
+			}
+		}
+	}
+	return nil
+}
+
+// defaultsBlock removes a defaults block from doc and checks its keys
+// against the fields of the configs it applies to. The field naming each
+// agent or task cannot have a default.
+func defaultsBlock(doc map[string]interface{}, block string, t reflect.Type, own string) (map[string]interface{}, error) {
+	raw, ok := doc[block]
+	if !ok || raw == nil {
+		return nil, nil
+	}
+	delete(doc, block)
+	defaults, ok := raw.(map[string]interface{})
+	if !ok {
+		return nil, errors.InvalidField(block, "must be a mapping of field defaults")
+	}
+
+	known := yamlKeys(t)
+	keys := make([]string, 0, len(defaults))
+	for key := range defaults {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if !known[key] || key == own {
+			return nil, errors.Validationf("%s: %s cannot have a default", block, key).WithContext("field", key)
+		}
+	}
+	return defaults, nil
+}
+
+// inherit sets the keys of defaults that cfg lacks, merging mappings both
+// set. skip, if not nil, excludes keys from inheritance.
+func inherit(cfg, defaults map[string]interface{}, skip func(key string) bool) {
+	for key, value := range defaults {
+		if skip != nil && skip(key) {
+			continue
+		}
+		own, set := cfg[key]
+		if !set {
+			cfg[key] = value
+			continue
+		}
+		ownMap, ok := own.(map[string]interface{})
+		defaultMap, isMap := value.(map[string]interface{})
+		if ok && isMap {
+			merged := make(map[string]interface{}, len(defaultMap)+len(ownMap))
+			for k, v := range defaultMap {
+				merged[k] = v
+			}
+			for k, v := range ownMap {
+				merged[k] = v
+			}
+			cfg[key] = merged
+		}
+	}
+}
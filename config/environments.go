@@ -0,0 +1,95 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// EnvironmentVar selects the environment LoadYAML applies, e.g. "prod"
+const EnvironmentVar = "GITTYAI_ENV"
+
+// selectEnvironment applies the overrides of the named environment from
+// the environments section of a parsed config document and removes the
+// section. Mappings are merged key by key, agents are matched by name and
+// other values replace the project's. Selecting an environment the section
+// does not define is an error; a document without the section ignores the
+// selection.
+func selectEnvironment(doc map[string]interface{}, env string) error {
+	raw, ok := doc["environments"]
+	delete(doc, "environments")
+	if !ok || raw == nil || env == "" {
+		return nil
+	}
+	environments, ok := raw.(map[string]interface{})
+	if !ok {
+		return errors.InvalidField("environments", "must map environment names to overrides")
+	}
+	overrides, ok := environments[env]
+	if !ok {
+		names := make([]string, 0, len(environments))
+		for name := range environments {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return errors.Validationf("unknown environment %q (defined: %s)", env, strings.Join(names, ", ")).WithContext("environment", env)
+	}
+	if overrides == nil {
+		return nil
+	}
+	values, ok := overrides.(map[string]interface{})
+	if !ok {
+		return errors.InvalidField(fmt.Sprintf("environments.%s", env), "must be a mapping of overrides")
+	}
+	if _, ok := values["environments"]; ok {
+		return errors.InvalidField(fmt.Sprintf("environments.%s", env), "cannot define environments")
+	}
+	overlay(doc, values)
+	return nil
+}
+
+// overlay merges src into dst: mappings key by key, the agents list by
+// agent name, and any other value replacing dst's
+func overlay(dst, src map[string]interface{}) {
+	for key, value := range src {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			if own, ok := dst[key].(map[string]interface{}); ok {
+				overlay(own, v)
+				continue
+			}
+		case []interface{}:
+			if own, ok := dst[key].([]interface{}); ok && key == "agents" {
+				dst[key] = overlayByName(own, v)
+				continue
+			}
+		}
+		dst[key] = value
+	}
+}
+
+// overlayByName merges the items of src into the items of dst with the
+// same name, appending items with new names
+func overlayByName(dst, src []interface{}) []interface{} {
+	index := make(map[interface{}]int, len(dst))
+	for i, item := range dst {
+		if m, ok := item.(map[string]interface{}); ok {
+			index[m["name"]] = i
+		}
+	}
+	for _, item := range src {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			dst = append(dst, item)
+			continue
+		}
+		if i, found := index[m["name"]]; found && m["name"] != nil {
+			overlay(dst[i].(map[string]interface{}), m)
+			continue
+		}
+		dst = append(dst, item)
+	}
+	return dst
+}
@@ -0,0 +1,164 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Warning is a lint finding. Unlike a Validate error it does not stop the
+// project from running, but likely makes it behave other than intended.
+type Warning struct {
+	Field   string // Location of the finding, e.g. "agents[writer].tools"
+	Message string
+}
+
+// String returns the warning as "field: message"
+func (w Warning) String() string {
+	return w.Field + ": " + w.Message
+}
+
+// temperatureRanges are the temperatures each provider accepts; providers
+// not listed accept 0 to 2
+var temperatureRanges = map[string][2]float32{
+	ProviderAnthropic: {0, 1},
+}
+
+// temperatureRange returns the temperatures a provider accepts
+func temperatureRange(provider string) (min, max float32) {
+	if r, ok := temperatureRanges[provider]; ok {
+		return r[0], r[1]
+	}
+	return 0, 2
+}
+
+// Lint reports likely mistakes in a valid project: provider-specific
+// temperature ranges, deprecated or upgraded models, max_tokens above the
+// model's output limit, tasks without expected_output, agents without tasks
+// and unused tools. When registry is not nil, agent tools are also checked
+// against it. The changes made when migrating an older config version and
+// the problems worked around when loading come first. Lint assumes Validate
+// passed.
+func (p *Project) Lint(registry *tools.Registry) []Warning {
+	// Changes made when migrating an older config version, then problems
+	// worked around when loading
+	warnings := append([]Warning(nil), p.Migrated...)
+	warnings = append(warnings, p.Warnings...)
+	warn := func(field, format string, args ...interface{}) {
+		warnings = append(warnings, Warning{Field: field, Message: fmt.Sprintf(format, args...)})
+	}
+
+	// Generation parameters, for the base LLM and every profile in use
+	lintLLM("llm", p.LLM, warn)
+	for _, name := range p.usedProfiles() {
+		profile, err := p.Profile(name)
+		if err != nil {
+			continue
+		}
+		lintLLM(fmt.Sprintf("profiles[%s]", name), p.LLM.WithProfile(profile), warn)
+	}
+
+	tasksPerAgent := make(map[string]int)
+	for i, t := range p.Tasks {
+		tasksPerAgent[t.Agent]++
+		if strings.TrimSpace(t.ExpectedOutput) == "" {
+			warn(fmt.Sprintf("tasks[%d]", i), "no expected_output; agents decide the format and length of the result")
+		}
+	}
+
+	// The manager may assign any agent in hierarchical mode
+	process, _ := orchestrator.ParseProcess(p.Execution.Process)
+	registered := make(map[string]bool)
+	if registry != nil {
+		for _, name := range registry.List() {
+			registered[name] = false
+		}
+	}
+	for _, a := range p.Agents {
+		a, _ = a.withPreset()
+		field := fmt.Sprintf("agents[%s]", a.Name)
+		idle := tasksPerAgent[a.Name] == 0 && process != orchestrator.Hierarchical
+		if idle {
+			warn(field, "agent has no tasks")
+		}
+
+		seen := make(map[string]bool)
+		for _, name := range a.Tools {
+			if seen[name] {
+				warn(field+".tools", "tool %s is listed more than once", name)
+			}
+			seen[name] = true
+
+			if _, ok := registered[name]; registry != nil && !ok {
+				warn(field+".tools", "tool %s is not registered", name)
+			} else if !idle {
+				registered[name] = true
+			}
+		}
+		if idle && len(a.Tools) > 0 {
+			warn(field+".tools", "tools of an agent without tasks are never used: %s", strings.Join(a.Tools, ", "))
+		}
+	}
+
+	var unused []string
+	for name, used := range registered {
+		if !used {
+			unused = append(unused, name)
+		}
+	}
+	sort.Strings(unused)
+	for _, name := range unused {
+		warn("tools", "registered tool %s is not used by any agent with tasks", name)
+	}
+
+	return warnings
+}
+
+// lintLLM checks the generation parameters of an LLM configuration
+func lintLLM(field string, cfg LLMConfig, warn func(field, format string, args ...interface{})) {
+	if min, max := temperatureRange(cfg.Provider); cfg.Temperature < min || cfg.Temperature > max {
+		warn(field+".temperature", "%g is outside the range %s accepts (%g to %g)", cfg.Temperature, cfg.Provider, min, max)
+	}
+
+	if res, err := resolveModel(cfg); err == nil && res.Warning != "" {
+		warn(field+".model", "%s", res.Warning)
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = cfg.DeploymentName
+	}
+	if limit, ok := llm.MaxOutputTokens(model); ok && cfg.MaxTokens > limit {
+		warn(field+".max_tokens", "%d exceeds the %d output tokens %s can generate", cfg.MaxTokens, limit, model)
+	}
+}
+
+// usedProfiles returns the sorted names of profiles referenced by agents or
+// tasks or defined by the project
+func (p *Project) usedProfiles() []string {
+	seen := make(map[string]bool)
+	for name := range p.Profiles {
+		seen[name] = true
+	}
+	for _, a := range p.Agents {
+		if a.Profile != "" {
+			seen[a.Profile] = true
+		}
+	}
+	for _, t := range p.Tasks {
+		if t.Profile != "" {
+			seen[t.Profile] = true
+		}
+	}
+
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,227 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// SchemaVersion is the newest config layout this library reads. Files with
+// an older version are migrated when loaded; newer ones are rejected.
+const SchemaVersion = "2.0"
+
+// Version is a config schema version, "major.minor"
+type Version struct {
+	Major int
+	Minor int
+}
+
+// ParseVersion parses a schema version such as "2", "2.0" or "v2.0"
+func ParseVersion(s string) (Version, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "v")
+	major, minor, hasMinor := strings.Cut(s, ".")
+	var v Version
+	var err error
+	if v.Major, err = strconv.Atoi(major); err != nil || v.Major < 0 {
+		return Version{}, errors.InvalidField("version", "must be a schema version such as "+SchemaVersion).WithContext("value", s)
+	}
+	if hasMinor {
+		if v.Minor, err = strconv.Atoi(minor); err != nil || v.Minor < 0 {
+			return Version{}, errors.InvalidField("version", "must be a schema version such as "+SchemaVersion).WithContext("value", s)
+		}
+	}
+	return v, nil
+}
+
+// String formats the version as "major.minor"
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d", v.Major, v.Minor)
+}
+
+// Less reports whether v is older than other
+func (v Version) Less(other Version) bool {
+	return v.Major < other.Major || v.Major == other.Major && v.Minor < other.Minor
+}
+
+// Migration upgrades a parsed config document from one major schema
+// version to the next, returning a warning for every change it makes, or an
+// error when the document cannot be upgraded
+type Migration struct {
+	From        int // Major version upgraded
+	Description string
+	Apply       func(doc map[string]interface{}) ([]Warning, error)
+}
+
+// migrations upgrade each major version to the next, in order
+var migrations = []Migration{
+	{From: 1, Description: "move settings.timeout_seconds and provider fields into llm", Apply: migrateV1},
+}
+
+// checkVersion rejects schema versions newer than SchemaVersion
+func checkVersion(s string) (Version, error) {
+	current, _ := ParseVersion(SchemaVersion)
+	if s == "" {
+		return current, nil
+	}
+	v, err := ParseVersion(s)
+	if err != nil {
+		return Version{}, err
+	}
+	if current.Less(v) {
+		return Version{}, errors.Newf(errors.ErrInvalidConfig, "config version %s is newer than the supported version %s; upgrade the library", v, SchemaVersion).
+			WithContext("version", v.String()).
+			WithContext("supported", SchemaVersion)
+	}
+	return v, nil
+}
+
+// Migrate upgrades a parsed config document to SchemaVersion in place and
+// returns a warning for every change. A document without a version is
+// taken to be current.
+func Migrate(doc map[string]interface{}) ([]Warning, error) {
+	raw := ""
+	if v, ok := doc["version"]; ok && v != nil {
+		raw = fmt.Sprint(v)
+		if f, ok := v.(float64); ok {
+			raw = strconv.FormatFloat(f, 'f', -1, 64)
+		}
+	}
+	v, err := checkVersion(raw)
+	if err != nil {
+		return nil, err
+	}
+
+	current, _ := ParseVersion(SchemaVersion)
+	var warnings []Warning
+	for _, m := range migrations {
+		if v.Major != m.From || !v.Less(current) {
+			continue
+		}
+		applied, err := m.Apply(doc)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, applied...)
+		to := Version{Major: m.From + 1}
+		warnings = append(warnings, Warning{Field: "version", Message: fmt.Sprintf("migrated from %s to %s: %s", v, to, m.Description)})
+		v = to
+	}
+	if raw != "" && v.Less(current) {
+		return nil, errors.Newf(errors.ErrInvalidConfig, "no migration from config version %s to %s", v, SchemaVersion).WithContext("version", v.String())
+	}
+	if len(warnings) > 0 {
+		doc["version"] = SchemaVersion
+	}
+	return warnings, nil
+}
+
+// MigrateYAML upgrades a YAML config to SchemaVersion, e.g. to rewrite a
+// file in the current layout. The data is returned unchanged when no
+// migration applies.
+func MigrateYAML(data []byte) ([]byte, []Warning, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, errors.Wrap(errors.ErrInvalidConfig, "failed to parse YAML", err)
+	}
+	if doc == nil {
+		return data, nil, nil
+	}
+	warnings, err := Migrate(doc)
+	if err != nil || len(warnings) == 0 {
+		return data, nil, err
+	}
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return nil, nil, errors.Wrap(errors.ErrInternal, "failed to marshal YAML", err)
+	}
+	return out, warnings, nil
+}
+
+// v1RequestFields are the provider request fields version 1 configs set
+// directly under llm
+var v1RequestFields = map[string]bool{
+	"seed":               true,
+	"stop":               true,
+	"frequency_penalty":  true,
+	"presence_penalty":   true,
+	"repetition_penalty": true,
+	"logit_bias":         true,
+	"response_format":    true,
+	"top_k":              true,
+	"min_p":              true,
+	"n":                  true,
+	"user":               true,
+}
+
+// migrateV1 upgrades the 1.x layout. Version 1 examples set the request
+// timeout as settings.timeout_seconds, which nothing read, and put
+// provider-specific request fields directly under llm, where they were
+// ignored; they are now llm.timeout and llm.extra_body. Other unknown llm
+// keys are rejected rather than sent to the provider, since they are more
+// likely typos than request fields.
+func migrateV1(doc map[string]interface{}) ([]Warning, error) {
+	var warnings []Warning
+	llmDoc, _ := doc["llm"].(map[string]interface{})
+	if llmDoc == nil {
+		llmDoc = make(map[string]interface{})
+	}
+
+	if settings, ok := doc["settings"].(map[string]interface{}); ok {
+		if seconds, ok := settings["timeout_seconds"]; ok {
+			delete(settings, "timeout_seconds")
+			if _, set := llmDoc["timeout"]; !set {
+				llmDoc["timeout"] = fmt.Sprintf("%vs", seconds)
+				doc["llm"] = llmDoc
+			}
+			warnings = append(warnings, Warning{Field: "settings.timeout_seconds", Message: "moved to llm.timeout"})
+		}
+	}
+
+	known := yamlKeys(reflect.TypeOf(LLMConfig{}))
+	var extra []string
+	for key := range llmDoc {
+		if !known[key] {
+			extra = append(extra, key)
+		}
+	}
+	sort.Strings(extra)
+	for _, key := range extra {
+		if !v1RequestFields[key] {
+			return nil, errors.InvalidField("llm."+key, "unknown field; move provider request fields to llm.extra_body").
+				WithContext("version", "1")
+		}
+	}
+	if len(extra) > 0 {
+		body, _ := llmDoc["extra_body"].(map[string]interface{})
+		if body == nil {
+			body = make(map[string]interface{})
+		}
+		for _, key := range extra {
+			if _, set := body[key]; !set {
+				body[key] = llmDoc[key]
+			}
+			delete(llmDoc, key)
+			warnings = append(warnings, Warning{Field: "llm." + key, Message: "moved to llm.extra_body, which is sent with every request"})
+		}
+		llmDoc["extra_body"] = body
+	}
+	return warnings, nil
+}
+
+// yamlKeys returns the YAML keys of a struct's fields, excluding inline ones
+func yamlKeys(t reflect.Type) map[string]bool {
+	keys := make(map[string]bool)
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("yaml"), ",")
+		if name != "" && name != "-" {
+			keys[name] = true
+		}
+	}
+	return keys
+}
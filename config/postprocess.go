@@ -0,0 +1,63 @@
+package config
+
+import (
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/postprocess"
+	"github.com/counhopig/gittyai/task"
+)
+
+// Post-processor types available in YAML
+const (
+	PostProcessTrim        = "trim"
+	PostProcessStripFences = "strip_fences"
+	PostProcessExtractJSON = "extract_json"
+	PostProcessWriteFile   = "write_file"
+)
+
+// BuildPostProcess converts post-processor configurations into a chain
+func BuildPostProcess(cfgs []PostProcessConfig) (postprocess.Chain, error) {
+	if len(cfgs) == 0 {
+		return nil, nil
+	}
+
+	chain := make(postprocess.Chain, 0, len(cfgs))
+	for i, cfg := range cfgs {
+		switch cfg.Type {
+		case PostProcessTrim:
+			chain = append(chain, postprocess.TrimSpace())
+		case PostProcessStripFences:
+			chain = append(chain, postprocess.StripFences())
+		case PostProcessExtractJSON:
+			chain = append(chain, postprocess.ExtractJSON())
+		case PostProcessWriteFile:
+			if cfg.Path == "" {
+				return nil, errors.RequiredField("post_process path").WithContext("step", i)
+			}
+			chain = append(chain, postprocess.WriteFile(cfg.Path))
+		default:
+			return nil, errors.InvalidField("post_process type", "must be one of trim, strip_fences, extract_json, write_file").
+				WithContext("step", i).
+				WithContext("type", cfg.Type)
+		}
+	}
+	return chain, nil
+}
+
+// BuildOutputFormat converts an output format configuration into a task
+// format contract
+func BuildOutputFormat(cfg *OutputFormatConfig) (*task.OutputFormat, error) {
+	if cfg == nil {
+		return nil, nil
+	}
+	kind, err := task.ParseFormatKind(cfg.Type)
+	if err != nil {
+		return nil, err
+	}
+	return &task.OutputFormat{
+		Kind:     kind,
+		Headers:  cfg.Headers,
+		Language: cfg.Language,
+		Retries:  cfg.Retries,
+		Repair:   cfg.Repair,
+	}, nil
+}
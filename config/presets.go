@@ -0,0 +1,32 @@
+package config
+
+import "github.com/counhopig/gittyai/presets"
+
+// withPreset returns the agent configuration with empty fields filled from
+// its preset, if it names one
+func (a AgentConfig) withPreset() (AgentConfig, error) {
+	if a.Preset == "" {
+		return a, nil
+	}
+	preset, err := presets.Get(a.Preset)
+	if err != nil {
+		return a, err
+	}
+
+	if a.Role == "" {
+		a.Role = preset.Role
+	}
+	if a.Goal == "" {
+		a.Goal = preset.Goal
+	}
+	if a.Backstory == "" {
+		a.Backstory = preset.Backstory
+	}
+	if len(a.Skills) == 0 {
+		a.Skills = preset.Skills
+	}
+	if len(a.Tools) == 0 {
+		a.Tools = preset.Tools
+	}
+	return a, nil
+}
@@ -0,0 +1,72 @@
+package config
+
+import (
+	"sort"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Built-in profile names
+const (
+	ProfileCreative = "creative"
+	ProfilePrecise  = "precise"
+	ProfileCheap    = "cheap"
+)
+
+func float32Ptr(v float32) *float32 {
+	return &v
+}
+
+// DefaultProfiles returns the built-in generation parameter profiles.
+// Profiles declared in a project with the same name replace them.
+func DefaultProfiles() map[string]ProfileConfig {
+	return map[string]ProfileConfig{
+		ProfileCreative: {Temperature: float32Ptr(1.0), TopP: float32Ptr(0.95)},
+		ProfilePrecise:  {Temperature: float32Ptr(0.1), TopP: float32Ptr(0.5)},
+		ProfileCheap:    {Temperature: float32Ptr(0.3), MaxTokens: 512},
+	}
+}
+
+// Profile looks up a profile by name, preferring project-defined profiles
+// over the built-in defaults
+func (p *Project) Profile(name string) (ProfileConfig, error) {
+	if profile, ok := p.Profiles[name]; ok {
+		return profile, nil
+	}
+	if profile, ok := DefaultProfiles()[name]; ok {
+		return profile, nil
+	}
+	return ProfileConfig{}, errors.NotFound("profile", name).WithContext("available", p.profileNames())
+}
+
+// profileNames returns the sorted names of all available profiles
+func (p *Project) profileNames() []string {
+	seen := DefaultProfiles()
+	for name, profile := range p.Profiles {
+		seen[name] = profile
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// WithProfile returns a copy of the LLM configuration with the profile's
+// parameters applied
+func (c LLMConfig) WithProfile(profile ProfileConfig) LLMConfig {
+	if profile.Model != "" {
+		c.Model = profile.Model
+	}
+	if profile.Temperature != nil {
+		c.Temperature, c.TemperatureSet = *profile.Temperature, true
+	}
+	if profile.TopP != nil {
+		c.TopP = *profile.TopP
+	}
+	if profile.MaxTokens > 0 {
+		c.MaxTokens = profile.MaxTokens
+	}
+	return c
+}
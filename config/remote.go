@@ -0,0 +1,272 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/errors"
+)
+
+// CacheDirVar overrides the directory caching remote configs (default: the
+// user cache directory)
+const CacheDirVar = "GITTYAI_CONFIG_CACHE"
+
+// remoteTimeout bounds fetching a remote config
+const remoteTimeout = time.Minute
+
+// maxConfigSize bounds the size of a remote config
+const maxConfigSize = 10 << 20
+
+// isRemote reports whether path is a URL LoadYAML fetches rather than a
+// file. Plain http:// URLs must be pinned, see fetchConfig.
+func isRemote(path string) bool {
+	for _, prefix := range []string{"https://", "http://", "s3://", "git::"} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// readConfig reads a local config file or fetches a remote one, returning
+// a warning when a remote config is served from the cache
+func readConfig(path string) ([]byte, []Warning, error) {
+	if !isRemote(path) {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, nil, errors.Wrap(errors.ErrMissingConfig, fmt.Sprintf("failed to read file %s", path), err).WithContext("path", path)
+		}
+		return data, nil, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), remoteTimeout)
+	defer cancel()
+	return fetchConfig(ctx, path)
+}
+
+// fetchConfig fetches a remote config through the cache. A config pinned
+// with a "checksum=sha256:<hex>" query parameter is served from the cache
+// when a copy with that checksum exists and fails to load when the fetched
+// content differs. An unpinned config is fetched every time, falling back
+// to the last cached copy when the fetch fails, with a warning. Configs
+// fetched over plain HTTP could be altered in transit, so they must be
+// pinned.
+func fetchConfig(ctx context.Context, rawURL string) ([]byte, []Warning, error) {
+	source, checksum, err := splitChecksum(rawURL)
+	if err != nil {
+		return nil, nil, err
+	}
+	if strings.HasPrefix(source, "http://") && checksum == "" {
+		return nil, nil, errors.InvalidField("url", "http:// configs must be pinned with checksum=sha256:<hex>; use https:// otherwise").
+			WithContext("url", source)
+	}
+
+	cacheKey := checksum
+	if cacheKey == "" {
+		sum := sha256.Sum256([]byte(source))
+		cacheKey = hex.EncodeToString(sum[:])
+	}
+	cached := cachePath(cacheKey)
+	if checksum != "" && cached != "" {
+		if data, err := os.ReadFile(cached); err == nil && sha256Hex(data) == checksum {
+			return data, nil, nil
+		}
+	}
+
+	data, err := fetchRemote(ctx, source)
+	if err != nil {
+		if checksum == "" && cached != "" {
+			if data, cacheErr := os.ReadFile(cached); cacheErr == nil {
+				return data, []Warning{{Field: source, Message: fmt.Sprintf("fetch failed (%v); using the cached copy", err)}}, nil
+			}
+		}
+		return nil, nil, err
+	}
+	if checksum != "" && sha256Hex(data) != checksum {
+		return nil, nil, errors.Newf(errors.ErrInvalidConfig, "checksum mismatch for %s", source).
+			WithContext("want", checksum).
+			WithContext("got", sha256Hex(data))
+	}
+
+	if cached != "" {
+		if err := os.MkdirAll(filepath.Dir(cached), 0700); err == nil {
+			_ = os.WriteFile(cached, data, 0600)
+		}
+	}
+	return data, nil, nil
+}
+
+// splitChecksum removes the checksum query parameter from a config URL and
+// returns its lowercase SHA-256 hex digest
+func splitChecksum(rawURL string) (source, checksum string, err error) {
+	base, query, ok := strings.Cut(rawURL, "?")
+	if !ok {
+		return rawURL, "", nil
+	}
+	values, err := url.ParseQuery(query)
+	if err != nil {
+		return "", "", errors.Wrap(errors.ErrInvalidConfig, "invalid config URL query", err).WithContext("url", rawURL)
+	}
+	pin := values.Get("checksum")
+	if pin == "" {
+		return rawURL, "", nil
+	}
+	values.Del("checksum")
+	algorithm, digest, _ := strings.Cut(pin, ":")
+	digest = strings.ToLower(digest)
+	if algorithm != "sha256" || len(digest) != sha256.Size*2 {
+		return "", "", errors.InvalidField("checksum", "must be sha256:<64 hex digits>").WithContext("url", rawURL)
+	}
+	if _, err := hex.DecodeString(digest); err != nil {
+		return "", "", errors.InvalidField("checksum", "must be sha256:<64 hex digits>").WithContext("url", rawURL)
+	}
+	if encoded := values.Encode(); encoded != "" {
+		base += "?" + encoded
+	}
+	return base, digest, nil
+}
+
+// cachePath returns the cache file of a key, or "" without a cache directory
+func cachePath(key string) string {
+	dir := os.Getenv(CacheDirVar)
+	if dir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return ""
+		}
+		dir = filepath.Join(userCache, "gittyai", "config")
+	}
+	return filepath.Join(dir, key+".yaml")
+}
+
+// fetchRemote downloads a config from an HTTP(S) URL, an S3 object or a
+// file in a Git repository
+func fetchRemote(ctx context.Context, source string) ([]byte, error) {
+	switch {
+	case strings.HasPrefix(source, "git::"):
+		return fetchGit(ctx, strings.TrimPrefix(source, "git::"))
+	case strings.HasPrefix(source, "s3://"):
+		return fetchS3(ctx, source)
+	default:
+		return fetchHTTP(ctx, source)
+	}
+}
+
+// fetchHTTP downloads a config over HTTP(S)
+func fetchHTTP(ctx context.Context, source string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid config URL", err).WithContext("url", source)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, errors.APICallError("fetch config", err).WithContext("url", source)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxConfigSize+1))
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrNetworkUnavail, "failed to read config", err).WithContext("url", source)
+	}
+	if len(data) > maxConfigSize {
+		return nil, errors.Newf(errors.ErrInvalidConfig, "config exceeds %d bytes", maxConfigSize).WithContext("url", source)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.APIStatusCodeError(resp.StatusCode, string(data)).WithContext("url", source)
+	}
+	return data, nil
+}
+
+// fetchS3 downloads a config object with the AWS credentials and region of
+// the environment; AWS_ENDPOINT_URL selects an S3-compatible store
+func fetchS3(ctx context.Context, source string) ([]byte, error) {
+	bucket, _, _ := strings.Cut(strings.TrimPrefix(source, "s3://"), "/")
+	region := os.Getenv("AWS_REGION")
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	store, err := artifact.NewS3Store(artifact.S3Config{
+		Bucket:          bucket,
+		Region:          region,
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+		Endpoint:        os.Getenv("AWS_ENDPOINT_URL"),
+	})
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrMissingConfig, "cannot fetch config from S3", err).WithContext("url", source)
+	}
+	return store.Get(ctx, source)
+}
+
+// fetchGit reads a file from a Git repository, addressed as
+// "<repository>//<file>?ref=<branch or tag>", by shallow-cloning it with
+// the git command. Neither the repository nor the ref can be taken for an
+// option of git clone.
+func fetchGit(ctx context.Context, source string) ([]byte, error) {
+	source, query, _ := strings.Cut(source, "?")
+	ref, err := url.ParseQuery(query)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid config URL query", err).WithContext("url", source)
+	}
+
+	// The file follows the first "//" after the scheme's
+	rest := source
+	scheme := ""
+	if i := strings.Index(source, "://"); i >= 0 {
+		scheme, rest = source[:i+3], source[i+3:]
+	}
+	repo, file, ok := strings.Cut(rest, "//")
+	if !ok || file == "" || !filepath.IsLocal(filepath.FromSlash(file)) {
+		return nil, errors.InvalidField("url", "git sources must be git::<repository>//<file>").WithContext("url", source)
+	}
+	branch := ref.Get("ref")
+	if strings.HasPrefix(branch, "-") {
+		return nil, errors.InvalidField("ref", "must not start with '-'").WithContext("url", source)
+	}
+
+	dir, err := os.MkdirTemp("", "gittyai-config-")
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to create clone directory", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, "git", cloneArgs(scheme+repo, branch, dir)...)
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, errors.Wrap(errors.ErrNetworkUnavail, "git clone failed: "+strings.TrimSpace(stderr.String()), err).WithContext("url", source)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrMissingConfig, "config file not found in repository", err).WithContext("file", file)
+	}
+	return data, nil
+}
+
+// cloneArgs returns the arguments of a shallow git clone of repo into dir.
+// The repository follows "--", so it is never parsed as an option.
+func cloneArgs(repo, branch, dir string) []string {
+	args := []string{"clone", "--quiet", "--depth", "1"}
+	if branch != "" {
+		args = append(args, "--branch", branch)
+	}
+	return append(args, "--", repo, dir)
+}
+
+// sha256Hex returns the lowercase SHA-256 hex digest of data
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
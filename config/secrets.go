@@ -0,0 +1,195 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// KeyVar holds the base64-encoded 256-bit key decrypting enc[...] config
+// values when no Cipher is set with SetCipher
+const KeyVar = "GITTYAI_CONFIG_KEY"
+
+// Cipher encrypts and decrypts config values, e.g. a client of a key
+// management service
+type Cipher interface {
+	Encrypt(plaintext []byte) ([]byte, error)
+	Decrypt(ciphertext []byte) ([]byte, error)
+}
+
+var (
+	cipherMu     sync.RWMutex
+	configCipher Cipher
+)
+
+// SetCipher selects the cipher decrypting enc[...] values when configs are
+// loaded. A nil cipher restores the key from GITTYAI_CONFIG_KEY.
+func SetCipher(c Cipher) {
+	cipherMu.Lock()
+	defer cipherMu.Unlock()
+	configCipher = c
+}
+
+// loadCipher returns the cipher set with SetCipher, or an AESKey from
+// GITTYAI_CONFIG_KEY
+func loadCipher() (Cipher, error) {
+	cipherMu.RLock()
+	c := configCipher
+	cipherMu.RUnlock()
+	if c != nil {
+		return c, nil
+	}
+	return KeyFromEnv()
+}
+
+// AESKey is a 256-bit key encrypting config values with AES-GCM
+type AESKey []byte
+
+// NewAESKey generates a random key
+func NewAESKey() (AESKey, error) {
+	key := make(AESKey, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to generate key", err)
+	}
+	return key, nil
+}
+
+// KeyFromEnv decodes the key in GITTYAI_CONFIG_KEY
+func KeyFromEnv() (AESKey, error) {
+	encoded := os.Getenv(KeyVar)
+	if encoded == "" {
+		return nil, errors.MissingConfig(KeyVar)
+	}
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+	if err != nil || len(key) != 32 {
+		return nil, errors.InvalidConfig(KeyVar, "must be a base64-encoded 256-bit key")
+	}
+	return key, nil
+}
+
+// String returns the key base64-encoded, as GITTYAI_CONFIG_KEY expects
+func (k AESKey) String() string {
+	return base64.StdEncoding.EncodeToString(k)
+}
+
+// gcm returns the AES-GCM mode of the key
+func (k AESKey) gcm() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid config key", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+// Encrypt seals plaintext, prefixed with a random nonce
+func (k AESKey) Encrypt(plaintext []byte) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to generate nonce", err)
+	}
+	return gcm.Seal(nonce, nonce, plaintext, nil), nil
+}
+
+// Decrypt opens ciphertext sealed by Encrypt
+func (k AESKey) Decrypt(ciphertext []byte) ([]byte, error) {
+	gcm, err := k.gcm()
+	if err != nil {
+		return nil, err
+	}
+	if len(ciphertext) < gcm.NonceSize() {
+		return nil, errors.New(errors.ErrInvalidConfig, "ciphertext is too short")
+	}
+	nonce, sealed := ciphertext[:gcm.NonceSize()], ciphertext[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, sealed, nil)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to decrypt value; wrong key or corrupted ciphertext", err)
+	}
+	return plaintext, nil
+}
+
+// EncryptValue encrypts a config value into the enc[...] form LoadYAML
+// decrypts
+func EncryptValue(c Cipher, plaintext string) (string, error) {
+	ciphertext, err := c.Encrypt([]byte(plaintext))
+	if err != nil {
+		return "", err
+	}
+	return "enc[" + base64.StdEncoding.EncodeToString(ciphertext) + "]", nil
+}
+
+// encrypted returns the base64 ciphertext of an enc[...] value
+func encrypted(s string) (string, bool) {
+	if strings.HasPrefix(s, "enc[") && strings.HasSuffix(s, "]") {
+		return s[len("enc[") : len(s)-1], true
+	}
+	return "", false
+}
+
+// decryptValues replaces the enc[...] strings of a parsed config document
+// with their plaintext. The cipher is loaded only if a value is encrypted.
+func decryptValues(doc map[string]interface{}) error {
+	var c Cipher
+	var decrypt func(path string, v interface{}) (interface{}, error)
+	decrypt = func(path string, v interface{}) (interface{}, error) {
+		switch v := v.(type) {
+		case string:
+			encoded, ok := encrypted(v)
+			if !ok {
+				return v, nil
+			}
+			if c == nil {
+				var err error
+				if c, err = loadCipher(); err != nil {
+					return nil, errors.Wrap(errors.ErrMissingConfig, "config has encrypted values but no key", err).WithContext("field", path)
+				}
+			}
+			ciphertext, err := base64.StdEncoding.DecodeString(encoded)
+			if err != nil {
+				return nil, errors.InvalidField(path, "encrypted value is not base64")
+			}
+			plaintext, err := c.Decrypt(ciphertext)
+			if err != nil {
+				return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to decrypt "+path, err).WithContext("field", path)
+			}
+			return string(plaintext), nil
+		case map[string]interface{}:
+			for key, item := range v {
+				value, err := decrypt(joinPath(path, key), item)
+				if err != nil {
+					return nil, err
+				}
+				v[key] = value
+			}
+		case []interface{}:
+			for i, item := range v {
+				value, err := decrypt(fmt.Sprintf("%s[%d]", path, i), item)
+				if err != nil {
+					return nil, err
+				}
+				v[i] = value
+			}
+		}
+		return v, nil
+	}
+	_, err := decrypt("", doc)
+	return err
+}
+
+// joinPath appends a key to a dotted field path
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/orchestrator"
+)
+
+// DefaultWatchInterval is how often a Watcher checks a config file
+const DefaultWatchInterval = 5 * time.Second
+
+// DefaultRemoteWatchInterval is how often a Watcher checks a remote config,
+// which is fetched, or cloned for git sources, on every check
+const DefaultRemoteWatchInterval = time.Minute
+
+// retireTimeout bounds shutting down a replaced orchestrator
+const retireTimeout = 30 * time.Second
+
+// WatchConfig represents the configuration for a Watcher
+type WatchConfig struct {
+	Path        string        // Config file or URL, as accepted by LoadYAML
+	Environment string        // Environment to apply (default: GITTYAI_ENV)
+	Interval    time.Duration // How often the source is checked (default: DefaultWatchInterval, DefaultRemoteWatchInterval for URLs)
+	Events      events.Sink   // Optional: receives config.reloaded and config.reload_failed events
+
+	// Build creates the orchestrator of a loaded project, e.g. to set an
+	// event sink or tools (default: NewBuilder(project).Build())
+	Build func(project *Project) (*orchestrator.Orchestrator, error)
+}
+
+// Watcher keeps an orchestrator built from a config source up to date for
+// long-running services. Each change of the source is loaded, validated
+// and built into a new orchestrator, which replaces the current one for
+// subsequent runs; runs started with Kickoff keep the orchestrator they
+// started with, which is shut down once they finish. A change that fails
+// to load or build leaves the previous config active, and is not built
+// again until the source changes.
+type Watcher struct {
+	config    WatchConfig
+	current   atomic.Pointer[watched]
+	mu        sync.Mutex // Serializes reloads
+	data      []byte     // Content of the active config
+	failed    []byte     // Content that last failed to load or build
+	failedErr error
+}
+
+// watched is an orchestrator and the project it was built from
+type watched struct {
+	project *Project
+	orch    *orchestrator.Orchestrator
+
+	mu      sync.Mutex
+	runs    int  // Runs started with Watcher.Kickoff still in flight
+	retired bool // Replaced by a newer config
+}
+
+// NewWatcher loads and builds the config once; the initial config must be
+// valid. Call Run to watch for changes.
+func NewWatcher(cfg WatchConfig) (*Watcher, error) {
+	if cfg.Environment == "" {
+		cfg.Environment = os.Getenv(EnvironmentVar)
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = DefaultWatchInterval
+		if isRemote(cfg.Path) {
+			cfg.Interval = DefaultRemoteWatchInterval
+		}
+	}
+	if cfg.Build == nil {
+		cfg.Build = func(project *Project) (*orchestrator.Orchestrator, error) {
+			return NewBuilder(project).Build()
+		}
+	}
+
+	w := &Watcher{config: cfg}
+	data, warnings, err := readConfig(cfg.Path)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.swap(data, warnings); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// Orchestrator returns the orchestrator of the active config. Runs started
+// on it directly are not tracked, so a reload may shut it down while they
+// are in flight; start runs with Kickoff instead.
+func (w *Watcher) Orchestrator() *orchestrator.Orchestrator {
+	return w.current.Load().orch
+}
+
+// Kickoff runs the orchestrator of the active config. A reload during the
+// run does not affect it; the replaced orchestrator is shut down once its
+// runs finish.
+func (w *Watcher) Kickoff(ctx context.Context) ([]*orchestrator.TaskResult, error) {
+	current := w.acquire()
+	defer current.release()
+	return current.orch.Kickoff(ctx)
+}
+
+// acquire registers a run on the active orchestrator
+func (w *Watcher) acquire() *watched {
+	for {
+		current := w.current.Load()
+		current.mu.Lock()
+		if !current.retired {
+			current.runs++
+			current.mu.Unlock()
+			return current
+		}
+		// Replaced since it was loaded; the newer one is active
+		current.mu.Unlock()
+	}
+}
+
+// release unregisters a run, shutting the orchestrator down when it was
+// replaced and this was its last run
+func (c *watched) release() {
+	c.mu.Lock()
+	c.runs--
+	idle := c.retired && c.runs == 0
+	c.mu.Unlock()
+	if idle {
+		c.shutdown()
+	}
+}
+
+// retire marks the orchestrator replaced, shutting it down when no run is
+// in flight
+func (c *watched) retire() {
+	c.mu.Lock()
+	c.retired = true
+	idle := c.runs == 0
+	c.mu.Unlock()
+	if idle {
+		c.shutdown()
+	}
+}
+
+// shutdown shuts the orchestrator down, flushing its agents' memories
+func (c *watched) shutdown() {
+	ctx, cancel := context.WithTimeout(context.Background(), retireTimeout)
+	defer cancel()
+	_, _ = c.orch.Shutdown(ctx)
+}
+
+// Project returns the active config
+func (w *Watcher) Project() *Project {
+	return w.current.Load().project
+}
+
+// Run checks the config source every interval and reloads it when its
+// content changes, until ctx is done
+func (w *Watcher) Run(ctx context.Context) error {
+	ticker := time.NewTicker(w.config.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			_, _ = w.Reload(ctx)
+		}
+	}
+}
+
+// Reload reads the config source and, if its content changed, rebuilds the
+// orchestrator. It reports whether the config was replaced; on error the
+// previous config stays active. Content that already failed returns its
+// error again without being rebuilt or reported as an event.
+func (w *Watcher) Reload(ctx context.Context) (bool, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	data, warnings, err := readConfig(w.config.Path)
+	if err == nil && bytes.Equal(data, w.data) {
+		return false, nil
+	}
+	if err == nil && w.failed != nil && bytes.Equal(data, w.failed) {
+		return false, w.failedErr
+	}
+	if err == nil {
+		if err = w.swap(data, warnings); err != nil {
+			w.failed, w.failedErr = data, err
+		}
+	}
+
+	event := events.New(events.ConfigReloaded, "")
+	event.Source = w.config.Path
+	if err != nil {
+		event.Type = events.ConfigReloadFailed
+		event.Error = err.Error()
+		event.ErrorFingerprint = errors.Fingerprint(err)
+	}
+	if w.config.Events != nil {
+		_ = w.config.Events.Publish(ctx, event)
+	}
+	return err == nil, err
+}
+
+// swap builds the config data and makes it active
+func (w *Watcher) swap(data []byte, warnings []Warning) error {
+	project, err := parseYAML(data, w.config.Path, w.config.Environment)
+	if err != nil {
+		return err
+	}
+	project.Warnings = warnings
+	orch, err := w.config.Build(project)
+	if err != nil {
+		return err
+	}
+	if previous := w.current.Swap(&watched{project: project, orch: orch}); previous != nil {
+		previous.retire()
+	}
+	w.data, w.failed, w.failedErr = data, nil, nil
+	return nil
+}
@@ -3,20 +3,73 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/postprocess"
 )
 
-// LoadYAML loads and parses a YAML configuration file
+// LoadYAML loads and parses a YAML configuration file, applying the
+// environment named by the GITTYAI_ENV variable, if set. The path may also
+// be an https://, s3:// or git:: URL, optionally pinned with a
+// "checksum=sha256:<hex>" query parameter, or an http:// URL that must be
+// pinned; remote configs are cached.
 func LoadYAML(path string) (*Project, error) {
-	data, err := os.ReadFile(path)
+	return LoadYAMLEnv(path, os.Getenv(EnvironmentVar))
+}
+
+// LoadYAMLEnv loads and parses a YAML configuration file, applying the
+// overrides of the named environment from its environments section. An
+// empty env applies none.
+func LoadYAMLEnv(path, env string) (*Project, error) {
+	data, warnings, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	project, err := parseYAML(data, path, env)
+	if err != nil {
+		return nil, err
+	}
+	project.Warnings = warnings
+	return project, nil
+}
+
+// parseYAML parses, upgrades and validates the config read from path
+func parseYAML(data []byte, path, env string) (*Project, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to parse YAML", err)
+	}
+
+	// Upgrade older config versions to the current layout
+	migrated, err := Migrate(doc)
 	if err != nil {
-		return nil, errors.Wrap(errors.ErrMissingConfig, fmt.Sprintf("failed to read file %s", path), err).WithContext("path", path)
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to migrate config", err).WithContext("path", path)
+	}
+
+	// Apply the selected environment's overrides
+	if err := selectEnvironment(doc, env); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid project configuration", err).WithContext("path", path)
+	}
+
+	// Decrypt enc[...] values
+	if err := decryptValues(doc); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to decrypt config", err).WithContext("path", path)
+	}
+
+	// Fill agents and tasks from agent_defaults and task_defaults
+	if err := inheritDefaults(doc); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidConfig, "invalid project configuration", err)
+	}
+	if data, err = yaml.Marshal(doc); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to marshal YAML", err)
 	}
 
-	project := &Project{}
+	project := &Project{Migrated: migrated, Environment: env}
 	if err := yaml.Unmarshal(data, project); err != nil {
 		return nil, errors.Wrap(errors.ErrInvalidConfig, "failed to parse YAML", err)
 	}
@@ -53,6 +106,10 @@ func (p *Project) Validate() error {
 		return errors.RequiredField("project name")
 	}
 
+	if _, err := checkVersion(p.Version); err != nil {
+		return err
+	}
+
 	if len(p.Agents) == 0 {
 		return errors.Validation("at least one agent is required")
 	}
@@ -66,16 +123,78 @@ func (p *Project) Validate() error {
 		return errors.RequiredField("LLM provider")
 	}
 
+	switch llm.AliasPolicy(p.LLM.ModelPolicy) {
+	case "", llm.AliasPolicyWarn, llm.AliasPolicyUpgrade, llm.AliasPolicyStrict, llm.AliasPolicyIgnore:
+	default:
+		return errors.InvalidField("model_policy", "must be one of warn, upgrade, strict, ignore")
+	}
+
+	if err := validateSampling("llm", p.LLM.Temperature, p.LLM.TopP); err != nil {
+		return err
+	}
+	for name, profile := range p.Profiles {
+		var temperature, topP float32
+		if profile.Temperature != nil {
+			temperature = *profile.Temperature
+		}
+		if profile.TopP != nil {
+			topP = *profile.TopP
+		}
+		if err := validateSampling("profile "+name, temperature, topP); err != nil {
+			return err
+		}
+	}
+
 	// Validate agents
 	agentNames := make(map[string]bool)
+	agentSkills := make(map[string][]string)
 	for _, agent := range p.Agents {
 		if agent.Name == "" {
 			return errors.RequiredField("agent name")
 		}
-		if agentNames[agent.Name] {
-			return errors.Validationf("duplicate agent name: %s", agent.Name)
+		resolved, err := agent.withPreset()
+		if err != nil {
+			return errors.Validationf("agent '%s' references unknown preset: %s", agent.Name, agent.Preset)
+		}
+		if agentNames[resolved.Name] {
+			return errors.Validationf("duplicate agent name: %s", resolved.Name)
+		}
+		agentNames[resolved.Name] = true
+		agentSkills[resolved.Name] = resolved.Skills
+		if resolved.Profile != "" {
+			if _, err := p.Profile(resolved.Profile); err != nil {
+				return errors.Validationf("agent '%s' references unknown profile: %s", resolved.Name, resolved.Profile)
+			}
+		}
+	}
+
+	if err := validateMemory("memory", p.Memory); err != nil {
+		return err
+	}
+	for _, agent := range p.Agents {
+		if agent.Memory != nil {
+			if err := validateMemory("agent '"+agent.Name+"' memory", *agent.Memory); err != nil {
+				return err
+			}
+		}
+	}
+
+	if p.Quotas != nil {
+		for name := range p.Quotas.Agents {
+			if !agentNames[name] {
+				return errors.Validationf("quota references non-existent agent: %s", name)
+			}
+		}
+	}
+
+	// Validate prompts
+	for _, prompt := range p.Prompts {
+		if prompt.Name == "" {
+			return errors.RequiredField("prompt name")
+		}
+		if prompt.Version == "" {
+			return errors.RequiredField("prompt version").WithContext("prompt", prompt.Name)
 		}
-		agentNames[agent.Name] = true
 	}
 
 	// Validate tasks
@@ -89,7 +208,86 @@ func (p *Project) Validate() error {
 		if !agentNames[task.Agent] {
 			return errors.Validationf("task references non-existent agent: %s", task.Agent)
 		}
+		if missing := missingSkills(agentSkills[task.Agent], task.Skills); len(missing) > 0 {
+			return errors.Validationf("agent '%s' lacks skills required by task: %s", task.Agent, strings.Join(missing, ", "))
+		}
+		if task.Profile != "" {
+			if _, err := p.Profile(task.Profile); err != nil {
+				return errors.Validationf("task references unknown profile: %s", task.Profile)
+			}
+		}
+		if _, err := BuildPostProcess(task.PostProcess); err != nil {
+			return err
+		}
+		if _, err := BuildOutputFormat(task.OutputFormat); err != nil {
+			return err
+		}
+	}
+
+	if _, err := orchestrator.ParseProcess(p.Execution.Process); err != nil {
+		return err
+	}
+
+	if p.Execution.AbortSeverity != "" {
+		if _, err := errors.ParseSeverity(p.Execution.AbortSeverity); err != nil {
+			return errors.InvalidField("execution.abort_severity", "must be one of low, medium, high, critical").WithContext("value", p.Execution.AbortSeverity)
+		}
+	}
+
+	if _, err := BuildPostProcess(p.Execution.PostProcess); err != nil {
+		return err
+	}
+	// Run-level processors apply to every task, which must not share a file
+	for i, step := range p.Execution.PostProcess {
+		if step.Type == PostProcessWriteFile && !strings.Contains(step.Path, postprocess.KeyPlaceholder) {
+			return errors.InvalidField("execution.post_process path", "must contain "+postprocess.KeyPlaceholder+" so each task writes its own file").
+				WithContext("step", i).
+				WithContext("path", step.Path)
+		}
+	}
+
+	return nil
+}
+
+// validateSampling rejects sampling parameters no provider accepts;
+// provider-specific ranges are reported by Lint
+func validateSampling(source string, temperature, topP float32) error {
+	if temperature < 0 || temperature > 2 {
+		return errors.InvalidField("temperature", "must be between 0 and 2").WithContext("source", source).WithContext("value", temperature)
+	}
+	if topP < 0 || topP > 1 {
+		return errors.InvalidField("top_p", "must be between 0 and 1").WithContext("source", source).WithContext("value", topP)
 	}
+	return nil
+}
 
+// validateMemory rejects unknown memory scopes and negative half-lives
+func validateMemory(source string, m MemoryConfig) error {
+	switch m.Scope {
+	case "", MemoryScopeAgent, MemoryScopeShared:
+	default:
+		return errors.InvalidField("memory.scope", "must be agent or shared").WithContext("source", source).WithContext("value", m.Scope)
+	}
+	if m.HalfLife < 0 {
+		return errors.InvalidField("memory.half_life", "must not be negative").WithContext("source", source).WithContext("value", m.HalfLife.String())
+	}
 	return nil
 }
+
+// missingSkills returns the required skills not present in have
+func missingSkills(have, required []string) []string {
+	var missing []string
+	for _, want := range required {
+		found := false
+		for _, skill := range have {
+			if strings.EqualFold(skill, want) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, want)
+		}
+	}
+	return missing
+}
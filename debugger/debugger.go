@@ -0,0 +1,155 @@
+// Package debugger pauses a run before each task and LLM call, so the
+// pending prompt can be inspected, edited, skipped or answered with a fake
+// response while developing a crew.
+package debugger
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Kind is the kind of step a run is paused at
+type Kind string
+
+const (
+	KindTask Kind = "task" // Before a task is executed; Prompt is its description
+	KindLLM  Kind = "llm"  // Before an LLM call; Prompt is the assembled prompt
+)
+
+// Step describes the pending task or LLM call
+type Step struct {
+	Kind   Kind
+	RunID  string
+	Agent  string // "manager" for calls of the hierarchical manager
+	Prompt string
+}
+
+// Action is what to do with a paused step
+type Action int
+
+const (
+	// Continue runs the step, with Decision.Prompt when it is set
+	Continue Action = iota
+	// Skip does not run the step: a skipped task has no result and a
+	// skipped LLM call returns an empty response
+	Skip
+	// Respond does not run the step and uses Decision.Response as its result
+	Respond
+	// Abort fails the run
+	Abort
+)
+
+// Decision is the outcome of a pause
+type Decision struct {
+	Action   Action
+	Prompt   string // Replacement prompt for Continue; empty keeps the original
+	Response string // Fake response for Respond
+}
+
+// Hook is called before each step. Returning an error aborts the run.
+type Hook func(ctx context.Context, step Step) (Decision, error)
+
+// contextKey is the context key for the hook
+type contextKey struct{}
+
+// NewContext returns a context carrying the hook
+func NewContext(ctx context.Context, hook Hook) context.Context {
+	return context.WithValue(ctx, contextKey{}, hook)
+}
+
+// FromContext returns the hook carried by ctx, or nil if none
+func FromContext(ctx context.Context) Hook {
+	hook, _ := ctx.Value(contextKey{}).(Hook)
+	return hook
+}
+
+// Pause passes step to the hook carried by ctx. Without a hook it continues
+// with the original prompt. An Abort decision is returned as an error.
+func Pause(ctx context.Context, step Step) (Decision, error) {
+	hook := FromContext(ctx)
+	if hook == nil {
+		return Decision{Action: Continue, Prompt: step.Prompt}, nil
+	}
+
+	decision, err := hook(ctx, step)
+	if err == nil && decision.Action == Abort {
+		err = errors.New(errors.ErrPolicyViolation, "run aborted by debugger")
+	}
+	if err != nil {
+		return Decision{}, errors.Wrap(errors.ErrPolicyViolation, "debugger stopped the run", err).
+			WithContext("step", string(step.Kind)).
+			WithContext("agent", step.Agent)
+	}
+	if decision.Prompt == "" {
+		decision.Prompt = step.Prompt
+	}
+	return decision, nil
+}
+
+// Prompt returns a Hook that shows each step on out and reads commands
+// from in. Steps of parallel tasks are shown one at a time:
+//
+//	c             continue
+//	s             skip the step
+//	r text        use text as the response
+//	e text        replace the prompt with text and continue
+//	p             print the full prompt
+//	q             abort the run
+func Prompt(in io.Reader, out io.Writer) Hook {
+	scanner := bufio.NewScanner(in)
+	var mu sync.Mutex // Parallel tasks pause one at a time
+	return func(ctx context.Context, step Step) (Decision, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "\n[Debug] Paused before %s (agent: %s, %d chars)\n", step.Kind, step.Agent, len([]rune(step.Prompt)))
+		fmt.Fprintln(out, preview(step.Prompt))
+		for {
+			fmt.Fprint(out, "[c]ontinue, [s]kip, r text, e text, [p]rint, [q]uit: ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return Decision{}, err
+				}
+				return Decision{}, errors.New(errors.ErrPolicyViolation, "no debugger command received")
+			}
+			if err := ctx.Err(); err != nil {
+				return Decision{}, err
+			}
+
+			cmd, text, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+			switch strings.ToLower(cmd) {
+			case "", "c", "continue":
+				return Decision{Action: Continue}, nil
+			case "s", "skip":
+				return Decision{Action: Skip}, nil
+			case "r":
+				return Decision{Action: Respond, Response: strings.TrimSpace(text)}, nil
+			case "e":
+				return Decision{Action: Continue, Prompt: strings.TrimSpace(text)}, nil
+			case "p", "print":
+				fmt.Fprintln(out, step.Prompt)
+			case "q", "quit":
+				return Decision{Action: Abort}, nil
+			default:
+				fmt.Fprintf(out, "Unknown command %q\n", cmd)
+			}
+		}
+	}
+}
+
+// previewLength is the number of prompt characters shown when pausing
+const previewLength = 500
+
+// preview shortens a prompt for display
+func preview(prompt string) string {
+	runes := []rune(prompt)
+	if len(runes) <= previewLength {
+		return prompt
+	}
+	return string(runes[:previewLength]) + fmt.Sprintf("... (%d more chars, p to print)", len(runes)-previewLength)
+}
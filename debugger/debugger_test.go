@@ -0,0 +1,71 @@
+package debugger
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestPrompt_Commands(t *testing.T) {
+	in := strings.NewReader("x\np\ne shorter prompt\nr fake answer\ns\nq\n")
+	var out strings.Builder
+	hook := Prompt(in, &out)
+	ctx := NewContext(context.Background(), hook)
+	step := Step{Kind: KindLLM, Agent: "writer", Prompt: "original prompt"}
+
+	decision, err := Pause(ctx, step)
+	if err != nil || decision.Action != Continue || decision.Prompt != "shorter prompt" {
+		t.Fatalf("Pause() = %+v, %v; want Continue with the edited prompt", decision, err)
+	}
+	if !strings.Contains(out.String(), `Unknown command "x"`) || strings.Count(out.String(), "original prompt") != 2 {
+		t.Errorf("output = %q, want unknown command notice and printed prompt", out.String())
+	}
+
+	if decision, _ := Pause(ctx, step); decision.Action != Respond || decision.Response != "fake answer" || decision.Prompt != "original prompt" {
+		t.Errorf("Pause() = %+v, want Respond with the fake answer", decision)
+	}
+	if decision, _ := Pause(ctx, step); decision.Action != Skip {
+		t.Errorf("Pause() = %+v, want Skip", decision)
+	}
+	if _, err := Pause(ctx, step); !errors.HasCode(err, errors.ErrPolicyViolation) {
+		t.Errorf("Pause() after q error = %v, want ErrPolicyViolation", err)
+	}
+}
+
+func TestPrompt_Parallel(t *testing.T) {
+	const steps = 8
+	in := strings.NewReader(strings.Repeat("p\nc\n", steps))
+	var out strings.Builder
+	ctx := NewContext(context.Background(), Prompt(in, &out))
+
+	var wg sync.WaitGroup
+	for i := 0; i < steps; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if decision, err := Pause(ctx, Step{Kind: KindLLM, Agent: "writer", Prompt: fmt.Sprintf("prompt %d", i)}); err != nil || decision.Action != Continue {
+				t.Errorf("Pause() = %+v, %v; want Continue", decision, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// Each pause shows its prompt, then prints it again, uninterrupted
+	for i := 0; i < steps; i++ {
+		prompt := fmt.Sprintf("prompt %d", i)
+		if want := prompt + "\n[c]ontinue, [s]kip, r text, e text, [p]rint, [q]uit: " + prompt + "\n"; !strings.Contains(out.String(), want) {
+			t.Errorf("output does not show %q uninterrupted:\n%s", prompt, out.String())
+		}
+	}
+}
+
+func TestPause_WithoutHook(t *testing.T) {
+	decision, err := Pause(context.Background(), Step{Kind: KindTask, Prompt: "task"})
+	if err != nil || decision.Action != Continue || decision.Prompt != "task" {
+		t.Errorf("Pause() = %+v, %v; want Continue with the original prompt", decision, err)
+	}
+}
@@ -0,0 +1,218 @@
+// Package diff compares task results and runs. Textual diffs show the lines
+// that changed; an optional LLM judge summarizes how the meaning changed,
+// which is what matters for A/B comparisons and regression alerts.
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Op is the kind of an edit
+type Op int
+
+// Edit operations
+const (
+	Equal Op = iota
+	Delete
+	Insert
+)
+
+// String returns the prefix of the operation in unified diffs
+func (o Op) String() string {
+	switch o {
+	case Delete:
+		return "-"
+	case Insert:
+		return "+"
+	default:
+		return " "
+	}
+}
+
+// Edit is one line of a diff
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// maxCells bounds the work of the line matching; larger inputs are diffed
+// as a full replacement of their differing middle
+const maxCells = 4_000_000
+
+// Text returns the line edits turning a into b
+func Text(a, b string) []Edit {
+	linesA := splitLines(a)
+	linesB := splitLines(b)
+
+	// Common prefix and suffix need no matching
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix &&
+		linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	edits := make([]Edit, 0, len(linesA)+len(linesB))
+	for _, line := range linesA[:prefix] {
+		edits = append(edits, Edit{Op: Equal, Text: line})
+	}
+	edits = append(edits, lcs(linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix])...)
+	for _, line := range linesA[len(linesA)-suffix:] {
+		edits = append(edits, Edit{Op: Equal, Text: line})
+	}
+	return edits
+}
+
+// lcs diffs two line slices through their longest common subsequence
+func lcs(a, b []string) []Edit {
+	var edits []Edit
+	if len(a)*len(b) > maxCells {
+		for _, line := range a {
+			edits = append(edits, Edit{Op: Delete, Text: line})
+		}
+		for _, line := range b {
+			edits = append(edits, Edit{Op: Insert, Text: line})
+		}
+		return edits
+	}
+
+	// length[i][j] is the LCS length of a[i:] and b[j:]
+	length := make([][]int, len(a)+1)
+	for i := range length {
+		length[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				length[i][j] = length[i+1][j+1] + 1
+			} else {
+				length[i][j] = max(length[i+1][j], length[i][j+1])
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Op: Equal, Text: a[i]})
+			i++
+			j++
+		case length[i+1][j] >= length[i][j+1]:
+			edits = append(edits, Edit{Op: Delete, Text: a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Op: Insert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Op: Delete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Op: Insert, Text: b[j]})
+	}
+	return edits
+}
+
+// Similarity is the share of lines the edits keep, from 0 to 1
+func Similarity(edits []Edit) float64 {
+	if len(edits) == 0 {
+		return 1
+	}
+	equal, total := 0, 0
+	for _, e := range edits {
+		if e.Op == Equal {
+			equal++
+			total += 2
+		} else {
+			total++
+		}
+	}
+	return float64(2*equal) / float64(total)
+}
+
+// Unified formats edits as a unified diff with context unchanged lines
+// around each change. It returns "" when nothing changed.
+func Unified(edits []Edit, context int) string {
+	if context < 0 {
+		context = 0
+	}
+
+	var b strings.Builder
+	lineA, lineB := 1, 1 // Line numbers of edits[i] in each text
+	for i := 0; i < len(edits); {
+		if edits[i].Op == Equal {
+			lineA++
+			lineB++
+			i++
+			continue
+		}
+
+		// A hunk starts context lines before the change and ends when more
+		// than 2*context unchanged lines follow a change
+		start := max(i-context, 0)
+		for k := start; k < i; k++ {
+			lineA--
+			lineB--
+		}
+		end := i
+		for end < len(edits) {
+			if edits[end].Op != Equal {
+				end++
+				continue
+			}
+			run := end
+			for run < len(edits) && edits[run].Op == Equal {
+				run++
+			}
+			if run == len(edits) || run-end > 2*context {
+				end = min(end+context, len(edits))
+				break
+			}
+			end = run
+		}
+
+		countA, countB := 0, 0
+		for _, e := range edits[start:end] {
+			if e.Op != Insert {
+				countA++
+			}
+			if e.Op != Delete {
+				countB++
+			}
+		}
+		fmt.Fprintf(&b, "@@ -%s +%s @@\n", hunkRange(lineA, countA), hunkRange(lineB, countB))
+		for _, e := range edits[start:end] {
+			fmt.Fprintf(&b, "%s%s\n", e.Op, e.Text)
+		}
+		lineA += countA
+		lineB += countB
+		i = end
+	}
+	return b.String()
+}
+
+// hunkRange formats the start and length of a hunk; an empty range starts
+// at the line before it
+func hunkRange(start, count int) string {
+	if count == 0 {
+		start--
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start)
+	}
+	return fmt.Sprintf("%d,%d", start, count)
+}
+
+// splitLines splits text into lines without their terminators
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(s, "\r\n", "\n"), "\n"), "\n")
+}
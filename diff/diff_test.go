@@ -0,0 +1,84 @@
+package diff
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/task"
+)
+
+func TestUnified(t *testing.T) {
+	a := "one\ntwo\nthree\nfour\nfive\nsix\nseven\neight\nnine\n"
+	b := "one\ntwo\n3\nfour\nfive\nsix\nseven\neight\nnine\nten\n"
+
+	got := Unified(Text(a, b), 1)
+	want := "@@ -2,3 +2,3 @@\n two\n-three\n+3\n four\n@@ -9 +9,2 @@\n nine\n+ten\n"
+	if got != want {
+		t.Errorf("Unified() =\n%s\nwant\n%s", got, want)
+	}
+	if got := Unified(Text(a, a), 3); got != "" {
+		t.Errorf("Unified() of equal texts = %q, want empty", got)
+	}
+	if got := Similarity(Text(a, b)); got < 0.8 || got >= 1 {
+		t.Errorf("Similarity() = %.2f", got)
+	}
+}
+
+type verdictLLM struct {
+	verdict string
+	calls   *int
+}
+
+func (v verdictLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	*v.calls++
+	return v.verdict, nil
+}
+
+func result(description, agent, output string) *orchestrator.TaskResult {
+	return &orchestrator.TaskResult{
+		Task:   task.New(task.Config{Description: description}),
+		Agent:  agent,
+		Status: orchestrator.StatusCompleted,
+		Result: output,
+	}
+}
+
+func TestDiffer_Runs(t *testing.T) {
+	calls := 0
+	d := New(Config{Judge: verdictLLM{verdict: "DIFFERENT: B drops the pricing section.", calls: &calls}})
+	a := []*orchestrator.TaskResult{
+		result("Research", "researcher", "Prices rose 5%.\nDemand is flat."),
+		result("Write", "writer", "Draft"),
+		result("Review", "editor", "Approved"),
+	}
+	b := []*orchestrator.TaskResult{
+		result("Write", "writer", "Draft"),
+		result("Research", "researcher", "Demand is flat."),
+		result("Translate", "writer", "Brouillon"),
+		result("Publish", "writer", "Published"),
+	}
+
+	run, err := d.Runs(context.Background(), a, b)
+	if err != nil {
+		t.Fatalf("Runs() unexpected error: %v", err)
+	}
+	if len(run.Tasks) != 4 || run.Changed() != 3 {
+		t.Fatalf("Runs() = %d tasks, %d changed; want 4 and 3", len(run.Tasks), run.Changed())
+	}
+	research := run.Tasks[0]
+	if !research.Judged || research.Equivalent || research.Summary != "B drops the pricing section." {
+		t.Errorf("research diff = %+v", research)
+	}
+	if calls != 2 {
+		t.Errorf("judge called %d times, want only for the 2 changed results", calls)
+	}
+	// Review has no match by description and takes the free position
+	if !strings.Contains(run.String(), "Review -> Translate [similarity") || !run.Tasks[3].OnlyB {
+		t.Errorf("String() =\n%s", run)
+	}
+	if len(run.Diverged()) != 3 {
+		t.Errorf("Diverged() = %d tasks, want 3", len(run.Diverged()))
+	}
+}
@@ -0,0 +1,256 @@
+package diff
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/orchestrator"
+)
+
+// DefaultContext is the number of unchanged lines shown around changes
+const DefaultContext = 3
+
+// Config configures a Differ
+type Config struct {
+	// Judge, when set, summarizes how the meaning of changed results differs
+	Judge llm.LLM
+	// Context is the number of unchanged lines shown around changes
+	// (0 = DefaultContext, negative = none)
+	Context int
+}
+
+// Differ compares task results and runs
+type Differ struct {
+	judge   llm.LLM
+	context int
+}
+
+// New creates a differ
+func New(cfg Config) *Differ {
+	switch {
+	case cfg.Context == 0:
+		cfg.Context = DefaultContext
+	case cfg.Context < 0:
+		cfg.Context = 0
+	}
+	return &Differ{judge: cfg.Judge, context: cfg.Context}
+}
+
+// ResultDiff compares the results of one task in runs A and B
+type ResultDiff struct {
+	Task    string // Task description, "A -> B" when it changed
+	AgentA  string
+	AgentB  string
+	StatusA orchestrator.TaskStatus
+	StatusB orchestrator.TaskStatus
+	A       string
+	B       string
+	Edits   []Edit
+	// Similarity is the share of lines the results have in common, from 0 to 1
+	Similarity float64
+	// OnlyA and OnlyB are set when the task ran in one run only
+	OnlyA bool
+	OnlyB bool
+
+	// Judged is set when the judge compared the results; Equivalent is its
+	// verdict and Summary describes the differences in meaning
+	Judged     bool
+	Equivalent bool
+	Summary    string
+}
+
+// Changed reports whether the task's outcome differs between the runs
+func (d *ResultDiff) Changed() bool {
+	return d.OnlyA || d.OnlyB || d.StatusA != d.StatusB || d.AgentA != d.AgentB || d.A != d.B
+}
+
+// RunDiff compares two runs task by task
+type RunDiff struct {
+	Tasks   []*ResultDiff
+	context int
+}
+
+// Changed returns the number of tasks whose outcome differs
+func (r *RunDiff) Changed() int {
+	n := 0
+	for _, d := range r.Tasks {
+		if d.Changed() {
+			n++
+		}
+	}
+	return n
+}
+
+// Diverged returns the changed tasks the judge did not find equivalent,
+// the candidates for a regression alert. Without a judge every changed task
+// is returned.
+func (r *RunDiff) Diverged() []*ResultDiff {
+	var diverged []*ResultDiff
+	for _, d := range r.Tasks {
+		if d.Changed() && !(d.Judged && d.Equivalent) {
+			diverged = append(diverged, d)
+		}
+	}
+	return diverged
+}
+
+// String formats the comparison with a unified diff of each changed result
+func (r *RunDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d tasks changed\n", r.Changed(), len(r.Tasks))
+	for i, d := range r.Tasks {
+		fmt.Fprintf(&b, "\n%d. %s", i+1, d.Task)
+		switch {
+		case d.OnlyA:
+			b.WriteString(" [only in A]\n")
+			continue
+		case d.OnlyB:
+			b.WriteString(" [only in B]\n")
+			continue
+		case !d.Changed():
+			b.WriteString(" [unchanged]\n")
+			continue
+		}
+		fmt.Fprintf(&b, " [similarity %.2f]\n", d.Similarity)
+		if d.AgentA != d.AgentB {
+			fmt.Fprintf(&b, "agent: %s -> %s\n", d.AgentA, d.AgentB)
+		}
+		if d.StatusA != d.StatusB {
+			fmt.Fprintf(&b, "status: %s -> %s\n", d.StatusA, d.StatusB)
+		}
+		if d.Judged {
+			if d.Equivalent {
+				b.WriteString("judge: equivalent\n")
+			} else {
+				fmt.Fprintf(&b, "judge: %s\n", d.Summary)
+			}
+		}
+		b.WriteString(Unified(d.Edits, r.context))
+	}
+	return b.String()
+}
+
+// Results compares two results of the same task. The judge, if any, is
+// asked about changed results only.
+func (d *Differ) Results(ctx context.Context, a, b *orchestrator.TaskResult) (*ResultDiff, error) {
+	if a == nil && b == nil {
+		return nil, errors.RequiredField("result")
+	}
+	diff := &ResultDiff{}
+	switch {
+	case a == nil:
+		diff.Task, diff.AgentB, diff.StatusB, diff.B, diff.OnlyB = b.Title(), b.Agent, b.Status, b.Result, true
+		return diff, nil
+	case b == nil:
+		diff.Task, diff.AgentA, diff.StatusA, diff.A, diff.OnlyA = a.Title(), a.Agent, a.Status, a.Result, true
+		return diff, nil
+	}
+
+	diff.Task = b.Title()
+	if a.Title() != diff.Task {
+		diff.Task = a.Title() + " -> " + diff.Task
+	}
+	diff.AgentA, diff.AgentB = a.Agent, b.Agent
+	diff.StatusA, diff.StatusB = a.Status, b.Status
+	diff.A, diff.B = a.Result, b.Result
+	diff.Edits = Text(a.Result, b.Result)
+	diff.Similarity = Similarity(diff.Edits)
+	if d.judge != nil && diff.A != diff.B {
+		if err := d.judgeResults(ctx, diff); err != nil {
+			return nil, err
+		}
+	}
+	return diff, nil
+}
+
+// Runs compares the results of two runs. Tasks are matched by description,
+// falling back to position for tasks whose description changed.
+func (d *Differ) Runs(ctx context.Context, a, b []*orchestrator.TaskResult) (*RunDiff, error) {
+	a = nonNil(a)
+	b = nonNil(b)
+	run := &RunDiff{context: d.context}
+
+	used := make([]bool, len(b))
+	for i, ra := range a {
+		j := match(ra, i, b, used)
+		var rb *orchestrator.TaskResult
+		if j >= 0 {
+			used[j] = true
+			rb = b[j]
+		}
+		diff, err := d.Results(ctx, ra, rb)
+		if err != nil {
+			return nil, err
+		}
+		run.Tasks = append(run.Tasks, diff)
+	}
+	for j, rb := range b {
+		if !used[j] {
+			diff, _ := d.Results(ctx, nil, rb)
+			run.Tasks = append(run.Tasks, diff)
+		}
+	}
+	return run, nil
+}
+
+// judgePrompt asks the judge to compare two results of a task
+const judgePrompt = `Two versions of the result of a task follow. Compare what they say, not how they are worded.
+
+Task: %s
+
+--- Version A ---
+%s
+
+--- Version B ---
+%s
+
+If both versions convey the same information and conclusions, reply with EQUIVALENT only.
+Otherwise reply with DIFFERENT followed by one or two sentences summarizing what changed in meaning.`
+
+// judgeResults asks the judge whether the results differ in meaning
+func (d *Differ) judgeResults(ctx context.Context, diff *ResultDiff) error {
+	answer, err := d.judge.Generate(ctx, fmt.Sprintf(judgePrompt, diff.Task, diff.A, diff.B))
+	if err != nil {
+		return errors.Wrap(errors.ErrAPIResponse, "failed to judge result diff", err).WithContext("task", diff.Task)
+	}
+	answer = strings.TrimSpace(answer)
+	upper := strings.ToUpper(answer)
+	switch {
+	case strings.HasPrefix(upper, "EQUIVALENT"):
+		diff.Equivalent = true
+		answer = ""
+	case strings.HasPrefix(upper, "DIFFERENT"):
+		answer = strings.TrimLeft(answer[len("DIFFERENT"):], " :.-\n")
+	}
+	diff.Judged = true
+	diff.Summary = answer
+	return nil
+}
+
+// match returns the index of the result in b matching a's result at
+// position i, or -1
+func match(r *orchestrator.TaskResult, i int, b []*orchestrator.TaskResult, used []bool) int {
+	for j, rb := range b {
+		if !used[j] && rb.Title() == r.Title() {
+			return j
+		}
+	}
+	if i < len(b) && !used[i] {
+		return i
+	}
+	return -1
+}
+
+// nonNil drops the nil entries of results
+func nonNil(results []*orchestrator.TaskResult) []*orchestrator.TaskResult {
+	kept := make([]*orchestrator.TaskResult, 0, len(results))
+	for _, r := range results {
+		if r != nil {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
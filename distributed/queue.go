@@ -0,0 +1,152 @@
+package distributed
+
+import (
+	"context"
+	"sync"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/task"
+)
+
+// Job is a serializable unit of work sent to workers. Agents are referenced by
+// name, so every worker must be configured with the same agents, unless the
+// job carries the agent's definition for workers with a runtime to build it.
+type Job struct {
+	ID             string   `json:"id"`
+	RunID          string   `json:"run_id,omitempty"`
+	Description    string   `json:"description"`
+	ExpectedOutput string   `json:"expected_output,omitempty"`
+	AgentName      string   `json:"agent_name"`
+	Context        []string `json:"context,omitempty"`
+
+	// Task is the exact definition of the task, including its output
+	// format; when set, it replaces the fields above
+	Task *task.Definition `json:"task,omitempty"`
+	// Agent is the definition of the agent, used by workers that have no
+	// agent of that name
+	Agent *agent.Definition `json:"agent,omitempty"`
+}
+
+// JobResult is the outcome of a job reported by a worker
+type JobResult struct {
+	JobID  string `json:"job_id"`
+	RunID  string `json:"run_id,omitempty"`
+	Worker string `json:"worker"`
+	Agent  string `json:"agent"`
+	Output string `json:"output,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+// Queue transports jobs from the orchestrator to workers and results back.
+// Network-backed implementations such as RedisQueue allow workers to run in
+// separate processes or on separate machines.
+type Queue interface {
+	// Enqueue publishes a job for any worker to pick up
+	Enqueue(ctx context.Context, job Job) error
+
+	// Dequeue blocks until a job is available or ctx is done
+	Dequeue(ctx context.Context) (Job, error)
+
+	// Complete publishes the result of a job
+	Complete(ctx context.Context, result JobResult) error
+
+	// Await blocks until the result of the given job is available or ctx is done
+	Await(ctx context.Context, jobID string) (JobResult, error)
+}
+
+// MemoryQueue is an in-process Queue, useful for tests and for running
+// workers as goroutines in the same binary
+type MemoryQueue struct {
+	jobs chan Job
+
+	mu      sync.Mutex
+	results map[string]chan JobResult
+}
+
+// NewMemoryQueue creates an in-memory queue holding up to size pending jobs
+func NewMemoryQueue(size int) *MemoryQueue {
+	if size <= 0 {
+		size = 100
+	}
+	return &MemoryQueue{
+		jobs:    make(chan Job, size),
+		results: make(map[string]chan JobResult),
+	}
+}
+
+// Enqueue publishes a job
+func (q *MemoryQueue) Enqueue(ctx context.Context, job Job) error {
+	if job.ID == "" {
+		return errors.RequiredField("job id")
+	}
+	q.resultChan(job.ID)
+
+	select {
+	case q.jobs <- job:
+		return nil
+	case <-ctx.Done():
+		q.forget(job.ID)
+		return ctx.Err()
+	}
+}
+
+// Dequeue blocks until a job is available
+func (q *MemoryQueue) Dequeue(ctx context.Context) (Job, error) {
+	select {
+	case job := <-q.jobs:
+		return job, nil
+	case <-ctx.Done():
+		return Job{}, ctx.Err()
+	}
+}
+
+// Complete publishes the result of a job. Results of jobs nobody awaits
+// anymore are dropped.
+func (q *MemoryQueue) Complete(ctx context.Context, result JobResult) error {
+	q.mu.Lock()
+	ch, ok := q.results[result.JobID]
+	q.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	select {
+	case ch <- result:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Await blocks until the result of the given job is available
+func (q *MemoryQueue) Await(ctx context.Context, jobID string) (JobResult, error) {
+	ch := q.resultChan(jobID)
+	select {
+	case result := <-ch:
+		q.forget(jobID)
+		return result, nil
+	case <-ctx.Done():
+		q.forget(jobID)
+		return JobResult{}, ctx.Err()
+	}
+}
+
+// forget removes the result channel of a job
+func (q *MemoryQueue) forget(jobID string) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	delete(q.results, jobID)
+}
+
+// resultChan returns the result channel for a job, creating it if needed
+func (q *MemoryQueue) resultChan(jobID string) chan JobResult {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	ch, ok := q.results[jobID]
+	if !ok {
+		ch = make(chan JobResult, 1)
+		q.results[jobID] = ch
+	}
+	return ch
+}
@@ -0,0 +1,435 @@
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// redisPollInterval bounds each blocking pop, so waiting Dequeue and Await
+// calls notice cancellation of their context
+const redisPollInterval = time.Second
+
+// RedisConfig represents the configuration for a RedisQueue
+type RedisConfig struct {
+	// Addr is the Redis server address (default: "localhost:6379")
+	Addr string
+	// Password authenticates the connection (optional)
+	Password string
+	// DB selects the logical database (default: 0)
+	DB int
+	// Prefix namespaces the queue's keys: jobs are pushed to "<prefix>:jobs"
+	// and results to "<prefix>:result:<job id>" (default: "gittyai")
+	Prefix string
+	// ResultTTL expires results nobody awaits (default: 1h)
+	ResultTTL time.Duration
+	// VisibilityTimeout is how long a dequeued job may go without a result
+	// before it is delivered again, on the assumption that its worker died.
+	// Set it above the longest job, or jobs run twice. (default: 15m)
+	VisibilityTimeout time.Duration
+	// DialTimeout bounds connection setup and each command (default: 5s)
+	DialTimeout time.Duration
+	// MaxIdle is the number of idle connections kept open (default: 4)
+	MaxIdle int
+}
+
+// RedisQueue is a Queue backed by Redis lists, so the orchestrator and its
+// workers can run in separate processes or on separate machines. Jobs are
+// pushed to one list shared by all workers and each result to a list of its
+// own, popped by the orchestrator awaiting it.
+//
+// Delivery is at least once: Dequeue moves a job to a processing list and
+// leases it for VisibilityTimeout, and Complete acknowledges it. Jobs whose
+// lease expires are put back on the job list by the next Dequeue of any
+// worker. Complete must be called on the queue that dequeued the job.
+type RedisQueue struct {
+	config RedisConfig
+	idle   chan *redisConn
+
+	mu       sync.Mutex
+	inflight map[string]string // payloads of jobs dequeued here, by job ID
+}
+
+// NewRedisQueue creates a Redis-backed queue. Connections are established
+// lazily and pooled; broken connections are dropped and redialed.
+func NewRedisQueue(cfg RedisConfig) *RedisQueue {
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:6379"
+	}
+	if cfg.Prefix == "" {
+		cfg.Prefix = "gittyai"
+	}
+	if cfg.ResultTTL <= 0 {
+		cfg.ResultTTL = time.Hour
+	}
+	if cfg.VisibilityTimeout <= 0 {
+		cfg.VisibilityTimeout = 15 * time.Minute
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.MaxIdle <= 0 {
+		cfg.MaxIdle = 4
+	}
+	return &RedisQueue{
+		config:   cfg,
+		idle:     make(chan *redisConn, cfg.MaxIdle),
+		inflight: make(map[string]string),
+	}
+}
+
+// Enqueue pushes a job onto the shared job list
+func (q *RedisQueue) Enqueue(ctx context.Context, job Job) error {
+	if job.ID == "" {
+		return errors.RequiredField("job id")
+	}
+	payload, err := json.Marshal(job)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal job", err).WithContext("job", job.ID)
+	}
+	_, err = q.do(ctx, 0, "LPUSH", q.jobsKey(), string(payload))
+	return err
+}
+
+// Dequeue blocks until a job is available, moving it to the processing list
+// and leasing it until it is completed or VisibilityTimeout passes
+func (q *RedisQueue) Dequeue(ctx context.Context) (Job, error) {
+	timeout := strconv.Itoa(int(redisPollInterval / time.Second))
+	for {
+		if err := ctx.Err(); err != nil {
+			return Job{}, err
+		}
+		if err := q.requeueExpired(ctx); err != nil {
+			return Job{}, err
+		}
+		reply, err := q.do(ctx, redisPollInterval, "BRPOPLPUSH", q.jobsKey(), q.processingKey(), timeout)
+		if err != nil {
+			return Job{}, err
+		}
+		// BRPOPLPUSH answers the element, or nil when the poll timed out
+		payload, ok := reply.(string)
+		if !ok {
+			continue
+		}
+
+		// The job is off the job list: lease it even if ctx ends now, so it
+		// is not stranded in the processing list
+		leaseCtx := context.WithoutCancel(ctx)
+		var job Job
+		if err := json.Unmarshal([]byte(payload), &job); err != nil {
+			q.ack(leaseCtx, payload)
+			return Job{}, errors.Wrap(errors.ErrInvalidFormat, "invalid job in Redis queue", err).WithContext("key", q.jobsKey())
+		}
+		expiry := strconv.FormatInt(time.Now().Add(q.config.VisibilityTimeout).UnixMilli(), 10)
+		if _, err := q.do(leaseCtx, 0, "ZADD", q.leasesKey(), expiry, payload); err != nil {
+			return Job{}, err
+		}
+		q.mu.Lock()
+		q.inflight[job.ID] = payload
+		q.mu.Unlock()
+		return job, nil
+	}
+}
+
+// Complete pushes the result of a job onto its result list, which expires
+// after ResultTTL if nobody awaits it, and acknowledges the job
+func (q *RedisQueue) Complete(ctx context.Context, result JobResult) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal job result", err).WithContext("job", result.JobID)
+	}
+	key := q.resultKey(result.JobID)
+	if _, err := q.do(ctx, 0, "LPUSH", key, string(payload)); err != nil {
+		return err
+	}
+	ttl := strconv.Itoa(int(q.config.ResultTTL / time.Second))
+	if _, err := q.do(ctx, 0, "EXPIRE", key, ttl); err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	job, ok := q.inflight[result.JobID]
+	delete(q.inflight, result.JobID)
+	q.mu.Unlock()
+	if !ok {
+		return nil
+	}
+	return q.ack(ctx, job)
+}
+
+// Await blocks until the result of the given job is available
+func (q *RedisQueue) Await(ctx context.Context, jobID string) (JobResult, error) {
+	payload, err := q.pop(ctx, q.resultKey(jobID))
+	if err != nil {
+		return JobResult{}, err
+	}
+	var result JobResult
+	if err := json.Unmarshal([]byte(payload), &result); err != nil {
+		return JobResult{}, errors.Wrap(errors.ErrInvalidFormat, "invalid job result in Redis queue", err).WithContext("job", jobID)
+	}
+	return result, nil
+}
+
+// Close closes the idle connections. Connections in use by pending calls
+// are closed when those calls return.
+func (q *RedisQueue) Close() error {
+	for {
+		select {
+		case c := <-q.idle:
+			c.conn.Close()
+		default:
+			return nil
+		}
+	}
+}
+
+func (q *RedisQueue) jobsKey() string {
+	return q.config.Prefix + ":jobs"
+}
+
+func (q *RedisQueue) resultKey(jobID string) string {
+	return q.config.Prefix + ":result:" + jobID
+}
+
+func (q *RedisQueue) processingKey() string {
+	return q.config.Prefix + ":processing"
+}
+
+// leasesKey is a sorted set of the payloads in the processing list, scored
+// by the Unix time in milliseconds their lease expires
+func (q *RedisQueue) leasesKey() string {
+	return q.config.Prefix + ":leases"
+}
+
+// ack removes a job from the processing list and drops its lease
+func (q *RedisQueue) ack(ctx context.Context, payload string) error {
+	if _, err := q.do(ctx, 0, "LREM", q.processingKey(), "1", payload); err != nil {
+		return err
+	}
+	_, err := q.do(ctx, 0, "ZREM", q.leasesKey(), payload)
+	return err
+}
+
+// requeueExpired puts jobs whose lease expired back on the job list, to be
+// dequeued next. When several workers find the same job expired, the one
+// whose LREM removes it from the processing list requeues it.
+func (q *RedisQueue) requeueExpired(ctx context.Context) error {
+	now := strconv.FormatInt(time.Now().UnixMilli(), 10)
+	reply, err := q.do(ctx, 0, "ZRANGEBYSCORE", q.leasesKey(), "-inf", now, "LIMIT", "0", "16")
+	if err != nil {
+		return err
+	}
+	items, _ := reply.([]interface{})
+	for _, item := range items {
+		payload, ok := item.(string)
+		if !ok {
+			continue
+		}
+		removed, err := q.do(ctx, 0, "LREM", q.processingKey(), "1", payload)
+		if err != nil {
+			return err
+		}
+		if n, _ := removed.(int64); n > 0 {
+			if _, err := q.do(ctx, 0, "RPUSH", q.jobsKey(), payload); err != nil {
+				return err
+			}
+		}
+		if _, err := q.do(ctx, 0, "ZREM", q.leasesKey(), payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// pop waits for an element of the list at key, polling with BRPOP until one
+// arrives or ctx is done
+func (q *RedisQueue) pop(ctx context.Context, key string) (string, error) {
+	timeout := strconv.Itoa(int(redisPollInterval / time.Second))
+	for {
+		if err := ctx.Err(); err != nil {
+			return "", err
+		}
+		reply, err := q.do(ctx, redisPollInterval, "BRPOP", key, timeout)
+		if err != nil {
+			return "", err
+		}
+		// BRPOP answers [key, element], or nil when the poll timed out
+		if items, ok := reply.([]interface{}); ok && len(items) == 2 {
+			if payload, ok := items[1].(string); ok {
+				return payload, nil
+			}
+		}
+	}
+}
+
+// do sends a command on a pooled connection and returns its reply. block is
+// how long the server may hold the reply back, added to the read deadline.
+func (q *RedisQueue) do(ctx context.Context, block time.Duration, args ...string) (interface{}, error) {
+	c, err := q.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(block + q.config.DialTimeout)
+	ctxDeadline := false
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline, ctxDeadline = d, true
+	}
+	_ = c.conn.SetDeadline(deadline)
+
+	reply, err := c.do(args...)
+	if err != nil {
+		if replyErr, ok := err.(redisError); ok {
+			// The connection is still usable after an error reply
+			q.put(c)
+			return nil, errors.Newf(errors.ErrAPIResponse, "Redis %s failed: %s", args[0], string(replyErr)).
+				WithContext("addr", q.config.Addr)
+		}
+		c.conn.Close()
+		if netErr, ok := err.(net.Error); ok && netErr.Timeout() && ctxDeadline {
+			// The read timed out at the deadline of ctx, which expires with it
+			<-ctx.Done()
+		}
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return nil, ctxErr
+		}
+		return nil, errors.Wrap(errors.ErrNetworkUnavail, "Redis "+args[0]+" failed", err).
+			WithContext("addr", q.config.Addr).
+			WithRetryable(true).
+			WithTemporary(true)
+	}
+	q.put(c)
+	return reply, nil
+}
+
+// get returns an idle connection, or dials a new one
+func (q *RedisQueue) get(ctx context.Context) (*redisConn, error) {
+	select {
+	case c := <-q.idle:
+		return c, nil
+	default:
+	}
+
+	dialer := net.Dialer{Timeout: q.config.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", q.config.Addr)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrNetworkRefused, "failed to connect to Redis", err).
+			WithContext("addr", q.config.Addr).
+			WithRetryable(true).
+			WithTemporary(true)
+	}
+	c := &redisConn{conn: conn, reader: bufio.NewReader(conn)}
+
+	_ = conn.SetDeadline(time.Now().Add(q.config.DialTimeout))
+	if q.config.Password != "" {
+		if _, err := c.do("AUTH", q.config.Password); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(errors.ErrUnauthorized, "Redis authentication failed", err).WithContext("addr", q.config.Addr)
+		}
+	}
+	if q.config.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(q.config.DB)); err != nil {
+			conn.Close()
+			return nil, errors.Wrap(errors.ErrProviderConfig, "failed to select Redis database", err).
+				WithContext("addr", q.config.Addr).
+				WithContext("db", q.config.DB)
+		}
+	}
+	return c, nil
+}
+
+// put returns a healthy connection to the pool, closing it if the pool is full
+func (q *RedisQueue) put(c *redisConn) {
+	select {
+	case q.idle <- c:
+	default:
+		c.conn.Close()
+	}
+}
+
+// redisError is an error reply sent by the server
+type redisError string
+
+func (e redisError) Error() string {
+	return string(e)
+}
+
+// redisConn speaks the RESP protocol over a single connection
+type redisConn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// do writes a command as an array of bulk strings and reads its reply
+func (c *redisConn) do(args ...string) (interface{}, error) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read parses one reply: simple strings and bulk strings become string,
+// integers int64, arrays []interface{} and null replies nil
+func (c *redisConn) read() (interface{}, error) {
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimRight(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("empty Redis reply")
+	}
+
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, redisError(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.reader, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	default:
+		return nil, fmt.Errorf("unexpected Redis reply %q", line)
+	}
+}
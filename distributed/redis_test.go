@@ -0,0 +1,270 @@
+package distributed
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeRedis serves the list and sorted set commands RedisQueue uses
+type fakeRedis struct {
+	ln net.Listener
+
+	mu    sync.Mutex
+	lists map[string][]string
+	ttls  map[string]string
+	zsets map[string]map[string]int64
+}
+
+func newFakeRedis(t *testing.T) *fakeRedis {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	s := &fakeRedis{ln: ln, lists: make(map[string][]string), ttls: make(map[string]string), zsets: make(map[string]map[string]int64)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		args, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		var reply string
+		switch strings.ToUpper(args[0]) {
+		case "LPUSH":
+			s.mu.Lock()
+			s.lists[args[1]] = append([]string{args[2]}, s.lists[args[1]]...)
+			n := len(s.lists[args[1]])
+			s.mu.Unlock()
+			reply = fmt.Sprintf(":%d\r\n", n)
+		case "RPUSH":
+			s.mu.Lock()
+			s.lists[args[1]] = append(s.lists[args[1]], args[2])
+			n := len(s.lists[args[1]])
+			s.mu.Unlock()
+			reply = fmt.Sprintf(":%d\r\n", n)
+		case "LREM":
+			s.mu.Lock()
+			removed := 0
+			list := s.lists[args[1]]
+			for i, item := range list {
+				if item == args[3] {
+					s.lists[args[1]] = append(list[:i:i], list[i+1:]...)
+					removed = 1
+					break
+				}
+			}
+			s.mu.Unlock()
+			reply = fmt.Sprintf(":%d\r\n", removed)
+		case "ZADD":
+			score, _ := strconv.ParseInt(args[2], 10, 64)
+			s.mu.Lock()
+			if s.zsets[args[1]] == nil {
+				s.zsets[args[1]] = make(map[string]int64)
+			}
+			s.zsets[args[1]][args[3]] = score
+			s.mu.Unlock()
+			reply = ":1\r\n"
+		case "ZREM":
+			s.mu.Lock()
+			_, ok := s.zsets[args[1]][args[2]]
+			delete(s.zsets[args[1]], args[2])
+			s.mu.Unlock()
+			if ok {
+				reply = ":1\r\n"
+			} else {
+				reply = ":0\r\n"
+			}
+		case "ZRANGEBYSCORE":
+			max, _ := strconv.ParseInt(args[3], 10, 64)
+			var b strings.Builder
+			n := 0
+			s.mu.Lock()
+			for member, score := range s.zsets[args[1]] {
+				if score <= max {
+					fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(member), member)
+					n++
+				}
+			}
+			s.mu.Unlock()
+			reply = fmt.Sprintf("*%d\r\n%s", n, b.String())
+		case "EXPIRE":
+			s.mu.Lock()
+			s.ttls[args[1]] = args[2]
+			s.mu.Unlock()
+			reply = ":1\r\n"
+		case "BRPOP":
+			reply = "*-1\r\n"
+			if item, ok := s.pop(args[1], ""); ok {
+				reply = fmt.Sprintf("*2\r\n$%d\r\n%s\r\n$%d\r\n%s\r\n", len(args[1]), args[1], len(item), item)
+			}
+		case "BRPOPLPUSH":
+			reply = "$-1\r\n"
+			if item, ok := s.pop(args[1], args[2]); ok {
+				reply = fmt.Sprintf("$%d\r\n%s\r\n", len(item), item)
+			}
+		default:
+			reply = "-ERR unknown command\r\n"
+		}
+		if _, err := io.WriteString(conn, reply); err != nil {
+			return
+		}
+	}
+}
+
+// pop waits up to a second for the last element of the list at key and
+// pushes it onto the list at dest, if any
+func (s *fakeRedis) pop(key, dest string) (string, bool) {
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		s.mu.Lock()
+		list := s.lists[key]
+		if len(list) > 0 {
+			item := list[len(list)-1]
+			s.lists[key] = list[:len(list)-1]
+			if dest != "" {
+				s.lists[dest] = append([]string{item}, s.lists[dest]...)
+			}
+			s.mu.Unlock()
+			return item, true
+		}
+		s.mu.Unlock()
+		time.Sleep(10 * time.Millisecond)
+	}
+	return "", false
+}
+
+// readCommand reads a command sent as an array of bulk strings
+func readCommand(r *bufio.Reader) ([]string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(line)[1:])
+	if err != nil {
+		return nil, err
+	}
+	args := make([]string, n)
+	for i := range args {
+		if _, err := r.ReadString('\n'); err != nil {
+			return nil, err
+		}
+		arg, err := r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		args[i] = strings.TrimSuffix(arg, "\r\n")
+	}
+	return args, nil
+}
+
+func TestRedisQueue_RoundTrip(t *testing.T) {
+	server := newFakeRedis(t)
+	q := NewRedisQueue(RedisConfig{Addr: server.ln.Addr().String(), Prefix: "test"})
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := q.Enqueue(ctx, Job{ID: "job-1", Description: "Say hi", AgentName: "writer"}); err != nil {
+		t.Fatalf("Enqueue() unexpected error: %v", err)
+	}
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() unexpected error: %v", err)
+	}
+	if job.ID != "job-1" || job.AgentName != "writer" {
+		t.Errorf("Dequeue() = %+v", job)
+	}
+
+	if err := q.Complete(ctx, JobResult{JobID: "job-1", Output: "hi"}); err != nil {
+		t.Fatalf("Complete() unexpected error: %v", err)
+	}
+	server.mu.Lock()
+	if ttl := server.ttls["test:result:job-1"]; ttl != "3600" {
+		t.Errorf("result TTL = %q, want 3600", ttl)
+	}
+	if len(server.lists["test:processing"]) != 0 || len(server.zsets["test:leases"]) != 0 {
+		t.Errorf("completed job still processing: %q, leases %v", server.lists["test:processing"], server.zsets["test:leases"])
+	}
+	server.mu.Unlock()
+	result, err := q.Await(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Await() unexpected error: %v", err)
+	}
+	if result.Output != "hi" {
+		t.Errorf("Await() output = %q, want hi", result.Output)
+	}
+}
+
+func TestRedisQueue_DequeueHonorsCancel(t *testing.T) {
+	server := newFakeRedis(t)
+	q := NewRedisQueue(RedisConfig{Addr: server.ln.Addr().String()})
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	if _, err := q.Dequeue(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Dequeue() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestRedisQueue_RedeliversExpiredJobs(t *testing.T) {
+	server := newFakeRedis(t)
+	crashed := NewRedisQueue(RedisConfig{Addr: server.ln.Addr().String(), VisibilityTimeout: 50 * time.Millisecond})
+	defer crashed.Close()
+	q := NewRedisQueue(RedisConfig{Addr: server.ln.Addr().String(), VisibilityTimeout: time.Minute})
+	defer q.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := q.Enqueue(ctx, Job{ID: "job-1", Description: "Say hi", AgentName: "writer"}); err != nil {
+		t.Fatalf("Enqueue() unexpected error: %v", err)
+	}
+	// The first worker takes the job and dies without completing it
+	if _, err := crashed.Dequeue(ctx); err != nil {
+		t.Fatalf("Dequeue() unexpected error: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	job, err := q.Dequeue(ctx)
+	if err != nil {
+		t.Fatalf("Dequeue() of the expired job unexpected error: %v", err)
+	}
+	if job.ID != "job-1" {
+		t.Fatalf("Dequeue() = %+v, want job-1 again", job)
+	}
+	if err := q.Complete(ctx, JobResult{JobID: "job-1", Output: "hi"}); err != nil {
+		t.Fatalf("Complete() unexpected error: %v", err)
+	}
+
+	server.mu.Lock()
+	defer server.mu.Unlock()
+	if len(server.lists["gittyai:jobs"]) != 0 || len(server.lists["gittyai:processing"]) != 0 || len(server.zsets["gittyai:leases"]) != 0 {
+		t.Errorf("jobs %q, processing %q, leases %v, want all empty", server.lists["gittyai:jobs"], server.lists["gittyai:processing"], server.zsets["gittyai:leases"])
+	}
+}
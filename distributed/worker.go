@@ -0,0 +1,265 @@
+package distributed
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/task"
+)
+
+// Logger receives progress messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Backoff bounds for retrying temporary queue errors
+const (
+	minQueueBackoff = 100 * time.Millisecond
+	maxQueueBackoff = 5 * time.Second
+)
+
+// Worker pulls jobs from a queue and executes them with its local agents
+type Worker struct {
+	name        string
+	queue       Queue
+	agents      map[string]*agent.Agent
+	runtime     *agent.Runtime
+	concurrency int
+	verbose     bool
+	logger      Logger
+
+	mu    sync.Mutex
+	built map[string]*agent.Agent // Agents built by runtime, by definition
+}
+
+// WorkerConfig represents the configuration for creating a Worker
+type WorkerConfig struct {
+	// Name identifies the worker in results (default: hostname and pid)
+	Name string
+	// Queue is the source of jobs
+	Queue Queue
+	// Agents available to this worker, matched to jobs by name
+	Agents []*agent.Agent
+	// Runtime builds the agents of jobs carrying an agent definition that
+	// matches none of Agents (optional)
+	Runtime *agent.Runtime
+	// Concurrency is the number of jobs executed at once (default: 1)
+	Concurrency int
+	Verbose     bool
+	// Logger receives progress messages when Verbose (default: stdout)
+	Logger Logger
+}
+
+// NewWorker creates a new Worker
+func NewWorker(cfg WorkerConfig) (*Worker, error) {
+	if cfg.Queue == nil {
+		return nil, errors.RequiredField("queue")
+	}
+	if len(cfg.Agents) == 0 && cfg.Runtime == nil {
+		return nil, errors.Validation("at least one agent or a runtime is required")
+	}
+
+	agents := make(map[string]*agent.Agent, len(cfg.Agents))
+	for _, a := range cfg.Agents {
+		agents[a.Name] = a
+	}
+
+	name := cfg.Name
+	if name == "" {
+		host, _ := os.Hostname()
+		name = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+
+	concurrency := cfg.Concurrency
+	if concurrency <= 0 {
+		concurrency = 1
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New(os.Stdout, "", 0)
+	}
+
+	return &Worker{
+		name:        name,
+		queue:       cfg.Queue,
+		agents:      agents,
+		runtime:     cfg.Runtime,
+		concurrency: concurrency,
+		verbose:     cfg.Verbose,
+		logger:      logger,
+		built:       make(map[string]*agent.Agent),
+	}, nil
+}
+
+// Run processes jobs until ctx is cancelled. Temporary queue errors are
+// retried with backoff; any other queue error stops the worker and is
+// returned, so a broken queue is not mistaken for a clean shutdown.
+func (w *Worker) Run(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+	for i := 0; i < w.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := w.loop(ctx); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}()
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	return ctx.Err()
+}
+
+// loop dequeues and executes jobs one at a time. It returns nil once ctx is
+// done and the error of the queue when it fails permanently.
+func (w *Worker) loop(ctx context.Context) error {
+	backoff := minQueueBackoff
+	for {
+		job, err := w.queue.Dequeue(ctx)
+		if err != nil {
+			if !w.retry(ctx, err, "dequeue", &backoff) {
+				return w.stopErr(ctx, err)
+			}
+			continue
+		}
+		backoff = minQueueBackoff
+
+		result := w.execute(ctx, job)
+		for {
+			err := w.queue.Complete(ctx, result)
+			if err == nil {
+				break
+			}
+			if !w.retry(ctx, err, "complete job "+job.ID, &backoff) {
+				return w.stopErr(ctx, err)
+			}
+		}
+		backoff = minQueueBackoff
+	}
+}
+
+// stopErr is the error a loop stops with: nil when ctx is done, since
+// queue calls then fail because of the cancellation
+func (w *Worker) stopErr(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil
+	}
+	return err
+}
+
+// retry waits out the backoff after a temporary queue error and doubles it.
+// It returns false when err is permanent or ctx is done.
+func (w *Worker) retry(ctx context.Context, err error, operation string, backoff *time.Duration) bool {
+	if !errors.IsTemporary(err) {
+		return false
+	}
+	if w.verbose {
+		w.logger.Printf("[Worker %s] Failed to %s, retrying in %s: %v", w.name, operation, *backoff, err)
+	}
+
+	timer := time.NewTimer(*backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+		return false
+	}
+
+	*backoff *= 2
+	if *backoff > maxQueueBackoff {
+		*backoff = maxQueueBackoff
+	}
+	return true
+}
+
+// execute runs a single job and converts the outcome into a JobResult
+func (w *Worker) execute(ctx context.Context, job Job) JobResult {
+	result := JobResult{
+		JobID:  job.ID,
+		RunID:  job.RunID,
+		Worker: w.name,
+		Agent:  job.AgentName,
+	}
+
+	a, err := w.agent(job)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	if job.RunID != "" {
+		ctx = runid.NewContext(ctx, job.RunID)
+	}
+
+	if w.verbose {
+		w.logger.Printf("[Worker %s] Executing job %s: %s", w.name, job.ID, job.Description)
+	}
+
+	t := task.New(task.Config{
+		Description:    job.Description,
+		ExpectedOutput: job.ExpectedOutput,
+		Agent:          a,
+		Context:        job.Context,
+	})
+	if job.Task != nil {
+		t = task.FromDefinition(*job.Task, a)
+	}
+
+	output, err := t.Execute(ctx)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	result.Output = output
+	return result
+}
+
+// agent returns the worker's agent for a job, built from the job's agent
+// definition when the worker has none of that name. Built agents are kept
+// for later jobs with the same definition.
+func (w *Worker) agent(job Job) (*agent.Agent, error) {
+	if a, ok := w.agents[job.AgentName]; ok {
+		return a, nil
+	}
+	if job.Agent == nil || w.runtime == nil {
+		return nil, errors.AgentNotFound(job.AgentName).WithContext("worker", w.name)
+	}
+	data, err := json.Marshal(job.Agent)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid agent definition", err).WithContext("agent", job.AgentName)
+	}
+	key := string(data)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if a, ok := w.built[key]; ok {
+		return a, nil
+	}
+	a, err := agent.FromDefinition(*job.Agent, *w.runtime)
+	if err != nil {
+		return nil, err
+	}
+	w.built[key] = a
+	return a, nil
+}
@@ -0,0 +1,86 @@
+package distributed
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+)
+
+type replyLLM struct {
+	reply string
+}
+
+func (r replyLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return r.reply, nil
+}
+
+// failingQueue fails Dequeue with err, temporarily for the first failures calls
+type failingQueue struct {
+	*MemoryQueue
+	err      *errors.Error
+	failures int32
+	calls    int32
+}
+
+func (q *failingQueue) Dequeue(ctx context.Context) (Job, error) {
+	if atomic.AddInt32(&q.calls, 1) <= q.failures {
+		return Job{}, q.err
+	}
+	return q.MemoryQueue.Dequeue(ctx)
+}
+
+func newTestWorker(t *testing.T, q Queue) *Worker {
+	t.Helper()
+	w, err := NewWorker(WorkerConfig{
+		Queue:  q,
+		Agents: []*agent.Agent{agent.New(agent.Config{Name: "writer", Role: "Writer", Goal: "Write", LLM: replyLLM{reply: "done"}})},
+	})
+	if err != nil {
+		t.Fatalf("NewWorker() unexpected error: %v", err)
+	}
+	return w
+}
+
+func TestWorker_RunReturnsQueueError(t *testing.T) {
+	q := &failingQueue{MemoryQueue: NewMemoryQueue(1), err: errors.Internal("queue broken"), failures: 1 << 30}
+	w := newTestWorker(t, q)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	err := w.Run(ctx)
+	if !errors.HasCode(err, errors.ErrInternal) {
+		t.Errorf("Run() error = %v, want the queue's error", err)
+	}
+}
+
+func TestWorker_RetriesTemporaryQueueErrors(t *testing.T) {
+	temporary := errors.NetworkUnavailable("queue").WithTemporary(true)
+	q := &failingQueue{MemoryQueue: NewMemoryQueue(1), err: temporary, failures: 2}
+	w := newTestWorker(t, q)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- w.Run(ctx) }()
+
+	if err := q.Enqueue(ctx, Job{ID: "job-1", Description: "Say hi", AgentName: "writer"}); err != nil {
+		t.Fatalf("Enqueue() unexpected error: %v", err)
+	}
+	result, err := q.Await(ctx, "job-1")
+	if err != nil {
+		t.Fatalf("Await() unexpected error: %v", err)
+	}
+	if result.Error != "" || result.Output == "" {
+		t.Errorf("Await() = %+v, want an output", result)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"context"
+	stderrors "errors"
+	"io"
+	"io/fs"
+	"net"
+	"os"
+	"syscall"
+	"time"
+)
+
+// Classify returns err as an *Error with the code and Retryable and
+// Temporary flags matching its cause, for errors from the standard library
+// crossing a provider or tool boundary:
+//
+//   - context.Canceled becomes ErrCanceled, never retried
+//   - context.DeadlineExceeded becomes ErrTimeout
+//   - net.Error timeouts and os.ErrDeadlineExceeded become ErrNetworkTimeout
+//   - refused connections become ErrNetworkRefused
+//   - reset or aborted connections, unreachable hosts, DNS failures and
+//     unexpected EOFs become ErrNetworkUnavail
+//   - fs.ErrNotExist becomes ErrNotFound and fs.ErrPermission ErrUnauthorized
+//
+// Network and deadline failures are retryable and temporary. An *Error is
+// returned unchanged; an *Error wrapped by another error keeps its code and
+// flags. Other errors become ErrUnexpected. Classify returns nil for nil.
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	if e, ok := err.(*Error); ok {
+		return e
+	}
+	classified := func(code ErrorCode, transient bool) *Error {
+		return &Error{
+			Code:      code,
+			Message:   err.Error(),
+			Err:       err,
+			Timestamp: time.Now(),
+			Severity:  SeverityMedium,
+			Stack:     captureStack(3),
+			Retryable: transient,
+			Temporary: transient,
+		}
+	}
+
+	var inner *Error
+	var dnsErr *net.DNSError
+	var netErr net.Error
+	switch {
+	case stderrors.As(err, &inner):
+		e := classified(inner.Code, inner.Retryable)
+		e.Temporary = inner.Temporary
+		return e
+	case stderrors.Is(err, context.Canceled):
+		return classified(ErrCanceled, false)
+	case stderrors.Is(err, context.DeadlineExceeded):
+		return classified(ErrTimeout, true)
+	case stderrors.Is(err, os.ErrDeadlineExceeded):
+		return classified(ErrNetworkTimeout, true)
+	case stderrors.As(err, &dnsErr):
+		if dnsErr.IsTimeout {
+			return classified(ErrNetworkTimeout, true)
+		}
+		return classified(ErrNetworkUnavail, !dnsErr.IsNotFound)
+	case stderrors.As(err, &netErr) && netErr.Timeout():
+		return classified(ErrNetworkTimeout, true)
+	case stderrors.Is(err, syscall.ECONNREFUSED):
+		return classified(ErrNetworkRefused, true)
+	case stderrors.Is(err, syscall.ECONNRESET), stderrors.Is(err, syscall.ECONNABORTED),
+		stderrors.Is(err, syscall.EPIPE), stderrors.Is(err, syscall.ENETUNREACH),
+		stderrors.Is(err, syscall.EHOSTUNREACH), stderrors.Is(err, io.ErrUnexpectedEOF):
+		return classified(ErrNetworkUnavail, true)
+	case stderrors.Is(err, fs.ErrNotExist):
+		return classified(ErrNotFound, false)
+	case stderrors.Is(err, fs.ErrPermission):
+		return classified(ErrUnauthorized, false)
+	default:
+		return classified(ErrUnexpected, false)
+	}
+}
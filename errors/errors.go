@@ -1,6 +1,8 @@
 package errors
 
 import (
+	"context"
+	stderrors "errors"
 	"fmt"
 	"runtime"
 	"strings"
@@ -30,6 +32,8 @@ const (
 	CategoryAuth        = "auth"
 	CategoryTimeout     = "timeout"
 	CategoryRateLimit   = "ratelimit"
+	CategoryPolicy      = "policy"
+	CategoryTool        = "tool"
 )
 
 // Predefined error codes
@@ -49,6 +53,7 @@ var (
 	ErrAPICall       = ErrorCode{CategoryAPI, "call_failed"}
 	ErrAPIResponse   = ErrorCode{CategoryAPI, "invalid_response"}
 	ErrAPIStatusCode = ErrorCode{CategoryAPI, "bad_status_code"}
+	ErrAPITruncated  = ErrorCode{CategoryAPI, "truncated"}
 
 	// Network errors
 	ErrNetworkTimeout = ErrorCode{CategoryNetwork, "timeout"}
@@ -59,6 +64,8 @@ var (
 	ErrInternal       = ErrorCode{CategoryInternal, "internal"}
 	ErrNotImplemented = ErrorCode{CategoryInternal, "not_implemented"}
 	ErrUnexpected     = ErrorCode{CategoryInternal, "unexpected"}
+	ErrShutdown       = ErrorCode{CategoryInternal, "shutdown"}
+	ErrCanceled       = ErrorCode{CategoryInternal, "canceled"}
 
 	// Not found errors
 	ErrNotFound      = ErrorCode{CategoryNotFound, "resource"}
@@ -75,7 +82,17 @@ var (
 
 	// Rate limit and timeout
 	ErrRateLimitExceeded = ErrorCode{CategoryRateLimit, "exceeded"}
+	ErrQuotaExceeded     = ErrorCode{CategoryRateLimit, "quota_exceeded"}
 	ErrTimeout           = ErrorCode{CategoryTimeout, "exceeded"}
+
+	// Policy errors
+	ErrPolicyViolation = ErrorCode{CategoryPolicy, "violation"}
+
+	// Tool errors
+	ErrToolFailed      = ErrorCode{CategoryTool, "failed"}
+	ErrToolInvalidArgs = ErrorCode{CategoryTool, "invalid_args"}
+	ErrToolUnavailable = ErrorCode{CategoryTool, "unavailable"}
+	ErrToolTimeout     = ErrorCode{CategoryTool, "timeout"}
 )
 
 // Severity levels for errors
@@ -103,6 +120,16 @@ func (s Severity) String() string {
 	}
 }
 
+// ParseSeverity parses a severity name such as "high" or "CRITICAL"
+func ParseSeverity(s string) (Severity, error) {
+	for _, severity := range []Severity{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical} {
+		if strings.EqualFold(s, severity.String()) {
+			return severity, nil
+		}
+	}
+	return SeverityLow, InvalidField("severity", "must be one of low, medium, high, critical").WithContext("value", s)
+}
+
 // StackFrame represents a single frame in the call stack
 type StackFrame struct {
 	File     string
@@ -173,31 +200,59 @@ func (e *Error) Is(target error) bool {
 	return false
 }
 
-// WithContext adds context information to the error
+// The With* methods return a copy of the error with the change applied and
+// never modify the receiver, so an error shared between goroutines, such as
+// a task failure reported to several parallel runs, can be annotated
+// safely. Use the returned error.
+
+// clone returns a copy of e with its own context map
+func (e *Error) clone(extra int) *Error {
+	c := *e
+	if len(e.Context) > 0 || extra > 0 {
+		c.Context = make(map[string]interface{}, len(e.Context)+extra)
+		for k, v := range e.Context {
+			c.Context[k] = v
+		}
+	}
+	return &c
+}
+
+// WithContext returns a copy of the error with a context entry added
 func (e *Error) WithContext(key string, value interface{}) *Error {
-	if e.Context == nil {
-		e.Context = make(map[string]interface{})
+	c := e.clone(1)
+	c.Context[key] = value
+	return c
+}
+
+// WithContextMap returns a copy of the error with several context entries
+// added; entries replace existing ones with the same key
+func (e *Error) WithContextMap(fields map[string]interface{}) *Error {
+	c := e.clone(len(fields))
+	for k, v := range fields {
+		c.Context[k] = v
 	}
-	e.Context[key] = value
-	return e
+	return c
 }
 
-// WithSeverity sets the severity of the error
+// WithSeverity returns a copy of the error with the given severity
 func (e *Error) WithSeverity(severity Severity) *Error {
-	e.Severity = severity
-	return e
+	c := e.clone(0)
+	c.Severity = severity
+	return c
 }
 
-// WithRetryable marks the error as retryable
+// WithRetryable returns a copy of the error marked retryable or not
 func (e *Error) WithRetryable(retryable bool) *Error {
-	e.Retryable = retryable
-	return e
+	c := e.clone(0)
+	c.Retryable = retryable
+	return c
 }
 
-// WithTemporary marks the error as temporary
+// WithTemporary returns a copy of the error marked temporary or not
 func (e *Error) WithTemporary(temporary bool) *Error {
-	e.Temporary = temporary
-	return e
+	c := e.clone(0)
+	c.Temporary = temporary
+	return c
 }
 
 // captureStack captures the current call stack
@@ -327,11 +382,16 @@ func Configf(format string, args ...interface{}) *Error {
 
 // API errors
 
-// APICallError returns an error for API call failures
+// APICallError returns an error for API call failures. It is retryable
+// unless Classify finds a cause that is not, such as a canceled context.
 func APICallError(operation string, err error) *Error {
+	retryable, temporary := true, true
+	if c := Classify(err); c != nil && c.Code != ErrUnexpected {
+		retryable, temporary = c.Retryable, c.Temporary
+	}
 	return Wrap(ErrAPICall, fmt.Sprintf("failed to %s", operation), err).
-		WithRetryable(true).
-		WithTemporary(true)
+		WithRetryable(retryable).
+		WithTemporary(temporary)
 }
 
 // APIResponseError returns an error for invalid API responses
@@ -341,7 +401,7 @@ func APIResponseError(message string) *Error {
 
 // APIStatusCodeError returns an error for bad status codes
 func APIStatusCodeError(statusCode int, body string) *Error {
-	return Newf(ErrAPIStatusCode, "unexpected status code %d: %s", statusCode, body)
+	return Newf(ErrAPIStatusCode, "unexpected status code %d: %s", statusCode, body).WithContext("status_code", statusCode)
 }
 
 // API creates a generic API error
@@ -354,6 +414,12 @@ func APIf(format string, args ...interface{}) *Error {
 	return Newf(ErrorCode{CategoryAPI, "error"}, format, args...)
 }
 
+// OutputTruncated returns an error for a response cut off before completion
+func OutputTruncated(finishReason string) *Error {
+	return Newf(ErrAPITruncated, "response truncated (finish reason: %s)", finishReason).
+		WithContext("finish_reason", finishReason)
+}
+
 // Network errors
 
 // NetworkTimeout returns a network timeout error
@@ -402,6 +468,11 @@ func Unexpected(message string) *Error {
 	return New(ErrUnexpected, message).WithSeverity(SeverityHigh)
 }
 
+// ShuttingDown returns an error for work rejected because a component is draining
+func ShuttingDown(component string) *Error {
+	return Newf(ErrShutdown, "%s is shutting down", component)
+}
+
 // NotFound errors
 
 // NotFound creates a generic not found error
@@ -457,6 +528,12 @@ func RateLimitExceeded(resource string, limit int) *Error {
 		WithTemporary(true)
 }
 
+// QuotaExceeded returns an error for an exhausted usage quota or budget.
+// Unlike rate limits, quotas do not replenish on their own, so it is not retryable.
+func QuotaExceeded(resource string, limit int) *Error {
+	return Newf(ErrQuotaExceeded, "quota exceeded for '%s' (limit: %d)", resource, limit)
+}
+
 // Timeout returns a timeout error
 func Timeout(operation string, duration time.Duration) *Error {
 	return Newf(ErrTimeout, "operation '%s' timed out after %v", operation, duration).
@@ -464,6 +541,76 @@ func Timeout(operation string, duration time.Duration) *Error {
 		WithTemporary(true)
 }
 
+// Policy errors
+
+// PolicyViolation returns an error for content blocked by a policy check
+func PolicyViolation(check string, categories []string) *Error {
+	return Newf(ErrPolicyViolation, "content blocked by %s: %s", check, strings.Join(categories, ", ")).
+		WithContext("categories", categories)
+}
+
+// Tool errors
+
+// Hints telling a model how to react to a tool error
+const (
+	hintToolArgs        = "Check the arguments against the tool's description and call it again with corrected values."
+	hintToolNotFound    = "The requested item does not exist; try a different query or identifier."
+	hintToolUnavailable = "The service behind the tool is temporarily unavailable; retry later or continue without it."
+	hintToolTimeout     = "The tool took too long; retry with a smaller request."
+	hintToolForbidden   = "The tool is not permitted to do this; do not retry it."
+	hintToolFailed      = "The tool failed; do not retry it with the same arguments."
+)
+
+// ToolError classifies the failure of a tool under CategoryTool, marking
+// whether the call can be retried and adding a "hint" context entry on how
+// to proceed, for error descriptions given back to a model
+func ToolError(tool string, err error) *Error {
+	code, hint, retryable := ErrToolFailed, hintToolFailed, false
+	message := err.Error()
+
+	var cause *Error
+	for e := err; e != nil; e = stderrors.Unwrap(e) {
+		if se, ok := e.(*Error); ok {
+			cause = se
+			break
+		}
+	}
+	if cause == nil {
+		cause = Classify(err)
+		if cause.Code == ErrUnexpected {
+			cause = nil
+		}
+	}
+	switch {
+	case cause != nil && cause.Code.Category == CategoryTool:
+		return cause
+	case stderrors.Is(err, context.DeadlineExceeded) || (cause != nil && cause.Code.Category == CategoryTimeout) ||
+		(cause != nil && cause.Code == ErrNetworkTimeout):
+		code, hint, retryable = ErrToolTimeout, hintToolTimeout, true
+	case cause == nil:
+	case cause.Code.Category == CategoryValidation:
+		code, hint = ErrToolInvalidArgs, hintToolArgs
+	case cause.Code.Category == CategoryNotFound:
+		hint = hintToolNotFound
+	case cause.Code.Category == CategoryNetwork, cause.Code.Category == CategoryRateLimit && cause.Retryable,
+		cause.Code.Category == CategoryAPI && cause.Retryable:
+		code, hint, retryable = ErrToolUnavailable, hintToolUnavailable, true
+	case cause.Code.Category == CategoryAuth, cause.Code.Category == CategoryPolicy, cause.Code == ErrQuotaExceeded:
+		hint = hintToolForbidden
+	default:
+		retryable = cause.Retryable
+	}
+	if cause != nil {
+		message = cause.Message
+	}
+
+	return Wrapf(code, err, "tool %s failed: %s", tool, message).
+		WithContext("tool", tool).
+		WithContext("hint", hint).
+		WithRetryable(retryable).
+		WithTemporary(retryable)
+}
+
 // Helper functions for error checking
 
 // IsRetryable checks if an error is retryable
@@ -497,3 +644,24 @@ func GetSeverity(err error) Severity {
 	}
 	return SeverityLow
 }
+
+// MaxSeverity returns the highest severity of the structured errors in the
+// chain of err, including every error of a joined error
+func MaxSeverity(err error) Severity {
+	highest := SeverityLow
+	for err != nil {
+		if e, ok := err.(*Error); ok && e.Severity > highest {
+			highest = e.Severity
+		}
+		if joined, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, inner := range joined.Unwrap() {
+				if s := MaxSeverity(inner); s > highest {
+					highest = s
+				}
+			}
+			break
+		}
+		err = stderrors.Unwrap(err)
+	}
+	return highest
+}
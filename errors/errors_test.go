@@ -1,8 +1,15 @@
 package errors
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
 	"strings"
+	"sync"
+	"syscall"
 	"testing"
 	"time"
 )
@@ -111,6 +118,40 @@ func TestError_WithContext(t *testing.T) {
 	}
 }
 
+func TestError_WithContextMap(t *testing.T) {
+	err := New(ErrAPICall, "test").
+		WithContext("request_id", "123").
+		WithContextMap(map[string]interface{}{"request_id": "456", "attempt": 2})
+
+	if err.Context["request_id"] != "456" || err.Context["attempt"] != 2 {
+		t.Errorf("WithContextMap() context = %v", err.Context)
+	}
+}
+
+func TestError_WithCopiesError(t *testing.T) {
+	shared := New(ErrAPICall, "test").WithContext("provider", "openai")
+
+	var wg sync.WaitGroup
+	tagged := make([]*Error, 8)
+	for i := range tagged {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			tagged[i] = shared.WithContext("run_id", i).WithSeverity(SeverityHigh).WithRetryable(true)
+		}(i)
+	}
+	wg.Wait()
+
+	if len(shared.Context) != 1 || shared.Severity != SeverityMedium || shared.Retryable {
+		t.Errorf("With* modified the shared error: %+v", shared)
+	}
+	for i, e := range tagged {
+		if e.Context["run_id"] != i || e.Context["provider"] != "openai" {
+			t.Errorf("tagged[%d].Context = %v", i, e.Context)
+		}
+	}
+}
+
 func TestError_WithSeverity(t *testing.T) {
 	err := New(ErrInternal, "test").WithSeverity(SeverityCritical)
 	if err.Severity != SeverityCritical {
@@ -291,3 +332,106 @@ func TestSeverity_String(t *testing.T) {
 		})
 	}
 }
+
+func TestToolError(t *testing.T) {
+	tests := []struct {
+		cause     error
+		want      ErrorCode
+		retryable bool
+	}{
+		{InvalidField("query", "must not be empty"), ErrToolInvalidArgs, false},
+		{NetworkUnavailable("search API"), ErrToolUnavailable, true},
+		{RateLimitExceeded("search API", 10), ErrToolUnavailable, true},
+		{Timeout("search", time.Second), ErrToolTimeout, true},
+		{NotFound("page", "/missing"), ErrToolFailed, false},
+		{errors.New("exit status 1"), ErrToolFailed, false},
+	}
+	for _, tt := range tests {
+		err := ToolError("search", tt.cause)
+		if err.Code != tt.want || err.Retryable != tt.retryable {
+			t.Errorf("ToolError(%v) = %s retryable=%v, want %s retryable=%v", tt.cause, err.Code, err.Retryable, tt.want, tt.retryable)
+		}
+		if hint, _ := err.Context["hint"].(string); hint == "" {
+			t.Errorf("ToolError(%v) has no hint", tt.cause)
+		}
+	}
+
+	first := ToolError("search", InvalidField("query", "empty"))
+	if again := ToolError("search", first); again != first {
+		t.Error("ToolError() re-wrapped a classified tool error")
+	}
+	if !strings.Contains(first.Message, "field 'query' is invalid") {
+		t.Errorf("Message = %q, want the cause's message", first.Message)
+	}
+}
+
+// lookupFailure fails like a recurring failure with varying details
+func lookupFailure(id string, attempts int) error {
+	return Wrap(ErrAPICall, fmt.Sprintf("lookup of %q failed after %d attempts", id, attempts),
+		fmt.Errorf("dial tcp 10.0.0.%d:443: i/o timeout", attempts))
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint(lookupFailure("run-1f3a9c2d", 3))
+	b := Fingerprint(lookupFailure("run-77e0b4aa", 5))
+	if a == "" || a != b {
+		t.Errorf("Fingerprint() = %q and %q, want one fingerprint for one failure", a, b)
+	}
+
+	if c := Fingerprint(New(ErrAPICall, `lookup of "x" failed after 3 attempts`)); c == a {
+		t.Error("Fingerprint() ignores the cause and stack of the error")
+	}
+	if d := Fingerprint(Wrap(ErrNetworkTimeout, "wrapped", lookupFailure("x", 1))); d == a {
+		t.Error("Fingerprint() ignores the outer error")
+	}
+	if Fingerprint(nil) != "" {
+		t.Error("Fingerprint(nil) should be empty")
+	}
+
+	if got := NormalizeMessage(`task "write" failed after 2.5s (id 0x1f, trace 4bf92f3577b34da6)`); got != "task <str> failed after <n>s (id <hex>, trace <hex>)" {
+		t.Errorf("NormalizeMessage() = %q", got)
+	}
+}
+
+func TestClassify(t *testing.T) {
+	timeout := &net.OpError{Op: "dial", Net: "tcp", Err: os.ErrDeadlineExceeded}
+	tests := []struct {
+		name      string
+		err       error
+		code      ErrorCode
+		retryable bool
+	}{
+		{"canceled", fmt.Errorf("request: %w", context.Canceled), ErrCanceled, false},
+		{"deadline", context.DeadlineExceeded, ErrTimeout, true},
+		{"net timeout", timeout, ErrNetworkTimeout, true},
+		{"refused", &net.OpError{Op: "dial", Net: "tcp", Err: &os.SyscallError{Syscall: "connect", Err: syscall.ECONNREFUSED}}, ErrNetworkRefused, true},
+		{"reset", fmt.Errorf("read: %w", syscall.ECONNRESET), ErrNetworkUnavail, true},
+		{"dns not found", &net.DNSError{Err: "no such host", Name: "api.invalid", IsNotFound: true}, ErrNetworkUnavail, false},
+		{"missing file", &fs.PathError{Op: "open", Path: "x", Err: fs.ErrNotExist}, ErrNotFound, false},
+		{"wrapped structured", fmt.Errorf("outer: %w", RateLimitExceeded("api", 10)), ErrRateLimitExceeded, true},
+		{"other", errors.New("boom"), ErrUnexpected, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Classify(tt.err)
+			if got.Code != tt.code || got.Retryable != tt.retryable {
+				t.Errorf("Classify() = %v (retryable %v), want %v (retryable %v)", got.Code, got.Retryable, tt.code, tt.retryable)
+			}
+			if !errors.Is(got, tt.err) && got != tt.err {
+				t.Error("Classify() should wrap the original error")
+			}
+		})
+	}
+
+	structured := New(ErrInternal, "x")
+	if Classify(structured) != structured || Classify(nil) != nil {
+		t.Error("Classify() should return structured and nil errors unchanged")
+	}
+
+	if APICallError("call API", context.Canceled).Retryable {
+		t.Error("APICallError() of a canceled request should not be retryable")
+	}
+	if tool := ToolError("search", timeout); tool.Code != ErrToolTimeout || !tool.Retryable {
+		t.Errorf("ToolError() of a network timeout = %v, want a retryable %v", tool.Code, ErrToolTimeout)
+	}
+}
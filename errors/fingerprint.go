@@ -0,0 +1,83 @@
+package errors
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	stderrors "errors"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// fingerprintFrames is how many stack frames of the originating error
+// contribute to a fingerprint
+const fingerprintFrames = 3
+
+// variableParts match the parts of error messages that differ between
+// occurrences of one failure, in the order they are replaced
+var variableParts = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`"[^"]*"|'[^']*'|` + "`[^`]*`"), "<str>"},
+	{regexp.MustCompile(`(?i)\b[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}\b`), "<id>"},
+	{regexp.MustCompile(`(?i)\b(?:0x[0-9a-f]+|[0-9a-f]{8,})\b`), "<hex>"},
+	{regexp.MustCompile(`\d+(?:\.\d+)?`), "<n>"},
+}
+
+// NormalizeMessage replaces quoted strings, IDs and numbers in an error
+// message with placeholders, so occurrences of one failure compare equal
+func NormalizeMessage(message string) string {
+	for _, v := range variableParts {
+		message = v.pattern.ReplaceAllString(message, v.replacement)
+	}
+	return strings.Join(strings.Fields(message), " ")
+}
+
+// Fingerprint returns a stable identifier of the kind of failure err is,
+// for grouping recurring failures across runs. It hashes the codes of the
+// error chain, the normalized message of the outermost error and of the
+// root cause, and the top stack frames where the innermost *Error was
+// created. Line numbers are left out, so fingerprints survive unrelated
+// edits. Fingerprint returns "" for a nil error.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	h := sha256.New()
+	write := func(s string) {
+		h.Write([]byte(s))
+		h.Write([]byte{0})
+	}
+
+	var origin *Error
+	for cause := err; cause != nil; cause = stderrors.Unwrap(cause) {
+		if e, ok := cause.(*Error); ok {
+			write(e.Code.String())
+			origin = e
+		}
+	}
+
+	outer := err.Error()
+	var top *Error
+	if stderrors.As(err, &top) {
+		outer = top.Message
+	}
+	write(NormalizeMessage(outer))
+	if root := RootCause(err); root != err {
+		if _, ok := root.(*Error); !ok {
+			write(NormalizeMessage(root.Error()))
+		}
+	}
+
+	if origin != nil {
+		for i, frame := range origin.Stack {
+			if i == fingerprintFrames {
+				break
+			}
+			write(frame.Function + "@" + path.Base(frame.File))
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
@@ -2,8 +2,11 @@ package errors
 
 import (
 	"encoding/json"
+	stderrors "errors"
 	"fmt"
+	"regexp"
 	"strings"
+	"sync/atomic"
 )
 
 // FormatOption defines formatting options for errors
@@ -18,16 +21,48 @@ const (
 	FormatJSON
 	// FormatWithStack outputs error with stack trace
 	FormatWithStack
+	// FormatProduction outputs a single JSON line without stack traces and
+	// with secrets redacted, safe to log at high volume
+	FormatProduction
+	// FormatDeveloper outputs every error of the chain with its context and
+	// stack trace
+	FormatDeveloper
+	// FormatDefault uses the option set with SetDefaultFormat
+	FormatDefault
 )
 
+// defaultFormat is the option FormatDefault selects
+var defaultFormat atomic.Int32
+
+// SetDefaultFormat sets the option FormatDefault selects, e.g.
+// FormatProduction in deployed services (initially FormatSimple)
+func SetDefaultFormat(option FormatOption) {
+	if option == FormatDefault {
+		option = FormatSimple
+	}
+	defaultFormat.Store(int32(option))
+}
+
 // Format formats an error according to the specified option
 func Format(err error, option FormatOption) string {
 	if err == nil {
 		return ""
 	}
+	if option == FormatDefault {
+		option = FormatOption(defaultFormat.Load())
+	}
 
 	e, ok := err.(*Error)
 	if !ok {
+		switch option {
+		case FormatProduction:
+			return formatProduction(FromError(err, ErrUnexpected))
+		case FormatDeveloper:
+			var inner *Error
+			if stderrors.As(err, &inner) {
+				return err.Error() + "\n\n" + formatDeveloper(inner)
+			}
+		}
 		return err.Error()
 	}
 
@@ -40,6 +75,10 @@ func Format(err error, option FormatOption) string {
 		return formatJSON(e)
 	case FormatWithStack:
 		return formatWithStack(e)
+	case FormatProduction:
+		return formatProduction(e)
+	case FormatDeveloper:
+		return formatDeveloper(e)
 	default:
 		return e.Error()
 	}
@@ -155,6 +194,96 @@ func formatWithStack(e *Error) string {
 	return strings.Join(parts, "\n")
 }
 
+// formatProduction returns the error as one JSON line without stack traces,
+// with sensitive context values and secrets in messages redacted
+func formatProduction(e *Error) string {
+	data := map[string]interface{}{
+		"code":      e.Code.String(),
+		"category":  e.Code.Category,
+		"message":   Redact(e.Message),
+		"severity":  e.Severity.String(),
+		"timestamp": e.Timestamp.Format("2006-01-02T15:04:05Z07:00"),
+		"retryable": e.Retryable,
+		"temporary": e.Temporary,
+	}
+
+	if len(e.Context) > 0 {
+		context := make(map[string]interface{}, len(e.Context))
+		for k, v := range e.Context {
+			context[k] = redactValue(k, v)
+		}
+		data["context"] = context
+	}
+
+	if e.Err != nil {
+		data["cause"] = Redact(e.Err.Error())
+	}
+
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Sprintf(`{"error": "failed to marshal error: %v"}`, err)
+	}
+	return string(jsonData)
+}
+
+// formatDeveloper returns every error of the chain with its stack trace,
+// followed by the root cause if it is not an *Error
+func formatDeveloper(e *Error) string {
+	var parts []string
+	chain := ErrorChain(e)
+	for i, link := range chain {
+		if i > 0 {
+			parts = append(parts, fmt.Sprintf("\nCaused by (%d of %d):", i+1, len(chain)))
+		}
+		parts = append(parts, formatWithStack(link))
+	}
+	if root := RootCause(e); root != nil {
+		if _, ok := root.(*Error); !ok {
+			parts = append(parts, fmt.Sprintf("\nRoot cause: %v", root))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+// redacted replaces secrets in production output
+const redacted = "[REDACTED]"
+
+// sensitiveKey matches context keys whose values are secrets
+var sensitiveKey = regexp.MustCompile(`(?i)(api[_-]?key|token|secret|password|passwd|authorization|credential|cookie)`)
+
+// secretPatterns match secrets embedded in messages, with the replacement
+// keeping the text around the secret
+var secretPatterns = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+}{
+	{regexp.MustCompile(`(?i)\b(bearer|basic)\s+[A-Za-z0-9._~+/=-]{8,}`), "${1} " + redacted},
+	{regexp.MustCompile(`(?i)\b((?:api[_-]?key|access[_-]?token|token|secret|password|passwd)["']?\s*[:=]\s*["']?)[^\s"'&,;]+`), "${1}" + redacted},
+	{regexp.MustCompile(`\b(?:sk|pk|rk)-[A-Za-z0-9_-]{16,}`), redacted},
+	{regexp.MustCompile(`\bgh[pousr]_[A-Za-z0-9]{20,}`), redacted},
+	{regexp.MustCompile(`\bxox[abprs]-[A-Za-z0-9-]{10,}`), redacted},
+	{regexp.MustCompile(`\bAKIA[0-9A-Z]{16}\b`), redacted},
+}
+
+// Redact replaces API keys, tokens and passwords in s with [REDACTED]
+func Redact(s string) string {
+	for _, p := range secretPatterns {
+		s = p.pattern.ReplaceAllString(s, p.replacement)
+	}
+	return s
+}
+
+// redactValue redacts a context value, entirely if its key names a secret
+func redactValue(key string, value interface{}) interface{} {
+	if sensitiveKey.MatchString(key) {
+		return redacted
+	}
+	if s, ok := value.(string); ok {
+		return Redact(s)
+	}
+	return value
+}
+
 // MarshalJSON implements json.Marshaler
 func (e *Error) MarshalJSON() ([]byte, error) {
 	data := map[string]interface{}{
@@ -161,3 +161,56 @@ func TestRootCause_SingleError(t *testing.T) {
 		t.Errorf("RootCause() should return the error itself when there's no wrapped error")
 	}
 }
+
+func TestFormat_Production(t *testing.T) {
+	err := Wrap(ErrAPICall, "request with Authorization: Bearer abcdef123456789 failed",
+		errors.New("upstream said:\ninvalid api_key=sk-abcdefghijklmnopqrstuvwx")).
+		WithContext("api_key", "sk-live-123").
+		WithContext("url", "https://api.example.com?token=secret123")
+
+	got := Format(err, FormatProduction)
+	if strings.Contains(got, "\n") {
+		t.Errorf("Format(FormatProduction) spans several lines: %q", got)
+	}
+	for _, secret := range []string{"abcdef123456789", "sk-abcdefghijklmnopqrstuvwx", "sk-live-123", "secret123"} {
+		if strings.Contains(got, secret) {
+			t.Errorf("Format(FormatProduction) leaks %q: %s", secret, got)
+		}
+	}
+
+	var data map[string]interface{}
+	if err := json.Unmarshal([]byte(got), &data); err != nil {
+		t.Fatalf("Format(FormatProduction) is not JSON: %v", err)
+	}
+	if _, ok := data["stack"]; ok {
+		t.Error("Format(FormatProduction) includes a stack trace")
+	}
+	if data["code"] != "api.call_failed" {
+		t.Errorf("code = %v, want api.call_failed", data["code"])
+	}
+
+	plain := Format(errors.New("password=hunter2"), FormatProduction)
+	if strings.Contains(plain, "hunter2") || json.Unmarshal([]byte(plain), &data) != nil {
+		t.Errorf("Format(FormatProduction) of a plain error = %s, want redacted JSON", plain)
+	}
+}
+
+func TestFormat_DeveloperAndDefault(t *testing.T) {
+	err := Wrap(ErrInternal, "outer", Wrap(ErrNetworkTimeout, "inner", errors.New("dial tcp: i/o timeout")))
+
+	got := Format(err, FormatDeveloper)
+	for _, want := range []string{"outer", "Caused by (2 of 2)", "inner", "Root cause: dial tcp", "Stack trace:"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Format(FormatDeveloper) should contain %q:\n%s", want, got)
+		}
+	}
+
+	defer SetDefaultFormat(FormatSimple)
+	if got := Format(err, FormatDefault); got != formatSimple(err) {
+		t.Errorf("Format(FormatDefault) = %q, want the simple format", got)
+	}
+	SetDefaultFormat(FormatProduction)
+	if got := Format(err, FormatDefault); !strings.HasPrefix(got, "{") {
+		t.Errorf("Format(FormatDefault) after SetDefaultFormat(FormatProduction) = %q, want JSON", got)
+	}
+}
@@ -0,0 +1,231 @@
+package events
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Policy decides what happens when a subscriber's buffer is full
+type Policy string
+
+// Slow-consumer policies
+const (
+	// PolicyDrop discards the event for that subscriber (default)
+	PolicyDrop Policy = "drop"
+	// PolicyBlock waits up to the subscriber's BlockTimeout for buffer space,
+	// then discards the event
+	PolicyBlock Policy = "block"
+	// PolicyDisconnect closes the subscription
+	PolicyDisconnect Policy = "disconnect"
+)
+
+// Defaults applied to SubscriberConfig
+const (
+	DefaultSubscriberBuffer = 256
+	DefaultBlockTimeout     = time.Second
+)
+
+// SubscriberConfig configures a subscription
+type SubscriberConfig struct {
+	Name   string // Shown in stats, e.g. the websocket's remote address
+	Buffer int    // Events buffered for the subscriber (0 = DefaultSubscriberBuffer)
+	Policy Policy // What to do when the buffer is full (empty = PolicyDrop)
+	// BlockTimeout bounds how long PolicyBlock stalls the publisher
+	// (0 = DefaultBlockTimeout)
+	BlockTimeout time.Duration
+}
+
+// SubscriberStats reports the delivery metrics of a subscriber
+type SubscriberStats struct {
+	Name         string `json:"name"`
+	Policy       Policy `json:"policy"`
+	Buffered     int    `json:"buffered"`  // Events waiting to be read
+	Delivered    int64  `json:"delivered"` // Events placed in the buffer
+	Dropped      int64  `json:"dropped"`   // Events discarded because the buffer was full
+	Disconnected bool   `json:"disconnected"`
+}
+
+// Broadcaster is a Sink that fans events out to any number of subscribers,
+// such as the websockets of a server or the output of a CLI. Each subscriber
+// has its own buffer and slow-consumer policy, so one slow reader does not
+// stall the run or the other subscribers. It is safe for concurrent use.
+type Broadcaster struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+// NewBroadcaster creates a broadcaster without subscribers
+func NewBroadcaster() *Broadcaster {
+	return &Broadcaster{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events of a Broadcaster
+type Subscription struct {
+	b      *Broadcaster
+	config SubscriberConfig
+	ch     chan Event
+	done   chan struct{}
+	once   sync.Once
+
+	delivered    atomic.Int64
+	dropped      atomic.Int64
+	disconnected atomic.Bool
+}
+
+// Subscribe registers a subscriber. Read its events from Events and call
+// Close when done.
+func (b *Broadcaster) Subscribe(cfg SubscriberConfig) (*Subscription, error) {
+	switch cfg.Policy {
+	case "":
+		cfg.Policy = PolicyDrop
+	case PolicyDrop, PolicyBlock, PolicyDisconnect:
+	default:
+		return nil, errors.InvalidField("policy", "must be drop, block or disconnect").WithContext("value", string(cfg.Policy))
+	}
+	if cfg.Buffer < 0 {
+		return nil, errors.InvalidField("buffer", "must not be negative")
+	}
+	if cfg.Buffer == 0 {
+		cfg.Buffer = DefaultSubscriberBuffer
+	}
+	if cfg.BlockTimeout <= 0 {
+		cfg.BlockTimeout = DefaultBlockTimeout
+	}
+
+	s := &Subscription{
+		b:      b,
+		config: cfg,
+		ch:     make(chan Event, cfg.Buffer),
+		done:   make(chan struct{}),
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, errors.New(errors.ErrInternal, "broadcaster is closed")
+	}
+	b.subs[s] = struct{}{}
+	return s, nil
+}
+
+// Publish delivers the event to every subscriber according to its policy. It
+// never fails; slow subscribers show up in Stats.
+func (b *Broadcaster) Publish(ctx context.Context, event Event) error {
+	var slow []*Subscription
+	b.mu.RLock()
+	for s := range b.subs {
+		if !s.deliver(ctx, event) {
+			slow = append(slow, s)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, s := range slow {
+		s.disconnected.Store(true)
+		s.Close()
+	}
+	return nil
+}
+
+// Stats returns the metrics of the current subscribers, sorted by name
+func (b *Broadcaster) Stats() []SubscriberStats {
+	b.mu.RLock()
+	stats := make([]SubscriberStats, 0, len(b.subs))
+	for s := range b.subs {
+		stats = append(stats, s.Stats())
+	}
+	b.mu.RUnlock()
+	sort.SliceStable(stats, func(i, j int) bool { return stats[i].Name < stats[j].Name })
+	return stats
+}
+
+// Close closes every subscription and rejects new ones
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	b.closed = true
+	subs := make([]*Subscription, 0, len(b.subs))
+	for s := range b.subs {
+		subs = append(subs, s)
+	}
+	b.mu.Unlock()
+
+	for _, s := range subs {
+		s.Close()
+	}
+}
+
+// deliver places the event in the subscriber's buffer. It returns false when
+// the subscriber must be disconnected. b.mu must be read-locked.
+func (s *Subscription) deliver(ctx context.Context, event Event) bool {
+	select {
+	case <-s.done:
+		return true
+	case s.ch <- event:
+		s.delivered.Add(1)
+		return true
+	default:
+	}
+
+	switch s.config.Policy {
+	case PolicyDisconnect:
+		s.dropped.Add(1)
+		return false
+	case PolicyBlock:
+		timer := time.NewTimer(s.config.BlockTimeout)
+		defer timer.Stop()
+		select {
+		case s.ch <- event:
+			s.delivered.Add(1)
+			return true
+		case <-s.done:
+			return true
+		case <-ctx.Done():
+		case <-timer.C:
+		}
+	}
+	s.dropped.Add(1)
+	return true
+}
+
+// Events returns the channel of events. It is closed when the subscription
+// is closed, by the subscriber, the broadcaster or the disconnect policy.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Disconnected reports whether the subscription was closed by the disconnect
+// policy
+func (s *Subscription) Disconnected() bool {
+	return s.disconnected.Load()
+}
+
+// Stats returns the subscriber's metrics
+func (s *Subscription) Stats() SubscriberStats {
+	return SubscriberStats{
+		Name:         s.config.Name,
+		Policy:       s.config.Policy,
+		Buffered:     len(s.ch),
+		Delivered:    s.delivered.Load(),
+		Dropped:      s.dropped.Load(),
+		Disconnected: s.disconnected.Load(),
+	}
+}
+
+// Close unsubscribes and closes the events channel. Buffered events can
+// still be read. It is safe to call more than once.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		// Unblock a publisher waiting on this subscriber before taking the
+		// write lock it holds for reading
+		close(s.done)
+		s.b.mu.Lock()
+		delete(s.b.subs, s)
+		close(s.ch)
+		s.b.mu.Unlock()
+	})
+}
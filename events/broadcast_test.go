@@ -0,0 +1,73 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestBroadcaster_SlowConsumerPolicies(t *testing.T) {
+	ctx := context.Background()
+	b := NewBroadcaster()
+	fast, _ := b.Subscribe(SubscriberConfig{Name: "fast", Buffer: 10})
+	dropping, _ := b.Subscribe(SubscriberConfig{Name: "drop", Buffer: 1})
+	blocking, _ := b.Subscribe(SubscriberConfig{Name: "block", Buffer: 1, Policy: PolicyBlock, BlockTimeout: 10 * time.Millisecond})
+	disconnecting, _ := b.Subscribe(SubscriberConfig{Name: "disconnect", Buffer: 1, Policy: PolicyDisconnect})
+
+	for i := 0; i < 3; i++ {
+		if err := b.Publish(ctx, New(TaskCompleted, "run-1")); err != nil {
+			t.Fatalf("Publish() unexpected error: %v", err)
+		}
+	}
+
+	if got := fast.Stats(); got.Delivered != 3 || got.Dropped != 0 {
+		t.Errorf("fast stats = %+v", got)
+	}
+	for _, s := range []*Subscription{dropping, blocking} {
+		if got := s.Stats(); got.Delivered != 1 || got.Dropped != 2 || got.Disconnected {
+			t.Errorf("%s stats = %+v, want 1 delivered and 2 dropped", got.Name, got)
+		}
+	}
+	if !disconnecting.Disconnected() {
+		t.Error("disconnect subscriber still connected")
+	}
+	n := 0
+	for range disconnecting.Events() {
+		n++
+	}
+	if n != 1 {
+		t.Errorf("disconnected subscriber read %d events, want the 1 buffered", n)
+	}
+	if got := len(b.Stats()); got != 3 {
+		t.Errorf("Stats() has %d subscribers, want 3", got)
+	}
+
+	if _, err := b.Subscribe(SubscriberConfig{Policy: "retry"}); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("Subscribe() with unknown policy error = %v, want ErrInvalidField", err)
+	}
+	b.Close()
+	if _, ok := <-fast.Events(); !ok {
+		t.Error("buffered events lost on Close")
+	}
+}
+
+func TestBroadcaster_CloseUnblocksPublisher(t *testing.T) {
+	b := NewBroadcaster()
+	s, _ := b.Subscribe(SubscriberConfig{Buffer: 1, Policy: PolicyBlock, BlockTimeout: time.Minute})
+	_ = b.Publish(context.Background(), New(RunStarted, "run-1"))
+
+	published := make(chan struct{})
+	go func() {
+		_ = b.Publish(context.Background(), New(RunCompleted, "run-1"))
+		close(published)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	s.Close()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("Publish() still blocked after the subscriber closed")
+	}
+}
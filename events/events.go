@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// SchemaVersion is the version of the Event JSON schema. It is bumped on any
+// incompatible change so that downstream consumers can branch on it.
+const SchemaVersion = 1
+
+// Type identifies the kind of an event
+type Type string
+
+// Event types emitted by the orchestrator
+const (
+	RunStarted    Type = "run.started"
+	RunCompleted  Type = "run.completed"
+	RunFailed     Type = "run.failed"
+	TaskStarted   Type = "task.started"
+	TaskCompleted Type = "task.completed"
+	TaskFailed    Type = "task.failed"
+)
+
+// Event types emitted when a watched config is reloaded
+const (
+	ConfigReloaded     Type = "config.reloaded"
+	ConfigReloadFailed Type = "config.reload_failed" // The previous config stays active
+)
+
+// Event describes a single occurrence during a run
+type Event struct {
+	SchemaVersion int       `json:"schema_version"`
+	Type          Type      `json:"type"`
+	RunID         string    `json:"run_id"`
+	Timestamp     time.Time `json:"timestamp"`
+	Task          string    `json:"task,omitempty"`
+	Agent         string    `json:"agent,omitempty"`
+	Result        string    `json:"result,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	// ErrorFingerprint groups recurring failures across runs (see
+	// errors.Fingerprint)
+	ErrorFingerprint string `json:"error_fingerprint,omitempty"`
+	DurationMS       int64  `json:"duration_ms,omitempty"`
+
+	// Resource usage of the task or run (completion events only)
+	LLMCalls         int     `json:"llm_calls,omitempty"`
+	PromptTokens     int     `json:"prompt_tokens,omitempty"`
+	CompletionTokens int     `json:"completion_tokens,omitempty"`
+	CachedTokens     int     `json:"cached_tokens,omitempty"` // Prompt tokens served from the provider's cache
+	CostUSD          float64 `json:"cost_usd,omitempty"`
+	Continuations    int     `json:"continuations,omitempty"`
+	Reused           bool    `json:"reused,omitempty"` // Result taken from a checkpoint or result store
+	// Retry counts the times RetryFailed re-executed the task, including
+	// this execution (task completion events only)
+	Retry int `json:"retry,omitempty"`
+
+	// SharedPrefixTokens are prompt tokens repeating a prefix shared by
+	// parallel tasks, left to the provider's prompt cache (run events only)
+	SharedPrefixTokens int `json:"shared_prefix_tokens,omitempty"`
+
+	// Source is the location of a reloaded config (config events only)
+	Source string `json:"source,omitempty"`
+
+	// Tools summarizes tool executions by tool name (completion events only)
+	Tools map[string]ToolUsage `json:"tools,omitempty"`
+}
+
+// ToolUsage summarizes the executions of one tool
+type ToolUsage struct {
+	Calls         int   `json:"calls"`
+	Failures      int   `json:"failures,omitempty"`
+	DurationMS    int64 `json:"duration_ms"` // Total time spent in the tool
+	MaxDurationMS int64 `json:"max_duration_ms"`
+	OutputBytes   int   `json:"output_bytes"`
+}
+
+// New creates an event of the given type stamped with the current time
+func New(typ Type, runID string) Event {
+	return Event{
+		SchemaVersion: SchemaVersion,
+		Type:          typ,
+		RunID:         runID,
+		Timestamp:     time.Now().UTC(),
+	}
+}
+
+// Sink receives events
+type Sink interface {
+	// Publish delivers an event. Implementations must be safe for concurrent use.
+	Publish(ctx context.Context, event Event) error
+}
+
+// MultiSink fans events out to several sinks
+type MultiSink []Sink
+
+// Publish delivers the event to every sink and returns the first error
+func (m MultiSink) Publish(ctx context.Context, event Event) error {
+	var firstErr error
+	for _, s := range m {
+		if err := s.Publish(ctx, event); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// WriterSink writes events as JSON lines to an io.Writer
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink writing JSON lines to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Publish writes the event as a single JSON line
+func (s *WriterSink) Publish(ctx context.Context, event Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal event", err).WithContext("type", event.Type)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.w.Write(append(data, '\n')); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write event", err).WithContext("type", event.Type)
+	}
+	return nil
+}
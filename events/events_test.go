@@ -0,0 +1,226 @@
+package events
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriterSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := NewWriterSink(&buf)
+
+	if err := sink.Publish(context.Background(), New(TaskCompleted, "run-1")); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+
+	var got Event
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("output is not valid JSON: %v", err)
+	}
+	if got.Type != TaskCompleted || got.RunID != "run-1" || got.SchemaVersion != SchemaVersion {
+		t.Errorf("Publish() wrote %+v", got)
+	}
+}
+
+func TestNATSSink_Publish(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {}\r\n"))
+		r := bufio.NewReader(conn)
+		_, _ = r.ReadString('\n') // CONNECT
+		pub, _ := r.ReadString('\n')
+		received <- strings.TrimSpace(pub)
+	}()
+
+	sink, err := NewNATSSink(NATSConfig{Addr: ln.Addr().String(), SubjectPrefix: "gittyai.events"})
+	if err != nil {
+		t.Fatalf("NewNATSSink() unexpected error: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish(context.Background(), New(RunStarted, "run-1")); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+
+	if got := <-received; !strings.HasPrefix(got, "PUB gittyai.events.run.started ") {
+		t.Errorf("server received %q, want PUB on gittyai.events.run.started", got)
+	}
+}
+
+func TestNATSSink_AnswersPing(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 2)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {}\r\n"))
+		r := bufio.NewReader(conn)
+		_, _ = r.ReadString('\n') // CONNECT
+		pub, _ := r.ReadString('\n')
+		_, _ = r.ReadString('\n') // payload
+		received <- strings.TrimSpace(pub)
+
+		// The server pings the idle client and expects a PONG
+		_, _ = conn.Write([]byte("PING\r\n"))
+		_ = conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		pong, _ := r.ReadString('\n')
+		received <- strings.TrimSpace(pong)
+	}()
+
+	sink, err := NewNATSSink(NATSConfig{Addr: ln.Addr().String(), SubjectPrefix: "gittyai.events"})
+	if err != nil {
+		t.Fatalf("NewNATSSink() unexpected error: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish(context.Background(), New(RunStarted, "run-1")); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+	if got := <-received; !strings.HasPrefix(got, "PUB ") {
+		t.Fatalf("server received %q, want PUB", got)
+	}
+	if got := <-received; got != "PONG" {
+		t.Errorf("server received %q after PING, want PONG", got)
+	}
+}
+
+func TestNATSSink_ReportsServerError(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen: %v", err)
+	}
+	defer ln.Close()
+
+	rejected := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _ = conn.Write([]byte("INFO {}\r\n"))
+		r := bufio.NewReader(conn)
+		_, _ = r.ReadString('\n') // CONNECT
+		_, _ = r.ReadString('\n') // PUB
+		_, _ = r.ReadString('\n') // payload
+		_, _ = conn.Write([]byte("-ERR 'Permissions Violation for Publish to gittyai.events.run.started'\r\n"))
+		close(rejected)
+	}()
+
+	sink, err := NewNATSSink(NATSConfig{Addr: ln.Addr().String(), SubjectPrefix: "gittyai.events"})
+	if err != nil {
+		t.Fatalf("NewNATSSink() unexpected error: %v", err)
+	}
+	defer sink.Close()
+
+	if err := sink.Publish(context.Background(), New(RunStarted, "run-1")); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+	<-rejected
+
+	// The error arrives asynchronously; the next Publish reports it
+	deadline := time.Now().Add(5 * time.Second)
+	for time.Now().Before(deadline) {
+		sink.mu.Lock()
+		pending := sink.err != nil
+		sink.mu.Unlock()
+		if pending {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	err = sink.Publish(context.Background(), New(RunCompleted, "run-1"))
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Fatalf("Publish() error = %v, want the server's -ERR", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Errorf("Close() error = %v, want nil once the error was reported", err)
+	}
+}
+
+func TestKafkaSink_Publish(t *testing.T) {
+	var path, contentType string
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		contentType = r.Header.Get("Content-Type")
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	sink, err := NewKafkaSink(KafkaConfig{RESTProxyURL: server.URL, Topic: "agent-events"})
+	if err != nil {
+		t.Fatalf("NewKafkaSink() unexpected error: %v", err)
+	}
+
+	if err := sink.Publish(context.Background(), New(TaskFailed, "run-1")); err != nil {
+		t.Fatalf("Publish() unexpected error: %v", err)
+	}
+
+	if path != "/topics/agent-events" {
+		t.Errorf("path = %q, want /topics/agent-events", path)
+	}
+	if contentType != "application/vnd.kafka.json.v2+json" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	if !bytes.Contains(body, []byte(`"key":"run-1"`)) {
+		t.Errorf("body = %s, want record keyed by run ID", body)
+	}
+}
+
+func TestKafkaSink_Timeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	sink, err := NewKafkaSink(KafkaConfig{RESTProxyURL: server.URL, Topic: "agent-events", Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewKafkaSink() unexpected error: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- sink.Publish(context.Background(), New(RunStarted, "run-1")) }()
+	select {
+	case err := <-done:
+		if err == nil {
+			t.Error("Publish() to a hung proxy succeeded, want an error")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish() to a hung proxy did not time out")
+	}
+}
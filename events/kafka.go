@@ -0,0 +1,96 @@
+package events
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// KafkaConfig represents the configuration for a Kafka sink
+type KafkaConfig struct {
+	// RESTProxyURL is the base URL of a Kafka REST proxy (e.g. Confluent REST
+	// Proxy at "http://localhost:8082")
+	RESTProxyURL string
+	// Topic receives all events; records are keyed by run ID so that events
+	// of a run land on the same partition in order
+	Topic string
+	// Headers allows custom HTTP headers, e.g. for authentication
+	Headers map[string]string
+	// Timeout bounds each publish (0 = 10 seconds)
+	Timeout time.Duration
+}
+
+// maxKafkaErrorBody caps how much of an error response is read into the
+// returned error
+const maxKafkaErrorBody = 64 << 10
+
+// KafkaSink publishes events to a Kafka topic through a REST proxy
+type KafkaSink struct {
+	config KafkaConfig
+	client *http.Client
+}
+
+// NewKafkaSink creates a Kafka sink
+func NewKafkaSink(cfg KafkaConfig) (*KafkaSink, error) {
+	if cfg.RESTProxyURL == "" {
+		return nil, errors.RequiredField("REST proxy URL")
+	}
+	if cfg.Topic == "" {
+		return nil, errors.RequiredField("topic")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	return &KafkaSink{
+		config: cfg,
+		client: &http.Client{Timeout: cfg.Timeout},
+	}, nil
+}
+
+type kafkaRecord struct {
+	Key   string `json:"key,omitempty"`
+	Value Event  `json:"value"`
+}
+
+type kafkaRequest struct {
+	Records []kafkaRecord `json:"records"`
+}
+
+// Publish produces the event as a JSON record
+func (s *KafkaSink) Publish(ctx context.Context, event Event) error {
+	body, err := json.Marshal(kafkaRequest{Records: []kafkaRecord{{Key: event.RunID, Value: event}}})
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal event", err).WithContext("type", event.Type)
+	}
+
+	endpoint := fmt.Sprintf("%s/topics/%s", strings.TrimRight(s.config.RESTProxyURL, "/"), url.PathEscape(s.config.Topic))
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", endpoint)
+	}
+
+	req.Header.Set("Content-Type", "application/vnd.kafka.json.v2+json")
+	for key, value := range s.config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return errors.APICallError("publish event to Kafka", err).WithContext("topic", s.config.Topic)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxKafkaErrorBody))
+		return errors.APIStatusCodeError(resp.StatusCode, string(respBody)).WithContext("topic", s.config.Topic)
+	}
+	return nil
+}
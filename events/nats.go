@@ -0,0 +1,196 @@
+package events
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// NATSConfig represents the configuration for a NATS sink
+type NATSConfig struct {
+	// Addr is the NATS server address (default: "localhost:4222")
+	Addr string
+	// SubjectPrefix is prepended to the event type, e.g. "gittyai.events"
+	// publishes task completions to "gittyai.events.task.completed"
+	SubjectPrefix string
+	// Token is an optional auth token
+	Token string
+	// DialTimeout bounds connection setup (default: 5s)
+	DialTimeout time.Duration
+	// WriteTimeout bounds each publish when the context has no earlier
+	// deadline, so a stalled server cannot block publishers (default: 5s)
+	WriteTimeout time.Duration
+}
+
+// NATSSink publishes events to NATS subjects using the core text protocol
+type NATSSink struct {
+	config NATSConfig
+
+	mu   sync.Mutex
+	conn net.Conn
+	// err is the last -ERR sent by the server, reported by the next Publish
+	// or Close
+	err error
+}
+
+// NewNATSSink creates a NATS sink. The connection is established lazily on
+// the first Publish and re-established after write failures.
+func NewNATSSink(cfg NATSConfig) (*NATSSink, error) {
+	if cfg.SubjectPrefix == "" {
+		return nil, errors.RequiredField("subject prefix")
+	}
+	if cfg.Addr == "" {
+		cfg.Addr = "localhost:4222"
+	}
+	if cfg.DialTimeout <= 0 {
+		cfg.DialTimeout = 5 * time.Second
+	}
+	if cfg.WriteTimeout <= 0 {
+		cfg.WriteTimeout = 5 * time.Second
+	}
+	return &NATSSink{config: cfg}, nil
+}
+
+// Subject returns the subject an event is published to
+func (s *NATSSink) Subject(event Event) string {
+	return s.config.SubjectPrefix + "." + string(event.Type)
+}
+
+// Publish sends the event as a JSON payload
+func (s *NATSSink) Publish(ctx context.Context, event Event) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal event", err).WithContext("type", event.Type)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.takeErr(); err != nil {
+		return err
+	}
+	if s.conn == nil {
+		if err := s.connect(ctx); err != nil {
+			return err
+		}
+	}
+
+	deadline := time.Now().Add(s.config.WriteTimeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = s.conn.SetWriteDeadline(deadline)
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\n", s.Subject(event), len(payload), payload)
+	if _, err := s.conn.Write([]byte(msg)); err != nil {
+		s.conn.Close()
+		s.conn = nil
+		return errors.Wrap(errors.ErrNetworkUnavail, "failed to publish event to NATS", err).
+			WithContext("addr", s.config.Addr).
+			WithRetryable(true).
+			WithTemporary(true)
+	}
+	return nil
+}
+
+// Close closes the underlying connection. It reports an error the server
+// sent that no Publish has returned yet.
+func (s *NATSSink) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	serverErr := s.takeErr()
+	if s.conn == nil {
+		return serverErr
+	}
+	err := s.conn.Close()
+	s.conn = nil
+	if serverErr != nil {
+		return serverErr
+	}
+	return err
+}
+
+// takeErr returns and clears the pending server error. Callers must hold
+// s.mu.
+func (s *NATSSink) takeErr() error {
+	err := s.err
+	s.err = nil
+	return err
+}
+
+// connect dials the server, consumes its INFO line and sends CONNECT.
+// Callers must hold s.mu.
+func (s *NATSSink) connect(ctx context.Context) error {
+	dialer := net.Dialer{Timeout: s.config.DialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", s.config.Addr)
+	if err != nil {
+		return errors.Wrap(errors.ErrNetworkRefused, "failed to connect to NATS", err).
+			WithContext("addr", s.config.Addr).
+			WithRetryable(true).
+			WithTemporary(true)
+	}
+
+	_ = conn.SetReadDeadline(time.Now().Add(s.config.DialTimeout))
+	reader := bufio.NewReader(conn)
+	info, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(info, "INFO") {
+		conn.Close()
+		return errors.Network("NATS server did not send INFO").WithContext("addr", s.config.Addr)
+	}
+	_ = conn.SetReadDeadline(time.Time{})
+
+	opts := map[string]interface{}{"verbose": false, "pedantic": false, "name": "gittyai"}
+	if s.config.Token != "" {
+		opts["auth_token"] = s.config.Token
+	}
+	connectJSON, _ := json.Marshal(opts)
+	if _, err := conn.Write([]byte("CONNECT " + string(connectJSON) + "\r\n")); err != nil {
+		conn.Close()
+		return errors.Wrap(errors.ErrNetworkUnavail, "failed to send CONNECT to NATS", err).WithContext("addr", s.config.Addr)
+	}
+
+	s.conn = conn
+	go s.read(conn, reader)
+	return nil
+}
+
+// read consumes what the server sends on conn until it is closed, answering
+// its PINGs so it does not drop the connection as stale and keeping its
+// -ERR messages for the next Publish or Close. A broken connection is
+// dropped and re-established on the next Publish.
+func (s *NATSSink) read(conn net.Conn, reader *bufio.Reader) {
+	for {
+		line, err := reader.ReadString('\n')
+		if err == nil && strings.HasPrefix(line, "-ERR") {
+			msg := strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'")
+			s.mu.Lock()
+			s.err = errors.Newf(errors.ErrAPIResponse, "NATS server error: %s", msg).WithContext("addr", s.config.Addr)
+			s.mu.Unlock()
+		}
+		if err == nil && strings.HasPrefix(line, "PING") {
+			s.mu.Lock()
+			if s.conn == conn {
+				_ = conn.SetWriteDeadline(time.Now().Add(s.config.WriteTimeout))
+				_, err = conn.Write([]byte("PONG\r\n"))
+			}
+			s.mu.Unlock()
+		}
+		if err != nil {
+			s.mu.Lock()
+			if s.conn == conn {
+				s.conn.Close()
+				s.conn = nil
+			}
+			s.mu.Unlock()
+			return
+		}
+	}
+}
@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"flag"
 	"fmt"
 	"log"
 	"os"
@@ -16,15 +17,23 @@ func main() {
 	// Example 1: Programmatic API usage
 	// This example creates agents and tasks programmatically
 
+	// -simulate runs the workflow against a synthetic LLM without API calls
+	simulate := flag.Bool("simulate", false, "use canned LLM responses instead of calling OpenAI")
+	flag.Parse()
+
 	// Create LLM provider
-	llmProvider, err := llm.NewOpenAI(llm.Config{
-		APIKey:      getEnv("OPENAI_API_KEY", "your-api-key"),
-		Model:       "gpt-4o-mini",
-		Temperature: 0.7,
-		MaxTokens:   2000,
-	})
-	if err != nil {
-		log.Fatalf("Failed to create LLM provider: %v", err)
+	var llmProvider llm.LLM = llm.NewSimulator(llm.SimulatorConfig{Echo: true})
+	if !*simulate {
+		var err error
+		llmProvider, err = llm.NewOpenAI(llm.Config{
+			APIKey:      getEnv("OPENAI_API_KEY", "your-api-key"),
+			Model:       "gpt-4o-mini",
+			Temperature: 0.7,
+			MaxTokens:   2000,
+		})
+		if err != nil {
+			log.Fatalf("Failed to create LLM provider: %v", err)
+		}
 	}
 
 	// Create agents
@@ -0,0 +1,288 @@
+package flow
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+)
+
+// StepFunc is a single step in a flow. It receives the shared state and the
+// output of the step (or router) that triggered it, and returns its own output.
+type StepFunc[S any] func(ctx context.Context, state *S, input string) (string, error)
+
+// RouterFunc inspects the output of a step and returns the name of the event
+// to emit. Steps listening on that event are triggered next.
+type RouterFunc[S any] func(ctx context.Context, state *S, output string) (string, error)
+
+// step holds a registered step function
+type step[S any] struct {
+	name string
+	fn   StepFunc[S]
+}
+
+// Flow is an event-driven workflow over a typed state struct.
+// Steps are registered with Start and Listen; control flow between steps is
+// decided by listeners and optional routers attached to step results.
+type Flow[S any] struct {
+	state     *S
+	steps     map[string]*step[S]
+	start     string
+	listeners map[string][]string // event (step or route name) -> listening steps
+	routers   map[string]RouterFunc[S]
+	maxSteps  int
+	verbose   bool
+	logger    Logger
+	err       error
+}
+
+// Logger receives progress messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
+// Config represents the configuration for creating a Flow
+type Config struct {
+	// MaxSteps bounds the number of step executions, protecting against
+	// router cycles that never terminate (default: 100)
+	MaxSteps int
+	Verbose  bool
+	// Logger receives progress messages when Verbose (default: stdout)
+	Logger Logger
+}
+
+// StepResult records a single executed step
+type StepResult struct {
+	Step   string
+	Input  string
+	Output string
+	Route  string // Event emitted by the step's router, if any
+}
+
+// Result holds the outcome of a flow run
+type Result[S any] struct {
+	State   *S
+	Steps   []StepResult
+	Outputs map[string]string // Last output of each executed step
+}
+
+// Output returns the output of the last executed step
+func (r *Result[S]) Output() string {
+	if len(r.Steps) == 0 {
+		return ""
+	}
+	return r.Steps[len(r.Steps)-1].Output
+}
+
+// New creates a new Flow operating on the given state
+func New[S any](state *S, cfg Config) *Flow[S] {
+	if state == nil {
+		state = new(S)
+	}
+
+	maxSteps := cfg.MaxSteps
+	if maxSteps <= 0 {
+		maxSteps = 100
+	}
+
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New(os.Stdout, "", 0)
+	}
+
+	return &Flow[S]{
+		state:     state,
+		steps:     make(map[string]*step[S]),
+		listeners: make(map[string][]string),
+		routers:   make(map[string]RouterFunc[S]),
+		maxSteps:  maxSteps,
+		verbose:   cfg.Verbose,
+		logger:    logger,
+	}
+}
+
+// State returns the flow's shared state
+func (f *Flow[S]) State() *S {
+	return f.state
+}
+
+// Start registers the entry step of the flow
+func (f *Flow[S]) Start(name string, fn StepFunc[S]) *Flow[S] {
+	if f.start != "" {
+		f.setErr(errors.InvalidConfig("flow", fmt.Sprintf("start step already set to '%s'", f.start)).WithContext("step", name))
+		return f
+	}
+	if f.addStep(name, fn) {
+		f.start = name
+	}
+	return f
+}
+
+// Listen registers a step that runs whenever the given event fires.
+// An event is either the name of a step (fired when it completes without a
+// router) or a route name returned by a router.
+func (f *Flow[S]) Listen(event, name string, fn StepFunc[S]) *Flow[S] {
+	if event == "" {
+		f.setErr(errors.RequiredField("event").WithContext("step", name))
+		return f
+	}
+	if f.addStep(name, fn) {
+		f.listeners[event] = append(f.listeners[event], name)
+	}
+	return f
+}
+
+// On subscribes an already registered step to an additional event, which
+// allows routers to loop back to earlier steps
+func (f *Flow[S]) On(event, stepName string) *Flow[S] {
+	if event == "" {
+		f.setErr(errors.RequiredField("event").WithContext("step", stepName))
+		return f
+	}
+	if _, exists := f.steps[stepName]; !exists {
+		f.setErr(errors.Validationf("event '%s' references non-existent step: %s", event, stepName))
+		return f
+	}
+	f.listeners[event] = append(f.listeners[event], stepName)
+	return f
+}
+
+// Router attaches a router to a step. When the step completes, the router
+// decides which event to emit instead of the step's own name.
+func (f *Flow[S]) Router(stepName string, fn RouterFunc[S]) *Flow[S] {
+	if fn == nil {
+		f.setErr(errors.RequiredField("router").WithContext("step", stepName))
+		return f
+	}
+	if _, exists := f.routers[stepName]; exists {
+		f.setErr(errors.Validationf("router for step '%s' already registered", stepName))
+		return f
+	}
+	f.routers[stepName] = fn
+	return f
+}
+
+// addStep registers a step function, recording an error on failure
+func (f *Flow[S]) addStep(name string, fn StepFunc[S]) bool {
+	if name == "" {
+		f.setErr(errors.RequiredField("step name"))
+		return false
+	}
+	if fn == nil {
+		f.setErr(errors.RequiredField("step function").WithContext("step", name))
+		return false
+	}
+	if _, exists := f.steps[name]; exists {
+		f.setErr(errors.Validationf("step '%s' already registered", name))
+		return false
+	}
+	f.steps[name] = &step[S]{name: name, fn: fn}
+	return true
+}
+
+// setErr keeps the first registration error, reported by Validate and Kickoff
+func (f *Flow[S]) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
+// Validate checks that the flow is well-formed
+func (f *Flow[S]) Validate() error {
+	if f.err != nil {
+		return f.err
+	}
+	if f.start == "" {
+		return errors.MissingConfig("start step").WithContext("component", "flow")
+	}
+	for stepName := range f.routers {
+		if _, exists := f.steps[stepName]; !exists {
+			return errors.Validationf("router references non-existent step: %s", stepName)
+		}
+	}
+	return nil
+}
+
+// Kickoff runs the flow from its start step until no more events fire
+func (f *Flow[S]) Kickoff(ctx context.Context) (*Result[S], error) {
+	if err := f.Validate(); err != nil {
+		return nil, err
+	}
+
+	result := &Result[S]{
+		State:   f.state,
+		Steps:   make([]StepResult, 0),
+		Outputs: make(map[string]string),
+	}
+
+	type pending struct {
+		step  string
+		input string
+	}
+	queue := []pending{{step: f.start}}
+
+	for len(queue) > 0 {
+		select {
+		case <-ctx.Done():
+			return result, ctx.Err()
+		default:
+		}
+
+		if len(result.Steps) >= f.maxSteps {
+			return result, errors.Newf(errors.ErrOutOfRange, "flow exceeded maximum of %d steps", f.maxSteps).
+				WithContext("max_steps", f.maxSteps)
+		}
+
+		next := queue[0]
+		queue = queue[1:]
+		s := f.steps[next.step]
+
+		if f.verbose {
+			f.logger.Printf("[Flow] Running step '%s'", s.name)
+		}
+
+		output, err := s.fn(ctx, f.state, next.input)
+		if err != nil {
+			return result, errors.Wrap(errors.ErrInternal, fmt.Sprintf("flow step '%s' failed", s.name), err).
+				WithContext("step", s.name)
+		}
+
+		record := StepResult{Step: s.name, Input: next.input, Output: output}
+		event := s.name
+		if router, ok := f.routers[s.name]; ok {
+			route, err := router(ctx, f.state, output)
+			if err != nil {
+				return result, errors.Wrap(errors.ErrInternal, fmt.Sprintf("router for step '%s' failed", s.name), err).
+					WithContext("step", s.name)
+			}
+			record.Route = route
+			event = route
+			if f.verbose {
+				f.logger.Printf("[Flow] Step '%s' routed to '%s'", s.name, route)
+			}
+		}
+
+		result.Steps = append(result.Steps, record)
+		result.Outputs[s.name] = output
+
+		for _, listener := range f.listeners[event] {
+			queue = append(queue, pending{step: listener, input: output})
+		}
+	}
+
+	return result, nil
+}
+
+// AgentStep adapts an agent into a flow step. The prompt function builds the
+// task description from the state and the triggering input.
+func AgentStep[S any](a *agent.Agent, prompt func(state *S, input string) string) StepFunc[S] {
+	return func(ctx context.Context, state *S, input string) (string, error) {
+		if a == nil {
+			return "", errors.MissingConfig("agent").WithContext("component", "flow")
+		}
+		return a.Execute(ctx, prompt(state, input))
+	}
+}
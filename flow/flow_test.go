@@ -0,0 +1,114 @@
+package flow
+
+import (
+	"bytes"
+	"context"
+	"log"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+type reviewState struct {
+	Draft    string
+	Attempts int
+}
+
+func TestFlow_RouterLoop(t *testing.T) {
+	f := New(&reviewState{}, Config{})
+	f.Start("draft", func(ctx context.Context, s *reviewState, input string) (string, error) {
+		s.Attempts++
+		s.Draft = strings.Repeat("x", s.Attempts)
+		return s.Draft, nil
+	}).
+		Router("draft", func(ctx context.Context, s *reviewState, output string) (string, error) {
+			if len(output) < 3 {
+				return "retry", nil
+			}
+			return "approved", nil
+		}).
+		On("retry", "draft").
+		Listen("approved", "publish", func(ctx context.Context, s *reviewState, input string) (string, error) {
+			return "published:" + input, nil
+		})
+
+	result, err := f.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	if result.Output() != "published:xxx" {
+		t.Errorf("Output() = %q, want %q", result.Output(), "published:xxx")
+	}
+	if result.State.Attempts != 3 {
+		t.Errorf("State.Attempts = %d, want 3", result.State.Attempts)
+	}
+	if len(result.Steps) != 4 {
+		t.Errorf("len(Steps) = %d, want 4", len(result.Steps))
+	}
+}
+
+func TestFlow_Validate(t *testing.T) {
+	noop := func(ctx context.Context, s *reviewState, input string) (string, error) { return "", nil }
+
+	tests := []struct {
+		name  string
+		build func() *Flow[reviewState]
+	}{
+		{
+			name: "missing start",
+			build: func() *Flow[reviewState] {
+				return New(&reviewState{}, Config{}).Listen("a", "b", noop)
+			},
+		},
+		{
+			name: "duplicate step",
+			build: func() *Flow[reviewState] {
+				return New(&reviewState{}, Config{}).Start("a", noop).Listen("a", "a", noop)
+			},
+		},
+		{
+			name: "router on unknown step",
+			build: func() *Flow[reviewState] {
+				return New(&reviewState{}, Config{}).Start("a", noop).
+					Router("missing", func(ctx context.Context, s *reviewState, output string) (string, error) { return "", nil })
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.build().Validate(); err == nil {
+				t.Errorf("Validate() expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestFlow_MaxSteps(t *testing.T) {
+	f := New(&reviewState{}, Config{MaxSteps: 5})
+	f.Start("loop", func(ctx context.Context, s *reviewState, input string) (string, error) {
+		return "", nil
+	}).On("loop", "loop")
+
+	_, err := f.Kickoff(context.Background())
+	if !errors.HasCode(err, errors.ErrOutOfRange) {
+		t.Errorf("Kickoff() error = %v, want code %v", err, errors.ErrOutOfRange)
+	}
+}
+
+func TestFlow_VerboseLogsToLogger(t *testing.T) {
+	var buf bytes.Buffer
+	f := New(&reviewState{}, Config{Verbose: true, Logger: log.New(&buf, "", 0)})
+	f.Start("draft", func(ctx context.Context, s *reviewState, input string) (string, error) {
+		return "draft", nil
+	})
+
+	if _, err := f.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if got := buf.String(); got != "[Flow] Running step 'draft'\n" {
+		t.Errorf("logged %q, want the step's progress", got)
+	}
+}
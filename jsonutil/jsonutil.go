@@ -0,0 +1,252 @@
+// Package jsonutil extracts and parses JSON from LLM responses, which often
+// wrap it in prose or markdown fences, leave trailing commas or stop before
+// the closing brackets.
+package jsonutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// fence matches a markdown code fence, with an optional language tag
+var fence = regexp.MustCompile("(?s)```[\\w+-]*[ \\t]*\\n(.*?)```")
+
+// StripFences returns the content of the first fenced code block of s, or s
+// when it has none
+func StripFences(s string) string {
+	if m := fence.FindStringSubmatch(s); m != nil {
+		return m[1]
+	}
+	// An unterminated fence, typically from a truncated response
+	if start := strings.Index(s, "```"); start >= 0 {
+		rest := s[start+3:]
+		if nl := strings.IndexByte(rest, '\n'); nl >= 0 {
+			return rest[nl+1:]
+		}
+	}
+	return s
+}
+
+// Extract returns the first JSON object or array in s, repaired if needed.
+// An object is preferred over an array appearing before it, unless the
+// array contains it, so bracketed prose like "[1]" is not mistaken for the
+// answer.
+func Extract(s string) (string, error) {
+	return extract(s, "{[", true)
+}
+
+// ExtractObject returns the first JSON object in s, repaired if needed
+func ExtractObject(s string) (string, error) {
+	return extract(s, "{", true)
+}
+
+// ExtractArray returns the first JSON array in s, repaired if needed
+func ExtractArray(s string) (string, error) {
+	return extract(s, "[", true)
+}
+
+// ExtractStrict returns the first complete, valid JSON object or array in
+// s, like Extract but without repair: truncated or malformed JSON is an
+// error rather than a guess. Use it where a repaired value could do harm,
+// such as tool calls and output contracts.
+func ExtractStrict(s string) (string, error) {
+	return extract(s, "{[", false)
+}
+
+// Unmarshal extracts JSON from s and decodes it into v. Slices and arrays
+// are decoded from the first JSON array, other types from the first object
+// or array.
+func Unmarshal(s string, v interface{}) error {
+	return unmarshal(s, v, true)
+}
+
+// UnmarshalStrict is Unmarshal without repair: it decodes the first
+// complete, valid JSON value of s
+func UnmarshalStrict(s string, v interface{}) error {
+	return unmarshal(s, v, false)
+}
+
+// unmarshal extracts JSON from s, repaired if allowed, and decodes it into v
+func unmarshal(s string, v interface{}, repair bool) error {
+	openers := "{["
+	if t := reflect.TypeOf(v); t != nil && t.Kind() == reflect.Ptr {
+		switch t.Elem().Kind() {
+		case reflect.Slice, reflect.Array:
+			openers = "["
+		case reflect.Struct, reflect.Map:
+			openers = "{"
+		}
+	}
+
+	raw, err := extract(s, openers, repair)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal([]byte(raw), v); err != nil {
+		return errors.Wrap(errors.ErrInvalidFormat, "failed to decode JSON", err).WithContext("json_length", len(raw))
+	}
+	return nil
+}
+
+// extract returns the first valid, or with repair repairable, JSON value of
+// s starting with one of openers. Objects are preferred over arrays that
+// don't contain them.
+func extract(s, openers string, repair bool) (string, error) {
+	s = StripFences(s)
+	var found []match
+	for _, opener := range openers {
+		if m, ok := find(s, byte(opener), repair); ok {
+			found = append(found, m)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", errors.New(errors.ErrInvalidFormat, "no valid JSON found").WithContext("length", len(s))
+	case 2:
+		object, array := found[0], found[1]
+		if array.start < object.start && array.end > object.start {
+			return array.raw, nil
+		}
+		return object.raw, nil
+	default:
+		return found[0].raw, nil
+	}
+}
+
+// match is a JSON value found in a string, spanning s[start:end]
+type match struct {
+	raw        string
+	start, end int
+}
+
+// find returns the first valid, or with repair repairable, JSON value of s
+// starting with opener
+func find(s string, opener byte, repair bool) (match, bool) {
+	for i := 0; i < len(s); i++ {
+		if s[i] != opener {
+			continue
+		}
+		raw, complete := span(s, i)
+		if complete && json.Valid([]byte(raw)) {
+			return match{raw: raw, start: i, end: i + len(raw)}, true
+		}
+		if !repair {
+			continue
+		}
+		if repaired := Repair(raw); json.Valid([]byte(repaired)) {
+			return match{raw: repaired, start: i, end: i + len(raw)}, true
+		}
+	}
+	return match{}, false
+}
+
+// span returns the balanced value starting at s[start], skipping brackets
+// inside strings. complete is false when s ends before the value does.
+func span(s string, start int) (value string, complete bool) {
+	depth := 0
+	inString, escaped := false, false
+	for i := start; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			escaped = false
+		case inString:
+			if c == '\\' {
+				escaped = true
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+		case c == '{' || c == '[':
+			depth++
+		case c == '}' || c == ']':
+			depth--
+			if depth == 0 {
+				return s[start : i+1], true
+			}
+		}
+	}
+	return s[start:], false
+}
+
+// Repair makes a best effort to turn almost-JSON into JSON: it replaces
+// typographic quotes used as delimiters, removes trailing commas and closes
+// strings, objects and arrays left open by a truncated response. Valid JSON
+// is returned unchanged.
+func Repair(s string) string {
+	if json.Valid([]byte(s)) {
+		return s
+	}
+
+	var b strings.Builder
+	var closers []byte
+	inString, escaped, smart := false, false, false
+	for _, r := range s {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case r == '\\':
+				escaped = true
+			case r == '"' || (smart && r == '”'):
+				inString, smart = false, false
+				b.WriteByte('"')
+				continue
+			}
+			b.WriteRune(r)
+			continue
+		}
+
+		switch r {
+		case '"', '“', '”':
+			inString, smart = true, r != '"'
+			b.WriteByte('"')
+		case '{':
+			closers = append(closers, '}')
+			b.WriteRune(r)
+		case '[':
+			closers = append(closers, ']')
+			b.WriteRune(r)
+		case '}', ']':
+			trimTrailingComma(&b)
+			if len(closers) > 0 {
+				closers = closers[:len(closers)-1]
+			}
+			b.WriteRune(r)
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	// Close whatever a truncated response left open
+	if inString {
+		if escaped {
+			b.WriteByte('\\')
+		}
+		b.WriteByte('"')
+	}
+	out := strings.TrimRight(b.String(), " \t\r\n")
+	out = strings.TrimSuffix(strings.TrimSuffix(out, ","), ":")
+	for i := len(closers) - 1; i >= 0; i-- {
+		out = strings.TrimRight(out, " \t\r\n")
+		out = strings.TrimSuffix(out, ",")
+		out += string(closers[i])
+	}
+	return out
+}
+
+// trimTrailingComma removes a comma, and the whitespace after it, at the end
+// of b
+func trimTrailingComma(b *strings.Builder) {
+	s := b.String()
+	trimmed := strings.TrimRight(s, " \t\r\n")
+	if strings.HasSuffix(trimmed, ",") {
+		b.Reset()
+		b.WriteString(trimmed[:len(trimmed)-1])
+	}
+}
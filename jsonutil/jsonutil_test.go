@@ -0,0 +1,79 @@
+package jsonutil
+
+import (
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{"bare object", `{"a":1}`, `{"a":1}`},
+		{"prose around", `Sure! Here it is: [1, 2] Hope that helps.`, `[1, 2]`},
+		{"brackets in strings", `{"note": "use [x] and {y}", "n": 1} trailing }`, `{"note": "use [x] and {y}", "n": 1}`},
+		{"escaped quote", `{"q": "say \"}\""}`, `{"q": "say \"}\""}`},
+		{"fenced", "Result:\n```json\n{\"a\": [1]}\n```\nDone.", `{"a": [1]}`},
+		{"skips non-json brackets", `See [note 1]. {"ok": true}`, `{"ok": true}`},
+		{"prefers objects to bracketed prose", `See [1] for details: {"ok": true}`, `{"ok": true}`},
+		{"array of objects", `Items: [{"a": 1}, {"a": 2}]`, `[{"a": 1}, {"a": 2}]`},
+		{"trailing commas", `{"a": [1, 2,], "b": 3,}`, `{"a": [1, 2], "b": 3}`},
+		{"smart quotes", `{“name”: “ada”}`, `{"name": "ada"}`},
+		{"truncated", "```json\n{\"steps\": [{\"a\": \"one\"}, {\"a\": \"tw", `{"steps": [{"a": "one"}, {"a": "tw"}]}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Extract(tt.input)
+			if err != nil {
+				t.Fatalf("Extract() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Extract() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := Extract("no json here"); !errors.HasCode(err, errors.ErrInvalidFormat) {
+		t.Errorf("Extract() error = %v, want invalid format", err)
+	}
+}
+
+func TestStrict_RejectsRepairs(t *testing.T) {
+	for _, input := range []string{
+		`{"name":"delete_file","arguments":{"path":"/data/reports/2024-q1-fin`,
+		`{"a": [1, 2,]}`,
+		"```json\n{\"steps\": [",
+	} {
+		if got, err := ExtractStrict(input); !errors.HasCode(err, errors.ErrInvalidFormat) {
+			t.Errorf("ExtractStrict(%q) = %q, %v, want invalid format", input, got, err)
+		}
+	}
+
+	var v struct {
+		OK bool `json:"ok"`
+	}
+	if err := UnmarshalStrict("Done:\n```json\n{\"ok\": true}\n```", &v); err != nil || !v.OK {
+		t.Errorf("UnmarshalStrict() = %+v, %v", v, err)
+	}
+}
+
+func TestUnmarshal_PicksValueByTarget(t *testing.T) {
+	input := `Plan {"draft": true}: [{"step": "research"}, {"step": "write"},]`
+
+	var steps []struct {
+		Step string `json:"step"`
+	}
+	if err := Unmarshal(input, &steps); err != nil || len(steps) != 2 || steps[1].Step != "write" {
+		t.Errorf("Unmarshal() into slice = %+v, %v", steps, err)
+	}
+
+	var meta struct {
+		Draft bool `json:"draft"`
+	}
+	if err := Unmarshal(input, &meta); err != nil || !meta.Draft {
+		t.Errorf("Unmarshal() into struct = %+v, %v", meta, err)
+	}
+}
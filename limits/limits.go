@@ -0,0 +1,195 @@
+package limits
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+)
+
+// Config holds per-run resource limits. Zero values mean unlimited.
+type Config struct {
+	MaxToolCalls int           // Total tool executions across all agents
+	MaxLLMCalls  int           // Total LLM calls by agents and the manager
+	MaxWallTime  time.Duration // Maximum duration of the run
+}
+
+// Usage reports resources consumed by a run
+type Usage struct {
+	ToolCalls     int
+	LLMCalls      int
+	Continuations int // Follow-up requests for truncated responses
+	// Tokens counts tokens per model, as reported by providers
+	Tokens map[string]llm.TokenUsage
+	// Tools summarizes the executions of each tool by name
+	Tools map[string]ToolUsage
+}
+
+// ToolUsage summarizes the executions of one tool
+type ToolUsage struct {
+	Calls       int
+	Failures    int
+	Duration    time.Duration // Total time spent in the tool
+	MaxDuration time.Duration
+	OutputBytes int
+}
+
+// TotalTokens sums token usage across models
+func (u Usage) TotalTokens() llm.TokenUsage {
+	var total llm.TokenUsage
+	for _, t := range u.Tokens {
+		total = total.Add(t)
+	}
+	return total
+}
+
+// Cost prices token usage with per-model prices; models without a price
+// count as free
+func (u Usage) Cost(prices map[string]llm.Price) float64 {
+	var cost float64
+	for model, t := range u.Tokens {
+		cost += prices[model].Cost(t)
+	}
+	return cost
+}
+
+// Meter counts resource usage for a run and enforces its limits.
+// A nil Meter is valid and enforces nothing.
+type Meter struct {
+	cfg       Config
+	parent    *Meter
+	toolCalls atomic.Int64
+	llmCalls  atomic.Int64
+
+	mu            sync.Mutex
+	continuations int
+	tokens        map[string]llm.TokenUsage
+	tools         map[string]ToolUsage
+}
+
+// NewMeter creates a meter enforcing the given limits
+func NewMeter(cfg Config) *Meter {
+	return &Meter{cfg: cfg}
+}
+
+// Scope returns a meter counting a part of the run, such as one task.
+// Usage recorded on it also counts toward m and m's limits.
+func (m *Meter) Scope() *Meter {
+	return &Meter{parent: m}
+}
+
+// ToolCall records a tool execution, failing once MaxToolCalls is exceeded
+func (m *Meter) ToolCall(name string) error {
+	if m == nil {
+		return nil
+	}
+	if err := m.parent.ToolCall(name); err != nil {
+		return err
+	}
+	n := m.toolCalls.Add(1)
+	if m.cfg.MaxToolCalls > 0 && n > int64(m.cfg.MaxToolCalls) {
+		return errors.QuotaExceeded("tool_calls", m.cfg.MaxToolCalls).WithContext("tool", name)
+	}
+	return nil
+}
+
+// LLMCall records an LLM call, failing once MaxLLMCalls is exceeded
+func (m *Meter) LLMCall() error {
+	if m == nil {
+		return nil
+	}
+	if err := m.parent.LLMCall(); err != nil {
+		return err
+	}
+	n := m.llmCalls.Add(1)
+	if m.cfg.MaxLLMCalls > 0 && n > int64(m.cfg.MaxLLMCalls) {
+		return errors.QuotaExceeded("llm_calls", m.cfg.MaxLLMCalls)
+	}
+	return nil
+}
+
+// RecordResponse records the tokens and continuations of an LLM response
+func (m *Meter) RecordResponse(resp *llm.Response) {
+	if m == nil || resp == nil {
+		return
+	}
+	m.parent.RecordResponse(resp)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.continuations += resp.Continuations
+	if resp.Usage.Total() > 0 {
+		if m.tokens == nil {
+			m.tokens = make(map[string]llm.TokenUsage)
+		}
+		m.tokens[resp.Model] = m.tokens[resp.Model].Add(resp.Usage)
+	}
+}
+
+// RecordTool records the outcome of a tool execution: its duration, the size
+// of its output and whether it failed
+func (m *Meter) RecordTool(name string, d time.Duration, outputBytes int, failed bool) {
+	if m == nil {
+		return
+	}
+	m.parent.RecordTool(name, d, outputBytes, failed)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.tools == nil {
+		m.tools = make(map[string]ToolUsage)
+	}
+	t := m.tools[name]
+	t.Calls++
+	if failed {
+		t.Failures++
+	}
+	t.Duration += d
+	if d > t.MaxDuration {
+		t.MaxDuration = d
+	}
+	t.OutputBytes += outputBytes
+	m.tools[name] = t
+}
+
+// Usage returns the resources consumed so far, including rejected calls
+func (m *Meter) Usage() Usage {
+	if m == nil {
+		return Usage{}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	tokens := make(map[string]llm.TokenUsage, len(m.tokens))
+	for model, t := range m.tokens {
+		tokens[model] = t
+	}
+	tools := make(map[string]ToolUsage, len(m.tools))
+	for name, t := range m.tools {
+		tools[name] = t
+	}
+	return Usage{
+		ToolCalls:     int(m.toolCalls.Load()),
+		LLMCalls:      int(m.llmCalls.Load()),
+		Continuations: m.continuations,
+		Tokens:        tokens,
+		Tools:         tools,
+	}
+}
+
+// contextKey is the context key for the meter
+type contextKey struct{}
+
+// NewContext returns a context carrying the meter
+func NewContext(ctx context.Context, m *Meter) context.Context {
+	return context.WithValue(ctx, contextKey{}, m)
+}
+
+// FromContext returns the meter carried by ctx, or nil if none
+func FromContext(ctx context.Context) *Meter {
+	m, _ := ctx.Value(contextKey{}).(*Meter)
+	return m
+}
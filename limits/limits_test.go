@@ -0,0 +1,30 @@
+package limits
+
+import (
+	"context"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestMeter_ToolCalls(t *testing.T) {
+	m := NewMeter(Config{MaxToolCalls: 2})
+	ctx := NewContext(context.Background(), m)
+
+	for i := 0; i < 2; i++ {
+		if err := FromContext(ctx).ToolCall("search"); err != nil {
+			t.Fatalf("ToolCall() #%d unexpected error: %v", i+1, err)
+		}
+	}
+	if err := FromContext(ctx).ToolCall("search"); !errors.HasCode(err, errors.ErrQuotaExceeded) {
+		t.Errorf("ToolCall() over limit error = %v, want ErrQuotaExceeded", err)
+	}
+	if got := m.Usage(); got.ToolCalls != 3 || got.LLMCalls != 0 {
+		t.Errorf("Usage() = %+v, want 3 tool calls", got)
+	}
+
+	// Without a meter in the context nothing is enforced
+	if err := FromContext(context.Background()).LLMCall(); err != nil {
+		t.Errorf("nil Meter LLMCall() error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"fmt"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// ModelStatus describes the lifecycle state of a model name
+type ModelStatus int
+
+const (
+	// ModelAlias is a valid name that points at another model
+	ModelAlias ModelStatus = iota
+	// ModelDeprecated still works but is scheduled for removal
+	ModelDeprecated
+	// ModelRetired is no longer served by the provider
+	ModelRetired
+)
+
+// String returns the string representation of a model status
+func (s ModelStatus) String() string {
+	switch s {
+	case ModelAlias:
+		return "alias"
+	case ModelDeprecated:
+		return "deprecated"
+	case ModelRetired:
+		return "retired"
+	default:
+		return "unknown"
+	}
+}
+
+// ModelAliasEntry maps a model name to its replacement
+type ModelAliasEntry struct {
+	Replacement string
+	Status      ModelStatus
+}
+
+// AliasPolicy controls how model aliases and deprecations are handled
+type AliasPolicy string
+
+const (
+	// AliasPolicyWarn keeps the configured model and warns about deprecated
+	// or retired names (default)
+	AliasPolicyWarn AliasPolicy = "warn"
+	// AliasPolicyUpgrade replaces aliased, deprecated and retired names
+	// with their replacement
+	AliasPolicyUpgrade AliasPolicy = "upgrade"
+	// AliasPolicyStrict rejects deprecated and retired names
+	AliasPolicyStrict AliasPolicy = "strict"
+	// AliasPolicyIgnore disables alias resolution
+	AliasPolicyIgnore AliasPolicy = "ignore"
+)
+
+// DefaultModelAliases returns the built-in alias table
+func DefaultModelAliases() map[string]ModelAliasEntry {
+	return map[string]ModelAliasEntry{
+		// OpenAI
+		"gpt-4-turbo-preview":  {Replacement: "gpt-4-turbo", Status: ModelAlias},
+		"gpt-4-1106-preview":   {Replacement: "gpt-4-turbo", Status: ModelDeprecated},
+		"gpt-4-0125-preview":   {Replacement: "gpt-4-turbo", Status: ModelDeprecated},
+		"gpt-4-vision-preview": {Replacement: "gpt-4o", Status: ModelRetired},
+		"gpt-4-32k":            {Replacement: "gpt-4o", Status: ModelRetired},
+		"gpt-3.5-turbo-0301":   {Replacement: "gpt-3.5-turbo", Status: ModelRetired},
+		"gpt-3.5-turbo-0613":   {Replacement: "gpt-3.5-turbo", Status: ModelRetired},
+		"text-davinci-003":     {Replacement: "gpt-3.5-turbo-instruct", Status: ModelRetired},
+
+		// Anthropic
+		"claude-instant-1.2":       {Replacement: "claude-3-haiku-20240307", Status: ModelRetired},
+		"claude-2.0":               {Replacement: "claude-3-5-sonnet-latest", Status: ModelRetired},
+		"claude-2.1":               {Replacement: "claude-3-5-sonnet-latest", Status: ModelRetired},
+		"claude-3-sonnet-20240229": {Replacement: "claude-3-5-sonnet-latest", Status: ModelDeprecated},
+	}
+}
+
+// ModelResolution is the outcome of resolving a model name
+type ModelResolution struct {
+	// Model is the name to use
+	Model string
+	// Warning describes a deprecation or upgrade, empty if none
+	Warning string
+}
+
+// ResolveModel consults the alias table for a model name and applies the
+// policy. An empty policy behaves like AliasPolicyWarn.
+func ResolveModel(model string, policy AliasPolicy, aliases map[string]ModelAliasEntry) (ModelResolution, error) {
+	res := ModelResolution{Model: model}
+	if policy == AliasPolicyIgnore || model == "" {
+		return res, nil
+	}
+
+	entry, ok := aliases[model]
+	if !ok {
+		return res, nil
+	}
+
+	switch policy {
+	case "", AliasPolicyWarn:
+		if entry.Status != ModelAlias {
+			res.Warning = fmt.Sprintf("model '%s' is %s, consider '%s'", model, entry.Status, entry.Replacement)
+		}
+	case AliasPolicyUpgrade:
+		res.Model = entry.Replacement
+		res.Warning = fmt.Sprintf("model '%s' (%s) replaced with '%s'", model, entry.Status, entry.Replacement)
+	case AliasPolicyStrict:
+		if entry.Status != ModelAlias {
+			return res, errors.InvalidField("model", fmt.Sprintf("'%s' is %s, use '%s'", model, entry.Status, entry.Replacement)).
+				WithContext("model", model).
+				WithContext("replacement", entry.Replacement)
+		}
+	default:
+		return res, errors.InvalidField("model_policy", fmt.Sprintf("unknown policy '%s'", policy))
+	}
+	return res, nil
+}
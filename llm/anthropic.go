@@ -3,9 +3,9 @@ package llm
 import (
 	"context"
 	"encoding/json"
-	"io"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
 )
@@ -14,9 +14,11 @@ import (
 type AnthropicMessage struct {
 	Model       string    `json:"model"`
 	MaxTokens   int       `json:"max_tokens"`
-	Temperature float32   `json:"temperature,omitempty"`
+	Temperature *float32  `json:"temperature,omitempty"`
+	TopP        float32   `json:"top_p,omitempty"`
 	Messages    []Message `json:"messages"`
 	System      string    `json:"system,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
 }
 
 // Message represents a single message in the conversation
@@ -25,6 +27,49 @@ type Message struct {
 	Content string `json:"content"`
 }
 
+// anthropicBlock is a text content block, optionally marked as a prompt
+// cache breakpoint
+type anthropicBlock struct {
+	Type         string                 `json:"type"`
+	Text         string                 `json:"text"`
+	CacheControl *anthropicCacheControl `json:"cache_control,omitempty"`
+}
+
+// anthropicCacheControl marks the end of a cacheable prompt prefix
+type anthropicCacheControl struct {
+	Type string `json:"type"`
+}
+
+// anthropicBlockMessage is a message whose content is a list of blocks
+type anthropicBlockMessage struct {
+	Role    string           `json:"role"`
+	Content []anthropicBlock `json:"content"`
+}
+
+// anthropicCachedRequest is a request whose first message caches a prefix;
+// its Messages replace those of the embedded request
+type anthropicCachedRequest struct {
+	AnthropicMessage
+	Messages []anthropicBlockMessage `json:"messages"`
+}
+
+// cacheRequest splits the first message at prefix and marks the prefix as
+// cacheable
+func cacheRequest(message AnthropicMessage, prefix string) anthropicCachedRequest {
+	req := anthropicCachedRequest{AnthropicMessage: message}
+	for i, m := range message.Messages {
+		blocks := []anthropicBlock{{Type: "text", Text: m.Content}}
+		if i == 0 {
+			blocks = []anthropicBlock{{Type: "text", Text: prefix, CacheControl: &anthropicCacheControl{Type: "ephemeral"}}}
+			if rest := m.Content[len(prefix):]; rest != "" {
+				blocks = append(blocks, anthropicBlock{Type: "text", Text: rest})
+			}
+		}
+		req.Messages = append(req.Messages, anthropicBlockMessage{Role: m.Role, Content: blocks})
+	}
+	return req
+}
+
 // AnthropicResponse defines the response from Anthropic API
 type AnthropicResponse struct {
 	ID         string    `json:"id"`
@@ -46,6 +91,19 @@ type Content struct {
 type Usage struct {
 	InputTokens  int `json:"input_tokens"`
 	OutputTokens int `json:"output_tokens"`
+	// Prompt caching; these tokens are not included in InputTokens
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// tokenUsage converts the usage to TokenUsage, counting cached prompt
+// tokens as prompt tokens
+func (u Usage) tokenUsage() TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens,
+		CompletionTokens: u.OutputTokens,
+		CachedTokens:     u.CacheReadInputTokens,
+	}
 }
 
 // Anthropic implements the LLM interface for Anthropic Claude
@@ -70,65 +128,185 @@ func NewAnthropic(cfg Config) (*Anthropic, error) {
 
 // Generate sends a prompt to Anthropic and returns the response
 func (a *Anthropic) Generate(ctx context.Context, prompt string) (string, error) {
-	model := a.config.Model
-	if model == "" {
-		model = "claude-3-sonnet-20240229"
+	resp, err := a.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
 	}
+	return resp.Content, nil
+}
 
-	maxTokens := a.config.MaxTokens
-	if maxTokens == 0 {
-		maxTokens = 1024
-	}
+// GenerateResponse sends a prompt to Anthropic and returns the response with
+// its normalized finish reason
+func (a *Anthropic) GenerateResponse(ctx context.Context, prompt string) (*Response, error) {
+	return generateWithContinuation(ctx, prompt, a.config.MaxContinuations, a.logged(a.complete))
+}
 
-	message := AnthropicMessage{
-		Model:       model,
-		MaxTokens:   maxTokens,
-		Temperature: a.config.Temperature,
-		Messages: []Message{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
+// GenerateStream sends a prompt to Anthropic and calls fn with each chunk
+// of the response as it arrives
+func (a *Anthropic) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*Response, error) {
+	complete := a.logged(func(ctx context.Context, history []Message) (*Response, error) {
+		return a.completeStream(ctx, history, fn)
+	})
+	return generateWithContinuation(ctx, prompt, a.config.MaxContinuations, complete)
+}
 
-	jsonData, err := json.Marshal(message)
-	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", model)
-	}
+// Chat sends a conversation to Anthropic and returns the assistant's reply
+func (a *Anthropic) Chat(ctx context.Context, messages []Message) (Message, error) {
+	return chatReply(a.ChatResponse(ctx, messages))
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", strings.NewReader(string(jsonData)))
+// ChatResponse sends a conversation to Anthropic and returns the reply with
+// its metadata
+func (a *Anthropic) ChatResponse(ctx context.Context, messages []Message) (*Response, error) {
+	return chatWithContinuation(ctx, messages, a.config.MaxContinuations, a.logged(a.complete))
+}
+
+// logged records the requests of complete with the configured logger
+func (a *Anthropic) logged(complete completeFunc) completeFunc {
+	return withLogger(a.config.Logger, logParams{
+		provider:    "anthropic",
+		model:       a.config.Model,
+		temperature: a.config.Temperature,
+		maxTokens:   a.config.MaxTokens,
+		topP:        a.config.TopP,
+	}, complete)
+}
+
+// complete sends a conversation to Anthropic
+func (a *Anthropic) complete(ctx context.Context, history []Message) (*Response, error) {
+	ctx, cancel := withCallTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	req, model, err := a.newRequest(ctx, history, false)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to create request", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("x-api-key", a.apiKey)
-	req.Header.Set("anthropic-version", "2023-06-01")
-
+	start := time.Now()
 	resp, err := a.client.Do(req)
 	if err != nil {
-		return "", errors.APICallError("call Anthropic API", err)
+		return nil, callError(ctx, "call Anthropic API", start, err).WithContext("model", model)
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, a.config.MaxResponseBytes)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+		return nil, readError(ctx, "call Anthropic API", start, err).WithContext("model", model)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.APIf("Anthropic API error (status %d): %s", resp.StatusCode, string(body))
+		return nil, errors.APIf("Anthropic API error (status %d): %s", resp.StatusCode, string(body))
 	}
 
 	var anthropicResp AnthropicResponse
 	if err := json.Unmarshal(body, &anthropicResp); err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
+		return nil, errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
 	}
 
 	if len(anthropicResp.Content) == 0 {
-		return "", errors.API("no content in response")
+		return nil, errors.API("no content in response")
+	}
+
+	return &Response{
+		Content:      anthropicResp.Content[0].Text,
+		FinishReason: normalizeFinishReason(anthropicResp.StopReason),
+		Model:        anthropicResp.Model,
+		Usage:        anthropicResp.Usage.tokenUsage(),
+	}, nil
+}
+
+// completeStream sends a conversation to Anthropic and streams the response
+func (a *Anthropic) completeStream(ctx context.Context, history []Message, fn func(chunk string) error) (*Response, error) {
+	ctx, timeout, cancel := withStreamTimeout(ctx, a.config.Timeout)
+	defer cancel()
+
+	req, model, err := a.newRequest(ctx, history, true)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, callError(ctx, "call Anthropic API", start, err).WithContext("model", model)
+	}
+	resp.Body = timeout.watch(resp.Body)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := readBody(resp, a.config.MaxResponseBytes)
+		if err != nil {
+			return nil, readError(ctx, "call Anthropic API", start, err).WithContext("model", model)
+		}
+		return nil, errors.APIf("Anthropic API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	out, err := readStream(ctx, "call Anthropic API", start, resp, a.config.MaxResponseBytes, parseAnthropicStream, fn)
+	if err != nil {
+		return nil, err
+	}
+	if out.Model == "" {
+		out.Model = model
+	}
+	return out, nil
+}
+
+// newRequest builds the messages request for a conversation and returns it
+// with the model it asks for
+func (a *Anthropic) newRequest(ctx context.Context, history []Message, stream bool) (*http.Request, string, error) {
+	model := a.config.Model
+	if model == "" {
+		model = "claude-3-sonnet-20240229"
+	}
+
+	maxTokens := a.config.MaxTokens
+	if maxTokens == 0 {
+		maxTokens = 1024
+	}
+	if err := checkPromptLength(model, history, maxTokens, a.config.MaxPromptTokens); err != nil {
+		return nil, model, err
+	}
+
+	// Anthropic takes system instructions in a separate field
+	var system []string
+	turns := make([]Message, 0, len(history))
+	for _, m := range history {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		turns = append(turns, m)
+	}
+
+	message := AnthropicMessage{
+		Model:       model,
+		MaxTokens:   maxTokens,
+		Temperature: requestTemperature(a.config.Temperature, a.config.TemperatureSet),
+		TopP:        a.config.TopP,
+		Messages:    turns,
+		System:      strings.Join(system, "\n\n"),
+		Stream:      stream,
 	}
 
-	return anthropicResp.Content[0].Text, nil
+	var payload interface{} = message
+	if len(turns) > 0 {
+		if prefix := cachePrefix(ctx, turns[0].Content); prefix != "" {
+			payload = cacheRequest(message, prefix)
+		}
+	}
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return nil, model, errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", model)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.anthropic.com/v1/messages", strings.NewReader(string(jsonData)))
+	if err != nil {
+		return nil, model, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	setCommonHeaders(req, a.config.Client)
+	req.Header.Set("x-api-key", a.apiKey)
+	req.Header.Set("anthropic-version", "2023-06-01")
+	return req, model, nil
 }
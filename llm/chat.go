@@ -0,0 +1,87 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Message roles
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// ChatLLM extends LLM with multi-turn conversations, sent as system, user
+// and assistant messages instead of one flattened prompt
+type ChatLLM interface {
+	LLM
+	// Chat sends a conversation and returns the assistant's reply
+	Chat(ctx context.Context, messages []Message) (Message, error)
+	// ChatResponse sends a conversation and returns the reply with its
+	// finish reason, token usage and model
+	ChatResponse(ctx context.Context, messages []Message) (*Response, error)
+}
+
+// ChatResponse sends a conversation when the provider supports it, falling
+// back to GenerateResponse with the conversation flattened into one prompt
+func ChatResponse(ctx context.Context, l LLM, messages []Message) (*Response, error) {
+	if err := checkMessages(messages); err != nil {
+		return nil, err
+	}
+	if cl, ok := l.(ChatLLM); ok {
+		return cl.ChatResponse(ctx, messages)
+	}
+	return GenerateResponse(ctx, l, FlattenMessages(messages))
+}
+
+// FlattenMessages joins a conversation into a single prompt for providers
+// without chat support: system messages first, then the turns labeled with
+// their roles
+func FlattenMessages(messages []Message) string {
+	var system, turns []string
+	var last Message
+	for _, m := range messages {
+		switch m.Role {
+		case RoleSystem:
+			system = append(system, m.Content)
+			continue
+		case RoleAssistant:
+			turns = append(turns, "Assistant: "+m.Content)
+		default:
+			turns = append(turns, "User: "+m.Content)
+		}
+		last = m
+	}
+	// A single request needs no labels
+	if len(turns) == 1 && last.Role == RoleUser {
+		turns[0] = last.Content
+	}
+	return strings.Join(append(system, turns...), "\n\n")
+}
+
+// checkMessages validates a conversation: it must not be empty and every
+// message must have a known role
+func checkMessages(messages []Message) error {
+	if len(messages) == 0 {
+		return errors.RequiredField("messages")
+	}
+	for i, m := range messages {
+		switch m.Role {
+		case RoleSystem, RoleUser, RoleAssistant:
+		default:
+			return errors.InvalidField("messages", "unknown role "+m.Role).WithContext("index", i)
+		}
+	}
+	return nil
+}
+
+// chatReply converts a response into the assistant message returned by Chat
+func chatReply(resp *Response, err error) (Message, error) {
+	if err != nil {
+		return Message{}, err
+	}
+	return Message{Role: RoleAssistant, Content: resp.Content}, nil
+}
@@ -0,0 +1,118 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// charsPerToken is the rough number of characters per token for English text
+const charsPerToken = 4
+
+// elisionMarker replaces text dropped by heuristic compression
+const elisionMarker = "\n[...]\n"
+
+// Compressor shrinks text to fit a token budget
+type Compressor interface {
+	Compress(ctx context.Context, text string, targetTokens int) (string, error)
+}
+
+// EstimateTokens approximates the token count of text without a tokenizer
+func EstimateTokens(text string) int {
+	return (len([]rune(text)) + charsPerToken - 1) / charsPerToken
+}
+
+// Compress shrinks text to roughly targetTokens without calling a model.
+// Whitespace runs are collapsed first; if that is not enough, the middle of
+// the text is dropped, keeping the beginning and the end where task context
+// and conclusions usually are.
+func Compress(ctx context.Context, text string, targetTokens int) (string, error) {
+	if targetTokens <= 0 {
+		return "", errors.InvalidField("targetTokens", "must be positive")
+	}
+	if EstimateTokens(text) <= targetTokens {
+		return text, nil
+	}
+
+	text = collapseWhitespace(text)
+	if EstimateTokens(text) <= targetTokens {
+		return text, nil
+	}
+
+	runes := []rune(text)
+	budget := targetTokens*charsPerToken - len([]rune(elisionMarker))
+	if budget <= 0 {
+		return string(runes[:targetTokens*charsPerToken]), nil
+	}
+	head := budget * 2 / 3
+	tail := budget - head
+	return string(runes[:head]) + elisionMarker + string(runes[len(runes)-tail:]), nil
+}
+
+// collapseWhitespace replaces runs of spaces and blank lines with single ones
+func collapseWhitespace(text string) string {
+	lines := strings.Split(text, "\n")
+	out := make([]string, 0, len(lines))
+	blank := false
+	for _, line := range lines {
+		line = strings.Join(strings.Fields(line), " ")
+		if line == "" {
+			if blank {
+				continue
+			}
+			blank = true
+		} else {
+			blank = false
+		}
+		out = append(out, line)
+	}
+	return strings.TrimSpace(strings.Join(out, "\n"))
+}
+
+// SummaryCompressor compresses text by asking an LLM to summarize it,
+// falling back to heuristic compression if the summary is still too long
+type SummaryCompressor struct {
+	llm LLM
+}
+
+// NewSummaryCompressor creates a compressor backed by an LLM
+func NewSummaryCompressor(l LLM) (*SummaryCompressor, error) {
+	if l == nil {
+		return nil, errors.MissingConfig("LLM provider").WithContext("component", "compressor")
+	}
+	return &SummaryCompressor{llm: l}, nil
+}
+
+// Compress summarizes text to roughly targetTokens
+func (c *SummaryCompressor) Compress(ctx context.Context, text string, targetTokens int) (string, error) {
+	if targetTokens <= 0 {
+		return "", errors.InvalidField("targetTokens", "must be positive")
+	}
+	if EstimateTokens(text) <= targetTokens {
+		return text, nil
+	}
+
+	// Roughly three words per four tokens
+	words := targetTokens * 3 / 4
+	prompt := fmt.Sprintf(`Summarize the following text in at most %d words.
+Keep facts, names, numbers and decisions; drop repetition and filler.
+Respond with the summary only.
+
+%s`, words, text)
+
+	summary, err := c.llm.Generate(ctx, prompt)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrAPICall, "failed to summarize text", err).WithContext("target_tokens", targetTokens)
+	}
+	return Compress(ctx, strings.TrimSpace(summary), targetTokens)
+}
+
+// HeuristicCompressor adapts Compress to the Compressor interface
+type HeuristicCompressor struct{}
+
+// Compress shrinks text to roughly targetTokens without calling a model
+func (HeuristicCompressor) Compress(ctx context.Context, text string, targetTokens int) (string, error) {
+	return Compress(ctx, text, targetTokens)
+}
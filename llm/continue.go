@@ -0,0 +1,67 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// continuePrompt asks the model to resume a truncated response
+const continuePrompt = "Continue exactly where you left off. Do not repeat anything you already wrote."
+
+// completeFunc sends a conversation and returns the next assistant turn
+type completeFunc func(ctx context.Context, history []Message) (*Response, error)
+
+// generateWithContinuation sends a prompt and, while the response is cut off
+// at the token limit, re-prompts with the conversation so far and stitches
+// the chunks together, up to maxContinuations follow-up requests
+func generateWithContinuation(ctx context.Context, prompt string, maxContinuations int, complete completeFunc) (*Response, error) {
+	return chatWithContinuation(ctx, []Message{{Role: RoleUser, Content: prompt}}, maxContinuations, complete)
+}
+
+// chatWithContinuation sends a conversation and continues a truncated reply
+// as generateWithContinuation does
+func chatWithContinuation(ctx context.Context, messages []Message, maxContinuations int, complete completeFunc) (*Response, error) {
+	if err := checkMessages(messages); err != nil {
+		return nil, err
+	}
+	history := append([]Message(nil), messages...)
+
+	resp, err := complete(ctx, history)
+	if err != nil {
+		return nil, err
+	}
+
+	var content strings.Builder
+	content.WriteString(resp.Content)
+	logprobs := resp.Logprobs
+	usage := resp.Usage
+	continuations := 0
+
+	for i := 0; i < maxContinuations && resp.Truncated(); i++ {
+		history = append(history,
+			Message{Role: RoleAssistant, Content: resp.Content},
+			Message{Role: RoleUser, Content: continuePrompt},
+		)
+
+		resp, err = complete(ctx, history)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrAPICall, "failed to continue truncated response", err).
+				WithContext("continuation", i+1)
+		}
+		content.WriteString(resp.Content)
+		logprobs = append(logprobs, resp.Logprobs...)
+		usage = usage.Add(resp.Usage)
+		continuations++
+	}
+
+	return &Response{
+		Content:       content.String(),
+		FinishReason:  resp.FinishReason,
+		Logprobs:      logprobs,
+		Model:         resp.Model,
+		Usage:         usage,
+		Continuations: continuations,
+	}, nil
+}
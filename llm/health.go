@@ -0,0 +1,52 @@
+package llm
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// HealthChecker is implemented by providers that can verify their endpoint
+// is reachable and their configured model is available
+type HealthChecker interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks that a provider is ready to serve requests.
+// Providers that do not implement HealthChecker are assumed healthy.
+func Ping(ctx context.Context, l LLM) error {
+	if hc, ok := l.(HealthChecker); ok {
+		return hc.Ping(ctx)
+	}
+	return nil
+}
+
+// Ping checks that the endpoint is reachable and serves the configured model.
+// It queries the OpenAI-compatible models endpoint and falls back to
+// Ollama's native /api/tags for servers that do not expose it.
+func (o *OpenAILike) Ping(ctx context.Context) error {
+	models, err := o.ListModels(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, name := range models {
+		if modelMatches(name, o.config.Model) {
+			return nil
+		}
+	}
+
+	return errors.NotFound("model", o.config.Model).
+		WithContext("base_url", o.config.BaseURL).
+		WithContext("available", models)
+}
+
+// modelMatches reports whether a served model name satisfies the configured
+// one. Ollama reports untagged models with an implicit ":latest" tag.
+func modelMatches(served, configured string) bool {
+	if served == configured {
+		return true
+	}
+	return !strings.Contains(configured, ":") && served == configured+":latest"
+}
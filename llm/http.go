@@ -0,0 +1,161 @@
+package llm
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// DefaultMaxResponseBytes caps the decoded size of a provider response body
+// when the config does not set a limit
+const DefaultMaxResponseBytes = 32 << 20
+
+// setCommonHeaders applies headers shared by every provider request: the
+// client identity and the run ID
+func setCommonHeaders(req *http.Request, id *ClientIdentity) {
+	setIdentityHeaders(req, id)
+
+	// Propagate the run ID so provider-side logs can be correlated with the run
+	if id := runid.FromContext(req.Context()); id != "" {
+		req.Header.Set(runid.HeaderName, id)
+	}
+}
+
+// marshalWithExtra encodes a request body and merges extra fields into the
+// top-level JSON object, with extra fields taking precedence
+func marshalWithExtra(body interface{}, extra map[string]interface{}) ([]byte, error) {
+	data, err := json.Marshal(body)
+	if err != nil || len(extra) == 0 {
+		return data, err
+	}
+
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	for key, value := range extra {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidField, "failed to encode extra body field", err).WithContext("field", key)
+		}
+		fields[key] = raw
+	}
+	return json.Marshal(fields)
+}
+
+// doGet performs a GET request and returns the status code and body.
+// setHeaders applies provider-specific authentication headers.
+func doGet(ctx context.Context, client *http.Client, id *ClientIdentity, url string, setHeaders func(req *http.Request)) (int, []byte, error) {
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", url)
+	}
+	setCommonHeaders(req, id)
+	if setHeaders != nil {
+		setHeaders(req)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, errors.Wrap(errors.ErrNetworkUnavail, "endpoint is unreachable", err).
+			WithContext("url", url).
+			WithRetryable(true).
+			WithTemporary(true)
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp, 0)
+	if err != nil {
+		if e, ok := err.(*errors.Error); ok {
+			return 0, nil, e
+		}
+		return 0, nil, errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+	}
+	return resp.StatusCode, body, nil
+}
+
+// readBody reads a response body, decoding gzip content the transport left
+// compressed (e.g. when a custom header requested it), and fails once the
+// decoded body exceeds limit bytes (0 = DefaultMaxResponseBytes, negative =
+// no cap). Malformed and oversized bodies are *errors.Error values; other
+// errors come from reading the connection.
+func readBody(resp *http.Response, limit int64) ([]byte, error) {
+	r, err := responseReader(resp, limit)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, gzipError(err) // Plain bodies never fail with gzip errors
+	}
+	return body, nil
+}
+
+// responseReader returns the decoded body of a response, failing reads past
+// limit bytes as readBody does
+func responseReader(resp *http.Response, limit int64) (io.Reader, error) {
+	var r io.Reader = resp.Body
+	if encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding"))); encoding == "gzip" || encoding == "x-gzip" {
+		gz, err := gzip.NewReader(resp.Body)
+		if err == io.EOF {
+			return http.NoBody, nil
+		}
+		if err != nil {
+			return nil, gzipError(err)
+		}
+		r = gz
+	}
+
+	if limit == 0 {
+		limit = DefaultMaxResponseBytes
+	}
+	if limit < 0 {
+		return r, nil
+	}
+	return &cappedReader{r: r, remaining: limit, limit: limit, status: resp.StatusCode}, nil
+}
+
+// cappedReader fails with an ErrAPIResponse error once more than limit
+// bytes are read
+type cappedReader struct {
+	r         io.Reader
+	remaining int64
+	limit     int64
+	status    int
+}
+
+// Read reads up to the remaining bytes, then probes for one more byte to
+// tell a body of exactly limit bytes from a larger one
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.remaining <= 0 {
+		var probe [1]byte
+		n, err := c.r.Read(probe[:])
+		if n > 0 {
+			return 0, errors.Newf(errors.ErrAPIResponse, "response body exceeds %d bytes", c.limit).
+				WithContext("limit", c.limit).
+				WithContext("status", c.status)
+		}
+		return 0, err
+	}
+	if int64(len(p)) > c.remaining {
+		p = p[:c.remaining]
+	}
+	n, err := c.r.Read(p)
+	c.remaining -= int64(n)
+	return n, err
+}
+
+// gzipError classifies a corrupt gzip stream as an invalid response,
+// leaving connection errors unchanged
+func gzipError(err error) error {
+	if err == gzip.ErrHeader || err == gzip.ErrChecksum {
+		return errors.Wrap(errors.ErrAPIResponse, "invalid gzip response", err)
+	}
+	return err
+}
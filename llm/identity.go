@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"net/http"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// modulePath is the module whose version identifies the library
+const modulePath = "github.com/counhopig/gittyai"
+
+// ClientIdentity identifies the application making provider requests.
+// Requests carry a User-Agent naming the application and the library, and
+// X-Client-Name and X-Client-Version headers naming the application, which
+// some gateways require for attribution.
+type ClientIdentity struct {
+	AppName    string // e.g. "research-bot"
+	AppVersion string // e.g. "1.4.0"
+}
+
+var (
+	identityMu      sync.RWMutex
+	defaultIdentity *ClientIdentity
+
+	libraryOnce    sync.Once
+	libraryVersion string
+)
+
+// SetClientIdentity sets the identity of providers whose config sets none;
+// nil identifies the library only
+func SetClientIdentity(id *ClientIdentity) {
+	identityMu.Lock()
+	defer identityMu.Unlock()
+	defaultIdentity = id
+}
+
+// LibraryVersion returns the version of this module in the running binary,
+// or "dev" when it is not known, e.g. in a local checkout
+func LibraryVersion() string {
+	libraryOnce.Do(func() {
+		libraryVersion = "dev"
+		info, ok := debug.ReadBuildInfo()
+		if !ok {
+			return
+		}
+		version := ""
+		if info.Main.Path == modulePath {
+			version = info.Main.Version
+		}
+		for _, dep := range info.Deps {
+			if dep.Path == modulePath {
+				version = dep.Version
+			}
+		}
+		if version != "" && version != "(devel)" {
+			libraryVersion = strings.TrimPrefix(version, "v")
+		}
+	})
+	return libraryVersion
+}
+
+// UserAgent returns the User-Agent of requests made with the identity,
+// e.g. "research-bot/1.4.0 gittyai/0.9.0 (go1.22.1)"
+func (id *ClientIdentity) UserAgent() string {
+	agent := "gittyai/" + LibraryVersion() + " (" + runtime.Version() + ")"
+	if id == nil || id.AppName == "" {
+		return agent
+	}
+	app := id.AppName
+	if id.AppVersion != "" {
+		app += "/" + id.AppVersion
+	}
+	return app + " " + agent
+}
+
+// setIdentityHeaders identifies the client of a request: id, or the
+// identity set with SetClientIdentity when id is nil
+func setIdentityHeaders(req *http.Request, id *ClientIdentity) {
+	if id == nil {
+		identityMu.RLock()
+		id = defaultIdentity
+		identityMu.RUnlock()
+	}
+	req.Header.Set("User-Agent", id.UserAgent())
+	if id != nil && id.AppName != "" {
+		req.Header.Set("X-Client-Name", id.AppName)
+		if id.AppVersion != "" {
+			req.Header.Set("X-Client-Version", id.AppVersion)
+		}
+	}
+}
@@ -1,6 +1,9 @@
 package llm
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 // LLM is the interface for Language Model providers
 type LLM interface {
@@ -41,8 +44,47 @@ type Config struct {
 	APIKey string
 	// Model name (e.g. "gpt-4o", "claude-3-opus-20240229")
 	Model string
-	// Temperature for generation (0.0 to 2.0)
+	// Temperature for generation (0.0 to 2.0, 0 = provider default unless
+	// TemperatureSet)
 	Temperature float32
+	// TemperatureSet sends Temperature even when it is 0
+	TemperatureSet bool
 	// MaxTokens limits the response length
 	MaxTokens int
+	// TopP enables nucleus sampling (0.0 to 1.0, 0 = provider default)
+	TopP float32
+	// Logprobs requests per-token log probabilities (OpenAI only)
+	Logprobs bool
+	// MaxContinuations enables automatic continuation of responses cut off
+	// at MaxTokens, up to this many follow-up requests (0 = disabled)
+	MaxContinuations int
+	// MaxPromptTokens caps the estimated prompt size checked before each
+	// request (0 = model's context window minus MaxTokens, negative = no check)
+	MaxPromptTokens int
+	// Timeout bounds each request, on top of any deadline on the caller's
+	// context; for streamed requests it bounds the wait for the response and
+	// for each chunk instead (0 = DefaultCallTimeout, negative = no per-call
+	// bound)
+	Timeout time.Duration
+	// MaxResponseBytes caps the decoded size of each response body
+	// (0 = DefaultMaxResponseBytes, negative = no cap)
+	MaxResponseBytes int64
+	// ExtraBody holds additional fields merged into the request payload,
+	// for parameters without a first-class field (e.g. response_format,
+	// logprobs, reasoning_effort). Only used by OpenAI-compatible providers.
+	ExtraBody map[string]interface{}
+	// Logger receives a structured record of every request (nil = none)
+	Logger Logger
+	// Client identifies the application in request headers (default: the
+	// identity set with SetClientIdentity)
+	Client *ClientIdentity
+}
+
+// requestTemperature returns the temperature to send, or nil to leave the
+// provider's default: 0 is only sent when set explicitly
+func requestTemperature(temperature float32, set bool) *float32 {
+	if temperature == 0 && !set {
+		return nil
+	}
+	return &temperature
 }
@@ -1,10 +1,25 @@
 package llm
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"encoding/json"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
 )
 
 func TestConfig_Validation(t *testing.T) {
@@ -317,3 +332,1147 @@ func TestDefaultModels(t *testing.T) {
 		})
 	}
 }
+
+func TestOpenAILike_RunIDHeader(t *testing.T) {
+	var gotHeader string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotHeader = r.Header.Get(runid.HeaderName)
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+
+	ctx := runid.NewContext(context.Background(), "run-123")
+	if _, err := provider.Generate(ctx, "hello"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if gotHeader != "run-123" {
+		t.Errorf("%s header = %q, want %q", runid.HeaderName, gotHeader, "run-123")
+	}
+}
+
+func TestOpenAILike_ClientIdentity(t *testing.T) {
+	var got http.Header
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = r.Header.Clone()
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	library := "gittyai/" + LibraryVersion() + " (" + runtime.Version() + ")"
+	SetClientIdentity(&ClientIdentity{AppName: "default-app"})
+	defer SetClientIdentity(nil)
+
+	tests := []struct {
+		name        string
+		client      *ClientIdentity
+		wantAgent   string
+		wantName    string
+		wantVersion string
+	}{
+		{"config identity", &ClientIdentity{AppName: "research-bot", AppVersion: "1.4.0"}, "research-bot/1.4.0 " + library, "research-bot", "1.4.0"},
+		{"default identity", nil, "default-app " + library, "default-app", ""},
+		{"library only", &ClientIdentity{}, library, "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model", Client: tt.client})
+			if err != nil {
+				t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+			}
+			if _, err := provider.Generate(context.Background(), "hello"); err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			if agent := got.Get("User-Agent"); agent != tt.wantAgent {
+				t.Errorf("User-Agent = %q, want %q", agent, tt.wantAgent)
+			}
+			if name := got.Get("X-Client-Name"); name != tt.wantName {
+				t.Errorf("X-Client-Name = %q, want %q", name, tt.wantName)
+			}
+			if version := got.Get("X-Client-Version"); version != tt.wantVersion {
+				t.Errorf("X-Client-Version = %q, want %q", version, tt.wantVersion)
+			}
+		})
+	}
+}
+
+func TestOpenAILike_ResponseBody(t *testing.T) {
+	const payload = `{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if r.Header.Get("Accept-Encoding") == "gzip" {
+			w.Header().Set("Content-Encoding", "gzip")
+			gz := gzip.NewWriter(w)
+			defer gz.Close()
+			_, _ = gz.Write([]byte(payload))
+			return
+		}
+		_, _ = w.Write([]byte(payload))
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		headers  map[string]string
+		limit    int64
+		wantCode *errors.ErrorCode
+	}{
+		{"plain", nil, 0, nil},
+		{"gzip requested by a custom header", map[string]string{"Accept-Encoding": "gzip"}, 0, nil},
+		{"over the cap", nil, 16, &errors.ErrAPIResponse},
+		{"gzip over the cap", map[string]string{"Accept-Encoding": "gzip"}, 16, &errors.ErrAPIResponse},
+		{"no cap", nil, -1, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model", Headers: tt.headers, MaxResponseBytes: tt.limit})
+			if err != nil {
+				t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+			}
+			got, err := provider.Generate(context.Background(), "hello")
+			if tt.wantCode != nil {
+				if !errors.HasCode(err, *tt.wantCode) {
+					t.Errorf("Generate() error = %v, want code %v", err, *tt.wantCode)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			if got != "ok" {
+				t.Errorf("Generate() = %q, want %q", got, "ok")
+			}
+		})
+	}
+}
+
+func TestOpenAILike_Ping(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/models":
+			_, _ = w.Write([]byte(`{"data":[{"id":"local-model"}]}`))
+		case "/ollama/api/tags":
+			_, _ = w.Write([]byte(`{"models":[{"name":"llama3.2:latest"}]}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name     string
+		baseURL  string
+		model    string
+		wantCode *errors.ErrorCode
+	}{
+		{"models endpoint", server.URL + "/v1", "local-model", nil},
+		{"missing model", server.URL + "/v1", "gpt-4o", &errors.ErrNotFound},
+		{"ollama tags fallback", server.URL + "/ollama/v1", "llama3.2", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: tt.baseURL, Model: tt.model})
+			if err != nil {
+				t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+			}
+			err = Ping(context.Background(), provider)
+			if tt.wantCode == nil && err != nil {
+				t.Errorf("Ping() unexpected error: %v", err)
+			}
+			if tt.wantCode != nil && !errors.HasCode(err, *tt.wantCode) {
+				t.Errorf("Ping() error = %v, want code %v", err, *tt.wantCode)
+			}
+		})
+	}
+}
+
+// rewriteTransport redirects requests to a test server
+type rewriteTransport struct {
+	target string
+}
+
+func (rt rewriteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	u, err := url.Parse(rt.target)
+	if err != nil {
+		return nil, err
+	}
+	req.URL.Scheme = u.Scheme
+	req.URL.Host = u.Host
+	return http.DefaultTransport.RoundTrip(req)
+}
+
+func TestAnthropic_ListModels_Pagination(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "test-key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.URL.Query().Get("after_id") == "" {
+			_, _ = w.Write([]byte(`{"data":[{"id":"claude-a"}],"has_more":true,"last_id":"claude-a"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":[{"id":"claude-b"}],"has_more":false}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	provider.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+
+	models, err := provider.ListModels(context.Background())
+	if err != nil {
+		t.Fatalf("ListModels() unexpected error: %v", err)
+	}
+	if strings.Join(models, ",") != "claude-a,claude-b" {
+		t.Errorf("ListModels() = %v, want [claude-a claude-b]", models)
+	}
+}
+
+func TestResolveModel(t *testing.T) {
+	aliases := DefaultModelAliases()
+
+	tests := []struct {
+		name      string
+		model     string
+		policy    AliasPolicy
+		want      string
+		wantWarn  bool
+		wantError bool
+	}{
+		{"unknown model", "gpt-4o", AliasPolicyWarn, "gpt-4o", false, false},
+		{"warn keeps retired", "gpt-4-32k", AliasPolicyWarn, "gpt-4-32k", true, false},
+		{"warn is default", "gpt-4-32k", "", "gpt-4-32k", true, false},
+		{"warn ignores alias", "gpt-4-turbo-preview", AliasPolicyWarn, "gpt-4-turbo-preview", false, false},
+		{"upgrade alias", "gpt-4-turbo-preview", AliasPolicyUpgrade, "gpt-4-turbo", true, false},
+		{"strict rejects retired", "claude-2.1", AliasPolicyStrict, "", false, true},
+		{"strict allows alias", "gpt-4-turbo-preview", AliasPolicyStrict, "gpt-4-turbo-preview", false, false},
+		{"ignore", "gpt-4-32k", AliasPolicyIgnore, "gpt-4-32k", false, false},
+		{"unknown policy", "gpt-4-32k", "sometimes", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ResolveModel(tt.model, tt.policy, aliases)
+			if tt.wantError {
+				if err == nil {
+					t.Errorf("ResolveModel() expected error, got %+v", res)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ResolveModel() unexpected error: %v", err)
+			}
+			if res.Model != tt.want {
+				t.Errorf("ResolveModel().Model = %q, want %q", res.Model, tt.want)
+			}
+			if (res.Warning != "") != tt.wantWarn {
+				t.Errorf("ResolveModel().Warning = %q, want warning: %v", res.Warning, tt.wantWarn)
+			}
+		})
+	}
+}
+
+func TestOpenRouter_RoutingAndHeaders(t *testing.T) {
+	var body map[string]interface{}
+	var referer, title string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		referer = r.Header.Get("HTTP-Referer")
+		title = r.Header.Get("X-Title")
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	allow := false
+	provider, err := NewOpenRouterWithConfig(OpenRouterConfig{
+		APIKey:         "test-key",
+		Model:          "anthropic/claude-3.5-sonnet",
+		FallbackModels: []string{"openai/gpt-4o"},
+		Routing: &OpenRouterRouting{
+			Order:          []string{"Anthropic"},
+			AllowFallbacks: &allow,
+			MaxPrice:       &OpenRouterMaxPrice{Prompt: 3},
+		},
+		Referer: "https://example.com",
+		Title:   "Example",
+	})
+	if err != nil {
+		t.Fatalf("NewOpenRouterWithConfig() unexpected error: %v", err)
+	}
+	provider.config.BaseURL = server.URL
+
+	if _, err := provider.Generate(context.Background(), "hello"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if referer != "https://example.com" || title != "Example" {
+		t.Errorf("headers = (%q, %q), want attribution headers", referer, title)
+	}
+	models, _ := body["models"].([]interface{})
+	if len(models) != 2 || models[0] != "anthropic/claude-3.5-sonnet" {
+		t.Errorf("models = %v, want primary model followed by fallbacks", body["models"])
+	}
+	routing, _ := body["provider"].(map[string]interface{})
+	if routing["allow_fallbacks"] != false {
+		t.Errorf("provider.allow_fallbacks = %v, want false", routing["allow_fallbacks"])
+	}
+	if price, _ := routing["max_price"].(map[string]interface{}); price["prompt"] != 3.0 {
+		t.Errorf("provider.max_price = %v, want prompt cap of 3", routing["max_price"])
+	}
+}
+
+func TestVLLM_ExtraBody(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	provider, err := NewVLLM("mistral-7b", SamplingParams{TopK: 40, BestOf: 3, RepetitionPenalty: 1.1}, server.URL)
+	if err != nil {
+		t.Fatalf("NewVLLM() unexpected error: %v", err)
+	}
+	if _, err := provider.Generate(context.Background(), "hello"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+
+	if body["model"] != "mistral-7b" {
+		t.Errorf("model = %v, want %q", body["model"], "mistral-7b")
+	}
+	if body["top_k"] != 40.0 || body["best_of"] != 3.0 {
+		t.Errorf("top_k, best_of = %v, %v, want 40, 3", body["top_k"], body["best_of"])
+	}
+	if _, ok := body["repetition_penalty"]; !ok {
+		t.Errorf("repetition_penalty missing from request body")
+	}
+}
+
+func TestMarshalWithExtra(t *testing.T) {
+	data, err := marshalWithExtra(openAIRequest{Model: "gpt-4o", Temperature: requestTemperature(0.5, false)}, map[string]interface{}{
+		"response_format": map[string]string{"type": "json_object"},
+		"logprobs":        true,
+		"temperature":     0.2,
+	})
+	if err != nil {
+		t.Fatalf("marshalWithExtra() unexpected error: %v", err)
+	}
+
+	var body map[string]interface{}
+	if err := json.Unmarshal(data, &body); err != nil {
+		t.Fatalf("request body is not valid JSON: %v", err)
+	}
+	if body["model"] != "gpt-4o" || body["logprobs"] != true {
+		t.Errorf("body = %v, want model and logprobs", body)
+	}
+	if format, _ := body["response_format"].(map[string]interface{}); format["type"] != "json_object" {
+		t.Errorf("response_format = %v, want json_object", body["response_format"])
+	}
+	if body["temperature"] != 0.2 {
+		t.Errorf("temperature = %v, want extra body to override 0.5 with 0.2", body["temperature"])
+	}
+}
+
+func TestGenerateResponse_FinishReason(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"partial"}],"stop_reason":"max_tokens"}`))
+			return
+		}
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["logprobs"] != true {
+			t.Errorf("logprobs not requested")
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"done"},"finish_reason":"stop","logprobs":{"content":[{"token":"done","logprob":-0.1}]}}]}`))
+	}))
+	defer server.Close()
+
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+
+	resp, err := GenerateResponse(context.Background(), anthropic, "hello")
+	if err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if resp.FinishReason != FinishLength || !resp.Truncated() {
+		t.Errorf("FinishReason = %q, want %q", resp.FinishReason, FinishLength)
+	}
+
+	like, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model", Logprobs: true})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	resp, err = like.GenerateResponse(context.Background(), "hello")
+	if err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if resp.FinishReason != FinishStop || len(resp.Logprobs) != 1 || resp.Logprobs[0].Token != "done" {
+		t.Errorf("GenerateResponse() = %+v, want stop with one logprob", resp)
+	}
+}
+
+func TestOpenAILike_AutoContinue(t *testing.T) {
+	chunks := []string{"first ", "second ", "third"}
+	var requests int
+	var lastMessages []interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		lastMessages, _ = req["messages"].([]interface{})
+
+		finish := "length"
+		if requests == len(chunks)-1 {
+			finish = "stop"
+		}
+		resp := map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{
+				"message":       map[string]string{"role": "assistant", "content": chunks[requests]},
+				"finish_reason": finish,
+			}},
+		}
+		requests++
+		_ = json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model", MaxContinuations: 5})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+
+	resp, err := provider.GenerateResponse(context.Background(), "write")
+	if err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if resp.Content != "first second third" || resp.FinishReason != FinishStop {
+		t.Errorf("GenerateResponse() = %+v, want stitched content ending in stop", resp)
+	}
+	if requests != 3 {
+		t.Errorf("requests = %d, want 3", requests)
+	}
+	// user, assistant, continue, assistant, continue
+	if len(lastMessages) != 5 {
+		t.Errorf("final request carried %d messages, want 5", len(lastMessages))
+	}
+
+	requests = 0
+	provider.config.MaxContinuations = 1
+	resp, err = provider.GenerateResponse(context.Background(), "write")
+	if err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if resp.Content != "first second " || !resp.Truncated() {
+		t.Errorf("GenerateResponse() = %+v, want truncated after one continuation", resp)
+	}
+}
+
+type summarizeLLM struct {
+	prompt string
+}
+
+func (s *summarizeLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	s.prompt = prompt
+	return "short summary", nil
+}
+
+func TestCompress(t *testing.T) {
+	ctx := context.Background()
+
+	if got, _ := Compress(ctx, "fits", 10); got != "fits" {
+		t.Errorf("Compress() = %q, want text under budget unchanged", got)
+	}
+
+	long := "BEGIN " + strings.Repeat("filler text ", 200) + " END"
+	got, err := Compress(ctx, long, 50)
+	if err != nil {
+		t.Fatalf("Compress() unexpected error: %v", err)
+	}
+	if EstimateTokens(got) > 50 {
+		t.Errorf("EstimateTokens(Compress()) = %d, want <= 50", EstimateTokens(got))
+	}
+	if !strings.HasPrefix(got, "BEGIN") || !strings.HasSuffix(got, "END") || !strings.Contains(got, "[...]") {
+		t.Errorf("Compress() = %q, want head and tail kept around an elision marker", got)
+	}
+
+	if _, err := Compress(ctx, long, 0); err == nil {
+		t.Errorf("Compress() expected error for zero budget")
+	}
+
+	model := &summarizeLLM{}
+	compressor, err := NewSummaryCompressor(model)
+	if err != nil {
+		t.Fatalf("NewSummaryCompressor() unexpected error: %v", err)
+	}
+	got, err = compressor.Compress(ctx, long, 50)
+	if err != nil {
+		t.Fatalf("SummaryCompressor.Compress() unexpected error: %v", err)
+	}
+	if got != "short summary" || !strings.Contains(model.prompt, "at most 37 words") {
+		t.Errorf("SummaryCompressor.Compress() = %q, prompt %q", got, model.prompt)
+	}
+}
+
+func TestOpenAILike_CallTimeout(t *testing.T) {
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer server.Close()
+	defer close(release)
+
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model", Timeout: 50 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+
+	_, err = provider.Generate(context.Background(), "hello")
+	if !errors.HasCode(err, errors.ErrTimeout) {
+		t.Fatalf("Generate() error = %v, want ErrTimeout", err)
+	}
+	if !strings.Contains(err.Error(), "call OpenAI-compatible API") {
+		t.Errorf("Generate() error = %v, want the operation name", err)
+	}
+}
+
+func TestGenerateResponse_CachedTokens(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn",
+"usage":{"input_tokens":100,"output_tokens":10,"cache_read_input_tokens":2000}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}],
+"usage":{"prompt_tokens":2100,"completion_tokens":10,"prompt_tokens_details":{"cached_tokens":2000}}}`))
+	}))
+	defer server.Close()
+
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+	like, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "test-model"})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+
+	want := TokenUsage{PromptTokens: 2100, CompletionTokens: 10, CachedTokens: 2000}
+	for name, provider := range map[string]LLM{"anthropic": anthropic, "openai-like": like} {
+		resp, err := GenerateResponse(context.Background(), provider, "hello")
+		if err != nil {
+			t.Fatalf("%s: GenerateResponse() unexpected error: %v", name, err)
+		}
+		if resp.Usage != want {
+			t.Errorf("%s: Usage = %+v, want %+v", name, resp.Usage, want)
+		}
+	}
+
+	// 100 uncached tokens at $2/M, 2000 cached at $1/M and 10 completion at $10/M
+	price := Price{Prompt: 2, Completion: 10, CachedPrompt: 1}
+	if cost := price.Cost(want); cost < 0.0022999 || cost > 0.0023001 {
+		t.Errorf("Cost() = %v, want 0.0023", cost)
+	}
+}
+
+func TestGenerate_PromptLengthCheck(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"content":"ok"},"finish_reason":"stop"}]}`))
+	}))
+	defer server.Close()
+
+	// gpt-4 has an 8192-token window; 4096 are reserved for the completion
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "gpt-4", MaxTokens: 4096})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	_, err = provider.Generate(context.Background(), strings.Repeat("word ", 5000))
+	if !errors.HasCode(err, errors.ErrOutOfRange) {
+		t.Fatalf("Generate() error = %v, want ErrOutOfRange", err)
+	}
+	ctx := err.(*errors.Error).Context
+	// One token per word and one for the trailing space, as tiktoken counts
+	if ctx["prompt_tokens"] != 5001 || ctx["max_prompt_tokens"] != 4096 || ctx["context_window"] != 8192 {
+		t.Errorf("error context = %v, want prompt, limit and window token counts", ctx)
+	}
+	if calls != 0 {
+		t.Errorf("server received %d requests, want none", calls)
+	}
+
+	if _, err := provider.Generate(context.Background(), "hello"); err != nil {
+		t.Errorf("Generate() short prompt unexpected error: %v", err)
+	}
+
+	// An explicit budget applies to unknown models; a negative one disables the check
+	for _, tt := range []struct {
+		maxPrompt int
+		wantErr   bool
+	}{{10, true}, {-1, false}} {
+		provider, _ := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "local-model", MaxPromptTokens: tt.maxPrompt})
+		_, err := provider.Generate(context.Background(), strings.Repeat("word ", 100))
+		if got := errors.HasCode(err, errors.ErrOutOfRange); got != tt.wantErr {
+			t.Errorf("MaxPromptTokens=%d: Generate() error = %v, want out of range %v", tt.maxPrompt, err, tt.wantErr)
+		}
+	}
+
+	// gpt-4 only names the 8k model: later gpt-4 models have their own
+	// windows, and unknown ones are not checked
+	for model, want := range map[string]int{
+		"gpt-4":              8192,
+		"gpt-4-0613":         8192,
+		"gpt-4-1106-preview": 128000,
+		"gpt-4-0125-preview": 128000,
+		"gpt-4-32k":          32768,
+		"gpt-4o-2024-08-06":  128000,
+		"gpt-4-unreleased":   0,
+	} {
+		if window, _ := ContextWindow(model); window != want {
+			t.Errorf("ContextWindow(%s) = %d, want %d", model, window, want)
+		}
+	}
+}
+
+func TestLogger_RecordsCalls(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"model":"m-1","choices":[{"message":{"content":"secret answer"},"finish_reason":"stop"}],"usage":{"prompt_tokens":7,"completion_tokens":2}}`))
+	}))
+	defer server.Close()
+
+	var plain, redacted bytes.Buffer
+	logger := MultiLogger{
+		NewWriterLogger(&plain, LogOptions{}),
+		NewWriterLogger(&redacted, LogOptions{IncludeText: true, Redact: func(s string) string {
+			return strings.ReplaceAll(s, "secret", "***")
+		}}),
+	}
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "m", TemperatureSet: true, Logger: logger})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	if _, err := provider.Generate(runid.NewContext(context.Background(), "run-1"), "my secret prompt"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if temperature, ok := body["temperature"]; !ok || temperature != 0.0 {
+		t.Errorf("request temperature = %v, want an explicit 0", temperature)
+	}
+
+	var record LogRecord
+	if err := json.Unmarshal(plain.Bytes(), &record); err != nil {
+		t.Fatalf("invalid log line %q: %v", plain.String(), err)
+	}
+	if record.Prompt != "" || record.Response != "" {
+		t.Errorf("text logged without IncludeText: %+v", record)
+	}
+	if record.PromptHash != hashText("my secret prompt") || record.Model != "m-1" || record.RunID != "run-1" ||
+		record.Temperature != 0 || record.PromptTokens != 7 || record.CompletionTokens != 2 || record.FinishReason != FinishStop {
+		t.Errorf("record = %+v", record)
+	}
+
+	if err := json.Unmarshal(redacted.Bytes(), &record); err != nil {
+		t.Fatalf("invalid log line %q: %v", redacted.String(), err)
+	}
+	if record.Prompt != "my *** prompt" || record.Response != "*** answer" {
+		t.Errorf("redacted text = %q / %q", record.Prompt, record.Response)
+	}
+
+	// Failed calls are logged with their error
+	plain.Reset()
+	provider, _ = NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL, Model: "m", MaxPromptTokens: 1, Logger: logger})
+	if _, err := provider.Generate(context.Background(), "too long a prompt"); err == nil {
+		t.Fatal("Generate() expected error")
+	}
+	if err := json.Unmarshal(plain.Bytes(), &record); err != nil || record.Error == "" {
+		t.Errorf("failed call record = %+v (%v), want error", record, err)
+	}
+}
+
+func TestHTTPLogger_DropsWhenFull(t *testing.T) {
+	release := make(chan struct{})
+	var posted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		posted.Add(1)
+	}))
+	defer server.Close()
+
+	logger, err := NewHTTPLogger(HTTPLoggerConfig{URL: server.URL, Buffer: 1})
+	if err != nil {
+		t.Fatalf("NewHTTPLogger() unexpected error: %v", err)
+	}
+
+	// The collector is stalled: logging returns at once, and records beyond
+	// the one being posted and the buffered one are dropped
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		logger.LogCall(context.Background(), LogRecord{Provider: "openai"})
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("LogCall() blocked for %v", elapsed)
+	}
+	if dropped := logger.Dropped(); dropped < 3 {
+		t.Errorf("Dropped() = %d, want at least 3", dropped)
+	}
+
+	close(release)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := logger.Close(ctx); err != nil {
+		t.Fatalf("Close() unexpected error: %v", err)
+	}
+	if got := int64(posted.Load()) + logger.Dropped(); got != 5 {
+		t.Errorf("posted + dropped = %d, want 5", got)
+	}
+	logger.LogCall(context.Background(), LogRecord{Provider: "openai"})
+	if logger.Dropped() < 4 {
+		t.Errorf("LogCall() after Close() was not dropped")
+	}
+}
+
+func TestTokenizers(t *testing.T) {
+	if got := pretokenize("Hello world's  2024 test!\n\nok"); strings.Join(got, "|") != "Hello| world|'s| | |202|4| test|!\n\n|ok" {
+		t.Errorf("pretokenize() = %q", got)
+	}
+
+	// Ranks for "a", "b", "ab" and " ab"
+	ranks := "YQ== 0\nYg== 1\nYWI= 2\nIGFi 3\nIA== 4\n"
+	tiktoken, err := NewTiktoken(strings.NewReader(ranks))
+	if err != nil {
+		t.Fatalf("NewTiktoken() unexpected error: %v", err)
+	}
+	if got := tiktoken.CountTokens("ab ab abba"); got != 5 { // ab| ab| ab|b|a
+		t.Errorf("Tiktoken.CountTokens() = %d, want 5", got)
+	}
+	if _, err := NewTiktoken(strings.NewReader("not base64!\n")); !errors.HasCode(err, errors.ErrInvalidFormat) {
+		t.Errorf("NewTiktoken() invalid file error = %v, want ErrInvalidFormat", err)
+	}
+
+	if TokenizerFor("openai/gpt-4o") != TiktokenEstimate || TokenizerFor("mixtral-8x7b") != SentencePieceEstimate {
+		t.Error("TokenizerFor() did not select the model family's tokenizer")
+	}
+	if got := CountTokens("my-model", "twelve chars"); got != 3 {
+		t.Errorf("CountTokens() unknown model = %d, want character estimate 3", got)
+	}
+	SetTokenizer("my-model", TokenizerFunc(func(string) int { return 42 }))
+	defer SetTokenizer("my-model", nil)
+	if got := CountTokens("my-model-v2", "anything"); got != 42 {
+		t.Errorf("CountTokens() after SetTokenizer = %d, want 42", got)
+	}
+}
+
+// overlapLLM records whether calls overlapped
+type overlapLLM struct {
+	running, maxRunning atomic.Int32
+}
+
+func (m *overlapLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	n := m.running.Add(1)
+	defer m.running.Add(-1)
+	if n > m.maxRunning.Load() {
+		m.maxRunning.Store(n)
+	}
+	time.Sleep(20 * time.Millisecond)
+	return "ok", nil
+}
+
+func TestSharedPrefix(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+	}))
+	defer server.Close()
+
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+
+	ctx := NewPrefixContext(context.Background(), NewSharedPrefix("Shared document\n"))
+	if _, err := GenerateResponse(ctx, anthropic, "Shared document\nQuestion"); err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	content := body["messages"].([]interface{})[0].(map[string]interface{})["content"].([]interface{})
+	first := content[0].(map[string]interface{})
+	if len(content) != 2 || first["text"] != "Shared document\n" || first["cache_control"] == nil {
+		t.Errorf("content = %v, want the shared prefix marked for caching, then the question", content)
+	}
+
+	// Requests not starting with the prefix are sent unchanged
+	if _, err := GenerateResponse(ctx, anthropic, "Other question"); err != nil {
+		t.Fatalf("GenerateResponse() unexpected error: %v", err)
+	}
+	if content := body["messages"].([]interface{})[0].(map[string]interface{})["content"]; content != "Other question" {
+		t.Errorf("content = %v, want plain text", content)
+	}
+
+	// The first request sharing a prefix runs alone
+	l := &overlapLLM{}
+	ctx = NewPrefixContext(context.Background(), NewSharedPrefix("Shared\n"))
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = GenerateResponse(ctx, l, "Shared\nTask")
+		}()
+	}
+	wg.Wait()
+	if got := l.maxRunning.Load(); got > 2 {
+		t.Errorf("max concurrent calls = %d, want the leader alone, then the others", got)
+	}
+
+	// Wrappers calling GenerateResponse again don't wait for themselves
+	ctx, cancel := context.WithTimeout(NewPrefixContext(context.Background(), NewSharedPrefix("Shared\n")), 2*time.Second)
+	defer cancel()
+	wrapped := wrapperLLM{wrapperLLM{&overlapLLM{}}}
+	for i := 0; i < 2; i++ {
+		if _, err := GenerateResponse(ctx, wrapped, "Shared\nTask"); err != nil {
+			t.Fatalf("GenerateResponse() through wrappers unexpected error: %v", err)
+		}
+	}
+}
+
+func TestGenerateStream_SharedPrefixFallback(t *testing.T) {
+	ctx, cancel := context.WithTimeout(NewPrefixContext(context.Background(), NewSharedPrefix("Shared\n")), 2*time.Second)
+	defer cancel()
+	var chunks []string
+	resp, err := GenerateStream(ctx, NewSimulator(SimulatorConfig{}), "Shared\nTask", func(chunk string) error {
+		chunks = append(chunks, chunk)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("GenerateStream() unexpected error: %v", err)
+	}
+	if len(chunks) != 1 || chunks[0] != resp.Content {
+		t.Errorf("chunks = %q, want the whole response once", chunks)
+	}
+}
+
+// wrapperLLM forwards to inner through the package helper, like the
+// tracing, quota and audit wrappers
+type wrapperLLM struct {
+	inner LLM
+}
+
+func (w wrapperLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return w.inner.Generate(ctx, prompt)
+}
+
+func (w wrapperLLM) GenerateResponse(ctx context.Context, prompt string) (*Response, error) {
+	return GenerateResponse(ctx, w.inner, prompt)
+}
+
+func TestGenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]interface{}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req["stream"] != true {
+			t.Errorf("request stream = %v, want true", req["stream"])
+		}
+		if r.URL.Path != "/v1/messages" {
+			if opts, _ := req["stream_options"].(map[string]interface{}); opts["include_usage"] != true {
+				t.Errorf("request stream_options = %v, want include_usage", req["stream_options"])
+			}
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte("event: message_start\n" +
+				`data: {"type":"message_start","message":{"model":"claude-test","usage":{"input_tokens":7}}}` + "\n\n" +
+				"event: ping\ndata: {\"type\":\"ping\"}\n\n" +
+				`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}` + "\n\n" +
+				`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"lo"}}` + "\n\n" +
+				`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}` + "\n\n" +
+				`data: {"type":"message_stop"}` + "\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(": keep-alive\n\n" +
+			`data: {"model":"gpt-test","choices":[{"delta":{"content":"Hel"}}]}` + "\n\n" +
+			`data: {"model":"gpt-test","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n" +
+			`data: {"choices":[],"usage":{"prompt_tokens":7,"completion_tokens":2}}` + "\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer server.Close()
+
+	openAILike, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL + "/v1", Model: "local-model"})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+
+	tests := []struct {
+		name      string
+		provider  LLM
+		wantModel string
+		wantUsage TokenUsage
+	}{
+		{"openai-like", openAILike, "gpt-test", TokenUsage{PromptTokens: 7, CompletionTokens: 2}},
+		{"anthropic", anthropic, "claude-test", TokenUsage{PromptTokens: 7, CompletionTokens: 2}},
+		{"simulator fallback", NewSimulator(SimulatorConfig{Responses: []SimulatedResponse{{Match: "hi", Content: "Hello"}}}), SimulatedModel, TokenUsage{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var chunks []string
+			resp, err := GenerateStream(context.Background(), tt.provider, "hi", func(chunk string) error {
+				chunks = append(chunks, chunk)
+				return nil
+			})
+			if err != nil {
+				t.Fatalf("GenerateStream() unexpected error: %v", err)
+			}
+			if resp.Content != "Hello" || strings.Join(chunks, "") != "Hello" {
+				t.Errorf("GenerateStream() content = %q, chunks = %q, want %q", resp.Content, chunks, "Hello")
+			}
+			if resp.Model != tt.wantModel {
+				t.Errorf("Model = %q, want %q", resp.Model, tt.wantModel)
+			}
+			if _, streamed := tt.provider.(StreamingLLM); streamed {
+				if len(chunks) != 2 {
+					t.Errorf("chunks = %q, want 2 chunks", chunks)
+				}
+				if resp.FinishReason != FinishStop || resp.Usage != tt.wantUsage {
+					t.Errorf("FinishReason = %q, Usage = %+v, want %q, %+v", resp.FinishReason, resp.Usage, FinishStop, tt.wantUsage)
+				}
+			}
+		})
+	}
+
+	// An error from the callback stops the stream and is returned unchanged
+	stop := stderrors.New("stop")
+	if _, err := openAILike.GenerateStream(context.Background(), "hi", func(string) error { return stop }); err != stop {
+		t.Errorf("GenerateStream() error = %v, want the callback's error", err)
+	}
+}
+
+func TestGenerateStream_Truncated(t *testing.T) {
+	// The connection drops after the first chunk, before the end marker
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`data: {"type":"message_start","message":{"model":"claude-test"}}` + "\n\n" +
+				`data: {"type":"content_block_delta","delta":{"type":"text_delta","text":"Hel"}}` + "\n\n"))
+			return
+		}
+		_, _ = w.Write([]byte(`data: {"model":"gpt-test","choices":[{"delta":{"content":"Hel"}}]}` + "\n\n"))
+	}))
+	defer server.Close()
+
+	openAILike, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL + "/v1", Model: "local-model"})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+
+	for name, provider := range map[string]StreamingLLM{"openai-like": openAILike, "anthropic": anthropic} {
+		t.Run(name, func(t *testing.T) {
+			resp, err := provider.GenerateStream(context.Background(), "hi", func(string) error { return nil })
+			if err == nil {
+				t.Fatalf("GenerateStream() = %+v, want an error for the truncated stream", resp)
+			}
+			if !stderrors.Is(err, io.ErrUnexpectedEOF) || !errors.IsTemporary(err) {
+				t.Errorf("GenerateStream() error = %v, want a temporary error wrapping io.ErrUnexpectedEOF", err)
+			}
+		})
+	}
+}
+
+func TestGenerateStream_IdleTimeout(t *testing.T) {
+	// Chunks arrive every 40ms: the stream outlasts the 100ms timeout but
+	// never waits that long for the next chunk
+	chunks := []string{"one ", "two ", "three ", "four"}
+	steady := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, chunk := range chunks {
+			time.Sleep(40 * time.Millisecond)
+			fmt.Fprintf(w, "data: {\"choices\":[{\"delta\":{\"content\":%q}}]}\n\n", chunk)
+			w.(http.Flusher).Flush()
+		}
+		_, _ = w.Write([]byte("data: [DONE]\n\n"))
+	}))
+	defer steady.Close()
+
+	provider, err := NewOpenAILike(OpenAILikeConfig{BaseURL: steady.URL, Model: "test-model", Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	resp, err := provider.GenerateStream(context.Background(), "hi", func(string) error { return nil })
+	if err != nil {
+		t.Fatalf("GenerateStream() unexpected error: %v", err)
+	}
+	if resp.Content != "one two three four" {
+		t.Errorf("GenerateStream() content = %q", resp.Content)
+	}
+
+	// A gap longer than the timeout fails the stream with ErrTimeout
+	release := make(chan struct{})
+	stalled := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		_, _ = w.Write([]byte(`data: {"choices":[{"delta":{"content":"one "}}]}` + "\n\n"))
+		w.(http.Flusher).Flush()
+		select {
+		case <-release:
+		case <-r.Context().Done():
+		}
+	}))
+	defer stalled.Close()
+	defer close(release)
+
+	provider, err = NewOpenAILike(OpenAILikeConfig{BaseURL: stalled.URL, Model: "test-model", Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	_, err = provider.GenerateStream(context.Background(), "hi", func(string) error { return nil })
+	if !errors.HasCode(err, errors.ErrTimeout) {
+		t.Fatalf("GenerateStream() error = %v, want ErrTimeout", err)
+	}
+}
+
+func TestOpenAILike_Endpoint(t *testing.T) {
+	var gotURL *url.URL
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotURL = r.URL
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	azure, err := NewAzureOpenAI(AzureOpenAIConfig{Endpoint: server.URL + "/", APIKey: "key", DeploymentName: "gpt 4o", APIVersion: "2024-06-01"})
+	if err != nil {
+		t.Fatalf("NewAzureOpenAI() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		provider  *OpenAILike
+		wantPath  string
+		wantQuery string
+	}{
+		{"no trailing slash", &OpenAILike{config: OpenAILikeConfig{BaseURL: server.URL + "/v1"}, client: &http.Client{}}, "/v1/chat/completions", ""},
+		{"trailing slash", &OpenAILike{config: OpenAILikeConfig{BaseURL: server.URL + "/v1/"}, client: &http.Client{}}, "/v1/chat/completions", ""},
+		{"query parameters", &OpenAILike{config: OpenAILikeConfig{BaseURL: server.URL + "/v1?tenant=a"}, client: &http.Client{}}, "/v1/chat/completions", "tenant=a"},
+		{"azure", azure, "/openai/deployments/gpt 4o/chat/completions", "api-version=2024-06-01"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.provider.Generate(context.Background(), "hello"); err != nil {
+				t.Fatalf("Generate() unexpected error: %v", err)
+			}
+			if gotURL.Path != tt.wantPath || gotURL.RawQuery != tt.wantQuery {
+				t.Errorf("request URL = %s, want path %q and query %q", gotURL, tt.wantPath, tt.wantQuery)
+			}
+		})
+	}
+
+	for _, baseURL := range []string{"localhost:11434", "/v1", "http://[::1"} {
+		if _, err := NewOpenAILike(OpenAILikeConfig{BaseURL: baseURL, Model: "m"}); !errors.HasCode(err, errors.ErrInvalidConfig) {
+			t.Errorf("NewOpenAILike(%q) error = %v, want ErrInvalidConfig", baseURL, err)
+		}
+	}
+}
+
+// promptLLM records the prompt of its last call
+type promptLLM struct {
+	prompt string
+}
+
+func (m *promptLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.prompt = prompt
+	return "ok", nil
+}
+
+func TestChatResponse(t *testing.T) {
+	var body map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body = nil
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		if r.URL.Path == "/v1/messages" {
+			_, _ = w.Write([]byte(`{"content":[{"type":"text","text":"ok"}],"stop_reason":"end_turn"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"ok"}}]}`))
+	}))
+	defer server.Close()
+
+	conversation := []Message{
+		{Role: RoleSystem, Content: "You are terse."},
+		{Role: RoleUser, Content: "Hi"},
+		{Role: RoleAssistant, Content: "Hello"},
+		{Role: RoleUser, Content: "Bye"},
+	}
+
+	openAILike, err := NewOpenAILike(OpenAILikeConfig{BaseURL: server.URL + "/v1", Model: "local-model"})
+	if err != nil {
+		t.Fatalf("NewOpenAILike() unexpected error: %v", err)
+	}
+	reply, err := openAILike.Chat(context.Background(), conversation)
+	if err != nil {
+		t.Fatalf("Chat() unexpected error: %v", err)
+	}
+	if reply != (Message{Role: RoleAssistant, Content: "ok"}) {
+		t.Errorf("Chat() = %+v, want the assistant's reply", reply)
+	}
+	if messages := body["messages"].([]interface{}); len(messages) != 4 || messages[0].(map[string]interface{})["role"] != RoleSystem {
+		t.Errorf("messages = %v, want the conversation with its roles", messages)
+	}
+
+	// Anthropic takes system messages in a separate field
+	anthropic, err := NewAnthropic(Config{APIKey: "test-key"})
+	if err != nil {
+		t.Fatalf("NewAnthropic() unexpected error: %v", err)
+	}
+	anthropic.client = &http.Client{Transport: rewriteTransport{target: server.URL}}
+	if _, err := ChatResponse(context.Background(), anthropic, conversation); err != nil {
+		t.Fatalf("ChatResponse() unexpected error: %v", err)
+	}
+	if messages := body["messages"].([]interface{}); body["system"] != "You are terse." || len(messages) != 3 {
+		t.Errorf("system = %v, messages = %v, want the system message split out", body["system"], messages)
+	}
+
+	// Providers without chat support get the conversation flattened
+	plain := &promptLLM{}
+	if _, err := ChatResponse(context.Background(), plain, conversation); err != nil {
+		t.Fatalf("ChatResponse() unexpected error: %v", err)
+	}
+	if want := "You are terse.\n\nUser: Hi\n\nAssistant: Hello\n\nUser: Bye"; plain.prompt != want {
+		t.Errorf("flattened prompt = %q, want %q", plain.prompt, want)
+	}
+	if got := FlattenMessages(conversation[:2]); got != "You are terse.\n\nHi" {
+		t.Errorf("FlattenMessages() = %q, want a single request without labels", got)
+	}
+
+	for _, messages := range [][]Message{nil, {{Role: "tool", Content: "x"}}} {
+		if _, err := ChatResponse(context.Background(), plain, messages); !errors.HasCode(err, errors.ErrRequiredField) && !errors.HasCode(err, errors.ErrInvalidField) {
+			t.Errorf("ChatResponse(%v) error = %v, want a validation error", messages, err)
+		}
+	}
+}
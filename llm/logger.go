@@ -0,0 +1,357 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// LogRecord describes one request to a provider and its outcome. Prompt and
+// Response hold the full text; loggers drop or redact them according to
+// their LogOptions.
+type LogRecord struct {
+	Time     time.Time `json:"time"`
+	RunID    string    `json:"run_id,omitempty"`
+	Provider string    `json:"provider"`
+	Model    string    `json:"model,omitempty"`
+
+	// Request parameters
+	Temperature float32 `json:"temperature,omitempty"`
+	MaxTokens   int     `json:"max_tokens,omitempty"`
+	TopP        float32 `json:"top_p,omitempty"`
+
+	PromptHash   string `json:"prompt_hash"`
+	Prompt       string `json:"prompt,omitempty"`
+	ResponseHash string `json:"response_hash,omitempty"`
+	Response     string `json:"response,omitempty"`
+	FinishReason string `json:"finish_reason,omitempty"`
+
+	LatencyMS        int64  `json:"latency_ms"`
+	PromptTokens     int    `json:"prompt_tokens,omitempty"`
+	CompletionTokens int    `json:"completion_tokens,omitempty"`
+	CachedTokens     int    `json:"cached_tokens,omitempty"`
+	Error            string `json:"error,omitempty"`
+}
+
+// Logger receives a record of every request sent to a provider. It is
+// separate from progress logging: records are structured and meant for
+// files, log pipelines or collectors. Implementations must be safe for
+// concurrent use; logging failures never fail the call.
+type Logger interface {
+	LogCall(ctx context.Context, record LogRecord)
+}
+
+// MultiLogger sends records to several loggers
+type MultiLogger []Logger
+
+// LogCall passes the record to every logger
+func (m MultiLogger) LogCall(ctx context.Context, record LogRecord) {
+	for _, l := range m {
+		l.LogCall(ctx, record)
+	}
+}
+
+// LogOptions controls what loggers record
+type LogOptions struct {
+	// IncludeText records the full prompt and response; by default only
+	// their SHA-256 hashes are recorded
+	IncludeText bool
+	// Redact rewrites the prompt and response before they are recorded,
+	// when IncludeText is set
+	Redact func(string) string
+}
+
+// apply drops or redacts the text of a record
+func (o LogOptions) apply(record LogRecord) LogRecord {
+	if !o.IncludeText {
+		record.Prompt, record.Response = "", ""
+	} else if o.Redact != nil {
+		record.Prompt = o.Redact(record.Prompt)
+		record.Response = o.Redact(record.Response)
+	}
+	return record
+}
+
+// WriterLogger writes records as JSON lines, e.g. to a file
+type WriterLogger struct {
+	mu   sync.Mutex
+	w    io.Writer
+	opts LogOptions
+}
+
+// NewWriterLogger creates a logger writing JSON lines to w
+func NewWriterLogger(w io.Writer, opts LogOptions) *WriterLogger {
+	return &WriterLogger{w: w, opts: opts}
+}
+
+// LogCall writes the record as a single JSON line
+func (l *WriterLogger) LogCall(ctx context.Context, record LogRecord) {
+	data, err := json.Marshal(l.opts.apply(record))
+	if err != nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.w.Write(append(data, '\n'))
+}
+
+// SlogLogger writes records to a log/slog logger
+type SlogLogger struct {
+	logger *slog.Logger
+	opts   LogOptions
+}
+
+// NewSlogLogger creates a logger writing to logger; nil uses slog.Default()
+func NewSlogLogger(logger *slog.Logger, opts LogOptions) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger, opts: opts}
+}
+
+// LogCall logs the record at Info level, or Error level for failed calls
+func (l *SlogLogger) LogCall(ctx context.Context, record LogRecord) {
+	r := l.opts.apply(record)
+	attrs := []slog.Attr{
+		slog.String("provider", r.Provider),
+		slog.String("model", r.Model),
+		slog.String("prompt_hash", r.PromptHash),
+		slog.Int64("latency_ms", r.LatencyMS),
+		slog.Int("prompt_tokens", r.PromptTokens),
+		slog.Int("completion_tokens", r.CompletionTokens),
+	}
+	if r.RunID != "" {
+		attrs = append(attrs, slog.String("run_id", r.RunID))
+	}
+	if r.Temperature != 0 || r.MaxTokens != 0 || r.TopP != 0 {
+		attrs = append(attrs, slog.Group("params",
+			slog.Float64("temperature", float64(r.Temperature)),
+			slog.Int("max_tokens", r.MaxTokens),
+			slog.Float64("top_p", float64(r.TopP)),
+		))
+	}
+	if r.Prompt != "" {
+		attrs = append(attrs, slog.String("prompt", r.Prompt))
+	}
+	if r.Response != "" {
+		attrs = append(attrs, slog.String("response", r.Response))
+	}
+
+	level := slog.LevelInfo
+	if r.Error != "" {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", r.Error))
+	} else {
+		attrs = append(attrs, slog.String("finish_reason", r.FinishReason))
+	}
+	l.logger.LogAttrs(ctx, level, "llm call", attrs...)
+}
+
+// DefaultHTTPLoggerBuffer is the number of records an HTTPLogger buffers by
+// default
+const DefaultHTTPLoggerBuffer = 256
+
+// HTTPLoggerConfig represents the configuration for an HTTP logger
+type HTTPLoggerConfig struct {
+	// URL receives each record as a JSON POST body
+	URL string
+	// Headers allows custom HTTP headers, e.g. for authentication
+	Headers map[string]string
+	// Timeout bounds each request (0 = 10 seconds)
+	Timeout time.Duration
+	// Buffer is the number of records waiting to be posted; records logged
+	// while it is full are dropped (0 = DefaultHTTPLoggerBuffer)
+	Buffer int
+	LogOptions
+}
+
+// HTTPLogger posts records to a log collector. Records are posted one at a
+// time by a background sender, so a slow collector never delays provider
+// calls; call Close to flush the buffer.
+type HTTPLogger struct {
+	config  HTTPLoggerConfig
+	client  *http.Client
+	records chan LogRecord
+	done    chan struct{}
+	dropped atomic.Int64
+
+	mu     sync.RWMutex
+	closed bool
+}
+
+// NewHTTPLogger creates a logger posting records to cfg.URL
+func NewHTTPLogger(cfg HTTPLoggerConfig) (*HTTPLogger, error) {
+	if cfg.URL == "" {
+		return nil, errors.RequiredField("URL")
+	}
+	if cfg.Buffer < 0 {
+		return nil, errors.InvalidField("buffer", "must not be negative")
+	}
+	if cfg.Timeout == 0 {
+		cfg.Timeout = 10 * time.Second
+	}
+	if cfg.Buffer == 0 {
+		cfg.Buffer = DefaultHTTPLoggerBuffer
+	}
+	l := &HTTPLogger{
+		config:  cfg,
+		client:  &http.Client{Timeout: cfg.Timeout},
+		records: make(chan LogRecord, cfg.Buffer),
+		done:    make(chan struct{}),
+	}
+	go l.send()
+	return l, nil
+}
+
+// LogCall queues the record for posting; it is dropped when the buffer is
+// full or the logger is closed. Delivery is not retried.
+func (l *HTTPLogger) LogCall(ctx context.Context, record LogRecord) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		l.dropped.Add(1)
+		return
+	}
+	select {
+	case l.records <- l.config.LogOptions.apply(record):
+	default:
+		l.dropped.Add(1)
+	}
+}
+
+// Dropped returns the number of records dropped because the buffer was full
+// or the logger closed
+func (l *HTTPLogger) Dropped() int64 {
+	return l.dropped.Load()
+}
+
+// Close stops accepting records and waits until the buffered ones are
+// posted or ctx is done
+func (l *HTTPLogger) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.records)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// send posts the buffered records until the logger is closed
+func (l *HTTPLogger) send() {
+	defer close(l.done)
+	for record := range l.records {
+		l.post(record)
+	}
+}
+
+// post sends one record to the collector
+func (l *HTTPLogger) post(record LogRecord) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return
+	}
+	req, err := http.NewRequest("POST", l.config.URL, bytes.NewReader(data))
+	if err != nil {
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range l.config.Headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := l.client.Do(req)
+	if err != nil {
+		return
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// logParams identifies the provider and request parameters of logged calls
+type logParams struct {
+	provider    string
+	model       string
+	temperature float32
+	maxTokens   int
+	topP        float32
+}
+
+// withLogger returns complete, reporting every request to logger. Each
+// continuation request is logged separately.
+func withLogger(logger Logger, params logParams, complete completeFunc) completeFunc {
+	if logger == nil {
+		return complete
+	}
+	return func(ctx context.Context, history []Message) (*Response, error) {
+		prompt := promptText(history)
+		record := LogRecord{
+			Time:        time.Now().UTC(),
+			RunID:       runid.FromContext(ctx),
+			Provider:    params.provider,
+			Model:       params.model,
+			Temperature: params.temperature,
+			MaxTokens:   params.maxTokens,
+			TopP:        params.topP,
+			PromptHash:  hashText(prompt),
+			Prompt:      prompt,
+		}
+
+		start := time.Now()
+		resp, err := complete(ctx, history)
+		record.LatencyMS = time.Since(start).Milliseconds()
+		if err != nil {
+			record.Error = err.Error()
+		} else {
+			if resp.Model != "" {
+				record.Model = resp.Model
+			}
+			record.Response = resp.Content
+			record.ResponseHash = hashText(resp.Content)
+			record.FinishReason = resp.FinishReason
+			record.PromptTokens = resp.Usage.PromptTokens
+			record.CompletionTokens = resp.Usage.CompletionTokens
+			record.CachedTokens = resp.Usage.CachedTokens
+		}
+		logger.LogCall(ctx, record)
+		return resp, err
+	}
+}
+
+// promptText joins a conversation into the logged prompt; a single user
+// message is logged as is
+func promptText(history []Message) string {
+	if len(history) == 1 {
+		return history[0].Content
+	}
+	var b strings.Builder
+	for i, m := range history {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		b.WriteString(m.Role + ": " + m.Content)
+	}
+	return b.String()
+}
+
+// hashText returns the hex SHA-256 of s
+func hashText(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
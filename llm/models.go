@@ -0,0 +1,156 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// ModelLister is implemented by providers that can report the models
+// available to the configured credentials
+type ModelLister interface {
+	ListModels(ctx context.Context) ([]string, error)
+}
+
+// openAIModelList is the response of the OpenAI-compatible models endpoint
+type openAIModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+}
+
+// ollamaTagList is the response of Ollama's native /api/tags endpoint
+type ollamaTagList struct {
+	Models []struct {
+		Name string `json:"name"`
+	} `json:"models"`
+}
+
+// anthropicModelList is a page of the Anthropic models endpoint
+type anthropicModelList struct {
+	Data []struct {
+		ID string `json:"id"`
+	} `json:"data"`
+	HasMore bool   `json:"has_more"`
+	LastID  string `json:"last_id"`
+}
+
+// ListModels returns the model IDs available to the API key
+func (o *OpenAI) ListModels(ctx context.Context) ([]string, error) {
+	status, body, err := doGet(ctx, o.client, o.config.Client, "https://api.openai.com/v1/models", func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("provider", "openai")
+	}
+	return parseOpenAIModels(body)
+}
+
+// ListModels returns the model names served by the endpoint. It queries the
+// OpenAI-compatible models endpoint and falls back to Ollama's native
+// /api/tags for servers that do not expose it.
+func (o *OpenAILike) ListModels(ctx context.Context) ([]string, error) {
+	base := o.config.BaseURL
+	endpoint, err := o.endpoint("models")
+	if err != nil {
+		return nil, err
+	}
+
+	status, body, err := doGet(ctx, o.client, o.config.Client, endpoint, o.setAuthHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if status == http.StatusOK {
+		return parseOpenAIModels(body)
+	}
+	if status != http.StatusNotFound {
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("base_url", base)
+	}
+
+	// Older Ollama releases only expose the native tags endpoint
+	native, _ := parseBaseURL(base)
+	native.Path = strings.TrimSuffix(strings.TrimSuffix(native.Path, "/"), "/v1")
+	native.RawPath = ""
+	status, body, err = doGet(ctx, o.client, o.config.Client, native.JoinPath("api", "tags").String(), o.setAuthHeaders)
+	if err != nil {
+		return nil, err
+	}
+	if status != http.StatusOK {
+		return nil, errors.APIStatusCodeError(status, string(body)).WithContext("base_url", base)
+	}
+	var tags ollamaTagList
+	if err := json.Unmarshal(body, &tags); err != nil {
+		return nil, errors.Wrap(errors.ErrAPIResponse, "failed to parse model list", err).WithContext("base_url", base)
+	}
+	names := make([]string, 0, len(tags.Models))
+	for _, m := range tags.Models {
+		names = append(names, m.Name)
+	}
+	return names, nil
+}
+
+// setAuthHeaders applies the API key and custom headers to a request
+func (o *OpenAILike) setAuthHeaders(req *http.Request) {
+	if o.config.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+o.config.APIKey)
+	}
+	for key, value := range o.config.Headers {
+		req.Header.Set(key, value)
+	}
+}
+
+// ListModels returns the model IDs available to the API key, following
+// pagination until all pages are read
+func (a *Anthropic) ListModels(ctx context.Context) ([]string, error) {
+	names := make([]string, 0)
+	afterID := ""
+	for {
+		endpoint := "https://api.anthropic.com/v1/models?limit=1000"
+		if afterID != "" {
+			endpoint += "&after_id=" + url.QueryEscape(afterID)
+		}
+
+		status, body, err := doGet(ctx, a.client, a.config.Client, endpoint, func(req *http.Request) {
+			req.Header.Set("x-api-key", a.apiKey)
+			req.Header.Set("anthropic-version", "2023-06-01")
+		})
+		if err != nil {
+			return nil, err
+		}
+		if status != http.StatusOK {
+			return nil, errors.APIStatusCodeError(status, string(body)).WithContext("provider", "anthropic")
+		}
+
+		var page anthropicModelList
+		if err := json.Unmarshal(body, &page); err != nil {
+			return nil, errors.Wrap(errors.ErrAPIResponse, "failed to parse model list", err).WithContext("provider", "anthropic")
+		}
+		for _, m := range page.Data {
+			names = append(names, m.ID)
+		}
+		if !page.HasMore || page.LastID == "" {
+			return names, nil
+		}
+		afterID = page.LastID
+	}
+}
+
+// parseOpenAIModels extracts model IDs from an OpenAI-style model list
+func parseOpenAIModels(body []byte) ([]string, error) {
+	var list openAIModelList
+	if err := json.Unmarshal(body, &list); err != nil {
+		return nil, errors.Wrap(errors.ErrAPIResponse, "failed to parse model list", err)
+	}
+	names := make([]string, 0, len(list.Data))
+	for _, m := range list.Data {
+		names = append(names, m.ID)
+	}
+	return names, nil
+}
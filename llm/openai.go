@@ -4,8 +4,8 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"io"
 	"net/http"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
 )
@@ -14,8 +14,23 @@ import (
 type openAIRequest struct {
 	Model       string          `json:"model"`
 	Messages    []openAIMessage `json:"messages"`
-	Temperature float32         `json:"temperature,omitempty"`
+	Temperature *float32        `json:"temperature,omitempty"`
 	MaxTokens   int             `json:"max_tokens,omitempty"`
+	TopP        float32         `json:"top_p,omitempty"`
+	Logprobs    bool            `json:"logprobs,omitempty"`
+
+	// Streaming
+	Stream        bool                 `json:"stream,omitempty"`
+	StreamOptions *openAIStreamOptions `json:"stream_options,omitempty"`
+
+	// OpenRouter extensions
+	Models   []string           `json:"models,omitempty"`
+	Provider *OpenRouterRouting `json:"provider,omitempty"`
+}
+
+// openAIStreamOptions asks for token usage in the last chunk of a stream
+type openAIStreamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
 type openAIMessage struct {
@@ -35,12 +50,11 @@ type openAIResponse struct {
 			Content string `json:"content"`
 		} `json:"message"`
 		FinishReason string `json:"finish_reason"`
+		Logprobs     *struct {
+			Content []TokenLogprob `json:"content"`
+		} `json:"logprobs,omitempty"`
 	} `json:"choices"`
-	Usage struct {
-		PromptTokens     int `json:"prompt_tokens"`
-		CompletionTokens int `json:"completion_tokens"`
-		TotalTokens      int `json:"total_tokens"`
-	} `json:"usage"`
+	Usage openAIUsage `json:"usage"`
 	Error *struct {
 		Message string `json:"message"`
 		Type    string `json:"type"`
@@ -48,6 +62,28 @@ type openAIResponse struct {
 	} `json:"error,omitempty"`
 }
 
+type openAIUsage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+	// PromptTokensDetails reports automatic prompt caching
+	PromptTokensDetails *struct {
+		CachedTokens int `json:"cached_tokens"`
+	} `json:"prompt_tokens_details,omitempty"`
+}
+
+// tokenUsage converts the usage to TokenUsage
+func (u openAIUsage) tokenUsage() TokenUsage {
+	usage := TokenUsage{
+		PromptTokens:     u.PromptTokens,
+		CompletionTokens: u.CompletionTokens,
+	}
+	if u.PromptTokensDetails != nil {
+		usage.CachedTokens = u.PromptTokensDetails.CachedTokens
+	}
+	return usage
+}
+
 // OpenAI implements the LLM interface for OpenAI
 type OpenAI struct {
 	apiKey string
@@ -70,63 +106,186 @@ func NewOpenAI(cfg Config) (*OpenAI, error) {
 
 // Generate sends a prompt to OpenAI and returns the response
 func (o *OpenAI) Generate(ctx context.Context, prompt string) (string, error) {
-	model := o.config.Model
-	if model == "" {
-		model = "gpt-4-turbo-preview"
+	resp, err := o.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
 	}
+	return resp.Content, nil
+}
 
-	reqBody := openAIRequest{
-		Model:       model,
-		Temperature: o.config.Temperature,
-		MaxTokens:   o.config.MaxTokens,
-		Messages: []openAIMessage{
-			{
-				Role:    "user",
-				Content: prompt,
-			},
-		},
-	}
+// GenerateResponse sends a prompt to OpenAI and returns the response with
+// its finish reason and, if requested, logprobs
+func (o *OpenAI) GenerateResponse(ctx context.Context, prompt string) (*Response, error) {
+	return generateWithContinuation(ctx, prompt, o.config.MaxContinuations, o.logged(o.complete))
+}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", model)
-	}
+// GenerateStream sends a prompt to OpenAI and calls fn with each chunk of
+// the response as it arrives
+func (o *OpenAI) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*Response, error) {
+	complete := o.logged(func(ctx context.Context, history []Message) (*Response, error) {
+		return o.completeStream(ctx, history, fn)
+	})
+	return generateWithContinuation(ctx, prompt, o.config.MaxContinuations, complete)
+}
 
-	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonData))
+// Chat sends a conversation to OpenAI and returns the assistant's reply
+func (o *OpenAI) Chat(ctx context.Context, messages []Message) (Message, error) {
+	return chatReply(o.ChatResponse(ctx, messages))
+}
+
+// ChatResponse sends a conversation to OpenAI and returns the reply with
+// its metadata
+func (o *OpenAI) ChatResponse(ctx context.Context, messages []Message) (*Response, error) {
+	return chatWithContinuation(ctx, messages, o.config.MaxContinuations, o.logged(o.complete))
+}
+
+// logged records the requests of complete with the configured logger
+func (o *OpenAI) logged(complete completeFunc) completeFunc {
+	return withLogger(o.config.Logger, logParams{
+		provider:    "openai",
+		model:       o.config.Model,
+		temperature: o.config.Temperature,
+		maxTokens:   o.config.MaxTokens,
+		topP:        o.config.TopP,
+	}, complete)
+}
+
+// complete sends a conversation to OpenAI
+func (o *OpenAI) complete(ctx context.Context, history []Message) (*Response, error) {
+	ctx, cancel := withCallTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	req, model, err := o.newRequest(ctx, history, false)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to create request", err)
+		return nil, err
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+o.apiKey)
-
+	start := time.Now()
 	resp, err := o.client.Do(req)
 	if err != nil {
-		return "", errors.APICallError("call OpenAI API", err).WithContext("model", model).WithContext("prompt_length", len(prompt))
+		return nil, callError(ctx, "call OpenAI API", start, err).WithContext("model", model).WithContext("messages", len(history))
 	}
 	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	body, err := readBody(resp, o.config.MaxResponseBytes)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+		return nil, readError(ctx, "call OpenAI API", start, err).WithContext("model", model)
 	}
 
 	var openAIResp openAIResponse
 	if err := json.Unmarshal(body, &openAIResp); err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
+		return nil, errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
 	}
 
 	if openAIResp.Error != nil {
-		return "", errors.APIResponseError(openAIResp.Error.Message).WithContext("type", openAIResp.Error.Type).WithContext("code", openAIResp.Error.Code)
+		return nil, errors.APIResponseError(openAIResp.Error.Message).WithContext("type", openAIResp.Error.Type).WithContext("code", openAIResp.Error.Code)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.APIStatusCodeError(resp.StatusCode, string(body)).WithContext("model", model)
+		return nil, errors.APIStatusCodeError(resp.StatusCode, string(body)).WithContext("model", model)
 	}
 
 	if len(openAIResp.Choices) == 0 {
-		return "", errors.API("no response from OpenAI")
+		return nil, errors.API("no response from OpenAI")
+	}
+
+	return openAIResp.toResponse(), nil
+}
+
+// completeStream sends a conversation to OpenAI and streams the response
+func (o *OpenAI) completeStream(ctx context.Context, history []Message, fn func(chunk string) error) (*Response, error) {
+	ctx, timeout, cancel := withStreamTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	req, model, err := o.newRequest(ctx, history, true)
+	if err != nil {
+		return nil, err
 	}
 
-	return openAIResp.Choices[0].Message.Content, nil
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, callError(ctx, "call OpenAI API", start, err).WithContext("model", model).WithContext("messages", len(history))
+	}
+	resp.Body = timeout.watch(resp.Body)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := readBody(resp, o.config.MaxResponseBytes)
+		if err != nil {
+			return nil, readError(ctx, "call OpenAI API", start, err).WithContext("model", model)
+		}
+		var openAIResp openAIResponse
+		if json.Unmarshal(body, &openAIResp) == nil && openAIResp.Error != nil {
+			return nil, errors.APIResponseError(openAIResp.Error.Message).WithContext("type", openAIResp.Error.Type).WithContext("code", openAIResp.Error.Code)
+		}
+		return nil, errors.APIStatusCodeError(resp.StatusCode, string(body)).WithContext("model", model)
+	}
+
+	out, err := readStream(ctx, "call OpenAI API", start, resp, o.config.MaxResponseBytes, parseOpenAIStream, fn)
+	if err != nil {
+		return nil, err
+	}
+	if out.Model == "" {
+		out.Model = model
+	}
+	return out, nil
+}
+
+// newRequest builds the chat completion request for a conversation and
+// returns it with the model it asks for
+func (o *OpenAI) newRequest(ctx context.Context, history []Message, stream bool) (*http.Request, string, error) {
+	model := o.config.Model
+	if model == "" {
+		model = "gpt-4-turbo-preview"
+	}
+	if err := checkPromptLength(model, history, o.config.MaxTokens, o.config.MaxPromptTokens); err != nil {
+		return nil, model, err
+	}
+
+	reqBody := openAIRequest{
+		Model:       model,
+		Temperature: requestTemperature(o.config.Temperature, o.config.TemperatureSet),
+		MaxTokens:   o.config.MaxTokens,
+		TopP:        o.config.TopP,
+		Logprobs:    o.config.Logprobs,
+		Messages:    make([]openAIMessage, 0, len(history)),
+	}
+	for _, m := range history {
+		reqBody.Messages = append(reqBody.Messages, openAIMessage(m))
+	}
+	if stream {
+		reqBody.Stream = true
+		reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+
+	jsonData, err := marshalWithExtra(reqBody, o.config.ExtraBody)
+	if err != nil {
+		return nil, model, errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", model)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, model, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	setCommonHeaders(req, o.config.Client)
+	req.Header.Set("Authorization", "Bearer "+o.apiKey)
+	return req, model, nil
+}
+
+// toResponse converts the first choice into a Response
+func (r *openAIResponse) toResponse() *Response {
+	choice := r.Choices[0]
+	resp := &Response{
+		Content:      choice.Message.Content,
+		FinishReason: normalizeFinishReason(choice.FinishReason),
+		Model:        r.Model,
+		Usage:        r.Usage.tokenUsage(),
+	}
+	if choice.Logprobs != nil {
+		resp.Logprobs = choice.Logprobs.Content
+	}
+	return resp
 }
@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
-	"io"
 	"net/http"
+	"net/url"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
 )
@@ -19,14 +19,50 @@ type OpenAILikeConfig struct {
 	APIKey string
 	// Model name to use
 	Model string
-	// Temperature for generation (0.0 to 1.0)
+	// Temperature for generation (0.0 to 1.0, 0 = provider default unless
+	// TemperatureSet)
 	Temperature float32
+	// TemperatureSet sends Temperature even when it is 0
+	TemperatureSet bool
 	// MaxTokens limits the response length
 	MaxTokens int
+	// TopP enables nucleus sampling (0.0 to 1.0, 0 = provider default)
+	TopP float32
+	// Logprobs requests per-token log probabilities in the response
+	Logprobs bool
+	// MaxContinuations enables automatic continuation of responses cut off
+	// at MaxTokens, up to this many follow-up requests (0 = disabled)
+	MaxContinuations int
+	// MaxPromptTokens caps the estimated prompt size checked before each
+	// request (0 = model's context window minus MaxTokens, negative = no check)
+	MaxPromptTokens int
+	// Timeout bounds each request, or the wait for each chunk of a stream
+	// (0 = DefaultCallTimeout, negative = none)
+	Timeout time.Duration
+	// MaxResponseBytes caps the decoded size of each response body
+	// (0 = DefaultMaxResponseBytes, negative = no cap)
+	MaxResponseBytes int64
 	// Headers allows custom HTTP headers
 	Headers map[string]string
+	// NoStreamUsage leaves stream_options.include_usage out of streamed
+	// requests, for backends that reject it; their streamed calls then
+	// report no token usage
+	NoStreamUsage bool
 	// SystemPrompt is an optional system message
 	SystemPrompt string
+	// Routing sets provider routing preferences (OpenRouter only)
+	Routing *OpenRouterRouting
+	// FallbackModels are tried when Model is unavailable (OpenRouter only)
+	FallbackModels []string
+	// ExtraBody holds additional fields merged into the request payload,
+	// for parameters without a first-class field (e.g. top_k,
+	// response_format, logprobs). Entries override fields set from the config.
+	ExtraBody map[string]interface{}
+	// Logger receives a structured record of every request (nil = none)
+	Logger Logger
+	// Client identifies the application in request headers (default: the
+	// identity set with SetClientIdentity)
+	Client *ClientIdentity
 }
 
 // OpenAILike implements the LLM interface for any OpenAI-compatible API
@@ -47,15 +83,167 @@ func NewOpenAILike(cfg OpenAILikeConfig) (*OpenAILike, error) {
 		return nil, errors.RequiredField("model")
 	}
 
+	if _, err := parseBaseURL(cfg.BaseURL); err != nil {
+		return nil, err
+	}
+
 	return &OpenAILike{
 		config: cfg,
 		client: &http.Client{},
 	}, nil
 }
 
+// parseBaseURL parses the base URL of an OpenAI-compatible API
+func parseBaseURL(baseURL string) (*url.URL, error) {
+	base, err := url.Parse(baseURL)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, errors.InvalidConfig("baseURL", "must be an absolute URL such as http://localhost:11434/v1").WithContext("base_url", baseURL)
+	}
+	return base, nil
+}
+
+// endpoint returns the URL of an API path below the base URL, keeping the
+// base URL's query parameters (e.g. Azure's api-version)
+func (o *OpenAILike) endpoint(elem ...string) (string, error) {
+	base, err := parseBaseURL(o.config.BaseURL)
+	if err != nil {
+		return "", err
+	}
+	return base.JoinPath(elem...).String(), nil
+}
+
 // Generate sends a prompt to the OpenAI-compatible API and returns the response
 func (o *OpenAILike) Generate(ctx context.Context, prompt string) (string, error) {
-	messages := make([]openAIMessage, 0, 2)
+	resp, err := o.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// GenerateResponse sends a prompt to the OpenAI-compatible API and returns
+// the response with its finish reason and, if requested, logprobs
+func (o *OpenAILike) GenerateResponse(ctx context.Context, prompt string) (*Response, error) {
+	return generateWithContinuation(ctx, prompt, o.config.MaxContinuations, o.logged(o.complete))
+}
+
+// GenerateStream sends a prompt to the OpenAI-compatible API and calls fn
+// with each chunk of the response as it arrives
+func (o *OpenAILike) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*Response, error) {
+	complete := o.logged(func(ctx context.Context, history []Message) (*Response, error) {
+		return o.completeStream(ctx, history, fn)
+	})
+	return generateWithContinuation(ctx, prompt, o.config.MaxContinuations, complete)
+}
+
+// Chat sends a conversation to the OpenAI-compatible API and returns the assistant's reply
+func (o *OpenAILike) Chat(ctx context.Context, messages []Message) (Message, error) {
+	return chatReply(o.ChatResponse(ctx, messages))
+}
+
+// ChatResponse sends a conversation to the OpenAI-compatible API and
+// returns the reply with its metadata
+func (o *OpenAILike) ChatResponse(ctx context.Context, messages []Message) (*Response, error) {
+	return chatWithContinuation(ctx, messages, o.config.MaxContinuations, o.logged(o.complete))
+}
+
+// logged records the requests of complete with the configured logger
+func (o *OpenAILike) logged(complete completeFunc) completeFunc {
+	return withLogger(o.config.Logger, logParams{
+		provider:    "openai-like",
+		model:       o.config.Model,
+		temperature: o.config.Temperature,
+		maxTokens:   o.config.MaxTokens,
+		topP:        o.config.TopP,
+	}, complete)
+}
+
+// complete sends a conversation to the OpenAI-compatible API
+func (o *OpenAILike) complete(ctx context.Context, history []Message) (*Response, error) {
+	ctx, cancel := withCallTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	req, err := o.newRequest(ctx, history, false)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, callError(ctx, "call OpenAI-compatible API", start, err).WithContext("model", o.config.Model)
+	}
+	defer resp.Body.Close()
+
+	body, err := readBody(resp, o.config.MaxResponseBytes)
+	if err != nil {
+		return nil, readError(ctx, "call OpenAI-compatible API", start, err).WithContext("model", o.config.Model)
+	}
+
+	var apiResp openAIResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
+	}
+
+	if apiResp.Error != nil {
+		return nil, errors.APIf("OpenAI-compatible API error: %s", apiResp.Error.Message)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.APIf("OpenAI-compatible API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	if len(apiResp.Choices) == 0 {
+		return nil, errors.API("no response from OpenAI-compatible API")
+	}
+
+	return apiResp.toResponse(), nil
+}
+
+// completeStream sends a conversation to the OpenAI-compatible API and
+// streams the response
+func (o *OpenAILike) completeStream(ctx context.Context, history []Message, fn func(chunk string) error) (*Response, error) {
+	ctx, timeout, cancel := withStreamTimeout(ctx, o.config.Timeout)
+	defer cancel()
+
+	req, err := o.newRequest(ctx, history, true)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, callError(ctx, "call OpenAI-compatible API", start, err).WithContext("model", o.config.Model)
+	}
+	resp.Body = timeout.watch(resp.Body)
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, err := readBody(resp, o.config.MaxResponseBytes)
+		if err != nil {
+			return nil, readError(ctx, "call OpenAI-compatible API", start, err).WithContext("model", o.config.Model)
+		}
+		var apiResp openAIResponse
+		if json.Unmarshal(body, &apiResp) == nil && apiResp.Error != nil {
+			return nil, errors.APIf("OpenAI-compatible API error: %s", apiResp.Error.Message)
+		}
+		return nil, errors.APIf("OpenAI-compatible API error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	out, err := readStream(ctx, "call OpenAI-compatible API", start, resp, o.config.MaxResponseBytes, parseOpenAIStream, fn)
+	if err != nil {
+		return nil, err
+	}
+	if out.Model == "" {
+		out.Model = o.config.Model
+	}
+	return out, nil
+}
+
+// newRequest builds the chat completion request for a conversation
+func (o *OpenAILike) newRequest(ctx context.Context, history []Message, stream bool) (*http.Request, error) {
+	messages := make([]openAIMessage, 0, len(history)+1)
 
 	// Add system prompt if provided
 	if o.config.SystemPrompt != "" {
@@ -65,39 +253,48 @@ func (o *OpenAILike) Generate(ctx context.Context, prompt string) (string, error
 		})
 	}
 
-	// Add user message
-	messages = append(messages, openAIMessage{
-		Role:    "user",
-		Content: prompt,
-	})
+	for _, m := range history {
+		messages = append(messages, openAIMessage(m))
+	}
+
+	if err := checkPromptLength(o.config.Model, append([]Message{{Content: o.config.SystemPrompt}}, history...), o.config.MaxTokens, o.config.MaxPromptTokens); err != nil {
+		return nil, err
+	}
 
 	reqBody := openAIRequest{
 		Model:       o.config.Model,
-		Temperature: o.config.Temperature,
+		Temperature: requestTemperature(o.config.Temperature, o.config.TemperatureSet),
 		MaxTokens:   o.config.MaxTokens,
+		TopP:        o.config.TopP,
+		Logprobs:    o.config.Logprobs,
 		Messages:    messages,
+		Provider:    o.config.Routing,
+		Stream:      stream,
+	}
+	if stream && !o.config.NoStreamUsage {
+		reqBody.StreamOptions = &openAIStreamOptions{IncludeUsage: true}
+	}
+	if len(o.config.FallbackModels) > 0 {
+		reqBody.Models = append([]string{o.config.Model}, o.config.FallbackModels...)
 	}
 
-	jsonData, err := json.Marshal(reqBody)
+	jsonData, err := marshalWithExtra(reqBody, o.config.ExtraBody)
 	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", o.config.Model)
+		return nil, errors.Wrap(errors.ErrInternal, "failed to marshal request", err).WithContext("model", o.config.Model)
 	}
 
-	// Build the endpoint URL
-	endpoint := o.config.BaseURL
-	// Ensure URL ends properly
-	if endpoint[len(endpoint)-1] != '/' {
-		endpoint += "/"
+	endpoint, err := o.endpoint("chat", "completions")
+	if err != nil {
+		return nil, err
 	}
-	endpoint += "chat/completions"
-
 	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(jsonData))
 	if err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", endpoint)
+		return nil, errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", endpoint)
 	}
 
 	// Set default headers
 	req.Header.Set("Content-Type", "application/json")
+	setCommonHeaders(req, o.config.Client)
 
 	// Set Authorization header if API key is provided
 	if o.config.APIKey != "" {
@@ -109,35 +306,7 @@ func (o *OpenAILike) Generate(ctx context.Context, prompt string) (string, error
 		req.Header.Set(key, value)
 	}
 
-	resp, err := o.client.Do(req)
-	if err != nil {
-		return "", errors.APICallError("call OpenAI-compatible API", err)
-	}
-	defer resp.Body.Close()
-
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return "", errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
-	}
-
-	var apiResp openAIResponse
-	if err := json.Unmarshal(body, &apiResp); err != nil {
-		return "", errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
-	}
-
-	if apiResp.Error != nil {
-		return "", errors.APIf("OpenAI-compatible API error: %s", apiResp.Error.Message)
-	}
-
-	if resp.StatusCode != http.StatusOK {
-		return "", errors.APIf("OpenAI-compatible API error (status %d): %s", resp.StatusCode, string(body))
-	}
-
-	if len(apiResp.Choices) == 0 {
-		return "", errors.API("no response from OpenAI-compatible API")
-	}
-
-	return apiResp.Choices[0].Message.Content, nil
+	return req, nil
 }
 
 // Common preset constructors for popular providers
@@ -166,6 +335,62 @@ func NewLMStudio(model string, baseURL ...string) (*OpenAILike, error) {
 	})
 }
 
+// SamplingParams holds sampling parameters supported by self-hosted
+// inference servers beyond the OpenAI API
+type SamplingParams struct {
+	// TopK samples from the K most likely tokens
+	TopK int
+	// BestOf generates this many candidates server-side and returns the best
+	BestOf int
+	// RepetitionPenalty penalizes repeated tokens (1.0 = no penalty)
+	RepetitionPenalty float32
+}
+
+// extraBody returns the parameters that are set, keyed by request field
+func (p SamplingParams) extraBody() map[string]interface{} {
+	extra := make(map[string]interface{})
+	if p.TopK > 0 {
+		extra["top_k"] = p.TopK
+	}
+	if p.BestOf > 0 {
+		extra["best_of"] = p.BestOf
+	}
+	if p.RepetitionPenalty > 0 {
+		extra["repetition_penalty"] = p.RepetitionPenalty
+	}
+	return extra
+}
+
+// NewVLLM creates a new LLM provider for a vLLM server
+func NewVLLM(model string, params SamplingParams, baseURL ...string) (*OpenAILike, error) {
+	url := "http://localhost:8000/v1"
+	if len(baseURL) > 0 && baseURL[0] != "" {
+		url = baseURL[0]
+	}
+	return NewOpenAILike(OpenAILikeConfig{
+		BaseURL:   url,
+		Model:     model,
+		ExtraBody: params.extraBody(),
+	})
+}
+
+// NewTGI creates a new LLM provider for a Hugging Face Text Generation
+// Inference server. TGI serves a single model, so model defaults to "tgi".
+func NewTGI(model string, params SamplingParams, baseURL ...string) (*OpenAILike, error) {
+	if model == "" {
+		model = "tgi"
+	}
+	url := "http://localhost:8080/v1"
+	if len(baseURL) > 0 && baseURL[0] != "" {
+		url = baseURL[0]
+	}
+	return NewOpenAILike(OpenAILikeConfig{
+		BaseURL:   url,
+		Model:     model,
+		ExtraBody: params.extraBody(),
+	})
+}
+
 // NewAzureOpenAI creates a new LLM provider for Azure OpenAI Service
 func NewAzureOpenAI(cfg AzureOpenAIConfig) (*OpenAILike, error) {
 	if cfg.Endpoint == "" {
@@ -183,15 +408,31 @@ func NewAzureOpenAI(cfg AzureOpenAIConfig) (*OpenAILike, error) {
 		apiVersion = "2024-02-15-preview"
 	}
 
-	// Azure OpenAI uses a different URL structure
-	baseURL := fmt.Sprintf("%s/openai/deployments/%s", cfg.Endpoint, cfg.DeploymentName)
+	// Azure OpenAI addresses deployments by name and versions the API with
+	// a query parameter on every request
+	base, err := url.Parse(cfg.Endpoint)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, errors.InvalidConfig("azure endpoint", "must be an absolute URL such as https://my-resource.openai.azure.com").WithContext("endpoint", cfg.Endpoint)
+	}
+	base = base.JoinPath("openai", "deployments", cfg.DeploymentName)
+	query := base.Query()
+	query.Set("api-version", apiVersion)
+	base.RawQuery = query.Encode()
 
 	return NewOpenAILike(OpenAILikeConfig{
-		BaseURL:     baseURL,
-		APIKey:      cfg.APIKey,
-		Model:       cfg.DeploymentName,
-		Temperature: cfg.Temperature,
-		MaxTokens:   cfg.MaxTokens,
+		BaseURL:          base.String(),
+		APIKey:           cfg.APIKey,
+		Model:            cfg.DeploymentName,
+		Temperature:      cfg.Temperature,
+		TemperatureSet:   cfg.TemperatureSet,
+		MaxTokens:        cfg.MaxTokens,
+		TopP:             cfg.TopP,
+		ExtraBody:        cfg.ExtraBody,
+		MaxPromptTokens:  cfg.MaxPromptTokens,
+		Timeout:          cfg.Timeout,
+		Logger:           cfg.Logger,
+		MaxResponseBytes: cfg.MaxResponseBytes,
+		Client:           cfg.Client,
 		Headers: map[string]string{
 			"api-key": cfg.APIKey,
 		},
@@ -208,10 +449,31 @@ type AzureOpenAIConfig struct {
 	DeploymentName string
 	// APIVersion is the API version (default: "2024-02-15-preview")
 	APIVersion string
-	// Temperature for generation
+	// Temperature for generation (0 = provider default unless TemperatureSet)
 	Temperature float32
+	// TemperatureSet sends Temperature even when it is 0
+	TemperatureSet bool
 	// MaxTokens limits the response length
 	MaxTokens int
+	// TopP enables nucleus sampling
+	TopP float32
+	// ExtraBody holds additional fields merged into the request payload
+	ExtraBody map[string]interface{}
+	// MaxPromptTokens caps the estimated prompt size checked before each
+	// request (negative = no check). Deployment names rarely identify the
+	// model, so set it to enable the check.
+	MaxPromptTokens int
+	// Timeout bounds each request, or the wait for each chunk of a stream
+	// (0 = DefaultCallTimeout, negative = none)
+	Timeout time.Duration
+	// MaxResponseBytes caps the decoded size of each response body
+	// (0 = DefaultMaxResponseBytes, negative = no cap)
+	MaxResponseBytes int64
+	// Logger receives a structured record of every request (nil = none)
+	Logger Logger
+	// Client identifies the application in request headers (default: the
+	// identity set with SetClientIdentity)
+	Client *ClientIdentity
 }
 
 // NewGroq creates a new LLM provider for Groq
@@ -0,0 +1,94 @@
+package llm
+
+import (
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// OpenRouterRouting holds OpenRouter provider routing preferences, sent as
+// the "provider" object of the request
+type OpenRouterRouting struct {
+	// Order lists provider names to try in order (e.g. "Anthropic", "Together")
+	Order []string `json:"order,omitempty"`
+	// AllowFallbacks permits providers outside Order when they fail (default: true)
+	AllowFallbacks *bool `json:"allow_fallbacks,omitempty"`
+	// Ignore lists providers that must not serve the request
+	Ignore []string `json:"ignore,omitempty"`
+	// MaxPrice caps the price in USD per million tokens
+	MaxPrice *OpenRouterMaxPrice `json:"max_price,omitempty"`
+}
+
+// OpenRouterMaxPrice caps the price a request may be routed at
+type OpenRouterMaxPrice struct {
+	Prompt     float64 `json:"prompt,omitempty"`
+	Completion float64 `json:"completion,omitempty"`
+}
+
+// OpenRouterConfig represents configuration for OpenRouter
+type OpenRouterConfig struct {
+	// APIKey is the OpenRouter API key
+	APIKey string
+	// Model name (default: "openai/gpt-4o-mini")
+	Model string
+	// FallbackModels are tried in order when Model is unavailable
+	FallbackModels []string
+	// Routing sets provider routing preferences
+	Routing *OpenRouterRouting
+	// Referer identifies your app (HTTP-Referer header)
+	Referer string
+	// Title is your app's display name on openrouter.ai (X-Title header)
+	Title string
+	// Temperature for generation (0 = provider default unless TemperatureSet)
+	Temperature float32
+	// TemperatureSet sends Temperature even when it is 0
+	TemperatureSet bool
+	// MaxTokens limits the response length
+	MaxTokens int
+	// TopP enables nucleus sampling
+	TopP float32
+	// SystemPrompt is an optional system message
+	SystemPrompt string
+	// Timeout bounds each request, or the wait for each chunk of a stream
+	// (0 = DefaultCallTimeout, negative = none)
+	Timeout time.Duration
+	// Logger receives a structured record of every request (nil = none)
+	Logger Logger
+}
+
+// NewOpenRouterWithConfig creates an OpenRouter provider with routing
+// preferences and app attribution headers
+func NewOpenRouterWithConfig(cfg OpenRouterConfig) (*OpenAILike, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.RequiredField("api key")
+	}
+
+	model := cfg.Model
+	if model == "" {
+		model = "openai/gpt-4o-mini"
+	}
+
+	headers := make(map[string]string)
+	if cfg.Referer != "" {
+		headers["HTTP-Referer"] = cfg.Referer
+	}
+	if cfg.Title != "" {
+		headers["X-Title"] = cfg.Title
+	}
+
+	return NewOpenAILike(OpenAILikeConfig{
+		BaseURL:        "https://openrouter.ai/api/v1",
+		APIKey:         cfg.APIKey,
+		Model:          model,
+		Temperature:    cfg.Temperature,
+		TemperatureSet: cfg.TemperatureSet,
+		MaxTokens:      cfg.MaxTokens,
+		TopP:           cfg.TopP,
+		Headers:        headers,
+		SystemPrompt:   cfg.SystemPrompt,
+		Routing:        cfg.Routing,
+		FallbackModels: cfg.FallbackModels,
+		Timeout:        cfg.Timeout,
+		Logger:         cfg.Logger,
+	})
+}
@@ -0,0 +1,86 @@
+package llm
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SharedPrefix is a prompt prefix shared by concurrent requests, such as an
+// agent's description or documents injected into several tasks. Providers
+// with explicit prompt caching mark it as cacheable, and the first request
+// sharing it runs alone, so that the others read the cache it writes rather
+// than paying for the prefix again.
+type SharedPrefix struct {
+	Text string
+
+	claimed atomic.Bool
+	once    sync.Once
+	ready   chan struct{}
+}
+
+// NewSharedPrefix creates a shared prefix
+func NewSharedPrefix(text string) *SharedPrefix {
+	return &SharedPrefix{Text: text, ready: make(chan struct{})}
+}
+
+// await makes the first caller the leader and holds the others until the
+// leader's request has finished, successfully or not
+func (p *SharedPrefix) await(ctx context.Context) (release func(), err error) {
+	if p.claimed.CompareAndSwap(false, true) {
+		return func() { p.once.Do(func() { close(p.ready) }) }, nil
+	}
+	select {
+	case <-p.ready:
+		return func() {}, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// prefixKey is the context key for the SharedPrefix
+type prefixKey struct{}
+
+// heldPrefixKey is the context key marking the SharedPrefix the request
+// already waited for, so that nested calls through wrappers such as
+// tracing.LLM or quota.LLM don't wait for the request holding them up
+type heldPrefixKey struct{}
+
+// NewPrefixContext returns a context whose requests share the prefix p
+func NewPrefixContext(ctx context.Context, p *SharedPrefix) context.Context {
+	return context.WithValue(ctx, prefixKey{}, p)
+}
+
+// PrefixFromContext returns the SharedPrefix carried by ctx, or nil
+func PrefixFromContext(ctx context.Context) *SharedPrefix {
+	p, _ := ctx.Value(prefixKey{}).(*SharedPrefix)
+	return p
+}
+
+// claimPrefix waits for the first request sharing the prefix of ctx when
+// prompt starts with it. The returned context marks the prefix as waited
+// for; pass it to the provider so nested helpers don't wait again. release
+// must be called once the request has finished.
+func claimPrefix(ctx context.Context, prompt string) (context.Context, func(), error) {
+	if cachePrefix(ctx, prompt) == "" {
+		return ctx, func() {}, nil
+	}
+	p := PrefixFromContext(ctx)
+	if held, _ := ctx.Value(heldPrefixKey{}).(*SharedPrefix); held == p {
+		return ctx, func() {}, nil
+	}
+	release, err := p.await(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	return context.WithValue(ctx, heldPrefixKey{}, p), release, nil
+}
+
+// cachePrefix returns the shared prefix of ctx when prompt starts with it
+func cachePrefix(ctx context.Context, prompt string) string {
+	if p := PrefixFromContext(ctx); p != nil && p.Text != "" && strings.HasPrefix(prompt, p.Text) {
+		return p.Text
+	}
+	return ""
+}
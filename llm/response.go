@@ -0,0 +1,125 @@
+package llm
+
+import "context"
+
+// Finish reasons, normalized across providers
+const (
+	FinishStop          = "stop"           // Natural end or stop sequence
+	FinishLength        = "length"         // Hit the max token limit
+	FinishContentFilter = "content_filter" // Blocked or cut by the provider's filter
+	FinishToolCalls     = "tool_calls"     // Stopped to call a tool
+)
+
+// Response is a generated completion with its metadata
+type Response struct {
+	// Content is the generated text
+	Content string
+	// FinishReason explains why generation stopped; empty if unknown
+	FinishReason string
+	// Logprobs holds per-token log probabilities when requested
+	Logprobs []TokenLogprob
+	// Model is the model that served the request, as reported by the provider
+	Model string
+	// Usage counts the tokens billed for the request, summed over continuations
+	Usage TokenUsage
+	// Continuations is the number of follow-up requests made to complete
+	// a truncated response
+	Continuations int
+}
+
+// TokenUsage counts prompt and completion tokens
+type TokenUsage struct {
+	PromptTokens     int
+	CompletionTokens int
+	// CachedTokens are the prompt tokens served from the provider's prompt
+	// cache; they are included in PromptTokens
+	CachedTokens int
+}
+
+// Total returns the sum of prompt and completion tokens
+func (u TokenUsage) Total() int {
+	return u.PromptTokens + u.CompletionTokens
+}
+
+// Add returns the sum of two usages
+func (u TokenUsage) Add(other TokenUsage) TokenUsage {
+	return TokenUsage{
+		PromptTokens:     u.PromptTokens + other.PromptTokens,
+		CompletionTokens: u.CompletionTokens + other.CompletionTokens,
+		CachedTokens:     u.CachedTokens + other.CachedTokens,
+	}
+}
+
+// Price is the cost of a model in USD per million tokens
+type Price struct {
+	Prompt     float64
+	Completion float64
+	// CachedPrompt is the discounted price of cached prompt tokens; when 0,
+	// they are billed at the Prompt price
+	CachedPrompt float64
+}
+
+// Cost returns the cost of the given usage in USD
+func (p Price) Cost(u TokenUsage) float64 {
+	cached := p.CachedPrompt
+	if cached == 0 {
+		cached = p.Prompt
+	}
+	uncached := u.PromptTokens - u.CachedTokens
+	return (float64(uncached)*p.Prompt + float64(u.CachedTokens)*cached + float64(u.CompletionTokens)*p.Completion) / 1e6
+}
+
+// Truncated reports whether generation stopped at the token limit
+func (r *Response) Truncated() bool {
+	return r.FinishReason == FinishLength
+}
+
+// TokenLogprob is the log probability of a generated token
+type TokenLogprob struct {
+	Token   string  `json:"token"`
+	Logprob float64 `json:"logprob"`
+}
+
+// ResponseLLM extends LLM with access to response metadata
+type ResponseLLM interface {
+	LLM
+	// GenerateResponse sends a prompt and returns the completion with metadata
+	GenerateResponse(ctx context.Context, prompt string) (*Response, error)
+}
+
+// GenerateResponse returns the completion with metadata when the provider
+// supports it, falling back to Generate with an unknown finish reason. A
+// prompt starting with the SharedPrefix of ctx waits for the first request
+// sharing the prefix to finish; calls nested in wrappers wait only once.
+func GenerateResponse(ctx context.Context, l LLM, prompt string) (*Response, error) {
+	ctx, release, err := claimPrefix(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if rl, ok := l.(ResponseLLM); ok {
+		return rl.GenerateResponse(ctx, prompt)
+	}
+	content, err := l.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	return &Response{Content: content}, nil
+}
+
+// normalizeFinishReason maps provider-specific stop reasons to the
+// Finish constants
+func normalizeFinishReason(reason string) string {
+	switch reason {
+	case "end_turn", "stop_sequence", "eos", "eos_token":
+		return FinishStop
+	case "max_tokens":
+		return FinishLength
+	case "refusal":
+		return FinishContentFilter
+	case "tool_use", "function_call":
+		return FinishToolCalls
+	default:
+		return reason
+	}
+}
@@ -0,0 +1,113 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SimulatedModel is the model name reported by the Simulator
+const SimulatedModel = "simulated"
+
+// SimulatedResponse is a canned answer for prompts containing Match
+type SimulatedResponse struct {
+	Match   string
+	Content string
+}
+
+// SimulatorConfig configures the Simulator
+type SimulatorConfig struct {
+	// Responses are canned answers; the first whose Match occurs in the
+	// prompt is returned
+	Responses []SimulatedResponse
+	// Echo appends the prompt to boilerplate responses
+	Echo bool
+	// Latency delays each response, to exercise timeouts and concurrency
+	Latency time.Duration
+	// Tokenizer counts the reported token usage, so cost estimates match the
+	// model the run is rehearsed for (default: character estimate)
+	Tokenizer Tokenizer
+}
+
+// Simulator is a synthetic LLM that answers instantly without calling a
+// provider. It lets orchestration logic, task graphs and output formatting
+// be validated for free before spending tokens.
+type Simulator struct {
+	config SimulatorConfig
+}
+
+// NewSimulator creates a synthetic LLM
+func NewSimulator(cfg SimulatorConfig) *Simulator {
+	if cfg.Tokenizer == nil {
+		cfg.Tokenizer = CharTokenizer{}
+	}
+	return &Simulator{config: cfg}
+}
+
+// Generate returns the simulated response to prompt
+func (s *Simulator) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := s.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// GenerateResponse returns the simulated response with estimated token
+// usage, so cost and usage reports can be exercised as well
+func (s *Simulator) GenerateResponse(ctx context.Context, prompt string) (*Response, error) {
+	if s.config.Latency > 0 {
+		start := time.Now()
+		select {
+		case <-time.After(s.config.Latency):
+		case <-ctx.Done():
+			return nil, callError(ctx, "simulate LLM call", start, ctx.Err())
+		}
+	}
+
+	content := s.respond(prompt)
+	return &Response{
+		Content:      content,
+		FinishReason: FinishStop,
+		Model:        SimulatedModel,
+		Usage: TokenUsage{
+			PromptTokens:     s.config.Tokenizer.CountTokens(prompt),
+			CompletionTokens: s.config.Tokenizer.CountTokens(content),
+		},
+	}, nil
+}
+
+// Chat returns the simulated reply to a conversation
+func (s *Simulator) Chat(ctx context.Context, messages []Message) (Message, error) {
+	return chatReply(s.ChatResponse(ctx, messages))
+}
+
+// ChatResponse returns the simulated response to the conversation
+// flattened into one prompt, so canned responses match any message
+func (s *Simulator) ChatResponse(ctx context.Context, messages []Message) (*Response, error) {
+	if err := checkMessages(messages); err != nil {
+		return nil, err
+	}
+	return s.GenerateResponse(ctx, FlattenMessages(messages))
+}
+
+// respond picks a canned response or builds a boilerplate one
+func (s *Simulator) respond(prompt string) string {
+	for _, r := range s.config.Responses {
+		if strings.Contains(prompt, r.Match) {
+			return r.Content
+		}
+	}
+
+	content := fmt.Sprintf("[simulated] Response to a %d-token prompt.", s.config.Tokenizer.CountTokens(prompt))
+	if s.config.Echo {
+		content += "\n\nPrompt:\n" + prompt
+	}
+	return content
+}
+
+// Ping always succeeds
+func (s *Simulator) Ping(ctx context.Context) error {
+	return nil
+}
@@ -0,0 +1,281 @@
+package llm
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// StreamingLLM extends LLM with incremental output, so long responses can
+// be rendered token by token instead of after the whole completion
+type StreamingLLM interface {
+	LLM
+	// GenerateStream sends a prompt and calls fn with each chunk of text as
+	// it arrives. It returns the complete response once the stream ends; an
+	// error returned by fn stops the stream and is returned unchanged.
+	GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*Response, error)
+}
+
+// GenerateStream streams the completion when the provider supports it,
+// falling back to GenerateResponse and a single chunk holding the whole
+// response
+func GenerateStream(ctx context.Context, l LLM, prompt string, fn func(chunk string) error) (*Response, error) {
+	ctx, release, err := claimPrefix(ctx, prompt)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	if sl, ok := l.(StreamingLLM); ok {
+		return sl.GenerateStream(ctx, prompt, fn)
+	}
+	resp, err := GenerateResponse(ctx, l, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if resp.Content != "" {
+		if err := fn(resp.Content); err != nil {
+			return nil, err
+		}
+	}
+	return resp, nil
+}
+
+// streamParser decodes a streamed response body, passing text chunks to
+// fn, and returns the assembled response
+type streamParser func(r io.Reader, fn func(chunk string) error) (*Response, error)
+
+// readStream decodes a streamed response with parse. Errors returned by fn
+// are returned unchanged; read failures are classified like those of
+// buffered responses.
+func readStream(ctx context.Context, operation string, start time.Time, resp *http.Response, limit int64, parse streamParser, fn func(chunk string) error) (*Response, error) {
+	r, err := responseReader(resp, limit)
+	if err != nil {
+		return nil, readError(ctx, operation, start, err)
+	}
+
+	var fnErr error
+	out, err := parse(r, func(chunk string) error {
+		if chunk == "" {
+			return nil
+		}
+		fnErr = fn(chunk)
+		return fnErr
+	})
+	if fnErr != nil {
+		return nil, fnErr
+	}
+	if err != nil {
+		return nil, readError(ctx, operation, start, gzipError(err))
+	}
+	return out, nil
+}
+
+// truncatedStream reports a stream that ended without its end marker, as
+// when the server or a proxy drops the connection mid-response. It is
+// retryable, so the truncated content is never taken for a completion.
+func truncatedStream(marker string) *errors.Error {
+	return errors.Wrap(errors.ErrNetworkUnavail, "stream ended before "+marker, io.ErrUnexpectedEOF).
+		WithRetryable(true).
+		WithTemporary(true)
+}
+
+// readEvents parses a server-sent event stream, calling fn with the name
+// and data of each event. It stops at the end of the stream or when fn
+// returns an error, which is io.EOF to end the stream early.
+func readEvents(r io.Reader, fn func(event, data string) error) error {
+	reader := bufio.NewReader(r)
+	var event string
+	var data strings.Builder
+	hasData := false
+
+	dispatch := func() error {
+		if !hasData {
+			event = ""
+			return nil
+		}
+		err := fn(event, data.String())
+		event, hasData = "", false
+		data.Reset()
+		return err
+	}
+
+	for {
+		line, readErr := reader.ReadString('\n')
+		if readErr != nil && readErr != io.EOF {
+			return readErr
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case line == "":
+			if err := dispatch(); err != nil {
+				if err == io.EOF {
+					return nil
+				}
+				return err
+			}
+		case strings.HasPrefix(line, ":"):
+			// Comment, e.g. a keep-alive
+		default:
+			field, value, _ := strings.Cut(line, ":")
+			value = strings.TrimPrefix(value, " ")
+			switch field {
+			case "event":
+				event = value
+			case "data":
+				if hasData {
+					data.WriteByte('\n')
+				}
+				data.WriteString(value)
+				hasData = true
+			}
+		}
+
+		if readErr == io.EOF {
+			if err := dispatch(); err != nil && err != io.EOF {
+				return err
+			}
+			return nil
+		}
+	}
+}
+
+// openAIStreamChunk is a chunk of a streamed OpenAI chat completion
+type openAIStreamChunk struct {
+	Model   string `json:"model"`
+	Choices []struct {
+		Delta struct {
+			Content string `json:"content"`
+		} `json:"delta"`
+		FinishReason string `json:"finish_reason"`
+		Logprobs     *struct {
+			Content []TokenLogprob `json:"content"`
+		} `json:"logprobs,omitempty"`
+	} `json:"choices"`
+	Usage *openAIUsage `json:"usage,omitempty"`
+	Error *struct {
+		Message string `json:"message"`
+		Type    string `json:"type"`
+		Code    string `json:"code"`
+	} `json:"error,omitempty"`
+}
+
+// parseOpenAIStream decodes a streamed OpenAI-compatible chat completion
+func parseOpenAIStream(r io.Reader, fn func(chunk string) error) (*Response, error) {
+	resp := &Response{}
+	var content strings.Builder
+	done := false
+	err := readEvents(r, func(_, data string) error {
+		if data == "[DONE]" {
+			done = true
+			return io.EOF
+		}
+		var chunk openAIStreamChunk
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return errors.Wrap(errors.ErrAPIResponse, "failed to decode stream chunk", err).WithContext("chunk_length", len(data))
+		}
+		if chunk.Error != nil {
+			return errors.APIResponseError(chunk.Error.Message).WithContext("type", chunk.Error.Type).WithContext("code", chunk.Error.Code)
+		}
+		if chunk.Model != "" {
+			resp.Model = chunk.Model
+		}
+		if chunk.Usage != nil {
+			resp.Usage = chunk.Usage.tokenUsage()
+		}
+		if len(chunk.Choices) == 0 {
+			return nil
+		}
+		choice := chunk.Choices[0]
+		if choice.FinishReason != "" {
+			resp.FinishReason = normalizeFinishReason(choice.FinishReason)
+		}
+		if choice.Logprobs != nil {
+			resp.Logprobs = append(resp.Logprobs, choice.Logprobs.Content...)
+		}
+		content.WriteString(choice.Delta.Content)
+		return fn(choice.Delta.Content)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !done {
+		return nil, truncatedStream("[DONE]")
+	}
+	resp.Content = content.String()
+	return resp, nil
+}
+
+// anthropicStreamEvent is an event of a streamed Anthropic message; the
+// fields used depend on its type
+type anthropicStreamEvent struct {
+	Type    string             `json:"type"`
+	Message *AnthropicResponse `json:"message,omitempty"` // message_start
+	Delta   struct {
+		Type       string `json:"type"`
+		Text       string `json:"text"`
+		StopReason string `json:"stop_reason"`
+	} `json:"delta"` // content_block_delta, message_delta
+	Usage *Usage `json:"usage,omitempty"` // message_delta
+	Error *struct {
+		Type    string `json:"type"`
+		Message string `json:"message"`
+	} `json:"error,omitempty"`
+}
+
+// parseAnthropicStream decodes a streamed Anthropic message
+func parseAnthropicStream(r io.Reader, fn func(chunk string) error) (*Response, error) {
+	var usage Usage
+	resp := &Response{}
+	var content strings.Builder
+	done := false
+	err := readEvents(r, func(_, data string) error {
+		var event anthropicStreamEvent
+		if err := json.Unmarshal([]byte(data), &event); err != nil {
+			return errors.Wrap(errors.ErrAPIResponse, "failed to decode stream event", err).WithContext("event_length", len(data))
+		}
+		switch event.Type {
+		case "message_start":
+			if event.Message != nil {
+				resp.Model = event.Message.Model
+				usage = event.Message.Usage
+			}
+		case "content_block_delta":
+			if event.Delta.Type == "text_delta" {
+				content.WriteString(event.Delta.Text)
+				return fn(event.Delta.Text)
+			}
+		case "message_delta":
+			if event.Delta.StopReason != "" {
+				resp.FinishReason = normalizeFinishReason(event.Delta.StopReason)
+			}
+			if event.Usage != nil {
+				usage.OutputTokens = event.Usage.OutputTokens
+			}
+		case "message_stop":
+			done = true
+			return io.EOF
+		case "error":
+			if event.Error != nil {
+				return errors.APIf("Anthropic API error: %s", event.Error.Message).WithContext("type", event.Error.Type)
+			}
+			return errors.API("Anthropic API error")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !done {
+		return nil, truncatedStream("message_stop")
+	}
+	resp.Content = content.String()
+	resp.Usage = usage.tokenUsage()
+	return resp, nil
+}
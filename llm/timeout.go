@@ -0,0 +1,111 @@
+package llm
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// DefaultCallTimeout bounds a single provider request when the config does
+// not set a timeout
+const DefaultCallTimeout = 120 * time.Second
+
+// withCallTimeout layers the per-call timeout onto ctx. A deadline already
+// set by the caller still applies when it is earlier. A zero timeout uses
+// DefaultCallTimeout; a negative one disables the per-call bound.
+func withCallTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+	if timeout < 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
+
+// errStreamIdle cancels a stream that sent nothing for the per-call timeout
+var errStreamIdle = fmt.Errorf("stream idle: %w", context.DeadlineExceeded)
+
+// streamTimeout bounds a streamed request by the time spent waiting on the
+// server rather than by its total duration: until the response arrives, and
+// then during each read of its body. Long answers that keep streaming are
+// not cut off.
+type streamTimeout struct {
+	timeout time.Duration
+	timer   *time.Timer
+}
+
+// withStreamTimeout layers the per-call timeout onto ctx as an idle timeout
+// for a stream, with the same zero and negative semantics as
+// withCallTimeout. A deadline already set by the caller still bounds the
+// whole stream.
+func withStreamTimeout(ctx context.Context, timeout time.Duration) (context.Context, *streamTimeout, context.CancelFunc) {
+	if timeout == 0 {
+		timeout = DefaultCallTimeout
+	}
+	ctx, cancel := context.WithCancelCause(ctx)
+	st := &streamTimeout{timeout: timeout}
+	if timeout > 0 {
+		st.timer = time.AfterFunc(timeout, func() { cancel(errStreamIdle) })
+	}
+	return ctx, st, func() {
+		if st.timer != nil {
+			st.timer.Stop()
+		}
+		cancel(context.Canceled)
+	}
+}
+
+// watch returns body with the idle timer running only while a read waits
+// for the server, so time spent handling chunks does not count against it
+func (st *streamTimeout) watch(body io.ReadCloser) io.ReadCloser {
+	if st.timer == nil {
+		return body
+	}
+	st.timer.Stop()
+	return &watchedBody{ReadCloser: body, st: st}
+}
+
+type watchedBody struct {
+	io.ReadCloser
+	st *streamTimeout
+}
+
+func (b *watchedBody) Read(p []byte) (int, error) {
+	b.st.timer.Reset(b.st.timeout)
+	n, err := b.ReadCloser.Read(p)
+	b.st.timer.Stop()
+	return n, err
+}
+
+// timedOut reports whether ctx ended at a deadline, the idle timeout of a
+// stream included
+func timedOut(ctx context.Context) bool {
+	return stderrors.Is(context.Cause(ctx), context.DeadlineExceeded)
+}
+
+// callError classifies a failed request. Deadline exceedances, whether from
+// the per-call timeout or the caller's deadline, become ErrTimeout naming the
+// operation; other failures are retryable API call errors.
+func callError(ctx context.Context, operation string, start time.Time, err error) *errors.Error {
+	if timedOut(ctx) {
+		return errors.Timeout(operation, time.Since(start).Round(time.Millisecond)).WithContext("cause", err.Error())
+	}
+	return errors.APICallError(operation, err)
+}
+
+// readError classifies a failure while reading a response body. Errors
+// readBody already classified, such as an oversized body, pass through.
+func readError(ctx context.Context, operation string, start time.Time, err error) *errors.Error {
+	if e, ok := err.(*errors.Error); ok {
+		return e
+	}
+	if timedOut(ctx) {
+		return errors.Timeout(operation, time.Since(start).Round(time.Millisecond)).WithContext("cause", err.Error())
+	}
+	return errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+}
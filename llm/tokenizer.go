@@ -0,0 +1,337 @@
+package llm
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Tokenizer counts the tokens of text for a model family. Implementations
+// must be safe for concurrent use.
+type Tokenizer interface {
+	CountTokens(text string) int
+}
+
+// TokenizerFunc adapts a function to the Tokenizer interface
+type TokenizerFunc func(text string) int
+
+// CountTokens calls f
+func (f TokenizerFunc) CountTokens(text string) int {
+	return f(text)
+}
+
+// CharTokenizer estimates tokens from the character count. It is the
+// fallback for models without a known tokenizer.
+type CharTokenizer struct {
+	CharsPerToken int // 0 = 4, the average for English text
+}
+
+// CountTokens returns the characters of text divided by CharsPerToken,
+// rounded up
+func (c CharTokenizer) CountTokens(text string) int {
+	per := c.CharsPerToken
+	if per <= 0 {
+		per = charsPerToken
+	}
+	return (utf8.RuneCountInString(text) + per - 1) / per
+}
+
+// Tiktoken is an exact byte-pair encoder for OpenAI models, loaded from a
+// tiktoken rank file such as cl100k_base.tiktoken or o200k_base.tiktoken
+type Tiktoken struct {
+	ranks map[string]int
+}
+
+// NewTiktoken reads a tiktoken rank file: one base64-encoded token and its
+// rank per line
+func NewTiktoken(r io.Reader) (*Tiktoken, error) {
+	ranks := make(map[string]int)
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		encoded, rank, ok := strings.Cut(text, " ")
+		token, err := base64.StdEncoding.DecodeString(encoded)
+		if !ok || err != nil {
+			return nil, errors.New(errors.ErrInvalidFormat, "invalid tiktoken rank").WithContext("line", line)
+		}
+		n, err := strconv.Atoi(rank)
+		if err != nil {
+			return nil, errors.New(errors.ErrInvalidFormat, "invalid tiktoken rank").WithContext("line", line)
+		}
+		ranks[string(token)] = n
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read tiktoken ranks", err)
+	}
+	if len(ranks) == 0 {
+		return nil, errors.New(errors.ErrInvalidFormat, "tiktoken rank file is empty")
+	}
+	return &Tiktoken{ranks: ranks}, nil
+}
+
+// LoadTiktoken reads a tiktoken rank file from disk
+func LoadTiktoken(path string) (*Tiktoken, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to open tiktoken ranks", err).WithContext("path", path)
+	}
+	defer f.Close()
+	t, err := NewTiktoken(f)
+	if err != nil {
+		if e, ok := err.(*errors.Error); ok {
+			return nil, e.WithContext("path", path)
+		}
+		return nil, err
+	}
+	return t, nil
+}
+
+// CountTokens returns the number of tokens text encodes to
+func (t *Tiktoken) CountTokens(text string) int {
+	n := 0
+	for _, piece := range pretokenize(text) {
+		n += t.countPiece(piece)
+	}
+	return n
+}
+
+// countPiece byte-pair encodes one pre-tokenized piece, repeatedly merging
+// the adjacent pair with the lowest rank
+func (t *Tiktoken) countPiece(piece string) int {
+	if _, ok := t.ranks[piece]; ok {
+		return 1
+	}
+	parts := make([]string, len(piece))
+	for i := 0; i < len(piece); i++ {
+		parts[i] = piece[i : i+1]
+	}
+	for len(parts) > 1 {
+		best, bestRank := -1, 0
+		for i := 0; i < len(parts)-1; i++ {
+			if rank, ok := t.ranks[parts[i]+parts[i+1]]; ok && (best < 0 || rank < bestRank) {
+				best, bestRank = i, rank
+			}
+		}
+		if best < 0 {
+			break
+		}
+		parts[best] += parts[best+1]
+		parts = append(parts[:best+1], parts[best+2:]...)
+	}
+	return len(parts)
+}
+
+// TiktokenEstimate approximates OpenAI's tokenizers without their rank
+// files. Text is split like tiktoken does; short words count as one token
+// and longer pieces by their length.
+var TiktokenEstimate Tokenizer = tiktokenEstimate{}
+
+// tiktokenEstimate implements TiktokenEstimate
+type tiktokenEstimate struct{}
+
+// CountTokens estimates the tokens of text
+func (tiktokenEstimate) CountTokens(text string) int {
+	n := 0
+	for _, piece := range pretokenize(text) {
+		word := strings.TrimLeftFunc(piece, unicode.IsSpace)
+		runes := utf8.RuneCountInString(word)
+		switch {
+		case runes == 0:
+			n++ // Whitespace
+		case isIdeographic(word):
+			n += runes
+		case runes <= 8:
+			n++
+		default:
+			n += (runes + 3) / 4
+		}
+	}
+	return n
+}
+
+// SentencePieceEstimate approximates the SentencePiece tokenizers of Llama,
+// Mistral and Gemma models, whose smaller vocabularies split words into more
+// pieces and digits one by one
+var SentencePieceEstimate Tokenizer = sentencePieceEstimate{}
+
+// sentencePieceEstimate implements SentencePieceEstimate
+type sentencePieceEstimate struct{}
+
+// CountTokens estimates the tokens of text
+func (sentencePieceEstimate) CountTokens(text string) int {
+	n := 0
+	for _, field := range strings.Fields(text) {
+		letters := 0
+		flush := func() {
+			switch {
+			case letters == 0:
+			case letters <= 5:
+				n++
+			default:
+				n += (letters + 3) / 4
+			}
+			letters = 0
+		}
+		for _, r := range field {
+			switch {
+			case isIdeographicRune(r):
+				flush()
+				n++
+			case unicode.IsLetter(r):
+				letters++
+			default:
+				flush()
+				n++ // Digits and punctuation are single pieces
+			}
+		}
+		flush()
+	}
+	return n + strings.Count(text, "\n")
+}
+
+// pretokenize splits text the way tiktoken's cl100k_base pattern does:
+// contractions, words with one leading non-letter, runs of at most three
+// digits, punctuation runs, and whitespace, where the last space before a
+// word stays with the word
+func pretokenize(text string) []string {
+	var pieces []string
+	runes := []rune(text)
+	for i := 0; i < len(runes); {
+		start := i
+		r := runes[i]
+		switch {
+		case r == '\'' && contractionLength(runes[i+1:]) > 0:
+			i += 1 + contractionLength(runes[i+1:])
+		case unicode.IsLetter(r) ||
+			(r != '\r' && r != '\n' && !unicode.IsNumber(r) && i+1 < len(runes) && unicode.IsLetter(runes[i+1])):
+			i++
+			for i < len(runes) && unicode.IsLetter(runes[i]) {
+				i++
+			}
+		case unicode.IsNumber(r):
+			for i < len(runes) && i-start < 3 && unicode.IsNumber(runes[i]) {
+				i++
+			}
+		case isPunct(r) || (r == ' ' && i+1 < len(runes) && isPunct(runes[i+1])):
+			i++
+			for i < len(runes) && isPunct(runes[i]) {
+				i++
+			}
+			for i < len(runes) && (runes[i] == '\r' || runes[i] == '\n') {
+				i++
+			}
+		default: // Whitespace
+			end := i
+			for end < len(runes) && unicode.IsSpace(runes[end]) {
+				end++
+			}
+			// Up to the last newline, or leave the final space to the next word
+			lastNewline := -1
+			for k := i; k < end; k++ {
+				if runes[k] == '\r' || runes[k] == '\n' {
+					lastNewline = k
+				}
+			}
+			switch {
+			case lastNewline >= 0:
+				i = lastNewline + 1
+			case end < len(runes) && end-i > 1:
+				i = end - 1
+			default:
+				i = end
+			}
+		}
+		pieces = append(pieces, string(runes[start:i]))
+	}
+	return pieces
+}
+
+// contractionLength returns the length of an English contraction suffix
+// ('s, 't, 're, 've, 'm, 'll, 'd) at the start of runes, or 0
+func contractionLength(runes []rune) int {
+	for _, suffix := range []string{"re", "ve", "ll", "s", "t", "m", "d"} {
+		if len(runes) >= len(suffix) && strings.EqualFold(string(runes[:len(suffix)]), suffix) {
+			return len(suffix)
+		}
+	}
+	return 0
+}
+
+// isPunct reports whether r is neither whitespace, a letter nor a number
+func isPunct(r rune) bool {
+	return !unicode.IsSpace(r) && !unicode.IsLetter(r) && !unicode.IsNumber(r)
+}
+
+// isIdeographic reports whether s is written in a script without spaces
+// between words, whose characters are tokenized one by one
+func isIdeographic(s string) bool {
+	r, _ := utf8.DecodeRuneInString(s)
+	return isIdeographicRune(r)
+}
+
+// isIdeographicRune reports whether r is a Chinese, Japanese or Korean
+// character
+func isIdeographicRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul)
+}
+
+// tokenizers selects a tokenizer by model name prefix
+var (
+	tokenizersMu sync.RWMutex
+	tokenizers   = map[string]Tokenizer{
+		"gpt-":           TiktokenEstimate,
+		"chatgpt":        TiktokenEstimate,
+		"o1":             TiktokenEstimate,
+		"o3":             TiktokenEstimate,
+		"o4":             TiktokenEstimate,
+		"text-embedding": TiktokenEstimate,
+		"llama":          SentencePieceEstimate,
+		"codellama":      SentencePieceEstimate,
+		"mistral":        SentencePieceEstimate,
+		"mixtral":        SentencePieceEstimate,
+		"gemma":          SentencePieceEstimate,
+	}
+)
+
+// SetTokenizer selects the tokenizer for models whose name starts with
+// prefix, for custom models or to replace an estimate with an exact
+// tokenizer. A nil tokenizer removes the selection.
+func SetTokenizer(prefix string, t Tokenizer) {
+	tokenizersMu.Lock()
+	defer tokenizersMu.Unlock()
+	prefix = strings.ToLower(prefix)
+	if t == nil {
+		delete(tokenizers, prefix)
+		return
+	}
+	tokenizers[prefix] = t
+}
+
+// TokenizerFor returns the tokenizer of the longest matching model name
+// prefix, or a CharTokenizer for unknown models
+func TokenizerFor(model string) Tokenizer {
+	tokenizersMu.RLock()
+	defer tokenizersMu.RUnlock()
+	if t, ok := lookupModel(tokenizers, model, nil); ok {
+		return t
+	}
+	return CharTokenizer{}
+}
+
+// CountTokens counts the tokens of text with the model's tokenizer
+func CountTokens(model, text string) int {
+	return TokenizerFor(model).CountTokens(text)
+}
@@ -0,0 +1,142 @@
+package llm
+
+import (
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// maxOutputTokens lists the largest completion each model family accepts,
+// keyed by model name prefix
+var maxOutputTokens = map[string]int{
+	"gpt-3.5-turbo":     4096,
+	"gpt-4":             8192,
+	"gpt-4-0314":        8192,
+	"gpt-4-0613":        8192,
+	"gpt-4-0125":        4096,
+	"gpt-4-1106":        4096,
+	"gpt-4-vision":      4096,
+	"gpt-4-turbo":       4096,
+	"gpt-4o":            16384,
+	"gpt-4o-mini":       16384,
+	"gpt-4.1":           32768,
+	"o1":                100000,
+	"o3":                100000,
+	"o4-mini":           100000,
+	"claude-3-haiku":    4096,
+	"claude-3-opus":     4096,
+	"claude-3-5-haiku":  8192,
+	"claude-3-5-sonnet": 8192,
+	"claude-3-7-sonnet": 64000,
+	"claude-sonnet-4":   64000,
+	"claude-opus-4":     32000,
+	"deepseek-chat":     8192,
+	"deepseek-reasoner": 65536,
+}
+
+// contextWindows lists the total tokens (prompt plus completion) each model
+// family can attend to, keyed by model name prefix
+var contextWindows = map[string]int{
+	"gpt-3.5-turbo":     16385,
+	"gpt-4":             8192,
+	"gpt-4-0314":        8192,
+	"gpt-4-0613":        8192,
+	"gpt-4-0125":        128000,
+	"gpt-4-1106":        128000,
+	"gpt-4-vision":      128000,
+	"gpt-4-32k":         32768,
+	"gpt-4-turbo":       128000,
+	"gpt-4o":            128000,
+	"gpt-4o-mini":       128000,
+	"gpt-4.1":           1047576,
+	"o1":                200000,
+	"o3":                200000,
+	"o4-mini":           200000,
+	"claude-3":          200000,
+	"claude-sonnet-4":   200000,
+	"claude-opus-4":     200000,
+	"deepseek-chat":     65536,
+	"deepseek-reasoner": 65536,
+}
+
+// exactModels are the entries of the limit tables matching only the model
+// of that name. They are not prefixes, since their names start those of
+// later models with other limits, such as gpt-4 and gpt-4-1106-preview.
+var exactModels = map[string]bool{
+	"gpt-4": true,
+}
+
+// MaxOutputTokens returns the largest completion a model accepts, matching
+// the longest known model name prefix. ok is false for unknown models.
+func MaxOutputTokens(model string) (limit int, ok bool) {
+	return lookupModel(maxOutputTokens, model, exactModels)
+}
+
+// ContextWindow returns the total tokens a model can attend to, prompt and
+// completion together, matching the longest known model name prefix. ok is
+// false for unknown models.
+func ContextWindow(model string) (window int, ok bool) {
+	return lookupModel(contextWindows, model, exactModels)
+}
+
+// lookupModel finds the entry for the longest prefix of model in table;
+// entries listed in exact only match their own name. Models without an
+// entry are unknown, and checks relying on their limits are skipped.
+func lookupModel[V any](table map[string]V, model string, exact map[string]bool) (value V, ok bool) {
+	model = strings.ToLower(model)
+	// OpenRouter and some gateways prefix models with their vendor
+	if _, name, found := strings.Cut(model, "/"); found {
+		model = name
+	}
+
+	longest := 0
+	for prefix, v := range table {
+		if exact[prefix] && model != prefix {
+			continue
+		}
+		if strings.HasPrefix(model, prefix) && len(prefix) > longest {
+			longest, value = len(prefix), v
+		}
+	}
+	return value, longest > 0
+}
+
+// checkPromptLength fails early when the size of messages, counted with the
+// model's tokenizer, exceeds the prompt budget, rather than letting the
+// provider reject the request.
+// A positive maxPrompt sets the budget explicitly; zero derives it from the
+// model's context window minus the tokens reserved for the completion, and
+// skips the check for unknown models; a negative maxPrompt disables it.
+func checkPromptLength(model string, messages []Message, maxTokens, maxPrompt int) error {
+	if maxPrompt < 0 {
+		return nil
+	}
+
+	limit, window := maxPrompt, 0
+	if limit == 0 {
+		var ok bool
+		if window, ok = ContextWindow(model); !ok {
+			return nil
+		}
+		limit = window - maxTokens
+	}
+
+	tokenizer := TokenizerFor(model)
+	tokens := 0
+	for _, m := range messages {
+		tokens += tokenizer.CountTokens(m.Content)
+	}
+	if tokens <= limit {
+		return nil
+	}
+
+	err := errors.Newf(errors.ErrOutOfRange, "prompt of ~%d tokens exceeds the limit of %d tokens for %s", tokens, limit, model).
+		WithContext("model", model).
+		WithContext("prompt_tokens", tokens).
+		WithContext("max_prompt_tokens", limit).
+		WithContext("max_tokens", maxTokens)
+	if window > 0 {
+		err = err.WithContext("context_window", window)
+	}
+	return err
+}
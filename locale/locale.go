@@ -0,0 +1,112 @@
+// Package locale carries the output language of a run and the localized
+// headings of its reports.
+package locale
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given output language, e.g.
+// "German" or "de"
+func NewContext(ctx context.Context, language string) context.Context {
+	return context.WithValue(ctx, contextKey{}, language)
+}
+
+// FromContext returns the output language carried by ctx, or "" if none is set
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	language, _ := ctx.Value(contextKey{}).(string)
+	return language
+}
+
+// Instruction returns the prompt sentence asking for output in language, or
+// "" when no language is set
+func Instruction(language string) string {
+	if language == "" {
+		return ""
+	}
+	return fmt.Sprintf("Write your entire response in %s, whatever the language of the task.", Name(language))
+}
+
+// Labels are the headings of a results report
+type Labels struct {
+	Results    string
+	Statistics string
+	Task       string
+	Agent      string
+	Status     string
+	Error      string
+	Result     string
+	NoResult   string
+	Sources    string
+}
+
+// English are the default report labels
+var English = Labels{
+	Results:    "EXECUTION RESULTS",
+	Statistics: "RUN STATISTICS",
+	Task:       "Task",
+	Agent:      "Agent",
+	Status:     "Status",
+	Error:      "Error",
+	Result:     "Result",
+	NoResult:   "no result",
+	Sources:    "Sources",
+}
+
+// languages maps ISO 639-1 codes to language names and report labels
+var languages = map[string]struct {
+	name   string
+	labels Labels
+}{
+	"en": {"English", English},
+	"de": {"German", Labels{"AUSFÜHRUNGSERGEBNISSE", "LAUFSTATISTIK", "Aufgabe", "Agent", "Status", "Fehler", "Ergebnis", "kein Ergebnis", "Quellen"}},
+	"es": {"Spanish", Labels{"RESULTADOS DE LA EJECUCIÓN", "ESTADÍSTICAS DE LA EJECUCIÓN", "Tarea", "Agente", "Estado", "Error", "Resultado", "sin resultado", "Fuentes"}},
+	"fr": {"French", Labels{"RÉSULTATS D'EXÉCUTION", "STATISTIQUES D'EXÉCUTION", "Tâche", "Agent", "Statut", "Erreur", "Résultat", "aucun résultat", "Sources"}},
+	"it": {"Italian", Labels{"RISULTATI DELL'ESECUZIONE", "STATISTICHE DELL'ESECUZIONE", "Attività", "Agente", "Stato", "Errore", "Risultato", "nessun risultato", "Fonti"}},
+	"pt": {"Portuguese", Labels{"RESULTADOS DA EXECUÇÃO", "ESTATÍSTICAS DA EXECUÇÃO", "Tarefa", "Agente", "Estado", "Erro", "Resultado", "sem resultado", "Fontes"}},
+	"ja": {"Japanese", Labels{"実行結果", "実行統計", "タスク", "エージェント", "ステータス", "エラー", "結果", "結果なし", "出典"}},
+	"zh": {"Chinese", Labels{"执行结果", "运行统计", "任务", "智能体", "状态", "错误", "结果", "无结果", "来源"}},
+}
+
+// lookup finds a language by ISO code, optionally with a region such as
+// "pt-BR", or by English name
+func lookup(language string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(language))
+	if code, _, found := strings.Cut(strings.ReplaceAll(key, "_", "-"), "-"); found {
+		key = code
+	}
+	if _, ok := languages[key]; ok {
+		return key, true
+	}
+	for code, l := range languages {
+		if strings.EqualFold(l.name, strings.TrimSpace(language)) {
+			return code, true
+		}
+	}
+	return "", false
+}
+
+// Name returns the English name of a language given by code or name. Unknown
+// languages are returned unchanged, so any language the model knows works.
+func Name(language string) string {
+	if code, ok := lookup(language); ok {
+		return languages[code].name
+	}
+	return language
+}
+
+// LabelsFor returns the report labels for a language, falling back to
+// English for languages without a translation
+func LabelsFor(language string) Labels {
+	if code, ok := lookup(language); ok {
+		return languages[code].labels
+	}
+	return English
+}
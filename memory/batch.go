@@ -0,0 +1,230 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Buffer defaults
+const (
+	DefaultBufferRecords = 64
+	DefaultFlushInterval = time.Second
+	DefaultFlushTimeout  = 10 * time.Second
+)
+
+// BatchStore is implemented by memories that save several records in one
+// operation, e.g. under a single lock or in one database round trip
+type BatchStore interface {
+	Memory
+	// StoreBatch saves records in order. It saves all of them or, on error,
+	// none.
+	StoreBatch(ctx context.Context, records []Record) error
+}
+
+// Flusher is implemented by memories that buffer writes
+type Flusher interface {
+	// Flush writes the buffered records to the underlying store
+	Flush(ctx context.Context) error
+}
+
+// StoreBatch saves records in one operation when m is a BatchStore, falling
+// back to storing them one by one
+func StoreBatch(ctx context.Context, m Memory, records []Record) error {
+	_, err := storeRecords(ctx, m, records)
+	return err
+}
+
+// Flush writes the buffered records of m if it buffers writes; memories that
+// write through need no flushing
+func Flush(ctx context.Context, m Memory) error {
+	if f, ok := m.(Flusher); ok {
+		return f.Flush(ctx)
+	}
+	return nil
+}
+
+// storeRecords saves records and returns how many were saved
+func storeRecords(ctx context.Context, m Memory, records []Record) (int, error) {
+	if len(records) == 0 {
+		return 0, nil
+	}
+	if bs, ok := m.(BatchStore); ok {
+		if err := bs.StoreBatch(ctx, records); err != nil {
+			return 0, err
+		}
+		return len(records), nil
+	}
+	for i, record := range records {
+		if err := m.Store(ctx, record); err != nil {
+			return i, err
+		}
+	}
+	return len(records), nil
+}
+
+// BufferConfig configures a Buffered memory
+type BufferConfig struct {
+	// MaxRecords starts a background flush once this many records are
+	// buffered (0 = DefaultBufferRecords)
+	MaxRecords int
+	// FlushInterval is the longest a record stays buffered before a
+	// background flush writes it (0 = DefaultFlushInterval, negative = only
+	// when the buffer fills or is flushed explicitly)
+	FlushInterval time.Duration
+	// FlushTimeout bounds background flushes (0 = DefaultFlushTimeout)
+	FlushTimeout time.Duration
+	// OnError receives the errors of background flushes (optional). Records
+	// that failed to flush stay buffered for the next attempt.
+	OnError func(err error)
+}
+
+// Buffered is a write-behind buffer in front of another memory. Store only
+// appends to the buffer, so agents recording often don't pay a storage round
+// trip per record; buffered records are written in batches when the buffer
+// fills, after FlushInterval, on Flush, and before Retrieve so reads see
+// every stored record.
+//
+// The orchestrator flushes agents' memories when a run completes and on
+// Shutdown. Records still buffered when the process exits are lost.
+type Buffered struct {
+	next   Memory
+	config BufferConfig
+
+	flushMu sync.Mutex // Serializes flushes so records are written in order
+
+	mu       sync.Mutex
+	pending  []Record
+	timer    *time.Timer
+	flushing bool // Whether a background flush was started for a full buffer
+}
+
+// NewBuffered creates a write-behind buffer in front of next
+func NewBuffered(next Memory, cfg BufferConfig) *Buffered {
+	if cfg.MaxRecords <= 0 {
+		cfg.MaxRecords = DefaultBufferRecords
+	}
+	if cfg.FlushInterval == 0 {
+		cfg.FlushInterval = DefaultFlushInterval
+	}
+	if cfg.FlushTimeout <= 0 {
+		cfg.FlushTimeout = DefaultFlushTimeout
+	}
+	return &Buffered{next: next, config: cfg}
+}
+
+// Store buffers a record. Records without a Timestamp are stamped now, so
+// the delayed write keeps the time the record was made.
+func (b *Buffered) Store(ctx context.Context, record Record) error {
+	return b.StoreBatch(ctx, []Record{record})
+}
+
+// StoreBatch buffers records
+func (b *Buffered) StoreBatch(ctx context.Context, records []Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := time.Now().Unix()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, record := range records {
+		if record.Timestamp == 0 {
+			record.Timestamp = now
+		}
+		b.pending = append(b.pending, record)
+	}
+	if len(b.pending) >= b.config.MaxRecords {
+		if !b.flushing {
+			b.flushing = true
+			go b.flushBackground()
+		}
+	} else if b.timer == nil && len(b.pending) > 0 && b.config.FlushInterval > 0 {
+		b.timer = time.AfterFunc(b.config.FlushInterval, b.flushBackground)
+	}
+	return nil
+}
+
+// Retrieve flushes the buffer, then fetches records from the underlying
+// memory
+func (b *Buffered) Retrieve(ctx context.Context, query string, limit int) ([]Record, error) {
+	if err := b.Flush(ctx); err != nil {
+		return nil, err
+	}
+	return b.next.Retrieve(ctx, query, limit)
+}
+
+// Clear drops the buffered records and clears the underlying memory
+func (b *Buffered) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	b.mu.Lock()
+	b.pending = nil
+	b.stopTimer()
+	b.mu.Unlock()
+	return b.next.Clear(ctx)
+}
+
+// Flush writes the buffered records to the underlying memory. When ctx ends
+// before every record is written, the rest stay buffered and ctx's error is
+// returned.
+func (b *Buffered) Flush(ctx context.Context) error {
+	b.flushMu.Lock()
+	defer b.flushMu.Unlock()
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	b.mu.Lock()
+	records := b.pending
+	b.pending = nil
+	b.stopTimer()
+	b.mu.Unlock()
+
+	n, err := storeRecords(ctx, b.next, records)
+	if err == nil {
+		return nil
+	}
+
+	// Requeue the unwritten records ahead of those buffered meanwhile
+	b.mu.Lock()
+	b.pending = append(records[n:len(records):len(records)], b.pending...)
+	if b.timer == nil && b.config.FlushInterval > 0 {
+		b.timer = time.AfterFunc(b.config.FlushInterval, b.flushBackground)
+	}
+	b.mu.Unlock()
+	return err
+}
+
+// Pending returns the number of buffered records
+func (b *Buffered) Pending() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.pending)
+}
+
+// flushBackground flushes the buffer within FlushTimeout, reporting errors
+// to OnError
+func (b *Buffered) flushBackground() {
+	ctx, cancel := context.WithTimeout(context.Background(), b.config.FlushTimeout)
+	defer cancel()
+	err := b.Flush(ctx)
+
+	b.mu.Lock()
+	b.flushing = false
+	b.mu.Unlock()
+	if err != nil && b.config.OnError != nil {
+		b.config.OnError(err)
+	}
+}
+
+// stopTimer cancels the pending interval flush; b.mu must be held
+func (b *Buffered) stopTimer() {
+	if b.timer != nil {
+		b.timer.Stop()
+		b.timer = nil
+	}
+}
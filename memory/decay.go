@@ -0,0 +1,174 @@
+package memory
+
+import (
+	"context"
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultHalfLife is the time after which an unused record's strength halves
+const DefaultHalfLife = 7 * 24 * time.Hour
+
+// DecayConfig configures a Decaying memory
+type DecayConfig struct {
+	// HalfLife is the time after which the strength of a record that was not
+	// retrieved halves (0 = DefaultHalfLife)
+	HalfLife time.Duration
+	// Reinforcement is the strength a retrieval adds to a record (0 = 1).
+	// New records start with a strength of 1.
+	Reinforcement float64
+	// ForgetBelow drops records whose strength decayed below it
+	// (0 = keep every record)
+	ForgetBelow float64
+	// Now returns the current time (default time.Now)
+	Now func() time.Time
+}
+
+// decayEntry is a stored record with its strength as of its last access
+type decayEntry struct {
+	record   Record
+	strength float64
+	accessed time.Time
+}
+
+// Decaying is an in-memory store ranking records by relevance to the query
+// and by strength. A record's strength decays exponentially while it is not
+// retrieved and grows each time it is, so records that keep proving useful
+// outrank stale ones as memories accumulate across runs.
+type Decaying struct {
+	config DecayConfig
+
+	mu      sync.Mutex
+	entries []*decayEntry
+}
+
+// NewDecaying creates an empty decaying memory
+func NewDecaying(cfg DecayConfig) *Decaying {
+	if cfg.HalfLife <= 0 {
+		cfg.HalfLife = DefaultHalfLife
+	}
+	if cfg.Reinforcement <= 0 {
+		cfg.Reinforcement = 1
+	}
+	if cfg.Now == nil {
+		cfg.Now = time.Now
+	}
+	return &Decaying{config: cfg}
+}
+
+// Store saves a record with a strength of 1. Its decay starts at its
+// Timestamp, or now when unset.
+func (m *Decaying) Store(ctx context.Context, record Record) error {
+	return m.StoreBatch(ctx, []Record{record})
+}
+
+// StoreBatch saves records under a single lock, like Store
+func (m *Decaying) StoreBatch(ctx context.Context, records []Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	now := m.config.Now()
+	entries := make([]*decayEntry, len(records))
+	for i, record := range records {
+		accessed := now
+		if record.Timestamp > 0 {
+			accessed = time.Unix(record.Timestamp, 0)
+		} else {
+			record.Timestamp = now.Unix()
+		}
+		entries[i] = &decayEntry{record: record, strength: 1, accessed: accessed}
+	}
+	m.mu.Lock()
+	m.entries = append(m.entries, entries...)
+	m.mu.Unlock()
+	return nil
+}
+
+// strength returns the entry's strength at now
+func (m *Decaying) strength(e *decayEntry, now time.Time) float64 {
+	elapsed := now.Sub(e.accessed)
+	if elapsed <= 0 {
+		return e.strength
+	}
+	return e.strength * math.Exp2(-float64(elapsed)/float64(m.config.HalfLife))
+}
+
+// Retrieve returns the records with the highest scores, best first. A
+// record's Score is its strength times the fraction of the query's terms it
+// contains; records containing none are skipped. Without a query every
+// record qualifies, ranked by strength. Records returned for a query are
+// reinforced; listing without a query, as session snapshots do, is not a
+// use and reinforces nothing.
+func (m *Decaying) Retrieve(ctx context.Context, query string, limit int) ([]Record, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	terms := queryTerms(query)
+	now := m.config.Now()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	type candidate struct {
+		entry    *decayEntry
+		strength float64
+		record   Record
+	}
+	var candidates []candidate
+	kept := m.entries[:0]
+	for _, e := range m.entries {
+		strength := m.strength(e, now)
+		if strength < m.config.ForgetBelow {
+			continue
+		}
+		kept = append(kept, e)
+
+		record := e.record
+		record.Score = strength
+		if len(terms) > 0 {
+			record.Highlights = Highlight(query, record.Content)
+			if len(record.Highlights) == 0 {
+				continue
+			}
+			record.Score *= float64(len(record.Highlights)) / float64(len(terms))
+		}
+		candidates = append(candidates, candidate{entry: e, strength: strength, record: record})
+	}
+	for i := len(kept); i < len(m.entries); i++ {
+		m.entries[i] = nil
+	}
+	m.entries = kept
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		if candidates[i].record.Score != candidates[j].record.Score {
+			return candidates[i].record.Score > candidates[j].record.Score
+		}
+		return candidates[i].record.Timestamp > candidates[j].record.Timestamp
+	})
+	if limit > 0 && len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	records := make([]Record, len(candidates))
+	for i, c := range candidates {
+		records[i] = c.record
+		if len(terms) > 0 {
+			c.entry.strength = c.strength + m.config.Reinforcement
+			c.entry.accessed = now
+		}
+	}
+	return records, nil
+}
+
+// Clear removes all records
+func (m *Decaying) Clear(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.entries = nil
+	m.mu.Unlock()
+	return nil
+}
@@ -2,7 +2,10 @@ package memory
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"sync"
+	"unicode"
 )
 
 // Memory defines the interface for agents' memory system
@@ -21,7 +24,66 @@ type Memory interface {
 type Record struct {
 	AgentName string
 	Content   string
-	Timestamp int64 // Unix timestamp (optional for implementations)
+	Timestamp int64  // Unix timestamp (optional for implementations)
+	RunID     string // Run that produced the record (optional)
+
+	// Set by implementations that rank records by relevance, to explain why
+	// a record was retrieved: Score is its similarity to the query (higher
+	// is closer) and Highlights are the query terms found in Content
+	Score      float64  `json:",omitempty"`
+	Highlights []string `json:",omitempty"`
+}
+
+// Highlight returns the distinct query terms that occur in content, in
+// query order, ignoring case. Retrieval implementations use it to fill
+// Record.Highlights.
+func Highlight(query, content string) []string {
+	content = strings.ToLower(content)
+	var terms []string
+	for _, term := range queryTerms(query) {
+		if strings.Contains(content, term) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// queryTerms returns the distinct lowercase words of a query, in order
+func queryTerms(query string) []string {
+	var terms []string
+	seen := make(map[string]bool)
+	for _, term := range strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	}) {
+		if !seen[term] {
+			seen[term] = true
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
+
+// Explain formats the score and highlights of retrieved records, one per
+// line, for verbose logs
+func Explain(records []Record) string {
+	var b strings.Builder
+	for i, r := range records {
+		fmt.Fprintf(&b, "%d. score=%.3f", i+1, r.Score)
+		if len(r.Highlights) > 0 {
+			fmt.Fprintf(&b, " matched=%s", strings.Join(r.Highlights, ","))
+		}
+		fmt.Fprintf(&b, " %q\n", summarize(r.Content))
+	}
+	return b.String()
+}
+
+// summarize shortens content to one line for Explain
+func summarize(content string) string {
+	content = strings.Join(strings.Fields(content), " ")
+	if runes := []rune(content); len(runes) > 80 {
+		return string(runes[:80]) + "..."
+	}
+	return content
 }
 
 // Base implements basic memory storage in-memory
@@ -51,6 +113,17 @@ func (m *Base) Store(ctx context.Context, record Record) error {
 	}
 }
 
+// StoreBatch saves records to memory under a single lock
+func (m *Base) StoreBatch(ctx context.Context, records []Record) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.records = append(m.records, records...)
+	m.mu.Unlock()
+	return nil
+}
+
 // Retrieve fetches records from memory based on a simple keyword match
 // This is a basic implementation - in production, use vector similarity search
 func (m *Base) Retrieve(ctx context.Context, query string, limit int) ([]Record, error) {
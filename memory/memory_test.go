@@ -0,0 +1,136 @@
+package memory
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHighlightAndExplain(t *testing.T) {
+	got := Highlight("Pricing of the Q3 launch, pricing", "Q3 pricing rose after the launch")
+	if strings.Join(got, ",") != "pricing,the,q3,launch" {
+		t.Errorf("Highlight() = %q", got)
+	}
+
+	explained := Explain([]Record{{Content: "Q3 pricing\nrose", Score: 0.8125, Highlights: got[:1]}})
+	if explained != "1. score=0.812 matched=pricing \"Q3 pricing rose\"\n" {
+		t.Errorf("Explain() = %q", explained)
+	}
+}
+
+func TestDecaying(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	mem := NewDecaying(DecayConfig{HalfLife: 24 * time.Hour, ForgetBelow: 0.1, Now: func() time.Time { return now }})
+	ctx := context.Background()
+
+	_ = mem.Store(ctx, Record{Content: "Pricing of the launch", Timestamp: now.Add(-48 * time.Hour).Unix()})
+	_ = mem.Store(ctx, Record{Content: "Pricing page copy"})
+	_ = mem.Store(ctx, Record{Content: "Hiring plan"})
+	_ = mem.Store(ctx, Record{Content: "Pricing notes from last quarter", Timestamp: now.Add(-10 * 24 * time.Hour).Unix()})
+
+	contents := func(records []Record) string {
+		var names []string
+		for _, r := range records {
+			names = append(names, r.Content)
+		}
+		return strings.Join(names, "|")
+	}
+
+	// The 10-day-old record decayed below ForgetBelow and is forgotten
+	got, _ := mem.Retrieve(ctx, "pricing", 0)
+	if contents(got) != "Pricing page copy|Pricing of the launch" {
+		t.Fatalf("Retrieve() = %q", contents(got))
+	}
+	if got[0].Score != 1 || got[1].Score != 0.25 || strings.Join(got[1].Highlights, ",") != "pricing" {
+		t.Errorf("scores = %v, %v, highlights = %v", got[0].Score, got[1].Score, got[1].Highlights)
+	}
+	if all, _ := mem.Retrieve(ctx, "", 0); len(all) != 3 {
+		t.Errorf("Retrieve() without query returned %d records, want 3", len(all))
+	}
+
+	// Records retrieved often outrank fresher ones left unused
+	now = now.Add(24 * time.Hour)
+	_ = mem.Store(ctx, Record{Content: "Pricing experiment"})
+	for i := 0; i < 2; i++ {
+		_, _ = mem.Retrieve(ctx, "launch", 1)
+	}
+	got, _ = mem.Retrieve(ctx, "pricing", 2)
+	if contents(got) != "Pricing of the launch|Pricing experiment" {
+		t.Errorf("Retrieve() after reinforcement = %q", contents(got))
+	}
+
+	// Only a query reinforces records
+	before, _ := mem.Retrieve(ctx, "", 0)
+	after, _ := mem.Retrieve(ctx, "", 0)
+	if before[0].Score != after[0].Score {
+		t.Errorf("listing changed a score from %v to %v", before[0].Score, after[0].Score)
+	}
+}
+
+// failingMemory stores records one by one and fails after limit records
+type failingMemory struct {
+	Memory
+	limit int
+}
+
+func (m *failingMemory) Store(ctx context.Context, record Record) error {
+	if all, _ := m.Retrieve(ctx, "", 0); len(all) >= m.limit {
+		return context.DeadlineExceeded
+	}
+	return m.Memory.Store(ctx, record)
+}
+
+func TestBuffered(t *testing.T) {
+	ctx := context.Background()
+	base := New()
+	mem := NewBuffered(base, BufferConfig{MaxRecords: 3, FlushInterval: -1})
+
+	_ = mem.Store(ctx, Record{Content: "first"})
+	_ = mem.Store(ctx, Record{Content: "second"})
+	if stored, _ := base.Retrieve(ctx, "", 0); len(stored) != 0 || mem.Pending() != 2 {
+		t.Fatalf("stored %d records and buffered %d, want 0 and 2", len(stored), mem.Pending())
+	}
+
+	// Retrieve sees buffered records
+	got, err := mem.Retrieve(ctx, "", 0)
+	if err != nil || len(got) != 2 || got[0].Timestamp == 0 {
+		t.Fatalf("Retrieve() = %v, %v, want 2 stamped records", got, err)
+	}
+
+	// A full buffer is flushed in the background
+	for _, content := range []string{"third", "fourth", "fifth"} {
+		_ = mem.Store(ctx, Record{Content: content})
+	}
+	deadline := time.Now().Add(time.Second)
+	for mem.Pending() > 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if stored, _ := base.Retrieve(ctx, "", 0); len(stored) != 5 {
+		t.Errorf("stored %d records after the buffer filled, want 5", len(stored))
+	}
+
+	// A flush cut short keeps the unwritten records, in order
+	failing := &failingMemory{Memory: New(), limit: 1}
+	mem = NewBuffered(failing, BufferConfig{FlushInterval: -1})
+	_ = mem.StoreBatch(ctx, []Record{{Content: "a"}, {Content: "b"}, {Content: "c"}})
+	if err := mem.Flush(ctx); err == nil || mem.Pending() != 2 {
+		t.Fatalf("Flush() = %v with %d pending, want an error and 2 pending", err, mem.Pending())
+	}
+	failing.limit = 3
+	if err := mem.Flush(ctx); err != nil {
+		t.Fatalf("Flush() unexpected error: %v", err)
+	}
+	stored, _ := failing.Retrieve(ctx, "", 0)
+	if len(stored) != 3 || stored[1].Content != "b" || stored[2].Content != "c" {
+		t.Errorf("stored %v, want a, b, c", stored)
+	}
+
+	// An expired context leaves the buffer untouched
+	expired, cancel := context.WithCancel(ctx)
+	cancel()
+	_ = mem.Store(ctx, Record{Content: "d"})
+	if err := mem.Flush(expired); err != context.Canceled || mem.Pending() != 1 {
+		t.Errorf("Flush() = %v with %d pending, want context.Canceled and 1 pending", err, mem.Pending())
+	}
+}
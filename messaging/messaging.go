@@ -0,0 +1,138 @@
+// Package messaging is a message bus on which agents post and read messages
+// on named channels during a run. It enables looser collaboration than task
+// chaining: agents exchange findings, questions and decisions through
+// channels, exposed to them as tools.
+package messaging
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// Message is a message posted on a channel
+type Message struct {
+	Seq     int       `json:"seq"` // Position in the channel, starting at 1
+	Channel string    `json:"channel"`
+	From    string    `json:"from,omitempty"`
+	Content string    `json:"content"`
+	Time    time.Time `json:"time"`
+}
+
+// Config configures a Bus
+type Config struct {
+	// MaxMessages caps the messages kept per channel; the oldest are dropped
+	// first (0 = DefaultMaxMessages)
+	MaxMessages int
+	// MaxMessageLength rejects longer messages (0 = no limit)
+	MaxMessageLength int
+}
+
+// DefaultMaxMessages is the number of messages kept per channel by default
+const DefaultMaxMessages = 1000
+
+// maxTrackedRuns bounds the runs whose channels are kept in memory; the
+// channels of the oldest run are dropped first
+const maxTrackedRuns = 64
+
+// Bus holds the channels of each run. Channels are scoped to the run ID
+// carried by the context, so concurrent runs do not see each other's
+// messages. It is safe for concurrent use.
+type Bus struct {
+	config Config
+
+	mu    sync.Mutex
+	runs  map[string]map[string]*channel // Run ID -> channel name -> channel
+	order []string                       // Tracked run IDs, oldest first
+}
+
+// channel is the message log of one channel
+type channel struct {
+	messages []Message
+	next     int // Sequence number of the next message
+}
+
+// New creates an empty bus
+func New(cfg Config) *Bus {
+	if cfg.MaxMessages <= 0 {
+		cfg.MaxMessages = DefaultMaxMessages
+	}
+	return &Bus{config: cfg, runs: make(map[string]map[string]*channel)}
+}
+
+// Post appends a message from sender to a channel of the current run and
+// returns it
+func (b *Bus) Post(ctx context.Context, channelName, from, content string) (Message, error) {
+	channelName = strings.TrimSpace(channelName)
+	if channelName == "" {
+		return Message{}, errors.RequiredField("channel")
+	}
+	if strings.TrimSpace(content) == "" {
+		return Message{}, errors.RequiredField("content")
+	}
+	if b.config.MaxMessageLength > 0 && len([]rune(content)) > b.config.MaxMessageLength {
+		return Message{}, errors.OutOfRange("content length", 1, b.config.MaxMessageLength).WithContext("channel", channelName)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := b.channel(runid.FromContext(ctx), channelName)
+	ch.next++
+	msg := Message{Seq: ch.next, Channel: channelName, From: from, Content: content, Time: time.Now().UTC()}
+	ch.messages = append(ch.messages, msg)
+	if len(ch.messages) > b.config.MaxMessages {
+		ch.messages = ch.messages[len(ch.messages)-b.config.MaxMessages:]
+	}
+	return msg, nil
+}
+
+// Read returns the messages of a channel of the current run with a sequence
+// number greater than since, oldest first
+func (b *Bus) Read(ctx context.Context, channelName string, since int) []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	ch := b.runs[runid.FromContext(ctx)][strings.TrimSpace(channelName)]
+	if ch == nil {
+		return nil
+	}
+	i := sort.Search(len(ch.messages), func(i int) bool { return ch.messages[i].Seq > since })
+	return append([]Message(nil), ch.messages[i:]...)
+}
+
+// Channels returns the names of the current run's channels, sorted
+func (b *Bus) Channels(ctx context.Context) []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	names := make([]string, 0, len(b.runs[runid.FromContext(ctx)]))
+	for name := range b.runs[runid.FromContext(ctx)] {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// channel returns a channel of a run, creating it on first use. b.mu must be
+// held.
+func (b *Bus) channel(run, name string) *channel {
+	channels, ok := b.runs[run]
+	if !ok {
+		if len(b.order) >= maxTrackedRuns {
+			delete(b.runs, b.order[0])
+			b.order = b.order[1:]
+		}
+		channels = make(map[string]*channel)
+		b.runs[run] = channels
+		b.order = append(b.order, run)
+	}
+	ch, ok := channels[name]
+	if !ok {
+		ch = &channel{}
+		channels[name] = ch
+	}
+	return ch
+}
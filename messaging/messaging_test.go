@@ -0,0 +1,60 @@
+package messaging
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/tools"
+)
+
+func TestBus_ChannelsPerRun(t *testing.T) {
+	bus := New(Config{MaxMessages: 2})
+	run1 := runid.NewContext(context.Background(), "run-1")
+	run2 := runid.NewContext(context.Background(), "run-2")
+
+	for _, content := range []string{"a", "b", "c"} {
+		if _, err := bus.Post(run1, "findings", "researcher", content); err != nil {
+			t.Fatalf("Post(%s) unexpected error: %v", content, err)
+		}
+	}
+	messages := bus.Read(run1, "findings", 0)
+	if len(messages) != 2 || messages[0].Content != "b" || messages[1].Seq != 3 {
+		t.Errorf("Read() = %+v, want the last 2 messages", messages)
+	}
+	if got := bus.Read(run1, "findings", 2); len(got) != 1 || got[0].Content != "c" {
+		t.Errorf("Read(since 2) = %+v, want message 3", got)
+	}
+	if got := bus.Read(run2, "findings", 0); len(got) != 0 {
+		t.Errorf("other run sees %d messages, want 0", len(got))
+	}
+
+	if _, err := bus.Post(run1, "", "x", "hi"); !errors.HasCode(err, errors.ErrRequiredField) {
+		t.Errorf("Post() without channel error = %v, want ErrRequiredField", err)
+	}
+}
+
+func TestBus_Tools(t *testing.T) {
+	bus := New(Config{})
+	registry := tools.NewRegistry()
+	if err := bus.Register(registry); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	ctx := runid.NewContext(context.Background(), "run-1")
+
+	if _, err := registry.Execute(ctx, PostToolName, map[string]interface{}{"channel": "plan", "from": "lead", "content": "Focus on pricing"}); err != nil {
+		t.Fatalf("post unexpected error: %v", err)
+	}
+	out, err := registry.Execute(ctx, ReadToolName, map[string]interface{}{"channel": "plan", "since": float64(0)})
+	if err != nil {
+		t.Fatalf("read unexpected error: %v", err)
+	}
+	if out != "[1] lead: Focus on pricing" {
+		t.Errorf("read = %q", out)
+	}
+	if out, _ := registry.Execute(ctx, ReadToolName, nil); !strings.Contains(out, "plan") {
+		t.Errorf("channel listing = %q, want plan", out)
+	}
+}
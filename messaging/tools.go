@@ -0,0 +1,129 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Names of the tools exposing the bus
+const (
+	PostToolName = "post_message"
+	ReadToolName = "read_messages"
+)
+
+// PostTool posts messages on the bus
+type PostTool struct {
+	*tools.BaseTool
+	bus *Bus
+}
+
+// PostTool returns a tool posting a message on a channel. Arguments are
+// "channel", "content" and optionally "from", the sender's name.
+func (b *Bus) PostTool() *PostTool {
+	return &PostTool{
+		BaseTool: tools.NewBaseTool(PostToolName,
+			"Posts a message on a named channel shared with the other agents of this run.",
+			map[string]interface{}{
+				"channel": "name of the channel, e.g. \"findings\"",
+				"content": "message text",
+				"from":    "your name",
+			}),
+		bus: b,
+	}
+}
+
+// Execute posts the message and confirms its sequence number
+func (t *PostTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	msg, err := t.bus.Post(ctx, stringArg(args, "channel"), stringArg(args, "from"), stringArg(args, "content"))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("Posted message %d on channel %q.", msg.Seq, msg.Channel), nil
+}
+
+// ReadTool reads messages from the bus
+type ReadTool struct {
+	*tools.BaseTool
+	bus *Bus
+}
+
+// ReadTool returns a tool reading the messages of a channel. Arguments are
+// "channel" and optionally "since", the last sequence number already read.
+// Without a channel it lists the run's channels.
+func (b *Bus) ReadTool() *ReadTool {
+	return &ReadTool{
+		BaseTool: tools.NewBaseTool(ReadToolName,
+			"Reads the messages posted on a channel by the agents of this run. Without a channel, lists the channels.",
+			map[string]interface{}{
+				"channel": "name of the channel",
+				"since":   "only return messages after this number (optional)",
+			}),
+		bus: b,
+	}
+}
+
+// Execute returns the channel's messages, one per paragraph
+func (t *ReadTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	name := stringArg(args, "channel")
+	if name == "" {
+		channels := t.bus.Channels(ctx)
+		if len(channels) == 0 {
+			return "No channels have messages yet.", nil
+		}
+		return "Channels: " + strings.Join(channels, ", "), nil
+	}
+
+	since := 0
+	if raw := stringArg(args, "since"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return "", errors.InvalidField("since", "must be a message number").WithContext("value", raw)
+		}
+		since = n
+	}
+
+	messages := t.bus.Read(ctx, name, since)
+	if len(messages) == 0 {
+		return fmt.Sprintf("No new messages on channel %q.", name), nil
+	}
+	var b strings.Builder
+	for i, m := range messages {
+		if i > 0 {
+			b.WriteString("\n\n")
+		}
+		from := m.From
+		if from == "" {
+			from = "unknown"
+		}
+		fmt.Fprintf(&b, "[%d] %s: %s", m.Seq, from, m.Content)
+	}
+	return b.String(), nil
+}
+
+// Register adds the post and read tools to a registry
+func (b *Bus) Register(registry *tools.Registry) error {
+	if err := registry.Register(b.PostTool()); err != nil {
+		return err
+	}
+	return registry.Register(b.ReadTool())
+}
+
+// stringArg returns an argument as a string; numbers decoded from JSON are
+// formatted without a fraction
+func stringArg(args map[string]interface{}, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		return ""
+	}
+}
@@ -0,0 +1,151 @@
+package moderation
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Result is the outcome of a moderation check
+type Result struct {
+	Flagged    bool
+	Categories []string           // Categories that triggered the flag
+	Scores     map[string]float64 // Per-category scores, when the moderator provides them
+}
+
+// Moderator classifies content before it is sent to a model or tool
+type Moderator interface {
+	Check(ctx context.Context, text string) (Result, error)
+}
+
+// Action determines what happens to flagged content
+type Action int
+
+const (
+	// ActionBlock rejects flagged content with a policy error
+	ActionBlock Action = iota
+	// ActionFlag lets flagged content through and reports it to OnFlag
+	ActionFlag
+)
+
+// Options configures a moderation guard
+type Options struct {
+	Action Action
+	// OnFlag is called for every flagged content, whatever the action
+	OnFlag func(ctx context.Context, text string, result Result)
+}
+
+// guard runs the pre-flight check and applies the configured action
+func guard(ctx context.Context, m Moderator, opts Options, source, text string) error {
+	result, err := m.Check(ctx, text)
+	if err != nil {
+		return errors.Wrap(errors.ErrAPICall, "moderation check failed", err).WithContext("source", source)
+	}
+	if !result.Flagged {
+		return nil
+	}
+
+	if opts.OnFlag != nil {
+		opts.OnFlag(ctx, text, result)
+	}
+	if opts.Action == ActionBlock {
+		return errors.PolicyViolation("moderation", result.Categories).WithContext("source", source)
+	}
+	return nil
+}
+
+// LLM checks every prompt before forwarding it to the wrapped provider
+type LLM struct {
+	inner     llm.LLM
+	moderator Moderator
+	opts      Options
+}
+
+// WrapLLM returns an LLM that moderates prompts before sending them
+func WrapLLM(inner llm.LLM, m Moderator, opts Options) *LLM {
+	return &LLM{inner: inner, moderator: m, opts: opts}
+}
+
+// Generate moderates the prompt and forwards it when allowed
+func (l *LLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if err := guard(ctx, l.moderator, l.opts, "prompt", prompt); err != nil {
+		return "", err
+	}
+	return l.inner.Generate(ctx, prompt)
+}
+
+// GenerateResponse moderates the prompt and forwards it when allowed,
+// returning the completion with its token usage and metadata
+func (l *LLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	if err := guard(ctx, l.moderator, l.opts, "prompt", prompt); err != nil {
+		return nil, err
+	}
+	return llm.GenerateResponse(ctx, l.inner, prompt)
+}
+
+// Tool checks tool inputs before executing the wrapped tool
+type Tool struct {
+	tools.Tool
+	moderator Moderator
+	opts      Options
+}
+
+// WrapTool returns a tool that moderates its arguments before executing
+func WrapTool(inner tools.Tool, m Moderator, opts Options) *Tool {
+	return &Tool{Tool: inner, moderator: m, opts: opts}
+}
+
+// SideEffects reports whether the wrapped tool has side effects, so that
+// wrapping keeps it behind confirmation
+func (t *Tool) SideEffects() bool { return tools.HasSideEffects(t.Tool) }
+
+// Execute moderates the JSON-encoded arguments and runs the tool when allowed
+func (t *Tool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "failed to marshal tool arguments", err).WithContext("tool", t.Name())
+	}
+	if err := guard(ctx, t.moderator, t.opts, "tool:"+t.Name(), string(data)); err != nil {
+		return "", err
+	}
+	return t.Tool.Execute(ctx, args)
+}
+
+// KeywordModerator is a local classifier flagging content that contains
+// any of the configured keywords, matched case-insensitively
+type KeywordModerator struct {
+	categories map[string][]string
+}
+
+// NewKeywordModerator creates a local moderator from category keyword lists
+func NewKeywordModerator(categories map[string][]string) *KeywordModerator {
+	normalized := make(map[string][]string, len(categories))
+	for category, words := range categories {
+		for _, w := range words {
+			normalized[category] = append(normalized[category], strings.ToLower(w))
+		}
+	}
+	return &KeywordModerator{categories: normalized}
+}
+
+// Check flags text containing any configured keyword
+func (k *KeywordModerator) Check(ctx context.Context, text string) (Result, error) {
+	lower := strings.ToLower(text)
+	var result Result
+	for category, words := range k.categories {
+		for _, w := range words {
+			if strings.Contains(lower, w) {
+				result.Categories = append(result.Categories, category)
+				break
+			}
+		}
+	}
+	sort.Strings(result.Categories)
+	result.Flagged = len(result.Categories) > 0
+	return result, nil
+}
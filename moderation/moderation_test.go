@@ -0,0 +1,68 @@
+package moderation
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+type countingLLM struct {
+	calls int
+}
+
+func (c *countingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	c.calls++
+	return "ok", nil
+}
+
+func TestWrapLLM_BlockAndFlag(t *testing.T) {
+	m := NewKeywordModerator(map[string][]string{"secrets": {"password"}})
+
+	inner := &countingLLM{}
+	blocking := WrapLLM(inner, m, Options{Action: ActionBlock})
+	_, err := blocking.Generate(context.Background(), "my PASSWORD is 123")
+	if !errors.HasCode(err, errors.ErrPolicyViolation) {
+		t.Errorf("Generate() error = %v, want policy violation", err)
+	}
+	if inner.calls != 0 {
+		t.Errorf("blocked prompt reached the provider")
+	}
+
+	var flagged []string
+	flagging := WrapLLM(inner, m, Options{
+		Action: ActionFlag,
+		OnFlag: func(ctx context.Context, text string, r Result) { flagged = append(flagged, r.Categories...) },
+	})
+	if _, err := flagging.Generate(context.Background(), "password reset"); err != nil {
+		t.Errorf("Generate() unexpected error: %v", err)
+	}
+	if inner.calls != 1 || len(flagged) != 1 || flagged[0] != "secrets" {
+		t.Errorf("flag mode: calls = %d, flagged = %v", inner.calls, flagged)
+	}
+}
+
+func TestOpenAI_Check(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/moderations" {
+			t.Errorf("path = %s, want /moderations", r.URL.Path)
+		}
+		_, _ = w.Write([]byte(`{"results":[{"flagged":true,"categories":{"violence":true,"hate":false},"category_scores":{"violence":0.9}}]}`))
+	}))
+	defer server.Close()
+
+	m, err := NewOpenAI(OpenAIConfig{APIKey: "key", BaseURL: server.URL})
+	if err != nil {
+		t.Fatalf("NewOpenAI() unexpected error: %v", err)
+	}
+
+	result, err := m.Check(context.Background(), "text")
+	if err != nil {
+		t.Fatalf("Check() unexpected error: %v", err)
+	}
+	if !result.Flagged || len(result.Categories) != 1 || result.Categories[0] != "violence" {
+		t.Errorf("Check() = %+v, want flagged for violence", result)
+	}
+}
@@ -0,0 +1,101 @@
+package moderation
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// OpenAIConfig represents the configuration for the OpenAI moderation endpoint
+type OpenAIConfig struct {
+	APIKey string
+	// Model is the moderation model (default: "omni-moderation-latest")
+	Model string
+	// BaseURL overrides the API base (default: "https://api.openai.com/v1")
+	BaseURL string
+}
+
+// OpenAI checks content with the OpenAI moderation endpoint
+type OpenAI struct {
+	config OpenAIConfig
+	client *http.Client
+}
+
+// NewOpenAI creates an OpenAI moderator
+func NewOpenAI(cfg OpenAIConfig) (*OpenAI, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.RequiredField("API key")
+	}
+	if cfg.Model == "" {
+		cfg.Model = "omni-moderation-latest"
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = "https://api.openai.com/v1"
+	}
+	return &OpenAI{config: cfg, client: &http.Client{}}, nil
+}
+
+type openAIModerationRequest struct {
+	Model string `json:"model"`
+	Input string `json:"input"`
+}
+
+type openAIModerationResponse struct {
+	Results []struct {
+		Flagged        bool               `json:"flagged"`
+		Categories     map[string]bool    `json:"categories"`
+		CategoryScores map[string]float64 `json:"category_scores"`
+	} `json:"results"`
+}
+
+// Check sends text to the moderation endpoint
+func (o *OpenAI) Check(ctx context.Context, text string) (Result, error) {
+	jsonData, err := json.Marshal(openAIModerationRequest{Model: o.config.Model, Input: text})
+	if err != nil {
+		return Result{}, errors.Wrap(errors.ErrInternal, "failed to marshal request", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", o.config.BaseURL+"/moderations", bytes.NewReader(jsonData))
+	if err != nil {
+		return Result{}, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+o.config.APIKey)
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return Result{}, errors.APICallError("call OpenAI moderation API", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Result{}, errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Result{}, errors.APIStatusCodeError(resp.StatusCode, string(body))
+	}
+
+	var modResp openAIModerationResponse
+	if err := json.Unmarshal(body, &modResp); err != nil {
+		return Result{}, errors.Wrap(errors.ErrInternal, "failed to unmarshal response", err).WithContext("response_length", len(body))
+	}
+	if len(modResp.Results) == 0 {
+		return Result{}, errors.API("no results from OpenAI moderation API")
+	}
+
+	r := modResp.Results[0]
+	result := Result{Flagged: r.Flagged, Scores: r.CategoryScores}
+	for category, hit := range r.Categories {
+		if hit {
+			result.Categories = append(result.Categories, category)
+		}
+	}
+	sort.Strings(result.Categories)
+	return result, nil
+}
@@ -0,0 +1,97 @@
+package orchestrator
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// PlanApproval reviews the manager's plan before any agent runs. It returns
+// the plan to execute, possibly edited, or an error to abort the run.
+type PlanApproval func(ctx context.Context, plan []PlanStep) ([]PlanStep, error)
+
+// approvePlan passes the plan through the configured approval hook
+func (o *Orchestrator) approvePlan(ctx context.Context, plan []PlanStep) ([]PlanStep, error) {
+	if o.approval == nil {
+		return plan, nil
+	}
+
+	approved, err := o.approval(ctx, plan)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrPolicyViolation, "execution plan was not approved", err).WithContext("steps", len(plan))
+	}
+	if len(approved) == 0 {
+		return nil, errors.New(errors.ErrPolicyViolation, "approved execution plan is empty")
+	}
+	if len(approved) != len(plan) {
+		o.logf("[Manager] Plan edited during approval: %d steps", len(approved))
+	}
+	return approved, nil
+}
+
+// PromptPlanApproval returns a PlanApproval that shows the plan on out and
+// reads commands from in:
+//
+//	y             approve the plan
+//	n             abort the run
+//	d N           delete step N
+//	e N text      replace the description of step N
+//	a N agent     assign step N to another agent
+func PromptPlanApproval(in io.Reader, out io.Writer) PlanApproval {
+	scanner := bufio.NewScanner(in)
+	return func(ctx context.Context, plan []PlanStep) ([]PlanStep, error) {
+		plan = append([]PlanStep(nil), plan...)
+		for {
+			printPlan(out, plan)
+			fmt.Fprint(out, "Approve plan? [y]es, [n]o, d N, e N text, a N agent: ")
+
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return nil, err
+				}
+				return nil, errors.New(errors.ErrPolicyViolation, "no approval received")
+			}
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
+			cmd, args, _ := strings.Cut(strings.TrimSpace(scanner.Text()), " ")
+			switch strings.ToLower(cmd) {
+			case "y", "yes":
+				return plan, nil
+			case "n", "no", "q":
+				return nil, errors.New(errors.ErrPolicyViolation, "plan rejected by reviewer")
+			case "d", "e", "a":
+				index, text, _ := strings.Cut(strings.TrimSpace(args), " ")
+				n, err := strconv.Atoi(index)
+				if err != nil || n < 1 || n > len(plan) {
+					fmt.Fprintf(out, "No step %q\n", index)
+					continue
+				}
+				switch cmd {
+				case "d":
+					plan = append(plan[:n-1], plan[n:]...)
+				case "e":
+					plan[n-1].TaskDescription = strings.TrimSpace(text)
+				case "a":
+					plan[n-1].AgentName = strings.TrimSpace(text)
+				}
+			default:
+				fmt.Fprintf(out, "Unknown command %q\n", cmd)
+			}
+		}
+	}
+}
+
+// printPlan writes a numbered plan
+func printPlan(out io.Writer, plan []PlanStep) {
+	fmt.Fprintf(out, "\nProposed plan (%d steps):\n", len(plan))
+	for i, step := range plan {
+		fmt.Fprintf(out, "  %d. [%s] %s\n", i+1, step.AgentName, step.TaskDescription)
+	}
+}
@@ -0,0 +1,57 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"unicode/utf8"
+
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/task"
+)
+
+const (
+	// defaultArtifactThreshold is the output size above which outputs are
+	// stored as artifacts
+	defaultArtifactThreshold = 64 << 10
+	// artifactPreview is the number of bytes kept in TaskResult.Result
+	artifactPreview = 2 << 10
+)
+
+// offload stores an output larger than the artifact threshold and returns a
+// preview in its place
+func (o *Orchestrator) offload(ctx context.Context, t *task.Task, output string) (string, *artifact.Ref, error) {
+	if o.artifacts == nil || len(output) <= o.artifactLimit {
+		return output, nil, nil
+	}
+
+	key := fmt.Sprintf("runs/%s/%s.txt", runid.FromContext(ctx), t.Key())
+	ref, err := o.artifacts.Put(ctx, key, []byte(output), "text/plain; charset=utf-8")
+	if err != nil {
+		return "", nil, errors.Wrap(errors.ErrInternal, "failed to store task output", err).WithContext("task_description", t.Description)
+	}
+
+	// The preview stays below a threshold lower than artifactPreview, too
+	preview := output[:min(len(output), artifactPreview, o.artifactLimit)]
+	for !utf8.ValidString(preview) {
+		preview = preview[:len(preview)-1]
+	}
+	return fmt.Sprintf("%s\n[... %d bytes total, stored at %s]", preview, ref.Size, ref.URI), ref, nil
+}
+
+// Content returns a result's full output, loading it from the artifact store
+// when it was offloaded
+func (o *Orchestrator) Content(ctx context.Context, r *TaskResult) (string, error) {
+	if r.Artifact == nil {
+		return r.Result, nil
+	}
+	if o.artifacts == nil {
+		return "", errors.MissingConfig("artifact store").WithContext("uri", r.Artifact.URI)
+	}
+	data, err := o.artifacts.Get(ctx, r.Artifact.URI)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
@@ -0,0 +1,48 @@
+package orchestrator
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// taskShare bounds a task to its share of the time left before the run's
+// deadline: the remaining time divided by the tasks not yet completed, this
+// one included. Shares grow when early tasks finish quickly and shrink as
+// the deadline approaches. It returns ctx unchanged, with a zero share,
+// when ApportionDeadline is off, the run has no deadline, or tasks run
+// concurrently.
+func (o *Orchestrator) taskShare(ctx context.Context) (context.Context, time.Duration, context.CancelFunc) {
+	deadline, ok := ctx.Deadline()
+	if !o.apportionDeadline || !ok || o.process == Parallel || o.process == Distributed {
+		return ctx, 0, func() {}
+	}
+
+	o.mu.Lock()
+	outstanding := o.progress.Total - o.progress.Completed
+	o.mu.Unlock()
+	if outstanding < 1 {
+		outstanding = 1
+	}
+	share := time.Until(deadline) / time.Duration(outstanding)
+	if share <= 0 {
+		return ctx, 0, func() {}
+	}
+	shareCtx, cancel := context.WithTimeout(ctx, share)
+	return shareCtx, share, cancel
+}
+
+// shareExceeded reports whether a task failed because it used up its share,
+// rather than because the run itself ended
+func shareExceeded(ctx, shareCtx context.Context, share time.Duration) bool {
+	return share > 0 && ctx.Err() == nil && stderrors.Is(shareCtx.Err(), context.DeadlineExceeded)
+}
+
+// shareTimeout is the error of a task that used up its share of the run's
+// deadline
+func shareTimeout(share time.Duration) error {
+	return errors.Timeout("task", share.Round(time.Millisecond)).
+		WithContext("reason", "the task used up its share of the run deadline")
+}
@@ -0,0 +1,72 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/task"
+)
+
+// minSharedPrefix is the size, in tokens, below which providers do not cache
+// a prompt prefix
+const minSharedPrefix = 1024
+
+// sharePrefixes finds prompt prefixes shared by parallel tasks, such as an
+// agent's description followed by the same injected documents. It returns
+// the SharedPrefix of each task, nil for tasks sharing none, and the prompt
+// tokens that tasks after the first of each group can read from the cache.
+func (o *Orchestrator) sharePrefixes(ctx context.Context, tasks []*task.Task) ([]*llm.SharedPrefix, int) {
+	prompts := make([]string, len(tasks))
+	for i, t := range tasks {
+		if t.Agent != nil {
+			prompts[i], _ = t.Agent.PromptFor(ctx, t.Prompt())
+		}
+	}
+
+	// Each task joins the longest prefix it shares with another task
+	groups := make(map[string][]int)
+	var order []string
+	for i, p := range prompts {
+		var best string
+		for j, other := range prompts {
+			if j != i {
+				if prefix := commonPrefix(p, other); len(prefix) > len(best) {
+					best = prefix
+				}
+			}
+		}
+		if best == "" || o.tokenizer.CountTokens(best) < minSharedPrefix {
+			continue
+		}
+		if _, ok := groups[best]; !ok {
+			order = append(order, best)
+		}
+		groups[best] = append(groups[best], i)
+	}
+
+	shared := make([]*llm.SharedPrefix, len(tasks))
+	var saved int
+	for _, prefix := range order {
+		members := groups[prefix]
+		if len(members) < 2 {
+			continue
+		}
+		p := llm.NewSharedPrefix(prefix)
+		for _, i := range members {
+			shared[i] = p
+		}
+		saved += o.tokenizer.CountTokens(prefix) * (len(members) - 1)
+	}
+	return shared, saved
+}
+
+// commonPrefix returns the longest prefix of a and b ending at a line break,
+// so that a prefix never splits a line of the prompt
+func commonPrefix(a, b string) string {
+	n := 0
+	for n < len(a) && n < len(b) && a[n] == b[n] {
+		n++
+	}
+	return a[:strings.LastIndexByte(a[:n], '\n')+1]
+}
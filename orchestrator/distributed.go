@@ -0,0 +1,222 @@
+package orchestrator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"time"
+
+	"github.com/counhopig/gittyai/distributed"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/task"
+)
+
+// DefaultJobTimeout bounds the wait for a distributed job's result when the
+// config does not set JobTimeout
+const DefaultJobTimeout = time.Hour
+
+// executeDistributed enqueues every task to the configured queue and waits
+// for workers to report results. Tasks must have an agent assigned, which
+// workers resolve by name or build from the definition sent with the job.
+// Jobs go through the lifecycle of local tasks: they count as in flight for
+// Shutdown, publish task events and progress, and failed jobs are reported
+// with StatusFailed. Tasks with a completed result in the result store are
+// reused without enqueueing a job.
+func (o *Orchestrator) executeDistributed(ctx context.Context) ([]*TaskResult, error) {
+	if o.queue == nil {
+		return nil, errors.MissingConfig("queue").WithContext("mode", "distributed")
+	}
+	for _, t := range o.tasks {
+		if t.Agent == nil {
+			return nil, errors.Validationf("task '%s' has no agent assigned", t.Description).
+				WithContext("mode", "distributed")
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		idx    int
+		result *TaskResult
+		err    error
+	}
+
+	results := make([]*TaskResult, len(o.tasks))
+	finished := make([]bool, len(o.tasks))
+	done := make(chan outcome, len(o.tasks))
+	var errs []error
+
+	o.logf("[Distributed Execution] Enqueuing %d tasks", len(o.tasks))
+
+	// Jobs are enqueued in task order; results are awaited concurrently
+	var stopErr error
+	awaiting := 0
+	for i, t := range o.tasks {
+		if stopErr = o.canSchedule(ctx); stopErr != nil {
+			break
+		}
+		await, err := o.enqueueTask(ctx, i, t)
+		if err != nil {
+			if errors.HasCode(err, errors.ErrShutdown) {
+				stopErr = err
+				break
+			}
+			err = errors.Wrap(errors.ErrInternal, fmt.Sprintf("failed to enqueue task %d", i), err).
+				WithContext("task_index", i)
+			errs = append(errs, err)
+			results[i], finished[i] = failedResult(t, err), true
+			o.yield(i, results[i])
+			continue
+		}
+		awaiting++
+		go func(idx int) {
+			result, err := await()
+			done <- outcome{idx: idx, result: result, err: err}
+		}(i)
+	}
+
+	for ; awaiting > 0; awaiting-- {
+		select {
+		case out := <-done:
+			t := o.tasks[out.idx]
+			finished[out.idx] = true
+			switch {
+			case errors.HasCode(out.err, errors.ErrShutdown) || out.err != nil && ctx.Err() != nil:
+				results[out.idx] = cancelledResult(t)
+			case out.err != nil:
+				errs = append(errs, out.err)
+				results[out.idx] = failedResult(t, out.err)
+			default:
+				results[out.idx] = out.result
+			}
+			o.yield(out.idx, results[out.idx])
+		case <-ctx.Done():
+			o.cancelUnfinished(results, finished)
+			return results, ctx.Err()
+		}
+	}
+
+	// Tasks never enqueued because the orchestrator is shutting down are
+	// cancelled
+	o.cancelUnfinished(results, finished)
+	if stopErr != nil && !errors.HasCode(stopErr, errors.ErrShutdown) {
+		errs = append(errs, stopErr)
+	}
+	if len(errs) > 0 {
+		return results, stderrors.Join(errs...)
+	}
+	return results, nil
+}
+
+// cancelUnfinished reports the tasks that have not finished as cancelled
+func (o *Orchestrator) cancelUnfinished(results []*TaskResult, finished []bool) {
+	for i, t := range o.tasks {
+		if !finished[i] {
+			results[i] = cancelledResult(t)
+			o.yield(i, results[i])
+		}
+	}
+}
+
+// enqueueTask starts the task at index i like a local one and enqueues its
+// job. The returned function waits for the job's result and finishes the
+// task. A task whose result is reused is finished without a job.
+func (o *Orchestrator) enqueueTask(ctx context.Context, i int, t *task.Task) (func() (*TaskResult, error), error) {
+	if err := o.startTask(); err != nil {
+		return nil, err
+	}
+	if reused := o.reusedResult(t); reused != nil {
+		defer o.finishTask()
+		o.reuseResult(ctx, t, reused)
+		o.acceptResult(reused)
+		return func() (*TaskResult, error) { return reused, nil }, nil
+	}
+	id := runid.FromContext(ctx)
+	start := o.taskStarted(t)
+	o.agentStarted(t.Agent.Name)
+
+	ev := events.New(events.TaskStarted, id)
+	ev.Task = t.Description
+	ev.Agent = t.Agent.Name
+	o.emit(ctx, ev)
+
+	// finish ends the task with its result or error
+	finish := func(result *TaskResult, err error) (*TaskResult, error) {
+		o.agentFinished(t.Agent.Name, time.Since(start))
+		o.taskFinished(start)
+		defer o.finishTask()
+
+		ev := events.New(events.TaskCompleted, id)
+		ev.Task = t.Description
+		ev.Agent = t.Agent.Name
+		ev.DurationMS = time.Since(start).Milliseconds()
+		if err != nil {
+			ev.Type = events.TaskFailed
+			ev.Error = err.Error()
+			ev.ErrorFingerprint = errors.Fingerprint(err)
+			o.emit(ctx, ev)
+			return nil, err
+		}
+		ev.Result = result.Result
+		o.emit(ctx, ev)
+		o.acceptResult(result)
+		return result, nil
+	}
+
+	taskDef, agentDef := t.Definition(), t.Agent.Definition()
+	job := distributed.Job{
+		ID:             fmt.Sprintf("%s-%d", id, i),
+		RunID:          id,
+		Description:    t.Description,
+		ExpectedOutput: t.ExpectedOutput,
+		AgentName:      t.Agent.Name,
+		Context:        t.Context,
+		Task:           &taskDef,
+		Agent:          &agentDef,
+	}
+	if err := o.queue.Enqueue(ctx, job); err != nil {
+		_, err = finish(nil, err)
+		return nil, err
+	}
+
+	return func() (*TaskResult, error) {
+		out, err := o.awaitJob(ctx, job.ID)
+		switch {
+		case err != nil:
+			return finish(nil, err)
+		case out.Error != "":
+			return finish(nil, errors.Newf(errors.ErrInternal, "task %d failed on worker %s: %s", i, out.Worker, out.Error).
+				WithContext("task_index", i).
+				WithContext("worker", out.Worker))
+		}
+		return finish(&TaskResult{
+			Task:   t,
+			Result: out.Output,
+			Agent:  out.Agent,
+			Status: StatusCompleted,
+			RunID:  id,
+			Key:    t.Key(),
+		}, nil)
+	}, nil
+}
+
+// awaitJob waits for the result of a job for up to the job timeout
+func (o *Orchestrator) awaitJob(ctx context.Context, jobID string) (distributed.JobResult, error) {
+	timeout := o.jobTimeout
+	if timeout == 0 {
+		timeout = DefaultJobTimeout
+	}
+	if timeout < 0 {
+		return o.queue.Await(ctx, jobID)
+	}
+	awaitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	out, err := o.queue.Await(awaitCtx, jobID)
+	if err != nil && ctx.Err() == nil && awaitCtx.Err() == context.DeadlineExceeded {
+		return out, errors.Timeout("await job "+jobID, timeout)
+	}
+	return out, err
+}
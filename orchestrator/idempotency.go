@@ -0,0 +1,108 @@
+package orchestrator
+
+import (
+	"context"
+	"sync"
+
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/task"
+)
+
+// ResultStore keeps completed task results by idempotency key, so a task
+// that already completed is not executed, and paid for, a second time
+type ResultStore interface {
+	Load(key string) (*TaskResult, bool)
+	Store(key string, result *TaskResult)
+}
+
+// MemoryResultStore is an in-process ResultStore
+type MemoryResultStore struct {
+	mu      sync.RWMutex
+	results map[string]*TaskResult
+}
+
+// NewMemoryResultStore creates an empty in-memory result store
+func NewMemoryResultStore() *MemoryResultStore {
+	return &MemoryResultStore{results: make(map[string]*TaskResult)}
+}
+
+// Load returns the result stored under key
+func (s *MemoryResultStore) Load(key string) (*TaskResult, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	r, ok := s.results[key]
+	return r, ok
+}
+
+// Store saves a result under key
+func (s *MemoryResultStore) Store(key string, result *TaskResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[key] = result
+}
+
+// Resume continues the run captured by a checkpoint, typically on a new
+// orchestrator since one that was shut down rejects new runs. Tasks whose
+// results are in the checkpoint are not re-executed; their stored results
+// are returned in place, marked Reused.
+func (o *Orchestrator) Resume(ctx context.Context, cp *Checkpoint) ([]*TaskResult, error) {
+	seed := NewMemoryResultStore()
+	if cp != nil {
+		for _, r := range cp.Results {
+			seed.Store(r.Task.Key(), r)
+		}
+	}
+
+	o.mu.Lock()
+	o.resumed = seed
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.resumed = nil
+		o.mu.Unlock()
+	}()
+	return o.Kickoff(ctx)
+}
+
+// reusedResult returns a copy of a completed result for t from the run's
+// checkpoint or the configured result store, or nil if t must be executed
+func (o *Orchestrator) reusedResult(t *task.Task) *TaskResult {
+	key := t.Key()
+
+	o.mu.Lock()
+	resumed := o.resumed
+	o.mu.Unlock()
+
+	var stored *TaskResult
+	var ok bool
+	if resumed != nil {
+		stored, ok = resumed.Load(key)
+	}
+	if !ok && o.results != nil {
+		stored, ok = o.results.Load(key)
+	}
+	if !ok || stored.Status != StatusCompleted {
+		return nil
+	}
+
+	o.logf("[Task] Reusing stored result for: %s", t.Description)
+	result := *stored
+	result.Task = t
+	result.Key = key
+	result.Reused = true
+	return &result
+}
+
+// reuseResult reports a task answered by a reused result as finished at
+// once, publishing its completion marked Reused
+func (o *Orchestrator) reuseResult(ctx context.Context, t *task.Task, reused *TaskResult) {
+	o.taskFinished(o.taskStarted(t))
+	ev := events.New(events.TaskCompleted, runid.FromContext(ctx))
+	ev.Task = t.Description
+	ev.Agent = reused.Agent
+	ev.Result = reused.Result
+	ev.Reused = true
+	o.emit(ctx, ev)
+}
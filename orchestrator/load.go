@@ -0,0 +1,74 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/counhopig/gittyai/agent"
+)
+
+// AgentStats tracks the load and latency of an agent across runs
+type AgentStats struct {
+	InFlight   int           // Tasks currently executing
+	Completed  int           // Tasks finished, successfully or not
+	AvgLatency time.Duration // Mean task duration; zero until a task finishes
+}
+
+// AgentStats returns a snapshot of per-agent load, keyed by agent name
+func (o *Orchestrator) AgentStats() map[string]AgentStats {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	stats := make(map[string]AgentStats, len(o.loads))
+	for name, s := range o.loads {
+		stats[name] = *s
+	}
+	return stats
+}
+
+// agentStarted records a task starting on an agent
+func (o *Orchestrator) agentStarted(name string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.agentLoad(name).InFlight++
+}
+
+// agentFinished records a task finishing on an agent
+func (o *Orchestrator) agentFinished(name string, d time.Duration) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	s := o.agentLoad(name)
+	s.InFlight--
+	s.AvgLatency = (s.AvgLatency*time.Duration(s.Completed) + d) / time.Duration(s.Completed+1)
+	s.Completed++
+}
+
+// agentLoad returns the stats entry for an agent. Callers must hold o.mu.
+func (o *Orchestrator) agentLoad(name string) *AgentStats {
+	s, ok := o.loads[name]
+	if !ok {
+		s = &AgentStats{}
+		o.loads[name] = s
+	}
+	return s
+}
+
+// leastLoaded picks the candidate with the fewest in-flight tasks, counting
+// assignments made but not yet started in pending. Ties go to the agent with
+// the lowest historical latency, so agents without history are tried early.
+func (o *Orchestrator) leastLoaded(candidates []*agent.Agent, pending map[string]int) *agent.Agent {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var best *agent.Agent
+	var bestLoad int
+	var bestLatency time.Duration
+	for _, a := range candidates {
+		s := o.agentLoad(a.Name)
+		load := s.InFlight + pending[a.Name]
+		if best == nil || load < bestLoad || (load == bestLoad && s.AvgLatency < bestLatency) {
+			best, bestLoad, bestLatency = a, load, s.AvgLatency
+		}
+	}
+	return best
+}
@@ -0,0 +1,134 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+	"github.com/counhopig/gittyai/task"
+)
+
+// MapReduceOptions configures MapReduce
+type MapReduceOptions struct {
+	// MapInstruction is the mapper's task; each item is appended to it
+	MapInstruction string
+	// MapExpectedOutput describes the output expected for each item
+	MapExpectedOutput string
+	// ReduceInstruction is the reducer's task; the mapper outputs are
+	// appended to it
+	ReduceInstruction string
+	// ReduceExpectedOutput describes the final output
+	ReduceExpectedOutput string
+	// MaxParallel caps the mapper tasks running at once (0 = all items)
+	MaxParallel int
+	// AllowPartial reduces over the successful outputs when some items fail,
+	// instead of failing the whole run
+	AllowPartial bool
+	// Config supplies shared settings such as Events, Limits, Logger or
+	// Artifacts; its Agents, Tasks and Process are ignored. Limits apply to
+	// each batch of mappers and to the reducer separately.
+	Config Config
+}
+
+// MapReduceResult holds the outputs of a MapReduce run
+type MapReduceResult struct {
+	// Mapped holds one result per item, in item order
+	Mapped []*TaskResult
+	// Reduced is the reducer's result
+	Reduced *TaskResult
+}
+
+// MapReduce runs a mapper task per item in parallel, then a reducer task
+// over the collected outputs. Both phases share the run ID of ctx.
+func MapReduce(ctx context.Context, items []string, mapper, reducer *agent.Agent, opts MapReduceOptions) (*MapReduceResult, error) {
+	if mapper == nil || reducer == nil {
+		return nil, errors.MissingConfig("mapper and reducer agents")
+	}
+	if len(items) == 0 {
+		return nil, errors.Validation("map-reduce requires at least one item")
+	}
+	if opts.MapInstruction == "" || opts.ReduceInstruction == "" {
+		return nil, errors.MissingConfig("map and reduce instructions")
+	}
+	ctx, id := runid.Ensure(ctx)
+
+	batch := opts.MaxParallel
+	if batch <= 0 || batch > len(items) {
+		batch = len(items)
+	}
+
+	result := &MapReduceResult{Mapped: make([]*TaskResult, 0, len(items))}
+	var outputs strings.Builder
+	var failed []error
+	for start := 0; start < len(items); start += batch {
+		end := start + batch
+		if end > len(items) {
+			end = len(items)
+		}
+
+		tasks := make([]*task.Task, 0, end-start)
+		for _, item := range items[start:end] {
+			tasks = append(tasks, task.New(task.Config{
+				Description:    fmt.Sprintf("%s\n\nInput:\n%s", opts.MapInstruction, item),
+				ExpectedOutput: opts.MapExpectedOutput,
+				Agent:          mapper,
+			}))
+		}
+
+		cfg := opts.Config
+		cfg.Agents, cfg.Tasks, cfg.Process = []*agent.Agent{mapper}, tasks, Parallel
+		phase := New(cfg)
+		mapped, err := phase.Kickoff(ctx)
+		if err != nil && (len(mapped) == 0 || ctx.Err() != nil) {
+			return nil, errors.Wrap(errors.ErrInternal, "map phase failed", err).WithContext("run_id", id)
+		}
+
+		for i, r := range mapped {
+			result.Mapped = append(result.Mapped, r)
+			if r == nil || r.Status != StatusCompleted {
+				failed = append(failed, fmt.Errorf("item %d: %v", start+i, mapErr(r)))
+				continue
+			}
+			output, err := phase.Content(ctx, r)
+			if err != nil {
+				return nil, err
+			}
+			fmt.Fprintf(&outputs, "\n--- Output %d ---\n%s\n", start+i+1, output)
+		}
+	}
+
+	if len(failed) > 0 && (!opts.AllowPartial || len(failed) == len(items)) {
+		return result, errors.Newf(errors.ErrInternal, "map phase failed for %d of %d items", len(failed), len(items)).
+			WithContext("run_id", id).
+			WithContext("errors", failed)
+	}
+
+	cfg := opts.Config
+	cfg.Agents, cfg.Process = []*agent.Agent{reducer}, Sequential
+	cfg.Tasks = []*task.Task{task.New(task.Config{
+		Description:    fmt.Sprintf("%s\n\nOutputs to combine:\n%s", opts.ReduceInstruction, outputs.String()),
+		ExpectedOutput: opts.ReduceExpectedOutput,
+		Agent:          reducer,
+	})}
+	reduced, err := New(cfg).Kickoff(ctx)
+	if err != nil {
+		return result, errors.Wrap(errors.ErrInternal, "reduce phase failed", err).WithContext("run_id", id)
+	}
+	result.Reduced = reduced[0]
+	return result, nil
+}
+
+// mapErr describes why a mapper task did not complete
+func mapErr(r *TaskResult) interface{} {
+	switch {
+	case r == nil:
+		return "no result"
+	case r.Error != nil:
+		return r.Error
+	default:
+		return r.Status
+	}
+}
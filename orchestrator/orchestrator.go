@@ -2,16 +2,31 @@ package orchestrator
 
 import (
 	"context"
-	"encoding/json"
 	stderrors "errors"
 	"fmt"
+	"log"
+	"os"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/citation"
+	"github.com/counhopig/gittyai/debugger"
+	"github.com/counhopig/gittyai/distributed"
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/jsonutil"
+	"github.com/counhopig/gittyai/limits"
 	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/locale"
+	"github.com/counhopig/gittyai/postprocess"
+	"github.com/counhopig/gittyai/prompts"
+	"github.com/counhopig/gittyai/runid"
 	"github.com/counhopig/gittyai/task"
+	"github.com/counhopig/gittyai/tenant"
+	"github.com/counhopig/gittyai/tools"
 )
 
 // Process defines how tasks are executed
@@ -26,16 +41,120 @@ const (
 	Parallel
 	// Hierarchical uses a manager LLM to orchestrate task assignments
 	Hierarchical
+	// Distributed enqueues tasks to a queue consumed by remote workers
+	Distributed
 )
 
+// processNames maps process names, as used in configuration, to processes
+var processNames = map[string]Process{
+	"sequential":   Sequential,
+	"parallel":     Parallel,
+	"hierarchical": Hierarchical,
+	"distributed":  Distributed,
+}
+
+// ParseProcess parses a process name. Names are case-insensitive and an
+// empty name selects Sequential.
+func ParseProcess(name string) (Process, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return Sequential, nil
+	}
+	if p, ok := processNames[name]; ok {
+		return p, nil
+	}
+	return processUnset, errors.InvalidField("process", "must be one of sequential, parallel, hierarchical, distributed").
+		WithContext("value", name)
+}
+
+func (p Process) String() string {
+	for name, process := range processNames {
+		if process == p {
+			return name
+		}
+	}
+	return "unknown"
+}
+
+// Logger receives progress messages. *log.Logger satisfies it.
+type Logger interface {
+	Printf(format string, v ...interface{})
+}
+
 // Orchestrator represents a group of agents working together
 type Orchestrator struct {
-	agents     []*agent.Agent
+	agents     *AgentRegistry
+	configErr  error // Invalid configuration detected by New
 	tasks      []*task.Task
 	process    Process
 	managerLLM llm.LLM // Manager LLM for hierarchical orchestration
 	goal       string  // High-level goal for hierarchical mode
+	queue      distributed.Queue
+	jobTimeout time.Duration // Wait for each distributed job's result
+	events     events.Sink
+	tenant     string
 	verbose    bool
+	logger     Logger
+
+	// Hierarchical context carried between steps
+	contextBudget     int
+	compressor        llm.Compressor
+	tokenizer         llm.Tokenizer
+	synthesis         bool // Whether the manager synthesizes a FinalResult
+	justify           bool // Whether the manager explains agent assignments
+	loadBalance       bool // Whether hierarchical assignment picks the least loaded agent
+	tools             *tools.Registry
+	confirmTools      tools.Confirmer
+	limits            limits.Config
+	apportionDeadline bool
+	speculative       bool
+	dedupPrompts      bool
+	onResult          ResultHandler
+	maxConcurrency    int
+	preempt           bool
+	resultOrder       ResultOrder
+	continueOnError   bool
+	abortSeverity     errors.Severity
+	results           ResultStore
+	postProcess       postprocess.Chain
+	artifacts         artifact.Store
+	artifactLimit     int
+	prices            map[string]llm.Price
+	stats             *statsCollector
+	approval          PlanApproval
+	language          string
+	selectPrompt      *prompts.Template
+	planPrompt        *prompts.Template
+	debug             debugger.Hook
+
+	// Run state used by Shutdown
+	mu        sync.Mutex
+	draining  bool
+	active    int
+	drained   chan struct{}
+	cancelRun context.CancelFunc
+	completed []*TaskResult
+	runID     string
+	final     *FinalResult
+	plan      *Plan // Plan executed by the most recent goal-driven run
+	replay    *Plan // Saved plan executed by KickoffWithPlan
+
+	// Progress tracking
+	progress     Progress
+	runStart     time.Time
+	meanDuration time.Duration // Mean duration of finished tasks across runs, used for ETA
+	timedTasks   int           // Number of tasks meanDuration averages
+	updates      chan Progress
+	loads        map[string]*AgentStats
+	meter        *limits.Meter
+	sharedTokens int             // Prompt tokens of the run served from shared prefixes
+	ranTasks     []*task.Task    // Tasks of the last parallel run, including submitted ones
+	parallel     []*TaskResult   // Results of the last parallel run, in task order
+	retrying     []int           // Task indices RetryFailed executes
+	retries      map[int]int     // Re-executions per task index since the last full parallel run
+	submissions  chan submission // Receives Submit calls during a parallel run
+	submitClosed chan struct{}   // Closed when the parallel run stops accepting tasks
+	resumed      ResultStore     // Checkpoint results while Resume runs
 }
 
 // Config represents the configuration for creating an Orchestrator
@@ -43,9 +162,120 @@ type Config struct {
 	Agents     []*agent.Agent
 	Tasks      []*task.Task
 	Process    Process
-	ManagerLLM llm.LLM // Optional: LLM for intelligent task orchestration
-	Goal       string  // Optional: High-level goal for hierarchical mode
-	Verbose    bool
+	ManagerLLM llm.LLM           // Optional: LLM for intelligent task orchestration
+	Goal       string            // Optional: High-level goal for hierarchical mode
+	Queue      distributed.Queue // Required for distributed mode
+	Events     events.Sink       // Optional: receives run and task events
+	Tenant     string            // Optional: tenant attached to runs whose context carries none
+	Verbose    bool              // Print progress messages; off by default for library use
+	Logger     Logger            // Optional: receives progress messages when Verbose (default: stdout)
+
+	// ContextBudget caps the tokens of previous results carried into each
+	// hierarchical step; older results are folded into a rolling summary
+	// (0 = unlimited)
+	ContextBudget int
+	// Compressor summarizes carried context when it exceeds ContextBudget
+	// (default: summarize with ManagerLLM, or heuristic compression without one)
+	Compressor llm.Compressor
+	// Tokenizer counts carried context against ContextBudget, e.g.
+	// llm.TokenizerFor(model) (default: character estimate)
+	Tokenizer llm.Tokenizer
+	// Synthesize makes the manager combine hierarchical step outputs into a
+	// single answer, available from FinalResult after the run
+	Synthesize bool
+	// JustifyAssignments asks the manager to explain each agent it assigns,
+	// recorded on TaskResult.Assignment
+	JustifyAssignments bool
+	// LoadBalance assigns hierarchical tasks to the least loaded agent with
+	// the required skills instead of asking the manager. Unassigned tasks in
+	// parallel mode are always balanced this way.
+	LoadBalance bool
+	// JobTimeout bounds how long a distributed run waits for the result of
+	// each job, so a job lost with its worker fails its task with ErrTimeout
+	// instead of hanging the run (0 = DefaultJobTimeout, negative = no bound)
+	JobTimeout time.Duration
+
+	// Tools is a registry shared by all agents of the orchestrator. Agents
+	// are told about the tools they may use and execute the tool calls of
+	// their responses with it during their tasks.
+	Tools *tools.Registry
+	// ConfirmTools must confirm every call to a side-effecting tool, e.g.
+	// tools.PromptConfirm(os.Stdin, os.Stdout) or tools.DryRunAll; read-only
+	// tools run without confirmation
+	ConfirmTools tools.Confirmer
+	// Limits caps tool calls, LLM calls and wall time per run
+	Limits limits.Config
+	// ApportionDeadline gives each task of a sequential or hierarchical run
+	// an equal share of the time left before the run's deadline
+	// (Limits.MaxWallTime or the deadline of the Kickoff context), so early
+	// tasks cannot consume the whole budget and leave later tasks none. A
+	// task exceeding its share fails with ErrTimeout.
+	ApportionDeadline bool
+	// Speculative starts the next task of a sequential run while the
+	// current one executes, when the next task has no Context reference to
+	// previous tasks. Results keep task order; a speculative task is
+	// cancelled and discarded when an earlier task fails. Ignored when Debug
+	// is set.
+	Speculative bool
+	// DedupPrompts detects large prompt prefixes shared by parallel tasks,
+	// such as the same agent description and injected documents, and marks
+	// them for the provider's prompt cache. The first task sharing a prefix
+	// calls the LLM alone, so the others read the cache it writes. The
+	// tokens saved are reported in RunStats.SharedPrefixTokens.
+	DedupPrompts bool
+	// MaxConcurrency bounds the tasks of a parallel run executed at once
+	// (0 = all at once). Waiting tasks start in order of Priority.
+	MaxConcurrency int
+	// Preempt lets a task added by Submit with a higher priority than a
+	// running task take its slot when none is free: the running task is
+	// cancelled and queued again
+	Preempt bool
+	// OnResult receives each result of a parallel run as soon as its task
+	// finishes, so results can be processed and displayed incrementally
+	OnResult ResultHandler
+	// ResultOrder orders the results returned by a parallel run (default:
+	// TaskOrder)
+	ResultOrder ResultOrder
+	// ContinueOnError keeps a sequential run going when a task fails: the
+	// task is reported with StatusFailed and the returned error joins the
+	// errors of all failed tasks. Parallel runs always continue.
+	ContinueOnError bool
+	// AbortSeverity aborts the run when a task fails with an error of this
+	// severity or higher anywhere in its chain, e.g. errors.SeverityCritical,
+	// even with ContinueOnError; running parallel tasks are cancelled.
+	// Failures of lower severity follow ContinueOnError. SeverityLow, the
+	// zero value, disables it.
+	AbortSeverity errors.Severity
+	// Results deduplicates task execution across runs: a task whose
+	// idempotency key has a completed result is not executed again
+	Results ResultStore
+	// PostProcess is applied to every task's output after the task's own
+	// post-processors
+	PostProcess postprocess.Chain
+	// Artifacts stores task outputs larger than ArtifactThreshold bytes;
+	// results then carry a reference and a preview. The store is also
+	// available to tools through artifact.FromContext.
+	Artifacts artifact.Store
+	// ArtifactThreshold is the output size above which outputs are stored
+	// as artifacts (default: 64 KiB)
+	ArtifactThreshold int
+	// Prices maps model names to prices, used to report run costs
+	Prices map[string]llm.Price
+	// ApprovePlan reviews a goal-driven hierarchical plan before execution;
+	// see PromptPlanApproval for an interactive reviewer
+	ApprovePlan PlanApproval
+	// Language asks agents and the manager to write in this language, e.g.
+	// "German" or "de", and localizes report headings (default: unset)
+	Language string
+	// SelectionPrompt replaces DefaultSelectionPrompt for assigning tasks in
+	// hierarchical mode; it is rendered with a SelectionPromptData value
+	SelectionPrompt *prompts.Template
+	// PlanningPrompt replaces DefaultPlanningPrompt for planning a goal; it
+	// is rendered with a PlanningPromptData value
+	PlanningPrompt *prompts.Template
+	// Debug pauses the run before each task and LLM call, e.g. with
+	// debugger.Prompt(os.Stdin, os.Stdout) to step through a run
+	Debug debugger.Hook
 }
 
 // New creates a new Orchestrator
@@ -55,18 +285,246 @@ func New(cfg Config) *Orchestrator {
 		process = Sequential
 	}
 
+	logger := cfg.Logger
+	if logger == nil {
+		logger = log.New(os.Stdout, "", 0)
+	}
+
+	// Duplicate agent names are reported by Kickoff, since New cannot fail
+	agents, configErr := NewAgentRegistry(cfg.Agents...)
+	if configErr != nil {
+		agents, _ = NewAgentRegistry()
+	}
+
+	// Manager calls count against the run's LLM call limit
+	var manager llm.LLM
+	if cfg.ManagerLLM != nil {
+		manager = meteredLLM{inner: cfg.ManagerLLM}
+	}
+
+	compressor := cfg.Compressor
+	if compressor == nil {
+		compressor = llm.HeuristicCompressor{}
+		if manager != nil {
+			compressor, _ = llm.NewSummaryCompressor(manager)
+		}
+	}
+
+	tokenizer := cfg.Tokenizer
+	if tokenizer == nil {
+		tokenizer = llm.CharTokenizer{}
+	}
+
+	selectPrompt := cfg.SelectionPrompt
+	if selectPrompt == nil {
+		selectPrompt = defaultSelectionTemplate
+	}
+	planPrompt := cfg.PlanningPrompt
+	if planPrompt == nil {
+		planPrompt = defaultPlanningTemplate
+	}
+
+	artifactThreshold := cfg.ArtifactThreshold
+	if artifactThreshold <= 0 {
+		artifactThreshold = defaultArtifactThreshold
+	}
+
 	return &Orchestrator{
-		agents:     cfg.Agents,
+		agents:     agents,
+		configErr:  configErr,
 		tasks:      cfg.Tasks,
 		process:    process,
-		managerLLM: cfg.ManagerLLM,
+		managerLLM: manager,
 		goal:       cfg.Goal,
+		queue:      cfg.Queue,
+		jobTimeout: cfg.JobTimeout,
+		events:     cfg.Events,
+		tenant:     cfg.Tenant,
 		verbose:    cfg.Verbose,
+		logger:     logger,
+		drained:    make(chan struct{}),
+		updates:    make(chan Progress, progressBuffer),
+
+		contextBudget:     cfg.ContextBudget,
+		compressor:        compressor,
+		tokenizer:         tokenizer,
+		synthesis:         cfg.Synthesize,
+		justify:           cfg.JustifyAssignments,
+		loadBalance:       cfg.LoadBalance,
+		loads:             make(map[string]*AgentStats),
+		tools:             cfg.Tools,
+		confirmTools:      cfg.ConfirmTools,
+		limits:            cfg.Limits,
+		apportionDeadline: cfg.ApportionDeadline,
+		speculative:       cfg.Speculative,
+		dedupPrompts:      cfg.DedupPrompts,
+		onResult:          cfg.OnResult,
+		maxConcurrency:    cfg.MaxConcurrency,
+		preempt:           cfg.Preempt,
+		resultOrder:       cfg.ResultOrder,
+		continueOnError:   cfg.ContinueOnError,
+		abortSeverity:     cfg.AbortSeverity,
+		results:           cfg.Results,
+		postProcess:       cfg.PostProcess,
+		artifacts:         cfg.Artifacts,
+		artifactLimit:     artifactThreshold,
+		prices:            cfg.Prices,
+		stats:             &statsCollector{},
+		approval:          cfg.ApprovePlan,
+		language:          cfg.Language,
+		selectPrompt:      selectPrompt,
+		planPrompt:        planPrompt,
+		debug:             cfg.Debug,
 	}
 }
 
-// Kickoff starts the execution of all tasks
+// Kickoff starts the execution of all tasks.
+// Each run is identified by a run ID, reused from ctx when the caller already
+// attached one with runid.NewContext, and propagated to every task and error.
 func (o *Orchestrator) Kickoff(ctx context.Context) ([]*TaskResult, error) {
+	if o.configErr != nil {
+		return nil, o.configErr
+	}
+	ctx, id := runid.Ensure(ctx)
+	if o.tenant != "" && tenant.FromContext(ctx) == "" {
+		ctx = tenant.NewContext(ctx, o.tenant)
+	}
+	if o.artifacts != nil {
+		ctx = artifact.NewContext(ctx, o.artifacts)
+	}
+	if o.language != "" && locale.FromContext(ctx) == "" {
+		ctx = locale.NewContext(ctx, o.language)
+	}
+	if o.debug != nil && debugger.FromContext(ctx) == nil {
+		ctx = debugger.NewContext(ctx, o.debug)
+	}
+	if o.confirmTools != nil && tools.ConfirmerFromContext(ctx) == nil {
+		ctx = tools.NewConfirmContext(ctx, o.confirmTools)
+	}
+	if o.tools != nil && tools.RegistryFromContext(ctx) == nil {
+		ctx = tools.NewRegistryContext(ctx, o.tools)
+	}
+	meter := limits.NewMeter(o.limits)
+	ctx = limits.NewContext(ctx, meter)
+	if o.limits.MaxWallTime > 0 {
+		var cancelWall context.CancelFunc
+		ctx, cancelWall = context.WithTimeout(ctx, o.limits.MaxWallTime)
+		defer cancelWall()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if err := o.beginRun(cancel, id, meter); err != nil {
+		return nil, err
+	}
+
+	o.logf("[Run %s] Starting", id)
+	o.emit(ctx, events.New(events.RunStarted, id))
+
+	start := time.Now()
+	results, err := o.run(ctx)
+	if err != nil && o.limits.MaxWallTime > 0 && time.Since(start) >= o.limits.MaxWallTime && stderrors.Is(ctx.Err(), context.DeadlineExceeded) {
+		err = errors.Timeout("run", o.limits.MaxWallTime).WithRetryable(false).WithTemporary(false)
+	}
+
+	// Write buffered memories even when the run was cancelled, within a
+	// deadline of their own
+	flushCtx, cancelFlush := context.WithTimeout(context.WithoutCancel(ctx), memoryFlushTimeout)
+	if flushErr := o.flushMemory(flushCtx); flushErr != nil {
+		o.logf("[Memory] %v", flushErr)
+	}
+	cancelFlush()
+
+	ev := events.New(events.RunCompleted, id)
+	if err != nil {
+		ev.Type = events.RunFailed
+		ev.Error = err.Error()
+		ev.ErrorFingerprint = errors.Fingerprint(err)
+	}
+	ev.DurationMS = time.Since(start).Milliseconds()
+	o.setUsage(&ev, meter.Usage())
+	o.mu.Lock()
+	ev.SharedPrefixTokens = o.sharedTokens
+	o.mu.Unlock()
+	o.emit(ctx, ev)
+
+	return results, tagRunID(err, id)
+}
+
+// logf reports progress in verbose mode
+func (o *Orchestrator) logf(format string, args ...interface{}) {
+	if o.verbose {
+		o.logger.Printf(format, args...)
+	}
+}
+
+// emit publishes an event to the configured sink. Sink failures never fail
+// the run; they are reported in verbose mode only.
+func (o *Orchestrator) emit(ctx context.Context, event events.Event) {
+	o.stats.record(event)
+	if o.events == nil {
+		return
+	}
+	if err := o.events.Publish(context.WithoutCancel(ctx), event); err != nil {
+		o.logf("[Events] Failed to publish %s: %v", event.Type, err)
+	}
+}
+
+// Agents returns the orchestrator's agent registry
+func (o *Orchestrator) Agents() *AgentRegistry {
+	return o.agents
+}
+
+// Tools returns the registry shared by the orchestrator's agents, or nil
+func (o *Orchestrator) Tools() *tools.Registry {
+	return o.tools
+}
+
+// Usage returns the resources consumed by the most recent run
+func (o *Orchestrator) Usage() limits.Usage {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.meter.Usage()
+}
+
+// meteredLLM counts calls against the limits of the run carried by ctx
+type meteredLLM struct {
+	inner llm.LLM
+}
+
+func (m meteredLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	decision, err := debugger.Pause(ctx, debugger.Step{Kind: debugger.KindLLM, RunID: runid.FromContext(ctx), Agent: "manager", Prompt: prompt})
+	if err != nil {
+		return "", err
+	}
+	if decision.Action == debugger.Skip || decision.Action == debugger.Respond {
+		return decision.Response, nil
+	}
+
+	meter := limits.FromContext(ctx)
+	if err := meter.LLMCall(); err != nil {
+		return "", err
+	}
+	resp, err := llm.GenerateResponse(ctx, m.inner, decision.Prompt)
+	if err != nil {
+		return "", err
+	}
+	meter.RecordResponse(resp)
+	return resp.Content, nil
+}
+
+// RunID returns the ID of the most recent run
+func (o *Orchestrator) RunID() string {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.runID
+}
+
+// run dispatches to the configured process
+func (o *Orchestrator) run(ctx context.Context) ([]*TaskResult, error) {
+	if o.replayPlan() != nil {
+		return o.executeHierarchical(ctx)
+	}
 	switch o.process {
 	case Sequential:
 		return o.executeSequential(ctx)
@@ -74,141 +532,342 @@ func (o *Orchestrator) Kickoff(ctx context.Context) ([]*TaskResult, error) {
 		return o.executeParallel(ctx)
 	case Hierarchical:
 		return o.executeHierarchical(ctx)
+	case Distributed:
+		return o.executeDistributed(ctx)
 	default:
 		return nil, errors.Unsupportedf("unknown process type: %v", o.process).WithContext("process", o.process)
 	}
 }
 
-// executeSequential runs tasks one by one
+// tagRunID adds the run ID to the context of structured errors, including
+// each error of a joined parallel failure. The errors are copied, since task
+// errors may be shared.
+func tagRunID(err error, id string) error {
+	switch e := err.(type) {
+	case *errors.Error:
+		return e.WithContext("run_id", id)
+	case interface{ Unwrap() []error }:
+		inner := e.Unwrap()
+		tagged := make([]error, len(inner))
+		for i, ie := range inner {
+			tagged[i] = tagRunID(ie, id)
+		}
+		return stderrors.Join(tagged...)
+	}
+	return err
+}
+
+// executeSequential runs tasks one by one, stopping at the first failure
+// unless ContinueOnError is set. With Speculative, a task that references
+// no previous task starts while its predecessor runs; results stay in task
+// order.
 func (o *Orchestrator) executeSequential(ctx context.Context) ([]*TaskResult, error) {
 	results := make([]*TaskResult, 0, len(o.tasks))
+	var errs []error // Failures passed over with ContinueOnError
+
+	var ahead *speculation
+	defer func() { ahead.discard() }()
 
 	for i, t := range o.tasks {
-		select {
-		case <-ctx.Done():
-			return results, ctx.Err()
-		default:
+		current := ahead
+		ahead = nil
+		if current == nil {
+			if err := o.canSchedule(ctx); err != nil {
+				return results, err
+			}
+			o.logf("[Task %d/%d] Starting: %s", i+1, len(o.tasks), t.Description)
+		}
+		ahead = o.speculate(ctx, i+1)
+
+		var result *TaskResult
+		var err error
+		if current != nil {
+			result, err = current.wait()
+		} else {
+			result, err = o.executeTask(ctx, t)
 		}
-
-		fmt.Printf("\n[Task %d/%d] Starting: %s\n", i+1, len(o.tasks), t.Description)
-
-		result, err := o.executeTask(ctx, t)
 		if err != nil {
-			return results, errors.Wrap(errors.ErrInternal, fmt.Sprintf("task %d failed", i), err).
+			err = errors.Wrap(errors.ErrInternal, fmt.Sprintf("task %d failed", i), err).
 				WithContext("task_index", i).
 				WithContext("agent", t.Agent.Name)
+			if !o.continueOnError || o.aborts(err) {
+				if len(errs) > 0 {
+					return results, stderrors.Join(append(errs, err)...)
+				}
+				return results, err
+			}
+			o.logf("[Task %d/%d] Failed, continuing: %v", i+1, len(o.tasks), err)
+			errs = append(errs, err)
+			results = append(results, failedResult(t, err))
+			continue
 		}
 
 		results = append(results, result)
-		fmt.Printf("[Task %d/%d] Completed\n", i+1, len(o.tasks))
+		o.logf("[Task %d/%d] Completed", i+1, len(o.tasks))
 	}
 
-	return results, nil
+	return results, stderrors.Join(errs...)
+}
+
+// aborts reports whether a task failure is severe enough to abort the run
+func (o *Orchestrator) aborts(err error) bool {
+	return o.abortSeverity > errors.SeverityLow && errors.MaxSeverity(err) >= o.abortSeverity
 }
 
-// executeParallel runs tasks concurrently
+// executeParallel runs tasks concurrently, at most MaxConcurrency at once
+// and in order of priority. Cancellation of ctx is propagated into every
+// running task and returns promptly with partial results; tasks that have
+// not finished are reported with StatusCancelled. Every task has a result:
+// failed tasks are reported with StatusFailed and their error, and the
+// returned error joins the errors of all failed tasks. Each result is passed
+// to OnResult as its task finishes and returned in the configured
+// ResultOrder. Tasks added by Submit while the run is in progress follow
+// those of Config.Tasks.
 func (o *Orchestrator) executeParallel(ctx context.Context) ([]*TaskResult, error) {
-	results := make([]*TaskResult, len(o.tasks))
-	var wg sync.WaitGroup
-	var mu sync.Mutex
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type outcome struct {
+		idx    int
+		result *TaskResult
+		err    error
+	}
+
+	tasks := o.parallelTasks()
+	results := make([]*TaskResult, len(tasks))
+	finished := make([]bool, len(tasks))
+	var completion []int // Task indices in the order they finished
+	done := make(chan outcome)
 	var errs []error
 
-	fmt.Printf("\n[Parallel Execution] Starting %d tasks\n", len(o.tasks))
+	// RetryFailed executes only the retried tasks and keeps the other
+	// results of the previous run; failures it does not retry still fail
+	// the run
+	launch, retrying, carried := o.parallelLaunch(results, finished)
+	errs = append(errs, carried...)
+	if retrying {
+		o.setProgressTotal(len(launch))
+	}
 
-	for i, t := range o.tasks {
-		select {
-		case <-ctx.Done():
-			return results[:i], ctx.Err()
-		default:
+	// Assign unassigned tasks up front, balancing across agents with the
+	// required skills; pending counts assignments whose tasks have not started
+	assigned := make([]*task.Task, len(tasks))
+	pending := make(map[string]int)
+	for _, i := range launch {
+		t, err := o.assignAgent(i, tasks[i], pending)
+		if err != nil {
+			return nil, err
+		}
+		assigned[i] = t
+	}
+
+	shared := make([]*llm.SharedPrefix, len(assigned))
+	if o.dedupPrompts {
+		launched := make([]*task.Task, len(launch))
+		for k, i := range launch {
+			launched[k] = assigned[i]
+		}
+		prefixes, saved := o.sharePrefixes(ctx, launched)
+		for k, i := range launch {
+			shared[i] = prefixes[k]
 		}
+		o.mu.Lock()
+		o.sharedTokens = saved
+		o.mu.Unlock()
+		if saved > 0 {
+			o.logf("[Parallel Execution] Shared prompt prefixes save %d prompt tokens", saved)
+		}
+	}
 
-		wg.Add(1)
-		go func(idx int, t *task.Task) {
-			defer wg.Done()
+	o.logf("[Parallel Execution] Starting %d tasks", len(launch))
+
+	sched := newScheduler(o.maxConcurrency)
+	for _, i := range launch {
+		sched.push(i, assigned[i].Priority)
+	}
+	submissions, closeSubmissions := o.openSubmissions()
+	defer closeSubmissions()
+
+	for remaining := len(launch); remaining > 0; {
+		for next, ok := sched.next(); ok; next, ok = sched.next() {
+			taskCtx, cancelTask := context.WithCancel(ctx)
+			if shared[next.idx] != nil {
+				taskCtx = llm.NewPrefixContext(taskCtx, shared[next.idx])
+			}
+			sched.start(next, cancelTask)
+			go func(idx int, t *task.Task) {
+				result, taskErr := o.executeRetried(taskCtx, idx, t, retrying)
+				select {
+				case done <- outcome{idx: idx, result: result, err: taskErr}:
+				case <-ctx.Done():
+				}
+			}(next.idx, assigned[next.idx])
+		}
 
-			result, taskErr := o.executeTask(ctx, t)
-			mu.Lock()
-			if taskErr != nil {
-				errs = append(errs, errors.Wrap(errors.ErrInternal, fmt.Sprintf("task %d failed", idx), taskErr).
-					WithContext("task_index", idx))
+		select {
+		case out := <-done:
+			if sched.finish(out.idx, out.err != nil) {
+				o.logf("[Task %d] Preempted, queued again", out.idx+1)
+				continue
+			}
+			remaining--
+			finished[out.idx] = true
+			completion = append(completion, out.idx)
+			if errors.HasCode(out.err, errors.ErrShutdown) {
+				results[out.idx] = cancelledResult(tasks[out.idx])
+			} else if out.err != nil {
+				err := errors.Wrap(errors.ErrInternal, fmt.Sprintf("task %d failed", out.idx), out.err).
+					WithContext("task_index", out.idx)
+				errs = append(errs, err)
+				results[out.idx] = failedResult(assigned[out.idx], err)
+				if o.aborts(err) {
+					o.logf("[Task %d] Failed with %s severity, aborting the run", out.idx+1, errors.MaxSeverity(err))
+					o.yield(out.idx, results[out.idx])
+					cancel()
+					for i, t := range tasks {
+						if !finished[i] {
+							results[i] = cancelledResult(t)
+							o.yield(i, results[i])
+						}
+					}
+					return o.finishParallel(tasks, results, completion), stderrors.Join(errs...)
+				}
 			} else {
-				results[idx] = result
+				results[out.idx] = out.result
+			}
+			o.yield(out.idx, results[out.idx])
+		case sub := <-submissions:
+			idx := len(tasks)
+			t, err := o.assignAgent(idx, sub.task, pending)
+			if err != nil {
+				sub.reply <- submitReply{index: -1, err: err}
+				continue
+			}
+			tasks = append(tasks, sub.task)
+			assigned = append(assigned, t)
+			results = append(results, nil)
+			finished = append(finished, false)
+			shared = append(shared, nil)
+			remaining++
+			sched.push(idx, t.Priority)
+			o.setProgressTotal(len(tasks))
+			o.logf("[Task %d] Submitted with priority %d: %s", idx+1, t.Priority, t.Description)
+			if o.preempt {
+				if victim, ok := sched.preempt(t.Priority); ok {
+					o.logf("[Task %d] Preempting for task %d", victim+1, idx+1)
+				}
+			}
+			sub.reply <- submitReply{index: idx}
+		case <-ctx.Done():
+			for i, t := range tasks {
+				if !finished[i] {
+					results[i] = cancelledResult(t)
+					o.yield(i, results[i])
+				}
 			}
-			mu.Unlock()
-		}(i, t)
+			return o.finishParallel(tasks, results, completion), ctx.Err()
+		}
 	}
 
-	wg.Wait()
-
 	if len(errs) > 0 {
-		return results, stderrors.Join(errs...)
+		return o.finishParallel(tasks, results, completion), stderrors.Join(errs...)
 	}
 
-	return results, nil
+	return o.finishParallel(tasks, results, completion), nil
 }
 
 // executeHierarchical uses a manager LLM to intelligently orchestrate tasks
 func (o *Orchestrator) executeHierarchical(ctx context.Context) ([]*TaskResult, error) {
-	if o.managerLLM == nil {
+	replay := o.replayPlan()
+	// A saved plan needs the manager only to synthesize the final result
+	if o.managerLLM == nil && (replay == nil || o.synthesis) {
 		return nil, errors.MissingConfig("manager_llm").WithContext("mode", "hierarchical")
 	}
 
-	if len(o.agents) == 0 {
+	if o.agents.Len() == 0 {
 		return nil, errors.InvalidConfig("agents", "no agents available for orchestration").WithContext("mode", "hierarchical")
 	}
 
-	fmt.Println("\n[Hierarchical Mode] Manager is planning task execution...")
-
-	// If we have predefined tasks, let manager assign agents
-	if len(o.tasks) > 0 {
-		return o.orchestratePredefinedTasks(ctx)
+	goal := o.goal
+	var results []*TaskResult
+	var err error
+	switch {
+	case replay != nil:
+		o.logf("[Hierarchical Mode] Executing saved plan with %d steps", len(replay.Steps))
+		if replay.Goal != "" {
+			goal = replay.Goal
+		}
+		results, err = o.executePlan(ctx, goal, replay.Steps)
+	case len(o.tasks) > 0:
+		o.logf("[Hierarchical Mode] Manager is planning task execution...")
+		// If we have predefined tasks, let manager assign agents
+		results, err = o.orchestratePredefinedTasks(ctx)
+	case o.goal != "":
+		// If we only have a goal, let manager decompose it into tasks
+		o.logf("[Hierarchical Mode] Manager is planning task execution...")
+		results, err = o.orchestrateFromGoal(ctx)
+	default:
+		return nil, errors.InvalidConfig("hierarchical_mode", "requires either tasks or a goal").
+			WithContext("has_tasks", len(o.tasks) > 0).
+			WithContext("has_goal", o.goal != "")
 	}
-
-	// If we only have a goal, let manager decompose it into tasks
-	if o.goal != "" {
-		return o.orchestrateFromGoal(ctx)
+	if err != nil || !o.synthesis {
+		return results, err
 	}
 
-	return nil, errors.InvalidConfig("hierarchical_mode", "requires either tasks or a goal").
-		WithContext("has_tasks", len(o.tasks) > 0).
-		WithContext("has_goal", o.goal != "")
+	final, err := o.synthesize(ctx, goal, results)
+	if err != nil {
+		return results, err
+	}
+	o.setFinal(final)
+	return results, nil
 }
 
 // orchestratePredefinedTasks assigns agents to predefined tasks using manager LLM
 func (o *Orchestrator) orchestratePredefinedTasks(ctx context.Context) ([]*TaskResult, error) {
 	results := make([]*TaskResult, 0, len(o.tasks))
 
-	// Build agent descriptions for the manager
-	agentDescriptions := o.buildAgentDescriptions()
-
 	for i, t := range o.tasks {
-		select {
-		case <-ctx.Done():
-			return results, ctx.Err()
-		default:
+		if err := o.canSchedule(ctx); err != nil {
+			return results, err
 		}
 
 		// If task already has an agent assigned, use it
 		if t.Agent != nil {
-			fmt.Printf("\n[Task %d/%d] Using assigned agent '%s' for: %s\n", i+1, len(o.tasks), t.Agent.Name, t.Description)
+			o.logf("[Task %d/%d] Using assigned agent '%s' for: %s", i+1, len(o.tasks), t.Agent.Name, t.Description)
 			result, err := o.executeTask(ctx, t)
 			if err != nil {
 				return results, errors.Wrap(errors.ErrInternal, fmt.Sprintf("task %d failed", i), err).WithContext("task_index", i).WithContext("agent", t.Agent.Name)
 			}
 			results = append(results, result)
-			fmt.Printf("[Task %d/%d] Completed\n", i+1, len(o.tasks))
+			o.logf("[Task %d/%d] Completed", i+1, len(o.tasks))
+			continue
+		}
+
+		// Don't pay for a manager decision on a task that won't be executed
+		if reused := o.reusedResult(t); reused != nil {
+			results = append(results, reused)
+			o.recordResult(reused)
 			continue
 		}
 
+		// Only agents with the task's required skills are candidates
+		candidates := o.agentsWithSkills(t.Skills)
+		if len(candidates) == 0 {
+			return results, errors.Validationf("no agent has the skills required by task %d: %s", i, strings.Join(t.Skills, ", ")).
+				WithContext("task_index", i).
+				WithContext("skills", t.Skills)
+		}
+
 		// Ask manager to select the best agent
-		selectedAgent, err := o.selectAgentForTask(ctx, t, agentDescriptions)
+		selectedAgent, assignment, err := o.selectAgentForTask(ctx, t, candidates)
 		if err != nil {
 			return results, errors.Wrap(errors.ErrInternal, fmt.Sprintf("manager failed to select agent for task %d", i), err).
 				WithContext("task_index", i).
 				WithContext("task_description", t.Description)
 		}
 
-		fmt.Printf("\n[Task %d/%d] Manager assigned '%s' for: %s\n", i+1, len(o.tasks), selectedAgent.Name, t.Description)
+		o.logf("[Task %d/%d] Manager assigned '%s' for: %s", i+1, len(o.tasks), selectedAgent.Name, t.Description)
 
 		// Create a new task with the selected agent
 		assignedTask := t.WithAgent(selectedAgent)
@@ -216,8 +875,9 @@ func (o *Orchestrator) orchestratePredefinedTasks(ctx context.Context) ([]*TaskR
 		if err != nil {
 			return results, fmt.Errorf("task %d failed: %w", i, err)
 		}
+		result.Assignment = assignment
 		results = append(results, result)
-		fmt.Printf("[Task %d/%d] Completed\n", i+1, len(o.tasks))
+		o.logf("[Task %d/%d] Completed", i+1, len(o.tasks))
 	}
 
 	return results, nil
@@ -225,8 +885,8 @@ func (o *Orchestrator) orchestratePredefinedTasks(ctx context.Context) ([]*TaskR
 
 // orchestrateFromGoal decomposes a high-level goal into tasks and executes them
 func (o *Orchestrator) orchestrateFromGoal(ctx context.Context) ([]*TaskResult, error) {
-	fmt.Printf("\n[Goal] %s\n", o.goal)
-	fmt.Println("[Manager] Decomposing goal into tasks...")
+	o.logf("[Goal] %s", o.goal)
+	o.logf("[Manager] Decomposing goal into tasks...")
 
 	// Build agent descriptions
 	agentDescriptions := o.buildAgentDescriptions()
@@ -237,35 +897,48 @@ func (o *Orchestrator) orchestrateFromGoal(ctx context.Context) ([]*TaskResult,
 		return nil, fmt.Errorf("manager failed to create execution plan: %w", err)
 	}
 
-	if o.verbose {
-		fmt.Printf("[Manager] Created plan with %d tasks\n", len(plan))
+	o.logf("[Manager] Created plan with %d tasks", len(plan))
+	return o.executePlan(ctx, o.goal, plan)
+}
+
+// executePlan runs the steps of an execution plan once approved, carrying
+// results forward to the steps that use context
+func (o *Orchestrator) executePlan(ctx context.Context, goal string, plan []PlanStep) ([]*TaskResult, error) {
+	plan, err := o.approvePlan(ctx, plan)
+	if err != nil {
+		return nil, err
 	}
+	o.setPlan(&Plan{Goal: goal, Steps: plan})
+	o.setProgressTotal(len(plan))
 
 	// Execute the plan
 	results := make([]*TaskResult, 0, len(plan))
 	previousResults := ""
+	var previousSources []citation.Source
 
 	for i, step := range plan {
-		select {
-		case <-ctx.Done():
-			return results, ctx.Err()
-		default:
+		if err := o.canSchedule(ctx); err != nil {
+			return results, err
 		}
 
-		fmt.Printf("\n[Step %d/%d] Agent '%s' executing: %s\n", i+1, len(plan), step.AgentName, step.TaskDescription)
+		o.logf("[Step %d/%d] Agent '%s' executing: %s", i+1, len(plan), step.AgentName, step.TaskDescription)
 
 		// Find the agent
-		selectedAgent := o.findAgentByName(step.AgentName)
-		if selectedAgent == nil {
-			// Fallback to first agent if not found
-			selectedAgent = o.agents[0]
-			fmt.Printf("[Warning] Agent '%s' not found, using '%s' instead\n", step.AgentName, selectedAgent.Name)
+		selectedAgent, _ := o.agents.Get(step.AgentName)
+		if selectedAgent == nil || !selectedAgent.HasSkills(step.Skills) {
+			// Fallback to the first agent with the step's skills, or the first agent
+			fallback := o.agents.All()[0]
+			if candidates := o.agentsWithSkills(step.Skills); len(candidates) > 0 {
+				fallback = candidates[0]
+			}
+			o.logf("[Warning] Agent '%s' not found or lacks skills %v, using '%s' instead", step.AgentName, step.Skills, fallback.Name)
+			selectedAgent = fallback
 		}
 
 		// Create task with context from previous results
 		taskDesc := step.TaskDescription
 		if previousResults != "" && step.UseContext {
-			taskDesc = fmt.Sprintf("%s\n\nContext from previous tasks:\n%s", taskDesc, previousResults)
+			taskDesc = taskDesc + contextSeparator + previousResults
 		}
 
 		newTask := task.New(task.Config{
@@ -281,9 +954,15 @@ func (o *Orchestrator) orchestrateFromGoal(ctx context.Context) ([]*TaskResult,
 				WithContext("agent", selectedAgent.Name)
 		}
 
+		if previousResults != "" && step.UseContext {
+			result.Sources = citation.Merge(previousSources, result.Sources)
+		}
+		previousSources = citation.Merge(previousSources, result.Sources)
+
 		results = append(results, result)
 		previousResults += fmt.Sprintf("\n--- %s (by %s) ---\n%s\n", step.TaskDescription, step.AgentName, result.Result)
-		fmt.Printf("[Step %d/%d] Completed\n", i+1, len(plan))
+		previousResults = o.compactContext(ctx, previousResults)
+		o.logf("[Step %d/%d] Completed", i+1, len(plan))
 	}
 
 	return results, nil
@@ -291,99 +970,91 @@ func (o *Orchestrator) orchestrateFromGoal(ctx context.Context) ([]*TaskResult,
 
 // PlanStep represents a single step in the execution plan
 type PlanStep struct {
-	TaskDescription string `json:"task_description"`
-	AgentName       string `json:"agent_name"`
-	ExpectedOutput  string `json:"expected_output"`
-	UseContext      bool   `json:"use_context"`
+	TaskDescription string   `json:"task_description" yaml:"task_description"`
+	AgentName       string   `json:"agent_name" yaml:"agent_name"`
+	ExpectedOutput  string   `json:"expected_output" yaml:"expected_output"`
+	UseContext      bool     `json:"use_context" yaml:"use_context"`
+	Skills          []string `json:"skills,omitempty" yaml:"skills,omitempty"`
+}
+
+// contextSeparator introduces carried-forward results in a step's task description
+const contextSeparator = "\n\nContext from previous tasks:\n"
+
+// compactContext folds carried-forward results into a rolling summary once
+// they exceed the context budget. Compression failures fall back to the
+// heuristic so a summarizer outage does not fail the run.
+func (o *Orchestrator) compactContext(ctx context.Context, carried string) string {
+	if o.contextBudget <= 0 || o.tokenizer.CountTokens(carried) <= o.contextBudget {
+		return carried
+	}
+
+	compacted, err := o.compressor.Compress(ctx, carried, o.contextBudget)
+	if err != nil {
+		o.logf("[Manager] Context summarization failed, truncating instead: %v", err)
+		compacted, _ = llm.Compress(ctx, carried, o.contextBudget)
+		return compacted
+	}
+
+	o.logf("[Manager] Compacted carried context from ~%d to ~%d tokens",
+		o.tokenizer.CountTokens(carried), o.tokenizer.CountTokens(compacted))
+	return "\n--- Summary of earlier steps ---\n" + compacted + "\n"
 }
 
 // buildAgentDescriptions creates a description of all available agents
 func (o *Orchestrator) buildAgentDescriptions() string {
+	return describeAgents(o.agents.All())
+}
+
+// describeAgents creates a numbered description of the given agents
+func describeAgents(agents []*agent.Agent) string {
 	var sb strings.Builder
 	sb.WriteString("Available Agents:\n")
-	for i, a := range o.agents {
+	for i, a := range agents {
 		sb.WriteString(fmt.Sprintf("%d. Name: %s\n", i+1, a.Name))
 		sb.WriteString(fmt.Sprintf("   Role: %s\n", a.Role))
 		sb.WriteString(fmt.Sprintf("   Goal: %s\n", a.Goal))
 		if a.Backstory != "" {
 			sb.WriteString(fmt.Sprintf("   Backstory: %s\n", a.Backstory))
 		}
+		if len(a.Skills) > 0 {
+			sb.WriteString(fmt.Sprintf("   Skills: %s\n", strings.Join(a.Skills, ", ")))
+		}
 		sb.WriteString("\n")
 	}
 	return sb.String()
 }
 
-// selectAgentForTask asks the manager LLM to select the best agent for a task
-func (o *Orchestrator) selectAgentForTask(ctx context.Context, t *task.Task, agentDescriptions string) (*agent.Agent, error) {
-	prompt := fmt.Sprintf(`You are a manager responsible for assigning tasks to the best-suited agent.
-
-%s
-Task to assign:
-Description: %s
-Expected Output: %s
-
-Based on the agents' roles and goals, which agent is best suited for this task?
-Respond with ONLY the agent's name, nothing else.`, agentDescriptions, t.Description, t.ExpectedOutput)
-
-	response, err := o.managerLLM.Generate(ctx, prompt)
-	if err != nil {
-		return nil, err
-	}
-
-	// Find the agent by name
-	agentName := strings.TrimSpace(response)
-	for _, a := range o.agents {
-		if strings.EqualFold(a.Name, agentName) || strings.Contains(strings.ToLower(response), strings.ToLower(a.Name)) {
-			return a, nil
+// agentsWithSkills returns the agents that have every required skill
+func (o *Orchestrator) agentsWithSkills(skills []string) []*agent.Agent {
+	agents := o.agents.All()
+	matched := make([]*agent.Agent, 0, len(agents))
+	for _, a := range agents {
+		if a.HasSkills(skills) {
+			matched = append(matched, a)
 		}
 	}
-
-	// Fallback to first agent if no match
-	if o.verbose {
-		fmt.Printf("[Manager] Could not match agent '%s', using '%s'\n", agentName, o.agents[0].Name)
-	}
-	return o.agents[0], nil
+	return matched
 }
 
 // createExecutionPlan asks the manager LLM to create an execution plan from a goal
 func (o *Orchestrator) createExecutionPlan(ctx context.Context, agentDescriptions string) ([]PlanStep, error) {
-	prompt := fmt.Sprintf(`You are a manager responsible for breaking down goals into tasks and assigning them to agents.
-
-%s
-Goal to achieve: %s
-
-Create an execution plan to achieve this goal. For each step, specify:
-1. The task description
-2. Which agent should handle it (use exact agent name)
-3. Expected output
-4. Whether it needs context from previous tasks (true/false)
-
-Respond in JSON format as an array of steps:
-[
-  {
-    "task_description": "...",
-    "agent_name": "...",
-    "expected_output": "...",
-    "use_context": false
-  }
-]
-
-Keep the plan focused and efficient. Only include necessary steps.`, agentDescriptions, o.goal)
-
-	response, err := o.managerLLM.Generate(ctx, prompt)
+	prompt, err := o.planPrompt.Render(PlanningPromptData{
+		Agents:    agentDescriptions,
+		AgentList: o.agents.All(),
+		Goal:      o.goal,
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Extract JSON from response
-	jsonStr := extractJSON(response)
-	if jsonStr == "" {
-		return nil, errors.Internal("manager did not return valid JSON plan")
+	response, err := o.managerLLM.Generate(ctx, prompt)
+	if err != nil {
+		return nil, err
 	}
 
 	var plan []PlanStep
-	if err := json.Unmarshal([]byte(jsonStr), &plan); err != nil {
-		return nil, errors.Wrap(errors.ErrInternal, "failed to parse execution plan", err).WithContext("response_length", len(jsonStr))
+	if err := jsonutil.Unmarshal(response, &plan); err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to parse execution plan", err).WithContext("response_length", len(response))
 	}
 
 	if len(plan) == 0 {
@@ -393,50 +1064,184 @@ Keep the plan focused and efficient. Only include necessary steps.`, agentDescri
 	return plan, nil
 }
 
-// findAgentByName finds an agent by name (case-insensitive)
-func (o *Orchestrator) findAgentByName(name string) *agent.Agent {
-	for _, a := range o.agents {
-		if strings.EqualFold(a.Name, name) {
-			return a
-		}
+// executeTask executes a single task and accepts its result
+func (o *Orchestrator) executeTask(ctx context.Context, t *task.Task) (*TaskResult, error) {
+	result, err := o.runTask(ctx, t)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	o.acceptResult(result)
+	return result, nil
 }
 
-// extractJSON extracts JSON array from a string that might contain other text
-func extractJSON(s string) string {
-	start := strings.Index(s, "[")
-	if start == -1 {
-		return ""
+// acceptResult records a completed result for checkpoints and stores it for
+// reuse by later runs. Results of skipped tasks are neither; reused results
+// are already stored.
+func (o *Orchestrator) acceptResult(result *TaskResult) {
+	if result.Status != StatusCompleted {
+		return
 	}
-
-	depth := 0
-	for i := start; i < len(s); i++ {
-		switch s[i] {
-		case '[':
-			depth++
-		case ']':
-			depth--
-			if depth == 0 {
-				return s[start : i+1]
-			}
-		}
+	if o.results != nil && !result.Reused {
+		o.results.Store(result.Key, result)
 	}
-	return ""
+	o.recordResult(result)
 }
 
-// executeTask executes a single task
-func (o *Orchestrator) executeTask(ctx context.Context, t *task.Task) (*TaskResult, error) {
-	result, err := t.Execute(ctx)
+// runTask executes a single task without accepting its result, which the
+// caller does once the run keeps it
+func (o *Orchestrator) runTask(ctx context.Context, t *task.Task) (*TaskResult, error) {
+	if err := o.startTask(); err != nil {
+		return nil, err
+	}
+	defer o.finishTask()
+	id := runid.FromContext(ctx)
+	if reused := o.reusedResult(t); reused != nil {
+		o.reuseResult(ctx, t, reused)
+		return reused, nil
+	}
+	decision, err := debugger.Pause(ctx, debugger.Step{Kind: debugger.KindTask, RunID: id, Agent: agentName(t), Prompt: t.Description})
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case decision.Action == debugger.Skip:
+		o.logf("[Debug] Skipped task: %s", t.Description)
+		o.taskFinished(o.taskStarted(t))
+		return &TaskResult{Task: t, Agent: agentName(t), Status: StatusSkipped, RunID: id, Key: t.Key()}, nil
+	case decision.Prompt != t.Description:
+		edited := *t
+		edited.Description = decision.Prompt
+		t = &edited
+	}
+
+	start := o.taskStarted(t)
+	defer o.taskFinished(start)
+	if t.Agent != nil {
+		o.agentStarted(t.Agent.Name)
+		defer func() { o.agentFinished(t.Agent.Name, time.Since(start)) }()
+	}
+
+	// Count the task's own usage; it still counts toward the run's limits
+	meter := limits.FromContext(ctx).Scope()
+	ctx = limits.NewContext(ctx, meter)
+	sources := citation.NewTracker()
+	ctx = citation.NewContext(ctx, sources)
+
+	ev := events.New(events.TaskStarted, id)
+	ev.Task = t.Description
+	ev.Agent = agentName(t)
+	o.emit(ctx, ev)
+
+	shareCtx, share, cancelShare := o.taskShare(ctx)
+	defer cancelShare()
+	shareCtx = postprocess.NewTaskContext(shareCtx, t.Key())
+	if share > 0 {
+		o.logf("[Task] Time share before the run deadline: %v", share.Round(time.Millisecond))
+	}
+
+	var result string
+	if decision.Action == debugger.Respond {
+		result = decision.Response
+	} else {
+		result, err = t.Execute(shareCtx)
+	}
+	if err == nil {
+		result, err = o.postProcess.Process(shareCtx, result)
+	}
+	if err != nil && shareExceeded(ctx, shareCtx, share) {
+		err = shareTimeout(share)
+	}
+	var ref *artifact.Ref
+	if err == nil {
+		result, ref, err = o.offload(ctx, t, result)
+	}
+	ev = events.New(events.TaskCompleted, id)
+	ev.Task = t.Description
+	ev.Agent = agentName(t)
+	ev.DurationMS = time.Since(start).Milliseconds()
+	ev.Retry = retryFromContext(ctx)
+	o.setUsage(&ev, meter.Usage())
 	if err != nil {
+		ev.Type = events.TaskFailed
+		ev.Error = err.Error()
+		ev.ErrorFingerprint = errors.Fingerprint(err)
+		o.emit(ctx, ev)
 		return nil, err
 	}
+	ev.Result = result
+	o.emit(ctx, ev)
+
+	taskResult := &TaskResult{
+		Task:     t,
+		Result:   result,
+		Agent:    t.Agent.Name,
+		Status:   StatusCompleted,
+		RunID:    id,
+		Key:      t.Key(),
+		Artifact: ref,
+		Sources:  sources.Sources(),
+	}
+	return taskResult, nil
+}
+
+// cancelledResult builds the result reported for a task that was interrupted
+func cancelledResult(t *task.Task) *TaskResult {
+	return &TaskResult{
+		Task:   t,
+		Agent:  agentName(t),
+		Status: StatusCancelled,
+	}
+}
 
+// failedResult builds the result reported for a task that returned an error
+func failedResult(t *task.Task, err error) *TaskResult {
 	return &TaskResult{
 		Task:   t,
-		Result: result,
-		Agent:  t.Agent.Name,
-	}, nil
+		Agent:  agentName(t),
+		Status: StatusFailed,
+		Error:  err,
+	}
+}
+
+// agentName returns the name of the task's agent, or "" when unassigned
+func agentName(t *task.Task) string {
+	if t.Agent == nil {
+		return ""
+	}
+	return t.Agent.Name
+}
+
+// TaskStatus describes the outcome of a task
+type TaskStatus int
+
+const (
+	// StatusPending means the task has not finished
+	StatusPending TaskStatus = iota
+	// StatusCompleted means the task finished successfully
+	StatusCompleted
+	// StatusFailed means the task returned an error
+	StatusFailed
+	// StatusCancelled means the task was interrupted before finishing
+	StatusCancelled
+	// StatusSkipped means the task was skipped in a debugging session
+	StatusSkipped
+)
+
+func (s TaskStatus) String() string {
+	switch s {
+	case StatusPending:
+		return "PENDING"
+	case StatusCompleted:
+		return "COMPLETED"
+	case StatusFailed:
+		return "FAILED"
+	case StatusCancelled:
+		return "CANCELLED"
+	case StatusSkipped:
+		return "SKIPPED"
+	default:
+		return "UNKNOWN"
+	}
 }
 
 // TaskResult holds the result of a task execution
@@ -444,15 +1249,72 @@ type TaskResult struct {
 	Task   *task.Task
 	Result string
 	Agent  string
+	Status TaskStatus
+	RunID  string
+
+	// Assignment is set when the manager chose the agent in hierarchical mode
+	Assignment *Assignment
+
+	// Key is the task's idempotency key
+	Key string
+	// Reused is true when the result was taken from a checkpoint or result
+	// store instead of executing the task
+	Reused bool
+	// Artifact references the full output when it was too large to keep in
+	// memory; Result then holds a preview
+	Artifact *artifact.Ref
+	// Sources are the documents cited by tools during the task, and by the
+	// tasks whose results it was given as context
+	Sources []citation.Source
+	// Error is set when Status is StatusFailed
+	Error error
+	// Index is the task's position in Config.Tasks, set in parallel mode
+	Index int
+}
+
+// Title returns the task's description without the results of previous
+// tasks carried forward as context
+func (r *TaskResult) Title() string {
+	if r.Task == nil {
+		return ""
+	}
+	title, _, _ := strings.Cut(r.Task.Description, contextSeparator)
+	return title
 }
 
 // String returns a formatted string of all results
 func FormatResults(results []*TaskResult) string {
-	output := "\n=== EXECUTION RESULTS ===\n\n"
+	return FormatResultsIn(results, locale.English)
+}
+
+// FormatResultsIn formats results with localized headings
+func FormatResultsIn(results []*TaskResult, labels locale.Labels) string {
+	output := fmt.Sprintf("\n=== %s ===\n\n", labels.Results)
 	for i, r := range results {
-		output += fmt.Sprintf("Task %d: %s\n", i+1, r.Task.Description)
-		output += fmt.Sprintf("Agent: %s\n", r.Agent)
-		output += fmt.Sprintf("Result:\n%s\n", r.Result)
+		if r == nil {
+			output += fmt.Sprintf("%s %d: %s\n", labels.Task, i+1, labels.NoResult)
+			output += "------------------------\n\n"
+			continue
+		}
+		description := ""
+		if r.Task != nil {
+			description = r.Task.Description
+		}
+		output += fmt.Sprintf("%s %d: %s\n", labels.Task, i+1, description)
+		output += fmt.Sprintf("%s: %s\n", labels.Agent, r.Agent)
+		if r.Status != StatusCompleted {
+			output += fmt.Sprintf("%s: %s\n", labels.Status, r.Status)
+		}
+		if r.Error != nil {
+			output += fmt.Sprintf("%s: %v\n", labels.Error, r.Error)
+		}
+		output += fmt.Sprintf("%s:\n%s\n", labels.Result, r.Result)
+		if len(r.Sources) > 0 {
+			output += fmt.Sprintf("%s:\n", labels.Sources)
+			for j, s := range r.Sources {
+				output += fmt.Sprintf("[%d] %s\n", j+1, s)
+			}
+		}
 		output += "------------------------\n\n"
 	}
 	return output
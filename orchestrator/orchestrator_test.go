@@ -0,0 +1,1902 @@
+package orchestrator
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/citation"
+	"github.com/counhopig/gittyai/debugger"
+	"github.com/counhopig/gittyai/distributed"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/limits"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/prompts"
+	"github.com/counhopig/gittyai/task"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// mockLLM returns a canned response, or blocks until ctx is done when the
+// prompt contains "block"
+type mockLLM struct {
+	response string
+}
+
+func (m *mockLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "block") {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return m.response, nil
+}
+
+func newTestAgent(name string) *agent.Agent {
+	return agent.New(agent.Config{
+		Name: name,
+		Role: "tester",
+		Goal: "test",
+		LLM:  &mockLLM{response: "done"},
+	})
+}
+
+func TestExecuteParallel_Cancellation(t *testing.T) {
+	a := newTestAgent("worker")
+	tasks := []*task.Task{
+		task.New(task.Config{Description: "quick", Agent: a}),
+		task.New(task.Config{Description: "block forever", Agent: a}),
+	}
+
+	orch := New(Config{
+		Agents:  []*agent.Agent{a},
+		Tasks:   tasks,
+		Process: Parallel,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	results, err := orch.Kickoff(ctx)
+	if err == nil {
+		t.Fatal("Kickoff() expected error after cancellation, got nil")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("Kickoff() took %v after cancellation, want prompt return", elapsed)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+	if results[0] == nil || results[0].Status != StatusCompleted {
+		t.Errorf("results[0] = %+v, want completed", results[0])
+	}
+	if results[1] == nil || results[1].Status != StatusCancelled {
+		t.Errorf("results[1] = %+v, want cancelled", results[1])
+	}
+}
+
+func TestShutdown_DeadlineCancelsInFlight(t *testing.T) {
+	a := newTestAgent("worker")
+	tasks := []*task.Task{
+		task.New(task.Config{Description: "first", Agent: a}),
+		task.New(task.Config{Description: "block until shutdown", Agent: a}),
+		task.New(task.Config{Description: "never scheduled", Agent: a}),
+	}
+
+	orch := New(Config{
+		Agents:  []*agent.Agent{a},
+		Tasks:   tasks,
+		Process: Sequential,
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := orch.Kickoff(context.Background())
+		done <- err
+	}()
+
+	// Give the run time to reach the blocking task
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	cp, err := orch.Shutdown(ctx)
+	if err == nil {
+		t.Error("Shutdown() expected deadline error, got nil")
+	}
+	if len(cp.Results) != 1 || cp.Results[0].Task.Description != "first" {
+		t.Errorf("Shutdown() results = %+v, want only 'first'", cp.Results)
+	}
+	if len(cp.Pending) != 2 {
+		t.Errorf("Shutdown() pending = %d tasks, want 2", len(cp.Pending))
+	}
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Kickoff() did not return after shutdown")
+	}
+
+	if _, err := orch.Kickoff(context.Background()); err == nil {
+		t.Error("Kickoff() after Shutdown expected error, got nil")
+	}
+}
+
+func TestCheckpoint_MatchesTasksByKey(t *testing.T) {
+	a := newTestAgent("worker")
+	tasks := []*task.Task{
+		task.New(task.Config{Description: "Summarize", ExpectedOutput: "one line", Agent: a}),
+		task.New(task.Config{Description: "Summarize", ExpectedOutput: "one paragraph", Agent: a}),
+		task.New(task.Config{Description: "Translate", Agent: a}),
+		task.New(task.Config{Description: "Translate", Agent: a}),
+	}
+	orch := New(Config{Agents: []*agent.Agent{a}, Tasks: tasks, Process: Sequential})
+	orch.recordResult(&TaskResult{Task: tasks[1], Status: StatusCompleted, Key: tasks[1].Key()})
+	orch.recordResult(&TaskResult{Task: tasks[2], Status: StatusCompleted, Key: tasks[2].Key()})
+
+	// Tasks sharing a description, or identical tasks, stay pending until
+	// each of them completed
+	cp := orch.checkpoint()
+	if len(cp.Pending) != 2 || cp.Pending[0] != tasks[0] || cp.Pending[1] != tasks[3] {
+		t.Errorf("checkpoint() pending = %+v, want the first and last tasks", cp.Pending)
+	}
+}
+
+func TestKickoff_FlushesBufferedMemory(t *testing.T) {
+	ctx := context.Background()
+	store := memory.New()
+	a := newTestAgent("worker")
+	a.Memory = memory.NewBuffered(store, memory.BufferConfig{FlushInterval: -1})
+
+	orch := New(Config{
+		Agents:  []*agent.Agent{a},
+		Tasks:   []*task.Task{task.New(task.Config{Description: "remember this", Agent: a})},
+		Process: Sequential,
+	})
+	if _, err := orch.Kickoff(ctx); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	records, _ := store.Retrieve(ctx, "", 0)
+	if len(records) != 1 || !strings.Contains(records[0].Content, "remember this") {
+		t.Errorf("stored records = %v, want the task's record", records)
+	}
+}
+
+func TestShutdown_FlushesMemoryAfterDeadline(t *testing.T) {
+	store := memory.New()
+	a := newTestAgent("worker")
+	a.Memory = memory.NewBuffered(store, memory.BufferConfig{FlushInterval: -1})
+	if err := a.Memory.Store(context.Background(), memory.Record{AgentName: "worker", Content: "remember this"}); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+	orch := New(Config{Agents: []*agent.Agent{a}})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := orch.Shutdown(ctx); err != nil && err != context.Canceled {
+		t.Errorf("Shutdown() error = %v, want at most the context's error", err)
+	}
+	records, _ := store.Retrieve(context.Background(), "", 0)
+	if len(records) != 1 {
+		t.Errorf("stored records = %v, want the buffered record flushed", records)
+	}
+}
+
+func TestProgress_Sequential(t *testing.T) {
+	a := newTestAgent("worker")
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: a}),
+		},
+	})
+
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	p := orch.Progress()
+	if p.Completed != 2 || p.Total != 2 || p.Percent() != 100 {
+		t.Errorf("Progress() = %+v, want 2/2 completed", p)
+	}
+	if p.CurrentTask != "two" {
+		t.Errorf("Progress().CurrentTask = %q, want %q", p.CurrentTask, "two")
+	}
+
+	// Two starts and two finishes were published
+	if got := len(orch.ProgressUpdates()); got != 4 {
+		t.Errorf("len(ProgressUpdates()) = %d, want 4", got)
+	}
+}
+
+func TestProgress_ETAFromMeanDuration(t *testing.T) {
+	orch := New(Config{})
+	orch.setProgressTotal(4)
+	orch.taskFinished(time.Now().Add(-2 * time.Second))
+	orch.taskFinished(time.Now().Add(-4 * time.Second))
+
+	// Two tasks remain at the mean of 3s each
+	if eta := orch.Progress().ETA; eta < 6*time.Second || eta > 7*time.Second {
+		t.Errorf("Progress().ETA = %v, want about 6s", eta)
+	}
+}
+
+func TestExecuteDistributed_MemoryQueue(t *testing.T) {
+	a := newTestAgent("worker")
+	queue := distributed.NewMemoryQueue(10)
+
+	w, err := distributed.NewWorker(distributed.WorkerConfig{
+		Name:        "w1",
+		Queue:       queue,
+		Agents:      []*agent.Agent{a},
+		Concurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("NewWorker() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx) }()
+
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: a}),
+		},
+		Process: Distributed,
+		Queue:   queue,
+	})
+
+	results, err := orch.Kickoff(ctx)
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	for i, r := range results {
+		if r == nil || r.Status != StatusCompleted || r.Result != "done" {
+			t.Errorf("results[%d] = %+v, want completed with 'done'", i, r)
+		}
+	}
+}
+
+func TestExecuteDistributed_ReusesStoredResults(t *testing.T) {
+	a := newTestAgent("worker")
+	queue := distributed.NewMemoryQueue(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var mu sync.Mutex
+	var jobs []string
+	go func() {
+		for {
+			job, err := queue.Dequeue(ctx)
+			if err != nil {
+				return
+			}
+			mu.Lock()
+			jobs = append(jobs, job.Description)
+			mu.Unlock()
+			_ = queue.Complete(ctx, distributed.JobResult{JobID: job.ID, Worker: "w1", Agent: job.AgentName, Output: "done"})
+		}
+	}()
+
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: a}),
+		},
+		Process: Distributed,
+		Queue:   queue,
+		Results: NewMemoryResultStore(),
+	})
+
+	if _, err := orch.Kickoff(ctx); err != nil {
+		t.Fatalf("first Kickoff() unexpected error: %v", err)
+	}
+	results, err := orch.Kickoff(ctx)
+	if err != nil {
+		t.Fatalf("second Kickoff() unexpected error: %v", err)
+	}
+	for i, r := range results {
+		if r == nil || !r.Reused || r.Result != "done" {
+			t.Errorf("results[%d] = %+v, want the reused result", i, r)
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(jobs) != 2 {
+		t.Errorf("jobs = %q, want only the first run's two jobs", jobs)
+	}
+}
+
+func TestExecuteDistributed_JobTimeout(t *testing.T) {
+	a := newTestAgent("worker")
+	// No worker consumes the queue, as if the job was lost with its worker
+	orch := New(Config{
+		Agents:     []*agent.Agent{a},
+		Tasks:      []*task.Task{task.New(task.Config{Description: "one", Agent: a})},
+		Process:    Distributed,
+		Queue:      distributed.NewMemoryQueue(10),
+		JobTimeout: 50 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	results, err := orch.Kickoff(ctx)
+	if !stderrors.Is(err, errors.New(errors.ErrTimeout, "")) {
+		t.Fatalf("Kickoff() error = %v, want ErrTimeout", err)
+	}
+	if len(results) != 1 || results[0] == nil || results[0].Status != StatusFailed {
+		t.Errorf("results = %+v, want the task failed", results)
+	}
+}
+
+// eventLog records the types of the events published to it
+type eventLog struct {
+	mu    sync.Mutex
+	types []events.Type
+}
+
+func (l *eventLog) Publish(ctx context.Context, event events.Event) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.types = append(l.types, event.Type)
+	return nil
+}
+
+func TestExecuteDistributed_FailedJob(t *testing.T) {
+	a := newTestAgent("worker")
+	queue := distributed.NewMemoryQueue(10)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// Workers fail the job of the second task
+	go func() {
+		for {
+			job, err := queue.Dequeue(ctx)
+			if err != nil {
+				return
+			}
+			result := distributed.JobResult{JobID: job.ID, Worker: "w1", Agent: job.AgentName, Output: "done"}
+			if job.Description == "two" {
+				result.Output, result.Error = "", "model unavailable"
+			}
+			_ = queue.Complete(ctx, result)
+		}
+	}()
+
+	log := &eventLog{}
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: a}),
+		},
+		Process: Distributed,
+		Queue:   queue,
+		Events:  log,
+	})
+
+	results, err := orch.Kickoff(ctx)
+	if err == nil || !strings.Contains(err.Error(), "model unavailable") {
+		t.Fatalf("Kickoff() error = %v, want the worker's error", err)
+	}
+	if len(results) != 2 || results[0].Status != StatusCompleted || results[1] == nil || results[1].Status != StatusFailed || results[1].Error == nil {
+		t.Fatalf("results = %+v, want completed then failed", results)
+	}
+
+	counts := make(map[events.Type]int)
+	for _, typ := range log.types {
+		counts[typ]++
+	}
+	if counts[events.TaskStarted] != 2 || counts[events.TaskCompleted] != 1 || counts[events.TaskFailed] != 1 {
+		t.Errorf("events = %v, want both tasks started, one completed and one failed", log.types)
+	}
+}
+
+// failingCompressor always fails, to exercise the heuristic fallback
+type failingCompressor struct{}
+
+func (failingCompressor) Compress(ctx context.Context, text string, targetTokens int) (string, error) {
+	return "", context.DeadlineExceeded
+}
+
+func TestCompactContext_Budget(t *testing.T) {
+	carried := strings.Repeat("\n--- step (by worker) ---\nlong result text\n", 100)
+	ctx := context.Background()
+
+	unlimited := New(Config{})
+	if got := unlimited.compactContext(ctx, carried); got != carried {
+		t.Errorf("compactContext() without budget changed the context")
+	}
+
+	summarizer := &mockLLM{response: "steps produced long results"}
+	orch := New(Config{ManagerLLM: summarizer, ContextBudget: 50})
+	got := orch.compactContext(ctx, carried)
+	if !strings.Contains(got, "Summary of earlier steps") || !strings.Contains(got, "steps produced long results") {
+		t.Errorf("compactContext() = %q, want manager summary", got)
+	}
+
+	fallback := New(Config{ContextBudget: 50, Compressor: failingCompressor{}})
+	got = fallback.compactContext(ctx, carried)
+	if llm.EstimateTokens(got) > 50 {
+		t.Errorf("compactContext() fallback = ~%d tokens, want <= 50", llm.EstimateTokens(got))
+	}
+}
+
+// managerLLM plans two steps for the "worker" agent and answers any other
+// prompt with a final answer
+type managerLLM struct {
+	prompts []string
+}
+
+func (m *managerLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.prompts = append(m.prompts, prompt)
+	if strings.Contains(prompt, "Create an execution plan") {
+		return `[{"task_description":"research","agent_name":"worker","expected_output":"notes"},
+{"task_description":"write","agent_name":"worker","expected_output":"draft","use_context":true}]`, nil
+	}
+	return "final answer", nil
+}
+
+func TestHierarchical_SynthesizesFinalResult(t *testing.T) {
+	manager := &managerLLM{}
+	orch := New(Config{
+		Agents:     []*agent.Agent{newTestAgent("worker")},
+		Process:    Hierarchical,
+		ManagerLLM: manager,
+		Goal:       "write a report",
+		Synthesize: true,
+	})
+
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("len(results) = %d, want 2", len(results))
+	}
+
+	final := orch.FinalResult()
+	if final == nil {
+		t.Fatal("FinalResult() = nil, want synthesized answer")
+	}
+	if final.Answer != "final answer" || final.Steps != 2 || final.RunID != orch.RunID() {
+		t.Errorf("FinalResult() = %+v, want answer from 2 steps of the run", final)
+	}
+	last := manager.prompts[len(manager.prompts)-1]
+	if !strings.Contains(last, "write a report") || !strings.Contains(last, "--- write (by worker) ---") {
+		t.Errorf("synthesis prompt missing goal or step outputs: %q", last)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	candidates := []*agent.Agent{newTestAgent("writer"), newTestAgent("reviewer")}
+
+	tests := []struct {
+		name         string
+		response     string
+		wantAgent    string
+		wantFallback bool
+		wantConf     float64
+	}{
+		{"index with reason naming another agent", `{"agent": 2, "confidence": 0.8, "reason": "the writer is busy"}`, "reviewer", false, 0.8},
+		{"json wrapped in prose", "Sure: {\"agent\": 1, \"confidence\": 1.5}", "writer", false, 1},
+		{"bare name", "Reviewer.", "reviewer", false, 0},
+		{"index out of range", `{"agent": 7, "confidence": 0.9}`, "writer", true, 0},
+		{"unparseable", "the reviewer, or maybe the writer", "writer", true, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			selected, assignment := parseSelection(tt.response, candidates)
+			if selected.Name != tt.wantAgent || assignment.Agent != tt.wantAgent {
+				t.Errorf("parseSelection() agent = %s, want %s", selected.Name, tt.wantAgent)
+			}
+			if assignment.Fallback != tt.wantFallback {
+				t.Errorf("parseSelection() Fallback = %v, want %v", assignment.Fallback, tt.wantFallback)
+			}
+			if assignment.Confidence != tt.wantConf {
+				t.Errorf("parseSelection() Confidence = %v, want %v", assignment.Confidence, tt.wantConf)
+			}
+		})
+	}
+}
+
+func TestHierarchical_RoutesBySkills(t *testing.T) {
+	writer := newTestAgent("writer")
+	writer.Skills = []string{"writing"}
+	analyst := newTestAgent("analyst")
+	analyst.Skills = []string{"SQL", "writing"}
+
+	manager := &managerLLM{}
+	orch := New(Config{
+		Agents:     []*agent.Agent{writer, analyst},
+		Tasks:      []*task.Task{task.New(task.Config{Description: "query sales", Skills: []string{"sql"}})},
+		Process:    Hierarchical,
+		ManagerLLM: manager,
+	})
+
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if results[0].Agent != "analyst" {
+		t.Errorf("task assigned to %q, want %q", results[0].Agent, "analyst")
+	}
+	if len(manager.prompts) != 0 {
+		t.Errorf("manager was asked %d times, want 0 with a single skilled candidate", len(manager.prompts))
+	}
+
+	orch = New(Config{
+		Agents:     []*agent.Agent{writer},
+		Tasks:      []*task.Task{task.New(task.Config{Description: "query sales", Skills: []string{"sql"}})},
+		Process:    Hierarchical,
+		ManagerLLM: manager,
+	})
+	if _, err := orch.Kickoff(context.Background()); err == nil {
+		t.Errorf("Kickoff() expected error when no agent has the required skills")
+	}
+}
+
+func TestParallel_BalancesUnassignedTasks(t *testing.T) {
+	a := newTestAgent("a")
+	a.Skills = []string{"research"}
+	b := newTestAgent("b")
+	b.Skills = []string{"research"}
+	c := newTestAgent("c")
+
+	tasks := make([]*task.Task, 4)
+	for i := range tasks {
+		tasks[i] = task.New(task.Config{Description: "research topic", Skills: []string{"research"}})
+	}
+
+	orch := New(Config{Agents: []*agent.Agent{a, b, c}, Tasks: tasks, Process: Parallel})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	counts := make(map[string]int)
+	for _, r := range results {
+		counts[r.Agent]++
+	}
+	if counts["a"] != 2 || counts["b"] != 2 || counts["c"] != 0 {
+		t.Errorf("assignments = %v, want 2 each for a and b", counts)
+	}
+
+	stats := orch.AgentStats()
+	if stats["a"].Completed != 2 || stats["a"].InFlight != 0 {
+		t.Errorf("AgentStats()[a] = %+v, want 2 completed and none in flight", stats["a"])
+	}
+}
+
+// causedBy reports whether any error in err's chain has the given code
+func causedBy(err error, code errors.ErrorCode) bool {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if errors.HasCode(err, code) {
+			return true
+		}
+	}
+	return false
+}
+
+func TestKickoff_EnforcesLimits(t *testing.T) {
+	a := newTestAgent("worker")
+	newOrch := func(l limits.Config) *Orchestrator {
+		return New(Config{
+			Agents: []*agent.Agent{a},
+			Tasks: []*task.Task{
+				task.New(task.Config{Description: "one", Agent: a}),
+				task.New(task.Config{Description: "two block", Agent: a}),
+			},
+			Limits: l,
+		})
+	}
+
+	orch := newOrch(limits.Config{MaxLLMCalls: 1})
+	_, err := orch.Kickoff(context.Background())
+	if !causedBy(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("Kickoff() error = %v, want ErrQuotaExceeded", err)
+	}
+	if got := orch.Usage().LLMCalls; got != 2 {
+		t.Errorf("Usage().LLMCalls = %d, want 2", got)
+	}
+
+	orch = newOrch(limits.Config{MaxWallTime: 50 * time.Millisecond})
+	_, err = orch.Kickoff(context.Background())
+	if !causedBy(err, errors.ErrTimeout) {
+		t.Fatalf("Kickoff() error = %v, want ErrTimeout", err)
+	}
+}
+
+// countingLLM counts calls and returns a fixed response
+type countingLLM struct {
+	calls atomic.Int32
+}
+
+func (m *countingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.calls.Add(1)
+	return "done", nil
+}
+
+func TestResume_SkipsCompletedTasks(t *testing.T) {
+	l := &countingLLM{}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: l})
+	tasks := []*task.Task{
+		task.New(task.Config{Description: "one", Agent: a}),
+		task.New(task.Config{Description: "two", Agent: a}),
+	}
+
+	cp := &Checkpoint{
+		Results: []*TaskResult{{Task: tasks[0], Result: "stored", Agent: "worker", Status: StatusCompleted}},
+		Pending: tasks[1:],
+	}
+	results, err := New(Config{Agents: []*agent.Agent{a}, Tasks: tasks}).Resume(context.Background(), cp)
+	if err != nil {
+		t.Fatalf("Resume() unexpected error: %v", err)
+	}
+	if !results[0].Reused || results[0].Result != "stored" {
+		t.Errorf("results[0] = %+v, want reused stored result", results[0])
+	}
+	if results[1].Reused || l.calls.Load() != 1 {
+		t.Errorf("LLM calls = %d, want 1 for the pending task only", l.calls.Load())
+	}
+
+	// With a result store, a retried run executes nothing again
+	orch := New(Config{Agents: []*agent.Agent{a}, Tasks: tasks, Results: NewMemoryResultStore()})
+	for i := 0; i < 2; i++ {
+		if _, err := orch.Kickoff(context.Background()); err != nil {
+			t.Fatalf("Kickoff() #%d unexpected error: %v", i+1, err)
+		}
+	}
+	if got := l.calls.Load(); got != 3 {
+		t.Errorf("LLM calls = %d, want 3 after two deduplicated runs", got)
+	}
+}
+
+func TestExecuteTask_OffloadsLargeOutput(t *testing.T) {
+	large := strings.Repeat("x", 5000)
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: &mockLLM{response: large}})
+	store, err := artifact.NewFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+
+	orch := New(Config{
+		Agents:            []*agent.Agent{a},
+		Tasks:             []*task.Task{task.New(task.Config{Description: "write", Agent: a})},
+		Artifacts:         store,
+		ArtifactThreshold: 4096,
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	r := results[0]
+	if r.Artifact == nil || r.Artifact.Size != 5000 {
+		t.Fatalf("Artifact = %+v, want a 5000 byte reference", r.Artifact)
+	}
+	if len(r.Result) >= len(large) {
+		t.Errorf("len(Result) = %d, want a preview", len(r.Result))
+	}
+	if content, err := orch.Content(context.Background(), r); err != nil || content != large {
+		t.Errorf("Content() = %d bytes, %v; want the full output", len(content), err)
+	}
+
+	// Thresholds below the preview size cut the preview to the threshold
+	a = agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: &mockLLM{response: strings.Repeat("é", 750)}})
+	orch = New(Config{
+		Agents:            []*agent.Agent{a},
+		Tasks:             []*task.Task{task.New(task.Config{Description: "write", Agent: a})},
+		Artifacts:         store,
+		ArtifactThreshold: 1001,
+	})
+	results, err = orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() with a low threshold unexpected error: %v", err)
+	}
+	if r := results[0]; r.Artifact == nil || !strings.HasPrefix(r.Result, strings.Repeat("é", 500)+"\n[...") {
+		t.Errorf("Result = %q, want a 1000 byte preview", r.Result)
+	}
+}
+
+// failingLLM always returns an error
+type failingLLM struct{}
+
+func (failingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return "", stderrors.New("provider down")
+}
+
+func TestExecuteParallel_ReportsFailedTasks(t *testing.T) {
+	ok := newTestAgent("ok")
+	broken := agent.New(agent.Config{Name: "broken", Role: "tester", Goal: "test", LLM: failingLLM{}})
+
+	orch := New(Config{
+		Agents: []*agent.Agent{ok, broken},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "works", Agent: ok}),
+			task.New(task.Config{Description: "fails", Agent: broken}),
+		},
+		Process: Parallel,
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err == nil {
+		t.Fatal("Kickoff() expected error for the failed task")
+	}
+
+	if len(results) != 2 || results[0] == nil || results[1] == nil {
+		t.Fatalf("results = %v, want an entry per task", results)
+	}
+	if results[0].Status != StatusCompleted {
+		t.Errorf("results[0].Status = %v, want COMPLETED", results[0].Status)
+	}
+	if results[1].Status != StatusFailed || results[1].Error == nil || results[1].Agent != "broken" {
+		t.Errorf("results[1] = %+v, want FAILED with an error", results[1])
+	}
+	if out := FormatResults(append(results, nil)); !strings.Contains(out, "provider down") {
+		t.Errorf("FormatResults() = %q, want the task error", out)
+	}
+}
+
+func TestParseProcess(t *testing.T) {
+	for name, want := range map[string]Process{"": Sequential, "Parallel": Parallel, " hierarchical ": Hierarchical, "distributed": Distributed} {
+		got, err := ParseProcess(name)
+		if err != nil || got != want {
+			t.Errorf("ParseProcess(%q) = %v, %v; want %v", name, got, err, want)
+		}
+	}
+	if _, err := ParseProcess("paralell"); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("ParseProcess(%q) error = %v, want ErrInvalidField", "paralell", err)
+	}
+}
+
+func TestAgentRegistry(t *testing.T) {
+	a := newTestAgent("Writer")
+	reg, err := NewAgentRegistry(a, a)
+	if err != nil {
+		t.Fatalf("NewAgentRegistry() with a repeated instance: unexpected error: %v", err)
+	}
+	if got, ok := reg.Get("writer"); !ok || got != a || reg.Len() != 1 {
+		t.Errorf("Get(%q) = %v, %v; want the registered agent", "writer", got, ok)
+	}
+
+	if _, err := NewAgentRegistry(a, newTestAgent("writer")); err == nil {
+		t.Error("NewAgentRegistry() with two agents named writer: expected error")
+	}
+
+	orch := New(Config{Agents: []*agent.Agent{a, newTestAgent("writer")}})
+	if _, err := orch.Kickoff(context.Background()); err == nil {
+		t.Error("Kickoff() with duplicate agent names: expected error")
+	}
+}
+
+// recordingLogger keeps logged messages
+type recordingLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *recordingLogger) Printf(format string, v ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, v...))
+}
+
+func TestVerbose_GatesLogging(t *testing.T) {
+	for _, verbose := range []bool{false, true} {
+		a := newTestAgent("worker")
+		logger := &recordingLogger{}
+		orch := New(Config{
+			Agents:  []*agent.Agent{a},
+			Tasks:   []*task.Task{task.New(task.Config{Description: "one", Agent: a})},
+			Process: Parallel,
+			Verbose: verbose,
+			Logger:  logger,
+		})
+		if _, err := orch.Kickoff(context.Background()); err != nil {
+			t.Fatalf("Kickoff() unexpected error: %v", err)
+		}
+		if got := len(logger.messages) > 0; got != verbose {
+			t.Errorf("Verbose=%v: logged %d messages", verbose, len(logger.messages))
+		}
+	}
+}
+
+// usageLLM reports fixed token usage for every response
+type usageLLM struct{}
+
+func (usageLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return "done", nil
+}
+
+func (usageLLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	return &llm.Response{
+		Content: "done",
+		Model:   "small",
+		Usage:   llm.TokenUsage{PromptTokens: 100, CompletionTokens: 50, CachedTokens: 40},
+	}, nil
+}
+
+func TestRunStats(t *testing.T) {
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: usageLLM{}})
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: a}),
+		},
+		Prices: map[string]llm.Price{"small": {Prompt: 1, Completion: 2}},
+	})
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	stats := orch.RunStats()
+	if stats == nil || stats.RunID != orch.RunID() {
+		t.Fatalf("RunStats() = %+v, want stats for the last run", stats)
+	}
+	if stats.Tasks != 2 || stats.LLMCalls != 2 || stats.PromptTokens != 200 || stats.CompletionTokens != 100 {
+		t.Errorf("RunStats() = %+v, want 2 tasks, 2 calls, 200+100 tokens", stats)
+	}
+	// 200 prompt tokens at $1/M plus 100 completion tokens at $2/M
+	if stats.Cost < 0.000399 || stats.Cost > 0.000401 {
+		t.Errorf("RunStats().Cost = %v, want 0.0004", stats.Cost)
+	}
+	if stats.CachedTokens != 80 || stats.PromptCacheRate() != 0.4 {
+		t.Errorf("RunStats() cached = %d (rate %v), want 80 (0.4)", stats.CachedTokens, stats.PromptCacheRate())
+	}
+	if w := stats.Agents["worker"]; w == nil || w.Tasks != 2 || w.PromptTokens != 200 {
+		t.Errorf("RunStats().Agents[worker] = %+v, want 2 tasks and 200 prompt tokens", w)
+	}
+	if !strings.Contains(FormatReport(nil, stats), "RUN STATISTICS") {
+		t.Error("FormatReport() does not include the run statistics")
+	}
+}
+
+func TestHierarchical_PlanApproval(t *testing.T) {
+	newOrch := func(approve PlanApproval) *Orchestrator {
+		return New(Config{
+			Agents:      []*agent.Agent{newTestAgent("worker")},
+			Process:     Hierarchical,
+			ManagerLLM:  &managerLLM{},
+			Goal:        "write a report",
+			ApprovePlan: approve,
+		})
+	}
+
+	// Delete the first step, edit the remaining one, then approve
+	var out strings.Builder
+	results, err := newOrch(PromptPlanApproval(strings.NewReader("d 1\ne 1 write a short draft\ny\n"), &out)).Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if len(results) != 1 || results[0].Task.Description != "write a short draft" {
+		t.Errorf("results = %v, want the single edited step", results)
+	}
+	if !strings.Contains(out.String(), "1. [worker] research") {
+		t.Errorf("approval prompt did not show the plan: %q", out.String())
+	}
+
+	// Rejecting the plan runs no agent
+	results, err = newOrch(PromptPlanApproval(strings.NewReader("n\n"), io.Discard)).Kickoff(context.Background())
+	if !causedBy(err, errors.ErrPolicyViolation) {
+		t.Errorf("Kickoff() error = %v, want ErrPolicyViolation", err)
+	}
+	if len(results) != 0 {
+		t.Errorf("results = %v, want none after rejection", results)
+	}
+}
+
+func TestLanguage_AppliesToPromptsAndReport(t *testing.T) {
+	recorder := &managerLLM{}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: recorder})
+	fixed := agent.New(agent.Config{Name: "translator", Role: "tester", Goal: "test", LLM: recorder, Language: "French"})
+	orch := New(Config{
+		Agents: []*agent.Agent{a, fixed},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "one", Agent: a}),
+			task.New(task.Config{Description: "two", Agent: fixed}),
+		},
+		Language: "de",
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	if len(recorder.prompts) != 2 {
+		t.Fatalf("recorded %d prompts, want 2", len(recorder.prompts))
+	}
+	if !strings.Contains(recorder.prompts[0], "in German") {
+		t.Errorf("prompt does not ask for German:\n%s", recorder.prompts[0])
+	}
+	if !strings.Contains(recorder.prompts[1], "in French") {
+		t.Errorf("agent language did not take precedence:\n%s", recorder.prompts[1])
+	}
+
+	report := orch.Report(results)
+	for _, want := range []string{"AUSFÜHRUNGSERGEBNISSE", "Aufgabe 1: one", "LAUFSTATISTIK"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("Report() missing %q:\n%s", want, report)
+		}
+	}
+	if !strings.Contains(FormatResults(results), "EXECUTION RESULTS") {
+		t.Error("FormatResults() is no longer English")
+	}
+}
+
+// echoLLM answers with the last line of the prompt, failing on "fail"
+type echoLLM struct{}
+
+func (echoLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(prompt), "\n")
+	last := lines[len(lines)-1]
+	if last == "fail" {
+		return "", stderrors.New("cannot map item")
+	}
+	return "mapped " + last, nil
+}
+
+func TestMapReduce(t *testing.T) {
+	mapper := agent.New(agent.Config{Name: "mapper", Role: "tester", Goal: "test", LLM: echoLLM{}})
+	reducer := &managerLLM{}
+	reducerAgent := agent.New(agent.Config{Name: "reducer", Role: "tester", Goal: "test", LLM: reducer})
+	opts := MapReduceOptions{
+		MapInstruction:    "Summarize the input.",
+		ReduceInstruction: "Combine the summaries.",
+		MaxParallel:       2,
+	}
+
+	out, err := MapReduce(context.Background(), []string{"a", "b", "c"}, mapper, reducerAgent, opts)
+	if err != nil {
+		t.Fatalf("MapReduce() unexpected error: %v", err)
+	}
+	if len(out.Mapped) != 3 || out.Mapped[2].Result != "mapped c" {
+		t.Errorf("Mapped = %+v, want 3 results in item order", out.Mapped)
+	}
+	if out.Reduced == nil || out.Reduced.Result != "final answer" || out.Reduced.RunID != out.Mapped[0].RunID {
+		t.Errorf("Reduced = %+v, want reducer output in the same run", out.Reduced)
+	}
+	if len(reducer.prompts) != 1 || !strings.Contains(reducer.prompts[0], "--- Output 3 ---\nmapped c") {
+		t.Errorf("reducer prompt = %q, want every mapper output", reducer.prompts)
+	}
+
+	if _, err := MapReduce(context.Background(), []string{"a", "fail"}, mapper, reducerAgent, opts); err == nil {
+		t.Error("MapReduce() expected error for a failed item")
+	}
+	opts.AllowPartial = true
+	out, err = MapReduce(context.Background(), []string{"a", "fail"}, mapper, reducerAgent, opts)
+	if err != nil || out.Mapped[1].Status != StatusFailed || out.Reduced == nil {
+		t.Errorf("MapReduce() with AllowPartial = %+v, %v", out, err)
+	}
+}
+
+func TestSimulatedLLM_RunsHierarchicalGoal(t *testing.T) {
+	researcher := agent.New(agent.Config{Name: "researcher", Role: "Researcher", Goal: "research"})
+	writer := agent.New(agent.Config{Name: "writer", Role: "Writer", Goal: "write"})
+	sim := NewSimulatedLLM(true, researcher, writer)
+	researcher.LLM, writer.LLM = sim, sim
+
+	orch := New(Config{
+		Agents:     []*agent.Agent{researcher, writer},
+		Process:    Hierarchical,
+		ManagerLLM: sim,
+		Goal:       "write a report",
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Agent != "researcher" || results[1].Agent != "writer" {
+		t.Fatalf("results = %+v, want one simulated step per agent", results)
+	}
+	if !strings.HasPrefix(results[1].Result, "[simulated]") || !strings.Contains(results[1].Result, "Simulated step 2 for writer") {
+		t.Errorf("Result = %q, want boilerplate echoing the prompt", results[1].Result)
+	}
+	if usage := orch.Usage().Tokens[llm.SimulatedModel]; usage.Total() == 0 {
+		t.Error("Usage() reports no simulated tokens")
+	}
+}
+
+func TestManagerPrompts_Overridable(t *testing.T) {
+	selection, err := prompts.Parse("select", "v1", `Wähle einen Agenten für "{{.Description}}" aus:
+{{range $i, $a := .AgentList}}{{$i}}: {{$a.Name}}
+{{end}}Antworte mit {{.Format}}`)
+	if err != nil {
+		t.Fatalf("Parse() unexpected error: %v", err)
+	}
+	manager := &managerLLM{}
+	a := task.New(task.Config{Description: "Bericht schreiben"})
+	orch := New(Config{
+		Agents:          []*agent.Agent{newTestAgent("writer"), newTestAgent("reviewer")},
+		Tasks:           []*task.Task{a},
+		Process:         Hierarchical,
+		ManagerLLM:      manager,
+		SelectionPrompt: selection,
+	})
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	want := "Wähle einen Agenten für \"Bericht schreiben\" aus:\n0: writer\n1: reviewer\nAntworte mit {\"agent\""
+	if len(manager.prompts) == 0 || !strings.HasPrefix(manager.prompts[0], want) {
+		t.Errorf("selection prompt = %q, want rendered override", manager.prompts)
+	}
+}
+
+func TestKickoffWithPlan_ReplaysSavedPlan(t *testing.T) {
+	manager := &managerLLM{}
+	orch := New(Config{
+		Agents:     []*agent.Agent{newTestAgent("worker")},
+		Process:    Hierarchical,
+		ManagerLLM: manager,
+		Goal:       "write a report",
+	})
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	plan := orch.Plan()
+	if plan == nil || plan.Goal != "write a report" || len(plan.Steps) != 2 {
+		t.Fatalf("Plan() = %+v, want the manager's two-step plan", plan)
+	}
+
+	for _, name := range []string{"plan.yaml", "plan.json"} {
+		path := filepath.Join(t.TempDir(), name)
+		if err := SavePlan(path, plan); err != nil {
+			t.Fatalf("SavePlan(%s) unexpected error: %v", name, err)
+		}
+		loaded, err := LoadPlan(path)
+		if err != nil {
+			t.Fatalf("LoadPlan(%s) unexpected error: %v", name, err)
+		}
+		if !reflect.DeepEqual(loaded, plan) {
+			t.Errorf("LoadPlan(%s) = %+v, want %+v", name, loaded, plan)
+		}
+
+		// Without a manager, the saved plan runs as is
+		replay := New(Config{Agents: []*agent.Agent{newTestAgent("worker")}})
+		results, err := replay.KickoffWithPlan(context.Background(), loaded)
+		if err != nil {
+			t.Fatalf("KickoffWithPlan() unexpected error: %v", err)
+		}
+		if len(results) != 2 || !strings.Contains(results[1].Task.Description, "Context from previous tasks") {
+			t.Errorf("results = %+v, want both steps with context carried forward", results)
+		}
+	}
+	if len(manager.prompts) != 1 {
+		t.Errorf("manager prompts = %d, want only the original planning call", len(manager.prompts))
+	}
+
+	if _, err := LoadPlan(filepath.Join(t.TempDir(), "missing.yaml")); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("LoadPlan() missing file error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestDebug_StepsThroughTasksAndLLMCalls(t *testing.T) {
+	a := newTestAgent("worker")
+	tasks := []*task.Task{
+		task.New(task.Config{Description: "skip me", Agent: a}),
+		task.New(task.Config{Description: "fake the answer", Agent: a}),
+		task.New(task.Config{Description: "edit me", Agent: a}),
+	}
+
+	var steps []debugger.Step
+	hook := func(ctx context.Context, step debugger.Step) (debugger.Decision, error) {
+		steps = append(steps, step)
+		switch {
+		case step.Kind == debugger.KindTask && step.Prompt == "skip me":
+			return debugger.Decision{Action: debugger.Skip}, nil
+		case step.Kind == debugger.KindLLM && strings.Contains(step.Prompt, "fake the answer"):
+			return debugger.Decision{Action: debugger.Respond, Response: "fake"}, nil
+		case step.Kind == debugger.KindTask && step.Prompt == "edit me":
+			return debugger.Decision{Action: debugger.Continue, Prompt: "edited"}, nil
+		}
+		return debugger.Decision{}, nil
+	}
+
+	orch := New(Config{Agents: []*agent.Agent{a}, Tasks: tasks, Debug: hook})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	if results[0].Status != StatusSkipped {
+		t.Errorf("results[0].Status = %v, want SKIPPED", results[0].Status)
+	}
+	if results[1].Result != "fake" {
+		t.Errorf("results[1].Result = %q, want the injected response", results[1].Result)
+	}
+	if results[2].Task.Description != "edited" || results[2].Result != "done" {
+		t.Errorf("results[2] = %+v, want the edited task run by the agent", results[2])
+	}
+	// skip: task; fake: task + llm; edit: task + llm
+	if len(steps) != 5 || steps[4].Kind != debugger.KindLLM || !strings.Contains(steps[4].Prompt, "Task: edited") {
+		t.Errorf("steps = %+v, want 5 pauses ending with the edited prompt", steps)
+	}
+	if orch.Usage().LLMCalls != 1 {
+		t.Errorf("LLMCalls = %d, want 1", orch.Usage().LLMCalls)
+	}
+}
+
+// searchTool returns a JSON list of documents
+type searchTool struct {
+	*tools.BaseTool
+}
+
+func (searchTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return `{"results": [{"title": "Go", "url": "https://go.dev"}]}`, nil
+}
+
+// citingLLM calls a tool before answering research prompts
+type citingLLM struct {
+	tool tools.Tool
+}
+
+func (m citingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "Task: research") {
+		return m.tool.Execute(ctx, nil)
+	}
+	return "draft", nil
+}
+
+func TestSources_AttachedToResults(t *testing.T) {
+	worker := newTestAgent("worker")
+	worker.LLM = citingLLM{tool: citation.WrapTool(searchTool{tools.NewBaseTool("search", "Web search", nil)}, nil)}
+	orch := New(Config{
+		Agents:     []*agent.Agent{worker},
+		Process:    Hierarchical,
+		ManagerLLM: &managerLLM{},
+		Goal:       "write a report",
+		Synthesize: true,
+	})
+
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	want := []citation.Source{{URL: "https://go.dev", Title: "Go", Tool: "search"}}
+	// The writing step was given the research as context, so it cites it too
+	for i, r := range results {
+		if !reflect.DeepEqual(r.Sources, want) {
+			t.Errorf("results[%d].Sources = %+v, want %+v", i, r.Sources, want)
+		}
+	}
+	if final := orch.FinalResult(); final == nil || !reflect.DeepEqual(final.Sources, want) {
+		t.Errorf("FinalResult().Sources = %+v, want %+v", final, want)
+	}
+	if report := FormatResults(results); !strings.Contains(report, "Sources:\n[1] Go (https://go.dev)") {
+		t.Errorf("FormatResults() missing sources:\n%s", report)
+	}
+}
+
+// editorLLM answers with what the results tool returns for args
+type editorLLM struct {
+	tool tools.Tool
+	args map[string]interface{}
+}
+
+func (m *editorLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return m.tool.Execute(ctx, m.args)
+}
+
+func TestResultsTool_ReadsCurrentRun(t *testing.T) {
+	writer := newTestAgent("writer")
+	writer.LLM = &mockLLM{response: strings.Repeat("finding ", 300) + "conclusion"}
+	editor := newTestAgent("editor")
+	orch := New(Config{
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "research", Agent: writer}),
+			task.New(task.Config{Description: "edit", Agent: editor}),
+		},
+	})
+	tool := orch.ResultsTool()
+	editor.LLM = &editorLLM{tool: tool}
+
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	listing := results[1].Result
+	if !strings.Contains(listing, "1. research (by writer)") || strings.Contains(listing, "2. edit") {
+		t.Errorf("listing should show only the completed research task:\n%s", listing)
+	}
+	if llm.EstimateTokens(listing) > 200 {
+		t.Errorf("listing has ~%d tokens, want a summary", llm.EstimateTokens(listing))
+	}
+
+	// After the run, the full output of a task can be expanded
+	full, err := tool.Execute(context.Background(), map[string]interface{}{"task": float64(1)})
+	if err != nil {
+		t.Fatalf("Execute(task=1) unexpected error: %v", err)
+	}
+	if !strings.HasSuffix(full, "conclusion") || !strings.Contains(full, strings.Repeat("finding ", 300)) {
+		t.Errorf("Execute(task=1) = %q, want the full result", full)
+	}
+	if _, err := tool.Execute(context.Background(), map[string]interface{}{"task": "9"}); !errors.HasCode(err, errors.ErrOutOfRange) {
+		t.Errorf("Execute(task=9) error = %v, want ErrOutOfRange", err)
+	}
+}
+
+// failingTool always fails
+type failingTool struct {
+	*tools.BaseTool
+}
+
+func (failingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return "", errors.New(errors.ErrInternal, "backend down")
+}
+
+// registryLLM executes tools through a registry before answering
+type registryLLM struct {
+	registry *tools.Registry
+	calls    []string
+}
+
+func (m registryLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	for _, name := range m.calls {
+		_, _ = m.registry.Execute(ctx, name, nil)
+	}
+	return "done", nil
+}
+
+func TestRunStats_Tools(t *testing.T) {
+	registry := tools.NewRegistry()
+	_ = registry.Register(searchTool{tools.NewBaseTool("search", "Web search", nil)})
+	_ = registry.Register(failingTool{tools.NewBaseTool("crm", "CRM lookup", nil)})
+	worker := newTestAgent("worker")
+	worker.LLM = registryLLM{registry: registry, calls: []string{"search", "search", "crm"}}
+	orch := New(Config{
+		Agents: []*agent.Agent{worker},
+		Tasks:  []*task.Task{task.New(task.Config{Description: "research", Agent: worker})},
+		Tools:  registry,
+	})
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	stats := orch.RunStats()
+	search, crm := stats.Tools["search"], stats.Tools["crm"]
+	if search == nil || search.Calls != 2 || search.Failures != 0 || search.OutputBytes != 2*len(`{"results": [{"title": "Go", "url": "https://go.dev"}]}`) {
+		t.Errorf("search stats = %+v, want 2 calls and their output", search)
+	}
+	if crm == nil || crm.Calls != 1 || crm.FailureRate() != 1 {
+		t.Errorf("crm stats = %+v, want 1 failed call", crm)
+	}
+	if report := stats.String(); !strings.Contains(report, "Tool crm: 1 calls, 100% failed") {
+		t.Errorf("report missing tool stats:\n%s", report)
+	}
+}
+
+// callingLLM calls a tool, then answers with the tool's result
+type callingLLM struct {
+	tool string
+}
+
+func (m callingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if i := strings.LastIndex(prompt, "Result of "); i >= 0 {
+		return prompt[i:], nil
+	}
+	return fmt.Sprintf(`{"name": %q, "arguments": {}}`, m.tool), nil
+}
+
+// countingTool counts its executions
+type countingTool struct {
+	*tools.BaseTool
+	runs *atomic.Int32
+}
+
+func (t countingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	t.runs.Add(1)
+	return "executed", nil
+}
+
+func TestKickoff_EnforcesToolPermissions(t *testing.T) {
+	var shellRuns atomic.Int32
+	registry := tools.NewRegistry()
+	_ = registry.Register(searchTool{tools.NewBaseTool("search", "Web search", nil)})
+	_ = registry.Register(countingTool{tools.NewBaseTool("shell", "Runs a command", nil), &shellRuns})
+
+	researcher := agent.New(agent.Config{Name: "researcher", Role: "tester", Goal: "test", Tools: []string{"search"}, LLM: callingLLM{"shell"}})
+	ops := agent.New(agent.Config{Name: "ops", Role: "tester", Goal: "test", DenyTools: []string{"shell"}, LLM: callingLLM{"shell"}})
+	searcher := agent.New(agent.Config{Name: "searcher", Role: "tester", Goal: "test", Tools: []string{"search"}, LLM: callingLLM{"search"}})
+	orch := New(Config{
+		Agents: []*agent.Agent{researcher, ops, searcher},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "research", Agent: researcher}),
+			task.New(task.Config{Description: "deploy", Agent: ops}),
+			task.New(task.Config{Description: "search", Agent: searcher}),
+		},
+		Tools: registry,
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+
+	for i, name := range []string{"researcher", "ops"} {
+		want := "agent " + name + " is not allowed to use tool shell"
+		if !strings.Contains(results[i].Result, want) || !strings.Contains(results[i].Result, errors.ErrUnauthorized.String()) {
+			t.Errorf("results[%d] = %q, want the model told %q", i, results[i].Result, want)
+		}
+	}
+	if n := shellRuns.Load(); n != 0 {
+		t.Errorf("shell ran %d times, want 0", n)
+	}
+	if !strings.Contains(results[2].Result, "Result of search:\n<<<UNTRUSTED ") || !strings.Contains(results[2].Result, "\n{\"results\"") {
+		t.Errorf("results[2] = %q, want the search result", results[2].Result)
+	}
+	if stats := orch.RunStats().Tools; stats["search"] == nil || stats["search"].Calls != 1 || stats["shell"] != nil {
+		t.Errorf("tool stats = %+v, want one search call only", stats)
+	}
+}
+
+// deadlineLLM records the time left before the deadline of each call, and
+// blocks until ctx is done when the prompt contains "block"
+type deadlineLLM struct {
+	mu   sync.Mutex
+	left []time.Duration
+}
+
+func (m *deadlineLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	deadline, _ := ctx.Deadline()
+	m.mu.Lock()
+	m.left = append(m.left, time.Until(deadline))
+	m.mu.Unlock()
+	if strings.Contains(prompt, "block") {
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return "done", nil
+}
+
+func TestApportionDeadline(t *testing.T) {
+	l := &deadlineLLM{}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: l})
+	newOrch := func(descriptions ...string) *Orchestrator {
+		var tasks []*task.Task
+		for _, d := range descriptions {
+			tasks = append(tasks, task.New(task.Config{Description: d, Agent: a}))
+		}
+		return New(Config{
+			Agents:            []*agent.Agent{a},
+			Tasks:             tasks,
+			Limits:            limits.Config{MaxWallTime: time.Second},
+			ApportionDeadline: true,
+		})
+	}
+
+	if _, err := newOrch("one", "two", "three").Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() error = %v", err)
+	}
+	if len(l.left) != 3 {
+		t.Fatalf("LLM calls = %d, want 3", len(l.left))
+	}
+	if l.left[0] > time.Second/3 || l.left[1] > time.Second/2 || l.left[2] < time.Second/2 {
+		t.Errorf("time left per task = %v, want about 1/3, 1/2 and all of the run deadline", l.left)
+	}
+
+	l.left = nil
+	start := time.Now()
+	_, err := newOrch("one block", "two").Kickoff(context.Background())
+	if !causedBy(err, errors.ErrTimeout) {
+		t.Fatalf("Kickoff() error = %v, want ErrTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed > 800*time.Millisecond {
+		t.Errorf("first task ran for %v, want it stopped at its share of the deadline", elapsed)
+	}
+}
+
+// concurrencyLLM records how many calls were running when each call
+// started, keyed by the task names found in the prompt. Prompts containing "fail"
+// fail and prompts containing "block" wait for cancellation.
+type concurrencyLLM struct {
+	mu      sync.Mutex
+	running int
+	seen    map[string]int
+}
+
+func (m *concurrencyLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.mu.Lock()
+	m.running++
+	for _, name := range []string{"first", "second", "third"} {
+		if strings.Contains(prompt, name) {
+			m.seen[name] = m.running
+		}
+	}
+	m.mu.Unlock()
+	defer func() {
+		m.mu.Lock()
+		m.running--
+		m.mu.Unlock()
+	}()
+
+	switch {
+	case strings.Contains(prompt, "fail"):
+		return "", stderrors.New("failed")
+	case strings.Contains(prompt, "block"):
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	select {
+	case <-time.After(50 * time.Millisecond):
+		return prompt, nil
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func TestSequential_Speculative(t *testing.T) {
+	l := &concurrencyLLM{seen: make(map[string]int)}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: l})
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "first", Agent: a}),
+			task.New(task.Config{Description: "second", Agent: a}),
+			task.New(task.Config{Description: "third", Agent: a, Context: []string{"second"}}),
+		},
+		Speculative: true,
+	})
+
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() error = %v", err)
+	}
+	var order []string
+	for _, r := range results {
+		order = append(order, r.Task.Description)
+	}
+	if want := []string{"first", "second", "third"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("result order = %v, want %v", order, want)
+	}
+	if l.seen["first"]+l.seen["second"] != 3 {
+		t.Errorf("running calls = %v, want first and second to overlap", l.seen)
+	}
+	if l.seen["third"] != 1 {
+		t.Errorf("third started with %d calls running, want it to wait for second", l.seen["third"])
+	}
+
+	// A failure discards the speculative task
+	orch = New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "fail", Agent: a}),
+			task.New(task.Config{Description: "block", Agent: a}),
+		},
+		Speculative: true,
+	})
+	results, err = orch.Kickoff(context.Background())
+	if err == nil || len(results) != 0 {
+		t.Fatalf("Kickoff() = %d results, %v; want no results and an error", len(results), err)
+	}
+	if l.running != 0 {
+		t.Errorf("%d calls still running after Kickoff returned", l.running)
+	}
+
+	// A speculative task that finished before its predecessor failed is
+	// not stored as a result of the run
+	store := NewMemoryResultStore()
+	quick := task.New(task.Config{Description: "quick", Agent: a})
+	lateFailure := agent.New(agent.Config{Name: "late", Role: "tester", Goal: "test", LLM: lateFailureLLM{}})
+	orch = New(Config{
+		Agents: []*agent.Agent{a, lateFailure},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "fail late", Agent: lateFailure}),
+			quick,
+		},
+		Speculative: true,
+		Results:     store,
+	})
+	if _, err := orch.Kickoff(context.Background()); err == nil {
+		t.Fatal("Kickoff() expected an error")
+	}
+	if _, ok := store.Load(quick.Key()); ok {
+		t.Error("discarded speculative result was stored")
+	}
+}
+
+// lateFailureLLM fails after the speculative task has finished
+type lateFailureLLM struct{}
+
+func (lateFailureLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	time.Sleep(100 * time.Millisecond)
+	return "", stderrors.New("failed late")
+}
+
+// prefixLLM records the shared prefix of each call
+type prefixLLM struct {
+	mu     sync.Mutex
+	shared map[string]bool
+}
+
+func (m *prefixLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	words := strings.Fields(prompt)
+	m.shared[words[len(words)-1]] = llm.PrefixFromContext(ctx) != nil
+	return "done", nil
+}
+
+func TestParallel_DedupPrompts(t *testing.T) {
+	l := &prefixLLM{shared: make(map[string]bool)}
+	a := agent.New(agent.Config{Name: "reader", Role: "tester", Goal: "test", LLM: l})
+	document := strings.Repeat("A long document shared by several tasks.\n", 200)
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: document + "summarize", Agent: a}),
+			task.New(task.Config{Description: document + "translate", Agent: a}),
+			task.New(task.Config{Description: "unrelated", Agent: a}),
+		},
+		Process:      Parallel,
+		DedupPrompts: true,
+	})
+
+	if _, err := orch.Kickoff(context.Background()); err != nil {
+		t.Fatalf("Kickoff() error = %v", err)
+	}
+	if want := map[string]bool{"summarize": true, "translate": true, "unrelated": false}; !reflect.DeepEqual(l.shared, want) {
+		t.Errorf("shared prefix per task = %v, want %v", l.shared, want)
+	}
+	stats := orch.RunStats()
+	if stats.SharedPrefixTokens < len(document)/4 {
+		t.Errorf("SharedPrefixTokens = %d, want at least the document's %d tokens", stats.SharedPrefixTokens, len(document)/4)
+	}
+	if !strings.Contains(stats.String(), "Shared prompt prefixes") {
+		t.Errorf("String() = %q, want the shared prefix savings", stats.String())
+	}
+}
+
+// delayLLM answers prompts containing "slow" after a delay
+type delayLLM struct{}
+
+func (delayLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if strings.Contains(prompt, "slow") {
+		time.Sleep(100 * time.Millisecond)
+	}
+	return "done", nil
+}
+
+func TestParallel_StreamsResults(t *testing.T) {
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: delayLLM{}})
+	newTasks := func() []*task.Task {
+		return []*task.Task{
+			task.New(task.Config{Description: "slow", Agent: a}),
+			task.New(task.Config{Description: "fast", Agent: a}),
+		}
+	}
+
+	var streamed []int
+	orch := New(Config{
+		Agents:   []*agent.Agent{a},
+		Tasks:    newTasks(),
+		Process:  Parallel,
+		OnResult: func(index int, result *TaskResult) { streamed = append(streamed, index) },
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() error = %v", err)
+	}
+	if !reflect.DeepEqual(streamed, []int{1, 0}) {
+		t.Errorf("streamed indices = %v, want [1 0]", streamed)
+	}
+	if results[0].Task.Description != "slow" || results[1].Index != 1 {
+		t.Errorf("results = %v, %v; want task order with indices", results[0].Task.Description, results[1].Index)
+	}
+
+	orch = New(Config{
+		Agents:      []*agent.Agent{a},
+		Tasks:       newTasks(),
+		Process:     Parallel,
+		ResultOrder: CompletionOrder,
+	})
+	results, err = orch.Kickoff(context.Background())
+	if err != nil {
+		t.Fatalf("Kickoff() error = %v", err)
+	}
+	if results[0].Task.Description != "fast" || results[0].Index != 1 || results[1].Index != 0 {
+		t.Errorf("results = [%s %d, %s %d], want completion order", results[0].Task.Description, results[0].Index, results[1].Task.Description, results[1].Index)
+	}
+}
+
+// flakyLLM fails prompts containing "flaky" on the first call and prompts
+// containing "broken" always, counting calls per prompt
+type flakyLLM struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (m *flakyLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	words := strings.Fields(prompt)
+	name := words[len(words)-1]
+	m.calls[name]++
+	if name == "broken" || name == "flaky" && m.calls[name] == 1 {
+		return "", stderrors.New("failed")
+	}
+	return "done", nil
+}
+
+func TestRetryFailed(t *testing.T) {
+	l := &flakyLLM{calls: make(map[string]int)}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: l})
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "ok", Agent: a}),
+			task.New(task.Config{Description: "flaky", Agent: a}),
+			task.New(task.Config{Description: "broken", Agent: a, Retry: &task.RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond}}),
+		},
+		Process: Parallel,
+	})
+
+	if _, err := orch.RetryFailed(context.Background()); !causedBy(err, errors.ErrTaskNotFound) {
+		t.Fatalf("RetryFailed() before a run error = %v, want ErrTaskNotFound", err)
+	}
+	if _, err := orch.Kickoff(context.Background()); err == nil {
+		t.Fatal("Kickoff() error = nil, want failed tasks")
+	}
+
+	results, err := orch.RetryFailed(context.Background())
+	if err == nil {
+		t.Fatal("RetryFailed() error = nil, want the broken task to fail again")
+	}
+	statuses := []TaskStatus{results[0].Status, results[1].Status, results[2].Status}
+	if want := []TaskStatus{StatusCompleted, StatusCompleted, StatusFailed}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("statuses = %v, want %v", statuses, want)
+	}
+	if want := map[string]int{"ok": 1, "flaky": 2, "broken": 2}; !reflect.DeepEqual(l.calls, want) {
+		t.Errorf("calls = %v, want %v", l.calls, want)
+	}
+	// The retry run counts the two retried tasks
+	if stats := orch.RunStats(); stats.Retries != 2 || stats.Agents["worker"].Retries != 2 || stats.Tasks != 2 {
+		t.Errorf("RunStats() = %d retries (%+v), want 2 retried tasks", stats.Retries, stats.Agents["worker"])
+	}
+
+	// The broken task has used its single retry
+	if _, err := orch.RetryFailed(context.Background()); err == nil {
+		t.Fatal("RetryFailed() error = nil, want the broken task's failure")
+	}
+	if l.calls["broken"] != 2 {
+		t.Errorf("broken task executed %d times, want 2", l.calls["broken"])
+	}
+
+	// Failures that are not retried fail the retry even when every retried
+	// task succeeds
+	l = &flakyLLM{calls: make(map[string]int)}
+	a.LLM = l
+	orch = New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "flaky", Agent: a}),
+			task.New(task.Config{Description: "broken", Agent: a, Retry: &task.RetryPolicy{MaxRetries: 0}}),
+		},
+		Process: Parallel,
+	})
+	_, _ = orch.Kickoff(context.Background())
+	results, err = orch.RetryFailed(context.Background())
+	if err == nil || results[0].Status != StatusCompleted || results[1].Status != StatusFailed {
+		t.Errorf("RetryFailed() = [%v %v], %v; want the broken task's failure", results[0].Status, results[1].Status, err)
+	}
+
+	// Retrying every task of the run still waits for the backoff
+	l = &flakyLLM{calls: make(map[string]int)}
+	a.LLM = l
+	orch = New(Config{
+		Agents:  []*agent.Agent{a},
+		Tasks:   []*task.Task{task.New(task.Config{Description: "flaky", Agent: a, Retry: &task.RetryPolicy{MaxRetries: 1, Backoff: 50 * time.Millisecond}})},
+		Process: Parallel,
+	})
+	_, _ = orch.Kickoff(context.Background())
+	start := time.Now()
+	if _, err := orch.RetryFailed(context.Background()); err != nil || time.Since(start) < 50*time.Millisecond {
+		t.Errorf("RetryFailed() = %v after %v, want success after the 50ms backoff", err, time.Since(start))
+	}
+}
+
+// startLLM records the order in which tasks start; prompts containing
+// "background" take 200ms unless cancelled
+type startLLM struct {
+	mu      sync.Mutex
+	started []string
+}
+
+func (m *startLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	words := strings.Fields(prompt)
+	m.mu.Lock()
+	m.started = append(m.started, words[len(words)-1])
+	m.mu.Unlock()
+	if strings.Contains(prompt, "background") {
+		select {
+		case <-time.After(200 * time.Millisecond):
+		case <-ctx.Done():
+			return "", ctx.Err()
+		}
+	}
+	return "done", nil
+}
+
+func TestParallel_PrioritiesAndPreemption(t *testing.T) {
+	l := &startLLM{}
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: l})
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "background low", Agent: a}),
+			task.New(task.Config{Description: "background high", Agent: a, Priority: 5}),
+		},
+		Process:        Parallel,
+		MaxConcurrency: 1,
+		Preempt:        true,
+	})
+
+	if _, err := orch.Submit(context.Background(), task.New(task.Config{Description: "urgent", Agent: a})); err == nil {
+		t.Fatal("Submit() outside a run error = nil")
+	}
+
+	type kickoff struct {
+		results []*TaskResult
+		err     error
+	}
+	out := make(chan kickoff, 1)
+	go func() {
+		results, err := orch.Kickoff(context.Background())
+		out <- kickoff{results, err}
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	idx, err := orch.Submit(context.Background(), task.New(task.Config{Description: "urgent", Agent: a, Priority: 10}))
+	if err != nil || idx != 2 {
+		t.Fatalf("Submit() = %d, %v; want index 2", idx, err)
+	}
+
+	k := <-out
+	if k.err != nil {
+		t.Fatalf("Kickoff() error = %v", k.err)
+	}
+	if len(k.results) != 3 || k.results[2].Task.Description != "urgent" || k.results[2].Status != StatusCompleted {
+		t.Fatalf("results = %v, want the submitted task last and completed", k.results)
+	}
+	if want := []string{"high", "urgent", "high", "low"}; !reflect.DeepEqual(l.started, want) {
+		t.Errorf("start order = %v, want %v", l.started, want)
+	}
+}
+
+func TestExecuteDistributed_ShipsDefinitions(t *testing.T) {
+	a := agent.New(agent.Config{Name: "remote", Role: "tester", Goal: "test", LLM: &mockLLM{response: "done"}, Model: "fast"})
+	queue := distributed.NewMemoryQueue(10)
+
+	// The worker has no agents of its own and builds them from the jobs
+	var models []string
+	var mu sync.Mutex
+	w, err := distributed.NewWorker(distributed.WorkerConfig{
+		Queue: queue,
+		Runtime: &agent.Runtime{LLM: func(model, agent string) (llm.LLM, error) {
+			mu.Lock()
+			models = append(models, model)
+			mu.Unlock()
+			return &mockLLM{response: `{"ok": true}`}, nil
+		}},
+	})
+	if err != nil {
+		t.Fatalf("NewWorker() unexpected error: %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() { _ = w.Run(ctx) }()
+
+	orch := New(Config{
+		Agents: []*agent.Agent{a},
+		Tasks: []*task.Task{
+			task.New(task.Config{Description: "report", Agent: a, OutputFormat: &task.OutputFormat{Kind: task.FormatJSON}}),
+			task.New(task.Config{Description: "summarize", Agent: a}),
+		},
+		Process: Distributed,
+		Queue:   queue,
+	})
+	results, err := orch.Kickoff(ctx)
+	if err != nil {
+		t.Fatalf("Kickoff() unexpected error: %v", err)
+	}
+	// Both jobs run on the agent built for the first
+	if results[0].Result != `{"ok": true}` || !reflect.DeepEqual(models, []string{"fast"}) {
+		t.Errorf("result %q with models %v, want the JSON output from the agent built once for model fast", results[0].Result, models)
+	}
+}
+
+// severityLLM fails tasks named "minor" with a medium-severity error and
+// tasks named "fatal" with a critical one; "slow" tasks wait for
+// cancellation
+type severityLLM struct{}
+
+func (severityLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	words := strings.Fields(prompt)
+	switch words[len(words)-1] {
+	case "minor":
+		return "", errors.New(errors.ErrAPIResponse, "unparseable answer")
+	case "fatal":
+		return "", errors.New(errors.ErrInternal, "credentials revoked").WithSeverity(errors.SeverityCritical)
+	case "slow":
+		<-ctx.Done()
+		return "", ctx.Err()
+	}
+	return "done", nil
+}
+
+func TestAbortSeverity(t *testing.T) {
+	a := agent.New(agent.Config{Name: "worker", Role: "tester", Goal: "test", LLM: severityLLM{}})
+	tasks := func(names ...string) []*task.Task {
+		var ts []*task.Task
+		for _, name := range names {
+			ts = append(ts, task.New(task.Config{Description: name, Agent: a}))
+		}
+		return ts
+	}
+
+	// Lower severities follow ContinueOnError
+	orch := New(Config{
+		Agents:          []*agent.Agent{a},
+		Tasks:           tasks("minor", "ok"),
+		ContinueOnError: true,
+		AbortSeverity:   errors.SeverityCritical,
+	})
+	results, err := orch.Kickoff(context.Background())
+	if err == nil || len(results) != 2 || results[0].Status != StatusFailed || results[1].Result != "done" {
+		t.Fatalf("Kickoff() = %v, %v, want the minor failure passed over", results, err)
+	}
+
+	// A critical failure aborts despite ContinueOnError
+	orch = New(Config{
+		Agents:          []*agent.Agent{a},
+		Tasks:           tasks("fatal", "ok"),
+		ContinueOnError: true,
+		AbortSeverity:   errors.SeverityCritical,
+	})
+	results, err = orch.Kickoff(context.Background())
+	if errors.MaxSeverity(err) != errors.SeverityCritical || len(results) != 0 {
+		t.Fatalf("Kickoff() = %v, %v, want the run aborted at the critical failure", results, err)
+	}
+
+	// In parallel runs, a critical failure cancels the running tasks
+	orch = New(Config{
+		Agents:        []*agent.Agent{a},
+		Tasks:         tasks("slow", "fatal", "minor"),
+		Process:       Parallel,
+		AbortSeverity: errors.SeverityCritical,
+	})
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		results, err = orch.Kickoff(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Kickoff() did not abort the parallel run")
+	}
+	if errors.MaxSeverity(err) != errors.SeverityCritical {
+		t.Errorf("Kickoff() error = %v, want the critical failure", err)
+	}
+	if results[0].Status != StatusCancelled || results[1].Status != StatusFailed {
+		t.Errorf("statuses = %v, %v, want cancelled and failed", results[0].Status, results[1].Status)
+	}
+}
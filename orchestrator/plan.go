@@ -0,0 +1,117 @@
+package orchestrator
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Plan is an execution plan for a goal, as created by the manager. Saved
+// plans can be reviewed and executed again without re-invoking the manager.
+type Plan struct {
+	Goal  string     `json:"goal" yaml:"goal"`
+	Steps []PlanStep `json:"steps" yaml:"steps"`
+}
+
+// Plan returns the plan executed by the most recent goal-driven run, after
+// approval, or nil if the run did not plan. It is set before the first step
+// runs, so it is available even when the run fails.
+func (o *Orchestrator) Plan() *Plan {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.plan
+}
+
+// setPlan records the plan of the current run
+func (o *Orchestrator) setPlan(plan *Plan) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.plan = plan
+}
+
+// replayPlan returns the saved plan KickoffWithPlan is executing, or nil
+func (o *Orchestrator) replayPlan() *Plan {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.replay
+}
+
+// KickoffWithPlan runs a saved plan instead of asking the manager for one.
+// Steps run as in a goal-driven hierarchical run, whatever the configured
+// process, and pass through ApprovePlan. The manager LLM is only needed
+// when Synthesize is set.
+func (o *Orchestrator) KickoffWithPlan(ctx context.Context, plan *Plan) ([]*TaskResult, error) {
+	if plan == nil || len(plan.Steps) == 0 {
+		return nil, errors.Validation("plan has no steps")
+	}
+
+	o.mu.Lock()
+	o.replay = plan
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.replay = nil
+		o.mu.Unlock()
+	}()
+	return o.Kickoff(ctx)
+}
+
+// SavePlan writes a plan to path as JSON if the file name ends in ".json",
+// YAML otherwise
+func SavePlan(path string, plan *Plan) error {
+	if plan == nil {
+		return errors.RequiredField("plan")
+	}
+
+	var data []byte
+	var err error
+	if isJSONFile(path) {
+		data, err = json.MarshalIndent(plan, "", "  ")
+	} else {
+		data, err = yaml.Marshal(plan)
+	}
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to encode plan", err).WithContext("path", path)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write plan", err).WithContext("path", path)
+	}
+	return nil
+}
+
+// LoadPlan reads a plan saved by SavePlan, or written by hand
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("plan", path)
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read plan", err).WithContext("path", path)
+	}
+
+	var plan Plan
+	if isJSONFile(path) {
+		err = json.Unmarshal(data, &plan)
+	} else {
+		err = yaml.Unmarshal(data, &plan)
+	}
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid plan file", err).WithContext("path", path)
+	}
+	if len(plan.Steps) == 0 {
+		return nil, errors.Validation("plan has no steps").WithContext("path", path)
+	}
+	return &plan, nil
+}
+
+// isJSONFile reports whether path names a JSON file
+func isJSONFile(path string) bool {
+	return strings.EqualFold(filepath.Ext(path), ".json")
+}
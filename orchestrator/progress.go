@@ -0,0 +1,101 @@
+package orchestrator
+
+import (
+	"time"
+
+	"github.com/counhopig/gittyai/task"
+)
+
+// progressBuffer is the capacity of the progress updates channel
+const progressBuffer = 64
+
+// Progress is a snapshot of a run's progress
+type Progress struct {
+	// Completed counts finished tasks, whether they succeeded or failed
+	Completed int
+	// Total is the number of tasks in the run. In goal-driven hierarchical
+	// mode it is zero until the manager has produced a plan.
+	Total int
+	// CurrentTask is the description of the most recently started task
+	CurrentTask string
+	// Elapsed is the time since Kickoff started
+	Elapsed time.Duration
+	// ETA estimates the remaining time from the average duration of
+	// previously finished tasks; zero when no history is available
+	ETA time.Duration
+}
+
+// Percent returns the completion percentage in the range [0, 100]
+func (p Progress) Percent() float64 {
+	if p.Total <= 0 {
+		return 0
+	}
+	return float64(p.Completed) / float64(p.Total) * 100
+}
+
+// Progress returns a snapshot of the current run's progress
+func (o *Orchestrator) Progress() Progress {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.progressSnapshot()
+}
+
+// ProgressUpdates returns a channel receiving a snapshot whenever a task
+// starts or finishes. Updates are dropped when the channel is full, so slow
+// readers only miss intermediate snapshots. The channel is never closed.
+func (o *Orchestrator) ProgressUpdates() <-chan Progress {
+	return o.updates
+}
+
+// taskStarted records a task start and returns the start time
+func (o *Orchestrator) taskStarted(t *task.Task) time.Time {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.progress.CurrentTask = t.Description
+	o.publishProgress()
+	return time.Now()
+}
+
+// taskFinished records a task completion
+func (o *Orchestrator) taskFinished(start time.Time) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	// A running mean keeps the history's memory constant however many
+	// tasks the orchestrator runs
+	o.timedTasks++
+	o.meanDuration += (time.Since(start) - o.meanDuration) / time.Duration(o.timedTasks)
+	o.progress.Completed++
+	o.publishProgress()
+}
+
+// setProgressTotal updates the total once the number of tasks is known
+func (o *Orchestrator) setProgressTotal(total int) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.progress.Total = total
+	o.publishProgress()
+}
+
+// progressSnapshot computes elapsed time and ETA. Callers must hold o.mu.
+func (o *Orchestrator) progressSnapshot() Progress {
+	p := o.progress
+	if !o.runStart.IsZero() {
+		p.Elapsed = time.Since(o.runStart)
+	}
+
+	if remaining := p.Total - p.Completed; remaining > 0 && o.timedTasks > 0 {
+		p.ETA = o.meanDuration * time.Duration(remaining)
+	}
+	return p
+}
+
+// publishProgress sends a snapshot without blocking. Callers must hold o.mu.
+func (o *Orchestrator) publishProgress() {
+	select {
+	case o.updates <- o.progressSnapshot():
+	default:
+	}
+}
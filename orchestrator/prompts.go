@@ -0,0 +1,83 @@
+package orchestrator
+
+import (
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/prompts"
+)
+
+// SelectionPromptData is the data available to selection prompt templates
+type SelectionPromptData struct {
+	// Agents is the numbered description of the candidate agents
+	Agents string
+	// AgentList holds the candidates in the order they are numbered, for
+	// templates that lay out the descriptions themselves
+	AgentList []*agent.Agent
+	// Format is the JSON object the manager must answer with; the answer
+	// is parsed in this format, so templates should include it verbatim
+	Format         string
+	Description    string // Description of the task to assign
+	ExpectedOutput string // Expected output of the task to assign
+}
+
+// PlanningPromptData is the data available to planning prompt templates
+type PlanningPromptData struct {
+	// Agents is the numbered description of the available agents
+	Agents    string
+	AgentList []*agent.Agent
+	Goal      string
+}
+
+// DefaultSelectionPrompt asks the manager to pick an agent for a task. The
+// task comes last so the agent list and instructions form a prefix shared
+// by every selection, which providers can serve from their cache.
+const DefaultSelectionPrompt = `You are a manager responsible for assigning tasks to the best-suited agent.
+
+{{.Agents}}
+Based on the agents' roles and goals, which agent is best suited for the task below?
+Respond with ONLY a JSON object, where agent is the agent's number from the list above:
+{{.Format}}
+
+Task to assign:
+Description: {{.Description}}
+Expected Output: {{.ExpectedOutput}}`
+
+// DefaultPlanningPrompt asks the manager to break a goal into steps. The
+// answer must be a JSON array of PlanStep objects.
+const DefaultPlanningPrompt = `You are a manager responsible for breaking down goals into tasks and assigning them to agents.
+
+{{.Agents}}
+Create an execution plan to achieve the goal given at the end. For each step, specify:
+1. The task description
+2. Which agent should handle it (use exact agent name)
+3. Expected output
+4. Whether it needs context from previous tasks (true/false)
+5. Skills the agent needs, if any (from the agents' listed skills)
+
+Respond in JSON format as an array of steps:
+[
+  {
+    "task_description": "...",
+    "agent_name": "...",
+    "expected_output": "...",
+    "use_context": false,
+    "skills": []
+  }
+]
+
+Keep the plan focused and efficient. Only include necessary steps.
+
+Goal to achieve: {{.Goal}}`
+
+var (
+	defaultSelectionTemplate = mustParse("manager-selection", DefaultSelectionPrompt)
+	defaultPlanningTemplate  = mustParse("manager-planning", DefaultPlanningPrompt)
+)
+
+// mustParse parses a built-in prompt, panicking on errors in its source
+func mustParse(name, source string) *prompts.Template {
+	t, err := prompts.Parse(name, "default", source)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
@@ -0,0 +1,62 @@
+package orchestrator
+
+import (
+	"strings"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+)
+
+// AgentRegistry holds an orchestrator's agents keyed by name. Names are
+// case-insensitive, matching how managers refer to agents.
+type AgentRegistry struct {
+	agents []*agent.Agent
+	byName map[string]*agent.Agent
+}
+
+// NewAgentRegistry creates a registry of agents. Listing the same agent
+// twice is harmless; two distinct agents sharing a name are an error.
+func NewAgentRegistry(agents ...*agent.Agent) (*AgentRegistry, error) {
+	r := &AgentRegistry{byName: make(map[string]*agent.Agent, len(agents))}
+	for _, a := range agents {
+		if err := r.Register(a); err != nil {
+			return nil, err
+		}
+	}
+	return r, nil
+}
+
+// Register adds an agent to the registry
+func (r *AgentRegistry) Register(a *agent.Agent) error {
+	if a == nil {
+		return errors.RequiredField("agent")
+	}
+	key := strings.ToLower(a.Name)
+	if existing, ok := r.byName[key]; ok {
+		if existing == a {
+			return nil
+		}
+		return errors.Validationf("duplicate agent name: %s", a.Name).WithContext("agent", a.Name)
+	}
+	r.byName[key] = a
+	r.agents = append(r.agents, a)
+	return nil
+}
+
+// Get returns the agent with the given name
+func (r *AgentRegistry) Get(name string) (*agent.Agent, bool) {
+	a, ok := r.byName[strings.ToLower(name)]
+	return a, ok
+}
+
+// All returns the agents in registration order
+func (r *AgentRegistry) All() []*agent.Agent {
+	agents := make([]*agent.Agent, len(r.agents))
+	copy(agents, r.agents)
+	return agents
+}
+
+// Len returns the number of agents
+func (r *AgentRegistry) Len() int {
+	return len(r.agents)
+}
@@ -0,0 +1,104 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// ResultsToolName is the name of the tool returned by ResultsTool
+const ResultsToolName = "run_results"
+
+// defaultSummaryTokens is the size of each result summary listed by the
+// results tool
+const defaultSummaryTokens = 100
+
+// ResultsTool gives an agent read access to the results completed so far in
+// the orchestrator's current run
+type ResultsTool struct {
+	*tools.BaseTool
+	orch *Orchestrator
+}
+
+// ResultsTool returns a tool listing the current run's completed tasks with
+// a short summary of each; the "task" argument expands one of them to its
+// full output. It lets a final editor agent assemble a deliverable from the
+// results it needs. Register it in the orchestrator's tool registry.
+func (o *Orchestrator) ResultsTool() *ResultsTool {
+	return &ResultsTool{
+		BaseTool: tools.NewBaseTool(ResultsToolName,
+			"Lists the tasks completed so far in this run with a summary of each result. Pass a task number to read its full result.",
+			map[string]interface{}{
+				"task":           "number of the task to read in full (optional)",
+				"summary_tokens": defaultSummaryTokens,
+			}),
+		orch: o,
+	}
+}
+
+// Execute lists the completed results, or returns the full output of the
+// task given by number
+func (t *ResultsTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	results := t.orch.completedResults()
+
+	if raw, ok := args["task"]; ok {
+		n, err := intArg(raw)
+		if err != nil || n < 1 || n > len(results) {
+			return "", errors.OutOfRange("task", 1, len(results)).WithContext("value", raw).WithContext("tool", ResultsToolName)
+		}
+		r := results[n-1]
+		content, err := t.orch.Content(ctx, r)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Task %d: %s (by %s)\n\n%s", n, r.Title(), r.Agent, content), nil
+	}
+
+	if len(results) == 0 {
+		return "No tasks have completed in this run yet.", nil
+	}
+	summaryTokens := defaultSummaryTokens
+	if raw, ok := args["summary_tokens"]; ok {
+		if n, err := intArg(raw); err == nil && n > 0 {
+			summaryTokens = n
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Completed tasks in this run: %d. Pass \"task\" with a number to read a full result.\n", len(results))
+	for i, r := range results {
+		fmt.Fprintf(&b, "\n%d. %s (by %s)", i+1, r.Title(), r.Agent)
+		if r.Status != StatusCompleted {
+			fmt.Fprintf(&b, " [%s]", r.Status)
+		}
+		summary, _ := llm.Compress(ctx, r.Result, summaryTokens)
+		fmt.Fprintf(&b, "\n   %s\n", strings.ReplaceAll(strings.TrimSpace(summary), "\n", "\n   "))
+	}
+	return b.String(), nil
+}
+
+// completedResults returns the results recorded so far in the current run
+func (o *Orchestrator) completedResults() []*TaskResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return append([]*TaskResult(nil), o.completed...)
+}
+
+// intArg converts a tool argument decoded from JSON or passed from Go to an int
+func intArg(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case int:
+		return n, nil
+	case float64:
+		return int(n), nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(n))
+	default:
+		return 0, errors.InvalidField("task", "must be a number")
+	}
+}
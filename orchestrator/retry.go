@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	stderrors "errors"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/task"
+)
+
+// RetryFailed re-executes the tasks that failed in the most recent parallel
+// run, as a new run, and returns the merged results: completed and
+// cancelled tasks keep their results, retried tasks get new ones. A task's
+// RetryPolicy limits how often it is retried, delays each retry and can
+// restrict retries to retryable errors; failures it excludes are returned
+// unchanged. Results are returned in the configured ResultOrder, and only
+// retried tasks are passed to OnResult. When no failed task may be retried,
+// the previous results are returned with their errors.
+func (o *Orchestrator) RetryFailed(ctx context.Context) ([]*TaskResult, error) {
+	if o.process != Parallel {
+		return nil, errors.Unsupportedf("RetryFailed requires the parallel process, not %v", o.process).WithContext("process", o.process)
+	}
+
+	o.mu.Lock()
+	previous := o.parallel
+	var retry []int
+	for i, r := range previous {
+		if r.Status == StatusFailed && o.mayRetry(i, r) {
+			retry = append(retry, i)
+		}
+	}
+	o.mu.Unlock()
+	if previous == nil {
+		return nil, errors.New(errors.ErrTaskNotFound, "no parallel run to retry")
+	}
+	if len(retry) == 0 {
+		var errs []error
+		for _, r := range previous {
+			if r.Status == StatusFailed {
+				errs = append(errs, r.Error)
+			}
+		}
+		return o.orderResults(append([]*TaskResult(nil), previous...), nil), stderrors.Join(errs...)
+	}
+
+	o.mu.Lock()
+	o.retrying = retry
+	for _, i := range retry {
+		o.retries[i]++
+	}
+	o.mu.Unlock()
+
+	defer func() {
+		o.mu.Lock()
+		o.retrying = nil
+		o.mu.Unlock()
+	}()
+	return o.Kickoff(ctx)
+}
+
+// mayRetry reports whether the policy of the task at index i allows
+// retrying its failed result. Callers must hold o.mu.
+func (o *Orchestrator) mayRetry(i int, r *TaskResult) bool {
+	policy := r.Task.Retry
+	if policy == nil {
+		return true
+	}
+	if o.retries[i] >= policy.MaxRetries {
+		return false
+	}
+	return !policy.RetryableOnly || retryable(r.Error)
+}
+
+// retryable reports whether any error in err's chain is marked retryable
+func retryable(err error) bool {
+	for ; err != nil; err = stderrors.Unwrap(err) {
+		if errors.IsRetryable(err) {
+			return true
+		}
+	}
+	return false
+}
+
+// retryBackoff waits for the backoff of a retried task, unless ctx ends
+// first
+func retryBackoff(ctx context.Context, t *task.Task) error {
+	if t.Retry == nil || t.Retry.Backoff <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(t.Retry.Backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// parallelTasks returns the tasks of a parallel run: those of the previous
+// run, including submitted ones, when RetryFailed runs, or Config.Tasks
+func (o *Orchestrator) parallelTasks() []*task.Task {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.retrying != nil {
+		return append([]*task.Task(nil), o.ranTasks...)
+	}
+	return append([]*task.Task(nil), o.tasks...)
+}
+
+// parallelLaunch returns the indices of the tasks a parallel run executes,
+// and whether RetryFailed runs. When it does, the results of the tasks it
+// does not retry are copied from the previous run and marked finished, and
+// the errors of those that failed are returned.
+func (o *Orchestrator) parallelLaunch(results []*TaskResult, finished []bool) (launch []int, retrying bool, carried []error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.retrying == nil {
+		o.retries = make(map[int]int)
+		launch := make([]int, len(results))
+		for i := range launch {
+			launch[i] = i
+		}
+		return launch, false, nil
+	}
+	for i, r := range o.parallel {
+		results[i], finished[i] = r, true
+	}
+	for _, i := range o.retrying {
+		results[i], finished[i] = nil, false
+	}
+	for i, r := range results {
+		if finished[i] && r != nil && r.Status == StatusFailed {
+			carried = append(carried, r.Error)
+		}
+	}
+	return o.retrying, true, carried
+}
+
+// retryKey is the context key of the retry count of an executing task
+type retryKey struct{}
+
+// retryFromContext returns how often the executing task has been retried
+func retryFromContext(ctx context.Context) int {
+	n, _ := ctx.Value(retryKey{}).(int)
+	return n
+}
+
+// executeRetried executes the task at index i, after its retry backoff when
+// retrying
+func (o *Orchestrator) executeRetried(ctx context.Context, i int, t *task.Task, retrying bool) (*TaskResult, error) {
+	if retrying {
+		if err := retryBackoff(ctx, t); err != nil {
+			return nil, err
+		}
+		o.mu.Lock()
+		ctx = context.WithValue(ctx, retryKey{}, o.retries[i])
+		o.mu.Unlock()
+	}
+	return o.executeTask(ctx, t)
+}
+
+// finishParallel keeps the tasks and results of a parallel run for
+// RetryFailed and returns the results in the configured order
+func (o *Orchestrator) finishParallel(tasks []*task.Task, results []*TaskResult, completion []int) []*TaskResult {
+	o.mu.Lock()
+	o.ranTasks = tasks
+	o.parallel = append([]*TaskResult(nil), results...)
+	o.mu.Unlock()
+	return o.orderResults(results, completion)
+}
@@ -0,0 +1,169 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/task"
+)
+
+// scheduled is a task of a parallel run waiting for or holding a slot
+type scheduled struct {
+	idx      int
+	priority int
+	cancel   context.CancelFunc // Set while running
+}
+
+// scheduler orders the tasks of a parallel run by priority and bounds how
+// many run at once
+type scheduler struct {
+	limit     int // 0 = unbounded
+	queue     []scheduled
+	running   map[int]scheduled
+	preempted map[int]bool
+}
+
+// newScheduler creates a scheduler running at most limit tasks at once
+func newScheduler(limit int) *scheduler {
+	return &scheduler{limit: limit, running: make(map[int]scheduled), preempted: make(map[int]bool)}
+}
+
+// push queues a task
+func (s *scheduler) push(idx, priority int) {
+	s.queue = append(s.queue, scheduled{idx: idx, priority: priority})
+}
+
+// next removes and returns the queued task to start, the one with the
+// highest priority and then the lowest index, if a slot is free
+func (s *scheduler) next() (scheduled, bool) {
+	if len(s.queue) == 0 || s.limit > 0 && len(s.running) >= s.limit {
+		return scheduled{}, false
+	}
+	best := 0
+	for i, q := range s.queue {
+		if q.priority > s.queue[best].priority || q.priority == s.queue[best].priority && q.idx < s.queue[best].idx {
+			best = i
+		}
+	}
+	next := s.queue[best]
+	s.queue = append(s.queue[:best], s.queue[best+1:]...)
+	return next, true
+}
+
+// start records a running task
+func (s *scheduler) start(t scheduled, cancel context.CancelFunc) {
+	t.cancel = cancel
+	s.running[t.idx] = t
+}
+
+// finish releases the slot of a task and reports whether it was preempted;
+// a preempted task that failed is queued again
+func (s *scheduler) finish(idx int, failed bool) (requeued bool) {
+	t := s.running[idx]
+	t.cancel()
+	delete(s.running, idx)
+	if !s.preempted[idx] {
+		return false
+	}
+	delete(s.preempted, idx)
+	if failed {
+		s.push(idx, t.priority)
+	}
+	return failed
+}
+
+// preempt cancels the running task with the lowest priority below
+// priority, the most recently added one among equals, when no slot is free.
+// It returns the index of the cancelled task.
+func (s *scheduler) preempt(priority int) (int, bool) {
+	if s.limit <= 0 || len(s.running) < s.limit {
+		return 0, false
+	}
+	victim := -1
+	for idx, t := range s.running {
+		if s.preempted[idx] || t.priority >= priority {
+			continue
+		}
+		if victim < 0 || t.priority < s.running[victim].priority || t.priority == s.running[victim].priority && idx > victim {
+			victim = idx
+		}
+	}
+	if victim < 0 {
+		return 0, false
+	}
+	s.preempted[victim] = true
+	s.running[victim].cancel()
+	return victim, true
+}
+
+// submission is a task added to a running parallel run by Submit
+type submission struct {
+	task  *task.Task
+	reply chan submitReply
+}
+
+// submitReply answers a submission with the task's index or an error
+type submitReply struct {
+	index int
+	err   error
+}
+
+// Submit adds a task to the parallel run in progress, for interactive
+// systems layered on top of a background crew. The task is scheduled by
+// its Priority among the tasks not yet started; with Preempt set and every
+// slot taken, it also cancels the running task with the lowest priority
+// below its own, which is queued again. Submit returns the task's index in
+// the run's results.
+func (o *Orchestrator) Submit(ctx context.Context, t *task.Task) (int, error) {
+	o.mu.Lock()
+	submissions, closed := o.submissions, o.submitClosed
+	o.mu.Unlock()
+	if submissions == nil {
+		return -1, errors.Unsupported("submitting tasks outside a parallel run")
+	}
+
+	s := submission{task: t, reply: make(chan submitReply, 1)}
+	select {
+	case submissions <- s:
+	case <-closed:
+		return -1, errors.Unsupported("submitting tasks outside a parallel run")
+	case <-ctx.Done():
+		return -1, ctx.Err()
+	}
+	r := <-s.reply
+	return r.index, r.err
+}
+
+// openSubmissions lets Submit add tasks to the current parallel run until
+// the returned function is called
+func (o *Orchestrator) openSubmissions() (<-chan submission, func()) {
+	submissions := make(chan submission)
+	closed := make(chan struct{})
+	o.mu.Lock()
+	o.submissions, o.submitClosed = submissions, closed
+	o.mu.Unlock()
+	return submissions, func() {
+		o.mu.Lock()
+		o.submissions, o.submitClosed = nil, nil
+		o.mu.Unlock()
+		close(closed)
+	}
+}
+
+// assignAgent returns the task with an agent, choosing the least loaded
+// agent with the required skills for a task without one
+func (o *Orchestrator) assignAgent(idx int, t *task.Task, pending map[string]int) (*task.Task, error) {
+	if t.Agent != nil {
+		return t, nil
+	}
+	candidates := o.agentsWithSkills(t.Skills)
+	if len(candidates) == 0 {
+		return nil, errors.Validationf("no agent has the skills required by task %d: %s", idx, strings.Join(t.Skills, ", ")).
+			WithContext("task_index", idx).
+			WithContext("skills", t.Skills)
+	}
+	selected := o.leastLoaded(candidates, pending)
+	pending[selected.Name]++
+	return t.WithAgent(selected), nil
+}
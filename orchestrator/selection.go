@@ -0,0 +1,109 @@
+package orchestrator
+
+import (
+	"context"
+	"strings"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/jsonutil"
+	"github.com/counhopig/gittyai/task"
+)
+
+// Assignment records how the manager chose an agent for a task
+type Assignment struct {
+	Agent      string
+	Confidence float64 // 0.0-1.0 as reported by the manager; 0 for fallbacks
+	Reason     string  // Manager's justification, when JustifyAssignments is set
+	Fallback   bool    // True if the manager's answer could not be used
+}
+
+// agentSelection is the manager's structured answer
+type agentSelection struct {
+	Agent      int     `json:"agent"`
+	Confidence float64 `json:"confidence"`
+	Reason     string  `json:"reason,omitempty"`
+}
+
+// selectAgentForTask asks the manager LLM to select the best agent for a task.
+// The manager answers with the agent's number from the list rather than its
+// name, so names mentioned in free text cannot cause a wrong match.
+func (o *Orchestrator) selectAgentForTask(ctx context.Context, t *task.Task, candidates []*agent.Agent) (*agent.Agent, *Assignment, error) {
+	if len(candidates) == 1 {
+		// Nothing to decide when a single agent has the required skills
+		return candidates[0], &Assignment{Agent: candidates[0].Name, Confidence: 1, Reason: "only agent with the required skills"}, nil
+	}
+	if o.loadBalance {
+		selected := o.leastLoaded(candidates, nil)
+		return selected, &Assignment{Agent: selected.Name, Confidence: 1, Reason: "least loaded agent with the required skills"}, nil
+	}
+
+	format := `{"agent": <number>, "confidence": <0.0-1.0>}`
+	if o.justify {
+		format = `{"agent": <number>, "confidence": <0.0-1.0>, "reason": "<one sentence>"}`
+	}
+
+	prompt, err := o.selectPrompt.Render(SelectionPromptData{
+		Agents:         describeAgents(candidates),
+		AgentList:      candidates,
+		Format:         format,
+		Description:    t.Description,
+		ExpectedOutput: t.ExpectedOutput,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, err := o.managerLLM.Generate(ctx, prompt)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	selected, assignment := parseSelection(response, candidates)
+	switch {
+	case assignment.Fallback:
+		o.logf("[Manager] Could not parse agent selection %q, using '%s'", strings.TrimSpace(response), selected.Name)
+	case assignment.Reason != "":
+		o.logf("[Manager] Selected '%s' (confidence %.2f): %s", selected.Name, assignment.Confidence, assignment.Reason)
+	default:
+		o.logf("[Manager] Selected '%s' (confidence %.2f)", selected.Name, assignment.Confidence)
+	}
+	return selected, assignment, nil
+}
+
+// parseSelection maps the manager's answer to an agent. Answers that are not
+// valid JSON are matched against agent names exactly; anything else falls
+// back to the first candidate.
+func parseSelection(response string, candidates []*agent.Agent) (*agent.Agent, *Assignment) {
+	var sel agentSelection
+	if jsonutil.Unmarshal(response, &sel) == nil {
+		if sel.Agent >= 1 && sel.Agent <= len(candidates) {
+			selected := candidates[sel.Agent-1]
+			return selected, &Assignment{
+				Agent:      selected.Name,
+				Confidence: clampConfidence(sel.Confidence),
+				Reason:     sel.Reason,
+			}
+		}
+	}
+
+	// Tolerate managers that answer with a bare agent name
+	name := strings.Trim(strings.TrimSpace(response), `"'.`)
+	for _, a := range candidates {
+		if strings.EqualFold(a.Name, name) {
+			return a, &Assignment{Agent: a.Name}
+		}
+	}
+
+	return candidates[0], &Assignment{Agent: candidates[0].Name, Fallback: true}
+}
+
+// clampConfidence bounds a reported confidence to [0, 1]
+func clampConfidence(c float64) float64 {
+	if c < 0 {
+		return 0
+	}
+	if c > 1 {
+		return 1
+	}
+	return c
+}
@@ -0,0 +1,173 @@
+package orchestrator
+
+import (
+	"context"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/limits"
+	"github.com/counhopig/gittyai/memory"
+	"github.com/counhopig/gittyai/task"
+)
+
+// Checkpoint captures the state of a run at shutdown
+type Checkpoint struct {
+	// Results holds every task completed before shutdown
+	Results []*TaskResult
+	// Pending holds the configured tasks that did not complete. Tasks are
+	// matched by idempotency key, since hierarchical mode executes copies;
+	// each result accounts for one task, so identical tasks are pending
+	// until each of them completed.
+	Pending []*task.Task
+}
+
+// Shutdown stops scheduling new tasks and waits for in-flight tasks to finish.
+// If ctx expires first, in-flight tasks are cancelled. The returned checkpoint
+// holds the partial results; the error is ctx.Err() when the deadline was hit.
+// Buffered agent memories are flushed before Shutdown returns.
+// An orchestrator that has been shut down rejects further Kickoff calls.
+func (o *Orchestrator) Shutdown(ctx context.Context) (*Checkpoint, error) {
+	o.mu.Lock()
+	if !o.draining {
+		o.draining = true
+		if o.active == 0 {
+			close(o.drained)
+		}
+	}
+	o.mu.Unlock()
+
+	var err error
+	select {
+	case <-o.drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+		o.mu.Lock()
+		if o.cancelRun != nil {
+			o.cancelRun()
+		}
+		o.mu.Unlock()
+	}
+
+	// Memories are flushed even when ctx has expired, within their own bound
+	flushCtx, cancelFlush := context.WithTimeout(context.WithoutCancel(ctx), memoryFlushTimeout)
+	defer cancelFlush()
+	if flushErr := o.flushMemory(flushCtx); err == nil {
+		err = flushErr
+	}
+	return o.checkpoint(), err
+}
+
+// memoryFlushTimeout bounds flushing buffered memories when a run completes
+// or the orchestrator shuts down
+const memoryFlushTimeout = 10 * time.Second
+
+// flushMemory writes the buffered records of the agents' memories
+func (o *Orchestrator) flushMemory(ctx context.Context) error {
+	for _, a := range o.agents.All() {
+		if a.Memory == nil {
+			continue
+		}
+		if err := memory.Flush(ctx, a.Memory); err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to flush memory", err).WithContext("agent", a.Name)
+		}
+	}
+	return nil
+}
+
+// beginRun resets the run state at the start of Kickoff
+func (o *Orchestrator) beginRun(cancel context.CancelFunc, id string, meter *limits.Meter) error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.draining {
+		return errors.ShuttingDown("orchestrator")
+	}
+	o.cancelRun = cancel
+	o.runID = id
+	o.completed = nil
+	o.final = nil
+	o.plan = nil
+	o.meter = meter
+	o.sharedTokens = 0
+	o.runStart = time.Now()
+	o.progress = Progress{Total: len(o.tasks)}
+	return nil
+}
+
+// canSchedule reports whether another task may be started
+func (o *Orchestrator) canSchedule(ctx context.Context) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if o.draining {
+		return errors.ShuttingDown("orchestrator")
+	}
+	return nil
+}
+
+// startTask registers an in-flight task unless the orchestrator is draining
+func (o *Orchestrator) startTask() error {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.draining {
+		return errors.ShuttingDown("orchestrator")
+	}
+	o.active++
+	return nil
+}
+
+// finishTask unregisters an in-flight task and signals Shutdown once drained
+func (o *Orchestrator) finishTask() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.active--
+	if o.draining && o.active == 0 {
+		close(o.drained)
+	}
+}
+
+// recordResult keeps a completed result for checkpointing
+func (o *Orchestrator) recordResult(result *TaskResult) {
+	o.mu.Lock()
+	o.completed = append(o.completed, result)
+	o.mu.Unlock()
+}
+
+// checkpoint snapshots completed results and pending tasks
+func (o *Orchestrator) checkpoint() *Checkpoint {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	results := make([]*TaskResult, len(o.completed))
+	copy(results, o.completed)
+
+	done := make(map[string]int, len(results))
+	for _, r := range results {
+		key := r.Key
+		if key == "" {
+			key = r.Task.Key()
+		}
+		done[key]++
+	}
+
+	pending := make([]*task.Task, 0)
+	for _, t := range o.tasks {
+		if key := t.Key(); done[key] > 0 {
+			done[key]--
+			continue
+		}
+		pending = append(pending, t)
+	}
+
+	return &Checkpoint{
+		Results: results,
+		Pending: pending,
+	}
+}
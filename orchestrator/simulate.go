@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/llm"
+)
+
+// Markers identifying the default manager prompts, matched by simulated
+// responses. Custom SelectionPrompt or PlanningPrompt templates get
+// boilerplate answers unless they contain the same phrases.
+const (
+	selectionPromptMarker = "responsible for assigning tasks to the best-suited agent"
+	planPromptMarker      = "Create an execution plan"
+)
+
+// SimulatedManagerResponses returns canned manager answers for a simulated
+// hierarchical run: the first candidate is selected for every task, and a
+// goal is planned as one step per named agent, each using the previous
+// results. Pass them in llm.SimulatorConfig.Responses.
+func SimulatedManagerResponses(agentNames ...string) []llm.SimulatedResponse {
+	plan := make([]PlanStep, 0, len(agentNames))
+	for i, name := range agentNames {
+		plan = append(plan, PlanStep{
+			TaskDescription: fmt.Sprintf("Simulated step %d for %s", i+1, name),
+			AgentName:       name,
+			ExpectedOutput:  "Simulated output",
+			UseContext:      i > 0,
+		})
+	}
+	planJSON, _ := json.Marshal(plan)
+
+	return []llm.SimulatedResponse{
+		{Match: selectionPromptMarker, Content: `{"agent": 1, "confidence": 1, "reason": "simulated selection"}`},
+		{Match: planPromptMarker, Content: string(planJSON)},
+	}
+}
+
+// NewSimulatedLLM returns a synthetic LLM answering manager prompts with
+// SimulatedManagerResponses for agents and everything else with boilerplate,
+// optionally echoing the prompt
+func NewSimulatedLLM(echo bool, agents ...*agent.Agent) *llm.Simulator {
+	names := make([]string, 0, len(agents))
+	for _, a := range agents {
+		names = append(names, a.Name)
+	}
+	return llm.NewSimulator(llm.SimulatorConfig{
+		Responses: SimulatedManagerResponses(names...),
+		Echo:      echo,
+	})
+}
@@ -0,0 +1,66 @@
+package orchestrator
+
+import (
+	"context"
+
+	"github.com/counhopig/gittyai/task"
+)
+
+// speculation is a task started before its predecessor finished. Its result
+// is only accepted into checkpoints and the result store once the run keeps
+// it, since a failing predecessor discards it.
+type speculation struct {
+	orch   *Orchestrator
+	idx    int
+	cancel context.CancelFunc
+	done   chan struct{}
+	result *TaskResult
+	err    error
+}
+
+// speculate starts task idx in the background when speculative execution is
+// on and the task references no previous task. It returns nil when the task
+// must wait for its predecessor instead.
+func (o *Orchestrator) speculate(ctx context.Context, idx int) *speculation {
+	if !o.speculative || o.debug != nil || idx >= len(o.tasks) || !independent(o.tasks[idx]) {
+		return nil
+	}
+	if o.canSchedule(ctx) != nil {
+		return nil
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	s := &speculation{orch: o, idx: idx, cancel: cancel, done: make(chan struct{})}
+	o.logf("[Task %d/%d] Starting speculatively: %s", idx+1, len(o.tasks), o.tasks[idx].Description)
+	go func() {
+		defer close(s.done)
+		s.result, s.err = o.runTask(ctx, o.tasks[idx])
+	}()
+	return s
+}
+
+// wait returns the outcome of the speculative task, accepting its result
+func (s *speculation) wait() (*TaskResult, error) {
+	<-s.done
+	s.cancel()
+	if s.err != nil {
+		return nil, s.err
+	}
+	s.orch.acceptResult(s.result)
+	return s.result, nil
+}
+
+// discard cancels the speculative task and waits for it to return, so no
+// task outlives the run
+func (s *speculation) discard() {
+	if s != nil {
+		s.cancel()
+		<-s.done
+	}
+}
+
+// independent reports whether a task can run without the output of the
+// tasks before it
+func independent(t *task.Task) bool {
+	return len(t.Context) == 0
+}
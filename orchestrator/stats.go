@@ -0,0 +1,289 @@
+package orchestrator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/limits"
+	"github.com/counhopig/gittyai/locale"
+)
+
+// RunStats summarizes a run. It is computed from the run's events, so a
+// consumer of the event stream can rebuild it from the same data.
+type RunStats struct {
+	RunID    string
+	WallTime time.Duration
+	Failed   bool
+
+	Tasks       int // Tasks that finished, successfully or not
+	FailedTasks int
+	ReusedTasks int // Tasks answered from a checkpoint or result store
+	Retries     int // Tasks re-executed by RetryFailed
+
+	// Totals for the run, including manager calls not attributed to an agent
+	LLMCalls         int
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int     // Prompt tokens served from the provider's prompt cache
+	Cost             float64 // USD, for models with a configured price
+	Continuations    int     // Follow-up requests for truncated responses
+	// SharedPrefixTokens are prompt tokens of parallel tasks that repeat a
+	// prefix shared with another task, sent for the provider's prompt cache
+	// to serve (see Config.DedupPrompts)
+	SharedPrefixTokens int
+
+	SlowestTask     string
+	SlowestDuration time.Duration
+
+	// Agents breaks down task usage by agent name
+	Agents map[string]*AgentRunStats
+	// Tools breaks down tool executions by tool name
+	Tools map[string]*ToolRunStats
+}
+
+// ToolRunStats summarizes the executions of a tool in a run
+type ToolRunStats struct {
+	Calls       int
+	Failures    int
+	Duration    time.Duration // Total time spent in the tool
+	MaxDuration time.Duration
+	OutputBytes int
+}
+
+// AvgDuration returns the mean duration of the tool's executions
+func (t *ToolRunStats) AvgDuration() time.Duration {
+	if t.Calls == 0 {
+		return 0
+	}
+	return t.Duration / time.Duration(t.Calls)
+}
+
+// FailureRate returns the fraction of the tool's executions that failed
+func (t *ToolRunStats) FailureRate() float64 {
+	if t.Calls == 0 {
+		return 0
+	}
+	return float64(t.Failures) / float64(t.Calls)
+}
+
+// AgentRunStats summarizes the tasks an agent executed in a run
+type AgentRunStats struct {
+	Tasks            int
+	FailedTasks      int
+	LLMCalls         int
+	PromptTokens     int
+	CompletionTokens int
+	CachedTokens     int
+	Cost             float64
+	Duration         time.Duration
+	Retries          int // Tasks re-executed by RetryFailed
+	Continuations    int // Follow-up requests for truncated responses
+}
+
+// CacheHitRate returns the fraction of tasks answered without executing them
+func (s *RunStats) CacheHitRate() float64 {
+	if s.Tasks == 0 {
+		return 0
+	}
+	return float64(s.ReusedTasks) / float64(s.Tasks)
+}
+
+// PromptCacheRate returns the fraction of prompt tokens served from the
+// provider's prompt cache
+func (s *RunStats) PromptCacheRate() float64 {
+	if s.PromptTokens == 0 {
+		return 0
+	}
+	return float64(s.CachedTokens) / float64(s.PromptTokens)
+}
+
+// String formats the statistics as a report section
+func (s *RunStats) String() string {
+	return s.format(locale.English)
+}
+
+// format formats the statistics under a localized heading
+func (s *RunStats) format(labels locale.Labels) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "=== %s ===\n\n", labels.Statistics)
+	fmt.Fprintf(&b, "Run: %s\n", s.RunID)
+	fmt.Fprintf(&b, "Wall time: %v\n", s.WallTime.Round(time.Millisecond))
+	fmt.Fprintf(&b, "Tasks: %d (%d failed, %d retried, %d reused, cache hit rate %.0f%%)\n", s.Tasks, s.FailedTasks, s.Retries, s.ReusedTasks, s.CacheHitRate()*100)
+	fmt.Fprintf(&b, "LLM calls: %d (%d continuations)\n", s.LLMCalls, s.Continuations)
+	fmt.Fprintf(&b, "Tokens: %d prompt (%d cached, %.0f%%), %d completion\n", s.PromptTokens, s.CachedTokens, s.PromptCacheRate()*100, s.CompletionTokens)
+	if s.SharedPrefixTokens > 0 {
+		fmt.Fprintf(&b, "Shared prompt prefixes: %d tokens deduplicated\n", s.SharedPrefixTokens)
+	}
+	fmt.Fprintf(&b, "Cost: $%.4f\n", s.Cost)
+	if s.SlowestTask != "" {
+		fmt.Fprintf(&b, "Slowest task: %s (%v)\n", s.SlowestTask, s.SlowestDuration.Round(time.Millisecond))
+	}
+
+	names := make([]string, 0, len(s.Agents))
+	for name := range s.Agents {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		a := s.Agents[name]
+		fmt.Fprintf(&b, "Agent %s: %d tasks (%d retried), %d LLM calls (%d continuations), %d tokens, $%.4f, %v\n",
+			name, a.Tasks, a.Retries, a.LLMCalls, a.Continuations, a.PromptTokens+a.CompletionTokens, a.Cost, a.Duration.Round(time.Millisecond))
+	}
+
+	names = names[:0]
+	for name := range s.Tools {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		t := s.Tools[name]
+		fmt.Fprintf(&b, "Tool %s: %d calls, %.0f%% failed, avg %v, max %v, %d bytes output\n",
+			name, t.Calls, t.FailureRate()*100, t.AvgDuration().Round(time.Millisecond), t.MaxDuration.Round(time.Millisecond), t.OutputBytes)
+	}
+	return b.String()
+}
+
+// RunStats returns statistics for the most recent run, or nil before the
+// first run
+func (o *Orchestrator) RunStats() *RunStats {
+	return o.stats.snapshot()
+}
+
+// FormatReport formats results followed by the run statistics
+func FormatReport(results []*TaskResult, stats *RunStats) string {
+	return FormatReportIn(results, stats, locale.English)
+}
+
+// FormatReportIn formats results and run statistics with localized headings
+func FormatReportIn(results []*TaskResult, stats *RunStats, labels locale.Labels) string {
+	report := FormatResultsIn(results, labels)
+	if stats != nil {
+		report += stats.format(labels)
+	}
+	return report
+}
+
+// Report formats results and the statistics of the most recent run in the
+// orchestrator's language
+func (o *Orchestrator) Report(results []*TaskResult) string {
+	return FormatReportIn(results, o.RunStats(), locale.LabelsFor(o.language))
+}
+
+// setUsage copies resource usage onto a completion event
+func (o *Orchestrator) setUsage(ev *events.Event, u limits.Usage) {
+	tokens := u.TotalTokens()
+	ev.LLMCalls = u.LLMCalls
+	ev.PromptTokens = tokens.PromptTokens
+	ev.CompletionTokens = tokens.CompletionTokens
+	ev.CachedTokens = tokens.CachedTokens
+	ev.CostUSD = u.Cost(o.prices)
+	ev.Continuations = u.Continuations
+	if len(u.Tools) > 0 {
+		ev.Tools = make(map[string]events.ToolUsage, len(u.Tools))
+		for name, t := range u.Tools {
+			ev.Tools[name] = events.ToolUsage{
+				Calls:         t.Calls,
+				Failures:      t.Failures,
+				DurationMS:    t.Duration.Milliseconds(),
+				MaxDurationMS: t.MaxDuration.Milliseconds(),
+				OutputBytes:   t.OutputBytes,
+			}
+		}
+	}
+}
+
+// statsCollector builds RunStats from the events of a run
+type statsCollector struct {
+	mu    sync.Mutex
+	stats *RunStats
+}
+
+// record updates the statistics with an event
+func (c *statsCollector) record(ev events.Event) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if ev.Type == events.RunStarted || c.stats == nil {
+		c.stats = &RunStats{RunID: ev.RunID, Agents: make(map[string]*AgentRunStats), Tools: make(map[string]*ToolRunStats)}
+	}
+	s := c.stats
+
+	switch ev.Type {
+	case events.TaskCompleted, events.TaskFailed:
+		d := time.Duration(ev.DurationMS) * time.Millisecond
+		s.Tasks++
+		if ev.Reused {
+			s.ReusedTasks++
+		}
+		if d > s.SlowestDuration || s.SlowestTask == "" {
+			s.SlowestTask, s.SlowestDuration = ev.Task, d
+		}
+
+		a, ok := s.Agents[ev.Agent]
+		if !ok {
+			a = &AgentRunStats{}
+			s.Agents[ev.Agent] = a
+		}
+		a.Tasks++
+		a.LLMCalls += ev.LLMCalls
+		a.PromptTokens += ev.PromptTokens
+		a.CompletionTokens += ev.CompletionTokens
+		a.CachedTokens += ev.CachedTokens
+		a.Cost += ev.CostUSD
+		a.Duration += d
+		a.Continuations += ev.Continuations
+		if ev.Retry > 0 {
+			s.Retries++
+			a.Retries++
+		}
+		if ev.Type == events.TaskFailed {
+			s.FailedTasks++
+			a.FailedTasks++
+		}
+	case events.RunCompleted, events.RunFailed:
+		s.WallTime = time.Duration(ev.DurationMS) * time.Millisecond
+		s.Failed = ev.Type == events.RunFailed
+		s.LLMCalls = ev.LLMCalls
+		s.PromptTokens = ev.PromptTokens
+		s.CompletionTokens = ev.CompletionTokens
+		s.CachedTokens = ev.CachedTokens
+		s.Cost = ev.CostUSD
+		s.Continuations = ev.Continuations
+		s.SharedPrefixTokens = ev.SharedPrefixTokens
+		for name, t := range ev.Tools {
+			s.Tools[name] = &ToolRunStats{
+				Calls:       t.Calls,
+				Failures:    t.Failures,
+				Duration:    time.Duration(t.DurationMS) * time.Millisecond,
+				MaxDuration: time.Duration(t.MaxDurationMS) * time.Millisecond,
+				OutputBytes: t.OutputBytes,
+			}
+		}
+	}
+}
+
+// snapshot returns a copy of the current statistics
+func (c *statsCollector) snapshot() *RunStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stats == nil {
+		return nil
+	}
+	s := *c.stats
+	s.Agents = make(map[string]*AgentRunStats, len(c.stats.Agents))
+	for name, a := range c.stats.Agents {
+		copied := *a
+		s.Agents[name] = &copied
+	}
+	s.Tools = make(map[string]*ToolRunStats, len(c.stats.Tools))
+	for name, t := range c.stats.Tools {
+		copied := *t
+		s.Tools[name] = &copied
+	}
+	return &s
+}
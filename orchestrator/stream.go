@@ -0,0 +1,46 @@
+package orchestrator
+
+// ResultOrder is the order of the results returned by a parallel run
+type ResultOrder int
+
+const (
+	// TaskOrder returns results in the order of Config.Tasks
+	TaskOrder ResultOrder = iota
+	// CompletionOrder returns results in the order tasks finished; tasks
+	// cancelled before finishing come last, in task order
+	CompletionOrder
+)
+
+// ResultHandler receives each result of a parallel run as soon as its task
+// finishes, with the task's index in Config.Tasks. Calls are made one at a
+// time from the goroutine running Kickoff, so a slow handler delays the
+// collection of later results but not their execution.
+type ResultHandler func(index int, result *TaskResult)
+
+// yield passes a finished result to the result handler
+func (o *Orchestrator) yield(index int, result *TaskResult) {
+	result.Index = index
+	if o.onResult != nil {
+		o.onResult(index, result)
+	}
+}
+
+// orderResults arranges the results of a parallel run in the configured
+// order; finished lists task indices in the order their tasks finished
+func (o *Orchestrator) orderResults(results []*TaskResult, finished []int) []*TaskResult {
+	if o.resultOrder != CompletionOrder {
+		return results
+	}
+	ordered := make([]*TaskResult, 0, len(results))
+	done := make([]bool, len(results))
+	for _, i := range finished {
+		ordered = append(ordered, results[i])
+		done[i] = true
+	}
+	for i, r := range results {
+		if !done[i] {
+			ordered = append(ordered, r)
+		}
+	}
+	return ordered
+}
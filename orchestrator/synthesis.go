@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/counhopig/gittyai/citation"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/locale"
+)
+
+// FinalResult is the manager's synthesized answer for a hierarchical run
+type FinalResult struct {
+	Goal   string // Goal the answer addresses; empty when the run had no goal
+	Answer string // Final deliverable combining the step outputs
+	Steps  int    // Number of task results the answer was synthesized from
+	RunID  string
+	// Sources are the sources of the synthesized task results
+	Sources []citation.Source
+}
+
+// FinalResult returns the synthesized answer of the most recent run, or nil
+// if synthesis was disabled, not applicable or the run failed before it
+func (o *Orchestrator) FinalResult() *FinalResult {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.final
+}
+
+// synthesize asks the manager to combine step outputs into a single answer
+func (o *Orchestrator) synthesize(ctx context.Context, goal string, results []*TaskResult) (*FinalResult, error) {
+	var outputs strings.Builder
+	var sources []citation.Source
+	steps := 0
+	for _, r := range results {
+		if r == nil || r.Status != StatusCompleted {
+			continue
+		}
+		steps++
+		sources = citation.Merge(sources, r.Sources)
+		// Drop carried-forward context the step was given; only its own output matters
+		fmt.Fprintf(&outputs, "\n--- %s (by %s) ---\n%s\n", r.Title(), r.Agent, r.Result)
+	}
+	if steps == 0 {
+		return nil, errors.Internal("no completed tasks to synthesize").WithContext("mode", "hierarchical")
+	}
+
+	objective := goal
+	if objective == "" {
+		objective = "Complete all of the tasks below."
+	}
+
+	prompt := fmt.Sprintf(`You are a manager delivering the final result of a multi-agent project.
+
+Goal: %s
+
+Outputs of the completed tasks:
+%s
+Combine these outputs into a single, complete deliverable that answers the goal.
+Resolve contradictions, remove repetition and do not mention the individual agents.
+If the outputs are not sufficient to fully achieve the goal, say what is missing at the end.`, objective, outputs.String())
+	if instruction := locale.Instruction(locale.FromContext(ctx)); instruction != "" {
+		prompt += "\n" + instruction
+	}
+
+	o.logf("[Manager] Synthesizing final result...")
+
+	answer, err := o.managerLLM.Generate(ctx, prompt)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "manager failed to synthesize final result", err).
+			WithContext("steps", steps)
+	}
+
+	return &FinalResult{
+		Goal:    goal,
+		Answer:  strings.TrimSpace(answer),
+		Steps:   steps,
+		RunID:   o.RunID(),
+		Sources: sources,
+	}, nil
+}
+
+// setFinal records the final result of the current run
+func (o *Orchestrator) setFinal(final *FinalResult) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.final = final
+}
@@ -0,0 +1,144 @@
+// Package postprocess provides chains of processors applied to task output,
+// so common output massaging lives in configuration rather than in callers.
+package postprocess
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/jsonutil"
+)
+
+// Processor transforms or consumes a task's output
+type Processor interface {
+	Process(ctx context.Context, output string) (string, error)
+}
+
+// Func adapts a function to the Processor interface
+type Func func(ctx context.Context, output string) (string, error)
+
+// Process calls f
+func (f Func) Process(ctx context.Context, output string) (string, error) {
+	return f(ctx, output)
+}
+
+// Chain applies processors in order, each receiving the previous output
+type Chain []Processor
+
+// Process runs the chain, stopping at the first error
+func (c Chain) Process(ctx context.Context, output string) (string, error) {
+	for i, p := range c {
+		var err error
+		output, err = p.Process(ctx, output)
+		if err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "post-processor failed", err).WithContext("step", i)
+		}
+	}
+	return output, nil
+}
+
+// TrimSpace removes leading and trailing whitespace
+func TrimSpace() Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		return strings.TrimSpace(output), nil
+	})
+}
+
+// fencePattern matches a markdown code fence, with an optional language tag
+var fencePattern = regexp.MustCompile("(?s)^\\s*```[\\w+-]*[ \\t]*\\n(.*?)\\n?```\\s*$")
+
+// StripFences removes a markdown code fence wrapping the whole output.
+// Output that is not fenced is returned unchanged.
+func StripFences() Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		if m := fencePattern.FindStringSubmatch(output); m != nil {
+			return m[1], nil
+		}
+		return output, nil
+	})
+}
+
+// ExtractJSON keeps the first JSON object or array in the output, dropping
+// surrounding prose and fences and repairing trailing commas and truncation
+func ExtractJSON() Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		raw, err := jsonutil.Extract(output)
+		if err != nil {
+			return "", errors.Wrap(errors.ErrInvalidFormat, "no valid JSON in output", err)
+		}
+		return raw, nil
+	})
+}
+
+// Decode unmarshals the JSON in the output into v and passes the output
+// through unchanged. v is typically a pointer to a struct.
+func Decode(v interface{}) Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		if err := jsonutil.Unmarshal(output, v); err != nil {
+			return "", errors.Wrap(errors.ErrInvalidFormat, "failed to decode output", err)
+		}
+		return output, nil
+	})
+}
+
+// KeyPlaceholder in a WriteFile path is replaced with the key of the task
+// whose output is written, so a processor applied to every task of a run
+// writes each to its own file
+const KeyPlaceholder = "{key}"
+
+// taskKey is the context key of the task being post-processed
+type taskKey struct{}
+
+// NewTaskContext returns a context carrying the key of the task whose
+// output is processed
+func NewTaskContext(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, taskKey{}, key)
+}
+
+// TaskFromContext returns the task key carried by ctx, or ""
+func TaskFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(taskKey{}).(string)
+	return key
+}
+
+// WriteFile writes the output to path, creating parent directories, and
+// passes it through unchanged. KeyPlaceholder in path is replaced with the
+// task key carried by ctx.
+func WriteFile(path string) Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		path := path
+		if strings.Contains(path, KeyPlaceholder) {
+			key := TaskFromContext(ctx)
+			if key == "" {
+				return "", errors.MissingConfig("task key").WithContext("path", path)
+			}
+			path = strings.ReplaceAll(path, KeyPlaceholder, key)
+		}
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "failed to create output directory", err).WithContext("path", path)
+		}
+		if err := os.WriteFile(path, []byte(output), 0o644); err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "failed to write output", err).WithContext("path", path)
+		}
+		return output, nil
+	})
+}
+
+// Uploader stores output in a remote store such as S3
+type Uploader interface {
+	Put(ctx context.Context, key string, data []byte) error
+}
+
+// Upload stores the output under key and passes it through unchanged
+func Upload(u Uploader, key string) Processor {
+	return Func(func(ctx context.Context, output string) (string, error) {
+		if err := u.Put(ctx, key, []byte(output)); err != nil {
+			return "", errors.Wrap(errors.ErrAPICall, "failed to upload output", err).WithContext("key", key)
+		}
+		return output, nil
+	})
+}
@@ -0,0 +1,54 @@
+package postprocess
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestChain(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "out", "result.json")
+	var decoded struct {
+		Title string `json:"title"`
+	}
+
+	chain := Chain{StripFences(), ExtractJSON(), Decode(&decoded), WriteFile(path)}
+	output := "```json\nHere you go: {\"title\": \"Go\"} hope this helps\n```"
+
+	got, err := chain.Process(context.Background(), output)
+	if err != nil {
+		t.Fatalf("Process() unexpected error: %v", err)
+	}
+	if got != `{"title": "Go"}` {
+		t.Errorf("Process() = %q, want the JSON object", got)
+	}
+	if decoded.Title != "Go" {
+		t.Errorf("decoded.Title = %q, want %q", decoded.Title, "Go")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != got {
+		t.Errorf("written file = %q, %v; want %q", data, err, got)
+	}
+
+	if _, err := ExtractJSON().Process(context.Background(), "no json here"); err == nil {
+		t.Error("ExtractJSON() on prose: expected error")
+	}
+	if got, _ := StripFences().Process(context.Background(), "plain"); got != "plain" {
+		t.Errorf("StripFences() on unfenced output = %q, want unchanged", got)
+	}
+
+	// Each task writes its own file
+	perTask := WriteFile(filepath.Join(dir, "out", KeyPlaceholder+".txt"))
+	for _, key := range []string{"a", "b"} {
+		if _, err := perTask.Process(NewTaskContext(context.Background(), key), key); err != nil {
+			t.Fatalf("Process(%s) unexpected error: %v", key, err)
+		}
+		if data, _ := os.ReadFile(filepath.Join(dir, "out", key+".txt")); string(data) != key {
+			t.Errorf("file of task %s = %q, want %q", key, data, key)
+		}
+	}
+	if _, err := perTask.Process(context.Background(), "x"); err == nil {
+		t.Error("WriteFile() with a key placeholder outside a task: expected error")
+	}
+}
@@ -0,0 +1,107 @@
+// Package presets provides reusable agent archetypes with tuned roles, goals
+// and backstories. Use agent.FromPreset to create an agent from one.
+package presets
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Built-in preset names
+const (
+	Researcher = "researcher"
+	Writer     = "writer"
+	Critic     = "critic"
+	Coder      = "coder"
+	Summarizer = "summarizer"
+	Planner    = "planner"
+)
+
+// Preset is an agent archetype
+type Preset struct {
+	Name      string
+	Role      string
+	Goal      string
+	Backstory string
+	Skills    []string
+	// Tools are the names of tools the archetype works best with; register
+	// them in the tool registry shared by the crew
+	Tools []string
+}
+
+var builtin = map[string]Preset{
+	Researcher: {
+		Name: Researcher,
+		Role: "Senior Research Analyst",
+		Goal: "Find accurate, current and well-sourced information on the topic and report the key facts",
+		Backstory: "You spent a decade as an investigative analyst. You cross-check every claim against several " +
+			"sources, note where sources disagree, and clearly separate facts from speculation.",
+		Skills: []string{"research", "analysis"},
+		Tools:  []string{"web_search", "web_scraper"},
+	},
+	Writer: {
+		Name: Writer,
+		Role: "Content Writer",
+		Goal: "Turn notes and research into clear, engaging and well-structured prose for the intended audience",
+		Backstory: "You are an experienced editor-turned-writer. You favor plain language, short paragraphs and " +
+			"concrete examples, and you never invent facts that are not in your source material.",
+		Skills: []string{"writing"},
+	},
+	Critic: {
+		Name: Critic,
+		Role: "Critical Reviewer",
+		Goal: "Find errors, gaps, unsupported claims and unclear passages, and suggest specific improvements",
+		Backstory: "You review work for a demanding publication. You are direct but constructive: every problem " +
+			"you raise comes with its location and a concrete fix, ordered by importance.",
+		Skills: []string{"review"},
+	},
+	Coder: {
+		Name: Coder,
+		Role: "Software Engineer",
+		Goal: "Write correct, readable and tested code that solves the task with the simplest working design",
+		Backstory: "You are a pragmatic senior engineer. You follow the conventions of the surrounding code, " +
+			"handle errors explicitly and explain any trade-offs you make.",
+		Skills: []string{"coding"},
+		Tools:  []string{"code_interpreter", "file_reader"},
+	},
+	Summarizer: {
+		Name: Summarizer,
+		Role: "Summarization Specialist",
+		Goal: "Condense material into a faithful summary that keeps every key point and drops the rest",
+		Backstory: "You prepare briefings for busy executives. You preserve numbers, names and decisions exactly, " +
+			"and never add information that is not in the source.",
+		Skills: []string{"summarization"},
+	},
+	Planner: {
+		Name: Planner,
+		Role: "Project Planner",
+		Goal: "Break goals into concrete, ordered steps with clear owners, dependencies and completion criteria",
+		Backstory: "You have planned projects of every size. You surface risks and open questions early and " +
+			"prefer small steps that can be verified over large ones that cannot.",
+		Skills: []string{"planning"},
+	},
+}
+
+// Get returns the preset with the given name, matched case-insensitively
+func Get(name string) (Preset, error) {
+	p, ok := builtin[strings.ToLower(strings.TrimSpace(name))]
+	if !ok {
+		return Preset{}, errors.NotFound("preset", name).WithContext("available", Names())
+	}
+	// Copy slices so callers cannot modify the built-in presets
+	p.Skills = append([]string(nil), p.Skills...)
+	p.Tools = append([]string(nil), p.Tools...)
+	return p, nil
+}
+
+// Names returns the sorted names of all presets
+func Names() []string {
+	names := make([]string, 0, len(builtin))
+	for name := range builtin {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,29 @@
+package presets
+
+import (
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestGet(t *testing.T) {
+	for _, name := range Names() {
+		p, err := Get(name)
+		if err != nil {
+			t.Fatalf("Get(%q) unexpected error: %v", name, err)
+		}
+		if p.Name != name || p.Role == "" || p.Goal == "" || p.Backstory == "" {
+			t.Errorf("Get(%q) = %+v, want a complete preset", name, p)
+		}
+	}
+
+	p, _ := Get("Researcher")
+	p.Tools[0] = "changed"
+	if again, _ := Get(Researcher); again.Tools[0] == "changed" {
+		t.Error("Get() returned a preset sharing slices with the built-in")
+	}
+
+	if _, err := Get("astronaut"); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("Get() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,136 @@
+package prompts
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"sync"
+	"text/template"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Template is a named, versioned prompt template
+type Template struct {
+	Name    string
+	Version string
+	Source  string
+	Hash    string // SHA-256 of Source, for auditing which text was used
+
+	tmpl *template.Template
+}
+
+// Ref returns the canonical "name@version" reference of the template
+func (t *Template) Ref() string {
+	return t.Name + "@" + t.Version
+}
+
+// Render executes the template with the given data
+func (t *Template) Render(data interface{}) (string, error) {
+	var sb strings.Builder
+	if err := t.tmpl.Execute(&sb, data); err != nil {
+		return "", errors.Wrap(errors.ErrInvalidFormat, "failed to render prompt template", err).WithContext("prompt", t.Ref())
+	}
+	return sb.String(), nil
+}
+
+// Library is a registry of prompt templates. Each name may have several
+// versions; the most recently registered version is the latest.
+type Library struct {
+	mu        sync.RWMutex
+	templates map[string]map[string]*Template
+	order     map[string][]string // Versions of each prompt in registration order
+}
+
+// NewLibrary creates an empty prompt library
+func NewLibrary() *Library {
+	return &Library{
+		templates: make(map[string]map[string]*Template),
+		order:     make(map[string][]string),
+	}
+}
+
+// Parse creates a template outside of a library, e.g. for a built-in prompt
+func Parse(name, version, source string) (*Template, error) {
+	if name == "" {
+		return nil, errors.RequiredField("prompt name")
+	}
+	if version == "" {
+		return nil, errors.RequiredField("prompt version").WithContext("prompt", name)
+	}
+	if strings.Contains(name, "@") || strings.Contains(version, "@") {
+		return nil, errors.InvalidField("prompt", "name and version must not contain '@'").WithContext("prompt", name)
+	}
+
+	tmpl, err := template.New(name + "@" + version).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "failed to parse prompt template", err).
+			WithContext("prompt", name+"@"+version)
+	}
+
+	sum := sha256.Sum256([]byte(source))
+	return &Template{
+		Name:    name,
+		Version: version,
+		Source:  source,
+		Hash:    hex.EncodeToString(sum[:]),
+		tmpl:    tmpl,
+	}, nil
+}
+
+// Register parses and adds a template version. Registered versions are
+// immutable: registering the same name and version twice is an error.
+func (l *Library) Register(name, version, source string) error {
+	t, err := Parse(name, version, source)
+	if err != nil {
+		return err
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	versions, ok := l.templates[name]
+	if !ok {
+		versions = make(map[string]*Template)
+		l.templates[name] = versions
+	}
+	if _, exists := versions[version]; exists {
+		return errors.Validationf("prompt %s@%s already registered", name, version)
+	}
+
+	versions[version] = t
+	l.order[name] = append(l.order[name], version)
+	return nil
+}
+
+// Get resolves a reference of the form "name@version" or "name" (latest)
+func (l *Library) Get(ref string) (*Template, error) {
+	name, version, _ := strings.Cut(ref, "@")
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions, ok := l.templates[name]
+	if !ok {
+		return nil, errors.NotFound("prompt", name)
+	}
+	if version == "" {
+		order := l.order[name]
+		version = order[len(order)-1]
+	}
+	t, ok := versions[version]
+	if !ok {
+		return nil, errors.NotFound("prompt version", ref)
+	}
+	return t, nil
+}
+
+// Versions returns the registered versions of a prompt in registration order
+func (l *Library) Versions(name string) []string {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+
+	versions := make([]string, len(l.order[name]))
+	copy(versions, l.order[name])
+	return versions
+}
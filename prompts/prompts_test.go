@@ -0,0 +1,50 @@
+package prompts
+
+import (
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestLibrary_Versions(t *testing.T) {
+	lib := NewLibrary()
+	if err := lib.Register("summary", "v1", "Summarize: {{.}}"); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+	if err := lib.Register("summary", "v2", "Briefly summarize: {{.}}"); err != nil {
+		t.Fatalf("Register() unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"summary@v1", "Summarize: text"},
+		{"summary@v2", "Briefly summarize: text"},
+		{"summary", "Briefly summarize: text"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			tmpl, err := lib.Get(tt.ref)
+			if err != nil {
+				t.Fatalf("Get(%q) unexpected error: %v", tt.ref, err)
+			}
+			got, err := tmpl.Render("text")
+			if err != nil {
+				t.Fatalf("Render() unexpected error: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("Render() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	if err := lib.Register("summary", "v1", "changed"); err == nil {
+		t.Error("Register() expected error when overwriting a version")
+	}
+
+	if _, err := lib.Get("summary@v3"); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("Get() error = %v, want not found", err)
+	}
+}
@@ -0,0 +1,257 @@
+// Package quota enforces usage quotas per agent and per provider key, so a
+// single crew in a shared deployment cannot exhaust the organization's API
+// quota. Daily token usage is persisted in a Store and survives restarts.
+package quota
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// Limit caps the usage of one agent or key. Zero values mean unlimited.
+type Limit struct {
+	// MaxTokensPerDay caps prompt and completion tokens per UTC day
+	MaxTokensPerDay int
+	// MaxCallsPerRun caps LLM calls within one run; calls made outside a
+	// run (without a run ID in the context) are not counted
+	MaxCallsPerRun int
+}
+
+// Config holds quota limits by subject
+type Config struct {
+	// Agents holds limits by agent name
+	Agents map[string]Limit
+	// Keys holds limits by provider key label, e.g. "openai-prod". Labels
+	// name keys; the keys themselves are never stored.
+	Keys map[string]Limit
+	// Store persists daily token usage (default: a MemoryStore)
+	Store Store
+}
+
+// maxTrackedRuns bounds the runs whose call counts are kept in memory; the
+// oldest run is forgotten first
+const maxTrackedRuns = 1024
+
+// Manager enforces quotas. It is safe for concurrent use.
+type Manager struct {
+	cfg   Config
+	store Store
+	now   func() time.Time
+
+	mu    sync.Mutex
+	calls map[string]map[string]int // Run ID -> subject -> calls
+	runs  []string                  // Tracked run IDs, oldest first
+}
+
+// NewManager creates a quota manager
+func NewManager(cfg Config) *Manager {
+	store := cfg.Store
+	if store == nil {
+		store = NewMemoryStore()
+	}
+	return &Manager{
+		cfg:   cfg,
+		store: store,
+		now:   time.Now,
+		calls: make(map[string]map[string]int),
+	}
+}
+
+// subject is a quota holder: an agent or a provider key
+type subject struct {
+	id    string // e.g. "agent:researcher" or "key:openai-prod"
+	limit Limit
+}
+
+// subjects returns the subjects with limits among the given agent and key.
+// Either may be empty.
+func (m *Manager) subjects(agentName, key string) []subject {
+	var out []subject
+	if limit, ok := m.cfg.Agents[agentName]; ok && agentName != "" {
+		out = append(out, subject{id: "agent:" + agentName, limit: limit})
+	}
+	if limit, ok := m.cfg.Keys[key]; ok && key != "" {
+		out = append(out, subject{id: "key:" + key, limit: limit})
+	}
+	return out
+}
+
+// day returns the current UTC date, the period of daily quotas
+func (m *Manager) day() string {
+	return m.now().UTC().Format("2006-01-02")
+}
+
+// Acquire checks the quotas of an agent and a key before an LLM call and
+// counts the call toward the run in ctx. It fails with ErrQuotaExceeded,
+// without counting the call, if any quota is exhausted.
+func (m *Manager) Acquire(ctx context.Context, agentName, key string) error {
+	subjects := m.subjects(agentName, key)
+	if len(subjects) == 0 {
+		return nil
+	}
+
+	day := m.day()
+	for _, s := range subjects {
+		if s.limit.MaxTokensPerDay <= 0 {
+			continue
+		}
+		used, err := m.store.Tokens(ctx, s.id, day)
+		if err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to load quota usage", err).WithContext("subject", s.id)
+		}
+		if used >= s.limit.MaxTokensPerDay {
+			return errors.QuotaExceeded(s.id+" tokens per day", s.limit.MaxTokensPerDay).
+				WithContext("subject", s.id).
+				WithContext("used", used).
+				WithContext("day", day)
+		}
+	}
+
+	run := runid.FromContext(ctx)
+	if run == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	counts := m.runCalls(run)
+	for _, s := range subjects {
+		if s.limit.MaxCallsPerRun > 0 && counts[s.id] >= s.limit.MaxCallsPerRun {
+			return errors.QuotaExceeded(s.id+" calls per run", s.limit.MaxCallsPerRun).
+				WithContext("subject", s.id).
+				WithContext("run_id", run)
+		}
+	}
+	for _, s := range subjects {
+		counts[s.id]++
+	}
+	return nil
+}
+
+// runCalls returns the call counts of a run, tracking it if new. The caller
+// must hold m.mu.
+func (m *Manager) runCalls(run string) map[string]int {
+	counts, ok := m.calls[run]
+	if !ok {
+		if len(m.runs) >= maxTrackedRuns {
+			delete(m.calls, m.runs[0])
+			m.runs = m.runs[1:]
+		}
+		counts = make(map[string]int)
+		m.calls[run] = counts
+		m.runs = append(m.runs, run)
+	}
+	return counts
+}
+
+// Record adds the tokens of a completed call to the daily usage of an agent
+// and a key. A call may overshoot a daily quota; the next one is rejected.
+func (m *Manager) Record(ctx context.Context, agentName, key string, usage llm.TokenUsage) error {
+	tokens := usage.Total()
+	if tokens == 0 {
+		return nil
+	}
+	day := m.day()
+	for _, s := range m.subjects(agentName, key) {
+		if s.limit.MaxTokensPerDay <= 0 {
+			continue
+		}
+		if err := m.store.AddTokens(ctx, s.id, day, tokens); err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to record quota usage", err).WithContext("subject", s.id)
+		}
+	}
+	return nil
+}
+
+// TokensToday returns the tokens an agent ("agent:<name>") or key
+// ("key:<label>") has used today
+func (m *Manager) TokensToday(ctx context.Context, subject string) (int, error) {
+	return m.store.Tokens(ctx, subject, m.day())
+}
+
+// LLM wraps a provider and enforces the quotas of an agent and a key on
+// every call
+type LLM struct {
+	inner   llm.LLM
+	manager *Manager
+	agent   string
+	key     string
+}
+
+// Wrap returns an LLM that enforces the quotas of agentName and key on
+// calls through inner. Either may be empty.
+func (m *Manager) Wrap(inner llm.LLM, agentName, key string) *LLM {
+	return &LLM{inner: inner, manager: m, agent: agentName, key: key}
+}
+
+// Generate checks the quotas, calls the wrapped provider and records usage
+func (q *LLM) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := q.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// GenerateResponse checks the quotas, calls the wrapped provider and records
+// the tokens it reports
+func (q *LLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	if err := q.manager.Acquire(ctx, q.agent, q.key); err != nil {
+		return nil, err
+	}
+	resp, err := llm.GenerateResponse(ctx, q.inner, prompt)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.manager.Record(ctx, q.agent, q.key, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// Chat checks the quotas, sends a conversation through the wrapped provider
+// and records usage
+func (q *LLM) Chat(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	resp, err := q.ChatResponse(ctx, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: resp.Content}, nil
+}
+
+// ChatResponse checks the quotas, sends a conversation through the wrapped
+// provider and records the tokens it reports
+func (q *LLM) ChatResponse(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	if err := q.manager.Acquire(ctx, q.agent, q.key); err != nil {
+		return nil, err
+	}
+	resp, err := llm.ChatResponse(ctx, q.inner, messages)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.manager.Record(ctx, q.agent, q.key, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// GenerateStream checks the quotas, streams the response of the wrapped
+// provider and records the tokens it reports
+func (q *LLM) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*llm.Response, error) {
+	if err := q.manager.Acquire(ctx, q.agent, q.key); err != nil {
+		return nil, err
+	}
+	resp, err := llm.GenerateStream(ctx, q.inner, prompt, fn)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.manager.Record(ctx, q.agent, q.key, resp.Usage); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
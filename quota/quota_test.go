@@ -0,0 +1,95 @@
+package quota
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// usageLLM reports a fixed token usage for every call
+type usageLLM struct {
+	calls int
+}
+
+func (u *usageLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	u.calls++
+	return "ok", nil
+}
+
+func (u *usageLLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	u.calls++
+	return &llm.Response{Content: "ok", Usage: llm.TokenUsage{PromptTokens: 60, CompletionTokens: 40}}, nil
+}
+
+func TestManager_CallsPerRun(t *testing.T) {
+	inner := &usageLLM{}
+	m := NewManager(Config{Agents: map[string]Limit{"writer": {MaxCallsPerRun: 2}}})
+	writer := m.Wrap(inner, "writer", "")
+
+	ctx := runid.NewContext(context.Background(), "run-1")
+	for i := 0; i < 2; i++ {
+		if _, err := writer.Generate(ctx, "hi"); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+	}
+	if _, err := writer.Generate(ctx, "hi"); !errors.HasCode(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("third call error = %v, want ErrQuotaExceeded", err)
+	}
+	if inner.calls != 2 {
+		t.Errorf("provider calls = %d, want 2", inner.calls)
+	}
+
+	// A new run starts with a fresh count; other agents are not limited
+	if _, err := writer.Generate(runid.NewContext(context.Background(), "run-2"), "hi"); err != nil {
+		t.Errorf("new run: unexpected error: %v", err)
+	}
+	if _, err := m.Wrap(inner, "critic", "").Generate(ctx, "hi"); err != nil {
+		t.Errorf("unlimited agent: unexpected error: %v", err)
+	}
+}
+
+func TestManager_TokensPerDayPersisted(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "quotas.json")
+	store, err := NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() unexpected error: %v", err)
+	}
+	m := NewManager(Config{Keys: map[string]Limit{"openai-prod": {MaxTokensPerDay: 150}}, Store: store})
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	m.now = func() time.Time { return now }
+	provider := m.Wrap(&usageLLM{}, "researcher", "openai-prod")
+
+	ctx := context.Background()
+	for i := 0; i < 2; i++ {
+		if _, err := provider.Generate(ctx, "hi"); err != nil {
+			t.Fatalf("call %d: unexpected error: %v", i+1, err)
+		}
+	}
+
+	// A restarted process sees the persisted usage
+	store, err = NewFileStore(path)
+	if err != nil {
+		t.Fatalf("NewFileStore() reload unexpected error: %v", err)
+	}
+	m = NewManager(Config{Keys: map[string]Limit{"openai-prod": {MaxTokensPerDay: 150}}, Store: store})
+	m.now = func() time.Time { return now }
+	if used, _ := m.TokensToday(ctx, "key:openai-prod"); used != 200 {
+		t.Errorf("TokensToday() = %d, want 200", used)
+	}
+	provider = m.Wrap(&usageLLM{}, "researcher", "openai-prod")
+	_, err = provider.Generate(ctx, "hi")
+	if !errors.HasCode(err, errors.ErrQuotaExceeded) {
+		t.Fatalf("Generate() over daily quota error = %v, want ErrQuotaExceeded", err)
+	}
+
+	// The quota resets the next day
+	now = now.Add(24 * time.Hour)
+	if _, err := provider.Generate(ctx, "hi"); err != nil {
+		t.Errorf("next day: unexpected error: %v", err)
+	}
+}
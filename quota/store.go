@@ -0,0 +1,135 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Store persists daily token usage per subject. Implementations backed by a
+// shared database let several processes enforce the same quotas.
+type Store interface {
+	// Tokens returns the tokens subject used on day ("2006-01-02")
+	Tokens(ctx context.Context, subject, day string) (int, error)
+	// AddTokens adds n tokens to the usage of subject on day
+	AddTokens(ctx context.Context, subject, day string, n int) error
+}
+
+// usage is the token count of a subject on one day. Only the latest day is
+// kept; earlier usage is dropped when a new day starts.
+type usage struct {
+	Day    string `json:"day"`
+	Tokens int    `json:"tokens"`
+}
+
+// add returns u with n tokens added on day
+func (u usage) add(day string, n int) usage {
+	if u.Day != day {
+		u = usage{Day: day}
+	}
+	u.Tokens += n
+	return u
+}
+
+// tokensOn returns the tokens of u on day
+func (u usage) tokensOn(day string) int {
+	if u.Day != day {
+		return 0
+	}
+	return u.Tokens
+}
+
+// MemoryStore keeps usage in memory, for single processes and tests
+type MemoryStore struct {
+	mu    sync.Mutex
+	usage map[string]usage
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{usage: make(map[string]usage)}
+}
+
+// Tokens returns the tokens subject used on day
+func (s *MemoryStore) Tokens(ctx context.Context, subject, day string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[subject].tokensOn(day), nil
+}
+
+// AddTokens adds n tokens to the usage of subject on day
+func (s *MemoryStore) AddTokens(ctx context.Context, subject, day string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage[subject] = s.usage[subject].add(day, n)
+	return nil
+}
+
+// FileStore keeps usage in a JSON file, so quotas survive restarts. It is
+// safe for concurrent use within one process; processes sharing quotas
+// need a shared Store.
+type FileStore struct {
+	path string
+
+	mu    sync.Mutex
+	usage map[string]usage
+}
+
+// NewFileStore creates a store persisted at path, loading existing usage
+func NewFileStore(path string) (*FileStore, error) {
+	if path == "" {
+		return nil, errors.RequiredField("path")
+	}
+	s := &FileStore{path: path, usage: make(map[string]usage)}
+
+	data, err := os.ReadFile(path)
+	switch {
+	case os.IsNotExist(err):
+		return s, nil
+	case err != nil:
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read quota file", err).WithContext("path", path)
+	}
+	if err := json.Unmarshal(data, &s.usage); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid quota file", err).WithContext("path", path)
+	}
+	return s, nil
+}
+
+// Tokens returns the tokens subject used on day
+func (s *FileStore) Tokens(ctx context.Context, subject, day string) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.usage[subject].tokensOn(day), nil
+}
+
+// AddTokens adds n tokens to the usage of subject on day and writes the file
+func (s *FileStore) AddTokens(ctx context.Context, subject, day string, n int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage[subject] = s.usage[subject].add(day, n)
+	return s.save()
+}
+
+// save writes the usage to a temporary file and renames it over the store's
+// file, so a crash never leaves a partial file. The caller must hold s.mu.
+func (s *FileStore) save() error {
+	data, err := json.MarshalIndent(s.usage, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to encode quota usage", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o755); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to create quota directory", err).WithContext("path", s.path)
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write quota file", err).WithContext("path", s.path)
+	}
+	if err := os.Rename(tmp, s.path); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write quota file", err).WithContext("path", s.path)
+	}
+	return nil
+}
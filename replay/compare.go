@@ -0,0 +1,77 @@
+package replay
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/counhopig/gittyai/diff"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/task"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Report compares a recorded run, as run A, with a re-run, as run B
+type Report struct {
+	RunID string
+	*diff.RunDiff
+}
+
+// String formats the report with a unified diff of each changed result
+func (r *Report) String() string {
+	return fmt.Sprintf("Replay of run %s: %s", r.RunID, r.RunDiff.String())
+}
+
+// Compare matches the results of a re-run with the recorded tasks by
+// description, falling back to position for tasks whose description changed
+func Compare(rec *Recording, results []*orchestrator.TaskResult) *Report {
+	// Without a judge, comparing never fails
+	run, _ := diff.New(diff.Config{}).Runs(context.Background(), rec.results(), results)
+	return &Report{RunID: rec.RunID, RunDiff: run}
+}
+
+// Rerun executes orch with the tools of the recording replayed and compares
+// its results with the recorded ones. orch should be built with the modified
+// configuration; its agents execute their tool calls with rec.Registry()
+// instead of the orchestrator's tools, so they get the recorded results.
+func Rerun(ctx context.Context, rec *Recording, orch *orchestrator.Orchestrator) (*Report, []*orchestrator.TaskResult, error) {
+	if rec == nil {
+		return nil, nil, errors.RequiredField("recording")
+	}
+	if orch == nil {
+		return nil, nil, errors.RequiredField("orchestrator")
+	}
+	registry, err := rec.Registry()
+	if err != nil {
+		return nil, nil, err
+	}
+	results, err := orch.Kickoff(tools.NewRegistryContext(ctx, registry))
+	if err != nil {
+		return nil, results, err
+	}
+	return Compare(rec, results), results, nil
+}
+
+// results converts the recorded tasks back into task results
+func (rec *Recording) results() []*orchestrator.TaskResult {
+	results := make([]*orchestrator.TaskResult, len(rec.Tasks))
+	for i, record := range rec.Tasks {
+		results[i] = &orchestrator.TaskResult{
+			Task:   &task.Task{Description: record.Description},
+			Agent:  record.Agent,
+			Status: parseStatus(record.Status),
+			Result: record.Result,
+		}
+	}
+	return results
+}
+
+// parseStatus returns the task status named s, or StatusPending
+func parseStatus(s string) orchestrator.TaskStatus {
+	for _, status := range []orchestrator.TaskStatus{orchestrator.StatusCompleted, orchestrator.StatusFailed, orchestrator.StatusCancelled, orchestrator.StatusSkipped} {
+		if status.String() == s {
+			return status
+		}
+	}
+	return orchestrator.StatusPending
+}
@@ -0,0 +1,234 @@
+// Package replay records runs and re-runs them with a modified
+// configuration, such as a new model or prompt template. Recorded tool
+// results are served again instead of calling the tools, so differences
+// between the runs come from the change under test.
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// Recording captures what a run produced
+type Recording struct {
+	RunID      string       `json:"run_id"`
+	RecordedAt time.Time    `json:"recorded_at"`
+	Tasks      []TaskRecord `json:"tasks"`
+	Tools      []ToolRecord `json:"tools,omitempty"`
+}
+
+// TaskRecord is the outcome of one task
+type TaskRecord struct {
+	Description string `json:"description"`
+	Agent       string `json:"agent"`
+	Status      string `json:"status"`
+	Result      string `json:"result"`
+}
+
+// ToolRecord is one tool execution
+type ToolRecord struct {
+	Tool        string                 `json:"tool"`
+	Description string                 `json:"description,omitempty"`
+	Args        map[string]interface{} `json:"args,omitempty"`
+	Output      string                 `json:"output"`
+	Error       string                 `json:"error,omitempty"`
+}
+
+// Recorder captures the tool executions and results of a run. It is safe
+// for concurrent use.
+type Recorder struct {
+	mu    sync.Mutex
+	tools []ToolRecord
+}
+
+// NewRecorder creates an empty recorder
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// WrapTool returns a tool that records every execution of inner
+func (r *Recorder) WrapTool(inner tools.Tool) tools.Tool {
+	return &recordingTool{Tool: inner, recorder: r}
+}
+
+// WrapRegistry returns a registry holding recording wrappers of every tool
+// in registry
+func (r *Recorder) WrapRegistry(registry *tools.Registry) (*tools.Registry, error) {
+	wrapped := tools.NewRegistry()
+	for _, name := range registry.List() {
+		t, err := registry.Get(name)
+		if err != nil {
+			return nil, err
+		}
+		if err := wrapped.Register(r.WrapTool(t)); err != nil {
+			return nil, err
+		}
+	}
+	return wrapped, nil
+}
+
+// Finish returns the recording of a run from its results and the tool
+// executions captured so far
+func (r *Recorder) Finish(runID string, results []*orchestrator.TaskResult) *Recording {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return &Recording{
+		RunID:      runID,
+		RecordedAt: time.Now().UTC(),
+		Tasks:      taskRecords(results),
+		Tools:      append([]ToolRecord(nil), r.tools...),
+	}
+}
+
+// taskRecords converts task results into records
+func taskRecords(results []*orchestrator.TaskResult) []TaskRecord {
+	records := make([]TaskRecord, 0, len(results))
+	for _, res := range results {
+		if res == nil {
+			continue
+		}
+		record := TaskRecord{Agent: res.Agent, Status: res.Status.String(), Result: res.Result}
+		if res.Task != nil {
+			record.Description = res.Task.Description
+		}
+		records = append(records, record)
+	}
+	return records
+}
+
+// recordingTool records the executions of a tool
+type recordingTool struct {
+	tools.Tool
+	recorder *Recorder
+}
+
+// SideEffects reports whether the wrapped tool has side effects, so that
+// wrapping keeps it behind confirmation
+func (t *recordingTool) SideEffects() bool { return tools.HasSideEffects(t.Tool) }
+
+// Execute runs the wrapped tool and records its output or error
+func (t *recordingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	output, err := t.Tool.Execute(ctx, args)
+	record := ToolRecord{Tool: t.Name(), Description: t.Description(), Args: args, Output: output}
+	if err != nil {
+		record.Error = err.Error()
+	}
+
+	t.recorder.mu.Lock()
+	t.recorder.tools = append(t.recorder.tools, record)
+	t.recorder.mu.Unlock()
+	return output, err
+}
+
+// Save writes a recording to path as JSON
+func Save(path string, rec *Recording) error {
+	if rec == nil {
+		return errors.RequiredField("recording")
+	}
+	data, err := json.MarshalIndent(rec, "", "  ")
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to encode recording", err).WithContext("path", path)
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write recording", err).WithContext("path", path)
+	}
+	return nil
+}
+
+// Load reads a recording written by Save
+func Load(path string) (*Recording, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("recording", path)
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read recording", err).WithContext("path", path)
+	}
+	var rec Recording
+	if err := json.Unmarshal(data, &rec); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid recording", err).WithContext("path", path)
+	}
+	return &rec, nil
+}
+
+// Registry returns a registry serving the recorded tool results. A call is
+// answered with the next recorded execution of the same tool with the same
+// arguments; once those are used up, the last one is repeated. Calls that
+// were never recorded fail with ErrNotFound. Pass the registry in
+// orchestrator.Config.Tools of the re-run.
+func (rec *Recording) Registry() (*tools.Registry, error) {
+	byTool := make(map[string]*replayTool)
+	registry := tools.NewRegistry()
+	for _, record := range rec.Tools {
+		t, ok := byTool[record.Tool]
+		if !ok {
+			description := record.Description
+			if description == "" {
+				description = "Replays recorded results of " + record.Tool
+			}
+			t = &replayTool{
+				BaseTool: tools.NewBaseTool(record.Tool, description, nil),
+				calls:    make(map[string][]ToolRecord),
+				next:     make(map[string]int),
+			}
+			byTool[record.Tool] = t
+			if err := registry.Register(t); err != nil {
+				return nil, err
+			}
+		}
+		key := argsKey(record.Args)
+		t.calls[key] = append(t.calls[key], record)
+	}
+	return registry, nil
+}
+
+// replayTool answers with recorded executions
+type replayTool struct {
+	*tools.BaseTool
+
+	mu    sync.Mutex
+	calls map[string][]ToolRecord // Recorded executions by argument key
+	next  map[string]int          // Next execution to replay by argument key
+}
+
+// Execute returns the next recorded result for args
+func (t *replayTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	key := argsKey(args)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	calls := t.calls[key]
+	if len(calls) == 0 {
+		return "", errors.NotFound("recorded tool call", t.Name()).WithContext("args", key)
+	}
+	i := t.next[key]
+	if i >= len(calls) {
+		i = len(calls) - 1
+	}
+	t.next[key] = i + 1
+
+	if calls[i].Error != "" {
+		return calls[i].Output, errors.New(errors.ErrInternal, calls[i].Error).WithContext("tool", t.Name())
+	}
+	return calls[i].Output, nil
+}
+
+// argsKey returns a canonical form of tool arguments; map keys are sorted
+// by encoding/json
+func argsKey(args map[string]interface{}) string {
+	if len(args) == 0 {
+		return "{}"
+	}
+	data, err := json.Marshal(args)
+	if err != nil {
+		return "{}"
+	}
+	return string(data)
+}
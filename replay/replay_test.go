@@ -0,0 +1,182 @@
+package replay
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/task"
+	"github.com/counhopig/gittyai/tools"
+)
+
+// counterTool returns a different answer on every call
+type counterTool struct {
+	*tools.BaseTool
+	calls int
+}
+
+func (c *counterTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	c.calls++
+	return strings.Repeat("x", c.calls), nil
+}
+
+func TestRecording_ReplaysToolResults(t *testing.T) {
+	live := &counterTool{BaseTool: tools.NewBaseTool("search", "Searches the web", nil)}
+	rec := NewRecorder()
+	wrapped := rec.WrapTool(live)
+
+	ctx := context.Background()
+	for _, query := range []string{"go", "go", "rust"} {
+		if _, err := wrapped.Execute(ctx, map[string]interface{}{"query": query}); err != nil {
+			t.Fatalf("Execute(%s) unexpected error: %v", query, err)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "run.json")
+	if err := Save(path, rec.Finish("run-1", nil)); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	registry, err := loaded.Registry()
+	if err != nil {
+		t.Fatalf("Registry() unexpected error: %v", err)
+	}
+	search, err := registry.Get("search")
+	if err != nil {
+		t.Fatalf("Get() unexpected error: %v", err)
+	}
+	if search.Description() != "Searches the web" {
+		t.Errorf("Description() = %q, want the recorded description", search.Description())
+	}
+
+	// Recorded calls are served in order per argument set, repeating the last
+	want := []struct{ query, output string }{{"go", "x"}, {"rust", "xxx"}, {"go", "xx"}, {"go", "xx"}}
+	for _, w := range want {
+		got, err := search.Execute(ctx, map[string]interface{}{"query": w.query})
+		if err != nil {
+			t.Fatalf("replay %s: unexpected error: %v", w.query, err)
+		}
+		if got != w.output {
+			t.Errorf("replay %s = %q, want %q", w.query, got, w.output)
+		}
+	}
+	if live.calls != 3 {
+		t.Errorf("live tool calls = %d, want 3", live.calls)
+	}
+
+	if _, err := search.Execute(ctx, map[string]interface{}{"query": "python"}); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("unrecorded call error = %v, want ErrNotFound", err)
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("Load(missing) error = %v, want ErrNotFound", err)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	research := &task.Task{Description: "Research Go"}
+	write := &task.Task{Description: "Write a summary"}
+	rec := NewRecorder().Finish("run-1", []*orchestrator.TaskResult{
+		{Task: research, Agent: "researcher", Status: orchestrator.StatusCompleted, Result: "Go is fast and simple"},
+		{Task: write, Agent: "writer", Status: orchestrator.StatusCompleted, Result: "Go is great"},
+	})
+
+	report := Compare(rec, []*orchestrator.TaskResult{
+		{Task: write, Agent: "writer", Status: orchestrator.StatusCompleted, Result: "Go is simple"},
+		{Task: research, Agent: "researcher", Status: orchestrator.StatusCompleted, Result: "Go is fast and simple"},
+		{Task: &task.Task{Description: "Review"}, Agent: "critic", Status: orchestrator.StatusCompleted, Result: "ok"},
+	})
+
+	if len(report.Tasks) != 3 {
+		t.Fatalf("len(Tasks) = %d, want 3", len(report.Tasks))
+	}
+	if report.Changed() != 2 {
+		t.Errorf("Changed() = %d, want 2", report.Changed())
+	}
+	if report.Tasks[0].Changed() || report.Tasks[0].Similarity != 1 || report.Tasks[0].StatusA != orchestrator.StatusCompleted {
+		t.Errorf("research diff = %+v, want unchanged", report.Tasks[0])
+	}
+	if !report.Tasks[1].Changed() || report.Tasks[1].Similarity != 0 {
+		t.Errorf("write diff = %+v, want changed", report.Tasks[1])
+	}
+	if !report.Tasks[2].OnlyB {
+		t.Errorf("review diff = %+v, want OnlyB", report.Tasks[2])
+	}
+
+	out := report.String()
+	for _, want := range []string{"Replay of run run-1: 2 of 3 tasks changed", "[unchanged]", "-Go is great\n+Go is simple", "[only in B]"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("String() missing %q:\n%s", want, out)
+		}
+	}
+}
+
+// searchingLLM calls the search tool, then answers with its result, taken
+// from the untrusted block enclosing it
+type searchingLLM struct{}
+
+func (searchingLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	if i := strings.LastIndex(prompt, "Result of search:\n"); i >= 0 {
+		block := strings.Split(prompt[i:], "\n")
+		return "Found " + block[2], nil
+	}
+	return `{"name": "search", "arguments": {"query": "go"}}`, nil
+}
+
+func TestRerun_ReplaysRecordedTools(t *testing.T) {
+	live := &counterTool{BaseTool: tools.NewBaseTool("search", "Searches the web", nil)}
+	registry := tools.NewRegistry()
+	_ = registry.Register(live)
+	newOrch := func(toolRegistry *tools.Registry) *orchestrator.Orchestrator {
+		a := agent.New(agent.Config{Name: "researcher", Role: "researcher", Goal: "research", LLM: searchingLLM{}})
+		return orchestrator.New(orchestrator.Config{
+			Agents: []*agent.Agent{a},
+			Tasks:  []*task.Task{task.New(task.Config{Description: "Research Go", Agent: a})},
+			Tools:  toolRegistry,
+		})
+	}
+
+	rec := NewRecorder()
+	recording, err := rec.WrapRegistry(registry)
+	if err != nil {
+		t.Fatalf("WrapRegistry() unexpected error: %v", err)
+	}
+	results, err := newOrch(recording).Kickoff(context.Background())
+	if err != nil || results[0].Result != "Found x" {
+		t.Fatalf("recorded run = %v, %v", results, err)
+	}
+
+	// The re-run gets the recorded result even from an orchestrator given
+	// the live tools
+	report, results, err := Rerun(context.Background(), rec.Finish("run-1", results), newOrch(registry))
+	if err != nil {
+		t.Fatalf("Rerun() unexpected error: %v", err)
+	}
+	if results[0].Result != "Found x" || report.Changed() != 0 {
+		t.Errorf("re-run = %q, report:\n%s", results[0].Result, report)
+	}
+	if live.calls != 1 {
+		t.Errorf("live tool calls = %d, want 1", live.calls)
+	}
+}
+
+func TestWrapTool_KeepsSideEffects(t *testing.T) {
+	live := &counterTool{BaseTool: tools.NewBaseTool("delete", "Deletes a file", nil)}
+	registry := tools.NewRegistry()
+	_ = registry.Register(NewRecorder().WrapTool(tools.MarkSideEffecting(live)))
+
+	ctx := tools.NewConfirmContext(context.Background(), tools.DryRunAll)
+	if _, err := registry.Execute(ctx, "delete", nil); err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if live.calls != 0 {
+		t.Errorf("wrapped side-effecting tool ran %d times under DryRunAll, want 0", live.calls)
+	}
+}
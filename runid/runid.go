@@ -0,0 +1,48 @@
+package runid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+)
+
+// HeaderName is the HTTP header used to propagate the run ID to providers
+const HeaderName = "X-Request-ID"
+
+type contextKey struct{}
+
+// New generates a new random run ID
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		// Fall back to a time-based ID; uniqueness within a process is enough
+		return fmt.Sprintf("run-%d", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(b)
+}
+
+// NewContext returns a copy of ctx carrying the given run ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the run ID carried by ctx, or "" if none is set
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Ensure returns ctx and its run ID, generating and attaching a new ID when
+// ctx does not carry one yet
+func Ensure(ctx context.Context) (context.Context, string) {
+	if id := FromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := New()
+	return NewContext(ctx, id), id
+}
@@ -0,0 +1,323 @@
+// Package support exports support bundles: a single archive holding the
+// redacted config, transcript, events, errors and environment of a run, for
+// users to attach to bug reports and maintainers to inspect and reproduce
+// the failure.
+package support
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/replay"
+)
+
+// FormatVersion is the version of the bundle layout. It is bumped on any
+// incompatible change; Read rejects bundles of a newer version.
+const FormatVersion = 1
+
+// maxEntrySize bounds the uncompressed size of each file Read accepts, so a
+// crafted archive cannot exhaust memory
+const maxEntrySize = 256 << 20
+
+// Files of a bundle archive
+const (
+	manifestFile    = "manifest.json"
+	configFile      = "config.yaml"
+	transcriptFile  = "transcript.json"
+	callsFile       = "calls.jsonl"
+	eventsFile      = "events.jsonl"
+	errorsFile      = "errors.json"
+	environmentFile = "environment.json"
+	recordingFile   = "recording.json"
+)
+
+// Bundle is the content of a support bundle. Everything in it has been
+// redacted by the Collector that built it.
+type Bundle struct {
+	Manifest Manifest
+	// Config is the YAML config of the run with its secrets redacted
+	Config []byte
+	// Transcript holds the outcome of every task
+	Transcript []replay.TaskRecord
+	// Calls are the requests sent to providers, with their text when the
+	// collector was created with IncludeText
+	Calls []llm.LogRecord
+	// Events are the run's events in the order they were published
+	Events []events.Event
+	// Errors are the run's error and those of its failed tasks
+	Errors []ErrorRecord
+	// Environment describes where the run executed
+	Environment Environment
+	// Recording holds the tool executions of the run when it was recorded,
+	// so maintainers can re-run it with replay.Rerun (optional)
+	Recording *replay.Recording
+}
+
+// Manifest identifies a bundle
+type Manifest struct {
+	FormatVersion int       `json:"format_version"`
+	RunID         string    `json:"run_id,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+	// Error is the run's error message
+	Error string `json:"error,omitempty"`
+}
+
+// ErrorRecord is an error of the run or of one of its tasks
+type ErrorRecord struct {
+	Task        string `json:"task,omitempty"`
+	Agent       string `json:"agent,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	// Detail is the error in errors.FormatProduction form: code, severity,
+	// redacted message, context and cause
+	Detail json.RawMessage `json:"detail"`
+}
+
+// Environment describes the process that executed the run
+type Environment struct {
+	GoVersion      string `json:"go_version"`
+	OS             string `json:"os"`
+	Arch           string `json:"arch"`
+	CPUs           int    `json:"cpus"`
+	LibraryVersion string `json:"library_version"`
+	// Module is the path and version of the application's main module
+	Module string `json:"module,omitempty"`
+	// Variables are the names of the GITTYAI_* and provider credential
+	// variables that were set; their values are never included
+	Variables []string `json:"variables,omitempty"`
+}
+
+// CurrentEnvironment describes the running process
+func CurrentEnvironment() Environment {
+	env := Environment{
+		GoVersion:      runtime.Version(),
+		OS:             runtime.GOOS,
+		Arch:           runtime.GOARCH,
+		CPUs:           runtime.NumCPU(),
+		LibraryVersion: llm.LibraryVersion(),
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Path != "" {
+		env.Module = info.Main.Path
+		if info.Main.Version != "" {
+			env.Module += "@" + info.Main.Version
+		}
+	}
+	for _, kv := range os.Environ() {
+		name, _, _ := strings.Cut(kv, "=")
+		if strings.HasPrefix(name, "GITTYAI_") || strings.HasSuffix(name, "_API_KEY") || strings.HasPrefix(name, "AWS_") {
+			env.Variables = append(env.Variables, name)
+		}
+	}
+	sort.Strings(env.Variables)
+	return env
+}
+
+// Write writes a bundle to w as a zip archive
+func Write(w io.Writer, b *Bundle) error {
+	if b == nil {
+		return errors.RequiredField("bundle")
+	}
+	zw := zip.NewWriter(w)
+	add := func(name string, data []byte) error {
+		f, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate, Modified: b.Manifest.CreatedAt})
+		if err == nil {
+			_, err = f.Write(data)
+		}
+		if err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to write bundle", err).WithContext("file", name)
+		}
+		return nil
+	}
+	addJSON := func(name string, v interface{}) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to encode bundle", err).WithContext("file", name)
+		}
+		return add(name, data)
+	}
+
+	manifest := b.Manifest
+	manifest.FormatVersion = FormatVersion
+	if err := addJSON(manifestFile, manifest); err != nil {
+		return err
+	}
+	if len(b.Config) > 0 {
+		if err := add(configFile, b.Config); err != nil {
+			return err
+		}
+	}
+	if err := addJSON(transcriptFile, b.Transcript); err != nil {
+		return err
+	}
+	if err := addJSON(errorsFile, b.Errors); err != nil {
+		return err
+	}
+	if err := addJSON(environmentFile, b.Environment); err != nil {
+		return err
+	}
+	if b.Recording != nil {
+		if err := addJSON(recordingFile, b.Recording); err != nil {
+			return err
+		}
+	}
+
+	// Calls and events are JSON lines, like the loggers and sinks write them
+	var calls, evs bytes.Buffer
+	for _, call := range b.Calls {
+		if err := json.NewEncoder(&calls).Encode(call); err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to encode bundle", err).WithContext("file", callsFile)
+		}
+	}
+	for _, ev := range b.Events {
+		if err := json.NewEncoder(&evs).Encode(ev); err != nil {
+			return errors.Wrap(errors.ErrInternal, "failed to encode bundle", err).WithContext("file", eventsFile)
+		}
+	}
+	if err := add(callsFile, calls.Bytes()); err != nil {
+		return err
+	}
+	if err := add(eventsFile, evs.Bytes()); err != nil {
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write bundle", err)
+	}
+	return nil
+}
+
+// Save writes a bundle to path. The file is readable by its owner only,
+// since transcripts may hold private data redaction did not catch.
+func Save(path string, b *Bundle) error {
+	var buf bytes.Buffer
+	if err := Write(&buf, b); err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o600); err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to write bundle", err).WithContext("path", path)
+	}
+	return nil
+}
+
+// Read reads a bundle archive written by Write
+func Read(r io.ReaderAt, size int64) (*Bundle, error) {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid support bundle", err)
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+	if files[manifestFile] == nil {
+		return nil, errors.Newf(errors.ErrInvalidFormat, "invalid support bundle: no %s", manifestFile)
+	}
+
+	// read reads a file of the archive; a missing file reads as empty. Files
+	// larger than their header declares, or than maxEntrySize, are rejected.
+	read := func(name string) ([]byte, error) {
+		f, ok := files[name]
+		if !ok {
+			return nil, nil
+		}
+		if f.UncompressedSize64 > maxEntrySize {
+			return nil, errors.Newf(errors.ErrInvalidFormat, "invalid support bundle: %s exceeds %d bytes", name, maxEntrySize).WithContext("file", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid support bundle", err).WithContext("file", name)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(io.LimitReader(rc, int64(f.UncompressedSize64)+1))
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid support bundle", err).WithContext("file", name)
+		}
+		if uint64(len(data)) > f.UncompressedSize64 {
+			return nil, errors.Newf(errors.ErrInvalidFormat, "invalid support bundle: %s is larger than declared", name).WithContext("file", name)
+		}
+		return data, nil
+	}
+	// decode decodes a JSON file, or each line of a JSON lines file
+	decode := func(name string, lines bool, v func() interface{}) error {
+		data, err := read(name)
+		if err != nil || len(data) == 0 {
+			return err
+		}
+		chunks := [][]byte{data}
+		if lines {
+			chunks = bytes.Split(bytes.TrimSpace(data), []byte("\n"))
+		}
+		for _, chunk := range chunks {
+			if err := json.Unmarshal(chunk, v()); err != nil {
+				return errors.Wrap(errors.ErrInvalidFormat, "invalid support bundle", err).WithContext("file", name)
+			}
+		}
+		return nil
+	}
+
+	b := &Bundle{}
+	if err := decode(manifestFile, false, func() interface{} { return &b.Manifest }); err != nil {
+		return nil, err
+	}
+	if b.Manifest.FormatVersion > FormatVersion {
+		return nil, errors.Unsupportedf("support bundle format version %d", b.Manifest.FormatVersion).WithContext("supported", FormatVersion)
+	}
+	if b.Config, err = read(configFile); err != nil {
+		return nil, err
+	}
+	if err := decode(transcriptFile, false, func() interface{} { return &b.Transcript }); err != nil {
+		return nil, err
+	}
+	if err := decode(errorsFile, false, func() interface{} { return &b.Errors }); err != nil {
+		return nil, err
+	}
+	if err := decode(environmentFile, false, func() interface{} { return &b.Environment }); err != nil {
+		return nil, err
+	}
+	if files[recordingFile] != nil {
+		b.Recording = &replay.Recording{}
+		if err := decode(recordingFile, false, func() interface{} { return b.Recording }); err != nil {
+			return nil, err
+		}
+	}
+	if err := decode(callsFile, true, func() interface{} {
+		b.Calls = append(b.Calls, llm.LogRecord{})
+		return &b.Calls[len(b.Calls)-1]
+	}); err != nil {
+		return nil, err
+	}
+	if err := decode(eventsFile, true, func() interface{} {
+		b.Events = append(b.Events, events.Event{})
+		return &b.Events[len(b.Events)-1]
+	}); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Load reads a bundle saved by Save
+func Load(path string) (*Bundle, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("support bundle", path)
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read bundle", err).WithContext("path", path)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read bundle", err).WithContext("path", path)
+	}
+	return Read(f, info.Size())
+}
@@ -0,0 +1,119 @@
+package support
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/replay"
+	"github.com/counhopig/gittyai/task"
+)
+
+func TestBundle_SaveAndLoad(t *testing.T) {
+	ctx := context.Background()
+	c := NewCollector(Options{
+		IncludeText: true,
+		Redact:      func(s string) string { return strings.ReplaceAll(s, "ada@example.com", "[EMAIL]") },
+	})
+
+	_ = c.Publish(ctx, events.New(events.RunStarted, "run-1"))
+	_ = c.Publish(ctx, events.New(events.RunStarted, "run-2"))
+	failed := events.New(events.TaskFailed, "run-1")
+	failed.Error = "request failed: api_key=sk-abcdefghijklmnopqrstuvwx"
+	_ = c.Publish(ctx, failed)
+	c.LogCall(ctx, llm.LogRecord{RunID: "run-1", Provider: "openai", Prompt: "Email ada@example.com", Response: "Sent"})
+	c.LogCall(ctx, llm.LogRecord{RunID: "run-2", Provider: "openai", Prompt: "other run"})
+
+	taskErr := errors.APIStatusCodeError(401, "invalid token").WithContext("api_key", "sk-abcdefghijklmnopqrstuvwx")
+	config := []byte("llm:\n  provider: openai\n  api_key: sk-abcdefghijklmnopqrstuvwx # inline\n  max_tokens: 500\nsecondary:\n  api_key: ${ANTHROPIC_API_KEY}\n  token: enc[c2VjcmV0]\n")
+	b, err := c.Bundle(Run{
+		ID: "run-1",
+		Results: []*orchestrator.TaskResult{
+			{Task: task.New(task.Config{Description: "Write to ada@example.com"}), Agent: "writer", Status: orchestrator.StatusFailed, Error: taskErr},
+		},
+		Err:       taskErr,
+		Config:    config,
+		Recording: &replay.Recording{RunID: "run-1", Tools: []replay.ToolRecord{{Tool: "search", Args: map[string]interface{}{"q": "ada@example.com"}, Output: "found"}}},
+	})
+	if err != nil {
+		t.Fatalf("Bundle() unexpected error: %v", err)
+	}
+
+	path := filepath.Join(t.TempDir(), "bundle.zip")
+	if err := Save(path, b); err != nil {
+		t.Fatalf("Save() unexpected error: %v", err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load() unexpected error: %v", err)
+	}
+
+	if loaded.Manifest.RunID != "run-1" || loaded.Manifest.FormatVersion != FormatVersion || loaded.Environment.GoVersion == "" {
+		t.Errorf("manifest = %+v, environment = %+v", loaded.Manifest, loaded.Environment)
+	}
+	if len(loaded.Events) != 2 || len(loaded.Calls) != 1 || loaded.Calls[0].Prompt != "Email [EMAIL]" {
+		t.Errorf("events = %+v, calls = %+v, want only run-1's, redacted", loaded.Events, loaded.Calls)
+	}
+	if len(loaded.Transcript) != 1 || loaded.Transcript[0].Description != "Write to [EMAIL]" || loaded.Transcript[0].Status != orchestrator.StatusFailed.String() {
+		t.Errorf("transcript = %+v", loaded.Transcript)
+	}
+	if len(loaded.Errors) != 2 || loaded.Errors[1].Task != "Write to [EMAIL]" || loaded.Errors[1].Fingerprint != errors.Fingerprint(taskErr) {
+		t.Errorf("errors = %+v", loaded.Errors)
+	}
+	if loaded.Recording == nil || loaded.Recording.Tools[0].Args["q"] != "[EMAIL]" {
+		t.Errorf("recording = %+v, want redacted tool arguments", loaded.Recording)
+	}
+
+	cfg := string(loaded.Config)
+	for _, want := range []string{"api_key: '[REDACTED]' # inline", "max_tokens: 500", "${ANTHROPIC_API_KEY}", "token: '[REDACTED]'"} {
+		if !strings.Contains(cfg, want) {
+			t.Errorf("config missing %q:\n%s", want, cfg)
+		}
+	}
+
+	// Nothing in the archive holds the secret
+	var all strings.Builder
+	for _, e := range loaded.Errors {
+		all.Write(e.Detail)
+	}
+	for _, ev := range loaded.Events {
+		all.WriteString(ev.Error)
+	}
+	all.WriteString(loaded.Manifest.Error + cfg)
+	if strings.Contains(all.String(), "sk-abcdefghijklmnopqrstuvwx") {
+		t.Errorf("bundle contains the API key: %s", all.String())
+	}
+}
+
+func TestRead_RejectsNewerFormat(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	f, _ := zw.Create("manifest.json")
+	_, _ = f.Write([]byte(`{"format_version": 99}`))
+	_ = zw.Close()
+
+	if _, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); !errors.HasCode(err, errors.ErrUnsupported) {
+		t.Errorf("Read() = %v, want ErrUnsupported", err)
+	}
+
+	// Entries declaring more than the size cap are not read
+	buf.Reset()
+	zw = zip.NewWriter(&buf)
+	f, _ = zw.CreateRaw(&zip.FileHeader{Name: "manifest.json", Method: zip.Store, UncompressedSize64: maxEntrySize + 1})
+	_, _ = f.Write([]byte(`{}`))
+	_ = zw.Close()
+	if _, err := Read(bytes.NewReader(buf.Bytes()), int64(buf.Len())); !errors.HasCode(err, errors.ErrInvalidFormat) {
+		t.Errorf("Read() of an oversized entry = %v, want ErrInvalidFormat", err)
+	}
+
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.zip")); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("Load() of a missing file = %v, want ErrNotFound", err)
+	}
+}
@@ -0,0 +1,257 @@
+package support
+
+import (
+	"context"
+	"encoding/json"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/orchestrator"
+	"github.com/counhopig/gittyai/replay"
+)
+
+// DefaultMaxRecords is the number of events and of provider calls a
+// Collector keeps by default
+const DefaultMaxRecords = 1000
+
+// Options configures a Collector
+type Options struct {
+	// IncludeText keeps the prompt and response text of provider calls; by
+	// default only their hashes are kept
+	IncludeText bool
+	// Redact rewrites every text of the bundle after the built-in redaction
+	// of API keys, tokens and passwords, e.g. to remove personal data
+	// (optional)
+	Redact func(string) string
+	// MaxRecords is the number of most recent events, and of provider
+	// calls, kept (0 = DefaultMaxRecords)
+	MaxRecords int
+}
+
+// Collector captures the events and provider calls of runs and builds
+// their support bundles. Register it as the orchestrator's event sink and
+// as the providers' logger; it is safe for concurrent use.
+type Collector struct {
+	opts Options
+
+	mu     sync.Mutex
+	events []events.Event
+	calls  []llm.LogRecord
+}
+
+// NewCollector creates an empty collector
+func NewCollector(opts Options) *Collector {
+	if opts.MaxRecords <= 0 {
+		opts.MaxRecords = DefaultMaxRecords
+	}
+	return &Collector{opts: opts}
+}
+
+// Publish implements events.Sink
+func (c *Collector) Publish(ctx context.Context, event events.Event) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.events = append(c.events, event)
+	if len(c.events) > c.opts.MaxRecords {
+		c.events = append(c.events[:0], c.events[1:]...)
+	}
+	return nil
+}
+
+// LogCall implements llm.Logger
+func (c *Collector) LogCall(ctx context.Context, record llm.LogRecord) {
+	if !c.opts.IncludeText {
+		record.Prompt, record.Response = "", ""
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.calls = append(c.calls, record)
+	if len(c.calls) > c.opts.MaxRecords {
+		c.calls = append(c.calls[:0], c.calls[1:]...)
+	}
+}
+
+// redact removes secrets, then applies Options.Redact
+func (c *Collector) redact(s string) string {
+	s = errors.Redact(s)
+	if c.opts.Redact != nil {
+		s = c.opts.Redact(s)
+	}
+	return s
+}
+
+// Run is what a bundle is built from, besides the collected events and
+// provider calls
+type Run struct {
+	// ID selects the events and calls of the run (default: every one collected)
+	ID string
+	// Results are the run's task results
+	Results []*orchestrator.TaskResult
+	// Err is the error returned by the run (optional)
+	Err error
+	// Config is the YAML config the run was built from (optional)
+	Config []byte
+	// Recording holds the run's tool executions (optional)
+	Recording *replay.Recording
+}
+
+// Bundle builds the support bundle of a run from its results and the
+// events and calls collected for it
+func (c *Collector) Bundle(run Run) (*Bundle, error) {
+	b := &Bundle{
+		Manifest:    Manifest{FormatVersion: FormatVersion, RunID: run.ID, CreatedAt: time.Now().UTC()},
+		Environment: CurrentEnvironment(),
+	}
+	if run.Err != nil {
+		b.Manifest.Error = c.redact(run.Err.Error())
+	}
+	if len(run.Config) > 0 {
+		config, err := RedactConfig(run.Config, c.redact)
+		if err != nil {
+			return nil, err
+		}
+		b.Config = config
+	}
+
+	c.mu.Lock()
+	for _, event := range c.events {
+		if run.ID != "" && event.RunID != run.ID {
+			continue
+		}
+		event.Task = c.redact(event.Task)
+		event.Result = c.redact(event.Result)
+		event.Error = c.redact(event.Error)
+		b.Events = append(b.Events, event)
+	}
+	for _, call := range c.calls {
+		if run.ID != "" && call.RunID != run.ID {
+			continue
+		}
+		call.Prompt = c.redact(call.Prompt)
+		call.Response = c.redact(call.Response)
+		call.Error = c.redact(call.Error)
+		b.Calls = append(b.Calls, call)
+	}
+	c.mu.Unlock()
+
+	if run.Err != nil {
+		b.Errors = append(b.Errors, c.errorRecord(run.Err, "", ""))
+	}
+	for _, res := range run.Results {
+		if res == nil {
+			continue
+		}
+		record := replay.TaskRecord{Description: c.redact(res.Title()), Agent: res.Agent, Status: res.Status.String(), Result: c.redact(res.Result)}
+		b.Transcript = append(b.Transcript, record)
+		if res.Error != nil {
+			b.Errors = append(b.Errors, c.errorRecord(res.Error, record.Description, res.Agent))
+		}
+	}
+
+	if run.Recording != nil {
+		b.Recording = c.redactRecording(run.Recording)
+	}
+	return b, nil
+}
+
+// errorRecord describes an error of the run or of a task
+func (c *Collector) errorRecord(err error, task, agent string) ErrorRecord {
+	detail := errors.Format(err, errors.FormatProduction)
+	if c.opts.Redact != nil {
+		// Re-encode the redacted message and cause
+		var fields map[string]interface{}
+		if json.Unmarshal([]byte(detail), &fields) == nil {
+			for _, key := range []string{"message", "cause"} {
+				if s, ok := fields[key].(string); ok {
+					fields[key] = c.opts.Redact(s)
+				}
+			}
+			if data, err := json.Marshal(fields); err == nil {
+				detail = string(data)
+			}
+		}
+	}
+	return ErrorRecord{Task: task, Agent: agent, Fingerprint: errors.Fingerprint(err), Detail: json.RawMessage(detail)}
+}
+
+// redactRecording returns a copy of a recording with its texts redacted
+func (c *Collector) redactRecording(rec *replay.Recording) *replay.Recording {
+	out := &replay.Recording{RunID: rec.RunID, RecordedAt: rec.RecordedAt}
+	for _, task := range rec.Tasks {
+		task.Description = c.redact(task.Description)
+		task.Result = c.redact(task.Result)
+		out.Tasks = append(out.Tasks, task)
+	}
+	for _, call := range rec.Tools {
+		args := make(map[string]interface{}, len(call.Args))
+		for k, v := range call.Args {
+			if s, ok := v.(string); ok {
+				v = c.redact(s)
+			}
+			args[k] = v
+		}
+		call.Args = args
+		call.Output = c.redact(call.Output)
+		call.Error = c.redact(call.Error)
+		out.Tools = append(out.Tools, call)
+	}
+	return out
+}
+
+// secretKey matches config keys whose values are secrets, but not settings
+// such as max_tokens
+var secretKey = regexp.MustCompile(`(?i)(api[_-]?key|access[_-]?key|secret|password|passwd|authorization|credential|(^|_)token$)`)
+
+// envReference matches a value that only references an environment variable
+var envReference = regexp.MustCompile(`^\$\{[A-Za-z_][A-Za-z0-9_]*\}$`)
+
+// RedactConfig returns a YAML config with the values of secret keys, such as
+// api_key, and encrypted enc[...] values replaced by [REDACTED]. References
+// to environment variables like ${OPENAI_API_KEY} are kept, and other
+// strings are passed to redact when it is not nil. Comments and key order
+// are kept.
+func RedactConfig(data []byte, redact func(string) string) ([]byte, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid YAML config", err)
+	}
+	var walk func(n *yaml.Node, secret bool)
+	walk = func(n *yaml.Node, secret bool) {
+		switch n.Kind {
+		case yaml.DocumentNode, yaml.SequenceNode:
+			for _, child := range n.Content {
+				walk(child, secret)
+			}
+		case yaml.MappingNode:
+			for i := 0; i+1 < len(n.Content); i += 2 {
+				walk(n.Content[i+1], secret || secretKey.MatchString(n.Content[i].Value))
+			}
+		case yaml.ScalarNode:
+			switch {
+			case n.Tag == "!!null" || n.Value == "" || envReference.MatchString(n.Value):
+			case secret || isEncrypted(n.Value):
+				n.Value, n.Tag, n.Style = "[REDACTED]", "!!str", 0
+			case redact != nil && n.Tag == "!!str":
+				n.Value = redact(n.Value)
+			}
+		}
+	}
+	walk(&doc, false)
+	out, err := yaml.Marshal(&doc)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to encode config", err)
+	}
+	return out, nil
+}
+
+// isEncrypted reports whether a config value is encrypted as enc[...]
+func isEncrypted(s string) bool {
+	return strings.HasPrefix(s, "enc[") && strings.HasSuffix(s, "]")
+}
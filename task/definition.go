@@ -0,0 +1,55 @@
+package task
+
+import "github.com/counhopig/gittyai/agent"
+
+// Definition is the serializable part of a task, referencing its agent by
+// name. Post-processors are runtime values and are not part of it.
+type Definition struct {
+	Description    string        `json:"description" yaml:"description"`
+	ExpectedOutput string        `json:"expected_output,omitempty" yaml:"expected_output,omitempty"`
+	Agent          string        `json:"agent,omitempty" yaml:"agent,omitempty"`
+	Context        []string      `json:"context,omitempty" yaml:"context,omitempty"`
+	PromptRef      string        `json:"prompt_ref,omitempty" yaml:"prompt_ref,omitempty"`
+	Skills         []string      `json:"skills,omitempty" yaml:"skills,omitempty"`
+	IdempotencyKey string        `json:"idempotency_key,omitempty" yaml:"idempotency_key,omitempty"`
+	OutputFormat   *OutputFormat `json:"output_format,omitempty" yaml:"output_format,omitempty"`
+	Retry          *RetryPolicy  `json:"retry,omitempty" yaml:"retry,omitempty"`
+	Priority       int           `json:"priority,omitempty" yaml:"priority,omitempty"`
+}
+
+// Definition returns the task's definition
+func (t *Task) Definition() Definition {
+	def := Definition{
+		Description:    t.Description,
+		ExpectedOutput: t.ExpectedOutput,
+		Context:        t.Context,
+		PromptRef:      t.PromptRef,
+		Skills:         t.Skills,
+		IdempotencyKey: t.IdempotencyKey,
+		OutputFormat:   t.OutputFormat,
+		Retry:          t.Retry,
+		Priority:       t.Priority,
+	}
+	if t.Agent != nil {
+		def.Agent = t.Agent.Name
+	}
+	return def
+}
+
+// FromDefinition builds a task from its definition, executed by a, which
+// the caller resolves from def.Agent; a may be nil for tasks the
+// orchestrator assigns
+func FromDefinition(def Definition, a *agent.Agent) *Task {
+	return New(Config{
+		Description:    def.Description,
+		ExpectedOutput: def.ExpectedOutput,
+		Agent:          a,
+		Context:        def.Context,
+		PromptRef:      def.PromptRef,
+		Skills:         def.Skills,
+		IdempotencyKey: def.IdempotencyKey,
+		OutputFormat:   def.OutputFormat,
+		Retry:          def.Retry,
+		Priority:       def.Priority,
+	})
+}
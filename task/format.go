@@ -0,0 +1,149 @@
+package task
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/jsonutil"
+)
+
+// FormatKind names an output format
+type FormatKind string
+
+// Supported output formats
+const (
+	FormatMarkdown FormatKind = "markdown"
+	FormatJSON     FormatKind = "json"
+	FormatCSV      FormatKind = "csv"
+	FormatCode     FormatKind = "code"
+)
+
+// DefaultFormatRetries is the number of times a task is retried after its
+// output violates the format
+const DefaultFormatRetries = 2
+
+// OutputFormat is a contract on a task's output. The format is added to the
+// prompt and the agent's response is validated against it; a response that
+// violates it is sent back to the agent with the violation.
+type OutputFormat struct {
+	Kind FormatKind `json:"kind" yaml:"kind"`
+	// Headers are the CSV columns the output must start with, in order
+	Headers []string `json:"headers,omitempty" yaml:"headers,omitempty"`
+	// Language is the language tag of the code fence, e.g. "go"
+	Language string `json:"language,omitempty" yaml:"language,omitempty"`
+	// Retries is the number of attempts after a violation (default:
+	// DefaultFormatRetries; negative disables retrying)
+	Retries int `json:"retries,omitempty" yaml:"retries,omitempty"`
+	// Repair accepts JSON output with trailing commas or cut off before its
+	// closing brackets, repaired, instead of asking the agent again. Off by
+	// default, since a truncated value passes as complete.
+	Repair bool `json:"repair,omitempty" yaml:"repair,omitempty"`
+}
+
+// ParseFormatKind parses an output format name
+func ParseFormatKind(name string) (FormatKind, error) {
+	switch kind := FormatKind(strings.ToLower(strings.TrimSpace(name))); kind {
+	case FormatMarkdown, FormatJSON, FormatCSV, FormatCode:
+		return kind, nil
+	default:
+		return "", errors.InvalidField("output_format", fmt.Sprintf("unknown format %q, must be one of markdown, json, csv, code", name))
+	}
+}
+
+// retries returns the number of attempts allowed after a violation
+func (f *OutputFormat) retries() int {
+	switch {
+	case f.Retries < 0:
+		return 0
+	case f.Retries == 0:
+		return DefaultFormatRetries
+	default:
+		return f.Retries
+	}
+}
+
+// Instruction returns the prompt text describing the format
+func (f *OutputFormat) Instruction() string {
+	switch f.Kind {
+	case FormatJSON:
+		return "Output format: respond with valid JSON only, without any text before or after it."
+	case FormatCSV:
+		instruction := "Output format: respond with CSV only, one record per line, without any text before or after it."
+		if len(f.Headers) > 0 {
+			instruction += " The first line must be the header: " + strings.Join(f.Headers, ",")
+		}
+		return instruction
+	case FormatCode:
+		return fmt.Sprintf("Output format: respond with the code in a single ```%s fenced code block.", f.Language)
+	default:
+		return "Output format: respond in Markdown."
+	}
+}
+
+// codeFence matches a fenced code block and its language tag
+var codeFence = regexp.MustCompile("(?s)```([\\w+#.-]*)[ \\t]*\\n(.*?)```")
+
+// stripFence returns the content of the first fenced block, or the output
+// unchanged when it has none
+func stripFence(output string) string {
+	if m := codeFence.FindStringSubmatch(output); m != nil {
+		return strings.TrimSpace(m[2])
+	}
+	return strings.TrimSpace(output)
+}
+
+// Check validates output against the format and returns it normalized: JSON
+// and CSV without surrounding fences, code and Markdown unchanged
+func (f *OutputFormat) Check(output string) (string, error) {
+	if strings.TrimSpace(output) == "" {
+		return "", errors.New(errors.ErrInvalidFormat, "output is empty")
+	}
+
+	switch f.Kind {
+	case FormatJSON:
+		extract := jsonutil.ExtractStrict
+		if f.Repair {
+			extract = jsonutil.Extract
+		}
+		data, err := extract(output)
+		if err != nil {
+			return "", errors.New(errors.ErrInvalidFormat, "output is not valid JSON")
+		}
+		return data, nil
+
+	case FormatCSV:
+		data := stripFence(output)
+		records, err := csv.NewReader(strings.NewReader(data)).ReadAll()
+		if err != nil && err != io.EOF {
+			return "", errors.Wrap(errors.ErrInvalidFormat, "output is not valid CSV", err)
+		}
+		if len(records) == 0 {
+			return "", errors.New(errors.ErrInvalidFormat, "CSV output has no records")
+		}
+		for i, want := range f.Headers {
+			if i >= len(records[0]) || !strings.EqualFold(strings.TrimSpace(records[0][i]), want) {
+				return "", errors.Newf(errors.ErrInvalidFormat, "CSV header must be %s, got %s",
+					strings.Join(f.Headers, ","), strings.Join(records[0], ","))
+			}
+		}
+		return data, nil
+
+	case FormatCode:
+		for _, m := range codeFence.FindAllStringSubmatch(output, -1) {
+			if f.Language == "" || strings.EqualFold(m[1], f.Language) {
+				return output, nil
+			}
+		}
+		if f.Language == "" {
+			return "", errors.New(errors.ErrInvalidFormat, "output has no fenced code block")
+		}
+		return "", errors.Newf(errors.ErrInvalidFormat, "output has no ```%s fenced code block", f.Language)
+
+	default:
+		return output, nil
+	}
+}
@@ -0,0 +1,91 @@
+package task
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+)
+
+// scriptedLLM returns its responses in order and records the prompts
+type scriptedLLM struct {
+	responses []string
+	prompts   []string
+}
+
+func (s *scriptedLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	s.prompts = append(s.prompts, prompt)
+	response := s.responses[0]
+	if len(s.responses) > 1 {
+		s.responses = s.responses[1:]
+	}
+	return response, nil
+}
+
+func TestOutputFormat_Check(t *testing.T) {
+	tests := []struct {
+		name   string
+		format OutputFormat
+		output string
+		want   string
+		valid  bool
+	}{
+		{"fenced json", OutputFormat{Kind: FormatJSON}, "```json\n{\"a\": 1}\n```", `{"a": 1}`, true},
+		{"invalid json", OutputFormat{Kind: FormatJSON}, "{a: 1}", "", false},
+		{"truncated json", OutputFormat{Kind: FormatJSON}, `{"items": ["a", "b`, "", false},
+		{"repaired json", OutputFormat{Kind: FormatJSON, Repair: true}, `{"items": ["a", "b`, `{"items": ["a", "b"]}`, true},
+		{"csv headers", OutputFormat{Kind: FormatCSV, Headers: []string{"name", "age"}}, "name,age\nada,36", "name,age\nada,36", true},
+		{"csv wrong headers", OutputFormat{Kind: FormatCSV, Headers: []string{"name", "age"}}, "age,name\n36,ada", "", false},
+		{"csv ragged", OutputFormat{Kind: FormatCSV}, "a,b\n1", "", false},
+		{"code language", OutputFormat{Kind: FormatCode, Language: "go"}, "Here:\n```go\nfunc main() {}\n```", "Here:\n```go\nfunc main() {}\n```", true},
+		{"code wrong language", OutputFormat{Kind: FormatCode, Language: "go"}, "```python\nprint(1)\n```", "", false},
+		{"markdown", OutputFormat{Kind: FormatMarkdown}, "# Title", "# Title", true},
+		{"empty", OutputFormat{Kind: FormatMarkdown}, "  ", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.format.Check(tt.output)
+			if tt.valid != (err == nil) {
+				t.Fatalf("Check() error = %v, want valid = %v", err, tt.valid)
+			}
+			if err != nil && !errors.HasCode(err, errors.ErrInvalidFormat) {
+				t.Errorf("Check() error = %v, want invalid format", err)
+			}
+			if got != tt.want {
+				t.Errorf("Check() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecute_RetriesFormatViolations(t *testing.T) {
+	llm := &scriptedLLM{responses: []string{"Sure! name: ada", "```json\n{\"name\": \"ada\"}\n```"}}
+	tsk := New(Config{
+		Description:  "describe ada",
+		Agent:        agent.New(agent.Config{Name: "writer", LLM: llm}),
+		OutputFormat: &OutputFormat{Kind: FormatJSON},
+	})
+
+	result, err := tsk.Execute(context.Background())
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if result != `{"name": "ada"}` {
+		t.Errorf("Execute() = %q, want the corrected JSON", result)
+	}
+	if len(llm.prompts) != 2 || !strings.Contains(llm.prompts[0], "valid JSON only") || !strings.Contains(llm.prompts[1], "rejected") {
+		t.Errorf("prompts = %q, want format instruction and a correction request", llm.prompts)
+	}
+
+	llm = &scriptedLLM{responses: []string{"not json"}}
+	tsk.Agent = agent.New(agent.Config{Name: "writer", LLM: llm})
+	tsk.OutputFormat.Retries = 1
+	if _, err := tsk.Execute(context.Background()); !errors.HasCode(err, errors.ErrInvalidFormat) {
+		t.Errorf("Execute() error = %v, want invalid format", err)
+	}
+	if len(llm.prompts) != 2 {
+		t.Errorf("LLM calls = %d, want 2", len(llm.prompts))
+	}
+}
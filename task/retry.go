@@ -0,0 +1,17 @@
+package task
+
+import "time"
+
+// RetryPolicy controls how orchestrator.RetryFailed re-executes the task
+// after it failed in a parallel run. Tasks without a policy are retried on
+// every call.
+type RetryPolicy struct {
+	// MaxRetries caps the re-executions across RetryFailed calls
+	// (0 = never retried)
+	MaxRetries int `json:"max_retries" yaml:"max_retries"`
+	// Backoff is the wait before each re-execution
+	Backoff time.Duration `json:"backoff,omitempty" yaml:"backoff,omitempty"`
+	// RetryableOnly skips failures whose error is not marked retryable,
+	// such as invalid configuration or policy violations
+	RetryableOnly bool `json:"retryable_only,omitempty" yaml:"retryable_only,omitempty"`
+}
@@ -2,10 +2,14 @@ package task
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
+	"strings"
 
 	"github.com/counhopig/gittyai/agent"
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/postprocess"
 )
 
 // Task represents a unit of work to be completed
@@ -13,7 +17,22 @@ type Task struct {
 	Description    string
 	ExpectedOutput string
 	Agent          *agent.Agent
-	Context        []string // References to previous tasks for context
+	Context        []string          // References to previous tasks for context
+	PromptRef      string            // Prompt template ("name@version") the description was rendered from, if any
+	Skills         []string          // Skills required of the agent; used when the orchestrator assigns one
+	IdempotencyKey string            // Overrides the key derived by Key, for tasks whose inputs live elsewhere
+	PostProcess    postprocess.Chain // Applied to the agent's output, in order
+	OutputFormat   *OutputFormat     // Optional contract the agent's output must satisfy
+	Retry          *RetryPolicy      // How RetryFailed re-executes the task after a parallel run
+	Priority       int               // Higher-priority tasks start first when parallel slots are limited
+}
+
+// PromptData is the data available to task prompt templates
+type PromptData struct {
+	Description    string
+	ExpectedOutput string
+	Agent          string
+	Context        []string
 }
 
 // Config represents the configuration for creating a Task
@@ -22,6 +41,13 @@ type Config struct {
 	ExpectedOutput string
 	Agent          *agent.Agent
 	Context        []string
+	PromptRef      string
+	Skills         []string
+	IdempotencyKey string
+	PostProcess    postprocess.Chain
+	OutputFormat   *OutputFormat
+	Retry          *RetryPolicy
+	Priority       int
 }
 
 // New creates a new Task
@@ -31,6 +57,13 @@ func New(cfg Config) *Task {
 		ExpectedOutput: cfg.ExpectedOutput,
 		Agent:          cfg.Agent,
 		Context:        cfg.Context,
+		PromptRef:      cfg.PromptRef,
+		Skills:         cfg.Skills,
+		IdempotencyKey: cfg.IdempotencyKey,
+		PostProcess:    cfg.PostProcess,
+		OutputFormat:   cfg.OutputFormat,
+		Retry:          cfg.Retry,
+		Priority:       cfg.Priority,
 	}
 }
 
@@ -41,26 +74,91 @@ func (t *Task) WithAgent(a *agent.Agent) *Task {
 	return &newTask
 }
 
+// Prompt returns the task prompt given to the agent, built from the task
+// description, expected output and output format
+func (t *Task) Prompt() string {
+	prompt := t.Description
+	if len(t.ExpectedOutput) > 0 {
+		prompt += fmt.Sprintf("\n\nExpected output: %s", t.ExpectedOutput)
+	}
+
+	if t.OutputFormat != nil {
+		prompt += "\n\n" + t.OutputFormat.Instruction()
+	}
+	return prompt
+}
+
 // Execute runs the task and returns the result
 func (t *Task) Execute(ctx context.Context) (string, error) {
 	if t.Agent == nil {
 		return "", errors.Validationf("task '%s' has no agent assigned", t.Description)
 	}
 
-	// Build prompt from task description and expected output
-	prompt := t.Description
-	if len(t.ExpectedOutput) > 0 {
-		prompt += fmt.Sprintf("\n\nExpected output: %s", t.ExpectedOutput)
-	}
-
+	prompt := t.Prompt()
 	result, err := t.Agent.Execute(ctx, prompt)
 	if err != nil {
 		return "", errors.Wrap(errors.ErrInternal, "task execution failed", err).WithContext("task_description", t.Description).WithContext("agent", t.Agent.Name)
 	}
 
+	if t.OutputFormat != nil {
+		if result, err = t.enforceFormat(ctx, prompt, result); err != nil {
+			return "", err
+		}
+	}
+
+	result, err = t.PostProcess.Process(ctx, result)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "task post-processing failed", err).WithContext("task_description", t.Description).WithContext("agent", t.Agent.Name)
+	}
+
 	return result, nil
 }
 
+// enforceFormat checks the output against the task's format, asking the
+// agent to correct violations until the retries are used up
+func (t *Task) enforceFormat(ctx context.Context, prompt, result string) (string, error) {
+	retries := t.OutputFormat.retries()
+	for attempt := 0; ; attempt++ {
+		checked, violation := t.OutputFormat.Check(result)
+		if violation == nil {
+			return checked, nil
+		}
+		if attempt >= retries {
+			return "", errors.Wrap(errors.ErrInvalidFormat, "task output violates its format", violation).
+				WithContext("format", string(t.OutputFormat.Kind)).
+				WithContext("attempts", attempt+1).
+				WithContext("agent", t.Agent.Name)
+		}
+
+		retry := fmt.Sprintf("%s\n\nYour previous response was rejected: %s.\nPrevious response:\n%s\n\nRespond again, following the output format exactly.",
+			prompt, violation.Error(), result)
+		var err error
+		if result, err = t.Agent.Execute(ctx, retry); err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "task execution failed", err).WithContext("task_description", t.Description).WithContext("agent", t.Agent.Name)
+		}
+	}
+}
+
+// Key returns the task's idempotency key. Unless IdempotencyKey is set, it
+// is derived from the description, expected output, context and prompt
+// reference, so the same work yields the same key whichever agent runs it.
+func (t *Task) Key() string {
+	if t.IdempotencyKey != "" {
+		return t.IdempotencyKey
+	}
+
+	h := sha256.New()
+	for _, part := range append([]string{t.Description, t.ExpectedOutput, t.PromptRef}, t.Context...) {
+		// Length prefixes keep ("ab", "c") and ("a", "bc") apart
+		fmt.Fprintf(h, "%d:%s;", len(part), part)
+	}
+	// The format changes the output, but keys of tasks without one are kept
+	if f := t.OutputFormat; f != nil {
+		fmt.Fprintf(h, "format:%s:%s:%s;", f.Kind, strings.Join(f.Headers, ","), f.Language)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
 // String returns a string representation of the task
 func (t *Task) String() string {
 	agentName := "unassigned"
@@ -0,0 +1,177 @@
+package tenant
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+)
+
+// Factory creates the LLM provider used for a tenant, typically selecting
+// the tenant's API key with cfg.APIKey
+type Factory func(cfg Config) (llm.LLM, error)
+
+// LLM implements llm.LLM by dispatching every request to a per-tenant
+// provider selected from the tenant in the request context, enforcing the
+// tenant's rate limit and request budget
+type LLM struct {
+	registry *Registry
+	factory  Factory
+
+	mu        sync.Mutex
+	providers map[string]cachedProvider
+	usage     map[string]*usage
+}
+
+// cachedProvider is a tenant's provider with the config it was created from
+type cachedProvider struct {
+	config   Config
+	provider llm.LLM
+}
+
+// usage tracks a tenant's request history
+type usage struct {
+	total  int
+	recent []time.Time // Request times within the last minute
+}
+
+// NewLLM creates a tenant-aware LLM
+func NewLLM(registry *Registry, factory Factory) (*LLM, error) {
+	if registry == nil {
+		return nil, errors.RequiredField("registry")
+	}
+	if factory == nil {
+		return nil, errors.RequiredField("factory")
+	}
+	return &LLM{
+		registry:  registry,
+		factory:   factory,
+		providers: make(map[string]cachedProvider),
+		usage:     make(map[string]*usage),
+	}, nil
+}
+
+// Generate sends the prompt with the provider of the tenant in ctx
+func (t *LLM) Generate(ctx context.Context, prompt string) (string, error) {
+	provider, err := t.provider(ctx)
+	if err != nil {
+		return "", err
+	}
+	return provider.Generate(ctx, prompt)
+}
+
+// GenerateResponse sends the prompt with the provider of the tenant in ctx,
+// returning the completion with its token usage and metadata
+func (t *LLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	provider, err := t.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return llm.GenerateResponse(ctx, provider, prompt)
+}
+
+// GenerateStream streams the response of the provider of the tenant in ctx,
+// or passes it as a single chunk when that provider cannot stream
+func (t *LLM) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*llm.Response, error) {
+	provider, err := t.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return llm.GenerateStream(ctx, provider, prompt, fn)
+}
+
+// Chat sends a conversation with the provider of the tenant in ctx
+func (t *LLM) Chat(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	resp, err := t.ChatResponse(ctx, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: resp.Content}, nil
+}
+
+// ChatResponse sends a conversation with the provider of the tenant in ctx,
+// flattened into one prompt when that provider has no chat support
+func (t *LLM) ChatResponse(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	provider, err := t.provider(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return llm.ChatResponse(ctx, provider, messages)
+}
+
+// provider returns the provider of the tenant in ctx, counting the request
+// against the tenant's limits
+func (t *LLM) provider(ctx context.Context) (llm.LLM, error) {
+	cfg, err := t.registry.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return t.acquire(cfg)
+}
+
+// acquire checks the tenant's limits, records the request and returns its provider
+func (t *LLM) acquire(cfg Config) (llm.LLM, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	u, ok := t.usage[cfg.ID]
+	if !ok {
+		u = &usage{}
+		t.usage[cfg.ID] = u
+	}
+
+	if cfg.MaxRequests > 0 && u.total >= cfg.MaxRequests {
+		return nil, errors.QuotaExceeded("tenant requests", cfg.MaxRequests).WithContext("tenant", cfg.ID)
+	}
+
+	now := time.Now()
+	if cfg.MaxRPM > 0 {
+		cutoff := now.Add(-time.Minute)
+		kept := u.recent[:0]
+		for _, ts := range u.recent {
+			if ts.After(cutoff) {
+				kept = append(kept, ts)
+			}
+		}
+		u.recent = kept
+		if len(u.recent) >= cfg.MaxRPM {
+			return nil, errors.RateLimitExceeded("tenant "+cfg.ID, cfg.MaxRPM).WithContext("tenant", cfg.ID)
+		}
+	}
+
+	// The provider is created again when the tenant's config changed in the
+	// registry, e.g. after its API key was rotated
+	cached, ok := t.providers[cfg.ID]
+	if !ok || !reflect.DeepEqual(cached.config, cfg) {
+		provider, err := t.factory(cfg)
+		if err != nil {
+			return nil, errors.Wrap(errors.ErrProviderConfig, "failed to create tenant LLM provider", err).WithContext("tenant", cfg.ID)
+		}
+		cached = cachedProvider{config: cfg, provider: provider}
+		cached.config.APIKeys = make(map[string]string, len(cfg.APIKeys))
+		for name, key := range cfg.APIKeys {
+			cached.config.APIKeys[name] = key
+		}
+		t.providers[cfg.ID] = cached
+	}
+
+	u.total++
+	if cfg.MaxRPM > 0 {
+		u.recent = append(u.recent, now)
+	}
+	return cached.provider, nil
+}
+
+// Usage returns the number of requests made by a tenant
+func (t *LLM) Usage(tenantID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if u, ok := t.usage[tenantID]; ok {
+		return u.total
+	}
+	return 0
+}
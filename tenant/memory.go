@@ -0,0 +1,104 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+
+	"github.com/counhopig/gittyai/memory"
+)
+
+// Memory implements memory.Memory with a separate store per tenant
+// namespace, so records of one tenant are never retrieved for another
+type Memory struct {
+	registry *Registry
+	newStore func() memory.Memory
+
+	mu     sync.Mutex
+	stores map[string]memory.Memory
+}
+
+// NewMemory creates a tenant-scoped memory. newStore creates the backing
+// store of each namespace; nil uses memory.New. To buffer writes, return a
+// memory.Buffered from newStore rather than buffering the tenant memory,
+// whose namespace is resolved from the context of each call.
+func NewMemory(registry *Registry, newStore func() memory.Memory) *Memory {
+	if newStore == nil {
+		newStore = func() memory.Memory { return memory.New() }
+	}
+	return &Memory{
+		registry: registry,
+		newStore: newStore,
+		stores:   make(map[string]memory.Memory),
+	}
+}
+
+// Store saves a record in the namespace of the tenant in ctx
+func (m *Memory) Store(ctx context.Context, record memory.Record) error {
+	store, err := m.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.Store(ctx, record)
+}
+
+// StoreBatch saves records in the namespace of the tenant in ctx
+func (m *Memory) StoreBatch(ctx context.Context, records []memory.Record) error {
+	store, err := m.store(ctx)
+	if err != nil {
+		return err
+	}
+	return memory.StoreBatch(ctx, store, records)
+}
+
+// Retrieve fetches records from the namespace of the tenant in ctx
+func (m *Memory) Retrieve(ctx context.Context, query string, limit int) ([]memory.Record, error) {
+	store, err := m.store(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return store.Retrieve(ctx, query, limit)
+}
+
+// Clear removes all records in the namespace of the tenant in ctx
+func (m *Memory) Clear(ctx context.Context) error {
+	store, err := m.store(ctx)
+	if err != nil {
+		return err
+	}
+	return store.Clear(ctx)
+}
+
+// Flush writes the buffered records of every namespace's store
+func (m *Memory) Flush(ctx context.Context) error {
+	m.mu.Lock()
+	stores := make([]memory.Memory, 0, len(m.stores))
+	for _, store := range m.stores {
+		stores = append(stores, store)
+	}
+	m.mu.Unlock()
+
+	for _, store := range stores {
+		if err := memory.Flush(ctx, store); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// store returns the backing store of the tenant's namespace
+func (m *Memory) store(ctx context.Context) (memory.Memory, error) {
+	cfg, err := m.registry.FromContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	store, ok := m.stores[cfg.MemoryNamespace]
+	if !ok {
+		store = m.newStore()
+		m.stores[cfg.MemoryNamespace] = store
+	}
+	return store, nil
+}
@@ -0,0 +1,98 @@
+package tenant
+
+import (
+	"context"
+	"sync"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying the given tenant ID
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ID carried by ctx, or "" if none is set
+func FromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Config holds the settings scoped to a single tenant
+type Config struct {
+	// ID uniquely identifies the tenant
+	ID string
+	// APIKeys maps provider names (see config.Provider*) to the tenant's keys
+	APIKeys map[string]string
+	// MaxRPM limits LLM requests per minute for the tenant (0 = unlimited)
+	MaxRPM int
+	// MaxRequests is the tenant's total LLM request budget (0 = unlimited)
+	MaxRequests int
+	// MemoryNamespace isolates the tenant's memory records (default: ID)
+	MemoryNamespace string
+}
+
+// Registry holds tenant configurations
+type Registry struct {
+	mu      sync.RWMutex
+	tenants map[string]Config
+}
+
+// NewRegistry creates an empty tenant registry
+func NewRegistry() *Registry {
+	return &Registry{
+		tenants: make(map[string]Config),
+	}
+}
+
+// Register adds or replaces a tenant configuration
+func (r *Registry) Register(cfg Config) error {
+	if cfg.ID == "" {
+		return errors.RequiredField("tenant id")
+	}
+	if cfg.MemoryNamespace == "" {
+		cfg.MemoryNamespace = cfg.ID
+	}
+
+	r.mu.Lock()
+	r.tenants[cfg.ID] = cfg
+	r.mu.Unlock()
+	return nil
+}
+
+// Get returns the configuration of a tenant
+func (r *Registry) Get(id string) (Config, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	cfg, ok := r.tenants[id]
+	if !ok {
+		return Config{}, errors.NotFound("tenant", id)
+	}
+	return cfg, nil
+}
+
+// FromContext resolves the configuration of the tenant carried by ctx
+func (r *Registry) FromContext(ctx context.Context) (Config, error) {
+	id := FromContext(ctx)
+	if id == "" {
+		return Config{}, errors.Unauthorized("no tenant identity in context")
+	}
+	return r.Get(id)
+}
+
+// APIKey returns the tenant's key for a provider
+func (c Config) APIKey(provider string) (string, error) {
+	key, ok := c.APIKeys[provider]
+	if !ok || key == "" {
+		return "", errors.MissingConfig("api key").
+			WithContext("tenant", c.ID).
+			WithContext("provider", provider)
+	}
+	return key, nil
+}
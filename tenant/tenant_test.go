@@ -0,0 +1,180 @@
+package tenant
+
+import (
+	"context"
+	stderrors "errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/agent"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/memory"
+)
+
+// keyEcho is an LLM that answers with the API key it was created with
+type keyEcho struct {
+	key string
+}
+
+func (k *keyEcho) Generate(ctx context.Context, prompt string) (string, error) {
+	return k.key, nil
+}
+
+func newTestRegistry(t *testing.T) *Registry {
+	t.Helper()
+	reg := NewRegistry()
+	for _, cfg := range []Config{
+		{ID: "acme", APIKeys: map[string]string{"openai": "acme-key"}, MaxRequests: 2},
+		{ID: "globex", APIKeys: map[string]string{"openai": "globex-key"}, MaxRPM: 1},
+	} {
+		if err := reg.Register(cfg); err != nil {
+			t.Fatalf("Register() unexpected error: %v", err)
+		}
+	}
+	return reg
+}
+
+func TestLLM_PerTenantKeysAndLimits(t *testing.T) {
+	reg := newTestRegistry(t)
+	provider, err := NewLLM(reg, func(cfg Config) (llm.LLM, error) {
+		key, err := cfg.APIKey("openai")
+		if err != nil {
+			return nil, err
+		}
+		return &keyEcho{key: key}, nil
+	})
+	if err != nil {
+		t.Fatalf("NewLLM() unexpected error: %v", err)
+	}
+
+	acme := NewContext(context.Background(), "acme")
+	globex := NewContext(context.Background(), "globex")
+
+	if got, _ := provider.Generate(acme, "hi"); got != "acme-key" {
+		t.Errorf("Generate(acme) = %q, want acme-key", got)
+	}
+	if got, _ := provider.Generate(globex, "hi"); got != "globex-key" {
+		t.Errorf("Generate(globex) = %q, want globex-key", got)
+	}
+
+	if _, err := provider.Generate(globex, "hi"); !errors.HasCode(err, errors.ErrRateLimitExceeded) {
+		t.Errorf("Generate(globex) error = %v, want rate limit", err)
+	}
+
+	_, _ = provider.Generate(acme, "hi")
+	if _, err := provider.Generate(acme, "hi"); !errors.HasCode(err, errors.ErrQuotaExceeded) {
+		t.Errorf("Generate(acme) error = %v, want quota exceeded", err)
+	}
+
+	if _, err := provider.Generate(context.Background(), "hi"); !errors.HasCode(err, errors.ErrUnauthorized) {
+		t.Errorf("Generate() without tenant error = %v, want unauthorized", err)
+	}
+}
+
+func TestLLM_RotatedKey(t *testing.T) {
+	reg := NewRegistry()
+	_ = reg.Register(Config{ID: "acme", APIKeys: map[string]string{"openai": "old-key"}})
+	created := 0
+	provider, _ := NewLLM(reg, func(cfg Config) (llm.LLM, error) {
+		created++
+		key, err := cfg.APIKey("openai")
+		return &keyEcho{key: key}, err
+	})
+	ctx := NewContext(context.Background(), "acme")
+
+	_, _ = provider.Generate(ctx, "hi")
+	if got, _ := provider.Generate(ctx, "hi"); got != "old-key" || created != 1 {
+		t.Errorf("Generate() = %q with %d providers created, want old-key from one cached provider", got, created)
+	}
+
+	_ = reg.Register(Config{ID: "acme", APIKeys: map[string]string{"openai": "new-key"}})
+	if got, _ := provider.Generate(ctx, "hi"); got != "new-key" || created != 2 {
+		t.Errorf("Generate() after rotation = %q with %d providers created, want new-key", got, created)
+	}
+}
+
+// streamingChat streams its key in two chunks and answers conversations
+// with the number of messages it was sent
+type streamingChat struct {
+	keyEcho
+}
+
+func (s *streamingChat) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*llm.Response, error) {
+	half := len(s.key) / 2
+	for _, chunk := range []string{s.key[:half], s.key[half:]} {
+		if err := fn(chunk); err != nil {
+			return nil, err
+		}
+	}
+	return &llm.Response{Content: s.key, FinishReason: llm.FinishStop}, nil
+}
+
+func (s *streamingChat) Chat(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	resp, err := s.ChatResponse(ctx, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: resp.Content}, nil
+}
+
+func (s *streamingChat) ChatResponse(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	return &llm.Response{Content: fmt.Sprintf("%s: %d messages", s.key, len(messages)), FinishReason: llm.FinishStop}, nil
+}
+
+func TestLLM_StreamsAndChatsThroughTenantProvider(t *testing.T) {
+	reg := newTestRegistry(t)
+	provider, err := NewLLM(reg, func(cfg Config) (llm.LLM, error) {
+		key, err := cfg.APIKey("openai")
+		return &streamingChat{keyEcho{key: key}}, err
+	})
+	if err != nil {
+		t.Fatalf("NewLLM() unexpected error: %v", err)
+	}
+	ctx := NewContext(context.Background(), "acme")
+
+	var chunks []string
+	streamer := agent.New(agent.Config{
+		Name:    "writer",
+		LLM:     provider,
+		OnChunk: func(chunk string) error { chunks = append(chunks, chunk); return nil },
+	})
+	out, err := streamer.Execute(ctx, "Write")
+	if err != nil {
+		t.Fatalf("Execute() unexpected error: %v", err)
+	}
+	if out != "acme-key" || len(chunks) != 2 {
+		t.Errorf("Execute() = %q in chunks %q, want acme-key streamed in 2 chunks", out, chunks)
+	}
+
+	chatter := agent.New(agent.Config{Name: "writer", LLM: provider, ChatMessages: true})
+	if out, err := chatter.Execute(ctx, "Write"); err != nil || !strings.HasPrefix(out, "acme-key: ") {
+		t.Errorf("Execute() = %q, %v, want a chat answer from the tenant's provider", out, err)
+	}
+	if _, err := chatter.Execute(ctx, "Write"); !stderrors.Is(err, errors.New(errors.ErrQuotaExceeded, "")) {
+		t.Errorf("Execute() error = %v, want the tenant's request budget enforced", err)
+	}
+}
+
+func TestMemory_Isolation(t *testing.T) {
+	mem := NewMemory(newTestRegistry(t), nil)
+	acme := NewContext(context.Background(), "acme")
+	globex := NewContext(context.Background(), "globex")
+
+	if err := mem.Store(acme, memory.Record{AgentName: "a", Content: "secret"}); err != nil {
+		t.Fatalf("Store() unexpected error: %v", err)
+	}
+
+	records, err := mem.Retrieve(globex, "", 10)
+	if err != nil {
+		t.Fatalf("Retrieve() unexpected error: %v", err)
+	}
+	if len(records) != 0 {
+		t.Errorf("Retrieve(globex) returned %d records, want 0", len(records))
+	}
+
+	if records, _ := mem.Retrieve(acme, "", 10); len(records) != 1 {
+		t.Errorf("Retrieve(acme) returned %d records, want 1", len(records))
+	}
+}
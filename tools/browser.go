@@ -0,0 +1,280 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// Names of the browser tools
+const (
+	BrowserNavigateToolName   = "browser_navigate"
+	BrowserClickToolName      = "browser_click"
+	BrowserTextToolName       = "browser_text"
+	BrowserScreenshotToolName = "browser_screenshot"
+)
+
+// Defaults applied to BrowserConfig
+const (
+	DefaultBrowserSteps       = 20
+	DefaultBrowserStepTimeout = 30 * time.Second
+	DefaultBrowserText        = 20000
+)
+
+// maxBrowserRuns bounds the runs whose step counts are kept. When another
+// run starts, the count of the least recently active run is dropped, so a
+// long-running service does not keep an entry for every run it served.
+const maxBrowserRuns = 256
+
+// Browser drives the page of a web browser. The browser tools serialize
+// their calls, so implementations need not be safe for concurrent use.
+// NewChromeBrowser, built with the chromedp tag, drives headless Chrome.
+type Browser interface {
+	// Navigate loads a URL and waits for the page
+	Navigate(ctx context.Context, url string) error
+	// Click clicks the first element matching a CSS selector
+	Click(ctx context.Context, selector string) error
+	// Text returns the visible text of the first element matching a CSS
+	// selector
+	Text(ctx context.Context, selector string) (string, error)
+	// Screenshot captures the viewport as a PNG image
+	Screenshot(ctx context.Context) ([]byte, error)
+	// URL returns the URL of the current page
+	URL(ctx context.Context) (string, error)
+}
+
+// BrowserConfig restricts the browser tools
+type BrowserConfig struct {
+	// AllowedHosts are the hosts pages may be on, including their
+	// subdomains, e.g. "example.com". Required: the browser never leaves
+	// them, even when a click or redirect leads elsewhere.
+	AllowedHosts []string
+	// MaxSteps bounds the browser actions of each run (0 = DefaultBrowserSteps)
+	MaxSteps int
+	// StepTimeout bounds each action (0 = DefaultBrowserStepTimeout)
+	StepTimeout time.Duration
+	// MaxText bounds the text returned to the model, in characters
+	// (0 = DefaultBrowserText)
+	MaxText int
+}
+
+// browser implements the browser tools
+type browser struct {
+	config  BrowserConfig
+	browser Browser
+
+	mu    sync.Mutex
+	steps map[string]*browserRun // Step counts per run ID
+	clock uint64                 // Orders the runs' last steps
+}
+
+// browserRun counts the actions of a run
+type browserRun struct {
+	steps    int
+	lastStep uint64
+}
+
+// NewBrowserTools creates tools to navigate, click, read and capture the
+// pages of a browser, for sites that only render with JavaScript. Clicks are
+// side-effecting, since they may submit forms, so they need confirmation when
+// the run has a Confirmer. Screenshots are saved to the artifact store of the
+// run.
+func NewBrowserTools(b Browser, cfg BrowserConfig) ([]Tool, error) {
+	if b == nil {
+		return nil, errors.RequiredField("browser")
+	}
+	if len(cfg.AllowedHosts) == 0 {
+		return nil, errors.RequiredField("allowed hosts")
+	}
+	hosts := make([]string, len(cfg.AllowedHosts))
+	for i, host := range cfg.AllowedHosts {
+		hosts[i] = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(host)), "*.")
+	}
+	cfg.AllowedHosts = hosts
+	if cfg.MaxSteps <= 0 {
+		cfg.MaxSteps = DefaultBrowserSteps
+	}
+	if cfg.StepTimeout <= 0 {
+		cfg.StepTimeout = DefaultBrowserStepTimeout
+	}
+	if cfg.MaxText <= 0 {
+		cfg.MaxText = DefaultBrowserText
+	}
+
+	br := &browser{config: cfg, browser: b, steps: make(map[string]*browserRun)}
+	return []Tool{
+		actionTool{BaseTool: NewBaseTool(BrowserNavigateToolName,
+			fmt.Sprintf("Opens a web page in a browser that runs its JavaScript. Allowed sites: %s.", strings.Join(hosts, ", ")),
+			map[string]interface{}{"url": "page URL"}), run: br.navigate},
+		actionTool{BaseTool: NewBaseTool(BrowserClickToolName,
+			"Clicks an element of the current page.",
+			map[string]interface{}{"selector": "CSS selector of the element"}), run: br.click, sideEffects: true},
+		actionTool{BaseTool: NewBaseTool(BrowserTextToolName,
+			"Returns the visible text of the current page or of an element.",
+			map[string]interface{}{"selector": "CSS selector (default the whole page)"}), run: br.text},
+		actionTool{BaseTool: NewBaseTool(BrowserScreenshotToolName,
+			"Captures the current page as an image and returns its artifact reference.",
+			nil), run: br.screenshot},
+	}, nil
+}
+
+// allowed reports whether a URL is on an allowed host
+func (br *browser) allowed(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, h := range br.config.AllowedHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// step runs an action under the lock, the step budget of the run and the
+// step timeout
+func (br *browser) step(ctx context.Context, action func(ctx context.Context) (string, error)) (string, error) {
+	br.mu.Lock()
+	defer br.mu.Unlock()
+
+	run := br.run(runid.FromContext(ctx))
+	if run.steps >= br.config.MaxSteps {
+		return "", errors.QuotaExceeded("browser_steps", br.config.MaxSteps)
+	}
+	run.steps++
+
+	ctx, cancel := context.WithTimeout(ctx, br.config.StepTimeout)
+	defer cancel()
+	return action(ctx)
+}
+
+// run returns the step count of a run, evicting the least recently active
+// run when maxBrowserRuns are kept. Callers must hold br.mu.
+func (br *browser) run(id string) *browserRun {
+	br.clock++
+	if run, ok := br.steps[id]; ok {
+		run.lastStep = br.clock
+		return run
+	}
+	if len(br.steps) >= maxBrowserRuns {
+		var oldest string
+		var oldestStep uint64
+		for other, run := range br.steps {
+			if oldestStep == 0 || run.lastStep < oldestStep {
+				oldest, oldestStep = other, run.lastStep
+			}
+		}
+		delete(br.steps, oldest)
+	}
+	run := &browserRun{lastStep: br.clock}
+	br.steps[id] = run
+	return run
+}
+
+// checkLocation leaves the page when it is off the allowed hosts, whether an
+// action led there or the page went there by itself, e.g. with a scripted
+// redirect. The blank page that replaces such pages is allowed.
+func (br *browser) checkLocation(ctx context.Context) (string, error) {
+	current, err := br.browser.URL(ctx)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "failed to read the page URL", err)
+	}
+	if current != "about:blank" && !br.allowed(current) {
+		_ = br.browser.Navigate(ctx, "about:blank")
+		return "", errors.Unauthorized("the page left the allowed sites and was closed").
+			WithContext("url", current).
+			WithContext("allowed", strings.Join(br.config.AllowedHosts, ", "))
+	}
+	return current, nil
+}
+
+func (br *browser) navigate(ctx context.Context, args map[string]interface{}) (string, error) {
+	target := stringArg(args, "url")
+	if target == "" {
+		return "", errors.RequiredField("url")
+	}
+	if !br.allowed(target) {
+		return "", errors.Unauthorized("URL is outside the allowed sites").
+			WithContext("url", target).
+			WithContext("allowed", strings.Join(br.config.AllowedHosts, ", "))
+	}
+	return br.step(ctx, func(ctx context.Context) (string, error) {
+		if err := br.browser.Navigate(ctx, target); err != nil {
+			return "", errors.Wrap(errors.ErrNetworkUnavail, "failed to load page", err).WithContext("url", target)
+		}
+		current, err := br.checkLocation(ctx)
+		if err != nil {
+			return "", err
+		}
+		return "Opened " + current, nil
+	})
+}
+
+func (br *browser) click(ctx context.Context, args map[string]interface{}) (string, error) {
+	selector := stringArg(args, "selector")
+	if selector == "" {
+		return "", errors.RequiredField("selector")
+	}
+	return br.step(ctx, func(ctx context.Context) (string, error) {
+		if err := br.browser.Click(ctx, selector); err != nil {
+			return "", errors.Wrap(errors.ErrInvalidField, "failed to click element", err).WithContext("selector", selector)
+		}
+		current, err := br.checkLocation(ctx)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Clicked %s, now on %s", selector, current), nil
+	})
+}
+
+func (br *browser) text(ctx context.Context, args map[string]interface{}) (string, error) {
+	selector := stringArg(args, "selector")
+	if selector == "" {
+		selector = "body"
+	}
+	return br.step(ctx, func(ctx context.Context) (string, error) {
+		if _, err := br.checkLocation(ctx); err != nil {
+			return "", err
+		}
+		text, err := br.browser.Text(ctx, selector)
+		if err != nil {
+			return "", errors.Wrap(errors.ErrInvalidField, "failed to read element text", err).WithContext("selector", selector)
+		}
+		if text = strings.TrimSpace(text); text == "" {
+			return "(no text)", nil
+		}
+		return truncate(text, br.config.MaxText), nil
+	})
+}
+
+func (br *browser) screenshot(ctx context.Context, args map[string]interface{}) (string, error) {
+	store := artifact.FromContext(ctx)
+	if store == nil {
+		return "", errors.MissingConfig("artifact store").WithContext("tool", BrowserScreenshotToolName)
+	}
+	return br.step(ctx, func(ctx context.Context) (string, error) {
+		current, err := br.checkLocation(ctx)
+		if err != nil {
+			return "", err
+		}
+		image, err := br.browser.Screenshot(ctx)
+		if err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "failed to capture screenshot", err)
+		}
+		key := fmt.Sprintf("screenshots/%s/%d.png", runid.FromContext(ctx), time.Now().UnixNano())
+		ref, err := store.Put(ctx, key, image, "image/png")
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Screenshot of %s saved to %s (%d bytes)", current, ref.URI, ref.Size), nil
+	})
+}
@@ -0,0 +1,85 @@
+//go:build chromedp
+
+package tools
+
+import (
+	"context"
+
+	"github.com/chromedp/chromedp"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// ChromeBrowser is a Browser driving headless Chrome through chromedp. It is
+// built with the chromedp tag, after adding github.com/chromedp/chromedp to
+// the module, so other builds do not depend on it.
+type ChromeBrowser struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewChromeBrowser starts headless Chrome with one tab. Close it when done.
+func NewChromeBrowser(opts ...chromedp.ExecAllocatorOption) (*ChromeBrowser, error) {
+	allocCtx, cancelAlloc := chromedp.NewExecAllocator(context.Background(), append(chromedp.DefaultExecAllocatorOptions[:], opts...)...)
+	ctx, cancelTab := chromedp.NewContext(allocCtx)
+	cancel := func() {
+		cancelTab()
+		cancelAlloc()
+	}
+	if err := chromedp.Run(ctx); err != nil {
+		cancel()
+		return nil, errors.Wrap(errors.ErrInternal, "failed to start Chrome", err)
+	}
+	return &ChromeBrowser{ctx: ctx, cancel: cancel}, nil
+}
+
+// run runs actions in the tab, stopping when ctx is done
+func (c *ChromeBrowser) run(ctx context.Context, actions ...chromedp.Action) error {
+	runCtx, cancel := context.WithCancel(c.ctx)
+	defer cancel()
+	stop := context.AfterFunc(ctx, cancel)
+	defer stop()
+	if err := chromedp.Run(runCtx, actions...); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// Navigate loads a URL and waits for its body
+func (c *ChromeBrowser) Navigate(ctx context.Context, url string) error {
+	return c.run(ctx, chromedp.Navigate(url), chromedp.WaitReady("body", chromedp.ByQuery))
+}
+
+// Click clicks an element and waits for the resulting page
+func (c *ChromeBrowser) Click(ctx context.Context, selector string) error {
+	return c.run(ctx, chromedp.Click(selector, chromedp.ByQuery, chromedp.NodeVisible), chromedp.WaitReady("body", chromedp.ByQuery))
+}
+
+// Text returns the visible text of an element
+func (c *ChromeBrowser) Text(ctx context.Context, selector string) (string, error) {
+	var text string
+	err := c.run(ctx, chromedp.Text(selector, &text, chromedp.ByQuery, chromedp.NodeReady))
+	return text, err
+}
+
+// Screenshot captures the viewport as a PNG image
+func (c *ChromeBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	var image []byte
+	err := c.run(ctx, chromedp.CaptureScreenshot(&image))
+	return image, err
+}
+
+// URL returns the URL of the current page
+func (c *ChromeBrowser) URL(ctx context.Context) (string, error) {
+	var location string
+	err := c.run(ctx, chromedp.Location(&location))
+	return location, err
+}
+
+// Close stops Chrome
+func (c *ChromeBrowser) Close() {
+	c.cancel()
+}
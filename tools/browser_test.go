@@ -0,0 +1,134 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/artifact"
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// fakeBrowser follows links from a map of selector to URL
+type fakeBrowser struct {
+	url   string
+	links map[string]string
+	text  map[string]string
+}
+
+func (b *fakeBrowser) Navigate(ctx context.Context, url string) error { b.url = url; return nil }
+
+func (b *fakeBrowser) Click(ctx context.Context, selector string) error {
+	target, ok := b.links[selector]
+	if !ok {
+		return errors.NotFound("element", selector)
+	}
+	b.url = target
+	return nil
+}
+
+func (b *fakeBrowser) Text(ctx context.Context, selector string) (string, error) {
+	return b.text[b.url+" "+selector], nil
+}
+
+func (b *fakeBrowser) Screenshot(ctx context.Context) ([]byte, error) {
+	return []byte("\x89PNG\r\n\x1a\nimage"), nil
+}
+
+func (b *fakeBrowser) URL(ctx context.Context) (string, error) { return b.url, nil }
+
+func TestBrowserTools(t *testing.T) {
+	fake := &fakeBrowser{
+		links: map[string]string{"#next": "https://docs.example.com/2", "#ad": "https://evil.test/"},
+		text:  map[string]string{"https://docs.example.com/2 body": "  Page two  ", "https://docs.example.com/2 main": strings.Repeat("x", 50)},
+	}
+	browserTools, err := NewBrowserTools(fake, BrowserConfig{AllowedHosts: []string{"example.com"}, MaxSteps: 8, MaxText: 10})
+	if err != nil {
+		t.Fatalf("NewBrowserTools() error = %v", err)
+	}
+	registry := toolRegistry(t, browserTools)
+	store, _ := artifact.NewFileStore(t.TempDir())
+	ctx := artifact.NewContext(runid.NewContext(context.Background(), "run-1"), store)
+
+	steps := []struct {
+		tool string
+		args map[string]interface{}
+		want string
+	}{
+		{BrowserNavigateToolName, map[string]interface{}{"url": "https://www.example.com/1"}, "Opened https://www.example.com/1"},
+		{BrowserClickToolName, map[string]interface{}{"selector": "#next"}, "Clicked #next, now on https://docs.example.com/2"},
+		{BrowserTextToolName, nil, "Page two"},
+		{BrowserTextToolName, map[string]interface{}{"selector": "main"}, "xxxxxxxxxx..."},
+	}
+	for _, s := range steps {
+		got, err := registry.Execute(ctx, s.tool, s.args)
+		if err != nil || got != s.want {
+			t.Errorf("%s(%v) = %q, %v, want %q", s.tool, s.args, got, err, s.want)
+		}
+	}
+	got, err := registry.Execute(ctx, BrowserScreenshotToolName, nil)
+	if err != nil || !strings.HasPrefix(got, "Screenshot of https://docs.example.com/2 saved to file://") {
+		t.Errorf("screenshot = %q, %v", got, err)
+	}
+
+	// Leaving the allowed hosts fails and closes the page
+	if _, err := registry.Execute(ctx, BrowserClickToolName, map[string]interface{}{"selector": "#ad"}); !errors.HasCode(cause(err), errors.ErrUnauthorized) {
+		t.Errorf("click off the allowed hosts error = %v, want ErrUnauthorized", err)
+	}
+	if fake.url != "about:blank" {
+		t.Errorf("page after leaving the allowed hosts = %q, want about:blank", fake.url)
+	}
+	// A page that went off the allowed hosts by itself is not read
+	for _, tool := range []string{BrowserTextToolName, BrowserScreenshotToolName} {
+		fake.url = "https://evil.test/"
+		if _, err := registry.Execute(ctx, tool, nil); !errors.HasCode(cause(err), errors.ErrUnauthorized) {
+			t.Errorf("%s off the allowed hosts error = %v, want ErrUnauthorized", tool, err)
+		}
+		if fake.url != "about:blank" {
+			t.Errorf("page after %s off the allowed hosts = %q, want about:blank", tool, fake.url)
+		}
+	}
+	for _, url := range []string{"https://notexample.com/", "file:///etc/passwd"} {
+		if _, err := registry.Execute(ctx, BrowserNavigateToolName, map[string]interface{}{"url": url}); !errors.HasCode(cause(err), errors.ErrUnauthorized) {
+			t.Errorf("navigate to %s error = %v, want ErrUnauthorized", url, err)
+		}
+	}
+
+	// The run used its 8 steps; other runs have their own budget
+	if _, err := registry.Execute(ctx, BrowserTextToolName, nil); !errors.HasCode(cause(err), errors.ErrQuotaExceeded) {
+		t.Errorf("step over budget error = %v, want ErrQuotaExceeded", err)
+	}
+	if _, err := registry.Execute(runid.NewContext(context.Background(), "run-2"), BrowserTextToolName, nil); err != nil {
+		t.Errorf("step of another run error = %v", err)
+	}
+
+	if tool, _ := registry.Get(BrowserClickToolName); !HasSideEffects(tool) {
+		t.Error("click tool is not side-effecting")
+	}
+	if _, err := NewBrowserTools(fake, BrowserConfig{}); !errors.HasCode(err, errors.ErrRequiredField) {
+		t.Errorf("NewBrowserTools() without allowed hosts error = %v, want ErrRequiredField", err)
+	}
+}
+
+func TestBrowserTools_BoundsRuns(t *testing.T) {
+	br := &browser{config: BrowserConfig{MaxSteps: 1, StepTimeout: DefaultBrowserStepTimeout}, browser: &fakeBrowser{}, steps: make(map[string]*browserRun)}
+	noop := func(context.Context) (string, error) { return "", nil }
+
+	first := runid.NewContext(context.Background(), "run-0")
+	if _, err := br.step(first, noop); err != nil {
+		t.Fatalf("step() error = %v", err)
+	}
+	for i := 1; i <= 2*maxBrowserRuns; i++ {
+		if _, err := br.step(runid.NewContext(context.Background(), fmt.Sprintf("run-%d", i)), noop); err != nil {
+			t.Fatalf("step() of run %d error = %v", i, err)
+		}
+	}
+	if len(br.steps) != maxBrowserRuns {
+		t.Errorf("step counts kept = %d, want %d", len(br.steps), maxBrowserRuns)
+	}
+	if _, ok := br.steps["run-0"]; ok {
+		t.Error("the least recently active run is still counted")
+	}
+}
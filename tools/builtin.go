@@ -0,0 +1,18 @@
+package tools
+
+// BuiltinConfig configures the builtin tools
+type BuiltinConfig struct {
+	// Rates enables currency conversion in the convert tool
+	Rates RateProvider
+}
+
+// RegisterBuiltins adds the datetime, calculator and convert tools, which
+// answer questions models reliably get wrong
+func RegisterBuiltins(r *Registry, cfg BuiltinConfig) error {
+	for _, tool := range []Tool{NewDateTimeTool(nil), NewCalculatorTool(), NewConvertTool(cfg.Rates)} {
+		if err := r.Register(tool); err != nil {
+			return err
+		}
+	}
+	return nil
+}
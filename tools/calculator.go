@@ -0,0 +1,293 @@
+package tools
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// CalculatorToolName is the name of the calculator tool
+const CalculatorToolName = "calculator"
+
+// CalculatorTool evaluates arithmetic expressions exactly where models
+// approximate
+type CalculatorTool struct {
+	*BaseTool
+}
+
+// NewCalculatorTool creates the calculator tool
+func NewCalculatorTool() *CalculatorTool {
+	return &CalculatorTool{
+		BaseTool: NewBaseTool(CalculatorToolName,
+			"Evaluates an arithmetic expression with + - * / % ^, parentheses, pi, e and the functions sqrt, abs, round, floor, ceil, ln, log10, exp, sin, cos, tan, min, max and pow.",
+			map[string]interface{}{
+				"expression": "expression to evaluate, e.g. \"(1200 * 1.19) / 12\"",
+			}),
+	}
+}
+
+// Execute evaluates the expression
+func (t *CalculatorTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	expression := stringArg(args, "expression")
+	if expression == "" {
+		return "", errors.RequiredField("expression")
+	}
+	v, err := Evaluate(expression)
+	if err != nil {
+		return "", err
+	}
+	return formatNumber(v), nil
+}
+
+// Evaluate computes the value of an arithmetic expression
+func Evaluate(expression string) (float64, error) {
+	p := &exprParser{input: expression}
+	v, err := p.expression()
+	if err != nil {
+		return 0, err
+	}
+	p.skipSpace()
+	if p.pos < len(p.input) {
+		return 0, p.errorf("unexpected %q", p.input[p.pos:])
+	}
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.InvalidField("expression", "result is not a finite number").WithContext("expression", expression)
+	}
+	return v, nil
+}
+
+// exprParser is a recursive-descent parser for arithmetic expressions:
+//
+//	expression = term { ("+" | "-") term }
+//	term       = unary { ("*" | "/" | "%") unary }
+//	unary      = ("-" | "+") unary | power
+//	power      = primary [ "^" unary ]
+//	primary    = number | name | name "(" expression { "," expression } ")" | "(" expression ")"
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) expression() (float64, error) {
+	v, err := p.term()
+	for err == nil {
+		switch p.peek() {
+		case '+', '-':
+			op := p.next()
+			var rhs float64
+			if rhs, err = p.term(); err == nil {
+				if op == '+' {
+					v += rhs
+				} else {
+					v -= rhs
+				}
+			}
+		default:
+			return v, nil
+		}
+	}
+	return 0, err
+}
+
+func (p *exprParser) term() (float64, error) {
+	v, err := p.unary()
+	for err == nil {
+		switch p.peek() {
+		case '*', '/', '%':
+			op := p.next()
+			var rhs float64
+			if rhs, err = p.unary(); err != nil {
+				break
+			}
+			if op != '*' && rhs == 0 {
+				return 0, p.errorf("division by zero")
+			}
+			switch op {
+			case '*':
+				v *= rhs
+			case '/':
+				v /= rhs
+			default:
+				v = math.Mod(v, rhs)
+			}
+		default:
+			return v, nil
+		}
+	}
+	return 0, err
+}
+
+func (p *exprParser) unary() (float64, error) {
+	switch p.peek() {
+	case '-':
+		p.next()
+		v, err := p.unary()
+		return -v, err
+	case '+':
+		p.next()
+		return p.unary()
+	}
+	return p.power()
+}
+
+func (p *exprParser) power() (float64, error) {
+	base, err := p.primary()
+	if err != nil || p.peek() != '^' {
+		return base, err
+	}
+	p.next()
+	exp, err := p.unary() // Right-associative: 2^3^2 = 2^9
+	if err != nil {
+		return 0, err
+	}
+	return math.Pow(base, exp), nil
+}
+
+func (p *exprParser) primary() (float64, error) {
+	c := p.peek()
+	switch {
+	case c == '(':
+		p.next()
+		v, err := p.expression()
+		if err != nil {
+			return 0, err
+		}
+		if p.next() != ')' {
+			return 0, p.errorf("missing )")
+		}
+		return v, nil
+	case c == '.' || (c >= '0' && c <= '9'):
+		start := p.pos
+		for p.pos < len(p.input) && (isDigit(p.input[p.pos]) || p.input[p.pos] == '.' || p.input[p.pos] == '_') {
+			p.pos++
+		}
+		// Exponent, e.g. 1.5e3
+		if p.pos < len(p.input) && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+			end := p.pos + 1
+			if end < len(p.input) && (p.input[end] == '+' || p.input[end] == '-') {
+				end++
+			}
+			if end < len(p.input) && isDigit(p.input[end]) {
+				for end < len(p.input) && isDigit(p.input[end]) {
+					end++
+				}
+				p.pos = end
+			}
+		}
+		v, err := strconv.ParseFloat(strings.ReplaceAll(p.input[start:p.pos], "_", ""), 64)
+		if err != nil {
+			return 0, p.errorf("invalid number %q", p.input[start:p.pos])
+		}
+		return v, nil
+	case unicode.IsLetter(rune(c)):
+		start := p.pos
+		for p.pos < len(p.input) && (unicode.IsLetter(rune(p.input[p.pos])) || isDigit(p.input[p.pos])) {
+			p.pos++
+		}
+		name := strings.ToLower(p.input[start:p.pos])
+		if p.peek() != '(' {
+			switch name {
+			case "pi":
+				return math.Pi, nil
+			case "e":
+				return math.E, nil
+			}
+			return 0, p.errorf("unknown constant %q", name)
+		}
+		p.next()
+		var argv []float64
+		for {
+			v, err := p.expression()
+			if err != nil {
+				return 0, err
+			}
+			argv = append(argv, v)
+			if c := p.next(); c == ')' {
+				break
+			} else if c != ',' {
+				return 0, p.errorf("missing ) after arguments of %s", name)
+			}
+		}
+		return callFunction(p, name, argv)
+	case c == 0:
+		return 0, p.errorf("unexpected end of expression")
+	}
+	return 0, p.errorf("unexpected %q", string(c))
+}
+
+// callFunction applies a named function to its arguments
+func callFunction(p *exprParser, name string, argv []float64) (float64, error) {
+	unary := map[string]func(float64) float64{
+		"sqrt": math.Sqrt, "abs": math.Abs, "round": math.Round, "floor": math.Floor, "ceil": math.Ceil,
+		"ln": math.Log, "log": math.Log10, "log10": math.Log10, "exp": math.Exp,
+		"sin": math.Sin, "cos": math.Cos, "tan": math.Tan,
+	}
+	if f, ok := unary[name]; ok {
+		if len(argv) != 1 {
+			return 0, p.errorf("%s takes 1 argument", name)
+		}
+		return f(argv[0]), nil
+	}
+	switch name {
+	case "min", "max":
+		v := argv[0]
+		for _, a := range argv[1:] {
+			if name == "min" {
+				v = math.Min(v, a)
+			} else {
+				v = math.Max(v, a)
+			}
+		}
+		return v, nil
+	case "pow":
+		if len(argv) != 2 {
+			return 0, p.errorf("pow takes 2 arguments")
+		}
+		return math.Pow(argv[0], argv[1]), nil
+	}
+	return 0, p.errorf("unknown function %q", name)
+}
+
+// peek returns the next non-space byte without consuming it, or 0 at the end
+func (p *exprParser) peek() byte {
+	p.skipSpace()
+	if p.pos >= len(p.input) {
+		return 0
+	}
+	return p.input[p.pos]
+}
+
+// next consumes and returns the next non-space byte, or 0 at the end
+func (p *exprParser) next() byte {
+	c := p.peek()
+	if c != 0 {
+		p.pos++
+	}
+	return c
+}
+
+func (p *exprParser) skipSpace() {
+	for p.pos < len(p.input) && (p.input[p.pos] == ' ' || p.input[p.pos] == '\t' || p.input[p.pos] == '\n') {
+		p.pos++
+	}
+}
+
+// errorf returns a validation error pointing at the current position
+func (p *exprParser) errorf(format string, args ...interface{}) *errors.Error {
+	return errors.Newf(errors.ErrInvalidFormat, "invalid expression: "+format, args...).
+		WithContext("expression", p.input).
+		WithContext("position", p.pos)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// formatNumber formats a result without float noise such as 0.30000000000000004
+func formatNumber(v float64) string {
+	return strconv.FormatFloat(v, 'g', 15, 64)
+}
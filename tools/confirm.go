@@ -0,0 +1,153 @@
+package tools
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// SideEffecting is implemented by tools that change external state, such as
+// sending messages or writing files. Their calls need confirmation when the
+// context carries a Confirmer; read-only tools always run.
+type SideEffecting interface {
+	SideEffects() bool
+}
+
+// HasSideEffects reports whether the tool declares side effects
+func HasSideEffects(tool Tool) bool {
+	s, ok := tool.(SideEffecting)
+	return ok && s.SideEffects()
+}
+
+// sideEffectingTool marks a tool as side-effecting
+type sideEffectingTool struct {
+	Tool
+}
+
+// SideEffects reports true
+func (sideEffectingTool) SideEffects() bool { return true }
+
+// MarkSideEffecting returns the tool marked as side-effecting
+func MarkSideEffecting(tool Tool) Tool {
+	return sideEffectingTool{Tool: tool}
+}
+
+// Decision is the answer to a confirmation request
+type Decision int
+
+const (
+	// Approve executes the call
+	Approve Decision = iota
+	// Reject fails the call with ErrPolicyViolation
+	Reject
+	// DryRun skips the call and returns a description of it as its output
+	DryRun
+)
+
+// ConfirmRequest describes a side-effecting tool call awaiting confirmation
+type ConfirmRequest struct {
+	RunID       string
+	Tool        string
+	Description string
+	Args        map[string]interface{}
+}
+
+// Confirmer decides whether a side-effecting tool call may execute
+type Confirmer func(ctx context.Context, req ConfirmRequest) (Decision, error)
+
+// DryRunAll is a Confirmer that executes no side-effecting call, so a run
+// can be rehearsed while read-only tools still work
+func DryRunAll(ctx context.Context, req ConfirmRequest) (Decision, error) {
+	return DryRun, nil
+}
+
+// confirmKey is the context key for the Confirmer
+type confirmKey struct{}
+
+// NewConfirmContext returns a context in which side-effecting tool calls
+// executed through a registry need confirmation by c
+func NewConfirmContext(ctx context.Context, c Confirmer) context.Context {
+	return context.WithValue(ctx, confirmKey{}, c)
+}
+
+// ConfirmerFromContext returns the Confirmer carried by ctx, or nil
+func ConfirmerFromContext(ctx context.Context) Confirmer {
+	c, _ := ctx.Value(confirmKey{}).(Confirmer)
+	return c
+}
+
+// confirm asks the context's Confirmer about a side-effecting call. It
+// returns the output of a dry run, or an error if the call must not run.
+func confirm(ctx context.Context, tool Tool, args map[string]interface{}) (dryRun string, err error) {
+	c := ConfirmerFromContext(ctx)
+	if c == nil || !HasSideEffects(tool) {
+		return "", nil
+	}
+	req := ConfirmRequest{RunID: runid.FromContext(ctx), Tool: tool.Name(), Description: tool.Description(), Args: args}
+	decision, err := c(ctx, req)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrPolicyViolation, "tool call was not confirmed", err).WithContext("tool", req.Tool)
+	}
+	switch decision {
+	case Approve:
+		return "", nil
+	case DryRun:
+		return fmt.Sprintf("[dry run] %s was not executed. Arguments: %s", req.Tool, formatArgs(args, "")), nil
+	default:
+		return "", errors.Newf(errors.ErrPolicyViolation, "call to %s was rejected", req.Tool).WithContext("tool", req.Tool)
+	}
+}
+
+// PromptConfirm returns a Confirmer that shows each side-effecting call with
+// its exact arguments on out and reads the answer from in: y to execute, n
+// to reject, d for a dry run
+func PromptConfirm(in io.Reader, out io.Writer) Confirmer {
+	scanner := bufio.NewScanner(in)
+	var mu sync.Mutex // Parallel tasks ask one at a time
+	return func(ctx context.Context, req ConfirmRequest) (Decision, error) {
+		mu.Lock()
+		defer mu.Unlock()
+		fmt.Fprintf(out, "\nTool %s wants to run with arguments:\n%s\n", req.Tool, formatArgs(req.Args, "  "))
+		for {
+			fmt.Fprint(out, "Execute? [y]es, [n]o, [d]ry run: ")
+			if !scanner.Scan() {
+				if err := scanner.Err(); err != nil {
+					return Reject, err
+				}
+				return Reject, nil
+			}
+			if err := ctx.Err(); err != nil {
+				return Reject, err
+			}
+			switch strings.ToLower(strings.TrimSpace(scanner.Text())) {
+			case "y", "yes":
+				return Approve, nil
+			case "n", "no":
+				return Reject, nil
+			case "d", "dry", "dry run":
+				return DryRun, nil
+			}
+		}
+	}
+}
+
+// formatArgs formats arguments as JSON, indented when indent is set
+func formatArgs(args map[string]interface{}, indent string) string {
+	if args == nil {
+		args = map[string]interface{}{}
+	}
+	var data []byte
+	if indent != "" {
+		data, _ = json.MarshalIndent(args, indent, "  ")
+		return indent + string(data)
+	}
+	data, _ = json.Marshal(args)
+	return string(data)
+}
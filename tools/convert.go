@@ -0,0 +1,228 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// ConvertToolName is the name of the unit and currency conversion tool
+const ConvertToolName = "convert"
+
+// RateProvider supplies currency exchange rates
+type RateProvider interface {
+	// Rate returns how many units of to one unit of from buys, for ISO 4217
+	// currency codes such as "EUR"
+	Rate(ctx context.Context, from, to string) (float64, error)
+}
+
+// StaticRates is a RateProvider with fixed rates relative to a base
+// currency, e.g. Base "USD" and Rates {"EUR": 0.92} for 1 USD = 0.92 EUR
+type StaticRates struct {
+	Base  string
+	Rates map[string]float64
+}
+
+// Rate converts through the base currency
+func (s StaticRates) Rate(ctx context.Context, from, to string) (float64, error) {
+	perBase := func(code string) (float64, error) {
+		if strings.EqualFold(code, s.Base) {
+			return 1, nil
+		}
+		for c, r := range s.Rates {
+			if strings.EqualFold(c, code) && r > 0 {
+				return r, nil
+			}
+		}
+		return 0, errors.NotFound("exchange rate", strings.ToUpper(code))
+	}
+	fromRate, err := perBase(from)
+	if err != nil {
+		return 0, err
+	}
+	toRate, err := perBase(to)
+	if err != nil {
+		return 0, err
+	}
+	return toRate / fromRate, nil
+}
+
+// unit is a unit of measure with its factor to the dimension's base unit
+type unit struct {
+	dimension string
+	factor    float64
+}
+
+// units maps unit names and symbols to their dimension and factor.
+// Temperatures are converted separately since their scales are offset.
+var units = map[string]unit{
+	// Length, in meters
+	"m": {"length", 1}, "meter": {"length", 1}, "km": {"length", 1000}, "cm": {"length", 0.01}, "mm": {"length", 0.001},
+	"mi": {"length", 1609.344}, "mile": {"length", 1609.344}, "yd": {"length", 0.9144}, "yard": {"length", 0.9144},
+	"ft": {"length", 0.3048}, "foot": {"length", 0.3048}, "feet": {"length", 0.3048}, "in": {"length", 0.0254}, "inch": {"length", 0.0254},
+	"nmi": {"length", 1852},
+	// Mass, in kilograms
+	"kg": {"mass", 1}, "g": {"mass", 0.001}, "mg": {"mass", 1e-6}, "t": {"mass", 1000}, "tonne": {"mass", 1000},
+	"lb": {"mass", 0.45359237}, "pound": {"mass", 0.45359237}, "oz": {"mass", 0.028349523125}, "ounce": {"mass", 0.028349523125},
+	"st": {"mass", 6.35029318},
+	// Volume, in liters (US customary units)
+	"l": {"volume", 1}, "liter": {"volume", 1}, "litre": {"volume", 1}, "ml": {"volume", 0.001}, "m3": {"volume", 1000},
+	"gal": {"volume", 3.785411784}, "gallon": {"volume", 3.785411784}, "qt": {"volume", 0.946352946}, "pt": {"volume", 0.473176473},
+	"cup": {"volume", 0.2365882365}, "floz": {"volume", 0.0295735295625},
+	// Area, in square meters
+	"m2": {"area", 1}, "km2": {"area", 1e6}, "ha": {"area", 1e4}, "acre": {"area", 4046.8564224}, "ft2": {"area", 0.09290304},
+	"mi2": {"area", 2589988.110336},
+	// Time, in seconds
+	"s": {"time", 1}, "sec": {"time", 1}, "ms": {"time", 0.001}, "min": {"time", 60}, "h": {"time", 3600}, "hour": {"time", 3600},
+	"d": {"time", 86400}, "day": {"time", 86400}, "wk": {"time", 604800}, "week": {"time", 604800},
+	// Speed, in meters per second
+	"m/s": {"speed", 1}, "km/h": {"speed", 1 / 3.6}, "kph": {"speed", 1 / 3.6}, "mph": {"speed", 0.44704}, "kn": {"speed", 0.514444},
+	// Data, in bytes
+	"b": {"data", 1}, "byte": {"data", 1}, "kb": {"data", 1e3}, "mb": {"data", 1e6}, "gb": {"data", 1e9}, "tb": {"data", 1e12},
+	"kib": {"data", 1 << 10}, "mib": {"data", 1 << 20}, "gib": {"data", 1 << 30}, "tib": {"data", 1 << 40},
+	// Energy, in joules
+	"j": {"energy", 1}, "kj": {"energy", 1000}, "cal": {"energy", 4.184}, "kcal": {"energy", 4184}, "kwh": {"energy", 3.6e6},
+}
+
+// ConvertTool converts amounts between units of measure, temperature scales
+// and currencies
+type ConvertTool struct {
+	*BaseTool
+	rates RateProvider
+}
+
+// NewConvertTool creates the conversion tool. Currency conversion needs
+// rates; without them only units convert.
+func NewConvertTool(rates RateProvider) *ConvertTool {
+	return &ConvertTool{
+		BaseTool: NewBaseTool(ConvertToolName,
+			"Converts an amount between units (length, mass, volume, area, time, speed, data, energy), temperatures (C, F, K) and currencies (ISO codes such as USD).",
+			map[string]interface{}{
+				"value": 1,
+				"from":  "unit or currency to convert from, e.g. \"mi\", \"F\" or \"USD\"",
+				"to":    "unit or currency to convert to, e.g. \"km\", \"C\" or \"EUR\"",
+			}),
+		rates: rates,
+	}
+}
+
+// Execute converts the value and returns "<value> <from> = <result> <to>"
+func (t *ConvertTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	from, to := stringArg(args, "from"), stringArg(args, "to")
+	if from == "" {
+		return "", errors.RequiredField("from")
+	}
+	if to == "" {
+		return "", errors.RequiredField("to")
+	}
+	value := 1.0
+	if raw, ok := args["value"]; ok {
+		v, err := numberArg(raw)
+		if err != nil {
+			return "", errors.InvalidField("value", "must be a number").WithContext("value", raw)
+		}
+		value = v
+	}
+
+	result, err := t.Convert(ctx, value, from, to)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s %s = %s %s", formatNumber(value), from, formatNumber(result), to), nil
+}
+
+// Convert converts value from one unit, temperature scale or currency to
+// another
+func (t *ConvertTool) Convert(ctx context.Context, value float64, from, to string) (float64, error) {
+	if c, ok := celsius(from); ok {
+		if _, ok := celsius(to); !ok {
+			return 0, incompatible(from, to)
+		}
+		return fromCelsius(c(value), to), nil
+	}
+
+	fromUnit, fromOK := units[strings.ToLower(from)]
+	toUnit, toOK := units[strings.ToLower(to)]
+	switch {
+	case fromOK && toOK:
+		if fromUnit.dimension != toUnit.dimension {
+			return 0, incompatible(from, to)
+		}
+		return value * fromUnit.factor / toUnit.factor, nil
+	case isCurrency(from) && isCurrency(to):
+		if t.rates == nil {
+			return 0, errors.MissingConfig("exchange rates").WithContext("tool", ConvertToolName)
+		}
+		rate, err := t.rates.Rate(ctx, strings.ToUpper(from), strings.ToUpper(to))
+		if err != nil {
+			return 0, err
+		}
+		return value * rate, nil
+	case !fromOK && !isCurrency(from):
+		return 0, errors.InvalidField("from", "unknown unit").WithContext("value", from)
+	case !toOK && !isCurrency(to):
+		return 0, errors.InvalidField("to", "unknown unit").WithContext("value", to)
+	}
+	return 0, incompatible(from, to)
+}
+
+// celsius returns the conversion of a temperature scale to Celsius
+func celsius(scale string) (func(float64) float64, bool) {
+	switch strings.ToLower(strings.TrimPrefix(scale, "°")) {
+	case "c", "celsius":
+		return func(v float64) float64 { return v }, true
+	case "f", "fahrenheit":
+		return func(v float64) float64 { return (v - 32) * 5 / 9 }, true
+	case "k", "kelvin":
+		return func(v float64) float64 { return v - 273.15 }, true
+	}
+	return nil, false
+}
+
+// fromCelsius converts a Celsius temperature to another scale
+func fromCelsius(c float64, scale string) float64 {
+	switch strings.ToLower(strings.TrimPrefix(scale, "°")) {
+	case "f", "fahrenheit":
+		return c*9/5 + 32
+	case "k", "kelvin":
+		return c + 273.15
+	}
+	return c
+}
+
+// isCurrency reports whether s looks like an ISO 4217 currency code
+func isCurrency(s string) bool {
+	if len(s) != 3 {
+		return false
+	}
+	for _, r := range s {
+		if (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') {
+			return false
+		}
+	}
+	return true
+}
+
+// incompatible returns an error for units of different dimensions
+func incompatible(from, to string) *errors.Error {
+	return errors.Newf(errors.ErrInvalidField, "cannot convert %s to %s", from, to).
+		WithContext("from", from).
+		WithContext("to", to)
+}
+
+// numberArg converts a numeric argument decoded from JSON or given as text
+func numberArg(v interface{}) (float64, error) {
+	switch n := v.(type) {
+	case float64:
+		return n, nil
+	case int:
+		return float64(n), nil
+	case string:
+		return strconv.ParseFloat(strings.TrimSpace(n), 64)
+	default:
+		return 0, errors.InvalidField("value", "must be a number")
+	}
+}
@@ -0,0 +1,74 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	_ "time/tzdata" // Time zones work on hosts without zoneinfo files
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// DateTimeToolName is the name of the date and time tool
+const DateTimeToolName = "datetime"
+
+// DateTimeTool tells the current date and time in a time zone, which models
+// cannot know
+type DateTimeTool struct {
+	*BaseTool
+	now func() time.Time
+}
+
+// NewDateTimeTool creates the date and time tool. now defaults to time.Now.
+func NewDateTimeTool(now func() time.Time) *DateTimeTool {
+	if now == nil {
+		now = time.Now
+	}
+	return &DateTimeTool{
+		BaseTool: NewBaseTool(DateTimeToolName,
+			"Returns the current date, time and weekday in a time zone.",
+			map[string]interface{}{
+				"timezone": "IANA time zone, e.g. \"Europe/Berlin\" (default UTC)",
+			}),
+		now: now,
+	}
+}
+
+// Execute returns the current time in the requested zone
+func (t *DateTimeTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	name := stringArg(args, "timezone")
+	if name == "" {
+		name = "UTC"
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return "", errors.InvalidField("timezone", "unknown time zone, use an IANA name such as \"America/New_York\"").WithContext("value", name)
+	}
+	now := t.now().In(loc)
+	_, offset := now.Zone()
+	return fmt.Sprintf("%s (%s, %s, UTC%s)", now.Format(time.RFC3339), now.Weekday(), loc, formatOffset(offset)), nil
+}
+
+// formatOffset formats a zone offset in seconds as +hh:mm
+func formatOffset(seconds int) string {
+	sign := "+"
+	if seconds < 0 {
+		sign, seconds = "-", -seconds
+	}
+	return fmt.Sprintf("%s%02d:%02d", sign, seconds/3600, seconds%3600/60)
+}
+
+// stringArg returns an argument as a trimmed string
+func stringArg(args map[string]interface{}, name string) string {
+	switch v := args[name].(type) {
+	case string:
+		return strings.TrimSpace(v)
+	case fmt.Stringer:
+		return v.String()
+	case nil:
+		return ""
+	default:
+		return fmt.Sprint(v)
+	}
+}
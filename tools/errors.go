@@ -0,0 +1,46 @@
+package tools
+
+import (
+	"encoding/json"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// maxErrorMessage bounds the error message given to a model, so a tool
+// dumping a stack trace or response body does not flood the prompt
+const maxErrorMessage = 500
+
+// ErrorDescription is the structured form of an error given to a model
+type ErrorDescription struct {
+	Code      string `json:"code"`
+	Message   string `json:"message"`
+	Hint      string `json:"hint,omitempty"`
+	Retryable bool   `json:"retryable"`
+}
+
+// DescribeError formats err as a bounded JSON object with its code, message
+// and a hint on how to proceed, instead of the raw Go error string with its
+// context and wrapped causes
+func DescribeError(err error) string {
+	desc := ErrorDescription{Code: errors.ErrToolFailed.String(), Message: err.Error()}
+	if e, ok := err.(*errors.Error); ok {
+		desc.Code = e.Code.String()
+		desc.Message = e.Message
+		desc.Retryable = e.Retryable
+		desc.Hint, _ = e.Context["hint"].(string)
+	}
+	desc.Message = truncate(desc.Message, maxErrorMessage)
+
+	data, _ := json.Marshal(struct {
+		Error ErrorDescription `json:"error"`
+	}{desc})
+	return string(data)
+}
+
+// truncate shortens s to at most n runes, marking the cut with "..."
+func truncate(s string, n int) string {
+	if runes := []rune(s); len(runes) > n {
+		return string(runes[:n]) + "..."
+	}
+	return s
+}
@@ -0,0 +1,255 @@
+package tools
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// FeedToolName is the name of the RSS and Atom feed tool
+const FeedToolName = "feed"
+
+// maxFeedSummary bounds the summary of a feed item in tool output
+const maxFeedSummary = 300
+
+// FeedItem is an entry of an RSS or Atom feed
+type FeedItem struct {
+	Title     string
+	Link      string
+	Published time.Time // Zero when the feed gives no date
+	Summary   string    // Plain text
+}
+
+// feedDocument decodes RSS 2.0, RSS 1.0 (RDF) and Atom feeds
+type feedDocument struct {
+	XMLName xml.Name
+	Title   string `xml:"title"`
+	Channel struct {
+		Title string    `xml:"title"`
+		Items []rssItem `xml:"item"`
+	} `xml:"channel"`
+	Items   []rssItem `xml:"item"` // RSS 1.0 items are outside the channel
+	Entries []struct {
+		Title string `xml:"title"`
+		Links []struct {
+			Href string `xml:"href,attr"`
+			Rel  string `xml:"rel,attr"`
+		} `xml:"link"`
+		Published string `xml:"published"`
+		Updated   string `xml:"updated"`
+		Summary   string `xml:"summary"`
+		Content   string `xml:"content"`
+	} `xml:"entry"`
+}
+
+type rssItem struct {
+	Title       string `xml:"title"`
+	Link        string `xml:"link"`
+	PubDate     string `xml:"pubDate"`
+	Date        string `xml:"http://purl.org/dc/elements/1.1/ date"`
+	Description string `xml:"description"`
+}
+
+// ParseFeed parses an RSS or Atom feed and returns its title and items
+func ParseFeed(data []byte) (string, []FeedItem, error) {
+	var doc feedDocument
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	decoder.Strict = false
+	decoder.Entity = xml.HTMLEntity
+	if err := decoder.Decode(&doc); err != nil {
+		return "", nil, errors.Wrap(errors.ErrInvalidFormat, "invalid feed", err)
+	}
+
+	var items []FeedItem
+	switch strings.ToLower(doc.XMLName.Local) {
+	case "rss", "rdf":
+		title := doc.Channel.Title
+		for _, it := range append(doc.Channel.Items, doc.Items...) {
+			date := it.PubDate
+			if date == "" {
+				date = it.Date
+			}
+			items = append(items, FeedItem{
+				Title:     strings.TrimSpace(it.Title),
+				Link:      strings.TrimSpace(it.Link),
+				Published: parseFeedDate(date),
+				Summary:   plainText(it.Description),
+			})
+		}
+		return strings.TrimSpace(title), items, nil
+	case "feed":
+		for _, e := range doc.Entries {
+			item := FeedItem{Title: strings.TrimSpace(e.Title), Summary: plainText(e.Summary)}
+			if item.Summary == "" {
+				item.Summary = plainText(e.Content)
+			}
+			for _, l := range e.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					item.Link = l.Href
+					break
+				}
+			}
+			item.Published = parseFeedDate(e.Published)
+			if item.Published.IsZero() {
+				item.Published = parseFeedDate(e.Updated)
+			}
+			items = append(items, item)
+		}
+		return strings.TrimSpace(doc.Title), items, nil
+	}
+	return "", nil, errors.New(errors.ErrInvalidFormat, "not an RSS or Atom feed").WithContext("root", doc.XMLName.Local)
+}
+
+// feedDateLayouts are the date formats found in feeds
+var feedDateLayouts = []string{
+	time.RFC1123Z, time.RFC1123, time.RFC3339, time.RFC822Z, time.RFC822,
+	"Mon, 2 Jan 2006 15:04:05 -0700", "Mon, 2 Jan 2006 15:04:05 MST", "2 Jan 2006 15:04:05 -0700",
+	"2006-01-02T15:04:05", "2006-01-02",
+}
+
+// parseFeedDate parses a feed date, returning the zero time when it fails
+func parseFeedDate(s string) time.Time {
+	s = strings.TrimSpace(s)
+	for _, layout := range feedDateLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// htmlTag matches HTML tags
+var htmlTag = regexp.MustCompile(`(?s)<[^>]*>`)
+
+// plainText strips HTML markup and collapses whitespace
+func plainText(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(htmlTag.ReplaceAllString(s, " "))), " ")
+}
+
+// FeedTool fetches RSS and Atom feeds, for monitoring news, blogs and
+// release notes
+type FeedTool struct {
+	*BaseTool
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewFeedTool creates the feed tool
+func NewFeedTool() *FeedTool {
+	return &FeedTool{
+		BaseTool: NewBaseTool(FeedToolName,
+			"Fetches the items of an RSS or Atom feed, newest first, optionally between two dates.",
+			map[string]interface{}{
+				"url":   "feed URL",
+				"since": "only items published after this date (2006-01-02 or RFC 3339) or this long ago (e.g. \"24h\", \"7d\")",
+				"until": "only items published before this date",
+				"limit": DefaultIssueLimit,
+			}),
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+}
+
+// Execute fetches the feed and lists its matching items
+func (t *FeedTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	url := stringArg(args, "url")
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		return "", errors.InvalidField("url", "must be an http or https URL").WithContext("value", url)
+	}
+	since, err := t.timeArg(args, "since")
+	if err != nil {
+		return "", err
+	}
+	until, err := t.timeArg(args, "until")
+	if err != nil {
+		return "", err
+	}
+	limit, err := limitArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	req.Header.Set("Accept", "application/rss+xml, application/atom+xml, application/xml;q=0.9, */*;q=0.8")
+	data, err := send(t.client, req, "feed")
+	if err != nil {
+		return "", err
+	}
+	title, items, err := ParseFeed(data)
+	if err != nil {
+		return "", err
+	}
+
+	// Undated items are kept only without a date filter
+	var matching []FeedItem
+	for _, item := range items {
+		if (!since.IsZero() || !until.IsZero()) && item.Published.IsZero() {
+			continue
+		}
+		if (!since.IsZero() && item.Published.Before(since)) || (!until.IsZero() && item.Published.After(until)) {
+			continue
+		}
+		matching = append(matching, item)
+	}
+	sort.SliceStable(matching, func(i, j int) bool { return matching[i].Published.After(matching[j].Published) })
+
+	if title == "" {
+		title = url
+	}
+	if len(matching) == 0 {
+		return fmt.Sprintf("%s: no matching items", title), nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s: %d items", title, len(matching))
+	if len(matching) > limit {
+		fmt.Fprintf(&b, " (showing %d)", limit)
+		matching = matching[:limit]
+	}
+	b.WriteString("\n")
+	for _, item := range matching {
+		b.WriteString("\n- " + item.Title)
+		if !item.Published.IsZero() {
+			b.WriteString(" (" + item.Published.UTC().Format("2006-01-02 15:04") + " UTC)")
+		}
+		if item.Link != "" {
+			b.WriteString("\n  " + item.Link)
+		}
+		if item.Summary != "" {
+			b.WriteString("\n  " + truncate(item.Summary, maxFeedSummary))
+		}
+	}
+	return b.String(), nil
+}
+
+// timeArg parses a date argument, either absolute or a duration before now
+// such as "36h" or "7d"
+func (t *FeedTool) timeArg(args map[string]interface{}, name string) (time.Time, error) {
+	s := stringArg(args, name)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return t.now().AddDate(0, 0, -n), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil && d >= 0 {
+		return t.now().Add(-d), nil
+	}
+	if date := parseFeedDate(s); !date.IsZero() {
+		return date, nil
+	}
+	return time.Time{}, errors.InvalidField(name, "must be a date such as 2024-05-01 or a duration such as 7d").WithContext("value", s)
+}
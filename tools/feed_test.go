@@ -0,0 +1,77 @@
+package tools
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+const rssFeed = `<?xml version="1.0"?>
+<rss version="2.0"><channel><title>Go Blog</title>
+<item><title>Go 1.22</title><link>https://go.dev/blog/go1.22</link><pubDate>Tue, 06 Feb 2024 10:00:00 +0000</pubDate>
+<description>&lt;p&gt;Range over &lt;b&gt;integers&lt;/b&gt;&amp;nbsp;and more&lt;/p&gt;</description></item>
+<item><title>Go 1.21</title><link>https://go.dev/blog/go1.21</link><pubDate>Tue, 08 Aug 2023 10:00:00 +0000</pubDate></item>
+<item><title>Undated</title></item>
+</channel></rss>`
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom"><title>Releases</title>
+<entry><title>v2.0.0</title><link rel="alternate" href="https://example.com/v2"/><updated>2024-02-01T00:00:00Z</updated><content type="html">Breaking &lt;i&gt;changes&lt;/i&gt;</content></entry>
+<entry><title>v2.1.0</title><link href="https://example.com/v2.1"/><published>2024-02-03T12:00:00Z</published><summary>Fixes</summary></entry>
+</feed>`
+
+func TestFeedTool(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/rss":
+			_, _ = io.WriteString(w, rssFeed)
+		case "/atom":
+			_, _ = io.WriteString(w, atomFeed)
+		default:
+			_, _ = io.WriteString(w, "<html><body>Not a feed</body></html>")
+		}
+	}))
+	defer server.Close()
+
+	tool := NewFeedTool()
+	tool.now = func() time.Time { return time.Date(2024, 2, 10, 0, 0, 0, 0, time.UTC) }
+	ctx := context.Background()
+
+	got, err := tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/rss", "since": "30d"})
+	want := "Go Blog: 1 items\n\n- Go 1.22 (2024-02-06 10:00 UTC)\n  https://go.dev/blog/go1.22\n  Range over integers and more"
+	if err != nil || got != want {
+		t.Errorf("rss since 30d = %q, %v, want %q", got, err, want)
+	}
+	got, err = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/rss"})
+	if err != nil || !strings.Contains(got, "3 items") || !strings.HasSuffix(got, "- Undated") {
+		t.Errorf("rss without filter = %q, %v, want every item, undated last", got, err)
+	}
+
+	got, err = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/atom", "until": "2024-02-02", "limit": 5})
+	want = "Releases: 1 items\n\n- v2.0.0 (2024-02-01 00:00 UTC)\n  https://example.com/v2\n  Breaking changes"
+	if err != nil || got != want {
+		t.Errorf("atom until = %q, %v, want %q", got, err, want)
+	}
+	got, _ = tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/atom", "limit": 1})
+	if !strings.HasPrefix(got, "Releases: 2 items (showing 1)\n\n- v2.1.0") {
+		t.Errorf("atom with limit = %q, want the newest item", got)
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"url": "file:///etc/passwd"},
+		{"url": server.URL + "/rss", "since": "last week"},
+	} {
+		if _, err := tool.Execute(ctx, args); !errors.HasCode(err, errors.ErrInvalidField) {
+			t.Errorf("Execute(%v) error = %v, want ErrInvalidField", args, err)
+		}
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"url": server.URL + "/page"}); !errors.HasCode(err, errors.ErrInvalidFormat) {
+		t.Errorf("Execute() on a page error = %v, want ErrInvalidFormat", err)
+	}
+}
@@ -0,0 +1,313 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// DefaultGitHubURL is the GitHub REST API endpoint
+const DefaultGitHubURL = "https://api.github.com"
+
+// Names of the GitHub tools
+const (
+	GitHubSearchToolName  = "github_search_issues"
+	GitHubGetToolName     = "github_get_issue"
+	GitHubCreateToolName  = "github_create_issue"
+	GitHubCommentToolName = "github_comment_issue"
+)
+
+// GitHubConfig configures the GitHub issue tools
+type GitHubConfig struct {
+	// Token is a personal access or app installation token
+	Token string
+	// BaseURL overrides the API base, e.g. for GitHub Enterprise
+	// (default: DefaultGitHubURL)
+	BaseURL string
+	// Repos are the "owner/repo" repositories the tools may access
+	// (empty = any the token can). The first is the default repository.
+	Repos []string
+	// DryRun describes issue creations and comments instead of sending them
+	DryRun bool
+}
+
+// github implements the GitHub tools
+type github struct {
+	config GitHubConfig
+	http   *issueClient
+}
+
+// NewGitHubTools creates tools to search, read, create and comment on GitHub
+// issues and pull requests. Creating and commenting are side-effecting, so
+// they need confirmation when the run has a Confirmer.
+func NewGitHubTools(cfg GitHubConfig) ([]Tool, error) {
+	if cfg.Token == "" {
+		return nil, errors.RequiredField("GitHub token")
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultGitHubURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	for _, repo := range cfg.Repos {
+		if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" || strings.Contains(name, "/") {
+			return nil, errors.InvalidField("repos", "must be owner/repo").WithContext("value", repo)
+		}
+	}
+
+	g := &github{config: cfg}
+	g.http = newIssueClient("GitHub", cfg.DryRun, func(req *http.Request) {
+		req.Header.Set("Authorization", "Bearer "+cfg.Token)
+		req.Header.Set("Accept", "application/vnd.github+json")
+		req.Header.Set("X-GitHub-Api-Version", "2022-11-28")
+	})
+	repoArg := "repository as owner/repo" + g.defaultNote()
+	return []Tool{
+		actionTool{BaseTool: NewBaseTool(GitHubSearchToolName,
+			"Searches GitHub issues and pull requests with GitHub search syntax, e.g. \"label:bug crash\".",
+			map[string]interface{}{
+				"query": "search terms and qualifiers",
+				"type":  "\"issue\", \"pr\" or empty for both",
+				"state": "\"open\", \"closed\" or empty for both",
+				"limit": DefaultIssueLimit,
+			}), run: g.search},
+		actionTool{BaseTool: NewBaseTool(GitHubGetToolName,
+			"Returns a GitHub issue or pull request with its description.",
+			map[string]interface{}{"repo": repoArg, "number": 1}), run: g.get},
+		actionTool{BaseTool: NewBaseTool(GitHubCreateToolName,
+			"Creates a GitHub issue.",
+			map[string]interface{}{
+				"repo":   repoArg,
+				"title":  "issue title",
+				"body":   "issue description in Markdown",
+				"labels": []string{"bug"},
+			}), run: g.create, sideEffects: true},
+		actionTool{BaseTool: NewBaseTool(GitHubCommentToolName,
+			"Comments on a GitHub issue or pull request.",
+			map[string]interface{}{"repo": repoArg, "number": 1, "body": "comment in Markdown"}), run: g.comment, sideEffects: true},
+	}, nil
+}
+
+// defaultNote describes the default repository in argument descriptions
+func (g *github) defaultNote() string {
+	if len(g.config.Repos) == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (default %s)", g.config.Repos[0])
+}
+
+// repo returns the repository argument, checked against the configured ones
+func (g *github) repo(args map[string]interface{}) (string, error) {
+	repo := stringArg(args, "repo")
+	switch {
+	case repo == "" && len(g.config.Repos) > 0:
+		return g.config.Repos[0], nil
+	case repo == "":
+		return "", errors.RequiredField("repo")
+	case len(g.config.Repos) > 0 && !containsFold(g.config.Repos, repo):
+		return "", errors.Unauthorized("repository is outside the configured repositories").
+			WithContext("repo", repo).
+			WithContext("allowed", strings.Join(g.config.Repos, ", "))
+	}
+	if owner, name, ok := strings.Cut(repo, "/"); !ok || owner == "" || name == "" {
+		return "", errors.InvalidField("repo", "must be owner/repo").WithContext("value", repo)
+	}
+	return repo, nil
+}
+
+// number returns the issue number argument
+func number(args map[string]interface{}) (int, error) {
+	raw, ok := args["number"]
+	if !ok {
+		return 0, errors.RequiredField("number")
+	}
+	n, err := numberArg(strings.TrimPrefix(fmt.Sprint(raw), "#"))
+	if err != nil || n < 1 || n != float64(int(n)) {
+		return 0, errors.InvalidField("number", "must be an issue number").WithContext("value", raw)
+	}
+	return int(n), nil
+}
+
+// gitHubIssue is an issue or pull request of the GitHub API
+type gitHubIssue struct {
+	Number        int    `json:"number"`
+	Title         string `json:"title"`
+	State         string `json:"state"`
+	HTMLURL       string `json:"html_url"`
+	Body          string `json:"body"`
+	Comments      int    `json:"comments"`
+	RepositoryURL string `json:"repository_url"`
+	PullRequest   *struct {
+		URL string `json:"url"`
+	} `json:"pull_request"`
+	User struct {
+		Login string `json:"login"`
+	} `json:"user"`
+	Labels []struct {
+		Name string `json:"name"`
+	} `json:"labels"`
+}
+
+// summary formats the issue on one line
+func (i gitHubIssue) summary() string {
+	kind := "issue"
+	if i.PullRequest != nil {
+		kind = "PR"
+	}
+	var labels []string
+	for _, l := range i.Labels {
+		labels = append(labels, l.Name)
+	}
+	line := fmt.Sprintf("#%d [%s, %s] %s (by %s) %s", i.Number, kind, i.State, i.Title, i.User.Login, i.HTMLURL)
+	if len(labels) > 0 {
+		line += " labels: " + strings.Join(labels, ", ")
+	}
+	return line
+}
+
+// search runs an issue search, scoped to the configured repositories
+func (g *github) search(ctx context.Context, args map[string]interface{}) (string, error) {
+	query := stringArg(args, "query")
+	limit, err := limitArg(args)
+	if err != nil {
+		return "", err
+	}
+
+	terms := []string{query}
+	if len(g.config.Repos) > 0 {
+		for _, field := range strings.Fields(query) {
+			qualifier, value, _ := strings.Cut(strings.TrimPrefix(field, "-"), ":")
+			switch strings.ToLower(qualifier) {
+			case "repo":
+				if !containsFold(g.config.Repos, value) {
+					return "", errors.Unauthorized("repository is outside the configured repositories").WithContext("repo", value)
+				}
+			case "org", "user":
+				return "", errors.Unauthorized("searches are limited to the configured repositories").WithContext("qualifier", field)
+			}
+		}
+		for _, repo := range g.config.Repos {
+			terms = append(terms, "repo:"+repo)
+		}
+	}
+	switch kind := stringArg(args, "type"); strings.ToLower(kind) {
+	case "":
+	case "issue", "issues":
+		terms = append(terms, "is:issue")
+	case "pr", "prs", "pull_request":
+		terms = append(terms, "is:pr")
+	default:
+		return "", errors.InvalidField("type", "must be issue or pr").WithContext("value", kind)
+	}
+	switch state := stringArg(args, "state"); strings.ToLower(state) {
+	case "", "all":
+	case "open", "closed":
+		terms = append(terms, "state:"+strings.ToLower(state))
+	default:
+		return "", errors.InvalidField("state", "must be open or closed").WithContext("value", state)
+	}
+	q := strings.TrimSpace(strings.Join(terms, " "))
+	if q == "" {
+		return "", errors.RequiredField("query")
+	}
+
+	var result struct {
+		TotalCount int           `json:"total_count"`
+		Items      []gitHubIssue `json:"items"`
+	}
+	endpoint := fmt.Sprintf("%s/search/issues?q=%s&per_page=%d", g.config.BaseURL, url.QueryEscape(q), limit)
+	if _, err := g.http.do(ctx, http.MethodGet, endpoint, nil, &result); err != nil {
+		return "", err
+	}
+	if len(result.Items) == 0 {
+		return "No issues found for: " + q, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d results for: %s\n", len(result.Items), result.TotalCount, q)
+	for _, issue := range result.Items {
+		b.WriteString(issue.summary())
+		if len(g.config.Repos) != 1 {
+			b.WriteString(" in " + repoFromURL(issue.RepositoryURL))
+		}
+		b.WriteString("\n")
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// repoFromURL returns the owner/repo of a repository API URL
+func repoFromURL(u string) string {
+	parts := strings.Split(strings.TrimSuffix(u, "/"), "/")
+	if len(parts) < 2 {
+		return u
+	}
+	return parts[len(parts)-2] + "/" + parts[len(parts)-1]
+}
+
+// get returns an issue with its description
+func (g *github) get(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := g.repo(args)
+	if err != nil {
+		return "", err
+	}
+	n, err := number(args)
+	if err != nil {
+		return "", err
+	}
+	var issue gitHubIssue
+	if _, err := g.http.do(ctx, http.MethodGet, fmt.Sprintf("%s/repos/%s/issues/%d", g.config.BaseURL, repo, n), nil, &issue); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n%d comments\n\n%s", issue.summary(), issue.Comments, truncate(issue.Body, maxIssueBody)), nil
+}
+
+// create opens an issue
+func (g *github) create(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := g.repo(args)
+	if err != nil {
+		return "", err
+	}
+	title := stringArg(args, "title")
+	if title == "" {
+		return "", errors.RequiredField("title")
+	}
+	body := map[string]interface{}{"title": title, "body": stringArg(args, "body")}
+	if labels := listArg(args, "labels"); len(labels) > 0 {
+		body["labels"] = labels
+	}
+
+	var issue gitHubIssue
+	dryRun, err := g.http.do(ctx, http.MethodPost, fmt.Sprintf("%s/repos/%s/issues", g.config.BaseURL, repo), body, &issue)
+	if err != nil || dryRun != "" {
+		return dryRun, err
+	}
+	return fmt.Sprintf("Created issue #%d in %s: %s", issue.Number, repo, issue.HTMLURL), nil
+}
+
+// comment adds a comment to an issue or pull request
+func (g *github) comment(ctx context.Context, args map[string]interface{}) (string, error) {
+	repo, err := g.repo(args)
+	if err != nil {
+		return "", err
+	}
+	n, err := number(args)
+	if err != nil {
+		return "", err
+	}
+	text := stringArg(args, "body")
+	if text == "" {
+		return "", errors.RequiredField("body")
+	}
+
+	var comment struct {
+		HTMLURL string `json:"html_url"`
+	}
+	endpoint := fmt.Sprintf("%s/repos/%s/issues/%d/comments", g.config.BaseURL, repo, n)
+	dryRun, err := g.http.do(ctx, http.MethodPost, endpoint, map[string]string{"body": text}, &comment)
+	if err != nil || dryRun != "" {
+		return dryRun, err
+	}
+	return fmt.Sprintf("Commented on #%d in %s: %s", n, repo, comment.HTMLURL), nil
+}
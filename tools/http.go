@@ -0,0 +1,37 @@
+package tools
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// maxResponseBody bounds the responses read by the tools
+const maxResponseBody = 10 << 20
+
+// send sends a request and returns the response body, turning failed
+// statuses into errors. service names the remote end in messages.
+func send(client *http.Client, req *http.Request, service string) ([]byte, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, errors.APICallError("call "+service, err)
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrNetworkUnavail, "failed to read response", err).WithRetryable(true).WithTemporary(true)
+	}
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, errors.Unauthorized(service+" denied access").WithContext("status_code", resp.StatusCode)
+	case resp.StatusCode == http.StatusNotFound:
+		return nil, errors.Newf(errors.ErrNotFound, "%s resource not found", service).WithContext("url", req.URL.String())
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, errors.APIStatusCodeError(resp.StatusCode, truncate(string(data), maxErrorMessage)).WithRetryable(true).WithTemporary(true)
+	case resp.StatusCode >= 300:
+		return nil, errors.APIStatusCodeError(resp.StatusCode, truncate(string(data), maxErrorMessage))
+	}
+	return data, nil
+}
@@ -0,0 +1,137 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Defaults of the issue tracker tools
+const (
+	// DefaultIssueLimit is the number of issues a search returns
+	DefaultIssueLimit = 20
+	// maxIssueLimit bounds the issues a search returns
+	maxIssueLimit = 100
+	// maxIssueBody bounds the issue description included in tool output
+	maxIssueBody = 4000
+)
+
+// actionTool is a tool running a function, used for the several tools of one
+// service
+type actionTool struct {
+	*BaseTool
+	run         func(ctx context.Context, args map[string]interface{}) (string, error)
+	sideEffects bool
+}
+
+// Execute runs the tool's function
+func (t actionTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return t.run(ctx, args)
+}
+
+// SideEffects reports whether the tool changes the tracker
+func (t actionTool) SideEffects() bool { return t.sideEffects }
+
+// issueClient sends the requests of an issue tracker's tools
+type issueClient struct {
+	service string
+	client  *http.Client
+	auth    func(req *http.Request)
+	dryRun  bool
+}
+
+// newIssueClient creates a client with the usual API timeout
+func newIssueClient(service string, dryRun bool, auth func(req *http.Request)) *issueClient {
+	return &issueClient{service: service, client: &http.Client{Timeout: 30 * time.Second}, auth: auth, dryRun: dryRun}
+}
+
+// do sends a JSON request and decodes the response into out. In dry-run mode
+// requests other than GET are not sent; do returns their description as
+// the tool output instead.
+func (c *issueClient) do(ctx context.Context, method, url string, body, out interface{}) (string, error) {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return "", errors.Wrap(errors.ErrInternal, "failed to marshal request", err)
+		}
+		if c.dryRun && method != http.MethodGet {
+			return fmt.Sprintf("[dry run] %s %s was not sent. Body: %s", method, url, data), nil
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	c.auth(req)
+
+	data, err := send(c.client, req, c.service+" API")
+	if err != nil {
+		return "", err
+	}
+
+	if out != nil {
+		if err := json.Unmarshal(data, out); err != nil {
+			return "", errors.Wrap(errors.ErrAPIResponse, "failed to unmarshal "+c.service+" response", err).WithContext("response_length", len(data))
+		}
+	}
+	return "", nil
+}
+
+// limitArg returns the "limit" argument, bounded to maxIssueLimit
+func limitArg(args map[string]interface{}) (int, error) {
+	raw, ok := args["limit"]
+	if !ok {
+		return DefaultIssueLimit, nil
+	}
+	n, err := numberArg(raw)
+	if err != nil || n < 1 {
+		return 0, errors.InvalidField("limit", "must be a positive number").WithContext("value", raw)
+	}
+	return min(int(n), maxIssueLimit), nil
+}
+
+// listArg returns a list argument given as an array or comma-separated text
+func listArg(args map[string]interface{}, name string) []string {
+	var items []string
+	switch v := args[name].(type) {
+	case []interface{}:
+		for _, item := range v {
+			items = append(items, fmt.Sprint(item))
+		}
+	case []string:
+		items = v
+	case string:
+		items = strings.Split(v, ",")
+	}
+	var list []string
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// containsFold reports whether list contains s, ignoring case
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
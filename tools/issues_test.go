@@ -0,0 +1,207 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// trackerServer records the requests sent to it and answers with responses
+// by "METHOD path"
+func trackerServer(t *testing.T, responses map[string]string) (*httptest.Server, *[]*http.Request, *[]string) {
+	t.Helper()
+	var requests []*http.Request
+	var bodies []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r)
+		bodies = append(bodies, string(body))
+		resp, ok := responses[r.Method+" "+r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		_, _ = io.WriteString(w, resp)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests, &bodies
+}
+
+func toolRegistry(t *testing.T, tools []Tool) *Registry {
+	t.Helper()
+	registry := NewRegistry()
+	for _, tool := range tools {
+		if err := registry.Register(tool); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return registry
+}
+
+func TestGitHubTools(t *testing.T) {
+	server, requests, bodies := trackerServer(t, map[string]string{
+		"GET /search/issues":           `{"total_count": 1, "items": [{"number": 7, "title": "Crash on start", "state": "open", "html_url": "https://github.com/acme/app/issues/7", "user": {"login": "ana"}, "labels": [{"name": "bug"}]}]}`,
+		"POST /repos/acme/app/issues":  `{"number": 8, "html_url": "https://github.com/acme/app/issues/8"}`,
+		"GET /repos/acme/app/issues/7": `{"number": 7, "title": "Crash on start", "state": "open", "body": "Stack trace", "comments": 2, "pull_request": {"url": "x"}, "user": {"login": "ana"}}`,
+	})
+	ghTools, err := NewGitHubTools(GitHubConfig{Token: "secret", BaseURL: server.URL, Repos: []string{"acme/app"}})
+	if err != nil {
+		t.Fatalf("NewGitHubTools() error = %v", err)
+	}
+	registry := toolRegistry(t, ghTools)
+	ctx := context.Background()
+
+	got, err := registry.Execute(ctx, GitHubSearchToolName, map[string]interface{}{"query": "crash", "type": "issue", "state": "open"})
+	if err != nil {
+		t.Fatalf("search error = %v", err)
+	}
+	if want := "#7 [issue, open] Crash on start (by ana) https://github.com/acme/app/issues/7 labels: bug"; !strings.Contains(got, want) {
+		t.Errorf("search = %q, want it to contain %q", got, want)
+	}
+	req := (*requests)[0]
+	if q := req.URL.Query().Get("q"); q != "crash repo:acme/app is:issue state:open" {
+		t.Errorf("search query = %q, want it scoped to the repository", q)
+	}
+	if auth := req.Header.Get("Authorization"); auth != "Bearer secret" {
+		t.Errorf("Authorization = %q", auth)
+	}
+
+	got, err = registry.Execute(ctx, GitHubGetToolName, map[string]interface{}{"number": "#7"})
+	if err != nil || !strings.Contains(got, "[PR, open]") || !strings.Contains(got, "2 comments\n\nStack trace") {
+		t.Errorf("get = %q, %v", got, err)
+	}
+
+	got, err = registry.Execute(ctx, GitHubCreateToolName, map[string]interface{}{"title": "Flaky test", "labels": []interface{}{"ci"}})
+	if err != nil || got != "Created issue #8 in acme/app: https://github.com/acme/app/issues/8" {
+		t.Errorf("create = %q, %v", got, err)
+	}
+	var sent map[string]interface{}
+	_ = json.Unmarshal([]byte((*bodies)[2]), &sent)
+	if sent["title"] != "Flaky test" || len(sent["labels"].([]interface{})) != 1 {
+		t.Errorf("create body = %v", sent)
+	}
+
+	for name, args := range map[string]map[string]interface{}{
+		GitHubGetToolName:    {"repo": "evil/repo", "number": 1},
+		GitHubSearchToolName: {"query": "org:evil leak"},
+	} {
+		if _, err := registry.Execute(ctx, name, args); !errors.HasCode(cause(err), errors.ErrUnauthorized) {
+			t.Errorf("%s(%v) error = %v, want ErrUnauthorized", name, args, err)
+		}
+	}
+	if _, err := registry.Execute(ctx, GitHubGetToolName, map[string]interface{}{"number": 404}); !errors.HasCode(cause(err), errors.ErrNotFound) {
+		t.Errorf("get missing issue error = %v, want ErrNotFound", err)
+	}
+
+	// Dry runs send no request
+	sentBefore := len(*requests)
+	ghTools, _ = NewGitHubTools(GitHubConfig{Token: "secret", BaseURL: server.URL, Repos: []string{"acme/app"}, DryRun: true})
+	registry = toolRegistry(t, ghTools)
+	got, err = registry.Execute(ctx, GitHubCommentToolName, map[string]interface{}{"number": 7, "body": "Fixed in #8"})
+	if err != nil || !strings.HasPrefix(got, "[dry run] POST "+server.URL+"/repos/acme/app/issues/7/comments was not sent") {
+		t.Errorf("dry-run comment = %q, %v", got, err)
+	}
+	if len(*requests) != sentBefore {
+		t.Errorf("dry run sent %d requests", len(*requests)-sentBefore)
+	}
+	if tool, _ := registry.Get(GitHubCommentToolName); !HasSideEffects(tool) {
+		t.Error("comment tool is not side-effecting")
+	}
+}
+
+func TestJiraTools(t *testing.T) {
+	server, requests, bodies := trackerServer(t, map[string]string{
+		"GET /rest/api/2/search": `{"total": 3, "issues": [{"key": "OPS-1", "fields": {"summary": "Disk full", "status": {"name": "Open"}, "issuetype": {"name": "Bug"}, "priority": {"name": "High"}}}, {"key": "HR-3", "fields": {"summary": "Salaries"}}]}`,
+		"POST /rest/api/2/issue": `{"key": "OPS-2"}`,
+	})
+	jiraTools, err := NewJiraTools(JiraConfig{BaseURL: server.URL, Email: "bot@example.com", Token: "secret", Projects: []string{"ops"}})
+	if err != nil {
+		t.Fatalf("NewJiraTools() error = %v", err)
+	}
+	registry := toolRegistry(t, jiraTools)
+	ctx := context.Background()
+
+	got, err := registry.Execute(ctx, JiraSearchToolName, map[string]interface{}{"jql": "status = Open ORDER BY created DESC", "limit": 5})
+	if err != nil || !strings.Contains(got, "OPS-1 [Bug, Open, High] Disk full (unassigned) "+server.URL+"/browse/OPS-1") {
+		t.Errorf("search = %q, %v", got, err)
+	}
+	query := (*requests)[0].URL.Query()
+	if jql := query.Get("jql"); jql != "project in (OPS) AND (status = Open) ORDER BY created DESC" {
+		t.Errorf("jql = %q, want it scoped to the project", jql)
+	}
+	if strings.Contains(got, "HR-3") {
+		t.Errorf("search = %q, want issues of other projects dropped", got)
+	}
+	if query.Get("maxResults") != "5" {
+		t.Errorf("maxResults = %q, want 5", query.Get("maxResults"))
+	}
+	if user, pass, ok := (*requests)[0].BasicAuth(); !ok || user != "bot@example.com" || pass != "secret" {
+		t.Errorf("basic auth = %q, %q, %v", user, pass, ok)
+	}
+
+	got, err = registry.Execute(ctx, JiraCreateToolName, map[string]interface{}{"summary": "Rotate logs", "labels": "ops, disk"})
+	if err != nil || got != "Created OPS-2: "+server.URL+"/browse/OPS-2" {
+		t.Errorf("create = %q, %v", got, err)
+	}
+	var sent struct {
+		Fields struct {
+			Project   map[string]string `json:"project"`
+			IssueType map[string]string `json:"issuetype"`
+			Labels    []string          `json:"labels"`
+		} `json:"fields"`
+	}
+	_ = json.Unmarshal([]byte((*bodies)[1]), &sent)
+	if sent.Fields.Project["key"] != "OPS" || sent.Fields.IssueType["name"] != "Task" || len(sent.Fields.Labels) != 2 {
+		t.Errorf("create body = %+v", sent)
+	}
+
+	for name, args := range map[string]map[string]interface{}{
+		JiraGetToolName:    {"key": "HR-9"},
+		JiraCreateToolName: {"project": "HR", "summary": "x"},
+	} {
+		if _, err := registry.Execute(ctx, name, args); !errors.HasCode(cause(err), errors.ErrUnauthorized) {
+			t.Errorf("%s(%v) error = %v, want ErrUnauthorized", name, args, err)
+		}
+	}
+	if _, err := registry.Execute(ctx, JiraGetToolName, map[string]interface{}{"key": "not a key"}); !errors.HasCode(err, errors.ErrToolInvalidArgs) {
+		t.Errorf("get with invalid key error = %v, want ErrToolInvalidArgs", err)
+	}
+
+	// Queries that could close the project scope are rejected
+	sentBefore := len(*requests)
+	for _, jql := range []string{
+		"status = Open) OR (project = HR",
+		"status = Open OR project = HR) ORDER BY (created",
+		`summary ~ "open`,
+	} {
+		if _, err := registry.Execute(ctx, JiraSearchToolName, map[string]interface{}{"jql": jql}); !errors.HasCode(err, errors.ErrToolInvalidArgs) {
+			t.Errorf("search(%q) error = %v, want ErrToolInvalidArgs", jql, err)
+		}
+	}
+	if len(*requests) != sentBefore {
+		t.Errorf("rejected searches sent %d requests", len(*requests)-sentBefore)
+	}
+	if _, err := registry.Execute(ctx, JiraSearchToolName, map[string]interface{}{"jql": `summary ~ "(order by)" ORDER BY key`}); err != nil {
+		t.Errorf("search with quoted parentheses error = %v", err)
+	}
+	if jql := (*requests)[len(*requests)-1].URL.Query().Get("jql"); jql != `project in (OPS) AND (summary ~ "(order by)") ORDER BY key` {
+		t.Errorf("jql = %q", jql)
+	}
+	if _, err := NewJiraTools(JiraConfig{BaseURL: server.URL}); !errors.HasCode(err, errors.ErrRequiredField) {
+		t.Errorf("NewJiraTools without token error = %v, want ErrRequiredField", err)
+	}
+}
+
+// cause returns the error a tool error wraps
+func cause(err error) error {
+	if e, ok := err.(*errors.Error); ok {
+		return e.Err
+	}
+	return nil
+}
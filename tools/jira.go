@@ -0,0 +1,328 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Names of the Jira tools
+const (
+	JiraSearchToolName  = "jira_search_issues"
+	JiraGetToolName     = "jira_get_issue"
+	JiraCreateToolName  = "jira_create_issue"
+	JiraCommentToolName = "jira_comment_issue"
+)
+
+// JiraConfig configures the Jira issue tools
+type JiraConfig struct {
+	// BaseURL is the Jira site, e.g. "https://example.atlassian.net"
+	BaseURL string
+	// Email and Token authenticate with Jira Cloud API tokens. Without an
+	// email, Token is sent as a bearer personal access token (Jira Data
+	// Center).
+	Email string
+	Token string
+	// Projects are the project keys the tools may access (empty = any the
+	// token can). The first is the default project for new issues.
+	Projects []string
+	// DryRun describes issue creations and comments instead of sending them
+	DryRun bool
+}
+
+// jira implements the Jira tools
+type jira struct {
+	config JiraConfig
+	http   *issueClient
+}
+
+// jiraKey matches issue keys such as "OPS-42"
+var jiraKey = regexp.MustCompile(`^([A-Z][A-Z0-9_]*)-[0-9]+$`)
+
+// NewJiraTools creates tools to search, read, create and comment on Jira
+// issues. Creating and commenting are side-effecting, so they need
+// confirmation when the run has a Confirmer.
+func NewJiraTools(cfg JiraConfig) ([]Tool, error) {
+	if cfg.BaseURL == "" {
+		return nil, errors.RequiredField("Jira URL")
+	}
+	if cfg.Token == "" {
+		return nil, errors.RequiredField("Jira token")
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	projects := make([]string, len(cfg.Projects))
+	for i, project := range cfg.Projects {
+		projects[i] = strings.ToUpper(strings.TrimSpace(project))
+	}
+	cfg.Projects = projects
+
+	j := &jira{config: cfg}
+	j.http = newIssueClient("Jira", cfg.DryRun, func(req *http.Request) {
+		if cfg.Email != "" {
+			req.SetBasicAuth(cfg.Email, cfg.Token)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+cfg.Token)
+		}
+	})
+	projectArg := "project key"
+	if len(cfg.Projects) > 0 {
+		projectArg += fmt.Sprintf(" (default %s)", cfg.Projects[0])
+	}
+	return []Tool{
+		actionTool{BaseTool: NewBaseTool(JiraSearchToolName,
+			"Searches Jira issues with JQL, e.g. \"status = 'In Progress' AND assignee = currentUser()\".",
+			map[string]interface{}{"jql": "JQL query", "limit": DefaultIssueLimit}), run: j.search},
+		actionTool{BaseTool: NewBaseTool(JiraGetToolName,
+			"Returns a Jira issue with its description.",
+			map[string]interface{}{"key": "issue key, e.g. OPS-42"}), run: j.get},
+		actionTool{BaseTool: NewBaseTool(JiraCreateToolName,
+			"Creates a Jira issue.",
+			map[string]interface{}{
+				"project":     projectArg,
+				"summary":     "issue summary",
+				"description": "issue description",
+				"type":        "issue type (default Task)",
+				"labels":      []string{"triage"},
+			}), run: j.create, sideEffects: true},
+		actionTool{BaseTool: NewBaseTool(JiraCommentToolName,
+			"Comments on a Jira issue.",
+			map[string]interface{}{"key": "issue key, e.g. OPS-42", "body": "comment"}), run: j.comment, sideEffects: true},
+	}, nil
+}
+
+// project returns the project argument, checked against the configured ones
+func (j *jira) project(args map[string]interface{}) (string, error) {
+	project := strings.ToUpper(stringArg(args, "project"))
+	switch {
+	case project == "" && len(j.config.Projects) > 0:
+		return j.config.Projects[0], nil
+	case project == "":
+		return "", errors.RequiredField("project")
+	}
+	return project, j.allowed(project)
+}
+
+// key returns the issue key argument, checked against the configured projects
+func (j *jira) key(args map[string]interface{}) (string, error) {
+	key := strings.ToUpper(stringArg(args, "key"))
+	if key == "" {
+		return "", errors.RequiredField("key")
+	}
+	m := jiraKey.FindStringSubmatch(key)
+	if m == nil {
+		return "", errors.InvalidField("key", "must be an issue key such as OPS-42").WithContext("value", key)
+	}
+	return key, j.allowed(m[1])
+}
+
+// allowed checks a project against the configured ones
+func (j *jira) allowed(project string) error {
+	if len(j.config.Projects) > 0 && !containsFold(j.config.Projects, project) {
+		return errors.Unauthorized("project is outside the configured projects").
+			WithContext("project", project).
+			WithContext("allowed", strings.Join(j.config.Projects, ", "))
+	}
+	return nil
+}
+
+// jiraIssue is an issue of the Jira REST API
+type jiraIssue struct {
+	Key    string `json:"key"`
+	Fields struct {
+		Summary     string `json:"summary"`
+		Description string `json:"description"`
+		Status      struct {
+			Name string `json:"name"`
+		} `json:"status"`
+		IssueType struct {
+			Name string `json:"name"`
+		} `json:"issuetype"`
+		Priority *struct {
+			Name string `json:"name"`
+		} `json:"priority"`
+		Assignee *struct {
+			DisplayName string `json:"displayName"`
+		} `json:"assignee"`
+		Labels []string `json:"labels"`
+	} `json:"fields"`
+}
+
+// jiraFields are the fields requested for issues
+const jiraFields = "summary,status,issuetype,priority,assignee,labels"
+
+// summary formats the issue on one line
+func (i jiraIssue) summary(baseURL string) string {
+	f := i.Fields
+	assignee := "unassigned"
+	if f.Assignee != nil {
+		assignee = f.Assignee.DisplayName
+	}
+	line := fmt.Sprintf("%s [%s, %s", i.Key, f.IssueType.Name, f.Status.Name)
+	if f.Priority != nil {
+		line += ", " + f.Priority.Name
+	}
+	line += fmt.Sprintf("] %s (%s) %s/browse/%s", f.Summary, assignee, baseURL, i.Key)
+	if len(f.Labels) > 0 {
+		line += " labels: " + strings.Join(f.Labels, ", ")
+	}
+	return line
+}
+
+// search runs a JQL search, scoped to the configured projects
+func (j *jira) search(ctx context.Context, args map[string]interface{}) (string, error) {
+	jql := stringArg(args, "jql")
+	if jql == "" {
+		return "", errors.RequiredField("jql")
+	}
+	limit, err := limitArg(args)
+	if err != nil {
+		return "", err
+	}
+	if len(j.config.Projects) > 0 {
+		// ORDER BY must stay at the end of the query
+		condition, order, err := splitJQL(jql)
+		if err != nil {
+			return "", err
+		}
+		scope := fmt.Sprintf("project in (%s)", strings.Join(j.config.Projects, ", "))
+		if condition != "" {
+			scope += " AND (" + condition + ")"
+		}
+		jql = scope + order
+	}
+
+	var result struct {
+		Total  int         `json:"total"`
+		Issues []jiraIssue `json:"issues"`
+	}
+	endpoint := fmt.Sprintf("%s/rest/api/2/search?jql=%s&maxResults=%d&fields=%s", j.config.BaseURL, url.QueryEscape(jql), limit, jiraFields)
+	if _, err := j.http.do(ctx, http.MethodGet, endpoint, nil, &result); err != nil {
+		return "", err
+	}
+	// The scope keeps other projects out of the query, and this keeps them
+	// out of the output should the query escape it anyway
+	issues := result.Issues[:0]
+	for _, issue := range result.Issues {
+		if m := jiraKey.FindStringSubmatch(issue.Key); m != nil && j.allowed(m[1]) == nil {
+			issues = append(issues, issue)
+		}
+	}
+	result.Issues = issues
+	if len(result.Issues) == 0 {
+		return "No issues found for: " + jql, nil
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d of %d results for: %s\n", len(result.Issues), result.Total, jql)
+	for _, issue := range result.Issues {
+		b.WriteString(issue.summary(j.config.BaseURL) + "\n")
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// splitJQL splits a JQL query into its condition and its ORDER BY clause.
+// Queries with unbalanced parentheses or quotes are rejected, since they
+// could close the project scope the condition is wrapped in.
+func splitJQL(jql string) (condition, order string, err error) {
+	depth, quote := 0, byte(0)
+	condition = jql
+	for i := 0; i < len(jql); i++ {
+		c := jql[i]
+		switch {
+		case quote != 0 && c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth < 0 {
+				return "", "", errors.InvalidField("jql", "has unbalanced parentheses").WithContext("value", jql)
+			}
+		case depth == 0 && order == "" && len(jql)-i >= 8 && strings.EqualFold(jql[i:i+8], "ORDER BY"):
+			condition, order = strings.TrimSpace(jql[:i]), " "+jql[i:]
+		}
+	}
+	switch {
+	case quote != 0:
+		return "", "", errors.InvalidField("jql", "has an unterminated quote").WithContext("value", jql)
+	case depth != 0:
+		return "", "", errors.InvalidField("jql", "has unbalanced parentheses").WithContext("value", jql)
+	}
+	return condition, order, nil
+}
+
+// get returns an issue with its description
+func (j *jira) get(ctx context.Context, args map[string]interface{}) (string, error) {
+	key, err := j.key(args)
+	if err != nil {
+		return "", err
+	}
+	var issue jiraIssue
+	endpoint := fmt.Sprintf("%s/rest/api/2/issue/%s?fields=%s,description", j.config.BaseURL, key, jiraFields)
+	if _, err := j.http.do(ctx, http.MethodGet, endpoint, nil, &issue); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s\n\n%s", issue.summary(j.config.BaseURL), truncate(issue.Fields.Description, maxIssueBody)), nil
+}
+
+// create opens an issue
+func (j *jira) create(ctx context.Context, args map[string]interface{}) (string, error) {
+	project, err := j.project(args)
+	if err != nil {
+		return "", err
+	}
+	summary := stringArg(args, "summary")
+	if summary == "" {
+		return "", errors.RequiredField("summary")
+	}
+	issueType := stringArg(args, "type")
+	if issueType == "" {
+		issueType = "Task"
+	}
+	fields := map[string]interface{}{
+		"project":     map[string]string{"key": project},
+		"summary":     summary,
+		"description": stringArg(args, "description"),
+		"issuetype":   map[string]string{"name": issueType},
+	}
+	if labels := listArg(args, "labels"); len(labels) > 0 {
+		fields["labels"] = labels
+	}
+
+	var created struct {
+		Key string `json:"key"`
+	}
+	dryRun, err := j.http.do(ctx, http.MethodPost, j.config.BaseURL+"/rest/api/2/issue", map[string]interface{}{"fields": fields}, &created)
+	if err != nil || dryRun != "" {
+		return dryRun, err
+	}
+	return fmt.Sprintf("Created %s: %s/browse/%s", created.Key, j.config.BaseURL, created.Key), nil
+}
+
+// comment adds a comment to an issue
+func (j *jira) comment(ctx context.Context, args map[string]interface{}) (string, error) {
+	key, err := j.key(args)
+	if err != nil {
+		return "", err
+	}
+	text := stringArg(args, "body")
+	if text == "" {
+		return "", errors.RequiredField("body")
+	}
+	endpoint := fmt.Sprintf("%s/rest/api/2/issue/%s/comment", j.config.BaseURL, key)
+	dryRun, err := j.http.do(ctx, http.MethodPost, endpoint, map[string]string{"body": text}, nil)
+	if err != nil || dryRun != "" {
+		return dryRun, err
+	}
+	return fmt.Sprintf("Commented on %s: %s/browse/%s", key, j.config.BaseURL, key), nil
+}
@@ -0,0 +1,59 @@
+package tools
+
+import (
+	"context"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// Permissions restricts the tools an agent may execute through a registry,
+// so agents sharing one registry can be given different tools
+type Permissions struct {
+	Agent string   // Name reported in errors
+	Allow []string // Tools the agent may use; empty allows every tool
+	Deny  []string // Tools the agent may never use, even if allowed
+}
+
+// Permits reports whether the tool may be executed
+func (p Permissions) Permits(name string) bool {
+	for _, denied := range p.Deny {
+		if denied == name {
+			return false
+		}
+	}
+	if len(p.Allow) == 0 {
+		return true
+	}
+	for _, allowed := range p.Allow {
+		if allowed == name {
+			return true
+		}
+	}
+	return false
+}
+
+// check returns an ErrUnauthorized error if the tool is not permitted
+func (p Permissions) check(name string) error {
+	if p.Permits(name) {
+		return nil
+	}
+	return errors.Newf(errors.ErrUnauthorized, "agent %s is not allowed to use tool %s", p.Agent, name).
+		WithContext("agent", p.Agent).
+		WithContext("tool", name)
+}
+
+// permissionsKey is the context key for Permissions
+type permissionsKey struct{}
+
+// NewPermissionsContext returns a context whose tool executions are
+// restricted by p. It replaces any permissions carried by ctx.
+func NewPermissionsContext(ctx context.Context, p Permissions) context.Context {
+	return context.WithValue(ctx, permissionsKey{}, p)
+}
+
+// PermissionsFromContext returns the permissions carried by ctx; ok is false
+// when tool use is unrestricted
+func PermissionsFromContext(ctx context.Context) (p Permissions, ok bool) {
+	p, ok = ctx.Value(permissionsKey{}).(Permissions)
+	return p, ok
+}
@@ -0,0 +1,663 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// TableToolName is the name of the spreadsheet tool
+const TableToolName = "table"
+
+// Defaults of the table tool
+const (
+	// DefaultTableRows bounds the rows loaded from a file
+	DefaultTableRows = 100000
+	// DefaultTableOutputRows bounds the rows of a query result given to the
+	// model
+	DefaultTableOutputRows = 50
+	// maxTableFile bounds the size of a loaded file
+	maxTableFile = 100 << 20
+	// topValues is the number of frequent values reported for text columns
+	topValues = 5
+)
+
+// Table is a spreadsheet with a header row
+type Table struct {
+	Columns []string
+	Rows    [][]string
+}
+
+// LoadTable reads a CSV, TSV or XLSX file, choosing the format by extension.
+// sheet selects an XLSX worksheet (empty = the first). The first row holds
+// the column names.
+func LoadTable(path, sheet string) (*Table, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, errors.NotFound("file", filepath.Base(path))
+		}
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read file", err).WithContext("path", path)
+	}
+	if info.Size() > maxTableFile {
+		return nil, errors.InvalidField("file", "is too large").WithContext("size", info.Size()).WithContext("max_size", maxTableFile)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to read file", err).WithContext("path", path)
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".csv":
+		return ReadCSV(bytes.NewReader(data), ',')
+	case ".tsv", ".tab":
+		return ReadCSV(bytes.NewReader(data), '\t')
+	case ".xlsx", ".xlsm":
+		rows, err := readXLSX(bytes.NewReader(data), int64(len(data)), sheet)
+		if err != nil {
+			return nil, err
+		}
+		return newTable(rows)
+	default:
+		return nil, errors.UnsupportedType(ext).WithContext("supported", ".csv, .tsv, .xlsx")
+	}
+}
+
+// ReadCSV reads a delimited table. Rows may have different lengths.
+func ReadCSV(r io.Reader, comma rune) (*Table, error) {
+	reader := csv.NewReader(r)
+	reader.Comma = comma
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid CSV file", err)
+	}
+	return newTable(rows)
+}
+
+// newTable splits the header from the rows, naming unnamed columns and
+// padding short rows
+func newTable(rows [][]string) (*Table, error) {
+	if len(rows) == 0 {
+		return nil, errors.New(errors.ErrInvalidFormat, "table is empty")
+	}
+	width := 0
+	for _, row := range rows {
+		width = max(width, len(row))
+	}
+	columns := make([]string, width)
+	for i := range columns {
+		if i < len(rows[0]) {
+			columns[i] = strings.TrimSpace(strings.TrimPrefix(rows[0][i], "\ufeff"))
+		}
+		if columns[i] == "" {
+			columns[i] = fmt.Sprintf("column%d", i+1)
+		}
+	}
+	t := &Table{Columns: columns}
+	for _, row := range rows[1:] {
+		for len(row) < width {
+			row = append(row, "")
+		}
+		t.Rows = append(t.Rows, row)
+	}
+	return t, nil
+}
+
+// column returns the index of a column, matched case-insensitively
+func (t *Table) column(name string) (int, error) {
+	name = strings.TrimSpace(name)
+	for i, c := range t.Columns {
+		if strings.EqualFold(c, name) {
+			return i, nil
+		}
+	}
+	return 0, errors.InvalidField("column", "does not exist").
+		WithContext("column", name).
+		WithContext("columns", strings.Join(t.Columns, ", "))
+}
+
+// ColumnStats describes a column of a table
+type ColumnStats struct {
+	Name     string
+	Type     string // "number", "text" or "empty"
+	Values   int    // Non-empty cells
+	Distinct int
+	// Min, Max and Mean summarize number columns
+	Min, Max, Mean float64
+	// Top are the most frequent values of text columns with their counts,
+	// e.g. "EU (12)"
+	Top []string
+}
+
+// Schema returns the statistics of every column
+func (t *Table) Schema() []ColumnStats {
+	stats := make([]ColumnStats, len(t.Columns))
+	for i, name := range t.Columns {
+		s := ColumnStats{Name: name, Type: "empty", Min: math.Inf(1), Max: math.Inf(-1)}
+		counts := make(map[string]int)
+		numbers, sum := 0, 0.0
+		for _, row := range t.Rows {
+			cell := strings.TrimSpace(row[i])
+			if cell == "" {
+				continue
+			}
+			s.Values++
+			counts[cell]++
+			if n, ok := parseNumber(cell); ok {
+				numbers++
+				sum += n
+				s.Min, s.Max = math.Min(s.Min, n), math.Max(s.Max, n)
+			}
+		}
+		s.Distinct = len(counts)
+		switch {
+		case s.Values == 0:
+		case numbers == s.Values:
+			s.Type = "number"
+			s.Mean = sum / float64(numbers)
+		default:
+			s.Type = "text"
+			s.Top = topCounts(counts, topValues)
+		}
+		if s.Type != "number" {
+			s.Min, s.Max = 0, 0
+		}
+		stats[i] = s
+	}
+	return stats
+}
+
+// topCounts returns the n most frequent values with their counts
+func topCounts(counts map[string]int, n int) []string {
+	values := make([]string, 0, len(counts))
+	for v := range counts {
+		values = append(values, v)
+	}
+	sort.Slice(values, func(i, j int) bool {
+		if counts[values[i]] != counts[values[j]] {
+			return counts[values[i]] > counts[values[j]]
+		}
+		return values[i] < values[j]
+	})
+	var top []string
+	for _, v := range values[:min(n, len(values))] {
+		top = append(top, fmt.Sprintf("%s (%d)", v, counts[v]))
+	}
+	return top
+}
+
+// TableQuery selects, filters, groups and sorts the rows of a table
+type TableQuery struct {
+	// Columns to return (empty = all), when not aggregating
+	Columns []string
+	// Filters are conditions all rows must meet, e.g. "region = EU",
+	// "amount >= 100" or "name contains smith". Numbers compare
+	// numerically, other values case-insensitively.
+	Filters []string
+	// GroupBy aggregates the rows per value of a column
+	GroupBy string
+	// Aggregates are count, or sum, avg, min, max, count or distinct of a
+	// column, e.g. "sum(amount)"
+	Aggregates []string
+	// Sort orders by a column, descending with a "-" prefix
+	Sort string
+}
+
+// Query runs q and returns the resulting table
+func (t *Table) Query(q TableQuery) (*Table, error) {
+	rows := t.Rows
+	for _, f := range q.Filters {
+		match, err := t.filter(f)
+		if err != nil {
+			return nil, err
+		}
+		var kept [][]string
+		for _, row := range rows {
+			if match(row) {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+
+	var result *Table
+	var err error
+	if q.GroupBy != "" || len(q.Aggregates) > 0 {
+		result, err = t.aggregate(rows, q.GroupBy, q.Aggregates)
+	} else {
+		result, err = t.project(rows, q.Columns)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if q.Sort != "" {
+		if err := result.sortBy(q.Sort); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// project returns the rows with the given columns
+func (t *Table) project(rows [][]string, columns []string) (*Table, error) {
+	if len(columns) == 0 {
+		return &Table{Columns: t.Columns, Rows: rows}, nil
+	}
+	indexes := make([]int, len(columns))
+	result := &Table{Columns: make([]string, len(columns))}
+	for i, name := range columns {
+		col, err := t.column(name)
+		if err != nil {
+			return nil, err
+		}
+		indexes[i], result.Columns[i] = col, t.Columns[col]
+	}
+	for _, row := range rows {
+		values := make([]string, len(indexes))
+		for i, col := range indexes {
+			values[i] = row[col]
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, nil
+}
+
+// filterOps are the filter operators, longer ones first so they match before
+// their prefixes
+var filterOps = []string{" contains ", ">=", "<=", "!=", "==", "=", ">", "<"}
+
+// filter parses a condition such as "amount >= 100"
+func (t *Table) filter(condition string) (func(row []string) bool, error) {
+	op, at := "", -1
+	lower := strings.ToLower(condition)
+	for _, candidate := range filterOps {
+		if i := strings.Index(lower, candidate); i > 0 && (at < 0 || i < at) {
+			op, at = candidate, i
+		}
+	}
+	if at < 0 {
+		return nil, errors.InvalidField("filter", "must be \"column operator value\" with =, !=, <, <=, >, >= or contains").WithContext("value", condition)
+	}
+	col, err := t.column(condition[:at])
+	if err != nil {
+		return nil, err
+	}
+	want := strings.Trim(strings.TrimSpace(condition[at+len(op):]), `"'`)
+	op = strings.TrimSpace(op)
+
+	return func(row []string) bool {
+		cell := strings.TrimSpace(row[col])
+		if op == "contains" {
+			return strings.Contains(strings.ToLower(cell), strings.ToLower(want))
+		}
+		c := compareCells(cell, want)
+		switch op {
+		case "=", "==":
+			return c == 0
+		case "!=":
+			return c != 0
+		case ">":
+			return c > 0
+		case ">=":
+			return c >= 0
+		case "<":
+			return c < 0
+		default:
+			return c <= 0
+		}
+	}, nil
+}
+
+// aggregator is an aggregate function of a column, or the row count when
+// col is negative
+type aggregator struct {
+	fn  string
+	col int
+}
+
+// aggregate computes aggregates per group of rows
+func (t *Table) aggregate(rows [][]string, groupBy string, aggregates []string) (*Table, error) {
+	if len(aggregates) == 0 {
+		aggregates = []string{"count"}
+	}
+	aggs := make([]aggregator, len(aggregates))
+	result := &Table{}
+	group := -1
+	if groupBy != "" {
+		col, err := t.column(groupBy)
+		if err != nil {
+			return nil, err
+		}
+		group = col
+		result.Columns = append(result.Columns, t.Columns[col])
+	}
+	for i, a := range aggregates {
+		a = strings.ToLower(strings.TrimSpace(a))
+		fn, arg, ok := strings.Cut(strings.TrimSuffix(a, ")"), "(")
+		switch {
+		case a == "count":
+			aggs[i] = aggregator{fn: "count", col: -1}
+			result.Columns = append(result.Columns, "count")
+			continue
+		case !ok || !strings.HasSuffix(a, ")"):
+			return nil, errors.InvalidField("aggregate", "must be count or function(column)").WithContext("value", a)
+		}
+		switch fn {
+		case "sum", "avg", "min", "max", "count", "distinct":
+		default:
+			return nil, errors.InvalidField("aggregate", "function must be sum, avg, min, max, count or distinct").WithContext("value", a)
+		}
+		col, err := t.column(arg)
+		if err != nil {
+			return nil, err
+		}
+		aggs[i] = aggregator{fn: fn, col: col}
+		result.Columns = append(result.Columns, fmt.Sprintf("%s(%s)", fn, t.Columns[col]))
+	}
+
+	var keys []string
+	groups := make(map[string][][]string)
+	for _, row := range rows {
+		key := ""
+		if group >= 0 {
+			key = strings.TrimSpace(row[group])
+		}
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], row)
+	}
+	if group < 0 && len(keys) == 0 {
+		keys = []string{""}
+	}
+
+	for _, key := range keys {
+		var values []string
+		if group >= 0 {
+			values = append(values, key)
+		}
+		for _, a := range aggs {
+			values = append(values, a.compute(groups[key]))
+		}
+		result.Rows = append(result.Rows, values)
+	}
+	return result, nil
+}
+
+// compute applies the aggregate to a group of rows
+func (a aggregator) compute(rows [][]string) string {
+	if a.col < 0 {
+		return strconv.Itoa(len(rows))
+	}
+	count, numbers, sum := 0, 0, 0.0
+	lo, hi := math.Inf(1), math.Inf(-1)
+	distinct := make(map[string]struct{})
+	for _, row := range rows {
+		cell := strings.TrimSpace(row[a.col])
+		if cell == "" {
+			continue
+		}
+		count++
+		distinct[cell] = struct{}{}
+		if n, ok := parseNumber(cell); ok {
+			numbers++
+			sum += n
+			lo, hi = math.Min(lo, n), math.Max(hi, n)
+		}
+	}
+	switch a.fn {
+	case "count":
+		return strconv.Itoa(count)
+	case "distinct":
+		return strconv.Itoa(len(distinct))
+	}
+	if numbers == 0 {
+		return ""
+	}
+	switch a.fn {
+	case "sum":
+		return formatNumber(sum)
+	case "avg":
+		return formatNumber(sum / float64(numbers))
+	case "min":
+		return formatNumber(lo)
+	default:
+		return formatNumber(hi)
+	}
+}
+
+// sortBy sorts the rows by a column, descending with a "-" prefix
+func (t *Table) sortBy(spec string) error {
+	spec = strings.TrimSpace(spec)
+	desc := strings.HasPrefix(spec, "-")
+	col, err := t.column(strings.TrimPrefix(spec, "-"))
+	if err != nil {
+		return err
+	}
+	sort.SliceStable(t.Rows, func(i, j int) bool {
+		c := compareCells(t.Rows[i][col], t.Rows[j][col])
+		if desc {
+			return c > 0
+		}
+		return c < 0
+	})
+	return nil
+}
+
+// String formats the table as Markdown
+func (t *Table) String() string {
+	var b strings.Builder
+	writeRow := func(values []string) {
+		b.WriteString("|")
+		for _, v := range values {
+			b.WriteString(" " + strings.ReplaceAll(strings.ReplaceAll(v, "|", `\|`), "\n", " ") + " |")
+		}
+		b.WriteString("\n")
+	}
+	writeRow(t.Columns)
+	b.WriteString("|" + strings.Repeat(" --- |", len(t.Columns)) + "\n")
+	for _, row := range t.Rows {
+		writeRow(row)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// parseNumber parses a cell as a number, allowing thousands separators
+func parseNumber(cell string) (float64, bool) {
+	n, err := strconv.ParseFloat(strings.ReplaceAll(strings.TrimSpace(cell), ",", ""), 64)
+	return n, err == nil && !math.IsNaN(n)
+}
+
+// compareCells compares two cells as numbers when both are, and as
+// case-insensitive text otherwise, which orders ISO dates correctly
+func compareCells(a, b string) int {
+	x, okX := parseNumber(a)
+	y, okY := parseNumber(b)
+	if okX && okY {
+		switch {
+		case x < y:
+			return -1
+		case x > y:
+			return 1
+		}
+		return 0
+	}
+	return strings.Compare(strings.ToLower(strings.TrimSpace(a)), strings.ToLower(strings.TrimSpace(b)))
+}
+
+// TableConfig configures the table tool
+type TableConfig struct {
+	// Root is the directory files are read from; paths outside it are
+	// rejected (default: the working directory)
+	Root string
+	// MaxRows bounds the rows loaded from a file (0 = DefaultTableRows)
+	MaxRows int
+	// MaxOutputRows bounds the rows of a result given to the model
+	// (0 = DefaultTableOutputRows)
+	MaxOutputRows int
+}
+
+// TableTool loads CSV and XLSX files and answers questions about them
+// locally, so agents get schemas, statistics and query results instead of
+// whole spreadsheets in their prompts
+type TableTool struct {
+	*BaseTool
+	config TableConfig
+}
+
+// NewTableTool creates the table tool
+func NewTableTool(cfg TableConfig) *TableTool {
+	if cfg.Root == "" {
+		cfg.Root = "."
+	}
+	if cfg.MaxRows <= 0 {
+		cfg.MaxRows = DefaultTableRows
+	}
+	if cfg.MaxOutputRows <= 0 {
+		cfg.MaxOutputRows = DefaultTableOutputRows
+	}
+	return &TableTool{
+		BaseTool: NewBaseTool(TableToolName,
+			"Reads a CSV or XLSX file. Action \"schema\" reports its columns with types and statistics; "+
+				"action \"query\" returns rows, optionally filtered, grouped, aggregated and sorted.",
+			map[string]interface{}{
+				"file":      "path of the .csv, .tsv or .xlsx file",
+				"sheet":     "XLSX worksheet (default the first)",
+				"action":    "\"schema\" or \"query\" (default schema)",
+				"columns":   []string{"region", "amount"},
+				"filter":    []string{"amount >= 100", "region = EU"},
+				"group_by":  "column to group by",
+				"aggregate": []string{"count", "sum(amount)"},
+				"sort":      "column to sort by, \"-column\" for descending",
+				"limit":     DefaultTableOutputRows,
+			}),
+		config: cfg,
+	}
+}
+
+// Execute loads the file and reports its schema or runs a query
+func (t *TableTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	path, err := t.resolve(stringArg(args, "file"))
+	if err != nil {
+		return "", err
+	}
+	table, err := LoadTable(path, stringArg(args, "sheet"))
+	if err != nil {
+		return "", err
+	}
+	truncated := len(table.Rows) > t.config.MaxRows
+	if truncated {
+		table.Rows = table.Rows[:t.config.MaxRows]
+	}
+
+	var b strings.Builder
+	switch action := strings.ToLower(stringArg(args, "action")); action {
+	case "", "schema":
+		fmt.Fprintf(&b, "%d rows, %d columns\n", len(table.Rows), len(table.Columns))
+		for _, s := range table.Schema() {
+			fmt.Fprintf(&b, "- %s (%s): %d values, %d distinct", s.Name, s.Type, s.Values, s.Distinct)
+			switch s.Type {
+			case "number":
+				fmt.Fprintf(&b, ", min %s, max %s, mean %s", formatNumber(s.Min), formatNumber(s.Max), formatNumber(s.Mean))
+			case "text":
+				fmt.Fprintf(&b, ", top: %s", strings.Join(s.Top, ", "))
+			}
+			b.WriteString("\n")
+		}
+	case "query":
+		limit := t.config.MaxOutputRows
+		if _, ok := args["limit"]; ok {
+			n, err := limitArg(args)
+			if err != nil {
+				return "", err
+			}
+			limit = min(n, t.config.MaxOutputRows)
+		}
+		result, err := table.Query(TableQuery{
+			Columns:    listArg(args, "columns"),
+			Filters:    filterArg(args),
+			GroupBy:    stringArg(args, "group_by"),
+			Aggregates: listArg(args, "aggregate"),
+			Sort:       stringArg(args, "sort"),
+		})
+		if err != nil {
+			return "", err
+		}
+		total := len(result.Rows)
+		if total > limit {
+			result.Rows = result.Rows[:limit]
+		}
+		fmt.Fprintf(&b, "%d rows", total)
+		if total > limit {
+			fmt.Fprintf(&b, " (showing %d)", limit)
+		}
+		b.WriteString("\n" + result.String() + "\n")
+	default:
+		return "", errors.InvalidField("action", "must be schema or query").WithContext("value", action)
+	}
+	if truncated {
+		fmt.Fprintf(&b, "Only the first %d rows of the file were read.\n", t.config.MaxRows)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// resolve returns the path of a file inside the root directory
+func (t *TableTool) resolve(file string) (string, error) {
+	if file == "" {
+		return "", errors.RequiredField("file")
+	}
+	root, err := filepath.Abs(t.config.Root)
+	if err != nil {
+		return "", errors.Wrap(errors.ErrInvalidConfig, "invalid table root", err).WithContext("root", t.config.Root)
+	}
+	path := file
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(root, path)
+	}
+	path = filepath.Clean(path)
+	if rel, err := filepath.Rel(root, path); err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Unauthorized("file is outside the table directory").WithContext("file", file)
+	}
+	return path, nil
+}
+
+// filterArg returns the filters, given as a list or as conditions joined
+// with " and "
+func filterArg(args map[string]interface{}) []string {
+	if s, ok := args["filter"].(string); ok {
+		var filters []string
+		for _, f := range splitFold(s, " and ") {
+			if f = strings.TrimSpace(f); f != "" {
+				filters = append(filters, f)
+			}
+		}
+		return filters
+	}
+	return listArg(args, "filter")
+}
+
+// splitFold splits s around each case-insensitive occurrence of sep
+func splitFold(s, sep string) []string {
+	var parts []string
+	lower := strings.ToLower(s)
+	for {
+		i := strings.Index(lower, sep)
+		if i < 0 {
+			return append(parts, s)
+		}
+		parts = append(parts, s[:i])
+		s, lower = s[i+len(sep):], lower[i+len(sep):]
+	}
+}
@@ -0,0 +1,131 @@
+package tools
+
+import (
+	"archive/zip"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+const salesCSV = `region,product,amount,date
+EU,Widget,"1,200",2024-01-05
+US,Widget,300,2024-01-07
+EU,Gadget,50,2024-02-01
+APAC,Widget,,2024-02-10
+US,Gadget,700,2024-03-02
+`
+
+func TestTableTool(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "sales.csv"), []byte(salesCSV), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tool := NewTableTool(TableConfig{Root: dir})
+	ctx := context.Background()
+
+	got, err := tool.Execute(ctx, map[string]interface{}{"file": "sales.csv"})
+	if err != nil {
+		t.Fatalf("schema error = %v", err)
+	}
+	for _, want := range []string{
+		"5 rows, 4 columns",
+		"- region (text): 5 values, 3 distinct, top: EU (2), US (2), APAC (1)",
+		"- amount (number): 4 values, 4 distinct, min 50, max 1200, mean 562.5",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("schema = %q, want it to contain %q", got, want)
+		}
+	}
+
+	tests := []struct {
+		name string
+		args map[string]interface{}
+		want string
+	}{
+		{
+			name: "filter and sort",
+			args: map[string]interface{}{"columns": "region, amount", "filter": "amount >= 300 AND product = widget", "sort": "-amount"},
+			want: "2 rows\n| region | amount |\n| --- | --- |\n| EU | 1,200 |\n| US | 300 |",
+		},
+		{
+			name: "group and aggregate",
+			args: map[string]interface{}{"group_by": "region", "aggregate": []interface{}{"count", "sum(amount)"}, "sort": "region"},
+			want: "3 rows\n| region | count | sum(amount) |\n| --- | --- | --- |\n| APAC | 1 |  |\n| EU | 2 | 1250 |\n| US | 2 | 1000 |",
+		},
+		{
+			name: "aggregate all rows",
+			args: map[string]interface{}{"aggregate": "avg(amount), distinct(product)", "filter": []interface{}{"date < 2024-03-01"}},
+			want: "1 rows\n| avg(amount) | distinct(product) |\n| --- | --- |\n| 516.666666666667 | 2 |",
+		},
+		{
+			name: "limit",
+			args: map[string]interface{}{"columns": []interface{}{"product"}, "filter": "product contains GAD", "limit": 1},
+			want: "2 rows (showing 1)\n| product |\n| --- |\n| Gadget |",
+		},
+	}
+	for _, tt := range tests {
+		tt.args["file"] = "sales.csv"
+		tt.args["action"] = "query"
+		got, err := tool.Execute(ctx, tt.args)
+		if err != nil || got != tt.want {
+			t.Errorf("%s = %q, %v, want %q", tt.name, got, err, tt.want)
+		}
+	}
+
+	for _, args := range []map[string]interface{}{
+		{"file": "sales.csv", "action": "query", "filter": "price > 3"},
+		{"file": "sales.csv", "action": "query", "aggregate": "median(amount)"},
+		{"file": "sales.csv", "action": "query", "filter": "amount"},
+	} {
+		if _, err := tool.Execute(ctx, args); !errors.HasCode(err, errors.ErrInvalidField) {
+			t.Errorf("Execute(%v) error = %v, want ErrInvalidField", args, err)
+		}
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"file": "../secret.csv"}); !errors.HasCode(err, errors.ErrUnauthorized) {
+		t.Errorf("file outside root error = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestLoadTable_XLSX(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "book.xlsx")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := zip.NewWriter(f)
+	for name, content := range map[string]string{
+		"xl/workbook.xml": `<workbook xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>` +
+			`<sheet name="Summary" r:id="rId1"/><sheet name="Data" r:id="rId2"/></sheets></workbook>`,
+		"xl/_rels/workbook.xml.rels": `<Relationships><Relationship Id="rId1" Target="worksheets/sheet1.xml"/>` +
+			`<Relationship Id="rId2" Target="worksheets/sheet2.xml"/></Relationships>`,
+		"xl/sharedStrings.xml":     `<sst><si><t>name</t></si><si><t>score</t></si><si><r><t>Ada </t></r><r><t>L.</t></r></si></sst>`,
+		"xl/worksheets/sheet1.xml": `<worksheet><sheetData><row><c r="A1" t="inlineStr"><is><t>total</t></is></c></row></sheetData></worksheet>`,
+		"xl/worksheets/sheet2.xml": `<worksheet><sheetData><row><c r="A1" t="s"><v>0</v></c><c r="C1" t="s"><v>1</v></c></row>` +
+			`<row><c r="A2" t="s"><v>2</v></c><c r="C2"><v>9.5</v></c></row></sheetData></worksheet>`,
+	} {
+		part, _ := w.Create(name)
+		_, _ = part.Write([]byte(content))
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	table, err := LoadTable(path, "data")
+	if err != nil {
+		t.Fatalf("LoadTable() error = %v", err)
+	}
+	if got := strings.Join(table.Columns, ","); got != "name,column2,score" {
+		t.Errorf("Columns = %q", got)
+	}
+	if got := strings.Join(table.Rows[0], ","); got != "Ada L.,,9.5" {
+		t.Errorf("Rows[0] = %q", got)
+	}
+	if _, err := LoadTable(path, "Missing"); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("LoadTable() with a missing sheet error = %v, want ErrNotFound", err)
+	}
+}
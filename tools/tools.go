@@ -2,9 +2,12 @@ package tools
 
 import (
 	"context"
-	"encoding/json"
+	"sync"
+	"time"
 
 	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/jsonutil"
+	"github.com/counhopig/gittyai/limits"
 )
 
 // Tool defines the interface for an agent's tool
@@ -22,8 +25,10 @@ type Tool interface {
 	Args() map[string]interface{}
 }
 
-// Registry manages a collection of tools
+// Registry manages a collection of tools. It is safe for concurrent use,
+// so a single registry can be shared by every agent of an orchestrator.
 type Registry struct {
+	mu    sync.RWMutex
 	tools map[string]Tool
 }
 
@@ -36,6 +41,9 @@ func NewRegistry() *Registry {
 
 // Register adds a tool to the registry
 func (r *Registry) Register(tool Tool) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
 	name := tool.Name()
 	if _, exists := r.tools[name]; exists {
 		return errors.Validationf("tool %s already registered", name)
@@ -46,6 +54,9 @@ func (r *Registry) Register(tool Tool) error {
 
 // Get retrieves a tool by name
 func (r *Registry) Get(name string) (Tool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	tool, exists := r.tools[name]
 	if !exists {
 		return nil, errors.NotFound("tool", name)
@@ -53,18 +64,70 @@ func (r *Registry) Get(name string) (Tool, error) {
 	return tool, nil
 }
 
-// Execute runs a tool by name with the given arguments
+// Execute runs a tool by name with the given arguments. Side-effecting tools
+// first need the confirmation of the Confirmer carried by ctx, if any. Tool
+// failures are classified with errors.ToolError. Calls count against
+// the tool call limit of the run carried by ctx, if any, and fail with
+// ErrUnauthorized when the agent's permissions carried by ctx exclude the
+// tool.
 func (r *Registry) Execute(ctx context.Context, name string, args map[string]interface{}) (string, error) {
 	tool, err := r.Get(name)
 	if err != nil {
 		return "", err
 	}
+	if p, ok := PermissionsFromContext(ctx); ok {
+		if err := p.check(name); err != nil {
+			return "", err
+		}
+	}
+	meter := limits.FromContext(ctx)
+	if err := meter.ToolCall(name); err != nil {
+		return "", err
+	}
+	if dryRun, err := confirm(ctx, tool, args); err != nil || dryRun != "" {
+		return dryRun, err
+	}
+
+	start := time.Now()
+	output, err := tool.Execute(ctx, args)
+	meter.RecordTool(name, time.Since(start), len(output), err != nil)
+	if err != nil {
+		return "", errors.ToolError(name, err)
+	}
+	return output, nil
+}
 
-	return tool.Execute(ctx, args)
+// Call executes a tool call parsed from model output and returns the text to
+// give back to the model: the tool's output, or a description of the error
+// the model can act on
+func (r *Registry) Call(ctx context.Context, call *ToolCall) string {
+	output, err := r.Execute(ctx, call.Name, call.Arguments)
+	if err != nil {
+		return DescribeError(err)
+	}
+	return output
+}
+
+// registryKey is the context key for the registry of a run
+type registryKey struct{}
+
+// NewRegistryContext returns a context whose agents execute the tool calls
+// of their responses with r
+func NewRegistryContext(ctx context.Context, r *Registry) context.Context {
+	return context.WithValue(ctx, registryKey{}, r)
+}
+
+// RegistryFromContext returns the registry carried by ctx, or nil
+func RegistryFromContext(ctx context.Context) *Registry {
+	r, _ := ctx.Value(registryKey{}).(*Registry)
+	return r
 }
 
 // List returns all registered tool names
 func (r *Registry) List() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
 	names := make([]string, 0, len(r.tools))
 	for name := range r.tools {
 		names = append(names, name)
@@ -101,10 +164,13 @@ type ToolCall struct {
 	Arguments map[string]interface{} `json:"arguments"`
 }
 
-// ParseToolCall parses a tool call from JSON
+// ParseToolCall parses a tool call from JSON, tolerating the prose and
+// fences found in model output. The JSON must be complete and valid: a
+// truncated call is never repaired, since its arguments may have been cut
+// off, e.g. in the middle of a path.
 func ParseToolCall(data string) (*ToolCall, error) {
 	var call ToolCall
-	if err := json.Unmarshal([]byte(data), &call); err != nil {
+	if err := jsonutil.UnmarshalStrict(data, &call); err != nil {
 		return nil, errors.Wrap(errors.ErrInternal, "failed to parse tool call", err).WithContext("json_length", len(data))
 	}
 	return &call, nil
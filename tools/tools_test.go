@@ -0,0 +1,166 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+type failingTool struct {
+	*BaseTool
+	err error
+}
+
+func (t failingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	return "", t.err
+}
+
+func TestRegistry_CallDescribesErrors(t *testing.T) {
+	registry := NewRegistry()
+	_ = registry.Register(failingTool{NewBaseTool("search", "Web search", nil), errors.InvalidField("query", strings.Repeat("x", 1000))})
+
+	if _, err := registry.Execute(context.Background(), "search", nil); !errors.HasCode(err, errors.ErrToolInvalidArgs) {
+		t.Errorf("Execute() error = %v, want ErrToolInvalidArgs", err)
+	}
+
+	var got struct {
+		Error ErrorDescription `json:"error"`
+	}
+	out := registry.Call(context.Background(), &ToolCall{Name: "search"})
+	if err := json.Unmarshal([]byte(out), &got); err != nil {
+		t.Fatalf("Call() = %q, not JSON: %v", out, err)
+	}
+	if got.Error.Code != "tool.invalid_args" || got.Error.Hint == "" || got.Error.Retryable {
+		t.Errorf("Call() = %+v", got.Error)
+	}
+	if n := len([]rune(got.Error.Message)); n > maxErrorMessage+3 {
+		t.Errorf("message length = %d, want at most %d", n, maxErrorMessage+3)
+	}
+
+	if out := registry.Call(context.Background(), &ToolCall{Name: "missing"}); !strings.Contains(out, "notfound.resource") {
+		t.Errorf("Call() unknown tool = %q", out)
+	}
+}
+
+func TestParseToolCall(t *testing.T) {
+	call, err := ParseToolCall("I'll search.\n```json\n{\"name\": \"search\", \"arguments\": {\"query\": \"go\"}}\n```")
+	if err != nil || call.Name != "search" || call.Arguments["query"] != "go" {
+		t.Errorf("ParseToolCall() = %+v, %v", call, err)
+	}
+
+	// A truncated call is rejected rather than run with cut-off arguments
+	if call, err := ParseToolCall(`{"name":"delete_file","arguments":{"path":"/data/reports/2024-q1-fin`); err == nil {
+		t.Errorf("ParseToolCall() of a truncated call = %+v, want an error", call)
+	}
+}
+
+type countingTool struct {
+	*BaseTool
+	calls *int
+}
+
+func (t countingTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	*t.calls++
+	return "sent", nil
+}
+
+func TestRegistry_ConfirmsSideEffectingTools(t *testing.T) {
+	var sends, reads int
+	registry := NewRegistry()
+	_ = registry.Register(MarkSideEffecting(countingTool{NewBaseTool("send_email", "Sends an email", nil), &sends}))
+	_ = registry.Register(countingTool{NewBaseTool("search", "Web search", nil), &reads})
+	args := map[string]interface{}{"to": "ops@example.com"}
+
+	var out strings.Builder
+	ctx := NewConfirmContext(context.Background(), PromptConfirm(strings.NewReader("maybe\nn\nd\ny\n"), &out))
+	if _, err := registry.Execute(ctx, "search", nil); err != nil || reads != 1 {
+		t.Errorf("read-only tool: err = %v, calls = %d", err, reads)
+	}
+	if _, err := registry.Execute(ctx, "send_email", args); !errors.HasCode(err, errors.ErrPolicyViolation) {
+		t.Errorf("rejected call error = %v, want ErrPolicyViolation", err)
+	}
+	if got, err := registry.Execute(ctx, "send_email", args); err != nil || !strings.HasPrefix(got, "[dry run] send_email") {
+		t.Errorf("dry run = %q, %v", got, err)
+	}
+	if got, err := registry.Execute(ctx, "send_email", args); err != nil || got != "sent" {
+		t.Errorf("approved call = %q, %v", got, err)
+	}
+	if sends != 1 {
+		t.Errorf("send_email ran %d times, want only once after approval", sends)
+	}
+	if !strings.Contains(out.String(), `"to": "ops@example.com"`) {
+		t.Errorf("prompt does not show the arguments:\n%s", out.String())
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	registry := NewRegistry()
+	if err := RegisterBuiltins(registry, BuiltinConfig{Rates: StaticRates{Base: "USD", Rates: map[string]float64{"EUR": 0.5, "GBP": 0.25}}}); err != nil {
+		t.Fatalf("RegisterBuiltins() error = %v", err)
+	}
+	ctx := context.Background()
+
+	clock := NewDateTimeTool(func() time.Time { return time.Date(2024, 3, 10, 12, 30, 0, 0, time.UTC) })
+	got, err := clock.Execute(ctx, map[string]interface{}{"timezone": "Asia/Kolkata"})
+	if want := "2024-03-10T18:00:00+05:30 (Sunday, Asia/Kolkata, UTC+05:30)"; err != nil || got != want {
+		t.Errorf("datetime = %q, %v, want %q", got, err, want)
+	}
+	if _, err := clock.Execute(ctx, map[string]interface{}{"timezone": "Mars/Olympus"}); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("datetime with unknown zone error = %v, want ErrInvalidField", err)
+	}
+
+	for expr, want := range map[string]string{
+		"1 + 2 * 3":               "7",
+		"(1 + 2) * 3":             "9",
+		"2 ^ 3 ^ 2":               "512",
+		"-2 ^ 2":                  "-4",
+		"10 % 4 + 1_000":          "1002",
+		"sqrt(16) + max(1, 5, 3)": "9",
+		"round(pi * 100)":         "314",
+		"1.5e3 / 4":               "375",
+	} {
+		got, err := registry.Execute(ctx, CalculatorToolName, map[string]interface{}{"expression": expr})
+		if err != nil || got != want {
+			t.Errorf("calculator(%q) = %q, %v, want %q", expr, got, err, want)
+		}
+	}
+	for _, expr := range []string{"1 / 0", "2 +", "foo(1)", "(1 + 2", "1 2"} {
+		if _, err := Evaluate(expr); !errors.HasCode(err, errors.ErrInvalidFormat) {
+			t.Errorf("Evaluate(%q) error = %v, want ErrInvalidFormat", expr, err)
+		}
+	}
+
+	for _, tt := range []struct {
+		value    interface{}
+		from, to string
+		want     string
+	}{
+		{1, "mi", "km", "1 mi = 1.609344 km"},
+		{"2", "GiB", "MiB", "2 GiB = 2048 MiB"},
+		{212.0, "F", "C", "212 F = 100 C"},
+		{0.0, "C", "K", "0 C = 273.15 K"},
+		{10.0, "eur", "gbp", "10 eur = 5 gbp"},
+	} {
+		got, err := registry.Execute(ctx, ConvertToolName, map[string]interface{}{"value": tt.value, "from": tt.from, "to": tt.to})
+		if err != nil || got != tt.want {
+			t.Errorf("convert(%v %s to %s) = %q, %v, want %q", tt.value, tt.from, tt.to, got, err, tt.want)
+		}
+	}
+	for _, args := range []map[string]interface{}{
+		{"from": "kg", "to": "km"},
+		{"from": "C", "to": "kg"},
+		{"from": "parsec", "to": "km"},
+		{"from": "USD", "to": "JPY"},
+	} {
+		if _, err := registry.Execute(ctx, ConvertToolName, args); err == nil {
+			t.Errorf("convert(%v) succeeded, want error", args)
+		}
+	}
+	if _, err := NewConvertTool(nil).Convert(ctx, 1, "USD", "EUR"); !errors.HasCode(err, errors.ErrMissingConfig) {
+		t.Errorf("convert without rates error = %v, want ErrMissingConfig", err)
+	}
+}
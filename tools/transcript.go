@@ -0,0 +1,278 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// TranscriptToolName is the name of the YouTube transcript tool
+const TranscriptToolName = "youtube_transcript"
+
+// Defaults of the transcript tool
+const (
+	DefaultYouTubeURL = "https://www.youtube.com"
+	// DefaultTranscriptLength bounds the transcript given to the model, in
+	// characters
+	DefaultTranscriptLength = 20000
+)
+
+// TranscriptConfig configures the transcript tool
+type TranscriptConfig struct {
+	// Language is the preferred caption language code (default "en")
+	Language string
+	// MaxLength bounds the transcript in characters
+	// (0 = DefaultTranscriptLength)
+	MaxLength int
+	// BaseURL overrides the YouTube site (default: DefaultYouTubeURL)
+	BaseURL string
+}
+
+// Transcript is the captions of a video
+type Transcript struct {
+	VideoID   string
+	Language  string
+	Automatic bool // Generated by speech recognition
+	Lines     []TranscriptLine
+}
+
+// TranscriptLine is a caption of a video
+type TranscriptLine struct {
+	Start time.Duration
+	Text  string
+}
+
+// TranscriptTool fetches the captions of YouTube videos, preferring
+// manually written ones over automatic captions
+type TranscriptTool struct {
+	*BaseTool
+	config TranscriptConfig
+	client *http.Client
+}
+
+// NewTranscriptTool creates the transcript tool
+func NewTranscriptTool(cfg TranscriptConfig) *TranscriptTool {
+	if cfg.Language == "" {
+		cfg.Language = "en"
+	}
+	if cfg.MaxLength <= 0 {
+		cfg.MaxLength = DefaultTranscriptLength
+	}
+	if cfg.BaseURL == "" {
+		cfg.BaseURL = DefaultYouTubeURL
+	}
+	cfg.BaseURL = strings.TrimSuffix(cfg.BaseURL, "/")
+	return &TranscriptTool{
+		BaseTool: NewBaseTool(TranscriptToolName,
+			"Returns the transcript of a YouTube video with timestamps.",
+			map[string]interface{}{
+				"video":    "video URL or ID",
+				"language": fmt.Sprintf("caption language code (default %s)", cfg.Language),
+			}),
+		config: cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Execute fetches and formats the transcript
+func (t *TranscriptTool) Execute(ctx context.Context, args map[string]interface{}) (string, error) {
+	id, err := videoID(stringArg(args, "video"))
+	if err != nil {
+		return "", err
+	}
+	language := stringArg(args, "language")
+	if language == "" {
+		language = t.config.Language
+	}
+	transcript, err := t.Transcript(ctx, id, language)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Transcript of %s (%s", id, transcript.Language)
+	if transcript.Automatic {
+		b.WriteString(", automatic captions")
+	}
+	b.WriteString(")\n")
+	header := b.Len()
+	for i, line := range transcript.Lines {
+		entry := fmt.Sprintf("[%s] %s\n", formatTimestamp(line.Start), line.Text)
+		if b.Len()-header+len(entry) > t.config.MaxLength {
+			fmt.Fprintf(&b, "[transcript truncated after %d of %d captions]", i, len(transcript.Lines))
+			break
+		}
+		b.WriteString(entry)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// captionTrack is a caption track listed on a video's page
+type captionTrack struct {
+	BaseURL      string `json:"baseUrl"`
+	LanguageCode string `json:"languageCode"`
+	Kind         string `json:"kind"` // "asr" for automatic captions
+}
+
+// Transcript returns the captions of a video in the preferred language, or
+// in the first available one
+func (t *TranscriptTool) Transcript(ctx context.Context, id, language string) (*Transcript, error) {
+	page, err := t.get(ctx, fmt.Sprintf("%s/watch?v=%s", t.config.BaseURL, url.QueryEscape(id)), language)
+	if err != nil {
+		return nil, err
+	}
+	tracks, err := captionTracks(page, id)
+	if err != nil {
+		return nil, err
+	}
+	track := chooseTrack(tracks, language)
+
+	data, err := t.get(ctx, track.BaseURL, language)
+	if err != nil {
+		return nil, err
+	}
+	lines, err := parseTimedText(data)
+	if err != nil {
+		return nil, err
+	}
+	return &Transcript{VideoID: id, Language: track.LanguageCode, Automatic: track.Kind == "asr", Lines: lines}, nil
+}
+
+// get fetches a YouTube page
+func (t *TranscriptTool) get(ctx context.Context, url, language string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInternal, "failed to create request", err)
+	}
+	req.Header.Set("Accept-Language", language)
+	return send(t.client, req, "YouTube")
+}
+
+// videoIDPattern matches YouTube video IDs
+var videoIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{11}$`)
+
+// videoID extracts the video ID from a watch, short, embed or youtu.be URL
+func videoID(video string) (string, error) {
+	if video == "" {
+		return "", errors.RequiredField("video")
+	}
+	if videoIDPattern.MatchString(video) {
+		return video, nil
+	}
+	u, err := url.Parse(video)
+	if err == nil {
+		candidates := []string{u.Query().Get("v")}
+		path := strings.Trim(u.Path, "/")
+		if strings.HasSuffix(u.Hostname(), "youtu.be") {
+			candidates = append(candidates, path)
+		}
+		for _, prefix := range []string{"shorts/", "embed/", "live/", "v/"} {
+			if id, ok := strings.CutPrefix(path, prefix); ok {
+				candidates = append(candidates, id)
+			}
+		}
+		for _, id := range candidates {
+			if videoIDPattern.MatchString(id) {
+				return id, nil
+			}
+		}
+	}
+	return "", errors.InvalidField("video", "must be a YouTube video URL or ID").WithContext("value", video)
+}
+
+// captionTracks extracts the caption tracks from a watch page
+func captionTracks(page []byte, id string) ([]captionTrack, error) {
+	const marker = `"captionTracks":`
+	i := strings.Index(string(page), marker)
+	if i < 0 {
+		return nil, errors.NotFound("transcript", id).WithContext("reason", "the video has no captions or is unavailable")
+	}
+	var tracks []captionTrack
+	if err := json.NewDecoder(strings.NewReader(string(page[i+len(marker):]))).Decode(&tracks); err != nil || len(tracks) == 0 {
+		return nil, errors.New(errors.ErrAPIResponse, "failed to read the caption tracks of the video page").WithContext("video", id)
+	}
+	return tracks, nil
+}
+
+// chooseTrack prefers manual captions in the language or a regional variant
+// of it such as en-GB, then automatic ones, then the first track
+func chooseTrack(tracks []captionTrack, language string) captionTrack {
+	base, _, _ := strings.Cut(strings.ToLower(language), "-")
+	best, bestScore := tracks[0], 0
+	for _, track := range tracks {
+		code := strings.ToLower(track.LanguageCode)
+		score := 0
+		switch {
+		case code == strings.ToLower(language):
+			score = 2
+		case strings.HasPrefix(code, base+"-") || code == base:
+			score = 1
+		}
+		if score > 0 && track.Kind != "asr" {
+			score += 2
+		}
+		if score > bestScore {
+			best, bestScore = track, score
+		}
+	}
+	return best
+}
+
+// timedText decodes YouTube's caption formats: <transcript><text> with
+// start seconds, and format 3 <timedtext><body><p> with milliseconds
+type timedText struct {
+	Texts []struct {
+		Start string `xml:"start,attr"`
+		Text  string `xml:",chardata"`
+	} `xml:"text"`
+	Paragraphs []struct {
+		Time string `xml:"t,attr"`
+		Text string `xml:",innerxml"`
+	} `xml:"body>p"`
+}
+
+// parseTimedText parses a caption track
+func parseTimedText(data []byte) ([]TranscriptLine, error) {
+	var doc timedText
+	if err := xml.Unmarshal(data, &doc); err != nil {
+		return nil, errors.Wrap(errors.ErrAPIResponse, "invalid caption track", err)
+	}
+	var lines []TranscriptLine
+	add := func(start time.Duration, text string) {
+		// Captions are often escaped twice
+		if text = plainText(html.UnescapeString(text)); text != "" {
+			lines = append(lines, TranscriptLine{Start: start, Text: text})
+		}
+	}
+	for _, t := range doc.Texts {
+		seconds, _ := strconv.ParseFloat(t.Start, 64)
+		add(time.Duration(seconds*float64(time.Second)), t.Text)
+	}
+	for _, p := range doc.Paragraphs {
+		ms, _ := strconv.Atoi(p.Time)
+		add(time.Duration(ms)*time.Millisecond, p.Text)
+	}
+	if len(lines) == 0 {
+		return nil, errors.New(errors.ErrAPIResponse, "caption track is empty")
+	}
+	return lines, nil
+}
+
+// formatTimestamp formats a position in a video as m:ss or h:mm:ss
+func formatTimestamp(d time.Duration) string {
+	s := int(d.Seconds())
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s%3600/60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
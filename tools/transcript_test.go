@@ -0,0 +1,61 @@
+package tools
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+func TestTranscriptTool(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/watch" && r.URL.Query().Get("v") == "dQw4w9WgXcQ":
+			fmt.Fprintf(w, `<script>var ytInitialPlayerResponse = {"captions":{"playerCaptionsTracklistRenderer":{"captionTracks":[`+
+				`{"baseUrl":"%[1]s/timedtext?lang=en&kind=asr","languageCode":"en","kind":"asr"},`+
+				`{"baseUrl":"%[1]s/timedtext?lang=en-GB","languageCode":"en-GB"},`+
+				`{"baseUrl":"%[1]s/timedtext?lang=de","languageCode":"de"}]}}};</script>`, server.URL)
+		case r.URL.Path == "/watch":
+			_, _ = io.WriteString(w, `<script>var ytInitialPlayerResponse = {"playabilityStatus":{}};</script>`)
+		case r.URL.Query().Get("lang") == "en-GB":
+			_, _ = io.WriteString(w, `<transcript><text start="0.5" dur="2">Never gonna &amp;#39;give&amp;#39;</text>`+
+				`<text start="3725.2" dur="2">you up</text></transcript>`)
+		case r.URL.Query().Get("lang") == "de":
+			_, _ = io.WriteString(w, `<timedtext format="3"><body><p t="61000" d="1000"><s>Niemals</s><s> aufgeben</s></p></body></timedtext>`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	tool := NewTranscriptTool(TranscriptConfig{BaseURL: server.URL})
+	ctx := context.Background()
+
+	got, err := tool.Execute(ctx, map[string]interface{}{"video": "https://www.youtube.com/watch?v=dQw4w9WgXcQ&t=42"})
+	want := "Transcript of dQw4w9WgXcQ (en-GB)\n[0:00] Never gonna 'give'\n[1:02:05] you up"
+	if err != nil || got != want {
+		t.Errorf("Execute() = %q, %v, want %q", got, err, want)
+	}
+	got, err = tool.Execute(ctx, map[string]interface{}{"video": "https://youtu.be/dQw4w9WgXcQ", "language": "de"})
+	if want := "Transcript of dQw4w9WgXcQ (de)\n[1:01] Niemals aufgeben"; err != nil || got != want {
+		t.Errorf("Execute() in German = %q, %v, want %q", got, err, want)
+	}
+
+	short := NewTranscriptTool(TranscriptConfig{BaseURL: server.URL, MaxLength: 30})
+	got, _ = short.Execute(ctx, map[string]interface{}{"video": "dQw4w9WgXcQ"})
+	if want := "Transcript of dQw4w9WgXcQ (en-GB)\n[0:00] Never gonna 'give'\n[transcript truncated after 1 of 2 captions]"; got != want {
+		t.Errorf("truncated transcript = %q, want %q", got, want)
+	}
+
+	if _, err := tool.Execute(ctx, map[string]interface{}{"video": "https://youtube.com/shorts/aaaaaaaaaaa"}); !errors.HasCode(err, errors.ErrNotFound) {
+		t.Errorf("video without captions error = %v, want ErrNotFound", err)
+	}
+	if _, err := tool.Execute(ctx, map[string]interface{}{"video": "https://vimeo.com/123"}); !errors.HasCode(err, errors.ErrInvalidField) {
+		t.Errorf("non-YouTube URL error = %v, want ErrInvalidField", err)
+	}
+}
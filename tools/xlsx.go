@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"io"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// maxXLSXPart bounds the size of an XML part read from a workbook
+const maxXLSXPart = 64 << 20
+
+// xlsxWorkbook lists the sheets of a workbook
+type xlsxWorkbook struct {
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		RID  string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships maps relationship IDs to parts
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxSharedStrings holds the strings cells refer to by index
+type xlsxSharedStrings struct {
+	Items []struct {
+		Text string `xml:"t"`
+		Runs []struct {
+			Text string `xml:"t"`
+		} `xml:"r"`
+	} `xml:"si"`
+}
+
+// xlsxSheet holds the cells of a worksheet
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string `xml:"r,attr"`
+			Type   string `xml:"t,attr"`
+			Value  string `xml:"v"`
+			Inline struct {
+				Text string `xml:"t"`
+			} `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// readXLSX reads the rows of a worksheet, the first when sheet is empty.
+// Cells hold their displayed text without number formats; formulas give
+// their cached values.
+func readXLSX(r io.ReaderAt, size int64, sheet string) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, errors.Wrap(errors.ErrInvalidFormat, "invalid XLSX file", err)
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, f := range archive.File {
+		parts[f.Name] = f
+	}
+	decode := func(name string, v interface{}) error {
+		f, ok := parts[name]
+		if !ok {
+			return errors.Newf(errors.ErrInvalidFormat, "invalid XLSX file: missing %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return errors.Wrap(errors.ErrInvalidFormat, "invalid XLSX file", err).WithContext("part", name)
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPart)).Decode(v); err != nil {
+			return errors.Wrap(errors.ErrInvalidFormat, "invalid XLSX file", err).WithContext("part", name)
+		}
+		return nil
+	}
+
+	var workbook xlsxWorkbook
+	var rels xlsxRelationships
+	if err := decode("xl/workbook.xml", &workbook); err != nil {
+		return nil, err
+	}
+	if err := decode("xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, err
+	}
+	if len(workbook.Sheets) == 0 {
+		return nil, errors.New(errors.ErrInvalidFormat, "XLSX file has no sheets")
+	}
+	index := 0
+	if sheet != "" {
+		index = -1
+		var names []string
+		for i, s := range workbook.Sheets {
+			names = append(names, s.Name)
+			if strings.EqualFold(s.Name, sheet) {
+				index = i
+			}
+		}
+		if index < 0 {
+			return nil, errors.NotFound("sheet", sheet).WithContext("sheets", strings.Join(names, ", "))
+		}
+	}
+	var target string
+	for _, rel := range rels.Relationships {
+		if rel.ID == workbook.Sheets[index].RID {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+
+	var shared xlsxSharedStrings
+	if _, ok := parts["xl/sharedStrings.xml"]; ok {
+		if err := decode("xl/sharedStrings.xml", &shared); err != nil {
+			return nil, err
+		}
+	}
+	var ws xlsxSheet
+	if err := decode(target, &ws); err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, row := range ws.Rows {
+		var values []string
+		for i, c := range row.Cells {
+			col := i
+			if c.Ref != "" {
+				col = columnIndex(c.Ref)
+			}
+			for len(values) <= col {
+				values = append(values, "")
+			}
+			switch c.Type {
+			case "s":
+				if n, err := strconv.Atoi(c.Value); err == nil && n >= 0 && n < len(shared.Items) {
+					item := shared.Items[n]
+					text := item.Text
+					for _, run := range item.Runs {
+						text += run.Text
+					}
+					values[col] = text
+				}
+			case "inlineStr":
+				values[col] = c.Inline.Text
+			case "b":
+				values[col] = map[string]string{"0": "FALSE", "1": "TRUE"}[c.Value]
+			default:
+				values[col] = c.Value
+			}
+		}
+		rows = append(rows, values)
+	}
+	return rows, nil
+}
+
+// columnIndex returns the zero-based column of a cell reference such as "AB12"
+func columnIndex(ref string) int {
+	n := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		n = n*26 + int(r-'A'+1)
+	}
+	return n - 1
+}
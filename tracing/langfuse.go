@@ -0,0 +1,224 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// DefaultLangfuseHost is the Langfuse cloud endpoint
+const DefaultLangfuseHost = "https://cloud.langfuse.com"
+
+// LangfuseConfig represents the configuration for a Langfuse exporter
+type LangfuseConfig struct {
+	// Host is the Langfuse base URL; defaults to DefaultLangfuseHost
+	Host      string
+	PublicKey string
+	SecretKey string
+	// Tags are attached to every trace
+	Tags []string
+}
+
+// LangfuseExporter sends traces to Langfuse through its ingestion API
+type LangfuseExporter struct {
+	config LangfuseConfig
+	client *http.Client
+}
+
+// NewLangfuseExporter creates a Langfuse exporter
+func NewLangfuseExporter(cfg LangfuseConfig) (*LangfuseExporter, error) {
+	if cfg.PublicKey == "" {
+		return nil, errors.RequiredField("public key")
+	}
+	if cfg.SecretKey == "" {
+		return nil, errors.RequiredField("secret key")
+	}
+	if cfg.Host == "" {
+		cfg.Host = DefaultLangfuseHost
+	}
+	return &LangfuseExporter{
+		config: cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// langfuseEvent is one entry of an ingestion batch
+type langfuseEvent struct {
+	ID        string      `json:"id"`
+	Type      string      `json:"type"`
+	Timestamp time.Time   `json:"timestamp"`
+	Body      interface{} `json:"body"`
+}
+
+type langfuseUsage struct {
+	Input     int     `json:"input"`
+	Output    int     `json:"output"`
+	Total     int     `json:"total"`
+	Unit      string  `json:"unit"`
+	TotalCost float64 `json:"totalCost,omitempty"`
+}
+
+type langfuseObservation struct {
+	ID            string                 `json:"id"`
+	TraceID       string                 `json:"traceId"`
+	Name          string                 `json:"name"`
+	StartTime     time.Time              `json:"startTime"`
+	EndTime       time.Time              `json:"endTime"`
+	Model         string                 `json:"model,omitempty"`
+	Input         interface{}            `json:"input,omitempty"`
+	Output        interface{}            `json:"output,omitempty"`
+	Usage         *langfuseUsage         `json:"usage,omitempty"`
+	Metadata      map[string]interface{} `json:"metadata,omitempty"`
+	Level         string                 `json:"level,omitempty"`
+	StatusMessage string                 `json:"statusMessage,omitempty"`
+}
+
+type langfuseScore struct {
+	ID      string  `json:"id"`
+	TraceID string  `json:"traceId"`
+	Name    string  `json:"name"`
+	Value   float64 `json:"value"`
+	Comment string  `json:"comment,omitempty"`
+}
+
+// Export sends the trace with its task spans, generations and scores in one
+// ingestion batch
+func (e *LangfuseExporter) Export(ctx context.Context, trace *Trace) error {
+	metadata := map[string]interface{}{
+		"prompt_tokens":     trace.Usage.PromptTokens,
+		"completion_tokens": trace.Usage.CompletionTokens,
+		"cost_usd":          trace.CostUSD,
+	}
+	if trace.Error != "" {
+		metadata["error"] = trace.Error
+	}
+	batch := []langfuseEvent{{
+		ID:        uuidFor(trace.ID + "/trace"),
+		Type:      "trace-create",
+		Timestamp: trace.Start,
+		Body: map[string]interface{}{
+			"id":        trace.ID,
+			"name":      trace.Name,
+			"timestamp": trace.Start,
+			"output":    trace.Output,
+			"metadata":  metadata,
+			"tags":      e.config.Tags,
+		},
+	}}
+
+	for i, s := range trace.Spans {
+		obs := langfuseObservation{
+			ID:        uuidFor(trace.ID + "/span/" + strconv.Itoa(i)),
+			TraceID:   trace.ID,
+			Name:      s.Name,
+			StartTime: s.Start,
+			EndTime:   s.End,
+			Output:    s.Output,
+			Metadata:  map[string]interface{}{"agent": s.Agent, "cost_usd": s.CostUSD},
+		}
+		setLevel(&obs, s.Error)
+		batch = append(batch, langfuseEvent{ID: obs.ID + "-event", Type: "span-create", Timestamp: s.Start, Body: obs})
+	}
+
+	for _, g := range trace.Generations {
+		obs := langfuseObservation{
+			ID:        g.ID,
+			TraceID:   trace.ID,
+			Name:      g.Agent,
+			StartTime: g.Start,
+			EndTime:   g.End,
+			Model:     g.Model,
+			Input:     g.Prompt,
+			Output:    g.Completion,
+			Usage: &langfuseUsage{
+				Input:     g.Usage.PromptTokens,
+				Output:    g.Usage.CompletionTokens,
+				Total:     g.Usage.PromptTokens + g.Usage.CompletionTokens,
+				Unit:      "TOKENS",
+				TotalCost: g.CostUSD,
+			},
+		}
+		setLevel(&obs, g.Error)
+		batch = append(batch, langfuseEvent{ID: g.ID + "-event", Type: "generation-create", Timestamp: g.Start, Body: obs})
+	}
+
+	for _, s := range trace.Scores {
+		batch = append(batch, e.scoreEvent(trace.ID, s))
+	}
+	return e.ingest(ctx, batch)
+}
+
+// ExportScore sends a score for an exported trace
+func (e *LangfuseExporter) ExportScore(ctx context.Context, traceID string, score Score) error {
+	return e.ingest(ctx, []langfuseEvent{e.scoreEvent(traceID, score)})
+}
+
+// scoreEvent builds the ingestion event of a score; scores with the same
+// name replace each other
+func (e *LangfuseExporter) scoreEvent(traceID string, s Score) langfuseEvent {
+	id := uuidFor(traceID + "/score/" + s.Name)
+	return langfuseEvent{
+		ID:        id + "-event",
+		Type:      "score-create",
+		Timestamp: time.Now().UTC(),
+		Body:      langfuseScore{ID: id, TraceID: traceID, Name: s.Name, Value: s.Value, Comment: s.Comment},
+	}
+}
+
+// ingest posts a batch to the ingestion API. Langfuse answers 207 with
+// per-event errors when some events are rejected.
+func (e *LangfuseExporter) ingest(ctx context.Context, batch []langfuseEvent) error {
+	body, err := json.Marshal(map[string]interface{}{"batch": batch})
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal Langfuse batch", err)
+	}
+
+	endpoint := strings.TrimRight(e.config.Host, "/") + "/api/public/ingestion"
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", endpoint)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.SetBasicAuth(e.config.PublicKey, e.config.SecretKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.APICallError("export trace to Langfuse", err)
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.APIStatusCodeError(resp.StatusCode, string(respBody)).WithContext("exporter", "langfuse")
+	}
+
+	var result struct {
+		Errors []struct {
+			ID      string `json:"id"`
+			Status  int    `json:"status"`
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if json.Unmarshal(respBody, &result) == nil && len(result.Errors) > 0 {
+		first := result.Errors[0]
+		return errors.Newf(errors.ErrAPIResponse, "Langfuse rejected %d of %d events: %s", len(result.Errors), len(batch), first.Message).
+			WithContext("event_id", first.ID).
+			WithContext("status", first.Status)
+	}
+	return nil
+}
+
+// setLevel marks failed observations
+func setLevel(obs *langfuseObservation, errMsg string) {
+	if errMsg != "" {
+		obs.Level = "ERROR"
+		obs.StatusMessage = errMsg
+	}
+}
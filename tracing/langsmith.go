@@ -0,0 +1,187 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+)
+
+// DefaultLangSmithEndpoint is the LangSmith cloud API
+const DefaultLangSmithEndpoint = "https://api.smith.langchain.com"
+
+// LangSmithConfig represents the configuration for a LangSmith exporter
+type LangSmithConfig struct {
+	// Endpoint is the LangSmith API URL; defaults to DefaultLangSmithEndpoint
+	Endpoint string
+	APIKey   string
+	// Project is the LangSmith project receiving the runs; defaults to
+	// "default"
+	Project string
+}
+
+// LangSmithExporter sends traces to LangSmith as run trees through its
+// batch ingestion API. Scores are sent as feedback.
+type LangSmithExporter struct {
+	config LangSmithConfig
+	client *http.Client
+}
+
+// NewLangSmithExporter creates a LangSmith exporter
+func NewLangSmithExporter(cfg LangSmithConfig) (*LangSmithExporter, error) {
+	if cfg.APIKey == "" {
+		return nil, errors.RequiredField("API key")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = DefaultLangSmithEndpoint
+	}
+	if cfg.Project == "" {
+		cfg.Project = "default"
+	}
+	return &LangSmithExporter{
+		config: cfg,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// langsmithRun is a node of a LangSmith run tree
+type langsmithRun struct {
+	ID          string                 `json:"id"`
+	TraceID     string                 `json:"trace_id"`
+	ParentRunID string                 `json:"parent_run_id,omitempty"`
+	DottedOrder string                 `json:"dotted_order"`
+	Name        string                 `json:"name"`
+	RunType     string                 `json:"run_type"`
+	SessionName string                 `json:"session_name"`
+	StartTime   time.Time              `json:"start_time"`
+	EndTime     time.Time              `json:"end_time"`
+	Inputs      map[string]interface{} `json:"inputs"`
+	Outputs     map[string]interface{} `json:"outputs,omitempty"`
+	Error       string                 `json:"error,omitempty"`
+	Extra       map[string]interface{} `json:"extra,omitempty"`
+}
+
+// Export sends the trace as a chain run with one child chain run per task
+// and one child LLM run per generation, then its scores as feedback
+func (e *LangSmithExporter) Export(ctx context.Context, trace *Trace) error {
+	rootID := uuidFor(trace.ID)
+	rootOrder := dottedOrder(trace.Start, rootID)
+	runs := []langsmithRun{{
+		ID:          rootID,
+		TraceID:     rootID,
+		DottedOrder: rootOrder,
+		Name:        trace.Name,
+		RunType:     "chain",
+		SessionName: e.config.Project,
+		StartTime:   trace.Start,
+		EndTime:     trace.End,
+		Inputs:      map[string]interface{}{"run_id": trace.ID},
+		Outputs:     map[string]interface{}{"output": trace.Output},
+		Error:       trace.Error,
+		Extra: map[string]interface{}{"metadata": map[string]interface{}{
+			"run_id":   trace.ID,
+			"cost_usd": trace.CostUSD,
+		}},
+	}}
+
+	child := func(id, name, runType string, start, end time.Time) langsmithRun {
+		return langsmithRun{
+			ID:          id,
+			TraceID:     rootID,
+			ParentRunID: rootID,
+			DottedOrder: rootOrder + "." + dottedOrder(start, id),
+			Name:        name,
+			RunType:     runType,
+			SessionName: e.config.Project,
+			StartTime:   start,
+			EndTime:     end,
+		}
+	}
+	for i, s := range trace.Spans {
+		run := child(uuidFor(trace.ID+"/span/"+strconv.Itoa(i)), s.Name, "chain", s.Start, s.End)
+		run.Inputs = map[string]interface{}{"task": s.Name, "agent": s.Agent}
+		run.Outputs = map[string]interface{}{"output": s.Output}
+		run.Error = s.Error
+		run.Extra = map[string]interface{}{"metadata": map[string]interface{}{"cost_usd": s.CostUSD}}
+		runs = append(runs, run)
+	}
+	for _, g := range trace.Generations {
+		run := child(uuidFor(g.ID), g.Agent, "llm", g.Start, g.End)
+		run.Inputs = map[string]interface{}{"prompt": g.Prompt}
+		run.Outputs = map[string]interface{}{
+			"output": g.Completion,
+			"usage_metadata": map[string]int{
+				"input_tokens":  g.Usage.PromptTokens,
+				"output_tokens": g.Usage.CompletionTokens,
+				"total_tokens":  g.Usage.PromptTokens + g.Usage.CompletionTokens,
+			},
+		}
+		run.Error = g.Error
+		run.Extra = map[string]interface{}{"metadata": map[string]interface{}{
+			"ls_model_name": g.Model,
+			"cost_usd":      g.CostUSD,
+		}}
+		runs = append(runs, run)
+	}
+
+	if err := e.post(ctx, "/runs/batch", map[string]interface{}{"post": runs}); err != nil {
+		return err
+	}
+	for _, s := range trace.Scores {
+		if err := e.ExportScore(ctx, trace.ID, s); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportScore sends a score as feedback on the trace's root run
+func (e *LangSmithExporter) ExportScore(ctx context.Context, traceID string, score Score) error {
+	return e.post(ctx, "/feedback", map[string]interface{}{
+		"id":      uuidFor(traceID + "/score/" + score.Name),
+		"run_id":  uuidFor(traceID),
+		"key":     score.Name,
+		"score":   score.Value,
+		"comment": score.Comment,
+	})
+}
+
+// post sends a JSON request to the LangSmith API
+func (e *LangSmithExporter) post(ctx context.Context, path string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to marshal LangSmith request", err).WithContext("path", path)
+	}
+
+	endpoint := strings.TrimRight(e.config.Endpoint, "/") + path
+	req, err := http.NewRequestWithContext(ctx, "POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(errors.ErrInternal, "failed to create request", err).WithContext("url", endpoint)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("x-api-key", e.config.APIKey)
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return errors.APICallError("export trace to LangSmith", err).WithContext("path", path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		respBody, _ := io.ReadAll(resp.Body)
+		return errors.APIStatusCodeError(resp.StatusCode, string(respBody)).WithContext("exporter", "langsmith")
+	}
+	return nil
+}
+
+// dottedOrder is the sort key LangSmith uses to order runs in a tree: the
+// start time in microseconds followed by the run ID
+func dottedOrder(start time.Time, id string) string {
+	return strings.Replace(start.UTC().Format("20060102T150405.000000Z"), ".", "", 1) + id
+}
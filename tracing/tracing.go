@@ -0,0 +1,320 @@
+// Package tracing collects run traces (tasks, prompts, completions,
+// latencies, costs and scores) and exports them to LLM observability
+// platforms such as Langfuse and LangSmith.
+//
+// A Collector is both an events.Sink, receiving run and task events from the
+// orchestrator, and an LLM wrapper recording every generation. When a run
+// finishes, its trace is sent to each exporter.
+package tracing
+
+import (
+	"context"
+	"crypto/sha256"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/counhopig/gittyai/errors"
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// Trace is the record of one run
+type Trace struct {
+	ID     string // Run ID
+	Name   string
+	Start  time.Time
+	End    time.Time
+	Output string // Result of the last completed task
+	Error  string
+
+	Usage   llm.TokenUsage
+	CostUSD float64
+
+	Spans       []Span       // One per task
+	Generations []Generation // One per LLM call
+	Scores      []Score
+}
+
+// Span is the execution of one task
+type Span struct {
+	Name    string // Task description
+	Agent   string
+	Start   time.Time
+	End     time.Time
+	Output  string
+	Error   string
+	Usage   llm.TokenUsage
+	CostUSD float64
+}
+
+// Generation is one LLM call
+type Generation struct {
+	ID         string
+	Agent      string
+	Model      string
+	Prompt     string
+	Completion string
+	Start      time.Time
+	End        time.Time
+	Usage      llm.TokenUsage
+	CostUSD    float64
+	Error      string
+}
+
+// Score is an evaluation of a run, such as a user rating or a judge's grade
+type Score struct {
+	Name    string
+	Value   float64
+	Comment string
+}
+
+// Exporter sends traces to an observability platform. Implementations must
+// be safe for concurrent use.
+type Exporter interface {
+	// Export sends a finished trace
+	Export(ctx context.Context, trace *Trace) error
+	// ExportScore attaches a score to a trace that was already exported
+	ExportScore(ctx context.Context, traceID string, score Score) error
+}
+
+// Config configures a Collector
+type Config struct {
+	// Exporters receive every finished trace
+	Exporters []Exporter
+	// Name is the trace name; defaults to "gittyai-run"
+	Name string
+	// Prices maps model names to prices, used to cost generations
+	Prices map[string]llm.Price
+	// Redact rewrites prompts and completions before they are exported
+	Redact func(string) string
+}
+
+// Collector assembles traces from orchestrator events and LLM calls
+type Collector struct {
+	config Config
+
+	mu     sync.Mutex
+	traces map[string]*Trace // Traces of runs in progress by run ID
+	seq    int
+}
+
+// NewCollector creates a collector exporting to cfg.Exporters
+func NewCollector(cfg Config) (*Collector, error) {
+	if len(cfg.Exporters) == 0 {
+		return nil, errors.RequiredField("exporters")
+	}
+	if cfg.Name == "" {
+		cfg.Name = "gittyai-run"
+	}
+	return &Collector{config: cfg, traces: make(map[string]*Trace)}, nil
+}
+
+// Publish implements events.Sink. A trace is opened when its run starts and
+// exported when it completes or fails; export errors are returned.
+func (c *Collector) Publish(ctx context.Context, event events.Event) error {
+	c.mu.Lock()
+	switch event.Type {
+	case events.RunStarted:
+		c.traces[event.RunID] = &Trace{ID: event.RunID, Name: c.config.Name, Start: event.Timestamp}
+		c.mu.Unlock()
+		return nil
+	case events.TaskCompleted, events.TaskFailed:
+		if trace := c.traces[event.RunID]; trace != nil {
+			trace.Spans = append(trace.Spans, span(event))
+			if event.Type == events.TaskCompleted {
+				trace.Output = event.Result
+			}
+		}
+		c.mu.Unlock()
+		return nil
+	case events.RunCompleted, events.RunFailed:
+		trace := c.traces[event.RunID]
+		delete(c.traces, event.RunID)
+		c.mu.Unlock()
+		if trace == nil {
+			return nil
+		}
+		trace.End = event.Timestamp
+		trace.Error = event.Error
+		trace.Usage = llm.TokenUsage{PromptTokens: event.PromptTokens, CompletionTokens: event.CompletionTokens, CachedTokens: event.CachedTokens}
+		trace.CostUSD = event.CostUSD
+		return c.export(ctx, trace)
+	default:
+		c.mu.Unlock()
+		return nil
+	}
+}
+
+// Score attaches a score to a run. Scores of runs in progress are exported
+// with their trace; scores of finished runs are sent to every exporter.
+func (c *Collector) Score(ctx context.Context, runID string, score Score) error {
+	if score.Name == "" {
+		return errors.RequiredField("score name")
+	}
+	c.mu.Lock()
+	if trace := c.traces[runID]; trace != nil {
+		trace.Scores = append(trace.Scores, score)
+		c.mu.Unlock()
+		return nil
+	}
+	c.mu.Unlock()
+
+	var firstErr error
+	for _, e := range c.config.Exporters {
+		if err := e.ExportScore(ctx, runID, score); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// export sends a trace to every exporter and returns the first error
+func (c *Collector) export(ctx context.Context, trace *Trace) error {
+	var firstErr error
+	for _, e := range c.config.Exporters {
+		if err := e.Export(ctx, trace); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// record adds a generation to the trace of the run carried by ctx. Calls
+// outside a traced run are dropped.
+func (c *Collector) record(ctx context.Context, gen Generation) {
+	if c.config.Redact != nil {
+		gen.Prompt = c.config.Redact(gen.Prompt)
+		gen.Completion = c.config.Redact(gen.Completion)
+	}
+	if price, ok := c.config.Prices[gen.Model]; ok {
+		gen.CostUSD = price.Cost(gen.Usage)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	trace := c.traces[runid.FromContext(ctx)]
+	if trace == nil {
+		return
+	}
+	c.seq++
+	gen.ID = fmt.Sprintf("%s-%d", trace.ID, c.seq)
+	trace.Generations = append(trace.Generations, gen)
+}
+
+// span converts a task completion event into a span
+func span(event events.Event) Span {
+	end := event.Timestamp
+	return Span{
+		Name:    event.Task,
+		Agent:   event.Agent,
+		Start:   end.Add(-time.Duration(event.DurationMS) * time.Millisecond),
+		End:     end,
+		Output:  event.Result,
+		Error:   event.Error,
+		Usage:   llm.TokenUsage{PromptTokens: event.PromptTokens, CompletionTokens: event.CompletionTokens, CachedTokens: event.CachedTokens},
+		CostUSD: event.CostUSD,
+	}
+}
+
+// LLM wraps a provider and records every call as a generation of the
+// current run's trace
+type LLM struct {
+	inner     llm.LLM
+	collector *Collector
+	agent     string
+	model     string
+}
+
+// WrapLLM returns an LLM that traces calls through inner. The agent name
+// labels the generations; model is used when the provider does not report
+// one.
+func (c *Collector) WrapLLM(inner llm.LLM, agentName, model string) *LLM {
+	return &LLM{inner: inner, collector: c, agent: agentName, model: model}
+}
+
+// Generate calls the wrapped provider and records the generation
+func (t *LLM) Generate(ctx context.Context, prompt string) (string, error) {
+	resp, err := t.GenerateResponse(ctx, prompt)
+	if err != nil {
+		return "", err
+	}
+	return resp.Content, nil
+}
+
+// GenerateResponse calls the wrapped provider and records the generation,
+// including failed calls
+func (t *LLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	gen := Generation{Agent: t.agent, Model: t.model, Prompt: prompt, Start: time.Now().UTC()}
+	resp, err := llm.GenerateResponse(ctx, t.inner, prompt)
+	gen.End = time.Now().UTC()
+	if err != nil {
+		gen.Error = err.Error()
+	} else {
+		gen.Completion = resp.Content
+		gen.Usage = resp.Usage
+		if resp.Model != "" {
+			gen.Model = resp.Model
+		}
+	}
+	t.collector.record(ctx, gen)
+	return resp, err
+}
+
+// Chat calls the wrapped provider with a conversation and records the
+// generation
+func (t *LLM) Chat(ctx context.Context, messages []llm.Message) (llm.Message, error) {
+	resp, err := t.ChatResponse(ctx, messages)
+	if err != nil {
+		return llm.Message{}, err
+	}
+	return llm.Message{Role: llm.RoleAssistant, Content: resp.Content}, nil
+}
+
+// ChatResponse calls the wrapped provider with a conversation and records
+// the generation with the flattened conversation as its prompt
+func (t *LLM) ChatResponse(ctx context.Context, messages []llm.Message) (*llm.Response, error) {
+	gen := Generation{Agent: t.agent, Model: t.model, Prompt: llm.FlattenMessages(messages), Start: time.Now().UTC()}
+	resp, err := llm.ChatResponse(ctx, t.inner, messages)
+	gen.End = time.Now().UTC()
+	if err != nil {
+		gen.Error = err.Error()
+	} else {
+		gen.Completion = resp.Content
+		gen.Usage = resp.Usage
+		if resp.Model != "" {
+			gen.Model = resp.Model
+		}
+	}
+	t.collector.record(ctx, gen)
+	return resp, err
+}
+
+// GenerateStream streams the response of the wrapped provider and records
+// the generation once the stream ends
+func (t *LLM) GenerateStream(ctx context.Context, prompt string, fn func(chunk string) error) (*llm.Response, error) {
+	gen := Generation{Agent: t.agent, Model: t.model, Prompt: prompt, Start: time.Now().UTC()}
+	resp, err := llm.GenerateStream(ctx, t.inner, prompt, fn)
+	gen.End = time.Now().UTC()
+	if err != nil {
+		gen.Error = err.Error()
+	} else {
+		gen.Completion = resp.Content
+		gen.Usage = resp.Usage
+		if resp.Model != "" {
+			gen.Model = resp.Model
+		}
+	}
+	t.collector.record(ctx, gen)
+	return resp, err
+}
+
+// uuidFor maps an ID to a stable UUID, for platforms requiring UUIDs
+func uuidFor(id string) string {
+	sum := sha256.Sum256([]byte(id))
+	b := sum[:16]
+	b[6] = b[6]&0x0f | 0x50 // Version 5 (name-based, SHA)
+	b[8] = b[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
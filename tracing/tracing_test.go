@@ -0,0 +1,195 @@
+package tracing
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/counhopig/gittyai/events"
+	"github.com/counhopig/gittyai/llm"
+	"github.com/counhopig/gittyai/runid"
+)
+
+// usageLLM answers with a fixed usage
+type usageLLM struct{}
+
+func (usageLLM) Generate(ctx context.Context, prompt string) (string, error) {
+	return "answer", nil
+}
+
+func (usageLLM) GenerateResponse(ctx context.Context, prompt string) (*llm.Response, error) {
+	return &llm.Response{Content: "answer", Model: "gpt-4o", Usage: llm.TokenUsage{PromptTokens: 1000, CompletionTokens: 500}}, nil
+}
+
+// memoryExporter keeps exported traces and scores
+type memoryExporter struct {
+	mu     sync.Mutex
+	traces []*Trace
+	scores []Score
+}
+
+func (m *memoryExporter) Export(ctx context.Context, trace *Trace) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.traces = append(m.traces, trace)
+	return nil
+}
+
+func (m *memoryExporter) ExportScore(ctx context.Context, traceID string, score Score) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.scores = append(m.scores, score)
+	return nil
+}
+
+// runTrace publishes the events of a one-task run with one LLM call
+func runTrace(t *testing.T, c *Collector) {
+	t.Helper()
+	ctx := runid.NewContext(context.Background(), "run-1")
+	publish := func(ev events.Event) {
+		if err := c.Publish(ctx, ev); err != nil {
+			t.Fatalf("Publish(%s) unexpected error: %v", ev.Type, err)
+		}
+	}
+
+	publish(events.New(events.RunStarted, "run-1"))
+	if _, err := c.WrapLLM(usageLLM{}, "writer", "").Generate(ctx, "secret prompt"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	if err := c.Score(ctx, "run-1", Score{Name: "quality", Value: 0.8}); err != nil {
+		t.Fatalf("Score() unexpected error: %v", err)
+	}
+	task := events.New(events.TaskCompleted, "run-1")
+	task.Task, task.Agent, task.Result, task.DurationMS = "Write", "writer", "answer", 1500
+	publish(task)
+	publish(events.New(events.RunCompleted, "run-1"))
+}
+
+func TestCollector_ExportsTraces(t *testing.T) {
+	exporter := &memoryExporter{}
+	c, err := NewCollector(Config{
+		Exporters: []Exporter{exporter},
+		Prices:    map[string]llm.Price{"gpt-4o": {Prompt: 2, Completion: 10}},
+		Redact:    func(s string) string { return strings.ReplaceAll(s, "secret", "[REDACTED]") },
+	})
+	if err != nil {
+		t.Fatalf("NewCollector() unexpected error: %v", err)
+	}
+
+	// Calls outside a traced run are dropped
+	if _, err := c.WrapLLM(usageLLM{}, "writer", "").Generate(context.Background(), "hi"); err != nil {
+		t.Fatalf("Generate() unexpected error: %v", err)
+	}
+	runTrace(t, c)
+
+	if len(exporter.traces) != 1 {
+		t.Fatalf("exported %d traces, want 1", len(exporter.traces))
+	}
+	trace := exporter.traces[0]
+	if trace.ID != "run-1" || trace.Output != "answer" || len(trace.Spans) != 1 || len(trace.Scores) != 1 {
+		t.Errorf("trace = %+v", trace)
+	}
+	if got := trace.Spans[0].End.Sub(trace.Spans[0].Start).Milliseconds(); got != 1500 {
+		t.Errorf("span duration = %dms, want 1500", got)
+	}
+	if len(trace.Generations) != 1 {
+		t.Fatalf("len(Generations) = %d, want 1", len(trace.Generations))
+	}
+	gen := trace.Generations[0]
+	if gen.Prompt != "[REDACTED] prompt" || gen.Model != "gpt-4o" || gen.CostUSD != 0.007 {
+		t.Errorf("generation = %+v", gen)
+	}
+
+	// Scores of finished runs go straight to the exporters
+	if err := c.Score(context.Background(), "run-1", Score{Name: "user", Value: 1}); err != nil {
+		t.Fatalf("Score() unexpected error: %v", err)
+	}
+	if len(exporter.scores) != 1 {
+		t.Errorf("exported %d scores, want 1", len(exporter.scores))
+	}
+}
+
+// captureServer records the paths and bodies of requests
+func captureServer(t *testing.T, check func(r *http.Request)) (*httptest.Server, map[string][]map[string]interface{}) {
+	t.Helper()
+	var mu sync.Mutex
+	bodies := make(map[string][]map[string]interface{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		check(r)
+		var body map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("invalid request body: %v", err)
+		}
+		mu.Lock()
+		bodies[r.URL.Path] = append(bodies[r.URL.Path], body)
+		mu.Unlock()
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`{}`))
+	}))
+	t.Cleanup(server.Close)
+	return server, bodies
+}
+
+func TestLangfuseExporter(t *testing.T) {
+	server, bodies := captureServer(t, func(r *http.Request) {
+		if user, pass, ok := r.BasicAuth(); !ok || user != "pk" || pass != "sk" {
+			t.Errorf("basic auth = %q/%q, want pk/sk", user, pass)
+		}
+	})
+	exporter, err := NewLangfuseExporter(LangfuseConfig{Host: server.URL, PublicKey: "pk", SecretKey: "sk"})
+	if err != nil {
+		t.Fatalf("NewLangfuseExporter() unexpected error: %v", err)
+	}
+	c, _ := NewCollector(Config{Exporters: []Exporter{exporter}})
+	runTrace(t, c)
+
+	batches := bodies["/api/public/ingestion"]
+	if len(batches) != 1 {
+		t.Fatalf("got %d ingestion requests, want 1", len(batches))
+	}
+	var types []string
+	for _, ev := range batches[0]["batch"].([]interface{}) {
+		types = append(types, ev.(map[string]interface{})["type"].(string))
+	}
+	if got := strings.Join(types, ","); got != "trace-create,span-create,generation-create,score-create" {
+		t.Errorf("event types = %s", got)
+	}
+}
+
+func TestLangSmithExporter(t *testing.T) {
+	server, bodies := captureServer(t, func(r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" {
+			t.Errorf("x-api-key = %q, want key", r.Header.Get("x-api-key"))
+		}
+	})
+	exporter, err := NewLangSmithExporter(LangSmithConfig{Endpoint: server.URL, APIKey: "key", Project: "demo"})
+	if err != nil {
+		t.Fatalf("NewLangSmithExporter() unexpected error: %v", err)
+	}
+	c, _ := NewCollector(Config{Exporters: []Exporter{exporter}})
+	runTrace(t, c)
+
+	batches := bodies["/runs/batch"]
+	if len(batches) != 1 {
+		t.Fatalf("got %d batch requests, want 1", len(batches))
+	}
+	runs := batches[0]["post"].([]interface{})
+	if len(runs) != 3 {
+		t.Fatalf("got %d runs, want 3", len(runs))
+	}
+	root := runs[0].(map[string]interface{})
+	llmRun := runs[2].(map[string]interface{})
+	if llmRun["run_type"] != "llm" || llmRun["parent_run_id"] != root["id"] || llmRun["session_name"] != "demo" {
+		t.Errorf("llm run = %v", llmRun)
+	}
+	if !strings.HasPrefix(llmRun["dotted_order"].(string), root["dotted_order"].(string)+".") {
+		t.Errorf("dotted_order %v is not under %v", llmRun["dotted_order"], root["dotted_order"])
+	}
+	if feedback := bodies["/feedback"]; len(feedback) != 1 || feedback[0]["key"] != "quality" || feedback[0]["run_id"] != root["id"] {
+		t.Errorf("feedback = %v", feedback)
+	}
+}